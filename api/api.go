@@ -0,0 +1,318 @@
+// Package api is the stable entry point for controlling the proxy from Go code
+//
+// Downstream tools should import this package instead of config, mcp, protocol
+// and proxy directly: those packages are internal building blocks and change
+// with the implementation, while the identifiers exported here follow Version.
+// Breaking changes to this package bump the major version.
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/health"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/monitoring"
+	"remote-mcp-proxy/protocol"
+	"remote-mcp-proxy/proxy"
+	"remote-mcp-proxy/state"
+	"remote-mcp-proxy/webhook"
+)
+
+// Version is the version of the api package contract
+const Version = "1.2.0"
+
+// webhookDrainTimeout bounds how long Shutdown waits for queued webhook events
+const webhookDrainTimeout = 5 * time.Second
+
+// Config is the proxy configuration (mcpServers plus environment settings)
+type Config = config.Config
+
+// MCPServerConfig describes how to launch one MCP server
+type MCPServerConfig = config.MCPServer
+
+// ServerStatus reports the state of one configured MCP server
+type ServerStatus = mcp.ServerStatus
+
+// Message is a JSON-RPC 2.0 message as exchanged with MCP servers
+type Message = protocol.JSONRPCMessage
+
+// ErrorResponse is the JSON body of the proxy's HTTP error responses
+type ErrorResponse = proxy.ErrorResponse
+
+// Server is a single running MCP server process
+type Server interface {
+	// SendAndReceive sends a JSON-RPC request and waits for its response
+	SendAndReceive(ctx context.Context, message []byte) ([]byte, error)
+	// IsRunning reports whether the process is running
+	IsRunning() bool
+	// PID returns the process ID, or 0 when not running
+	PID() int
+	// StderrLines returns up to the last n lines written to stderr
+	StderrLines(n int) []string
+	// Stop terminates the process
+	Stop()
+}
+
+// Manager starts, stops and looks up MCP servers
+type Manager interface {
+	// StartAll starts every configured server, returning an error listing
+	// those that failed to start while the others keep running
+	StartAll() error
+	// StopAll stops every server, including session instances
+	StopAll()
+	// RestartServer restarts a configured server by name
+	RestartServer(name string) error
+	// Server returns the shared instance of a configured server
+	Server(name string) (Server, bool)
+	// SessionServer returns the instance dedicated to a session, starting it if needed
+	SessionServer(sessionID, serverName string) (Server, bool)
+	// CleanupSession stops all instances belonging to a session
+	CleanupSession(sessionID string)
+	// Servers returns the status of every configured server
+	Servers() []ServerStatus
+}
+
+// Translator converts between Remote MCP and local MCP JSON-RPC messages
+type Translator interface {
+	// RemoteToMCP converts a Remote MCP message to local MCP JSON-RPC
+	RemoteToMCP(data []byte) ([]byte, error)
+	// MCPToRemote converts a local MCP JSON-RPC message to Remote MCP
+	MCPToRemote(data []byte) ([]byte, error)
+	// CreateErrorResponse builds a JSON-RPC error response
+	CreateErrorResponse(id interface{}, code int, message string, isRemoteMCP bool) ([]byte, error)
+	// IsInitialized reports whether a session completed the initialize handshake
+	IsInitialized(sessionID string) bool
+	// RemoveConnection forgets a session
+	RemoveConnection(sessionID string)
+}
+
+var (
+	_ Server     = (*mcp.Server)(nil)
+	_ Manager    = (*manager)(nil)
+	_ Translator = (*protocol.Translator)(nil)
+)
+
+// LoadConfig reads a configuration file and applies environment settings
+func LoadConfig(path string) (*Config, error) {
+	return config.Load(path)
+}
+
+// Proxy is a complete proxy instance: MCP servers, monitoring and HTTP routes
+type Proxy struct {
+	config          *config.Config
+	manager         *mcp.Manager
+	server          *proxy.Server
+	healthChecker   *health.HealthChecker
+	resourceMonitor *monitoring.ResourceMonitor
+	webhooks        *webhook.Notifier
+	usage           *state.Usage
+	httpServer      *http.Server
+	listener        net.Listener // Set by Listen
+}
+
+// New creates a proxy for cfg without starting any process
+func New(cfg *Config) (*Proxy, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("configuration is required")
+	}
+
+	// Secrets handed to servers are masked in every log line
+	for _, server := range cfg.MCPServers {
+		logger.RedactEnv(server.Env)
+		logger.RedactEnv(server.Headers)
+	}
+	for _, hook := range cfg.Webhooks {
+		logger.RedactValues(hook.Secret)
+	}
+
+	mcpManager := mcp.NewManager(cfg.MCPServers)
+
+	// Enable opt-in wire capture before any traffic flows
+	if cfg.WireCapture == "memory" || cfg.WireCapture == "file" {
+		traceDir := ""
+		if cfg.WireCapture == "file" {
+			traceDir = cfg.WireCaptureDir
+		}
+		recorder, err := mcp.NewTraceRecorder(1000, 100, traceDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to enable wire capture: %w", err)
+		}
+		mcpManager.EnableWireCapture(recorder)
+		logger.System().Warn("Wire capture enabled (mode: %s) - JSON-RPC payloads are being recorded", cfg.WireCapture)
+	}
+
+	// State files are encrypted at rest with the state encryption key, if any
+	stateKeys, err := cfg.StateEncryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+	logger.RedactValues(stateKeys...)
+	stateCipher, err := state.NewCipher(stateKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state encryption key: %w", err)
+	}
+	if stateCipher != nil && cfg.StateDir != "" {
+		logger.System().Info("State files in %s are encrypted with key %s", cfg.StateDir, stateCipher.KeyID())
+	}
+
+	// Incident history survives restarts in the state directory when it is writable
+	incidents, err := state.NewEncryptedStore(cfg.StateDir, stateCipher, cfg.MaxIncidents, cfg.IncidentRetention)
+	if err != nil {
+		logger.System().Warn("Incident history kept in memory only: %v", err)
+		incidents, _ = state.NewStore("", cfg.MaxIncidents, cfg.IncidentRetention)
+	}
+	mcpManager.EnableIncidentHistory(incidents)
+
+	// Webhooks hear about crashes, restarts and health changes through the incident history
+	webhooks := webhook.NewNotifier(cfg.Webhooks)
+	incidents.OnIncident(webhooks.NotifyIncident)
+	mcpManager.EnableWebhooks(webhooks)
+	mcpManager.SetPreinstall(mcp.PreinstallOptions{Auto: cfg.Preinstall == config.PreinstallAuto, Timeout: cfg.PreinstallTimeout})
+	mcpManager.SetStartRetry(mcp.StartRetryPolicy{Initial: cfg.StartupRetryInterval, Max: cfg.StartupRetryMaxInterval})
+
+	healthChecker := health.NewHealthChecker(mcpManager)
+	if cfg.AlertSlackWebhookURL != "" {
+		logger.RedactValues(cfg.AlertSlackWebhookURL)
+		healthChecker.AddNotifier(health.NewSlackNotifier(cfg.AlertSlackWebhookURL))
+	}
+	if cfg.AlertPagerDutyRoutingKey != "" {
+		logger.RedactValues(cfg.AlertPagerDutyRoutingKey)
+		healthChecker.AddNotifier(health.NewPagerDutyNotifier(cfg.AlertPagerDutyRoutingKey))
+	}
+	resourceMonitor := monitoring.NewResourceMonitor()
+	proxyServer := proxy.NewServerWithConfig(mcpManager, cfg, healthChecker, resourceMonitor)
+
+	// Tool calls are counted per principal for quotas and /usage
+	usage, err := state.NewEncryptedUsage(cfg.StateDir, stateCipher, cfg.UsageRetention)
+	if err != nil {
+		logger.System().Warn("Usage counters kept in memory only: %v", err)
+		usage, _ = state.NewUsage("", cfg.UsageRetention)
+	}
+	proxyServer.EnableUsageAccounting(usage)
+
+	return &Proxy{
+		config:          cfg,
+		manager:         mcpManager,
+		server:          proxyServer,
+		healthChecker:   healthChecker,
+		resourceMonitor: resourceMonitor,
+		webhooks:        webhooks,
+		usage:           usage,
+		httpServer: &http.Server{
+			Addr:    ":" + cfg.GetPort(),
+			Handler: proxyServer.Router(),
+		},
+	}, nil
+}
+
+// Start launches the MCP servers and the health and resource monitors
+// Servers failing to start are reported in Servers and by the health checker
+// while the others are served, and retried in the background. With
+// StartupFailFast set, Start returns the error instead.
+func (p *Proxy) Start() error {
+	if err := p.manager.StartAll(); err != nil {
+		if p.config.StartupFailFast {
+			return fmt.Errorf("failed to start MCP servers: %w", err)
+		}
+		logger.System().Error("Serving the MCP servers that started: %v", err)
+	}
+
+	p.healthChecker.Start()
+	p.resourceMonitor.Start()
+	logger.System().Info("Health checker and resource monitor started")
+	return nil
+}
+
+// Handler returns the HTTP routes of the proxy
+func (p *Proxy) Handler() http.Handler {
+	return p.httpServer.Handler
+}
+
+// ListenAndServe serves the proxy on the configured port until Shutdown is called
+func (p *Proxy) ListenAndServe() error {
+	if err := p.Listen(); err != nil {
+		return err
+	}
+	return p.Serve()
+}
+
+// Listen binds the configured port without serving yet
+// Once it returns, connections are queued until Serve is called.
+func (p *Proxy) Listen() error {
+	listener, err := net.Listen("tcp", p.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", p.httpServer.Addr, err)
+	}
+	p.listener = listener
+	return nil
+}
+
+// Serve serves the proxy on the port bound by Listen until Shutdown is called
+func (p *Proxy) Serve() error {
+	if p.listener == nil {
+		return fmt.Errorf("proxy is not listening: call Listen first")
+	}
+	logger.System().Info("Server starting on %s (Domain: %s)", p.httpServer.Addr, p.config.GetDomain())
+	if err := p.httpServer.Serve(p.listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops serving HTTP, then stops monitoring and all MCP servers and saves the usage counters
+// It must be called at most once.
+func (p *Proxy) Shutdown(ctx context.Context) error {
+	err := p.httpServer.Shutdown(ctx)
+
+	p.healthChecker.Stop()
+	p.resourceMonitor.Stop()
+	logger.System().Info("Monitoring services stopped")
+
+	p.manager.StopAll()
+	p.webhooks.Close(webhookDrainTimeout)
+	if saveErr := p.usage.Save(); saveErr != nil {
+		logger.System().Warn("Failed to save usage counters: %v", saveErr)
+	}
+	return err
+}
+
+// Manager returns the MCP server manager
+func (p *Proxy) Manager() Manager {
+	return &manager{p.manager}
+}
+
+// Translator returns the protocol translator holding session state
+func (p *Proxy) Translator() Translator {
+	return p.server.Translator()
+}
+
+// manager adapts *mcp.Manager to the Manager interface
+type manager struct {
+	*mcp.Manager
+}
+
+func (m *manager) Server(name string) (Server, bool) {
+	server, exists := m.GetServer(name)
+	if !exists {
+		return nil, false
+	}
+	return server, true
+}
+
+func (m *manager) SessionServer(sessionID, serverName string) (Server, bool) {
+	server, exists := m.GetServerForSession(sessionID, serverName)
+	if !exists {
+		return nil, false
+	}
+	return server, true
+}
+
+func (m *manager) Servers() []ServerStatus {
+	return m.GetAllServers()
+}
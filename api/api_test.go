@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProxyLifecycle(t *testing.T) {
+	proxy, err := New(&Config{
+		Port:       "0",
+		StateDir:   t.TempDir(),
+		MCPServers: map[string]MCPServerConfig{"memory": {Command: "cat"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create proxy: %v", err)
+	}
+
+	if err := proxy.Serve(); err == nil {
+		t.Error("Expected Serve to fail before Listen")
+	}
+	if err := proxy.Listen(); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- proxy.Serve() }()
+
+	if err := proxy.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	server, exists := proxy.Manager().Server("memory")
+	if !exists || !server.IsRunning() {
+		t.Fatalf("Expected the configured server to be running, got %+v", proxy.Manager().Servers())
+	}
+
+	response, err := http.Get(fmt.Sprintf("http://%s/live", proxy.listener.Addr()))
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the proxy to answer /live, got %v (%v)", response, err)
+	}
+	response.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := proxy.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Expected Serve to return cleanly after Shutdown, got %v", err)
+	}
+	if server.IsRunning() {
+		t.Error("Expected Shutdown to stop the MCP servers")
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for name, tt := range map[string]struct {
+		cfg  *Config
+		want string
+	}{
+		"missing":               {nil, "configuration is required"},
+		"malformed key":         {&Config{StateEncryptionKey: "not-a-key"}, "invalid state encryption key"},
+		"previous keys only":    {&Config{StateEncryptionPreviousKeys: []string{"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="}}, "without a current key"},
+		"key and key file both": {&Config{StateEncryptionKey: "a", StateEncryptionKeyFile: "b"}, "STATE_ENCRYPTION_KEY"},
+	} {
+		proxy, err := New(tt.cfg)
+		if err == nil || proxy != nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error mentioning %q, got %v", name, tt.want, err)
+		}
+	}
+}
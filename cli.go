@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/protocol"
+	"remote-mcp-proxy/proxy"
+)
+
+// command is a CLI subcommand
+// run returns the process exit code; exitUsage prints the usage.
+type command struct {
+	name string
+	args string
+	help string
+	run  func(configPath string, args []string) int
+}
+
+// exitUsage is returned by commands given the wrong arguments
+const exitUsage = 2
+
+// commands are the subcommands, in the order usage lists them; serve is the default
+var commands = []command{
+	{"serve", "", "Start the proxy (the default)", func(configPath string, args []string) int { return serve(configPath) }},
+	{"validate-config", "", "Load and validate the configuration, then exit", validateConfig},
+	{"list-servers", "", "List the configured MCP servers", listServers},
+	{"test-server", "<name>", "Start one server, run initialize and tools/list, print the tools", testServer},
+	{"import-claude-config", "<file>", "Import the mcpServers of a Claude Desktop claude_desktop_config.json", importClaudeConfig},
+}
+
+// testServerTimeout bounds each request test-server makes
+const testServerTimeout = 60 * time.Second
+
+// usage prints the subcommands
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] [args]\n\nCommands:\n", os.Args[0])
+	writer := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(writer, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.help)
+	}
+	writer.Flush()
+	fmt.Fprintln(os.Stderr, "\nThe configuration is read from CONFIG_FILE (default /app/config.json).")
+}
+
+// quietLogs keeps server and proxy logs to warnings so command output stays readable
+// Explicit LOG_LEVEL_SYSTEM and LOG_LEVEL_MCP settings win.
+func quietLogs() {
+	for _, name := range []string{"LOG_LEVEL_SYSTEM", "LOG_LEVEL_MCP"} {
+		if os.Getenv(name) == "" {
+			os.Setenv(name, "WARN")
+		}
+	}
+}
+
+// validateConfig loads the configuration the way serve does and reports the result
+func validateConfig(configPath string, args []string) int {
+	if len(args) != 0 {
+		return exitUsage
+	}
+	quietLogs()
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration %s: %v\n", configPath, err)
+		return 1
+	}
+	fmt.Printf("Configuration %s is valid: %d servers\n", configPath, len(cfg.MCPServers))
+	return 0
+}
+
+// listServers prints every configured server with what it runs
+func listServers(configPath string, args []string) int {
+	if len(args) != 0 {
+		return exitUsage
+	}
+	quietLogs()
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	names := make([]string, 0, len(cfg.MCPServers))
+	for name := range cfg.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tTYPE\tSESSION MODE\tTARGET")
+	for _, name := range names {
+		server := cfg.MCPServers[name]
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", name, server.ServerType(), server.SessionMode(), serverTarget(server))
+	}
+	writer.Flush()
+	return 0
+}
+
+// serverTarget describes what a server runs or connects to
+func serverTarget(server config.MCPServer) string {
+	switch server.ServerType() {
+	case config.TypeDocker:
+		return strings.TrimSpace(strings.Join(append([]string{server.Image, server.Command}, server.Args...), " "))
+	case config.TypeSSE, config.TypeStreamableHTTP:
+		return server.URL
+	}
+	return strings.Join(append([]string{server.Command}, server.Args...), " ")
+}
+
+// testServer starts a single server, performs the handshake and lists its tools
+func testServer(configPath string, args []string) int {
+	if len(args) != 1 {
+		return exitUsage
+	}
+	quietLogs()
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	name := config.NormalizeServerName(args[0])
+	server, exists := cfg.MCPServers[name]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "Server %q is not configured\n", args[0])
+		return 1
+	}
+	cfg.MCPServers = map[string]config.MCPServer{name: server}
+
+	fmt.Printf("Starting %s: %s\n", name, serverTarget(server))
+	embedded, err := proxy.NewInProcess(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start %s: %v\n", name, err)
+		return 1
+	}
+	defer embedded.Close()
+
+	client := embedded.NewClient(name)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testServerTimeout)
+	defer cancel()
+	response, err := client.Initialize(ctx)
+	if err := responseError(response, err); err != nil {
+		fmt.Fprintf(os.Stderr, "initialize failed: %v\n", err)
+		return 1
+	}
+	var initialize protocol.InitializeResult
+	decodeResult(response, &initialize)
+	fmt.Printf("initialize: OK (%s %s, protocol %s)\n", initialize.ServerInfo.Name, initialize.ServerInfo.Version, initialize.ProtocolVersion)
+
+	ctx, cancel = context.WithTimeout(context.Background(), testServerTimeout)
+	defer cancel()
+	response, err = client.ListTools(ctx)
+	if err := responseError(response, err); err != nil {
+		fmt.Fprintf(os.Stderr, "tools/list failed: %v\n", err)
+		return 1
+	}
+	var tools struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"tools"`
+	}
+	decodeResult(response, &tools)
+	fmt.Printf("tools/list: OK (%d tools)\n", len(tools.Tools))
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, tool := range tools.Tools {
+		description, _, _ := strings.Cut(tool.Description, "\n")
+		fmt.Fprintf(writer, "  %s\t%s\n", tool.Name, description)
+	}
+	writer.Flush()
+	return 0
+}
+
+// responseError turns a failed call or a JSON-RPC error response into an error
+func responseError(response *protocol.JSONRPCMessage, err error) error {
+	if err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return fmt.Errorf("%d %s", response.Error.Code, response.Error.Message)
+	}
+	return nil
+}
+
+// decodeResult decodes the result of a response into v, leaving v alone when it doesn't fit
+func decodeResult(response *protocol.JSONRPCMessage, v interface{}) {
+	if data, err := json.Marshal(response.Result); err == nil {
+		json.Unmarshal(data, v)
+	}
+}
+
+// importClaudeConfig imports a Claude Desktop config into the proxy's configuration
+func importClaudeConfig(configPath string, args []string) int {
+	if len(args) != 1 {
+		return exitUsage
+	}
+	return runImport(args[0], configPath)
+}
+
+// runCommand dispatches the command line to a subcommand and returns the exit code
+// Without a command the proxy serves, so existing deployments keep working.
+func runCommand(configPath string, args []string) int {
+	if len(args) == 0 {
+		return serve(configPath)
+	}
+	if strings.HasPrefix(args[0], "-") {
+		// The flag form predates the subcommands
+		flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+		flags.Usage = usage
+		importFrom := flags.String("import-claude-config", "", "Import the mcpServers of a Claude Desktop config, then exit")
+		if err := flags.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return 0
+			}
+			return exitUsage
+		}
+		if *importFrom != "" {
+			return runImport(*importFrom, configPath)
+		}
+		return runCommand(configPath, flags.Args())
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			code := cmd.run(configPath, args[1:])
+			if code == exitUsage {
+				usage()
+			}
+			return code
+		}
+	}
+	if args[0] == "help" {
+		usage()
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+	usage()
+	return exitUsage
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"mcpServers":{"memory":{"command":"echo"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	invalidPath := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalidPath, []byte(`{"mcpServers":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name       string
+		configPath string
+		args       []string
+		want       int
+	}{
+		{"unknown command", configPath, []string{"frobnicate"}, exitUsage},
+		{"unknown flag", configPath, []string{"--frobnicate"}, exitUsage},
+		{"help", configPath, []string{"help"}, 0},
+		{"help flag", configPath, []string{"-h"}, 0},
+		{"test-server without a name", configPath, []string{"test-server"}, exitUsage},
+		{"test-server with extra arguments", configPath, []string{"test-server", "memory", "extra"}, exitUsage},
+		{"test-server of an unknown server", configPath, []string{"test-server", "missing"}, 1},
+		{"import without a file", configPath, []string{"import-claude-config"}, exitUsage},
+		{"import of a missing file", filepath.Join(dir, "imported.json"), []string{"import-claude-config", filepath.Join(dir, "missing.json")}, 1},
+		{"import flag of a missing file", filepath.Join(dir, "imported.json"), []string{"-import-claude-config", filepath.Join(dir, "missing.json")}, 1},
+		{"validate-config with arguments", configPath, []string{"validate-config", "extra"}, exitUsage},
+		{"validate-config", configPath, []string{"validate-config"}, 0},
+		{"validate-config of an invalid file", invalidPath, []string{"validate-config"}, 1},
+		{"list-servers", configPath, []string{"list-servers"}, 0},
+		{"list-servers of an invalid file", invalidPath, []string{"list-servers"}, 1},
+	} {
+		if got := runCommand(tt.configPath, tt.args); got != tt.want {
+			t.Errorf("%s: expected exit code %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// claudeServer is a server entry of Claude Desktop's claude_desktop_config.json
+// Entries for remote servers carry a type and url instead of a command.
+type claudeServer struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`
+	Type    string            `json:"type"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+// ImportResult lists what an import did
+type ImportResult struct {
+	Imported []string // Servers added to the proxy's configuration
+	Skipped  []string // Servers the configuration already had
+	Warnings []string // Things to check before starting the proxy
+}
+
+// secretNamePattern matches env names that usually hold credentials
+var secretNamePattern = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD|CREDENTIAL)`)
+
+// localPathPattern matches commands and args pointing into a desktop user's home
+var localPathPattern = regexp.MustCompile(`^(/Users/|/home/|[A-Za-z]:\\|~)`)
+
+// ImportClaudeDesktop adds the servers of a Claude Desktop config to the proxy's configuration
+//
+// The target is a config file, created when missing, or a config directory that
+// gets one file per imported server. Servers the target already defines are left
+// alone, as are its other settings. Env values are copied as they are: "${" is
+// escaped so they aren't read as references, and credentials are reported so
+// they can be moved to ${secret:...} references.
+func ImportClaudeDesktop(source, target string) (*ImportResult, error) {
+	if strings.ContainsAny(target, "*?[") {
+		return nil, fmt.Errorf("cannot import into the pattern %s; give a config file or directory", target)
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Claude Desktop config: %w", err)
+	}
+	var desktop struct {
+		MCPServers map[string]claudeServer `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &desktop); err != nil {
+		return nil, fmt.Errorf("failed to parse Claude Desktop config: %w", err)
+	}
+	if len(desktop.MCPServers) == 0 {
+		return nil, fmt.Errorf("no mcpServers in %s", source)
+	}
+
+	// Servers already configured, by normalized name; references are left unresolved
+	existing := make(map[string]bool)
+	if paths, err := configPaths(target); err == nil && len(paths) > 0 {
+		var current Config
+		loader := &fileLoader{seen: make(map[string]bool), sources: make(map[string]string)}
+		if err := loader.loadFiles(&current, target); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", target, err)
+		}
+		for name := range current.MCPServers {
+			existing[NormalizeServerName(name)] = true
+		}
+	}
+
+	result := &ImportResult{}
+	servers := make(map[string]MCPServer)
+	names := make([]string, 0, len(desktop.MCPServers))
+	for name := range desktop.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if existing[NormalizeServerName(name)] {
+			result.Skipped = append(result.Skipped, name)
+			continue
+		}
+		server, warnings := convertClaudeServer(name, desktop.MCPServers[name])
+		servers[name] = server
+		result.Imported = append(result.Imported, name)
+		result.Warnings = append(result.Warnings, warnings...)
+	}
+	if len(servers) == 0 {
+		return result, nil
+	}
+
+	// Check the servers as the proxy will load them, before writing anything
+	check := &Config{MCPServers: make(map[string]MCPServer, len(servers))}
+	for name, server := range servers {
+		check.MCPServers[name] = server
+	}
+	if err := check.validate(); err != nil {
+		return nil, fmt.Errorf("imported servers are invalid: %w", err)
+	}
+
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		return result, writeServerFiles(target, servers)
+	}
+	return result, mergeServersIntoFile(target, servers)
+}
+
+// convertClaudeServer turns a Claude Desktop entry into a proxy server configuration
+func convertClaudeServer(name string, entry claudeServer) (MCPServer, []string) {
+	var warnings []string
+	server := MCPServer{Command: entry.Command, Args: entry.Args}
+
+	switch entry.Type {
+	case "", TypeStdio:
+	case TypeSSE:
+		server = MCPServer{Type: TypeSSE, URL: entry.URL}
+	case "http", TypeStreamableHTTP:
+		server = MCPServer{Type: TypeStreamableHTTP, URL: entry.URL}
+	default:
+		warnings = append(warnings, fmt.Sprintf("%s: unknown type %q, imported as a stdio server", name, entry.Type))
+	}
+
+	if len(entry.Env) > 0 {
+		server.Env = make(map[string]string, len(entry.Env))
+		for key, value := range entry.Env {
+			server.Env[key] = escapeReferences(value)
+			if secretNamePattern.MatchString(key) && value != "" {
+				warnings = append(warnings, fmt.Sprintf("%s: env %s looks like a credential; consider a ${secret:...} or ${VAR} reference", name, key))
+			}
+		}
+	}
+	if len(entry.Headers) > 0 && server.IsRemote() {
+		server.Headers = make(map[string]string, len(entry.Headers))
+		for key, value := range entry.Headers {
+			server.Headers[key] = escapeReferences(value)
+		}
+	}
+
+	for _, value := range append([]string{entry.Command}, entry.Args...) {
+		if localPathPattern.MatchString(value) {
+			warnings = append(warnings, fmt.Sprintf("%s: %q is a path on the desktop machine and may not exist where the proxy runs", name, value))
+		}
+	}
+	sort.Strings(warnings)
+	return server, warnings
+}
+
+// escapeReferences keeps a literal value from being expanded as a reference
+func escapeReferences(value string) string {
+	return strings.ReplaceAll(value, "${", "$${")
+}
+
+// mergeServersIntoFile adds servers to the mcpServers of a JSON config file, keeping its other keys
+func mergeServersIntoFile(target string, servers map[string]MCPServer) error {
+	if ext := strings.ToLower(filepath.Ext(target)); ext == ".yaml" || ext == ".yml" {
+		return fmt.Errorf("cannot merge into YAML file %s; import into a config directory instead", target)
+	}
+
+	document := make(map[string]json.RawMessage)
+	mode := os.FileMode(0644)
+	if data, err := os.ReadFile(target); err == nil {
+		if err := json.Unmarshal(data, &document); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", target, err)
+		}
+		if info, err := os.Stat(target); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	current := make(map[string]json.RawMessage)
+	if raw, exists := document["mcpServers"]; exists {
+		if err := json.Unmarshal(raw, &current); err != nil {
+			return fmt.Errorf("failed to parse mcpServers of %s: %w", target, err)
+		}
+	}
+	for name, server := range servers {
+		raw, err := json.Marshal(server)
+		if err != nil {
+			return err
+		}
+		current[name] = raw
+	}
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	document["mcpServers"] = raw
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(target, append(data, '\n'), mode)
+}
+
+// writeServerFiles writes each server to a file of its own in a config directory
+func writeServerFiles(dir string, servers map[string]MCPServer) error {
+	for name, server := range servers {
+		path := filepath.Join(dir, NormalizeServerName(name)+".json")
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+		data, err := json.MarshalIndent(server, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const claudeDesktopConfig = `{
+  "mcpServers": {
+    "memory": {"command": "npx", "args": ["-y", "@modelcontextprotocol/server-memory"]},
+    "github": {"command": "npx", "args": ["-y", "@modelcontextprotocol/server-github"], "env": {"GITHUB_PERSONAL_ACCESS_TOKEN": "ghp_123", "PROMPT": "${not-a-reference}"}},
+    "files": {"command": "/Users/me/bin/files-mcp"},
+    "search": {"type": "http", "url": "https://search.internal/mcp", "headers": {"Authorization": "Bearer abc"}}
+  },
+  "globalShortcut": "Ctrl+Space"
+}`
+
+func TestImportClaudeDesktopIntoFile(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"claude_desktop_config.json": claudeDesktopConfig,
+		"config.json":                `{"mcpServers": {"memory": {"command": "node", "args": ["memory.js"]}}, "timeouts": {"tools/call": "60s"}}`,
+	})
+	target := filepath.Join(dir, "config.json")
+
+	result, err := ImportClaudeDesktop(filepath.Join(dir, "claude_desktop_config.json"), target)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if strings.Join(result.Imported, ",") != "files,github,search" {
+		t.Errorf("Unexpected imported servers: %v", result.Imported)
+	}
+	if strings.Join(result.Skipped, ",") != "memory" {
+		t.Errorf("Expected memory to be skipped, got %v", result.Skipped)
+	}
+	warnings := strings.Join(result.Warnings, "\n")
+	if !strings.Contains(warnings, "GITHUB_PERSONAL_ACCESS_TOKEN") || !strings.Contains(warnings, "/Users/me/bin/files-mcp") {
+		t.Errorf("Expected credential and local path warnings, got %v", result.Warnings)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Merged config is not valid JSON: %v", err)
+	}
+	if _, exists := document["timeouts"]; !exists {
+		t.Error("Expected the existing timeouts to be kept")
+	}
+
+	cfg, err := Load(target)
+	if err != nil {
+		t.Fatalf("Failed to load the merged config: %v", err)
+	}
+	if cfg.MCPServers["memory"].Command != "node" {
+		t.Errorf("Expected the existing memory server to be kept, got %+v", cfg.MCPServers["memory"])
+	}
+	if value := cfg.MCPServers["github"].Env["PROMPT"]; value != "${not-a-reference}" {
+		t.Errorf("Expected the literal ${ to survive loading, got %q", value)
+	}
+	search := cfg.MCPServers["search"]
+	if search.Type != TypeStreamableHTTP || search.URL != "https://search.internal/mcp" || search.Headers["Authorization"] != "Bearer abc" {
+		t.Errorf("Expected search to become a streamable-http server, got %+v", search)
+	}
+}
+
+func TestImportClaudeDesktopIntoDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"claude_desktop_config.json": claudeDesktopConfig,
+		"config.d/github.yaml":       "command: npx\n",
+	})
+	target := filepath.Join(dir, "config.d")
+
+	result, err := ImportClaudeDesktop(filepath.Join(dir, "claude_desktop_config.json"), target)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if strings.Join(result.Skipped, ",") != "github" {
+		t.Errorf("Expected github to be skipped, got %v", result.Skipped)
+	}
+	for _, name := range []string{"memory.json", "files.json", "search.json"} {
+		if _, err := os.Stat(filepath.Join(target, name)); err != nil {
+			t.Errorf("Expected %s to be written: %v", name, err)
+		}
+	}
+
+	cfg, err := Load(target)
+	if err != nil {
+		t.Fatalf("Failed to load the config directory: %v", err)
+	}
+	if len(cfg.MCPServers) != 4 {
+		t.Errorf("Expected 4 servers, got %d", len(cfg.MCPServers))
+	}
+}
+
+func TestImportClaudeDesktopErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"empty.json":                 `{"globalShortcut": "Ctrl+Space"}`,
+		"claude_desktop_config.json": claudeDesktopConfig,
+		"config.yaml":                "mcpServers: {}\n",
+	})
+
+	tests := []struct {
+		name   string
+		source string
+		target string
+	}{
+		{"missing source", "missing.json", "config.json"},
+		{"no servers", "empty.json", "config.json"},
+		{"yaml target", "claude_desktop_config.json", "config.yaml"},
+		{"glob target", "claude_desktop_config.json", "*.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ImportClaudeDesktop(filepath.Join(dir, tt.source), filepath.Join(dir, tt.target)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -1,60 +1,1433 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // MCPServer represents a single MCP server configuration
 type MCPServer struct {
-	Command string            `json:"command"`
-	Args    []string          `json:"args"`
-	Env     map[string]string `json:"env"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+
+	// Proxy environment passed on to the server's processes; all of it by default
+	InheritEnv   *bool    `json:"inheritEnv,omitempty"`   // false passes on only PATH, HOME and envAllowlist
+	EnvAllowlist []string `json:"envAllowlist,omitempty"` // Proxy variables passed on, instead of the whole environment
+
+	Type string `json:"type,omitempty"` // How the server runs: "stdio" (default, a local command), "tcp", "docker", "sse" or "streamable-http"
+
+	Port int `json:"port,omitempty"` // Port a "tcp" server listens on (a free one per instance, substituted for {PORT}, when 0)
+
+	Framing string `json:"framing,omitempty"` // How messages to a local server are delimited: "auto" (default), "newline" or "content-length"
+
+	// Container of "docker" servers; command and args, when set, override the image's
+	Image   string   `json:"image,omitempty"`   // Image to run, e.g. "mcp/fetch:latest"
+	Volumes []string `json:"volumes,omitempty"` // Mounts in docker -v form, e.g. "/srv/data:/data:ro"
+	Network string   `json:"network,omitempty"` // Docker network to attach the container to ("none" for no network)
+
+	// Endpoint of "sse" and "streamable-http" servers already running elsewhere
+	URL     string            `json:"url,omitempty"`     // SSE stream or MCP endpoint, e.g. "https://mcp.internal/mcp"
+	Headers map[string]string `json:"headers,omitempty"` // Sent with every request, e.g. {"Authorization": "Bearer ..."}
+
+	Slug        string `json:"slug,omitempty"` // Name used in subdomains, paths and file names (normalized config key when empty)
+	DisplayName string `json:"-"`              // Original config key when it differs from the server's name
+
+	Hostnames []string `json:"hostnames,omitempty"` // Hosts routed to the server besides {server}.mcp.{domain}, e.g. "notion.example.org"
+	BasePath  string   `json:"basePath,omitempty"`  // Path prefix routed to the server on any host, e.g. "/integrations/notion/v1"
+
+	Heartbeat *Heartbeat `json:"heartbeat,omitempty"` // Overrides the global SSE heartbeat settings
+
+	EndpointFormat string `json:"endpointFormat,omitempty"` // Overrides the global SSE endpoint event format
+
+	Timeouts map[string]string `json:"timeouts,omitempty"` // Per-method request timeouts, e.g. {"tools/call": "300s"}
+
+	MaxRequestBytes  int64 `json:"maxRequestBytes,omitempty"`  // Overrides MAX_REQUEST_BYTES for client messages to this server
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"` // Overrides MAX_RESPONSE_BYTES for messages from this server
+
+	RewriteResourceURIs bool `json:"rewriteResourceURIs,omitempty"` // Serve resources through proxy URLs instead of backend URIs
+
+	AllowedTools []string `json:"allowedTools,omitempty"` // Tool name patterns exposed to remote clients (all when empty)
+	BlockedTools []string `json:"blockedTools,omitempty"` // Tool name patterns hidden from remote clients
+
+	WarmPool int `json:"warmPool,omitempty"` // Pre-initialized instances kept ready for new sessions
+
+	Mode         string `json:"mode,omitempty"`         // How sessions map to processes: "per-session" (default), "shared" or "pool"
+	MaxInstances int    `json:"maxInstances,omitempty"` // Process cap in "pool" mode (DefaultMaxInstances when 0)
+
+	Fallback *MCPServer `json:"fallback,omitempty"` // Warm standby serving new requests while this server is unhealthy
+
+	Sandbox *Sandbox `json:"sandbox,omitempty"` // Restricts what the server's processes can reach
+
+	Workspace string `json:"workspace,omitempty"` // Working directory scope: "session" (default) or "user", kept across the client's sessions
+
+	Cwd string `json:"cwd,omitempty"` // Working directory of the server's processes, e.g. "/data/projects" or "{WORKSPACE}/repo" (template variables allowed)
+
+	HeaderVariables map[string]string `json:"headerVariables,omitempty"` // Template variables set from request headers, e.g. {"TENANT": "X-Tenant-Id"} for {TENANT}
+
+	OAuth *OAuth `json:"oauth,omitempty"` // Overrides the global OAuth settings on the server's subdomain
+
+	APIKeys []APIKey `json:"apiKeys,omitempty"` // Static bearer keys accepted for this server only
+
+	SelfTest *SelfTest `json:"selfTest,omitempty"` // Harmless tool call made by /selftest/{server}
+
+	Preinstall []string `json:"preinstall,omitempty"` // Command run before the server first starts, e.g. to download its package
+
+	StopGracePeriod string `json:"stopGracePeriod,omitempty"` // Time between SIGTERM and SIGKILL when stopping, e.g. "30s" (overrides STOP_GRACE_PERIOD)
+
+	OperationTimeout string `json:"operationTimeout,omitempty"` // How long a request may run before cleanup stops waiting for it, e.g. "30m" (overrides OPERATION_TIMEOUT)
+
+	Recycle *Recycle `json:"recycle,omitempty"` // Restarts the server's process when it gets old or busy, e.g. to contain memory leaks
+
+	DependsOn    []string      `json:"dependsOn,omitempty"`    // Servers started, and ready, before this one at startup
+	StartupProbe *StartupProbe `json:"startupProbe,omitempty"` // Waits at startup for the server to answer before starting its dependents
+
+	Hooks []Hook `json:"hooks,omitempty"` // Built-in transformations of the messages exchanged with the server, applied in order
+}
+
+// Hook is a transformation of the requests sent to a server and the responses it returns
+// "header" sets HTTP headers on the responses to clients, "rewrite" changes
+// messages at dotted JSON paths, and "block" refuses requests with an error.
+// "command" and "http" hand each message to an external program or endpoint,
+// which lets it through, replaces it or rejects it. "scan" looks for sensitive
+// content in tool results.
+type Hook struct {
+	Type    string   `json:"type"`              // HookHeader, HookRewrite, HookBlock, HookCommand, HookHTTP or HookScan
+	Methods []string `json:"methods,omitempty"` // Method patterns the hook applies to, e.g. "tools/*" (all when empty)
+	Tools   []string `json:"tools,omitempty"`   // Tool name patterns; when set, the hook only applies to calls of these tools
+
+	Headers map[string]string `json:"headers,omitempty"` // "header": headers set, e.g. {"Cache-Control": "no-store"}; "http": headers sent to the endpoint
+
+	Phase  string                 `json:"phase,omitempty"`  // "rewrite": HookPhaseRequest or HookPhaseResponse (default); "command" and "http": both when empty
+	Set    map[string]interface{} `json:"set,omitempty"`    // "rewrite": values set at dotted paths, "*" matching every element, e.g. {"result.tools.*.annotations.readOnlyHint": true}
+	Remove []string               `json:"remove,omitempty"` // "rewrite": dotted paths removed, e.g. "result.tools.*.outputSchema"
+
+	Message string `json:"message,omitempty"` // "block" and "scan": error message returned to the client (a generic one when empty)
+
+	// Content scanning
+	Detectors []string          `json:"detectors,omitempty"` // "scan": built-in detectors, see ScanDetectors, e.g. ["credit-card", "secrets"]
+	Patterns  map[string]string `json:"patterns,omitempty"`  // "scan": custom regular expressions by name, e.g. {"employee-id": "\\bEMP-[0-9]{6}\\b"}
+	Action    string            `json:"action,omitempty"`    // "scan": ScanRedact (default) or ScanBlock
+
+	// External hooks
+	Command  string   `json:"command,omitempty"`  // "command": program run for each message, reading it on stdin and writing its decision on stdout
+	Args     []string `json:"args,omitempty"`     // "command": arguments of the program
+	URL      string   `json:"url,omitempty"`      // "http": endpoint each message is POSTed to, answering with the decision
+	Timeout  string   `json:"timeout,omitempty"`  // "command" and "http": how long a decision may take, e.g. "30s" (DefaultHookTimeout when empty)
+	FailOpen bool     `json:"failOpen,omitempty"` // "command" and "http": pass messages on unchanged when the hook fails, instead of refusing them
+}
+
+// Hook types
+const (
+	HookHeader  = "header"  // Sets HTTP headers on the responses to clients
+	HookRewrite = "rewrite" // Sets or removes values in requests or responses
+	HookBlock   = "block"   // Answers requests with an error instead of sending them
+	HookCommand = "command" // Asks an external program about each message
+	HookHTTP    = "http"    // Asks an external HTTP endpoint about each message
+	HookScan    = "scan"    // Redacts or withholds tool results with sensitive content
+)
+
+// Scan hook actions
+const (
+	ScanRedact = "redact" // Replaces each match with [REDACTED:<detector>]
+	ScanBlock  = "block"  // Answers with an error instead of the result
+)
+
+// ScanSecrets names every secret detector at once
+const ScanSecrets = "secrets"
+
+// ScanDetectors lists the built-in detectors of scan hooks
+var ScanDetectors = []string{
+	"credit-card",    // Card numbers passing the Luhn check
+	"aws-access-key", // AWS access key IDs
+	"private-key",    // PEM private keys
+	"github-token",   // GitHub personal access and app tokens
+	"slack-token",    // Slack bot, user and app tokens
+	"jwt",            // JSON Web Tokens
+}
+
+// ScanSecretDetectors are the detectors ScanSecrets stands for
+var ScanSecretDetectors = []string{"aws-access-key", "private-key", "github-token", "slack-token", "jwt"}
+
+// DefaultHookTimeout bounds an external hook's decision on one message
+const DefaultHookTimeout = 10 * time.Second
+
+// TimeoutDuration returns the parsed timeout of an external hook (DefaultHookTimeout when unset or invalid)
+func (h Hook) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultHookTimeout
+}
+
+// Phases of a rewrite hook
+const (
+	HookPhaseRequest  = "request"  // Rewrites requests before they are sent to the server
+	HookPhaseResponse = "response" // Rewrites responses before they are returned to the client
+)
+
+// StartupProbe tells when a server started at startup is ready to serve
+// The server is pinged until it answers, like the health checker does; an
+// error response counts as an answer.
+type StartupProbe struct {
+	Timeout  string `json:"timeout,omitempty"`  // How long the server may take to answer, e.g. "2m" (DefaultStartupProbeTimeout when empty)
+	Interval string `json:"interval,omitempty"` // Wait between pings the server failed, e.g. "5s" (DefaultStartupProbeInterval when empty)
+}
+
+// Startup probe defaults
+const (
+	DefaultStartupProbeTimeout  = time.Minute
+	DefaultStartupProbeInterval = time.Second
+)
+
+// TimeoutDuration returns the parsed timeout (DefaultStartupProbeTimeout when unset or invalid)
+func (p *StartupProbe) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(p.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultStartupProbeTimeout
+}
+
+// IntervalDuration returns the parsed interval (DefaultStartupProbeInterval when unset or invalid)
+func (p *StartupProbe) IntervalDuration() time.Duration {
+	if d, err := time.ParseDuration(p.Interval); err == nil && d > 0 {
+		return d
+	}
+	return DefaultStartupProbeInterval
+}
+
+// Recycle restarts a long-running server process before leaks pile up
+// The restart waits for a moment with no request in flight. Either limit may
+// be left out.
+type Recycle struct {
+	MaxAge      string `json:"maxAge,omitempty"`      // Process age after which it is restarted, e.g. "24h"
+	MaxRequests int64  `json:"maxRequests,omitempty"` // Requests handled after which it is restarted, pings excluded
+}
+
+// MaxAgeDuration returns the parsed maxAge (0 when unset or invalid)
+func (r *Recycle) MaxAgeDuration() time.Duration {
+	if r == nil {
+		return 0
+	}
+	d, err := time.ParseDuration(r.MaxAge)
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// SelfTest is the tool call /selftest makes after initialize and tools/list
+// The tool should have no side effects, e.g. a read or echo tool.
+type SelfTest struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// APIKey is a static bearer token accepted in place of an OAuth access token
+type APIKey struct {
+	Name      string     `json:"name"`                // Identifies the key in logs and the admin API
+	Key       string     `json:"key"`                 // The bearer token clients send
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // RFC 3339 time after which the key is refused
+	RateLimit int        `json:"rateLimit,omitempty"` // Requests per minute (unlimited when 0)
+}
+
+// OAuth customizes the authorization server a subdomain presents
+type OAuth struct {
+	RedirectURIs []string `json:"redirectUris,omitempty"` // Redirect URIs clients may use, as path.Match patterns, e.g. "http://localhost:*/callback"
+	Scopes       []string `json:"scopes,omitempty"`       // Scopes advertised and granted
+}
+
+// Sandbox isolates an MCP server's processes from the proxy and from other servers
+//
+// A sandboxed session instance runs in a working directory of its own inside the
+// session directory, readable only by the sandbox user, and inherits only the
+// allowlisted proxy environment variables.
+type Sandbox struct {
+	UID int `json:"uid,omitempty"` // User the process runs as (the proxy's own when 0)
+	GID int `json:"gid,omitempty"` // Group the process runs as (same as uid when 0)
+
+	EnvAllowlist []string `json:"envAllowlist,omitempty"` // Proxy environment variables passed on besides PATH
+
+	ReadOnlyWorkDir bool `json:"readOnlyWorkDir,omitempty"` // Working directory (root filesystem of containers) the process can't write to
+	NoNewPrivileges bool `json:"noNewPrivileges,omitempty"` // Launch through setpriv --no-new-privs, so setuid binaries can't raise privileges
+
+	Wrapper []string `json:"wrapper,omitempty"` // Command prefix the server is launched through, e.g. a seccomp launcher
+}
+
+// Group returns the group a sandboxed process runs as
+func (s *Sandbox) Group() int {
+	if s.GID != 0 {
+		return s.GID
+	}
+	return s.UID
+}
+
+// Server modes
+const (
+	ModePerSession = "per-session" // One process per session
+	ModeShared     = "shared"      // Every session uses the single global process
+	ModePool       = "pool"        // Sessions are spread over at most MaxInstances processes
+)
+
+// Workspace scopes
+const (
+	WorkspaceSession = "session" // Working directory is the session directory, deleted with the session
+	WorkspaceUser    = "user"    // Working directory per client identity and server, kept across sessions
+)
+
+// Message framings of local servers
+const (
+	FramingAuto          = "auto"           // Newlines until the server sends a Content-Length framed message
+	FramingNewline       = "newline"        // One JSON-RPC message per line
+	FramingContentLength = "content-length" // LSP-style Content-Length headers before each message
+)
+
+// Server types
+const (
+	TypeStdio  = "stdio"  // Local command speaking MCP over stdio
+	TypeTCP    = "tcp"    // Local command speaking MCP over a TCP socket on 127.0.0.1
+	TypeDocker = "docker" // Container run with docker run -i, attached over stdio
+
+	TypeSSE            = "sse"             // Remote server using the HTTP+SSE transport (MCP 2024-11-05)
+	TypeStreamableHTTP = "streamable-http" // Remote server using the Streamable HTTP transport
+)
+
+// ServerType returns the server's type, defaulting to stdio
+func (s MCPServer) ServerType() string {
+	if s.Type == "" {
+		return TypeStdio
+	}
+	return s.Type
+}
+
+// RunsCommand reports whether the server is a local command, speaking over stdio or TCP
+func (s MCPServer) RunsCommand() bool {
+	return s.ServerType() == TypeStdio || s.Type == TypeTCP
+}
+
+// InheritsEnv reports whether the server's processes get the proxy's whole environment, secrets included
+// Sandboxed servers and servers with inheritEnv false or an envAllowlist only
+// get the variables of InheritedEnv.
+func (s MCPServer) InheritsEnv() bool {
+	return s.Sandbox == nil && len(s.EnvAllowlist) == 0 && (s.InheritEnv == nil || *s.InheritEnv)
+}
+
+// InheritedEnv returns the proxy variables passed on to a server that doesn't inherit the whole environment
+func (s MCPServer) InheritedEnv() []string {
+	names := []string{"PATH"}
+	if s.Sandbox == nil {
+		names = append(names, "HOME")
+	} else {
+		names = append(names, s.Sandbox.EnvAllowlist...)
+	}
+	return append(names, s.EnvAllowlist...)
+}
+
+// IsRemote reports whether the server runs elsewhere and is reached over HTTP
+func (s MCPServer) IsRemote() bool {
+	return s.Type == TypeSSE || s.Type == TypeStreamableHTTP
+}
+
+// DefaultMaxInstances caps the processes of a "pool" mode server without maxInstances
+const DefaultMaxInstances = 4
+
+// SessionMode returns the server's mode, defaulting to per-session
+func (s MCPServer) SessionMode() string {
+	if s.Mode == "" {
+		return ModePerSession
+	}
+	return s.Mode
+}
+
+// Heartbeat configures the periodic SSE message keeping connections alive
+type Heartbeat struct {
+	Style    string `json:"style,omitempty"`    // "comment", "event" or "ping"
+	Interval string `json:"interval,omitempty"` // Go duration, e.g. "30s"
+}
+
+// Heartbeat styles
+const (
+	HeartbeatComment = "comment" // SSE comment line, ignored by spec-compliant clients
+	HeartbeatEvent   = "event"   // Named "keep-alive" event carrying the rolling reconnect token
+	HeartbeatPing    = "ping"    // MCP ping notification sent as a "message" event
+)
+
+// DefaultHeartbeatInterval is used when neither the environment nor the server overrides it
+const DefaultHeartbeatInterval = 30 * time.Second
+
+// SSE endpoint event formats
+const (
+	EndpointFormatObject = "object" // JSON object {"uri": "..."}, as Claude.ai expects
+	EndpointFormatString = "string" // Bare URI, as the MCP SDKs and MCP Inspector expect
+)
+
+// Config represents the entire configuration file
+type Config struct {
+	MCPServers map[string]MCPServer `json:"mcpServers"`
+	Timeouts   map[string]string    `json:"timeouts,omitempty"` // Per-method request timeouts for every server
+	APIKeys    []APIKey             `json:"apiKeys,omitempty"`  // Static bearer keys accepted for every server
+	Webhooks   []Webhook            `json:"webhooks,omitempty"` // Endpoints notified of server and session events
+	Policy     *Policy              `json:"policy,omitempty"`   // Which tool calls remote clients may make (all when nil)
+	Quotas     []UsageQuota         `json:"quotas,omitempty"`   // Daily tool call and response byte limits of principals
+	Tenants    map[string]Tenant    `json:"tenants,omitempty"`  // Teams sharing the proxy, each seeing only its own servers
+	// Environment-based configuration (loaded from env vars)
+	Domain  string `json:"-"` // Domain for subdomain routing
+	Port    string `json:"-"` // HTTP server port
+	Routing string `json:"-"` // How requests select a server: "subdomain", "path" or "both"
+
+	Domains []string `json:"-"` // Further domains served with {server}.mcp.{domain} hosts (MCP_DOMAINS); any domain is accepted when empty
+
+	TrustedProxies []netip.Prefix `json:"-"` // Peers whose X-Forwarded-Proto/Host headers are honored (TRUSTED_PROXIES); private networks when nil
+
+	MaxConcurrentToolCalls int `json:"-"` // Parallel tools/call allowed per session
+	MaxQueuedToolCalls     int `json:"-"` // tools/call allowed to wait per session before rejecting
+
+	RequireReconnectToken bool `json:"-"` // Reject SSE reconnects to known sessions without a valid reconnect token
+
+	AdminToken string `json:"-"` // Bearer token protecting /admin and /logs endpoints (open when empty)
+
+	StartupFailFast         bool          `json:"-"` // Exit when any MCP server fails to start instead of serving the others
+	StartupRetryInterval    time.Duration `json:"-"` // First delay before starting a failed server again (no retries when 0)
+	StartupRetryMaxInterval time.Duration `json:"-"` // Longest delay between two start retries
+
+	Preinstall        string        `json:"-"` // Which servers are preinstalled before starting: "off" (those with a preinstall command) or "auto"
+	PreinstallTimeout time.Duration `json:"-"` // How long one preinstall command may run
+
+	StopGracePeriod time.Duration `json:"-"` // How long stopped servers get to exit after SIGTERM before SIGKILL
+
+	OperationTimeout time.Duration `json:"-"` // How long a request may run before session cleanup stops waiting for it
+
+	ApprovalTimeout time.Duration `json:"-"` // How long a tool call requiring approval waits for an approver before it is denied
+
+	AlertSlackWebhookURL     string `json:"-"` // Slack incoming webhook alerted about server health (off when empty)
+	AlertPagerDutyRoutingKey string `json:"-"` // PagerDuty Events API v2 integration key alerted about server health (off when empty)
+
+	AuthMode string `json:"-"` // Which bearer tokens MCP endpoints accept: "oauth" or "api-key"
+
+	OAuthConsent         string        `json:"-"` // How /oauth/authorize authenticates the user: "off", "password", "approval-token" or "oidc"
+	OAuthConsentPassword string        `json:"-"` // Password asked for on the consent page in "password" mode
+	OAuthTokenTTL        time.Duration `json:"-"` // Lifetime of issued access tokens
+	OAuthRequirePKCE     bool          `json:"-"` // Refuse authorization requests without a PKCE code_challenge
+	OAuthRedirectURIs    []string      `json:"-"` // Default redirect URI patterns clients may use (any when empty)
+	OAuthScopes          []string      `json:"-"` // Default scopes advertised and granted
+
+	// Upstream OpenID Connect provider of the "oidc" consent mode
+	OIDCIssuer       string   `json:"-"` // Issuer URL, e.g. "https://example.eu.auth0.com/"
+	OIDCClientID     string   `json:"-"` // Client registered with the provider for the proxy
+	OIDCClientSecret string   `json:"-"` // Secret of that client
+	OIDCAudience     string   `json:"-"` // Audience accepted in access tokens besides the client ID
+	OIDCScopes       []string `json:"-"` // Scopes requested from the provider
+	OIDCRedirectURL  string   `json:"-"` // Callback registered with the provider (https://<host>/oauth/callback when empty)
+
+	WireCapture    string `json:"-"` // JSON-RPC capture mode: "off", "memory" or "file"
+	WireCaptureDir string `json:"-"` // Directory for per-session JSONL trace files in "file" mode
+
+	AggregateServer        bool   `json:"-"` // Expose every MCP server under the virtual "all" server
+	ToolNamespacing        string `json:"-"` // Aggregate tool prefixing: "always" or "collisions"
+	ToolNamespaceSeparator string `json:"-"` // Joins server and tool names in aggregate tool lists
+
+	LandingPage bool `json:"-"` // Serve connection instructions and health at /
+
+	ResponseCacheTTL time.Duration `json:"-"` // How long tools/list, resources/list and prompts/list responses are cached (off when 0)
+
+	ColdStartTimeout   time.Duration `json:"-"` // Request timeout until a new server process has answered once
+	SteadyStateTimeout time.Duration `json:"-"` // Request timeout afterwards, except for tools/call
+
+	SessionResumeGrace time.Duration `json:"-"` // How long a disconnected SSE session keeps its server processes (0 cleans up at once)
+	SSEReplayEvents    int           `json:"-"` // SSE events kept per session for replay to reconnecting clients
+
+	SessionIdleTimeout time.Duration `json:"-"` // Connections without requests or keep-alives for this long are cleaned up
+	SessionMaxAge      time.Duration `json:"-"` // Connections open this long are cleaned up however active (unlimited when 0)
+
+	SessionDiskQuota   int64 `json:"-"` // Largest size of a session directory in bytes before tool calls are refused (unlimited when 0)
+	SessionPersistence bool  `json:"-"` // Keep session directories per client identity instead of deleting them with the session
+
+	HeartbeatStyle    string        `json:"-"` // Default SSE heartbeat style
+	HeartbeatInterval time.Duration `json:"-"` // Default SSE heartbeat interval
+
+	EndpointFormat string `json:"-"` // Default SSE endpoint event format: "object" or "string"
+
+	MaxRequestBytes  int64 `json:"-"` // Largest client message accepted (unlimited when 0)
+	MaxResponseBytes int64 `json:"-"` // Largest message read from an MCP server (unlimited when 0)
+
+	StreamThreshold int `json:"-"` // Responses larger than this many bytes are written in flushed chunks (off when 0)
+	StreamChunkSize int `json:"-"` // Size of each chunk of a streamed response
+
+	CompressionThreshold int `json:"-"` // POST and /listtools responses larger than this many bytes are gzip/deflate compressed (off when 0)
+
+	StateDir          string        `json:"-"` // Directory for durable state such as the incident history (memory only when empty)
+	IncidentRetention time.Duration `json:"-"` // How long incidents are kept
+	MaxIncidents      int           `json:"-"` // Maximum number of incidents kept
+	UsageRetention    time.Duration `json:"-"` // How long the daily usage counters of principals are kept
+
+	StateEncryptionKey          string   `json:"-"` // Base64 AES-256 key encrypting the state directory files (plaintext when empty)
+	StateEncryptionKeyFile      string   `json:"-"` // File holding the key instead, e.g. a mounted secret
+	StateEncryptionPreviousKeys []string `json:"-"` // Keys rotated out, still used to read the files they encrypted
+}
+
+// Webhook is an HTTP endpoint that server and session events are POSTed to
+type Webhook struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // Signs deliveries with HMAC-SHA256 in X-Webhook-Signature
+	Events []string `json:"events,omitempty"` // Event types delivered (all when empty)
+}
+
+// Webhook event types
+const (
+	WebhookServerCrash      = "server.crash"      // MCP server process exited unexpectedly
+	WebhookServerRestart    = "server.restart"    // MCP server restarted by the health checker or an operator
+	WebhookServerHealth     = "server.health"     // Health status flipped, or the server failed over or back
+	WebhookSessionCreated   = "session.created"   // SSE session connected
+	WebhookSessionClosed    = "session.closed"    // Session ended and its servers were stopped
+	WebhookOperationTimeout = "operation.timeout" // Request to an MCP server timed out
+	WebhookApprovalRequest  = "approval.request"  // Tool call held until an approver allows or denies it
+)
+
+// Policy decides which tool calls remote clients may make
+// Rules are tried in order and the first one matching a call decides it; calls
+// no rule matches get the default effect.
+type Policy struct {
+	Default string       `json:"default,omitempty"` // Effect of calls no rule matches: PolicyAllow (default) or PolicyDeny
+	Rules   []PolicyRule `json:"rules"`
+}
+
+// PolicyRule matches tool calls by who makes them, on which server, and with which arguments
+type PolicyRule struct {
+	Effect     string            `json:"effect"`               // PolicyAllow, PolicyDeny or PolicyRequireApproval
+	Principals []string          `json:"principals,omitempty"` // Principal patterns, e.g. "api-key:ci" or "oidc:*" (anyone when empty)
+	Servers    []string          `json:"servers,omitempty"`    // Server name patterns (every server when empty)
+	Tools      []string          `json:"tools,omitempty"`      // Tool name patterns (every tool when empty)
+	Arguments  map[string]string `json:"arguments,omitempty"`  // Regular expressions the arguments at dotted paths must all match, e.g. {"path": "^/etc/"}
+	Reason     string            `json:"reason,omitempty"`     // Error message returned for calls the rule refuses
+}
+
+// Policy effects
+const (
+	PolicyAllow           = "allow"            // The call is forwarded
+	PolicyDeny            = "deny"             // The call is refused with an error
+	PolicyRequireApproval = "require-approval" // The call needs an approver's consent
+)
+
+// UsageQuota limits what each principal it matches may use per UTC day
+// Every principal has its own allowance: a quota for "api-key:*" lets each API
+// key make CallsPerDay calls. A principal is held to every quota matching it.
+type UsageQuota struct {
+	Principals  []string `json:"principals,omitempty"`  // Principal patterns, e.g. "api-key:ci" or "oidc:*" (anyone when empty)
+	Servers     []string `json:"servers,omitempty"`     // Server name patterns whose calls count against the quota (every server when empty)
+	CallsPerDay int64    `json:"callsPerDay,omitempty"` // Tool calls allowed per day (unlimited when 0)
+	BytesPerDay int64    `json:"bytesPerDay,omitempty"` // Bytes of tool call results allowed per day (unlimited when 0)
+}
+
+// Tenant is a team sharing the proxy that sees only its own servers
+// A request belongs to the tenant its host names, {server}.mcp.{tenant}.{domain},
+// or else to the first tenant, by name, its principal or OAuth client matches.
+// Requests of no tenant see the servers no tenant lists.
+type Tenant struct {
+	Principals  []string     `json:"principals,omitempty"`  // Principal patterns of the tenant's users, e.g. "api-key:team-a-*" or "oidc:*"
+	Clients     []string     `json:"clients,omitempty"`     // OAuth client ID patterns whose tokens belong to the tenant
+	Servers     []string     `json:"servers"`               // Server name patterns the tenant may use
+	SessionRoot string       `json:"sessionRoot,omitempty"` // Directory holding the session directories of the tenant's sessions (shared root when empty)
+	Quotas      []UsageQuota `json:"quotas,omitempty"`      // Daily limits of the tenant's principals, on top of the top-level quotas
+}
+
+// WebhookEvents lists every webhook event type
+var WebhookEvents = []string{
+	WebhookServerCrash, WebhookServerRestart, WebhookServerHealth,
+	WebhookSessionCreated, WebhookSessionClosed, WebhookOperationTimeout,
+	WebhookApprovalRequest,
+}
+
+// OAuth consent modes
+const (
+	ConsentOff           = "off"            // Authorization codes are issued without asking, any bearer token is accepted
+	ConsentPassword      = "password"       // The consent page asks for OAUTH_CONSENT_PASSWORD
+	ConsentApprovalToken = "approval-token" // The consent page asks for a single-use token minted by an operator
+	ConsentOIDC          = "oidc"           // Users sign in with an upstream OpenID Connect provider, whose tokens are accepted
+)
+
+// Authentication modes
+const (
+	AuthModeOAuth  = "oauth"   // OAuth access tokens, as OAUTH_CONSENT decides, and API keys
+	AuthModeAPIKey = "api-key" // API keys only
+)
+
+// DefaultOAuthTokenTTL is how long issued access tokens stay valid
+const DefaultOAuthTokenTTL = 24 * time.Hour
+
+// DefaultOAuthScope is the scope advertised and granted when none are configured
+const DefaultOAuthScope = "mcp"
+
+// Routing modes
+const (
+	RoutingSubdomain = "subdomain" // {server}.mcp.{domain}/sse only
+	RoutingPath      = "path"      // /{server}/sse only
+	RoutingBoth      = "both"      // Subdomains, with path-based routing as a fallback
+)
+
+// Default tool call limits applied when the environment does not override them
+const (
+	DefaultMaxConcurrentToolCalls = 4
+	DefaultMaxQueuedToolCalls     = 16
+)
+
+// Default request timeout tiers
+const (
+	DefaultColdStartTimeout   = 60 * time.Second
+	DefaultSteadyStateTimeout = 30 * time.Second
+)
+
+// Default backoff of start retries for servers that failed to start
+const (
+	DefaultStartupRetryInterval    = 10 * time.Second
+	DefaultStartupRetryMaxInterval = 5 * time.Minute
+)
+
+// Preinstall modes
+const (
+	PreinstallOff  = "off"  // Only servers with a preinstall command
+	PreinstallAuto = "auto" // Also npx, uvx and docker servers, with a command derived from their configuration
+)
+
+// DefaultPreinstallTimeout bounds one preinstall command, e.g. a cold npm download
+const DefaultPreinstallTimeout = 5 * time.Minute
+
+// DefaultStopGracePeriod is how long a stopped server gets to exit after SIGTERM
+const DefaultStopGracePeriod = 10 * time.Second
+
+// DefaultOperationTimeout is how long session cleanup waits for a running request
+const DefaultOperationTimeout = 5 * time.Minute
+
+// DefaultApprovalTimeout is how long a tool call waits for an approver
+const DefaultApprovalTimeout = 5 * time.Minute
+
+// Session resume defaults
+const (
+	DefaultSessionResumeGrace = 2 * time.Minute // How long a disconnected session waits for its client to reconnect
+	DefaultSSEReplayEvents    = 100             // SSE events kept per session for replay
+)
+
+// DefaultSessionIdleTimeout is how long a connection may go without requests or keep-alives
+const DefaultSessionIdleTimeout = 5 * time.Minute
+
+// Default message size limits
+const (
+	DefaultMaxRequestBytes  = 10 << 20 // 10 MiB
+	DefaultMaxResponseBytes = 50 << 20 // 50 MiB
+)
+
+// Response streaming defaults
+const (
+	DefaultStreamThreshold = 1 << 20  // 1 MiB
+	DefaultStreamChunkSize = 32 << 10 // 32 KiB
+)
+
+// DefaultCompressionThreshold is the response size above which clients accepting gzip or deflate get compressed bodies
+const DefaultCompressionThreshold = 1 << 10 // 1 KiB
+
+// Default incident history retention
+const (
+	DefaultIncidentRetention = 30 * 24 * time.Hour
+	DefaultMaxIncidents      = 10000
+)
+
+// DefaultUsageRetention keeps the daily usage counters a little over a year, for yearly chargeback
+const DefaultUsageRetention = 400 * 24 * time.Hour
+
+// Load reads and parses the configuration
+// filename is a JSON or YAML file, a directory of them, or a glob matching them.
+func Load(filename string) (*Config, error) {
+	var config Config
+	loader := &fileLoader{seen: make(map[string]bool), sources: make(map[string]string)}
+	if err := loader.loadFiles(&config, filename); err != nil {
+		return nil, err
+	}
+
+	// Resolve ${VAR}, ${file:...} and ${secret:...} references
+	if err := config.expandReferences(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Load environment variables
+	config.LoadEnvironmentConfig()
+
+	return &config, nil
+}
+
+// validate checks that the configuration is valid
+func (c *Config) validate() error {
+	if len(c.MCPServers) == 0 {
+		return fmt.Errorf("no MCP servers configured")
+	}
+
+	if err := c.normalizeServerNames(); err != nil {
+		return err
+	}
+
+	if err := validateTimeouts(c.Timeouts); err != nil {
+		return err
+	}
+
+	// Key names identify keys in the admin API, so they are unique across servers
+	keyNames := make(map[string]bool)
+	if err := validateAPIKeys(c.APIKeys, keyNames); err != nil {
+		return err
+	}
+
+	if err := validateWebhooks(c.Webhooks); err != nil {
+		return err
+	}
+
+	if err := validatePolicy(c.Policy); err != nil {
+		return err
+	}
+
+	if err := validateQuotas(c.Quotas); err != nil {
+		return err
+	}
+
+	if err := validateTenants(c.Tenants); err != nil {
+		return err
+	}
+
+	if err := validateDependencies(c.MCPServers); err != nil {
+		return err
+	}
+
+	// A hostname routes to a single server
+	hostnames := make(map[string]string)
+
+	for name, server := range c.MCPServers {
+		if err := validateAPIKeys(server.APIKeys, keyNames); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		for _, hostname := range server.Hostnames {
+			if !hostnamePattern.MatchString(hostname) {
+				return fmt.Errorf("server %s: invalid hostname %q (expected a lowercase host name without port)", name, hostname)
+			}
+			if other, taken := hostnames[hostname]; taken {
+				return fmt.Errorf("servers %s and %s both use the hostname %q", other, name, hostname)
+			}
+			hostnames[hostname] = name
+		}
+		if server.BasePath != "" {
+			if err := c.validateBasePath(name, server.BasePath); err != nil {
+				return fmt.Errorf("server %s: %w", name, err)
+			}
+		}
+		if err := validateType(server); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if server.SelfTest != nil && server.SelfTest.Tool == "" {
+			return fmt.Errorf("server %s: selfTest needs a tool", name)
+		}
+		for _, pattern := range append(append([]string{}, server.AllowedTools...), server.BlockedTools...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("server %s: invalid tool pattern %q", name, pattern)
+			}
+		}
+		if err := validateMode(server); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if err := validateWarmPool(server); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if err := validateWorkspace(server); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if err := validateHeaderVariables(server); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if err := validateFallback(server.Fallback); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if server.Heartbeat != nil {
+			if err := validateHeartbeat(server.Heartbeat.Style, server.Heartbeat.Interval); err != nil {
+				return fmt.Errorf("server %s: %w", name, err)
+			}
+		}
+		if err := validateEndpointFormat(server.EndpointFormat); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if err := validateTimeouts(server.Timeouts); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if server.StopGracePeriod != "" {
+			if d, err := time.ParseDuration(server.StopGracePeriod); err != nil || d <= 0 {
+				return fmt.Errorf("server %s: invalid stopGracePeriod %q", name, server.StopGracePeriod)
+			}
+		}
+		if server.OperationTimeout != "" {
+			if d, err := time.ParseDuration(server.OperationTimeout); err != nil || d <= 0 {
+				return fmt.Errorf("server %s: invalid operationTimeout %q", name, server.OperationTimeout)
+			}
+		}
+		if server.MaxRequestBytes < 0 || server.MaxResponseBytes < 0 {
+			return fmt.Errorf("server %s: maxRequestBytes and maxResponseBytes cannot be negative", name)
+		}
+		if err := validateSandbox(server.Sandbox); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if err := validateRecycle(server.Recycle); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if err := validateStartupProbe(server.StartupProbe); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		for i, hook := range server.Hooks {
+			if err := validateHook(hook); err != nil {
+				return fmt.Errorf("server %s: hook %d: %w", name, i+1, err)
+			}
+		}
+		if server.OAuth != nil {
+			for _, pattern := range server.OAuth.RedirectURIs {
+				if _, err := path.Match(pattern, ""); err != nil || !strings.Contains(pattern, "://") {
+					return fmt.Errorf("server %s: invalid redirect URI pattern %q", name, pattern)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// serverNamePattern matches names usable as a DNS label: they become subdomains, URL paths and log file names
+var serverNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// hostnamePattern matches lowercase host names of at least two labels
+var hostnamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)+$`)
+
+// basePathPattern matches absolute URL paths without a trailing slash or dot segments
+var basePathPattern = regexp.MustCompile(`^(/[A-Za-z0-9_~-][A-Za-z0-9._~-]*)+$`)
+
+// validateBasePath checks a server's base path is well formed and routes to it alone
+// Its first segment can't be one of the proxy's endpoints, a single segment
+// can't be another server's name, and no base path can contain another.
+func (c *Config) validateBasePath(name, basePath string) error {
+	if !basePathPattern.MatchString(basePath) {
+		return fmt.Errorf("invalid basePath %q (expected an absolute path like \"/integrations/%s\")", basePath, name)
+	}
+	first, _, nested := strings.Cut(basePath[1:], "/")
+	if reservedServerNames[first] {
+		return fmt.Errorf("basePath %q starts with /%s, which is reserved by the proxy", basePath, first)
+	}
+	if _, exists := c.MCPServers[first]; exists && !nested && first != name {
+		return fmt.Errorf("basePath %q is the path of server %s", basePath, first)
+	}
+	for other, server := range c.MCPServers {
+		if other != name && server.BasePath != "" && (basePath == server.BasePath || strings.HasPrefix(basePath, server.BasePath+"/")) {
+			return fmt.Errorf("basePath %q is within the basePath of server %s", basePath, other)
+		}
+	}
+	return nil
+}
+
+// reservedServerNames are the first path segments of the proxy's own endpoints
+// Under path-based routing a server named like one would be shadowed by the
+// endpoint or shadow it, so they can't be server names or start a basePath.
+// The proxy's router tests check every endpoint it registers is listed.
+var reservedServerNames = map[string]bool{
+	".well-known": true, "admin": true, "cleanup": true, "debug": true, "favicon.ico": true, "health": true,
+	"listmcp": true, "listtools": true, "live": true, "logs": true, "metrics": true, "oauth": true, "ready": true,
+	"resources": true, "selftest": true, "sessions": true, "sse": true, "ui": true, "usage": true,
+}
+
+// IsReservedName reports whether name is the first path segment of one of the proxy's endpoints
+func IsReservedName(name string) bool {
+	return reservedServerNames[name]
+}
+
+// NormalizeServerName turns a config key into a server name: lowercase, with
+// underscores, dots and spaces replaced by hyphens
+func NormalizeServerName(key string) string {
+	return strings.NewReplacer("_", "-", ".", "-", " ", "-").Replace(strings.ToLower(strings.TrimSpace(key)))
+}
+
+// normalizeServerNames re-keys the servers by their slug or normalized config key
+// Keys that were changed are kept as the server's display name.
+func (c *Config) normalizeServerNames() error {
+	keys := make([]string, 0, len(c.MCPServers))
+	for key := range c.MCPServers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	servers := make(map[string]MCPServer, len(keys))
+	origins := make(map[string]string, len(keys))
+	for _, key := range keys {
+		server := c.MCPServers[key]
+		name := server.Slug
+		if name == "" {
+			name = NormalizeServerName(key)
+		}
+
+		if !serverNamePattern.MatchString(name) {
+			return fmt.Errorf("server %q: name %q must be 1-63 lowercase letters, digits or hyphens, not starting or ending with a hyphen; set \"slug\" to choose one", key, name)
+		}
+		if reservedServerNames[name] {
+			return fmt.Errorf("server %q: name %q is reserved by the proxy; set \"slug\" to choose another", key, name)
+		}
+		if other, exists := origins[name]; exists {
+			return fmt.Errorf("servers %q and %q both use the name %q; set \"slug\" on one of them", other, key, name)
+		}
+		origins[name] = key
+
+		if name != key {
+			server.DisplayName = key
+		}
+		servers[name] = server
+	}
+
+	// Dependencies may name a server by its config key or its normalized name
+	names := make(map[string]string, len(origins))
+	for name, key := range origins {
+		names[key] = name
+	}
+	for name, server := range servers {
+		if len(server.DependsOn) == 0 {
+			continue
+		}
+		dependsOn := make([]string, len(server.DependsOn))
+		for i, dependency := range server.DependsOn {
+			if normalized, exists := names[dependency]; exists {
+				dependency = normalized
+			}
+			dependsOn[i] = dependency
+		}
+		server.DependsOn = dependsOn
+		servers[name] = server
+	}
+
+	c.MCPServers = servers
+	return nil
+}
+
+// validateWarmPool checks that a server's instances can be started before their session is known
+func validateWarmPool(server MCPServer) error {
+	if server.WarmPool < 0 {
+		return fmt.Errorf("warmPool cannot be negative")
+	}
+	if server.WarmPool == 0 {
+		return nil
+	}
+	if server.SessionMode() != ModePerSession {
+		return fmt.Errorf("warmPool requires mode %q", ModePerSession)
+	}
+	return checkNoSessionTemplate(server, "warmPool")
+}
+
+// validateFallback checks a fallback configuration, which only needs a command
+func validateFallback(fallback *MCPServer) error {
+	if fallback == nil {
+		return nil
+	}
+	if err := validateType(*fallback); err != nil {
+		return fmt.Errorf("fallback: %w", err)
+	}
+	if fallback.Fallback != nil {
+		return fmt.Errorf("fallback cannot have a fallback of its own")
+	}
+	return checkNoSessionTemplate(*fallback, "fallback")
+}
+
+// validateType checks that a server has what its type needs to start
+func validateType(server MCPServer) error {
+	switch server.ServerType() {
+	case TypeStdio:
+		if server.Command == "" {
+			return fmt.Errorf("command cannot be empty")
+		}
+	case TypeTCP:
+		if server.Command == "" {
+			return fmt.Errorf("command cannot be empty for type %q", TypeTCP)
+		}
+	case TypeDocker:
+		if server.Image == "" {
+			return fmt.Errorf("image cannot be empty for type %q", TypeDocker)
+		}
+		if server.Sandbox != nil && len(server.Sandbox.Wrapper) > 0 {
+			return fmt.Errorf("sandbox wrapper cannot be used with type %q", TypeDocker)
+		}
+	case TypeSSE, TypeStreamableHTTP:
+		endpoint, err := url.Parse(server.URL)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("url must be an http or https URL for type %q", server.Type)
+		}
+		if server.Command != "" || server.Sandbox != nil {
+			return fmt.Errorf("command and sandbox cannot be used with type %q", server.Type)
+		}
+	default:
+		return fmt.Errorf("invalid type %q (expected %s, %s, %s, %s or %s)", server.Type, TypeStdio, TypeTCP, TypeDocker, TypeSSE, TypeStreamableHTTP)
+	}
+
+	if server.Port < 0 || server.Port > 65535 {
+		return fmt.Errorf("invalid port %d", server.Port)
+	}
+	if server.Port != 0 && server.ServerType() != TypeTCP {
+		return fmt.Errorf("port requires type %q", TypeTCP)
+	}
+	if server.Port != 0 && server.SessionMode() != ModeShared {
+		return fmt.Errorf("a fixed port requires mode %q, as other modes run several processes", ModeShared)
+	}
+
+	switch server.Framing {
+	case "", FramingAuto, FramingNewline, FramingContentLength:
+	default:
+		return fmt.Errorf("invalid framing %q (expected %s, %s or %s)", server.Framing, FramingAuto, FramingNewline, FramingContentLength)
+	}
+	for _, name := range server.EnvAllowlist {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("envAllowlist: invalid variable name %q", name)
+		}
+	}
+	if server.IsRemote() && (server.InheritEnv != nil || len(server.EnvAllowlist) > 0) {
+		return fmt.Errorf("inheritEnv and envAllowlist cannot be used with type %q", server.Type)
+	}
+	if server.InheritEnv != nil && *server.InheritEnv && (server.Sandbox != nil || server.ServerType() == TypeDocker) {
+		return fmt.Errorf("inheritEnv cannot be true for sandboxed and %q servers, which never inherit the proxy environment", TypeDocker)
+	}
+	if server.Cwd != "" && !server.RunsCommand() {
+		return fmt.Errorf("cwd requires type %q or %q", TypeStdio, TypeTCP)
+	}
+	if server.Cwd != "" && !strings.HasPrefix(server.Cwd, "/") && !strings.HasPrefix(server.Cwd, "{") {
+		return fmt.Errorf("cwd %q must be an absolute path or start with a template variable", server.Cwd)
+	}
+	if server.Framing != "" && server.IsRemote() {
+		return fmt.Errorf("framing cannot be used with type %q", server.Type)
+	}
+
+	if server.ServerType() != TypeDocker && (server.Image != "" || len(server.Volumes) > 0 || server.Network != "") {
+		return fmt.Errorf("image, volumes and network require type %q", TypeDocker)
+	}
+	if !server.IsRemote() && (server.URL != "" || len(server.Headers) > 0) {
+		return fmt.Errorf("url and headers require type %q or %q", TypeSSE, TypeStreamableHTTP)
+	}
+	return nil
+}
+
+// validateAPIKeys checks API key definitions, recording their names in seen
+func validateAPIKeys(keys []APIKey, seen map[string]bool) error {
+	for _, key := range keys {
+		switch {
+		case key.Name == "":
+			return fmt.Errorf("API keys need a name")
+		case seen[key.Name]:
+			return fmt.Errorf("duplicate API key name %q", key.Name)
+		case key.Key == "":
+			return fmt.Errorf("API key %q has no key", key.Name)
+		case key.RateLimit < 0:
+			return fmt.Errorf("API key %q: rateLimit cannot be negative", key.Name)
+		}
+		seen[key.Name] = true
+	}
+	return nil
+}
+
+// validateWebhooks checks webhook URLs and event types
+func validateWebhooks(hooks []Webhook) error {
+	known := make(map[string]bool)
+	for _, event := range WebhookEvents {
+		known[event] = true
+	}
+
+	for _, hook := range hooks {
+		parsed, err := url.Parse(hook.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("webhook %q: url must be an http or https URL", hook.URL)
+		}
+		for _, event := range hook.Events {
+			if !known[event] {
+				return fmt.Errorf("webhook %q: unknown event %q (expected one of %s)", hook.URL, event, strings.Join(WebhookEvents, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// validatePolicy checks the tool call policy's effects, patterns and argument expressions
+func validatePolicy(policy *Policy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.Default != "" && policy.Default != PolicyAllow && policy.Default != PolicyDeny {
+		return fmt.Errorf("policy: invalid default %q (expected %s or %s)", policy.Default, PolicyAllow, PolicyDeny)
+	}
+	for i, rule := range policy.Rules {
+		switch rule.Effect {
+		case PolicyAllow, PolicyDeny, PolicyRequireApproval:
+		default:
+			return fmt.Errorf("policy rule %d: invalid effect %q (expected %s, %s or %s)", i+1, rule.Effect, PolicyAllow, PolicyDeny, PolicyRequireApproval)
+		}
+		for _, pattern := range append(append(append([]string{}, rule.Principals...), rule.Servers...), rule.Tools...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("policy rule %d: invalid pattern %q", i+1, pattern)
+			}
+		}
+		for dotted, expression := range rule.Arguments {
+			if dotted == "" || strings.HasPrefix(dotted, ".") || strings.HasSuffix(dotted, ".") || strings.Contains(dotted, "..") {
+				return fmt.Errorf("policy rule %d: invalid argument path %q", i+1, dotted)
+			}
+			if _, err := regexp.Compile(expression); err != nil {
+				return fmt.Errorf("policy rule %d: argument %s: %w", i+1, dotted, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateQuotas checks the usage quotas' patterns and limits
+func validateQuotas(quotas []UsageQuota) error {
+	for i, quota := range quotas {
+		if quota.CallsPerDay < 0 || quota.BytesPerDay < 0 {
+			return fmt.Errorf("quota %d: limits cannot be negative", i+1)
+		}
+		if quota.CallsPerDay == 0 && quota.BytesPerDay == 0 {
+			return fmt.Errorf("quota %d: callsPerDay or bytesPerDay is required", i+1)
+		}
+		for _, pattern := range append(append([]string{}, quota.Principals...), quota.Servers...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("quota %d: invalid pattern %q", i+1, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// validateTenants checks the tenants' names, patterns, session roots and quotas
+func validateTenants(tenants map[string]Tenant) error {
+	for name, tenant := range tenants {
+		if !serverNamePattern.MatchString(name) {
+			return fmt.Errorf("tenant %q: invalid name (expected a lowercase DNS label, it appears in hostnames)", name)
+		}
+		if len(tenant.Servers) == 0 {
+			return fmt.Errorf("tenant %s: servers is required", name)
+		}
+		for _, pattern := range append(append(append([]string{}, tenant.Principals...), tenant.Clients...), tenant.Servers...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("tenant %s: invalid pattern %q", name, pattern)
+			}
+		}
+		if tenant.SessionRoot != "" && !path.IsAbs(tenant.SessionRoot) {
+			return fmt.Errorf("tenant %s: sessionRoot %q must be an absolute path", name, tenant.SessionRoot)
+		}
+		if err := validateQuotas(tenant.Quotas); err != nil {
+			return fmt.Errorf("tenant %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateSandbox checks a server's sandbox settings
+func validateSandbox(sandbox *Sandbox) error {
+	if sandbox == nil {
+		return nil
+	}
+	if sandbox.UID < 0 || sandbox.GID < 0 {
+		return fmt.Errorf("sandbox uid and gid cannot be negative")
+	}
+	for _, name := range sandbox.EnvAllowlist {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("sandbox envAllowlist: invalid variable name %q", name)
+		}
+	}
+	for _, arg := range sandbox.Wrapper {
+		if arg == "" {
+			return fmt.Errorf("sandbox wrapper cannot contain empty arguments")
+		}
+	}
+	return nil
+}
+
+// validateRecycle checks a server's recycle policy
+func validateRecycle(recycle *Recycle) error {
+	if recycle == nil {
+		return nil
+	}
+	if recycle.MaxAge != "" {
+		if d, err := time.ParseDuration(recycle.MaxAge); err != nil || d <= 0 {
+			return fmt.Errorf("invalid recycle maxAge %q", recycle.MaxAge)
+		}
+	}
+	if recycle.MaxRequests < 0 {
+		return fmt.Errorf("recycle maxRequests cannot be negative")
+	}
+	if recycle.MaxAge == "" && recycle.MaxRequests == 0 {
+		return fmt.Errorf("recycle needs maxAge or maxRequests")
+	}
+	return nil
+}
+
+// validateStartupProbe checks a server's startup probe durations
+func validateStartupProbe(probe *StartupProbe) error {
+	if probe == nil {
+		return nil
+	}
+	if probe.Timeout != "" {
+		if d, err := time.ParseDuration(probe.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid startupProbe timeout %q", probe.Timeout)
+		}
+	}
+	if probe.Interval != "" {
+		if d, err := time.ParseDuration(probe.Interval); err != nil || d <= 0 {
+			return fmt.Errorf("invalid startupProbe interval %q", probe.Interval)
+		}
+	}
+	return nil
+}
+
+// validateHook checks a hook has a known type, valid patterns and what its type needs
+func validateHook(hook Hook) error {
+	for _, pattern := range append(append([]string{}, hook.Methods...), hook.Tools...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+
+	switch hook.Type {
+	case HookHeader:
+		if len(hook.Headers) == 0 {
+			return fmt.Errorf("header hook needs headers")
+		}
+	case HookRewrite:
+		if len(hook.Set) == 0 && len(hook.Remove) == 0 {
+			return fmt.Errorf("rewrite hook needs set or remove")
+		}
+		if hook.Phase != "" && hook.Phase != HookPhaseRequest && hook.Phase != HookPhaseResponse {
+			return fmt.Errorf("invalid rewrite phase %q (expected %s or %s)", hook.Phase, HookPhaseRequest, HookPhaseResponse)
+		}
+		for _, dotted := range append(mapKeys(hook.Set), hook.Remove...) {
+			if dotted == "" || strings.HasPrefix(dotted, ".") || strings.HasSuffix(dotted, ".") || strings.Contains(dotted, "..") {
+				return fmt.Errorf("invalid rewrite path %q", dotted)
+			}
+		}
+	case HookBlock:
+		if len(hook.Methods) == 0 && len(hook.Tools) == 0 {
+			return fmt.Errorf("block hook needs methods or tools")
+		}
+	case HookCommand, HookHTTP:
+		if hook.Type == HookCommand && hook.Command == "" {
+			return fmt.Errorf("command hook needs a command")
+		}
+		if hook.Type == HookHTTP {
+			if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("http hook needs an http(s) url, got %q", hook.URL)
+			}
+		}
+		if hook.Phase != "" && hook.Phase != HookPhaseRequest && hook.Phase != HookPhaseResponse {
+			return fmt.Errorf("invalid hook phase %q (expected %s or %s)", hook.Phase, HookPhaseRequest, HookPhaseResponse)
+		}
+		if hook.Timeout != "" {
+			if d, err := time.ParseDuration(hook.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("invalid hook timeout %q", hook.Timeout)
+			}
+		}
+	case HookScan:
+		if len(hook.Detectors) == 0 && len(hook.Patterns) == 0 {
+			return fmt.Errorf("scan hook needs detectors or patterns")
+		}
+		known := map[string]bool{ScanSecrets: true}
+		for _, detector := range ScanDetectors {
+			known[detector] = true
+		}
+		for _, detector := range hook.Detectors {
+			if !known[detector] {
+				return fmt.Errorf("unknown detector %q (expected %s or one of %s)", detector, ScanSecrets, strings.Join(ScanDetectors, ", "))
+			}
+		}
+		for name, expression := range hook.Patterns {
+			if name == "" {
+				return fmt.Errorf("scan pattern needs a name")
+			}
+			if _, err := regexp.Compile(expression); err != nil {
+				return fmt.Errorf("scan pattern %s: %w", name, err)
+			}
+		}
+		if hook.Action != "" && hook.Action != ScanRedact && hook.Action != ScanBlock {
+			return fmt.Errorf("invalid scan action %q (expected %s or %s)", hook.Action, ScanRedact, ScanBlock)
+		}
+	default:
+		return fmt.Errorf("invalid hook type %q (expected %s, %s, %s, %s, %s or %s)", hook.Type, HookHeader, HookRewrite, HookBlock, HookCommand, HookHTTP, HookScan)
+	}
+	return nil
 }
 
-// Config represents the entire configuration file
-type Config struct {
-	MCPServers map[string]MCPServer `json:"mcpServers"`
-	// Environment-based configuration (loaded from env vars)
-	Domain string `json:"-"` // Domain for subdomain routing
-	Port   string `json:"-"` // HTTP server port
+// mapKeys returns the keys of a map
+func mapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
 }
 
-// Load reads and parses the configuration file
-func Load(filename string) (*Config, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+// validateDependencies checks that dependsOn names configured servers and has no cycle
+func validateDependencies(servers map[string]MCPServer) error {
+	for name, server := range servers {
+		for _, dependency := range server.DependsOn {
+			if dependency == name {
+				return fmt.Errorf("server %s: cannot depend on itself", name)
+			}
+			if _, exists := servers[dependency]; !exists {
+				return fmt.Errorf("server %s: dependsOn names unknown server %q", name, dependency)
+			}
+		}
 	}
+	if order := StartOrder(servers); len(order) < len(servers) {
+		return fmt.Errorf("dependsOn has a cycle: servers %s cannot be ordered", strings.Join(unordered(servers, order), ", "))
+	}
+	return nil
+}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+// StartOrder returns server names with every server after the servers it depends on
+// Servers that don't depend on one another are ordered by name. Servers in a
+// dependency cycle, or depending on one, are left out.
+func StartOrder(servers map[string]MCPServer) []string {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	if err := config.validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	started := make(map[string]bool, len(names))
+	order := make([]string, 0, len(names))
+	for len(order) < len(names) {
+		progressed := false
+		for _, name := range names {
+			if started[name] || !dependenciesIn(servers[name], started) {
+				continue
+			}
+			started[name] = true
+			order = append(order, name)
+			progressed = true
+			break // Restart from the first name so the order stays alphabetical where it can
+		}
+		if !progressed {
+			break
+		}
 	}
+	return order
+}
 
-	// Load environment variables
-	config.LoadEnvironmentConfig()
+// dependenciesIn reports whether every dependency of a server is in set
+func dependenciesIn(server MCPServer, set map[string]bool) bool {
+	for _, dependency := range server.DependsOn {
+		if !set[dependency] {
+			return false
+		}
+	}
+	return true
+}
 
-	return &config, nil
+// unordered returns the sorted names of servers missing from order
+func unordered(servers map[string]MCPServer, order []string) []string {
+	ordered := make(map[string]bool, len(order))
+	for _, name := range order {
+		ordered[name] = true
+	}
+	var missing []string
+	for name := range servers {
+		if !ordered[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
 }
 
-// validate checks that the configuration is valid
-func (c *Config) validate() error {
-	if len(c.MCPServers) == 0 {
-		return fmt.Errorf("no MCP servers configured")
+// validateMode checks the session mode and that shared processes don't depend on one session
+func validateMode(server MCPServer) error {
+	mode := server.SessionMode()
+	if mode != ModePerSession && mode != ModeShared && mode != ModePool {
+		return fmt.Errorf("invalid mode %q (expected %s, %s or %s)", server.Mode, ModePerSession, ModeShared, ModePool)
+	}
+	if server.MaxInstances != 0 && mode != ModePool {
+		return fmt.Errorf("maxInstances requires mode %q", ModePool)
 	}
+	if server.MaxInstances < 0 {
+		return fmt.Errorf("maxInstances cannot be negative")
+	}
+	if mode == ModePerSession {
+		return nil
+	}
+	return checkNoSessionTemplate(server, "mode "+mode)
+}
 
-	for name, server := range c.MCPServers {
-		if server.Command == "" {
-			return fmt.Errorf("server %s: command cannot be empty", name)
+// validateWorkspace checks the workspace scope; user workspaces need the client, known only once its session starts
+func validateWorkspace(server MCPServer) error {
+	switch server.Workspace {
+	case "", WorkspaceSession:
+		return nil
+	case WorkspaceUser:
+	default:
+		return fmt.Errorf("invalid workspace %q (expected %s or %s)", server.Workspace, WorkspaceSession, WorkspaceUser)
+	}
+	if server.SessionMode() != ModePerSession {
+		return fmt.Errorf("workspace %q requires mode %q", WorkspaceUser, ModePerSession)
+	}
+	if server.WarmPool > 0 {
+		return fmt.Errorf("workspace %q cannot be used with warmPool", WorkspaceUser)
+	}
+	return nil
+}
+
+// builtinTemplateVariables are substituted in the args, env and volumes of session instances
+var builtinTemplateVariables = map[string]bool{
+	"SESSION_ID": true, "SERVER_NAME": true, "SESSION_DIR": true, "WORKSPACE": true,
+	"USER": true, "DATE": true, "PORT": true,
+}
+
+// templateVariableName matches names usable in headerVariables
+var templateVariableName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// validateHeaderVariables checks header variable names; like user workspaces they need the session's first request
+func validateHeaderVariables(server MCPServer) error {
+	if len(server.HeaderVariables) == 0 {
+		return nil
+	}
+	for variable, header := range server.HeaderVariables {
+		if !templateVariableName.MatchString(variable) || builtinTemplateVariables[variable] {
+			return fmt.Errorf("invalid header variable name %q (expected upper case, not a built-in variable)", variable)
+		}
+		if header == "" {
+			return fmt.Errorf("header variable %s has no header", variable)
 		}
 	}
+	if server.SessionMode() != ModePerSession || server.WarmPool > 0 {
+		return fmt.Errorf("headerVariables require mode %q without warmPool", ModePerSession)
+	}
+	return nil
+}
 
+// checkNoSessionTemplate rejects variables of one session for processes started before or across sessions
+func checkNoSessionTemplate(server MCPServer, feature string) error {
+	for _, variable := range []string{"{SESSION_ID}", "{WORKSPACE}", "{SESSION_DIR}", "{USER}"} {
+		if strings.Contains(server.Cwd, variable) {
+			return fmt.Errorf("%s cannot be used with %s in cwd", feature, variable)
+		}
+		for _, arg := range server.Args {
+			if strings.Contains(arg, variable) {
+				return fmt.Errorf("%s cannot be used with %s in args", feature, variable)
+			}
+		}
+		for key, value := range server.Env {
+			if strings.Contains(value, variable) {
+				return fmt.Errorf("%s cannot be used with %s in env %s", feature, variable, key)
+			}
+		}
+	}
 	return nil
 }
 
@@ -69,12 +1442,396 @@ func (c *Config) LoadEnvironmentConfig() {
 		c.Domain = "localhost" // Default for development
 	}
 
+	for _, domain := range strings.Split(os.Getenv("MCP_DOMAINS"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			c.Domains = append(c.Domains, domain)
+		}
+	}
+
+	if proxies := envList("TRUSTED_PROXIES"); len(proxies) > 0 {
+		c.TrustedProxies = parseTrustedProxies(proxies)
+	}
+
 	// Port configuration
 	if port := os.Getenv("PORT"); port != "" {
 		c.Port = port
 	} else {
 		c.Port = "8080" // Default port
 	}
+
+	// Per-session tool call limits
+	c.MaxConcurrentToolCalls = envInt("TOOL_CALL_CONCURRENCY", DefaultMaxConcurrentToolCalls)
+	c.MaxQueuedToolCalls = envInt("TOOL_CALL_QUEUE_DEPTH", DefaultMaxQueuedToolCalls)
+
+	// Session resumption
+	c.RequireReconnectToken = envBool("REQUIRE_RECONNECT_TOKEN", false)
+
+	// Operator endpoints
+	c.AdminToken = os.Getenv("ADMIN_TOKEN")
+
+	// Startup failure isolation (fail fast is opt-in)
+	c.StartupFailFast = envBool("STARTUP_FAIL_FAST", false)
+
+	// Servers that failed to start are retried with backoff (STARTUP_RETRY_INTERVAL=0 disables retries)
+	c.StartupRetryInterval = DefaultStartupRetryInterval
+	if interval := os.Getenv("STARTUP_RETRY_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d >= 0 {
+			c.StartupRetryInterval = d
+		}
+	}
+	c.StartupRetryMaxInterval = envDuration("STARTUP_RETRY_MAX_INTERVAL", DefaultStartupRetryMaxInterval)
+
+	// Package preinstallation before the first start (unknown modes fall back to off)
+	c.Preinstall = os.Getenv("PREINSTALL")
+	if c.Preinstall != PreinstallAuto {
+		c.Preinstall = PreinstallOff
+	}
+	c.PreinstallTimeout = envDuration("PREINSTALL_TIMEOUT", DefaultPreinstallTimeout)
+
+	// Graceful shutdown of server processes
+	c.StopGracePeriod = envDuration("STOP_GRACE_PERIOD", DefaultStopGracePeriod)
+
+	// Long-running requests protected from session cleanup
+	c.OperationTimeout = envDuration("OPERATION_TIMEOUT", DefaultOperationTimeout)
+	c.ApprovalTimeout = envDuration("APPROVAL_TIMEOUT", DefaultApprovalTimeout)
+
+	// Health alerts
+	c.AlertSlackWebhookURL = os.Getenv("ALERT_SLACK_WEBHOOK_URL")
+	c.AlertPagerDutyRoutingKey = os.Getenv("ALERT_PAGERDUTY_ROUTING_KEY")
+
+	// A webhook from the environment is added to those of the configuration file
+	if webhookURL := os.Getenv("WEBHOOK_URL"); webhookURL != "" && !c.hasWebhook(webhookURL) {
+		c.Webhooks = append(c.Webhooks, Webhook{
+			URL:    webhookURL,
+			Secret: os.Getenv("WEBHOOK_SECRET"),
+			Events: envList("WEBHOOK_EVENTS"),
+		})
+	}
+
+	// Bearer tokens accepted by MCP endpoints (unknown modes fall back to api-key, which accepts the fewest)
+	c.AuthMode = os.Getenv("AUTH_MODE")
+	switch c.AuthMode {
+	case "":
+		c.AuthMode = AuthModeOAuth
+	case AuthModeOAuth, AuthModeAPIKey:
+	default:
+		c.AuthMode = AuthModeAPIKey
+	}
+
+	// OAuth consent (unknown modes fall back to password, which refuses everyone without a password)
+	c.OAuthConsent = os.Getenv("OAUTH_CONSENT")
+	switch c.OAuthConsent {
+	case "":
+		c.OAuthConsent = ConsentOff
+	case ConsentOff, ConsentPassword, ConsentApprovalToken, ConsentOIDC:
+	default:
+		c.OAuthConsent = ConsentPassword
+	}
+	c.OAuthConsentPassword = os.Getenv("OAUTH_CONSENT_PASSWORD")
+	c.OAuthTokenTTL = envDuration("OAUTH_TOKEN_TTL", DefaultOAuthTokenTTL)
+	c.OAuthRequirePKCE = envBool("OAUTH_REQUIRE_PKCE", false)
+	c.OAuthRedirectURIs = envList("OAUTH_REDIRECT_URIS")
+	c.OAuthScopes = strings.Fields(os.Getenv("OAUTH_SCOPES"))
+
+	// Upstream OIDC provider
+	c.OIDCIssuer = os.Getenv("OIDC_ISSUER")
+	c.OIDCClientID = os.Getenv("OIDC_CLIENT_ID")
+	c.OIDCClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	c.OIDCAudience = os.Getenv("OIDC_AUDIENCE")
+	c.OIDCScopes = strings.Fields(os.Getenv("OIDC_SCOPES"))
+	if len(c.OIDCScopes) == 0 {
+		c.OIDCScopes = []string{"openid", "profile", "email"}
+	}
+	c.OIDCRedirectURL = os.Getenv("OIDC_REDIRECT_URL")
+
+	// Debug wire capture (opt-in)
+	c.WireCapture = os.Getenv("WIRE_CAPTURE")
+	if c.WireCapture == "" {
+		c.WireCapture = "off"
+	}
+	c.WireCaptureDir = os.Getenv("WIRE_CAPTURE_DIR")
+	if c.WireCaptureDir == "" {
+		c.WireCaptureDir = "/app/traces"
+	}
+
+	// Aggregate (fan-out) server
+	c.AggregateServer = envBool("AGGREGATE_SERVER", true)
+	c.ToolNamespacing = os.Getenv("TOOL_NAMESPACING")
+	c.ToolNamespaceSeparator = os.Getenv("TOOL_NAMESPACE_SEPARATOR")
+
+	// Request routing (invalid values fall back to both)
+	c.Routing = os.Getenv("ROUTING_MODE")
+	switch c.Routing {
+	case RoutingSubdomain, RoutingPath, RoutingBoth:
+	default:
+		c.Routing = RoutingBoth
+	}
+
+	// Informational landing page
+	c.LandingPage = envBool("LANDING_PAGE", true)
+
+	// Discovery response cache (off by default)
+	if ttl := os.Getenv("RESPONSE_CACHE_TTL"); ttl != "" {
+		if d, err := time.ParseDuration(ttl); err == nil && d > 0 {
+			c.ResponseCacheTTL = d
+		}
+	}
+
+	// Request timeout tiers for cold and warmed-up server processes
+	c.ColdStartTimeout = envDuration("COLD_START_TIMEOUT", DefaultColdStartTimeout)
+	c.SteadyStateTimeout = envDuration("STEADY_STATE_TIMEOUT", DefaultSteadyStateTimeout)
+
+	// Session affinity across SSE reconnects (0 cleans up as soon as the stream closes)
+	c.SessionResumeGrace = DefaultSessionResumeGrace
+	if grace := os.Getenv("SESSION_RESUME_GRACE"); grace != "" {
+		if d, err := time.ParseDuration(grace); err == nil && d >= 0 {
+			c.SessionResumeGrace = d
+		}
+	}
+	c.SSEReplayEvents = envInt("SSE_REPLAY_BUFFER", DefaultSSEReplayEvents)
+	if c.SSEReplayEvents == 0 {
+		c.SSEReplayEvents = DefaultSSEReplayEvents
+	}
+
+	// Idle connections are cleaned up after SESSION_IDLE_TIMEOUT; SESSION_MAX_AGE caps their lifetime
+	c.SessionIdleTimeout = envDuration("SESSION_IDLE_TIMEOUT", DefaultSessionIdleTimeout)
+	c.SessionMaxAge = envDuration("SESSION_MAX_AGE", 0)
+
+	// Session directories (no quota and deleted with their session by default)
+	c.SessionDiskQuota = int64(envInt("SESSION_DISK_QUOTA", 0))
+	c.SessionPersistence = envBool("SESSION_PERSISTENCE", false)
+
+	// SSE heartbeat defaults (invalid values fall back to the defaults)
+	c.HeartbeatStyle = os.Getenv("HEARTBEAT_STYLE")
+	c.HeartbeatInterval = DefaultHeartbeatInterval
+	if interval := os.Getenv("HEARTBEAT_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil && d > 0 {
+			c.HeartbeatInterval = d
+		}
+	}
+	if validateHeartbeat(c.HeartbeatStyle, "") != nil {
+		c.HeartbeatStyle = HeartbeatComment
+	}
+
+	// SSE endpoint event format (invalid values fall back to the object form)
+	c.EndpointFormat = os.Getenv("SSE_ENDPOINT_FORMAT")
+	if c.EndpointFormat == "" || validateEndpointFormat(c.EndpointFormat) != nil {
+		c.EndpointFormat = EndpointFormatObject
+	}
+
+	// Message size limits (0 disables a limit)
+	c.MaxRequestBytes = int64(envInt("MAX_REQUEST_BYTES", DefaultMaxRequestBytes))
+	c.MaxResponseBytes = int64(envInt("MAX_RESPONSE_BYTES", DefaultMaxResponseBytes))
+
+	// Large responses are streamed in chunks (STREAM_THRESHOLD=0 writes them at once)
+	c.StreamThreshold = envInt("STREAM_THRESHOLD", DefaultStreamThreshold)
+	c.StreamChunkSize = envInt("STREAM_CHUNK_SIZE", DefaultStreamChunkSize)
+	if c.StreamChunkSize == 0 {
+		c.StreamChunkSize = DefaultStreamChunkSize
+	}
+
+	// Large responses are compressed for clients that accept it (COMPRESSION_THRESHOLD=0 never compresses)
+	c.CompressionThreshold = envInt("COMPRESSION_THRESHOLD", DefaultCompressionThreshold)
+
+	// Durable state and incident history (STATE_DIR=off keeps it in memory)
+	c.StateDir = os.Getenv("STATE_DIR")
+	if c.StateDir == "" {
+		c.StateDir = "/app/state"
+	} else if c.StateDir == "off" {
+		c.StateDir = ""
+	}
+	c.IncidentRetention = DefaultIncidentRetention
+	if retention := os.Getenv("INCIDENT_RETENTION"); retention != "" {
+		if d, err := time.ParseDuration(retention); err == nil && d > 0 {
+			c.IncidentRetention = d
+		}
+	}
+	c.MaxIncidents = envInt("INCIDENT_MAX_ENTRIES", DefaultMaxIncidents)
+	c.UsageRetention = envDuration("USAGE_RETENTION", DefaultUsageRetention)
+
+	// State files are encrypted at rest when a key is set
+	c.StateEncryptionKey = os.Getenv("STATE_ENCRYPTION_KEY")
+	c.StateEncryptionKeyFile = os.Getenv("STATE_ENCRYPTION_KEY_FILE")
+	c.StateEncryptionPreviousKeys = envList("STATE_ENCRYPTION_PREVIOUS_KEYS")
+}
+
+// StateEncryptionKeys returns the keys of the state directory files, the current one first
+// There are none when state files are kept in plaintext; previous keys
+// without a current one are an error, as they would leave files unreadable.
+func (c *Config) StateEncryptionKeys() ([]string, error) {
+	current := c.StateEncryptionKey
+	if c.StateEncryptionKeyFile != "" {
+		if current != "" {
+			return nil, fmt.Errorf("set either STATE_ENCRYPTION_KEY or STATE_ENCRYPTION_KEY_FILE, not both")
+		}
+		data, err := os.ReadFile(c.StateEncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read state encryption key: %w", err)
+		}
+		current = strings.TrimSpace(string(data))
+	}
+	if current == "" {
+		if len(c.StateEncryptionPreviousKeys) > 0 {
+			return nil, fmt.Errorf("previous state encryption keys are set without a current key")
+		}
+		return nil, nil
+	}
+	return append([]string{current}, c.StateEncryptionPreviousKeys...), nil
+}
+
+// validateHeartbeat checks a heartbeat style and interval; empty values are allowed
+func validateHeartbeat(style, interval string) error {
+	switch style {
+	case "", HeartbeatComment, HeartbeatEvent, HeartbeatPing:
+	default:
+		return fmt.Errorf("invalid heartbeat style %q (expected comment, event or ping)", style)
+	}
+	if interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid heartbeat interval %q", interval)
+		}
+	}
+	return nil
+}
+
+// validateEndpointFormat checks an SSE endpoint event format; empty is allowed
+func validateEndpointFormat(format string) error {
+	switch format {
+	case "", EndpointFormatObject, EndpointFormatString:
+		return nil
+	}
+	return fmt.Errorf("invalid endpointFormat %q (expected object or string)", format)
+}
+
+// validateTimeouts checks per-method timeouts are positive Go durations
+func validateTimeouts(timeouts map[string]string) error {
+	for method, timeout := range timeouts {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid timeout %q for method %s", timeout, method)
+		}
+	}
+	return nil
+}
+
+// envBool reads a boolean from the environment, falling back to def
+func envBool(name string, def bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// envInt reads a non-negative integer from the environment, falling back to def
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
+
+// envList reads a comma-separated list from the environment, dropping empty items
+func envList(name string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// defaultTrustedProxies are the networks a reverse proxy in front of the proxy usually connects from
+var defaultTrustedProxies = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fc00::/7"),
+}
+
+// parseTrustedProxies parses CIDRs and bare addresses, skipping invalid entries
+// "none" trusts no peer, so forwarded headers are always ignored.
+func parseTrustedProxies(items []string) []netip.Prefix {
+	proxies := []netip.Prefix{}
+	for _, item := range items {
+		if strings.EqualFold(item, "none") {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(item); err == nil {
+			proxies = append(proxies, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(item); err == nil {
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return proxies
+}
+
+// envDuration reads a positive Go duration from the environment, falling back to def
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// hasWebhook reports whether a webhook is configured for url
+func (c *Config) hasWebhook(webhookURL string) bool {
+	for _, hook := range c.Webhooks {
+		if hook.URL == webhookURL {
+			return true
+		}
+	}
+	return false
+}
+
+// RoutingMode returns how requests select a server, defaulting to both
+func (c *Config) RoutingMode() string {
+	if c.Routing == "" {
+		return RoutingBoth
+	}
+	return c.Routing
+}
+
+// TrustsProxy reports whether forwarded headers from a request's peer (r.RemoteAddr) are honored
+// Without TRUSTED_PROXIES, peers on loopback and private networks are trusted.
+func (c *Config) TrustsProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	proxies := defaultTrustedProxies
+	if c != nil && c.TrustedProxies != nil {
+		proxies = c.TrustedProxies
+	}
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
 }
 
 // GetDomain returns the configured domain for subdomain routing
@@ -87,6 +1844,246 @@ func (c *Config) GetPort() string {
 	return c.Port
 }
 
+// GetMaxConcurrentToolCalls returns how many tools/call requests a session may run at once
+func (c *Config) GetMaxConcurrentToolCalls() int {
+	if c.MaxConcurrentToolCalls <= 0 {
+		return DefaultMaxConcurrentToolCalls
+	}
+	return c.MaxConcurrentToolCalls
+}
+
+// GetMaxQueuedToolCalls returns how many tools/call requests a session may have waiting
+func (c *Config) GetMaxQueuedToolCalls() int {
+	return c.MaxQueuedToolCalls
+}
+
+// GetHeartbeat returns the SSE heartbeat style and interval for a server
+// Per-server settings override the environment defaults.
+func (c *Config) GetHeartbeat(serverName string) (string, time.Duration) {
+	style, interval := c.HeartbeatStyle, c.HeartbeatInterval
+	if style == "" {
+		style = HeartbeatComment
+	}
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	if server, exists := c.MCPServers[serverName]; exists && server.Heartbeat != nil {
+		if server.Heartbeat.Style != "" {
+			style = server.Heartbeat.Style
+		}
+		if d, err := time.ParseDuration(server.Heartbeat.Interval); err == nil && d > 0 {
+			interval = d
+		}
+	}
+	return style, interval
+}
+
+// GetEndpointFormat returns the SSE endpoint event format for a server
+// The server's endpointFormat overrides SSE_ENDPOINT_FORMAT.
+func (c *Config) GetEndpointFormat(serverName string) string {
+	if server, exists := c.MCPServers[serverName]; exists && server.EndpointFormat != "" {
+		return server.EndpointFormat
+	}
+	if c.EndpointFormat == "" {
+		return EndpointFormatObject
+	}
+	return c.EndpointFormat
+}
+
+// GetMethodTimeout returns the timeout configured for a method on a server
+// The server's timeouts override the global ones. It returns false when neither
+// sets one, leaving the caller's default in place.
+func (c *Config) GetMethodTimeout(serverName, method string) (time.Duration, bool) {
+	if server, exists := c.MCPServers[serverName]; exists {
+		if d, err := time.ParseDuration(server.Timeouts[method]); err == nil && d > 0 {
+			return d, true
+		}
+	}
+	if d, err := time.ParseDuration(c.Timeouts[method]); err == nil && d > 0 {
+		return d, true
+	}
+	return 0, false
+}
+
+// GetOAuthRedirectURIs returns the redirect URI patterns allowed on a server's subdomain (any when empty)
+// The server's oauth.redirectUris override OAUTH_REDIRECT_URIS.
+func (c *Config) GetOAuthRedirectURIs(serverName string) []string {
+	if server, exists := c.MCPServers[serverName]; exists && server.OAuth != nil && len(server.OAuth.RedirectURIs) > 0 {
+		return server.OAuth.RedirectURIs
+	}
+	return c.OAuthRedirectURIs
+}
+
+// GetOAuthScopes returns the scopes a server's subdomain advertises and grants
+// The server's oauth.scopes override OAUTH_SCOPES, which defaults to "mcp".
+func (c *Config) GetOAuthScopes(serverName string) []string {
+	if server, exists := c.MCPServers[serverName]; exists && server.OAuth != nil && len(server.OAuth.Scopes) > 0 {
+		return server.OAuth.Scopes
+	}
+	if len(c.OAuthScopes) > 0 {
+		return c.OAuthScopes
+	}
+	return []string{DefaultOAuthScope}
+}
+
+// GetMaxRequestBytes returns the size limit for client messages to a server (unlimited when 0)
+// The server's maxRequestBytes overrides MAX_REQUEST_BYTES.
+func (c *Config) GetMaxRequestBytes(serverName string) int64 {
+	if server, exists := c.MCPServers[serverName]; exists && server.MaxRequestBytes > 0 {
+		return server.MaxRequestBytes
+	}
+	return c.MaxRequestBytes
+}
+
+// GetMaxResponseBytes returns the size limit for messages from a server (unlimited when 0)
+// The server's maxResponseBytes overrides MAX_RESPONSE_BYTES.
+func (c *Config) GetMaxResponseBytes(serverName string) int64 {
+	if server, exists := c.MCPServers[serverName]; exists && server.MaxResponseBytes > 0 {
+		return server.MaxResponseBytes
+	}
+	return c.MaxResponseBytes
+}
+
+// BasePathServer returns the server whose base path a request path is under, and that base path
+// serverName is "" when no server's base path matches.
+func (c *Config) BasePathServer(requestPath string) (serverName, basePath string) {
+	if c == nil {
+		return "", ""
+	}
+	for name, server := range c.MCPServers {
+		if server.BasePath != "" && (requestPath == server.BasePath || strings.HasPrefix(requestPath, server.BasePath+"/")) {
+			return name, server.BasePath
+		}
+	}
+	return "", ""
+}
+
+// hostName returns a request host in lowercase, without port and trailing dot
+func hostName(host string) string {
+	if colon := strings.LastIndex(host, ":"); colon != -1 && !strings.Contains(host[colon:], "]") {
+		host = host[:colon]
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// servesDomain reports whether {server}.mcp.{domain} hosts are accepted for domain
+// Any domain is accepted unless MCP_DOMAINS is set; then it must be the main
+// domain or one of those, optionally preceded by a tenant name.
+func (c *Config) servesDomain(domain string) bool {
+	if c.servesBaseDomain(domain) {
+		return true
+	}
+	tenant, rest, found := strings.Cut(domain, ".")
+	if _, exists := c.Tenants[tenant]; found && exists {
+		return c.servesBaseDomain(rest)
+	}
+	return false
+}
+
+// servesBaseDomain reports whether domain is the main domain or one of MCP_DOMAINS, or any domain when MCP_DOMAINS is not set
+func (c *Config) servesBaseDomain(domain string) bool {
+	return c == nil || len(c.Domains) == 0 || domain == strings.ToLower(c.Domain) || slices.Contains(c.Domains, domain)
+}
+
+// HostServer returns the name of the server a request host selects, or "" when it selects none
+// A server's own hostnames are matched first. Otherwise {server}.mcp.{domain}
+// selects {server}; when MCP_DOMAINS is set, domain must be the main domain or
+// one of those. The name is not checked against the configured servers.
+func (c *Config) HostServer(host string) string {
+	host = hostName(host)
+
+	if c != nil {
+		for name, server := range c.MCPServers {
+			for _, hostname := range server.Hostnames {
+				if host == hostname {
+					return name
+				}
+			}
+		}
+	}
+
+	parts := strings.SplitN(host, ".", 3)
+	if len(parts) < 3 || parts[1] != "mcp" || parts[0] == "" {
+		return ""
+	}
+	if !c.servesDomain(parts[2]) {
+		return ""
+	}
+	return parts[0]
+}
+
+// HostTenant returns the tenant a {server}.mcp.{tenant}.{domain} host selects, or "" when it selects none
+func (c *Config) HostTenant(host string) string {
+	if c == nil || len(c.Tenants) == 0 {
+		return ""
+	}
+	parts := strings.SplitN(hostName(host), ".", 4)
+	if len(parts) < 4 || parts[1] != "mcp" {
+		return ""
+	}
+	if _, exists := c.Tenants[parts[2]]; !exists || !c.servesBaseDomain(parts[3]) {
+		return ""
+	}
+	return parts[2]
+}
+
+// PrincipalTenant returns the first tenant, by name, whose principals match principal
+// or whose clients match the OAuth client that was issued its token (clientID,
+// empty when unknown). It returns "" when the principal belongs to no tenant.
+func (c *Config) PrincipalTenant(principal, clientID string) string {
+	if c == nil {
+		return ""
+	}
+	names := make([]string, 0, len(c.Tenants))
+	for name := range c.Tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if c.TenantMember(name, principal, clientID) {
+			return name
+		}
+	}
+	return ""
+}
+
+// TenantMember reports whether a principal, or the OAuth client that was issued its token, belongs to a tenant
+func (c *Config) TenantMember(tenant, principal, clientID string) bool {
+	if c == nil {
+		return false
+	}
+	members := c.Tenants[tenant]
+	return (principal != "" && matchesPattern(members.Principals, principal)) || (clientID != "" && matchesPattern(members.Clients, clientID))
+}
+
+// TenantServes reports whether requests of a tenant may use a server
+// Requests of no tenant ("") may use the servers no tenant lists.
+func (c *Config) TenantServes(tenant, serverName string) bool {
+	if c == nil || len(c.Tenants) == 0 {
+		return true
+	}
+	if tenant != "" {
+		return matchesPattern(c.Tenants[tenant].Servers, serverName)
+	}
+	for _, other := range c.Tenants {
+		if matchesPattern(other.Servers, serverName) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesPattern reports whether name matches one of the glob patterns
+func matchesPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateSubdomain checks if a subdomain matches the expected format for MCP servers
 func (c *Config) ValidateSubdomain(host string) (string, bool) {
 	// Expected format: {server}.mcp.{domain}
@@ -0,0 +1,608 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeServerNames(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{
+		"notionApi":           {Command: "npx"},
+		"sequential_thinking": {Command: "npx"},
+		"My Files":            {Command: "npx", Slug: "files"},
+		"memory":              {Command: "npx"},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid configuration, got %v", err)
+	}
+
+	expected := map[string]string{
+		"notionapi":           "notionApi",
+		"sequential-thinking": "sequential_thinking",
+		"files":               "My Files",
+		"memory":              "",
+	}
+	if len(cfg.MCPServers) != len(expected) {
+		t.Fatalf("Expected %d servers, got %v", len(expected), cfg.MCPServers)
+	}
+	for name, displayName := range expected {
+		server, exists := cfg.MCPServers[name]
+		if !exists {
+			t.Errorf("Expected server %q after normalization", name)
+			continue
+		}
+		if server.DisplayName != displayName {
+			t.Errorf("Expected display name %q for %s, got %q", displayName, name, server.DisplayName)
+		}
+	}
+}
+
+func TestInvalidServerNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers map[string]MCPServer
+		errText string
+	}{
+		{"slash", map[string]MCPServer{"team/notes": {Command: "npx"}}, "set \"slug\""},
+		{"invalid slug", map[string]MCPServer{"notes": {Command: "npx", Slug: "Notes"}}, "lowercase letters"},
+		{"too long", map[string]MCPServer{strings.Repeat("a", 64): {Command: "npx"}}, "1-63"},
+		{"reserved", map[string]MCPServer{"Health": {Command: "npx"}}, "reserved"},
+		{"reserved dashboard", map[string]MCPServer{"ui": {Command: "npx"}}, "reserved"},
+		{"reserved probe", map[string]MCPServer{"ready": {Command: "npx"}}, "reserved"},
+		{"reserved slug", map[string]MCPServer{"stats": {Command: "npx", Slug: "metrics"}}, "reserved"},
+		{"reserved basePath", map[string]MCPServer{"notes": {Command: "npx", BasePath: "/selftest/notes"}}, "reserved"},
+		{"collision", map[string]MCPServer{"my_server": {Command: "npx"}, "my.server": {Command: "npx"}}, "both use the name \"my-server\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{MCPServers: tt.servers}
+			err := cfg.validate()
+			if err == nil || !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("Expected error containing %q, got %v", tt.errText, err)
+			}
+		})
+	}
+}
+
+func TestGetEndpointFormat(t *testing.T) {
+	cfg := &Config{
+		EndpointFormat: EndpointFormatObject,
+		MCPServers: map[string]MCPServer{
+			"memory":    {Command: "npx"},
+			"inspector": {Command: "npx", EndpointFormat: EndpointFormatString},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid configuration, got %v", err)
+	}
+
+	if got := cfg.GetEndpointFormat("memory"); got != EndpointFormatObject {
+		t.Errorf("Expected the global format for memory, got %q", got)
+	}
+	if got := cfg.GetEndpointFormat("inspector"); got != EndpointFormatString {
+		t.Errorf("Expected the server override for inspector, got %q", got)
+	}
+
+	cfg.MCPServers["memory"] = MCPServer{Command: "npx", EndpointFormat: "bare"}
+	if err := cfg.validate(); err == nil || !strings.Contains(err.Error(), "endpointFormat") {
+		t.Errorf("Expected invalid endpointFormat to be rejected, got %v", err)
+	}
+}
+
+func TestGetMethodTimeout(t *testing.T) {
+	cfg := &Config{
+		Timeouts: map[string]string{"tools/call": "60s", "initialize": "45s"},
+		MCPServers: map[string]MCPServer{
+			"memory":  {Command: "npx"},
+			"crawler": {Command: "npx", Timeouts: map[string]string{"tools/call": "300s"}},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid configuration, got %v", err)
+	}
+
+	if d, ok := cfg.GetMethodTimeout("crawler", "tools/call"); !ok || d != 300*time.Second {
+		t.Errorf("Expected the server override for crawler, got %v (%v)", d, ok)
+	}
+	if d, ok := cfg.GetMethodTimeout("crawler", "initialize"); !ok || d != 45*time.Second {
+		t.Errorf("Expected the global initialize timeout for crawler, got %v (%v)", d, ok)
+	}
+	if d, ok := cfg.GetMethodTimeout("memory", "tools/call"); !ok || d != time.Minute {
+		t.Errorf("Expected the global tools/call timeout for memory, got %v (%v)", d, ok)
+	}
+	if _, ok := cfg.GetMethodTimeout("memory", "tools/list"); ok {
+		t.Error("Expected no timeout for an unconfigured method")
+	}
+
+	cfg.MCPServers["memory"] = MCPServer{Command: "npx", Timeouts: map[string]string{"tools/call": "5 minutes"}}
+	if err := cfg.validate(); err == nil || !strings.Contains(err.Error(), "invalid timeout") {
+		t.Errorf("Expected an invalid timeout to be rejected, got %v", err)
+	}
+}
+
+func TestGetMessageSizeLimits(t *testing.T) {
+	cfg := &Config{
+		MaxRequestBytes:  1000,
+		MaxResponseBytes: 5000,
+		MCPServers: map[string]MCPServer{
+			"memory":  {Command: "npx"},
+			"crawler": {Command: "npx", MaxResponseBytes: 50000},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid configuration, got %v", err)
+	}
+
+	if got := cfg.GetMaxResponseBytes("crawler"); got != 50000 {
+		t.Errorf("Expected the server override for crawler, got %d", got)
+	}
+	if got := cfg.GetMaxResponseBytes("memory"); got != 5000 {
+		t.Errorf("Expected the global response limit for memory, got %d", got)
+	}
+	if got := cfg.GetMaxRequestBytes("crawler"); got != 1000 {
+		t.Errorf("Expected the global request limit for crawler, got %d", got)
+	}
+
+	cfg.MCPServers["memory"] = MCPServer{Command: "npx", MaxRequestBytes: -1}
+	if err := cfg.validate(); err == nil {
+		t.Error("Expected a negative limit to be rejected")
+	}
+}
+
+func TestValidateSandbox(t *testing.T) {
+	sandbox := &Sandbox{UID: 1001, EnvAllowlist: []string{"HTTPS_PROXY"}, NoNewPrivileges: true}
+	cfg := &Config{MCPServers: map[string]MCPServer{"memory": {Command: "npx", Sandbox: sandbox}}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid sandbox, got %v", err)
+	}
+	if sandbox.Group() != 1001 {
+		t.Errorf("Expected the group to default to the uid, got %d", sandbox.Group())
+	}
+
+	for _, invalid := range []*Sandbox{
+		{UID: -1},
+		{EnvAllowlist: []string{"PATH=/tmp"}},
+		{Wrapper: []string{"bwrap", ""}},
+	} {
+		cfg.MCPServers["memory"] = MCPServer{Command: "npx", Sandbox: invalid}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected sandbox %+v to be rejected", *invalid)
+		}
+	}
+}
+
+func TestValidateRecycle(t *testing.T) {
+	recycle := &Recycle{MaxAge: "24h", MaxRequests: 10000}
+	cfg := &Config{MCPServers: map[string]MCPServer{"memory": {Command: "npx", Recycle: recycle}}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid recycle policy, got %v", err)
+	}
+	if recycle.MaxAgeDuration() != 24*time.Hour {
+		t.Errorf("Expected a 24h max age, got %v", recycle.MaxAgeDuration())
+	}
+
+	for _, invalid := range []*Recycle{
+		{},
+		{MaxAge: "daily"},
+		{MaxAge: "-1h"},
+		{MaxRequests: -1},
+	} {
+		cfg.MCPServers["memory"] = MCPServer{Command: "npx", Recycle: invalid}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected recycle policy %+v to be rejected", *invalid)
+		}
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{
+		"Query DB": {Command: "npx", DependsOn: []string{"postgres"}},
+		"postgres": {Command: "npx", StartupProbe: &StartupProbe{Timeout: "2m"}},
+		"reports":  {Command: "npx", DependsOn: []string{"Query DB"}},
+		"audit":    {Command: "npx"},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid dependencies, got %v", err)
+	}
+	if dependsOn := cfg.MCPServers["reports"].DependsOn; len(dependsOn) != 1 || dependsOn[0] != "query-db" {
+		t.Errorf("Expected the dependency to be normalized, got %v", dependsOn)
+	}
+	if order := strings.Join(StartOrder(cfg.MCPServers), " "); order != "audit postgres query-db reports" {
+		t.Errorf("Unexpected start order: %s", order)
+	}
+	if probe := cfg.MCPServers["postgres"].StartupProbe; probe.TimeoutDuration() != 2*time.Minute || probe.IntervalDuration() != DefaultStartupProbeInterval {
+		t.Errorf("Unexpected startup probe durations: %v, %v", probe.TimeoutDuration(), probe.IntervalDuration())
+	}
+
+	for _, invalid := range []map[string]MCPServer{
+		{"a": {Command: "npx", DependsOn: []string{"a"}}},
+		{"a": {Command: "npx", DependsOn: []string{"missing"}}},
+		{"a": {Command: "npx", DependsOn: []string{"b"}}, "b": {Command: "npx", DependsOn: []string{"a"}}},
+		{"a": {Command: "npx", StartupProbe: &StartupProbe{Interval: "often"}}},
+	} {
+		cfg := &Config{MCPServers: invalid}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected servers %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{"files": {Command: "npx", Hooks: []Hook{
+		{Type: HookBlock, Tools: []string{"delete_*"}},
+		{Type: HookRewrite, Phase: HookPhaseRequest, Set: map[string]interface{}{"params.arguments.limit": 10}},
+		{Type: HookHeader, Headers: map[string]string{"Cache-Control": "no-store"}},
+		{Type: HookCommand, Command: "/opt/hooks/dlp", Phase: HookPhaseResponse, Timeout: "30s"},
+		{Type: HookHTTP, URL: "https://policy.example.com/check", Tools: []string{"deploy"}, FailOpen: true},
+		{Type: HookScan, Detectors: []string{"credit-card", ScanSecrets}, Patterns: map[string]string{"employee-id": `\bEMP-[0-9]{6}\b`}, Action: ScanBlock},
+	}}}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid hooks, got %v", err)
+	}
+
+	for _, invalid := range []Hook{
+		{Type: "patch"},
+		{Type: HookBlock},
+		{Type: HookBlock, Methods: []string{"[tools"}},
+		{Type: HookHeader},
+		{Type: HookRewrite},
+		{Type: HookRewrite, Phase: "both", Remove: []string{"result._meta"}},
+		{Type: HookRewrite, Remove: []string{"result..tools"}},
+		{Type: HookCommand},
+		{Type: HookCommand, Command: "/opt/hooks/dlp", Timeout: "soon"},
+		{Type: HookHTTP, URL: "policy.example.com/check"},
+		{Type: HookHTTP, URL: "https://policy.example.com/check", Phase: "both"},
+		{Type: HookScan},
+		{Type: HookScan, Detectors: []string{"ssn"}},
+		{Type: HookScan, Patterns: map[string]string{"ticket": "(unclosed"}},
+		{Type: HookScan, Detectors: []string{"jwt"}, Action: "quarantine"},
+	} {
+		cfg.MCPServers["files"] = MCPServer{Command: "npx", Hooks: []Hook{invalid}}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected hook %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{"files": {Command: "npx"}}, Policy: &Policy{
+		Default: PolicyDeny,
+		Rules: []PolicyRule{
+			{Effect: PolicyDeny, Tools: []string{"delete_*"}, Arguments: map[string]string{"path": "^/etc/"}},
+			{Effect: PolicyRequireApproval, Principals: []string{"oidc:*"}, Servers: []string{"files"}, Tools: []string{"write_*"}},
+			{Effect: PolicyAllow},
+		},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected a valid policy, got %v", err)
+	}
+
+	for _, invalid := range []Policy{
+		{Default: PolicyRequireApproval},
+		{Rules: []PolicyRule{{}}},
+		{Rules: []PolicyRule{{Effect: "audit"}}},
+		{Rules: []PolicyRule{{Effect: PolicyDeny, Principals: []string{"[oidc"}}}},
+		{Rules: []PolicyRule{{Effect: PolicyDeny, Arguments: map[string]string{"path": "(unclosed"}}}},
+		{Rules: []PolicyRule{{Effect: PolicyDeny, Arguments: map[string]string{"options..force": "true"}}}},
+	} {
+		invalid := invalid
+		cfg.Policy = &invalid
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected policy %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestValidateQuotas(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{"files": {Command: "npx"}}, Quotas: []UsageQuota{
+		{Principals: []string{"api-key:*"}, CallsPerDay: 1000},
+		{Principals: []string{"oidc:*"}, Servers: []string{"files"}, BytesPerDay: 1 << 30},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid quotas, got %v", err)
+	}
+
+	for _, invalid := range []UsageQuota{
+		{},
+		{CallsPerDay: -1},
+		{CallsPerDay: 10, BytesPerDay: -1},
+		{Principals: []string{"[api-key"}, CallsPerDay: 10},
+	} {
+		cfg.Quotas = []UsageQuota{invalid}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected quota %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestTenants(t *testing.T) {
+	cfg := &Config{
+		Domain:  "example.com",
+		Domains: []string{"example.org"},
+		MCPServers: map[string]MCPServer{
+			"github": {Command: "npx"}, "jira": {Command: "npx"}, "billing": {Command: "npx"}, "wiki": {Command: "npx"},
+		},
+		Tenants: map[string]Tenant{
+			"team-a": {Principals: []string{"api-key:team-a-*"}, Servers: []string{"github", "jira"}, SessionRoot: "/srv/team-a"},
+			"team-b": {Clients: []string{"client-b"}, Servers: []string{"billing"}, Quotas: []UsageQuota{{CallsPerDay: 100}}},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid tenants, got %v", err)
+	}
+
+	for host, want := range map[string][2]string{
+		"github.mcp.team-a.example.com": {"github", "team-a"},
+		"github.mcp.team-a.example.org": {"github", "team-a"},
+		"github.mcp.example.com":        {"github", ""},
+		"github.mcp.team-c.example.com": {"", ""},
+		"github.mcp.team-a.other.net":   {"", ""},
+	} {
+		if server, tenant := cfg.HostServer(host), cfg.HostTenant(host); server != want[0] || tenant != want[1] {
+			t.Errorf("Host %s: expected server %q of tenant %q, got %q of %q", host, want[0], want[1], server, tenant)
+		}
+	}
+
+	if tenant := cfg.PrincipalTenant("api-key:team-a-ci", ""); tenant != "team-a" {
+		t.Errorf("Expected the API key to belong to team-a, got %q", tenant)
+	}
+	if tenant := cfg.PrincipalTenant("oauth:0f3c", "client-b"); tenant != "team-b" {
+		t.Errorf("Expected the OAuth client to belong to team-b, got %q", tenant)
+	}
+	if tenant := cfg.PrincipalTenant("api-key:ops", ""); tenant != "" {
+		t.Errorf("Expected no tenant, got %q", tenant)
+	}
+
+	if !cfg.TenantServes("team-a", "jira") || cfg.TenantServes("team-a", "billing") || cfg.TenantServes("", "github") || !cfg.TenantServes("", "wiki") {
+		t.Error("Expected tenants to see their own servers, and others the servers no tenant lists")
+	}
+
+	for _, invalid := range []Tenant{
+		{},
+		{Servers: []string{"[github"}},
+		{Servers: []string{"github"}, SessionRoot: "sessions/team-a"},
+		{Servers: []string{"github"}, Quotas: []UsageQuota{{}}},
+	} {
+		cfg.Tenants = map[string]Tenant{"team-a": invalid}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected tenant %+v to be rejected", invalid)
+		}
+	}
+	cfg.Tenants = map[string]Tenant{"Team A": {Servers: []string{"github"}}}
+	if err := cfg.validate(); err == nil {
+		t.Error("Expected a tenant name that is not a DNS label to be rejected")
+	}
+}
+
+func TestValidateServerType(t *testing.T) {
+	inherit := true
+	cfg := &Config{MCPServers: map[string]MCPServer{
+		"fetch": {Type: TypeDocker, Image: "mcp/fetch", Volumes: []string{"/srv:/srv"}},
+		"tcp":   {Type: TypeTCP, Command: "server", Args: []string{"--port", "{PORT}"}},
+		"fixed": {Type: TypeTCP, Command: "server", Port: 8080, Mode: ModeShared},
+		"lsp":   {Command: "server", Framing: FramingContentLength},
+		"env":   {Command: "server", InheritEnv: new(bool), EnvAllowlist: []string{"HTTPS_PROXY"}},
+		"files": {Command: "server", Cwd: "{WORKSPACE}/repo"},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid docker and tcp servers, got %v", err)
+	}
+
+	for _, invalid := range []MCPServer{
+		{Type: TypeDocker},
+		{Command: "npx", Image: "mcp/fetch"},
+		{Type: "ssh", Command: "npx"},
+		{Type: TypeDocker, Image: "mcp/fetch", Sandbox: &Sandbox{Wrapper: []string{"bwrap"}}},
+		{Type: TypeTCP},
+		{Type: TypeTCP, Command: "server", Port: 70000},
+		{Type: TypeTCP, Command: "server", Port: 8080},
+		{Command: "server", Port: 8080, Mode: ModeShared},
+		{Command: "server", Framing: "xml"},
+		{Type: TypeSSE, URL: "https://mcp.example.com/sse", Framing: FramingNewline},
+		{Command: "server", EnvAllowlist: []string{"A=B"}},
+		{Type: TypeSSE, URL: "https://mcp.example.com/sse", EnvAllowlist: []string{"HTTPS_PROXY"}},
+		{Type: TypeDocker, Image: "mcp/fetch", InheritEnv: &inherit},
+		{Command: "server", InheritEnv: &inherit, Sandbox: &Sandbox{}},
+		{Command: "server", Cwd: "data"},
+		{Type: TypeDocker, Image: "mcp/fetch", Cwd: "/data"},
+		{Command: "server", Mode: ModeShared, Cwd: "/data/{SESSION_ID}"},
+	} {
+		cfg.MCPServers["fetch"] = invalid
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected server %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestValidateWorkspace(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{
+		"memory": {Command: "npx", Workspace: WorkspaceUser, Env: map[string]string{"MEMORY_FILE_PATH": "{WORKSPACE}/memory.json"}},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected a valid user workspace, got %v", err)
+	}
+
+	for _, invalid := range []MCPServer{
+		{Command: "npx", Workspace: "team"},
+		{Command: "npx", Workspace: WorkspaceUser, Mode: ModeShared},
+		{Command: "npx", Workspace: WorkspaceUser, WarmPool: 2},
+		{Command: "npx", Mode: ModePool, Args: []string{"{WORKSPACE}/notes"}},
+	} {
+		cfg.MCPServers["memory"] = invalid
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected server %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestValidateHeaderVariables(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{
+		"notes": {Command: "npx", Args: []string{"--tenant", "{TENANT}"}, HeaderVariables: map[string]string{"TENANT": "X-Tenant-Id"}},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid header variables, got %v", err)
+	}
+
+	for _, invalid := range []MCPServer{
+		{Command: "npx", HeaderVariables: map[string]string{"tenant": "X-Tenant-Id"}},
+		{Command: "npx", HeaderVariables: map[string]string{"USER": "X-User"}},
+		{Command: "npx", HeaderVariables: map[string]string{"TENANT": ""}},
+		{Command: "npx", HeaderVariables: map[string]string{"TENANT": "X-Tenant-Id"}, Mode: ModeShared},
+		{Command: "npx", Mode: ModeShared, Env: map[string]string{"NAME": "{USER}"}},
+	} {
+		cfg.MCPServers["notes"] = invalid
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected server %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestValidateAPIKeys(t *testing.T) {
+	cfg := &Config{
+		APIKeys:    []APIKey{{Name: "ci", Key: "global-key"}},
+		MCPServers: map[string]MCPServer{"memory": {Command: "npx", APIKeys: []APIKey{{Name: "agent", Key: "memory-key", RateLimit: 60}}}},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid API keys, got %v", err)
+	}
+
+	for _, invalid := range [][]APIKey{
+		{{Key: "no-name"}},
+		{{Name: "agent"}},
+		{{Name: "ci", Key: "duplicate-name"}},
+		{{Name: "limited", Key: "key", RateLimit: -1}},
+	} {
+		cfg.MCPServers["memory"] = MCPServer{Command: "npx", APIKeys: invalid}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected API keys %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestValidateWebhooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook Webhook
+		valid   bool
+	}{
+		{"all events", Webhook{URL: "https://hooks.example.com/mcp"}, true},
+		{"some events", Webhook{URL: "http://alerts:9000", Events: []string{WebhookServerCrash, WebhookOperationTimeout}}, true},
+		{"unknown event", Webhook{URL: "https://hooks.example.com/mcp", Events: []string{"server.exploded"}}, false},
+		{"not HTTP", Webhook{URL: "ftp://hooks.example.com"}, false},
+		{"no host", Webhook{URL: "https://"}, false},
+	}
+	for _, tt := range tests {
+		if err := validateWebhooks([]Webhook{tt.webhook}); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestValidateHostnames(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{
+		"notion": {Command: "npx", Hostnames: []string{"notion.example.org", "docs.tenant-b.org"}},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid hostnames, got %v", err)
+	}
+
+	for _, servers := range []map[string]MCPServer{
+		{"notion": {Command: "npx", Hostnames: []string{"Notion.example.org"}}},
+		{"notion": {Command: "npx", Hostnames: []string{"notion.example.org:443"}}},
+		{"notion": {Command: "npx", Hostnames: []string{"localhost"}}},
+		{
+			"notion": {Command: "npx", Hostnames: []string{"docs.example.org"}},
+			"memory": {Command: "npx", Hostnames: []string{"docs.example.org"}},
+		},
+	} {
+		cfg := &Config{MCPServers: servers}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected hostnames of %v to be rejected", servers)
+		}
+	}
+}
+
+func TestValidateBasePath(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{
+		"notion": {Command: "npx", BasePath: "/integrations/notion/v1"},
+		"memory": {Command: "npx", BasePath: "/integrations/memory"},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected valid base paths, got %v", err)
+	}
+	if name, basePath := cfg.BasePathServer("/integrations/notion/v1/sessions/abc"); name != "notion" || basePath != "/integrations/notion/v1" {
+		t.Errorf("Expected the notion base path to match, got %q %q", name, basePath)
+	}
+	if name, _ := cfg.BasePathServer("/integrations/notion/v10/sse"); name != "" {
+		t.Errorf("Expected base paths to match whole segments, got %q", name)
+	}
+
+	for _, servers := range []map[string]MCPServer{
+		{"notion": {Command: "npx", BasePath: "integrations/notion"}},
+		{"notion": {Command: "npx", BasePath: "/integrations/notion/"}},
+		{"notion": {Command: "npx", BasePath: "/integrations/../notion"}},
+		{"notion": {Command: "npx", BasePath: "/health/notion"}},
+		{"notion": {Command: "npx"}, "memory": {Command: "npx", BasePath: "/notion"}},
+		{
+			"notion": {Command: "npx", BasePath: "/integrations"},
+			"memory": {Command: "npx", BasePath: "/integrations/memory"},
+		},
+	} {
+		cfg := &Config{MCPServers: servers}
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected base paths of %v to be rejected", servers)
+		}
+	}
+}
+
+func TestTrustsProxy(t *testing.T) {
+	var defaults *Config
+	for addr, want := range map[string]bool{
+		"127.0.0.1:4321":       true,
+		"172.18.0.5:4321":      true,
+		"[::ffff:10.0.0.2]:80": true,
+		"203.0.113.7:4321":     false,
+		"not-an-address":       false,
+	} {
+		if got := defaults.TrustsProxy(addr); got != want {
+			t.Errorf("Default trust of %s: expected %v, got %v", addr, want, got)
+		}
+	}
+
+	cfg := &Config{TrustedProxies: parseTrustedProxies([]string{"203.0.113.0/24", "2001:db8::1", "bogus"})}
+	if !cfg.TrustsProxy("203.0.113.7:4321") || !cfg.TrustsProxy("[2001:db8::1]:443") || cfg.TrustsProxy("127.0.0.1:4321") {
+		t.Errorf("Expected only the configured proxies to be trusted, got %v", cfg.TrustedProxies)
+	}
+
+	cfg = &Config{TrustedProxies: parseTrustedProxies([]string{"none"})}
+	if cfg.TrustsProxy("127.0.0.1:4321") {
+		t.Error("Expected \"none\" to trust no proxy")
+	}
+}
+
+func TestStateEncryptionKeys(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "state.key")
+	os.WriteFile(keyFile, []byte("from-file\n"), 0600)
+
+	for _, tt := range []struct {
+		cfg     Config
+		want    []string
+		invalid bool
+	}{
+		{cfg: Config{}, want: nil},
+		{cfg: Config{StateEncryptionKey: "current", StateEncryptionPreviousKeys: []string{"old"}}, want: []string{"current", "old"}},
+		{cfg: Config{StateEncryptionKeyFile: keyFile}, want: []string{"from-file"}},
+		{cfg: Config{StateEncryptionKey: "current", StateEncryptionKeyFile: keyFile}, invalid: true},
+		{cfg: Config{StateEncryptionKeyFile: keyFile + ".missing"}, invalid: true},
+		{cfg: Config{StateEncryptionPreviousKeys: []string{"old"}}, invalid: true},
+	} {
+		keys, err := tt.cfg.StateEncryptionKeys()
+		if (err != nil) != tt.invalid || strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%+v: expected %v (invalid: %v), got %v (%v)", tt.cfg, tt.want, tt.invalid, keys, err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the content of one configuration file
+// Besides servers and timeouts, a file may include further files, given as paths
+// or globs relative to its own directory.
+type configFile struct {
+	MCPServers map[string]MCPServer `json:"mcpServers"`
+	Timeouts   map[string]string    `json:"timeouts"`
+	Include    []string             `json:"include"`
+}
+
+// configFileKeys are the top-level keys telling a full configuration file from a single server file
+var configFileKeys = []string{"mcpServers", "timeouts", "include"}
+
+// configExtensions are the files picked up from a configuration directory
+var configExtensions = map[string]bool{".json": true, ".yaml": true, ".yml": true}
+
+// fileLoader merges configuration files, remembering where each server came from
+type fileLoader struct {
+	seen    map[string]bool   // Files already merged, by absolute path
+	sources map[string]string // Server name -> file defining it
+}
+
+// loadFiles merges the configuration found at a file, directory or glob into c
+//
+// Files are JSON or YAML, by extension. A file is either a full configuration
+// with "mcpServers", or a single server named after the file, e.g.
+// servers/notion.yaml holding the notion server's command, args and env.
+// Servers defined twice are an error; later files override earlier timeouts.
+func (l *fileLoader) loadFiles(c *Config, location string) error {
+	paths, err := configPaths(location)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if l.seen[absolute] {
+			continue // Included twice, or by itself
+		}
+		l.seen[absolute] = true
+
+		file, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		for name, server := range file.MCPServers {
+			if origin, exists := l.sources[name]; exists {
+				return fmt.Errorf("server %q is defined in both %s and %s", name, origin, path)
+			}
+			if c.MCPServers == nil {
+				c.MCPServers = make(map[string]MCPServer)
+			}
+			c.MCPServers[name] = server
+			l.sources[name] = path
+		}
+		for method, timeout := range file.Timeouts {
+			if c.Timeouts == nil {
+				c.Timeouts = make(map[string]string)
+			}
+			c.Timeouts[method] = timeout
+		}
+
+		for _, include := range file.Include {
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(filepath.Dir(path), include)
+			}
+			if err := l.loadFiles(c, include); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configPaths expands a configuration location into files, in the order they are merged
+// A directory yields its JSON and YAML files sorted by name, skipping hidden ones.
+func configPaths(location string) ([]string, error) {
+	info, err := os.Stat(location)
+	if err == nil && !info.IsDir() {
+		return []string{location}, nil
+	}
+
+	var paths []string
+	switch {
+	case err == nil:
+		entries, err := os.ReadDir(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config directory: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && configExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+				paths = append(paths, filepath.Join(location, entry.Name()))
+			}
+		}
+	case strings.ContainsAny(location, "*?["):
+		matches, err := filepath.Glob(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config pattern %q: %w", location, err)
+		}
+		paths = matches
+	default:
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no config files found at %s", location)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// readConfigFile parses one JSON or YAML configuration file
+func readConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// YAML goes through JSON so both formats share the json field names
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		var document interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if data, err = json.Marshal(document); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	var file configFile
+	if !hasAnyKey(keys, configFileKeys) {
+		// A single server, named after its file
+		var server MCPServer
+		if err := json.Unmarshal(data, &server); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		file.MCPServers = map[string]MCPServer{name: server}
+		return &file, nil
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// hasAnyKey reports whether a decoded object has one of the keys
+func hasAnyKey(object map[string]json.RawMessage, keys []string) bool {
+	for _, key := range keys {
+		if _, exists := object[key]; exists {
+			return true
+		}
+	}
+	return false
+}
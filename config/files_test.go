@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFiles writes files under dir, creating subdirectories
+func writeConfigFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadConfigDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"config.json":          `{"mcpServers": {"memory": {"command": "npx", "args": ["-y", "@modelcontextprotocol/server-memory"]}}, "timeouts": {"tools/call": "60s"}, "include": ["servers/*.yaml"]}`,
+		"notion.yml":           "command: npx\nargs: [\"-y\", \"@notionhq/notion-mcp-server\"]\nenv:\n  NOTION_TOKEN: abc\n",
+		"servers/fetch.yaml":   "type: docker\nimage: mcp/fetch:latest\n",
+		"servers/search.yaml":  "mcpServers:\n  search:\n    type: streamable-http\n    url: https://search.internal/mcp\ntimeouts:\n  tools/call: 300s\n",
+		"README.md":            "not a config file",
+		".hidden.json":         `{"command": "ignored"}`,
+		"servers/ignored.json": `{"command": "not matched by the include"}`,
+	})
+
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Failed to load config directory: %v", err)
+	}
+	for _, name := range []string{"memory", "notion", "fetch", "search"} {
+		if _, exists := cfg.MCPServers[name]; !exists {
+			t.Errorf("Expected server %s to be loaded, got %v", name, cfg.MCPServers)
+		}
+	}
+	if len(cfg.MCPServers) != 4 {
+		t.Errorf("Expected 4 servers, got %d", len(cfg.MCPServers))
+	}
+	if cfg.MCPServers["notion"].Env["NOTION_TOKEN"] != "abc" || cfg.MCPServers["fetch"].Image != "mcp/fetch:latest" {
+		t.Errorf("Expected YAML servers parsed with their fields, got %+v", cfg.MCPServers)
+	}
+	if cfg.Timeouts["tools/call"] != "300s" {
+		t.Errorf("Expected the included file's timeout to win, got %s", cfg.Timeouts["tools/call"])
+	}
+
+	// A glob selects files directly
+	cfg, err = Load(filepath.Join(dir, "servers", "*.yaml"))
+	if err != nil || len(cfg.MCPServers) != 2 {
+		t.Errorf("Expected the two matched servers, got %v (%v)", cfg, err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"a.json": `{"mcpServers": {"memory": {"command": "npx"}}}`,
+		"b.yaml": "mcpServers:\n  memory:\n    command: uvx\n",
+	})
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "defined in both") {
+		t.Errorf("Expected a duplicate server to be rejected, got %v", err)
+	}
+
+	if _, err := Load(filepath.Join(dir, "*.toml")); err == nil || !strings.Contains(err.Error(), "no config files") {
+		t.Errorf("Expected an empty glob to be rejected, got %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+		t.Errorf("Expected a missing file to be rejected, got %v", err)
+	}
+}
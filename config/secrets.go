@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// secretsDir holds Docker secrets, read by ${secret:name} references
+var secretsDir = "/run/secrets"
+
+// referencePattern matches ${...} references in config values; $${ escapes a literal ${
+var referencePattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// variablePattern matches environment references, with an optional default: NAME or NAME:-default
+var variablePattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(:-(.*))?$`)
+
+// expandReferences resolves the references in server env values and headers
+// so secrets can stay out of config.json:
+//
+//	${VAR}            the proxy's environment variable VAR (an error when unset)
+//	${VAR:-default}   VAR, or default when unset or empty
+//	${file:/path}     the contents of a mounted file
+//	${secret:name}    the Docker secret /run/secrets/name
+//
+// File contents lose their trailing newline.
+func (c *Config) expandReferences() error {
+	for name, server := range c.MCPServers {
+		if err := server.expandReferences(); err != nil {
+			return fmt.Errorf("server %s: %w", name, err)
+		}
+		if server.Fallback != nil {
+			if err := server.Fallback.expandReferences(); err != nil {
+				return fmt.Errorf("server %s: fallback: %w", name, err)
+			}
+		}
+	}
+	for i, hook := range c.Webhooks {
+		secret, err := expandValue(hook.Secret)
+		if err != nil {
+			return fmt.Errorf("webhook %s: secret: %w", hook.URL, err)
+		}
+		c.Webhooks[i].Secret = secret
+	}
+	return nil
+}
+
+// expandReferences resolves the references in the server's env values and headers
+func (s *MCPServer) expandReferences() error {
+	for key, value := range s.Env {
+		expanded, err := expandValue(value)
+		if err != nil {
+			return fmt.Errorf("env %s: %w", key, err)
+		}
+		s.Env[key] = expanded
+	}
+	for key, value := range s.Headers {
+		expanded, err := expandValue(value)
+		if err != nil {
+			return fmt.Errorf("header %s: %w", key, err)
+		}
+		s.Headers[key] = expanded
+	}
+	return nil
+}
+
+// expandValue replaces every reference in a value
+func expandValue(value string) (string, error) {
+	var firstErr error
+	expanded := referencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		resolved, err := resolveReference(match[2 : len(match)-1])
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return resolved
+	})
+	return expanded, firstErr
+}
+
+// resolveReference returns the value a reference stands for
+func resolveReference(reference string) (string, error) {
+	switch {
+	case strings.HasPrefix(reference, "file:"):
+		return readReference(strings.TrimPrefix(reference, "file:"))
+	case strings.HasPrefix(reference, "secret:"):
+		name := strings.TrimPrefix(reference, "secret:")
+		if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			return "", fmt.Errorf("invalid secret name %q", name)
+		}
+		return readReference(filepath.Join(secretsDir, name))
+	}
+
+	parts := variablePattern.FindStringSubmatch(reference)
+	if parts == nil {
+		return "", fmt.Errorf("invalid reference ${%s}", reference)
+	}
+	if value := os.Getenv(parts[1]); value != "" {
+		return value, nil
+	}
+	if parts[2] != "" {
+		return parts[3], nil
+	}
+	if _, set := os.LookupEnv(parts[1]); set {
+		return "", nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set", parts[1])
+}
+
+// readReference reads a referenced file without its trailing newline
+func readReference(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
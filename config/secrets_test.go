@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandReferences(t *testing.T) {
+	dir := t.TempDir()
+	secretsDir = dir
+	defer func() { secretsDir = "/run/secrets" }()
+	os.WriteFile(filepath.Join(dir, "github_token"), []byte("ghp_secret\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "notion.key"), []byte("ntn_key"), 0600)
+	t.Setenv("NOTION_TOKEN", "ntn_env")
+	t.Setenv("EMPTY_VALUE", "")
+
+	cfg := &Config{MCPServers: map[string]MCPServer{
+		"github": {
+			Command: "npx",
+			Env: map[string]string{
+				"GITHUB_TOKEN": "${secret:github_token}",
+				"NOTION":       "Bearer ${NOTION_TOKEN}",
+				"KEY_FILE":     "${file:" + filepath.Join(dir, "notion.key") + "}",
+				"REGION":       "${REGION:-eu-west-1}",
+				"EMPTY":        "${EMPTY_VALUE}",
+				"LITERAL":      "$${NOT_EXPANDED} and file:plain",
+			},
+		},
+		"search": {
+			Type:    TypeStreamableHTTP,
+			URL:     "https://search.internal/mcp",
+			Headers: map[string]string{"Authorization": "Bearer ${secret:github_token}"},
+		},
+	}}
+	if err := cfg.expandReferences(); err != nil {
+		t.Fatalf("Failed to expand references: %v", err)
+	}
+
+	want := map[string]string{
+		"GITHUB_TOKEN": "ghp_secret",
+		"NOTION":       "Bearer ntn_env",
+		"KEY_FILE":     "ntn_key",
+		"REGION":       "eu-west-1",
+		"EMPTY":        "",
+		"LITERAL":      "${NOT_EXPANDED} and file:plain",
+	}
+	for key, value := range want {
+		if got := cfg.MCPServers["github"].Env[key]; got != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, got)
+		}
+	}
+	if got := cfg.MCPServers["search"].Headers["Authorization"]; got != "Bearer ghp_secret" {
+		t.Errorf("Expected the header expanded, got %q", got)
+	}
+
+	for value, reason := range map[string]string{
+		"${MISSING_VARIABLE}": "MISSING_VARIABLE is not set",
+		"${secret:../passwd}": "invalid secret name",
+		"${secret:missing}":   "failed to read",
+		"${not a variable}":   "invalid reference",
+	} {
+		cfg := &Config{MCPServers: map[string]MCPServer{"memory": {Command: "npx", Env: map[string]string{"VALUE": value}}}}
+		if err := cfg.expandReferences(); err == nil || !strings.Contains(err.Error(), reason) {
+			t.Errorf("Expected %s to fail with %q, got %v", value, reason, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Alert kinds
+const (
+	AlertUnhealthy    = "unhealthy"     // Server failed a health check after being healthy or unknown
+	AlertRestartLimit = "restart-limit" // Server is still failing but has used up its restarts
+	AlertRecovered    = "recovered"     // Server passed a health check after being unhealthy
+)
+
+// alertTimeout bounds each notifier call
+const alertTimeout = 10 * time.Second
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Alert is a server health change worth telling an operator about
+type Alert struct {
+	Kind             string    `json:"kind"`
+	Server           string    `json:"server"`
+	LastError        string    `json:"lastError,omitempty"`
+	ConsecutiveFails int       `json:"consecutiveFails"`
+	RestartCount     int       `json:"restartCount"`
+	Time             time.Time `json:"time"`
+}
+
+// Summary is a one-line description of the alert
+func (a Alert) Summary() string {
+	switch a.Kind {
+	case AlertRecovered:
+		return fmt.Sprintf("MCP server %s recovered", a.Server)
+	case AlertRestartLimit:
+		return fmt.Sprintf("MCP server %s hit its restart limit after %d restarts: %s", a.Server, a.RestartCount, a.LastError)
+	default:
+		return fmt.Sprintf("MCP server %s is unhealthy: %s", a.Server, a.LastError)
+	}
+}
+
+// Notifier sends health alerts to an external service
+type Notifier interface {
+	// Name identifies the notifier in logs
+	Name() string
+	// Notify delivers one alert
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// AddNotifier sends every alert to n from now on
+// It should be called before Start.
+func (hc *HealthChecker) AddNotifier(n Notifier) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.notifiers = append(hc.notifiers, n)
+}
+
+// alert sends an alert about a server to every notifier in the background
+// NOTE: This method must be called with hc.mu locked
+func (hc *HealthChecker) alert(kind string, health *ServerHealth, lastError string) {
+	if len(hc.notifiers) == 0 {
+		return
+	}
+
+	alert := Alert{
+		Kind:             kind,
+		Server:           health.Name,
+		LastError:        lastError,
+		ConsecutiveFails: health.ConsecutiveFails,
+		RestartCount:     health.RestartCount,
+		Time:             time.Now(),
+	}
+	for _, n := range hc.notifiers {
+		go func(n Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+			defer cancel()
+			if err := n.Notify(ctx, alert); err != nil {
+				hc.logger.Error("Failed to send %s alert for server %s to %s: %v", alert.Kind, alert.Server, n.Name(), err)
+				return
+			}
+			hc.logger.Info("Sent %s alert for server %s to %s", alert.Kind, alert.Server, n.Name())
+		}(n)
+	}
+}
+
+// SlackNotifier posts alerts to a Slack incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a notifier for a Slack incoming webhook URL
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{webhookURL: webhookURL, client: &http.Client{}}
+}
+
+// Name implements Notifier
+func (s *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// Notify implements Notifier
+func (s *SlackNotifier) Notify(ctx context.Context, alert Alert) error {
+	icon := ":red_circle:"
+	if alert.Kind == AlertRecovered {
+		icon = ":large_green_circle:"
+	}
+	text := fmt.Sprintf("%s %s\nConsecutive failed checks: %d, restarts: %d", icon, alert.Summary(), alert.ConsecutiveFails, alert.RestartCount)
+	return postJSON(ctx, s.client, s.webhookURL, map[string]string{"text": text})
+}
+
+// PagerDutyNotifier triggers and resolves PagerDuty incidents through the Events API v2
+//
+// Every alert for a server uses the same dedup key, so repeated alerts update
+// one incident and a recovery resolves it.
+type PagerDutyNotifier struct {
+	routingKey string
+	eventsURL  string
+	client     *http.Client
+}
+
+// NewPagerDutyNotifier creates a notifier for the integration key of a PagerDuty service
+func NewPagerDutyNotifier(routingKey string) *PagerDutyNotifier {
+	return &PagerDutyNotifier{routingKey: routingKey, eventsURL: pagerDutyEventsURL, client: &http.Client{}}
+}
+
+// Name implements Notifier
+func (p *PagerDutyNotifier) Name() string {
+	return "pagerduty"
+}
+
+// Notify implements Notifier
+func (p *PagerDutyNotifier) Notify(ctx context.Context, alert Alert) error {
+	event := map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    "remote-mcp-proxy/" + alert.Server,
+	}
+	if alert.Kind == AlertRecovered {
+		event["event_action"] = "resolve"
+	} else {
+		severity := "error"
+		if alert.Kind == AlertRestartLimit {
+			severity = "critical"
+		}
+		event["payload"] = map[string]interface{}{
+			"summary":   alert.Summary(),
+			"source":    "remote-mcp-proxy",
+			"component": alert.Server,
+			"severity":  severity,
+			"timestamp": alert.Time.Format(time.RFC3339),
+			"custom_details": map[string]interface{}{
+				"lastError":        alert.LastError,
+				"consecutiveFails": alert.ConsecutiveFails,
+				"restartCount":     alert.RestartCount,
+			},
+		}
+	}
+	return postJSON(ctx, p.client, p.eventsURL, event)
+}
+
+// postJSON POSTs body as JSON, failing on any status other than 2xx
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+// recordingNotifier collects the alerts it is sent
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recordingNotifier) Name() string { return "recording" }
+
+func (r *recordingNotifier) Notify(ctx context.Context, alert Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *recordingNotifier) kinds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kinds []string
+	for _, alert := range r.alerts {
+		kinds = append(kinds, alert.Kind)
+	}
+	return kinds
+}
+
+func TestHealthAlerts(t *testing.T) {
+	hc := NewHealthChecker(mcp.NewManager(map[string]config.MCPServer{}))
+	notifier := &recordingNotifier{}
+	hc.AddNotifier(notifier)
+
+	hc.updateHealth("memory", "healthy", 5, "")
+	hc.updateHealth("memory", "unhealthy", 0, "Server not running")
+	hc.updateHealth("memory", "unhealthy", 0, "Server not running") // No new alert while still unhealthy
+	hc.mu.Lock()
+	hc.healthStatus["memory"].RestartCount = hc.maxRestarts
+	hc.shouldRestartServer("memory")
+	hc.shouldRestartServer("memory") // The limit is alerted once
+	hc.mu.Unlock()
+	hc.updateHealth("memory", "healthy", 5, "")
+
+	want := []string{AlertUnhealthy, AlertRestartLimit, AlertRecovered}
+	deadline := time.Now().Add(time.Second)
+	for len(notifier.kinds()) < len(want) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	got := notifier.kinds()
+	if len(got) != len(want) {
+		t.Fatalf("Expected alerts %v, got %v", want, got)
+	}
+	seen := make(map[string]bool)
+	for _, kind := range got {
+		seen[kind] = true
+	}
+	for _, kind := range want {
+		if !seen[kind] {
+			t.Errorf("Expected a %s alert, got %v", kind, got)
+		}
+	}
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	var events []map[string]interface{}
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer endpoint.Close()
+
+	p := NewPagerDutyNotifier("routing-key")
+	p.eventsURL = endpoint.URL
+	for _, kind := range []string{AlertRestartLimit, AlertRecovered} {
+		if err := p.Notify(context.Background(), Alert{Kind: kind, Server: "memory", LastError: "timeout", RestartCount: 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(events) != 2 || events[0]["dedup_key"] != events[1]["dedup_key"] {
+		t.Fatalf("Expected two events for the same incident, got %v", events)
+	}
+	if events[0]["event_action"] != "trigger" || events[0]["payload"].(map[string]interface{})["severity"] != "critical" {
+		t.Errorf("Expected a critical trigger, got %v", events[0])
+	}
+	if events[1]["event_action"] != "resolve" {
+		t.Errorf("Expected a resolve, got %v", events[1])
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"remote-mcp-proxy/logger"
 	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/state"
 )
 
 type ServerHealth struct {
@@ -19,6 +20,8 @@ type ServerHealth struct {
 	ConsecutiveFails int       `json:"consecutiveFails"`
 	RestartCount     int       `json:"restartCount"`
 	LastError        string    `json:"lastError,omitempty"`
+
+	restartLimited bool // A restart-limit alert was sent and the server has not recovered since
 }
 
 type HealthChecker struct {
@@ -26,10 +29,18 @@ type HealthChecker struct {
 	healthStatus  map[string]*ServerHealth
 	mu            sync.RWMutex
 	checkInterval time.Duration
+	checkTimeout  time.Duration
+	maxConcurrent int
 	maxRestarts   int
 	restartWindow time.Duration
 	stopChan      chan bool
 	logger        *logger.Logger
+
+	// Servers with a check still running; a slow server is not checked again until it answers
+	inFlight   map[string]bool
+	inFlightMu sync.Mutex
+
+	notifiers []Notifier // Alerted when servers turn unhealthy, hit the restart limit or recover
 }
 
 func NewHealthChecker(mcpManager *mcp.Manager) *HealthChecker {
@@ -37,16 +48,26 @@ func NewHealthChecker(mcpManager *mcp.Manager) *HealthChecker {
 		mcpManager:    mcpManager,
 		healthStatus:  make(map[string]*ServerHealth),
 		checkInterval: 30 * time.Second, // Check every 30 seconds
+		checkTimeout:  10 * time.Second, // Per-server ping timeout
+		maxConcurrent: 5,                // Servers checked in parallel
 		maxRestarts:   3,                // Max 3 restarts per window
 		restartWindow: 5 * time.Minute,  // 5-minute window
 		stopChan:      make(chan bool),
 		logger:        logger.System(),
+		inFlight:      make(map[string]bool),
 	}
 }
 
 func (hc *HealthChecker) Start() {
 	hc.logger.Info("Starting MCP server health checker (interval: %v)", hc.checkInterval)
 
+	// Servers that failed to start are reported at once rather than after the first interval
+	for _, serverStatus := range hc.mcpManager.GetAllServers() {
+		if serverStatus.Error != "" {
+			hc.updateHealth(serverStatus.Name, "unhealthy", 0, notRunningError(serverStatus))
+		}
+	}
+
 	go func() {
 		ticker := time.NewTicker(hc.checkInterval)
 		defer ticker.Stop()
@@ -67,20 +88,103 @@ func (hc *HealthChecker) Stop() {
 	close(hc.stopChan)
 }
 
+// checkAllServers checks running servers concurrently with at most maxConcurrent
+// checks at a time. The whole cycle must finish within one check interval;
+// servers not reached by then are checked on the next cycle.
 func (hc *HealthChecker) checkAllServers() {
 	servers := hc.mcpManager.GetAllServers()
 
+	cycleCtx, cancel := context.WithTimeout(context.Background(), hc.checkInterval)
+	defer cancel()
+
+	slots := make(chan struct{}, hc.maxConcurrent)
+	var wg sync.WaitGroup
+	skipped := 0
+
 	for _, serverStatus := range servers {
 		if !serverStatus.Running {
-			hc.updateHealth(serverStatus.Name, "unhealthy", 0, "Server not running")
+			hc.updateHealth(serverStatus.Name, "unhealthy", 0, notRunningError(serverStatus))
 			continue
 		}
 
-		hc.checkServerHealth(serverStatus.Name)
+		select {
+		case slots <- struct{}{}:
+		case <-cycleCtx.Done():
+			skipped++
+			continue
+		}
+
+		if !hc.markInFlight(serverStatus.Name) {
+			hc.logger.Debug("Health check for server %s still in flight, skipping", serverStatus.Name)
+			<-slots
+			continue
+		}
+
+		wg.Add(1)
+		go func(serverName string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer hc.clearInFlight(serverName)
+			hc.checkServerHealth(cycleCtx, serverName)
+		}(serverStatus.Name)
+	}
+
+	// Don't hold up the next cycle for checks stuck past the deadline (e.g. in
+	// a restart); they stay in flight and their servers are skipped until done
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-cycleCtx.Done():
+	}
+
+	if skipped > 0 {
+		hc.logger.Warn("Health check cycle deadline (%v) reached, %d servers not checked", hc.checkInterval, skipped)
+	}
+}
+
+// notRunningError describes why a stopped server is unhealthy
+func notRunningError(serverStatus mcp.ServerStatus) string {
+	if serverStatus.Error != "" {
+		return "Failed to start: " + serverStatus.Error
+	}
+	return "Server not running"
+}
+
+// markInFlight records a check for serverName, returning false if one is already running
+func (hc *HealthChecker) markInFlight(serverName string) bool {
+	hc.inFlightMu.Lock()
+	defer hc.inFlightMu.Unlock()
+
+	if hc.inFlight[serverName] {
+		return false
 	}
+	hc.inFlight[serverName] = true
+	return true
+}
+
+func (hc *HealthChecker) clearInFlight(serverName string) {
+	hc.inFlightMu.Lock()
+	defer hc.inFlightMu.Unlock()
+	delete(hc.inFlight, serverName)
 }
 
-func (hc *HealthChecker) checkServerHealth(serverName string) {
+func (hc *HealthChecker) checkServerHealth(cycleCtx context.Context, serverName string) {
+	if hc.mcpManager.InMaintenance(serverName) {
+		hc.logger.Debug("Skipping health check for server %s in maintenance mode", serverName)
+		return
+	}
+
+	// Servers due for recycling are restarted here rather than pinged, so a
+	// recycle never overlaps with the restart of an unhealthy server
+	if reason, err := hc.mcpManager.RecycleIfDue(serverName); reason != "" {
+		hc.recordRecycle(serverName, reason, err)
+		return
+	}
+
 	server, exists := hc.mcpManager.GetServer(serverName)
 	if !exists {
 		hc.updateHealth(serverName, "unknown", 0, "Server not found")
@@ -88,7 +192,7 @@ func (hc *HealthChecker) checkServerHealth(serverName string) {
 	}
 
 	start := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(cycleCtx, hc.checkTimeout)
 	defer cancel()
 
 	// Send a simple ping message to check responsiveness
@@ -121,10 +225,12 @@ func (hc *HealthChecker) handleUnhealthyServer(serverName string, responseTime i
 
 	health := hc.getOrCreateHealth(serverName)
 	health.ConsecutiveFails++
+	hc.recordTransition(health, "unhealthy", errorMsg)
 	health.Status = "unhealthy"
 	health.LastCheck = time.Now()
 	health.ResponseTime = responseTime
 	health.LastError = errorMsg
+	hc.updateFailover(health)
 
 	hc.logger.Warn("Health check failed for server %s (consecutive fails: %d): %s",
 		serverName, health.ConsecutiveFails, errorMsg)
@@ -143,12 +249,17 @@ func (hc *HealthChecker) shouldRestartServer(serverName string) bool {
 	if now.Sub(health.LastCheck) < hc.restartWindow && health.RestartCount >= hc.maxRestarts {
 		hc.logger.Warn("Server %s hit restart limit (%d restarts in %v), skipping restart",
 			serverName, hc.maxRestarts, hc.restartWindow)
+		if !health.restartLimited {
+			health.restartLimited = true
+			hc.alert(AlertRestartLimit, health, health.LastError)
+		}
 		return false
 	}
 
 	// Reset restart count if outside window
 	if now.Sub(health.LastCheck) >= hc.restartWindow {
 		health.RestartCount = 0
+		health.restartLimited = false
 	}
 
 	return true
@@ -162,6 +273,20 @@ func (hc *HealthChecker) restartUnhealthyServer(serverName string) {
 	health := hc.getOrCreateHealth(serverName)
 	health.RestartCount++
 
+	incident := state.Incident{
+		Kind:    state.IncidentRestart,
+		Server:  serverName,
+		Actor:   "health-checker",
+		Action:  "restart",
+		Reason:  fmt.Sprintf("%d consecutive failed health checks: %s", health.ConsecutiveFails, health.LastError),
+		Success: err == nil,
+		Details: map[string]interface{}{"restartCount": health.RestartCount},
+	}
+	if err != nil {
+		incident.Details["error"] = err.Error()
+	}
+	hc.mcpManager.GetIncidentStore().RecordIncident(incident)
+
 	if err != nil {
 		hc.logger.Error("Failed to restart server %s: %v", serverName, err)
 		health.LastError = fmt.Sprintf("Restart failed: %v", err)
@@ -174,15 +299,41 @@ func (hc *HealthChecker) restartUnhealthyServer(serverName string) {
 	}
 }
 
+// recordRecycle records a restart made by a server's recycle policy
+// Recycles don't count towards the restart limit; a failed one counts as a failed check.
+func (hc *HealthChecker) recordRecycle(serverName, reason string, err error) {
+	incident := state.Incident{
+		Kind:    state.IncidentRestart,
+		Server:  serverName,
+		Actor:   "health-checker",
+		Action:  "recycle",
+		Reason:  reason,
+		Success: err == nil,
+	}
+	if err != nil {
+		incident.Details = map[string]interface{}{"error": err.Error()}
+	}
+	hc.mcpManager.GetIncidentStore().RecordIncident(incident)
+
+	if err != nil {
+		hc.logger.Error("Failed to recycle server %s: %v", serverName, err)
+		hc.handleUnhealthyServer(serverName, 0, fmt.Sprintf("Recycle failed: %v", err))
+		return
+	}
+	hc.logger.Info("Recycled server %s: %s", serverName, reason)
+}
+
 func (hc *HealthChecker) updateHealth(serverName, status string, responseTime int64, errorMsg string) {
 	hc.mu.Lock()
 	defer hc.mu.Unlock()
 
 	health := hc.getOrCreateHealth(serverName)
+	hc.recordTransition(health, status, errorMsg)
 	health.Status = status
 	health.LastCheck = time.Now()
 	health.ResponseTime = responseTime
 	health.LastError = errorMsg
+	hc.updateFailover(health)
 
 	if status == "healthy" {
 		health.ConsecutiveFails = 0
@@ -195,10 +346,12 @@ func (hc *HealthChecker) updateHealthQuietly(serverName, status string, response
 	defer hc.mu.Unlock()
 
 	health := hc.getOrCreateHealth(serverName)
+	hc.recordTransition(health, status, errorMsg)
 	health.Status = status
 	health.LastCheck = time.Now()
 	health.ResponseTime = responseTime
 	health.LastError = errorMsg
+	hc.updateFailover(health)
 
 	if status == "healthy" {
 		health.ConsecutiveFails = 0
@@ -209,6 +362,64 @@ func (hc *HealthChecker) updateHealthQuietly(serverName, status string, response
 	}
 }
 
+// recordTransition adds a server turning unhealthy, or recovering from it, to the incident
+// history and alerts the notifiers
+// NOTE: This method must be called with hc.mu locked, before health.Status is updated
+func (hc *HealthChecker) recordTransition(health *ServerHealth, status, errorMsg string) {
+	flapped := status == "unhealthy" || (health.Status == "unhealthy" && status == "healthy")
+	if health.Status == status || !flapped {
+		return
+	}
+
+	hc.mcpManager.GetIncidentStore().RecordIncident(state.Incident{
+		Kind:    state.IncidentHealth,
+		Server:  health.Name,
+		Actor:   "health-checker",
+		Action:  fmt.Sprintf("%s -> %s", health.Status, status),
+		Reason:  errorMsg,
+		Success: status == "healthy",
+	})
+
+	if status == "unhealthy" {
+		hc.alert(AlertUnhealthy, health, errorMsg)
+	} else {
+		health.restartLimited = false
+		hc.alert(AlertRecovered, health, health.LastError)
+	}
+}
+
+// updateFailover routes a server with a fallback to it while unhealthy, and back once healthy
+// NOTE: This method must be called with hc.mu locked, after health.Status is updated
+func (hc *HealthChecker) updateFailover(health *ServerHealth) {
+	if health.Status == "unknown" || !hc.mcpManager.HasFallback(health.Name) {
+		return
+	}
+
+	failover := health.Status == "unhealthy"
+	changed, err := hc.mcpManager.SetFailover(health.Name, failover)
+	if !changed && err == nil {
+		return
+	}
+
+	action := "failback"
+	if failover {
+		action = "failover"
+	}
+	incident := state.Incident{
+		Kind:    state.IncidentHealth,
+		Server:  health.Name,
+		Actor:   "health-checker",
+		Action:  action,
+		Reason:  health.LastError,
+		Success: err == nil,
+	}
+	if err != nil {
+		hc.logger.Error("Failed to %s server %s: %v", action, health.Name, err)
+		incident.Details = map[string]interface{}{"error": err.Error()}
+	}
+	hc.mcpManager.GetIncidentStore().RecordIncident(incident)
+}
+
 func (hc *HealthChecker) getOrCreateHealth(serverName string) *ServerHealth {
 	if health, exists := hc.healthStatus[serverName]; exists {
 		return health
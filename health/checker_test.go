@@ -0,0 +1,64 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+func TestSlowServerDoesNotDelayOthers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping health check test in short mode")
+	}
+
+	// "fast" answers every ping at once, the slow ones never answer
+	fast := config.MCPServer{Command: "sh", Args: []string{"-c", `while read line; do echo '{"jsonrpc":"2.0","id":"health_check","result":{}}'; done`}}
+	slow := config.MCPServer{Command: "sh", Args: []string{"-c", "cat > /dev/null"}}
+	manager := mcp.NewManager(map[string]config.MCPServer{"fast": fast, "slow-1": slow, "slow-2": slow})
+	if err := manager.StartAll(); err != nil {
+		t.Fatalf("Failed to start servers: %v", err)
+	}
+	defer manager.StopAll()
+
+	hc := NewHealthChecker(manager)
+	hc.checkTimeout = time.Second
+	hc.checkInterval = 10 * time.Second
+
+	start := time.Now()
+	cycleDone := make(chan struct{})
+	go func() {
+		hc.checkAllServers()
+		close(cycleDone)
+	}()
+
+	// The fast server's result is in while the slow ones are still being waited for
+	deadline := time.Now().Add(hc.checkTimeout / 2)
+	for {
+		if health, exists := hc.GetServerHealth("fast"); exists && health.Status == "healthy" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the fast server to be reported healthy within %v", hc.checkTimeout/2)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	<-cycleDone
+	elapsed := time.Since(start)
+	for _, name := range []string{"slow-1", "slow-2"} {
+		health, exists := hc.GetServerHealth(name)
+		if !exists || health.Status != "unhealthy" || health.ResponseTime < hc.checkTimeout.Milliseconds() {
+			t.Errorf("Expected %s to time out after %v, got %+v", name, hc.checkTimeout, health)
+		}
+	}
+
+	// The slow servers timed out side by side, not one after the other
+	if elapsed >= 2*hc.checkTimeout {
+		t.Errorf("Expected the slow checks to run in parallel, the cycle took %v", elapsed)
+	}
+	if health, _ := hc.GetServerHealth("fast"); health.ResponseTime >= hc.checkTimeout.Milliseconds() {
+		t.Errorf("Expected the fast server's response time not to include the slow ones, got %dms", health.ResponseTime)
+	}
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Lifecycle phases announced on stdout
+const (
+	PhaseConfigLoaded   = "config-loaded"
+	PhaseServersStarted = "servers-started"
+	PhaseReady          = "ready"
+	PhaseDraining       = "draining"
+	PhaseStopped        = "stopped"
+)
+
+var (
+	lifecycleOutput io.Writer = os.Stdout
+	lifecycleMu     sync.Mutex
+)
+
+// SetLifecycleOutput redirects lifecycle events (stdout by default) and returns the previous writer
+func SetLifecycleOutput(w io.Writer) io.Writer {
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+
+	previous := lifecycleOutput
+	lifecycleOutput = w
+	return previous
+}
+
+// Lifecycle writes a machine-readable startup/shutdown marker as one JSON line
+//
+// Unlike log messages it is written regardless of LOG_LEVEL and log files, so
+// orchestrators and wrapper scripts can wait for a phase, e.g.
+// {"phase":"ready","time":"...","type":"lifecycle"}.
+func Lifecycle(phase string, fields map[string]interface{}) {
+	event := map[string]interface{}{}
+	for key, value := range fields {
+		event[key] = value
+	}
+	event["type"] = "lifecycle"
+	event["phase"] = phase
+	event["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+	lifecycleOutput.Write(append(line, '\n'))
+}
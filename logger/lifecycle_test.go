@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLifecycle(t *testing.T) {
+	var out strings.Builder
+	previous := SetLifecycleOutput(&out)
+	defer SetLifecycleOutput(previous)
+
+	Lifecycle(PhaseServersStarted, map[string]interface{}{"started": 2, "total": 3, "phase": "ignored"})
+	Lifecycle(PhaseReady, nil)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one line per event, got %q", out.String())
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", lines[0], err)
+	}
+	if event["type"] != "lifecycle" || event["phase"] != PhaseServersStarted || event["started"] != float64(2) || event["total"] != float64(3) || event["time"] == nil {
+		t.Errorf("Unexpected servers-started event: %v", event)
+	}
+	if !strings.Contains(lines[1], `"phase":"ready"`) {
+		t.Errorf("Expected the ready event, got %q", lines[1])
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,24 +27,37 @@ func (l LogLevel) String() string {
 }
 
 func ParseLogLevel(level string) LogLevel {
+	if parsed, ok := LookupLogLevel(level); ok {
+		return parsed
+	}
+	return INFO
+}
+
+// LookupLogLevel parses a level name, reporting whether it is valid
+func LookupLogLevel(level string) (LogLevel, bool) {
 	switch strings.ToUpper(level) {
 	case "TRACE":
-		return TRACE
+		return TRACE, true
 	case "DEBUG":
-		return DEBUG
+		return DEBUG, true
 	case "INFO":
-		return INFO
+		return INFO, true
 	case "WARN":
-		return WARN
+		return WARN, true
 	case "ERROR":
-		return ERROR
+		return ERROR, true
 	default:
-		return INFO
+		return INFO, false
 	}
 }
 
+// MarshalText writes the level's name, e.g. in JSON responses
+func (l LogLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
 type Logger struct {
-	level              LogLevel
+	level              atomic.Int32 // LogLevel, changed at runtime by SetLevel
 	logger             *log.Logger
 	file               *os.File
 	filename           string
@@ -56,13 +70,15 @@ type Logger struct {
 	healthCheckCounter int
 	lastHealthLog      time.Time
 	sessionID          string // Session ID for session-aware logging
+	server             string // MCP server the logger belongs to ("" for the system logger)
 }
 
 type Config struct {
 	Level     LogLevel
 	Filename  string
 	Retention time.Duration
-	SessionID string // Optional session ID for MCP loggers
+	SessionID string // Optional session ID for MCP loggers, written as session=<id> on every line
+	Server    string // MCP server of MCP loggers
 }
 
 func New(config Config) (*Logger, error) {
@@ -86,7 +102,6 @@ func New(config Config) (*Logger, error) {
 
 	// Use shorter timestamp format (only time, not date)
 	logger := &Logger{
-		level:              config.Level,
 		logger:             log.New(multiWriter, "", log.Ltime|log.Lmicroseconds),
 		file:               file,
 		filename:           datedFilename,
@@ -97,7 +112,9 @@ func New(config Config) (*Logger, error) {
 		healthCheckCounter: 0,
 		lastHealthLog:      time.Time{},
 		sessionID:          config.SessionID,
+		server:             config.Server,
 	}
+	logger.level.Store(int32(config.Level))
 
 	// Log startup message with date context
 	logger.logger.Printf("[INFO] === LOG SESSION START %s ===", now.Format("2006-01-02 15:04:05"))
@@ -119,16 +136,38 @@ func (l *Logger) Close() error {
 	return nil
 }
 
+// SessionField is how a session ID appears on the lines of session loggers
+func SessionField(sessionID string) string {
+	return "session=" + sessionID
+}
+
+// Level returns the lowest level written
+func (l *Logger) Level() LogLevel {
+	return LogLevel(l.level.Load())
+}
+
+// SetLevel changes the lowest level written, taking effect with the next message
+func (l *Logger) SetLevel(level LogLevel) {
+	l.level.Store(int32(level))
+}
+
+// Filename returns the path of the file this logger writes to
+func (l *Logger) Filename() string {
+	return l.filename
+}
+
 func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
-	if level < l.level {
+	if level < l.Level() {
 		return
 	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	// Mask secrets before anything else sees the message
+	message := Redact(fmt.Sprintf(format, args...))
+
 	// Smart content-based log level detection
-	message := fmt.Sprintf(format, args...)
 	adjustedLevel := l.detectLogLevel(message, level)
 
 	// Health check summarization to reduce token usage
@@ -142,12 +181,10 @@ func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
 	// Remove redundant prefixes (INFO: INFO becomes just INFO)
 	message = l.cleanMessage(message)
 
-	// Build log prefix with session ID if available
-	var prefix string
+	// Build log prefix with session ID if available, as a field /logs/sessions can filter on
+	prefix := fmt.Sprintf("[%s] ", adjustedLevel.String())
 	if l.sessionID != "" {
-		prefix = fmt.Sprintf("%s [%s] ", l.sessionID, adjustedLevel.String())
-	} else {
-		prefix = fmt.Sprintf("[%s] ", adjustedLevel.String())
+		prefix += SessionField(l.sessionID) + " "
 	}
 
 	l.logger.Print(prefix + message)
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 )
@@ -17,11 +16,23 @@ type Manager struct {
 	mcpLevel        LogLevel
 	systemRetention time.Duration
 	mcpRetention    time.Duration
+	serverLevels    map[string]LogLevel // Per-server overrides of mcpLevel, by base server name
+	traceSaved      *Levels             // Levels before ToggleTrace enabled TRACE
+	revert          *time.Timer         // Restores the levels replaced by SetLevelsFor
+	revertAt        time.Time
+}
+
+// Levels is a snapshot of the log levels in effect
+type Levels struct {
+	System  LogLevel            `json:"system"`
+	MCP     LogLevel            `json:"mcp"`               // Default for MCP servers
+	Servers map[string]LogLevel `json:"servers,omitempty"` // Per-server overrides
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		mcpLoggers: make(map[string]*Logger),
+		mcpLoggers:   make(map[string]*Logger),
+		serverLevels: make(map[string]LogLevel),
 	}
 }
 
@@ -59,6 +70,11 @@ func (m *Manager) Initialize() error {
 		return fmt.Errorf("invalid LOG_RETENTION_MCP: %w", err)
 	}
 
+	// Secret masking, set up before the first line is written
+	if err := configureRedaction(); err != nil {
+		return fmt.Errorf("invalid LOG_REDACT_PATTERNS: %w", err)
+	}
+
 	// Initialize system logger
 	systemConfig := Config{
 		Level:     m.systemLevel,
@@ -79,8 +95,23 @@ func (m *Manager) GetSystemLogger() *Logger {
 }
 
 func (m *Manager) GetMCPLogger(serverName string) (*Logger, error) {
+	return m.getMCPLogger(serverName, "")
+}
+
+// GetSessionLogger returns the logger of a session's instance of an MCP server
+// It writes to the server's file, with the session ID on every line.
+func (m *Manager) GetSessionLogger(serverName, sessionID string) (*Logger, error) {
+	return m.getMCPLogger(serverName, sessionID)
+}
+
+func (m *Manager) getMCPLogger(serverName, sessionID string) (*Logger, error) {
+	key := serverName
+	if sessionID != "" {
+		key = serverName + "-" + sessionID
+	}
+
 	m.mu.RLock()
-	logger, exists := m.mcpLoggers[serverName]
+	logger, exists := m.mcpLoggers[key]
 	m.mu.RUnlock()
 
 	if exists {
@@ -91,38 +122,150 @@ func (m *Manager) GetMCPLogger(serverName string) (*Logger, error) {
 	defer m.mu.Unlock()
 
 	// Double-check after acquiring write lock
-	if logger, exists := m.mcpLoggers[serverName]; exists {
+	if logger, exists := m.mcpLoggers[key]; exists {
 		return logger, nil
 	}
 
-	// Extract session ID and base server name from server name (format: servername-sessionid)
-	sessionID := ""
-	baseServerName := serverName
-	if parts := strings.Split(serverName, "-"); len(parts) >= 2 {
-		// First part is the base server name (e.g., memory, filesystem, etc.)
-		baseServerName = parts[0]
-		// Join all parts after the first one as session ID (e.g., memory-test-new -> test-new)
-		sessionID = strings.Join(parts[1:], "-")
-	}
-
-	// Create new MCP logger using ONLY base server name for filename (no session ID)
-	filename := filepath.Join("/app/logs", fmt.Sprintf("mcp-%s.log", baseServerName))
+	// Create new MCP logger using ONLY the server name for filename (no session ID)
+	filename := filepath.Join("/app/logs", fmt.Sprintf("mcp-%s.log", serverName))
 	config := Config{
-		Level:     m.mcpLevel,
+		Level:     m.serverLevelLocked(serverName),
 		Filename:  filename,
 		Retention: m.mcpRetention,
 		SessionID: sessionID,
+		Server:    serverName,
 	}
 
 	logger, err := New(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create MCP logger for %s: %w", serverName, err)
+		return nil, fmt.Errorf("failed to create MCP logger for %s: %w", key, err)
 	}
 
-	m.mcpLoggers[serverName] = logger
+	m.mcpLoggers[key] = logger
 	return logger, nil
 }
 
+// SessionLogFiles returns the log files a session's MCP servers write to, by server name
+func (m *Manager) SessionLogFiles(sessionID string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	files := make(map[string]string)
+	for _, logger := range m.mcpLoggers {
+		if logger.sessionID == sessionID {
+			files[logger.server] = logger.Filename()
+		}
+	}
+	return files
+}
+
+// serverLevelLocked returns the level of a server's loggers: its override, or the MCP default
+func (m *Manager) serverLevelLocked(server string) LogLevel {
+	if level, exists := m.serverLevels[server]; exists {
+		return level
+	}
+	return m.mcpLevel
+}
+
+// Levels returns the log levels in effect
+func (m *Manager) Levels() Levels {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	levels := Levels{System: m.systemLevel, MCP: m.mcpLevel, Servers: make(map[string]LogLevel)}
+	for server, level := range m.serverLevels {
+		levels.Servers[server] = level
+	}
+	return levels
+}
+
+// SetLevels changes log levels at runtime, without restarting or reopening files
+// The MCP default applies to servers without an override; Servers replaces the
+// overrides entirely, so Levels() output can be passed back to restore it.
+func (m *Manager) SetLevels(levels Levels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.revert != nil {
+		m.revert.Stop()
+		m.revert, m.revertAt = nil, time.Time{}
+	}
+	m.systemLevel = levels.System
+	if m.systemLogger != nil {
+		m.systemLogger.SetLevel(levels.System)
+	}
+	m.mcpLevel = levels.MCP
+	m.serverLevels = make(map[string]LogLevel)
+	for server, level := range levels.Servers {
+		m.serverLevels[server] = level
+	}
+	for _, logger := range m.mcpLoggers {
+		logger.SetLevel(m.serverLevelLocked(logger.server))
+	}
+}
+
+// SetLevelsFor applies levels for d, then restores the levels in effect before
+// A later SetLevels, SetLevelsFor or ToggleTrace cancels the restore.
+func (m *Manager) SetLevelsFor(levels Levels, d time.Duration) {
+	previous := m.Levels()
+	m.SetLevels(levels)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revertAt = time.Now().Add(d)
+	m.revert = time.AfterFunc(d, func() {
+		m.SetLevels(previous)
+		if m.systemLogger != nil {
+			m.systemLogger.Info("Log levels restored after %v", d)
+		}
+	})
+}
+
+// RevertAt returns when levels set by SetLevelsFor are restored (zero when they are not)
+func (m *Manager) RevertAt() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.revertAt
+}
+
+// ToggleTrace switches every logger to TRACE, or back to the previous levels when already toggled
+// It reports whether TRACE is now enabled.
+func (m *Manager) ToggleTrace() bool {
+	m.mu.Lock()
+	saved := m.traceSaved
+	m.traceSaved = nil
+	m.mu.Unlock()
+
+	if saved != nil {
+		m.SetLevels(*saved)
+		return false
+	}
+	current := m.Levels()
+	m.SetLevels(Levels{System: TRACE, MCP: TRACE})
+	m.mu.Lock()
+	m.traceSaved = &current
+	m.mu.Unlock()
+	return true
+}
+
+// SystemLogFile returns the path of the current system log file
+func (m *Manager) SystemLogFile() (string, bool) {
+	if m.systemLogger == nil {
+		return "", false
+	}
+	return m.systemLogger.Filename(), true
+}
+
+// MCPLogFile returns the path of the log file used by an MCP server's logger
+func (m *Manager) MCPLogFile(serverName string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	logger, exists := m.mcpLoggers[serverName]
+	if !exists {
+		return "", false
+	}
+	return logger.Filename(), true
+}
+
 func (m *Manager) Close() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -167,3 +310,8 @@ func System() *Logger {
 func MCP(serverName string) (*Logger, error) {
 	return GetManager().GetMCPLogger(serverName)
 }
+
+// MCPSession returns the logger of a session's instance of an MCP server
+func MCPSession(serverName, sessionID string) (*Logger, error) {
+	return GetManager().GetSessionLogger(serverName, sessionID)
+}
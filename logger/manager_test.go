@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetLevels(t *testing.T) {
+	dir := t.TempDir()
+	newLogger := func(name string, level LogLevel) *Logger {
+		t.Helper()
+		l, err := New(Config{Level: level, Filename: filepath.Join(dir, name+".log"), Server: name, SessionID: "abc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		return l
+	}
+
+	m := NewManager()
+	m.systemLevel, m.mcpLevel = INFO, DEBUG
+	m.systemLogger = newLogger("system", INFO)
+	m.mcpLoggers["memory-abc"] = newLogger("memory", DEBUG)
+	m.mcpLoggers["files-abc"] = newLogger("files", DEBUG)
+
+	m.SetLevels(Levels{System: WARN, MCP: INFO, Servers: map[string]LogLevel{"memory": TRACE}})
+	for name, tt := range map[string]struct {
+		logger *Logger
+		want   LogLevel
+	}{
+		"system":          {m.systemLogger, WARN},
+		"server override": {m.mcpLoggers["memory-abc"], TRACE},
+		"mcp default":     {m.mcpLoggers["files-abc"], INFO},
+	} {
+		if got := tt.logger.Level(); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", name, tt.want, got)
+		}
+	}
+
+	// Toggling TRACE twice restores the levels
+	if !m.ToggleTrace() || m.systemLogger.Level() != TRACE || m.mcpLoggers["files-abc"].Level() != TRACE {
+		t.Error("Expected ToggleTrace to enable TRACE everywhere")
+	}
+	if m.ToggleTrace() || m.systemLogger.Level() != WARN || m.mcpLoggers["memory-abc"].Level() != TRACE {
+		t.Errorf("Expected ToggleTrace to restore the levels, got %+v", m.Levels())
+	}
+
+	// Temporary levels are restored after their duration
+	m.SetLevelsFor(Levels{System: TRACE, MCP: TRACE}, 20*time.Millisecond)
+	if m.RevertAt().IsZero() || m.systemLogger.Level() != TRACE {
+		t.Fatal("Expected temporary TRACE logging")
+	}
+	deadline := time.Now().Add(time.Second)
+	for m.systemLogger.Level() != WARN && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if m.systemLogger.Level() != WARN || !m.RevertAt().IsZero() {
+		t.Errorf("Expected the levels to be restored, got %+v", m.Levels())
+	}
+}
+
+func TestSessionLogs(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "mcp-memory.log")
+	m := NewManager()
+	for _, sessionID := range []string{"session-a", "session-b"} {
+		l, err := New(Config{Level: INFO, Filename: filename, Server: "memory", SessionID: sessionID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		m.mcpLoggers["memory-"+sessionID] = l
+	}
+
+	for i := 0; i < 3; i++ {
+		m.mcpLoggers["memory-session-a"].Info("call %d", i)
+		m.mcpLoggers["memory-session-b"].Info("other call %d", i)
+	}
+
+	files := m.SessionLogFiles("session-a")
+	if len(files) != 1 || files["memory"] != m.mcpLoggers["memory-session-a"].Filename() {
+		t.Fatalf("Expected the memory log file, got %v", files)
+	}
+
+	lines, err := GrepFile(files["memory"], SessionField("session-a"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "call 1") || !strings.HasSuffix(lines[1], "call 2") {
+		t.Errorf("Expected the last two lines of session-a, got %q", lines)
+	}
+}
@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedMask replaces every secret in log messages
+const redactedMask = "[REDACTED]"
+
+// minRedactedValueLength keeps short values such as "1" or "true" from being masked everywhere
+const minRedactedValueLength = 6
+
+// DefaultRedactedFields are masked wherever they appear as a JSON key, key=value pair or header
+// A field matches names containing it, ignoring case, so "token" also covers "access_token".
+var DefaultRedactedFields = []string{
+	"authorization", "password", "passwd", "secret", "token", "api_key", "apikey", "api-key",
+	"cookie", "credential", "private_key", "code_verifier",
+}
+
+// defaultRedactedPatterns match well-known key formats wherever they appear
+var defaultRedactedPatterns = []string{
+	`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,         // JWTs
+	`\bsk-[A-Za-z0-9_-]{16,}`,                                     // OpenAI and Anthropic keys
+	`\b(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})`, // GitHub tokens
+	`\bAKIA[0-9A-Z]{16}\b`,                                        // AWS access key IDs
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,                              // Slack tokens
+	`\bAIza[0-9A-Za-z_-]{35}`,                                     // Google API keys
+	`\bmcp_[0-9a-f]{48}\b`,                                        // Proxy API keys
+}
+
+// redactionRule replaces the matches of a pattern
+type redactionRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// redactor masks secrets in log messages before they are written
+type redactor struct {
+	enabled  bool
+	fields   []string
+	rules    []redactionRule
+	values   map[string]bool
+	replacer *strings.Replacer // Masks values; nil when there are none
+	mu       sync.RWMutex
+}
+
+// redaction is shared by every logger so values registered once are masked everywhere
+var redaction = newRedactor()
+
+func newRedactor() *redactor {
+	r := &redactor{enabled: true, values: make(map[string]bool)}
+	if err := r.configure(true, nil, nil); err != nil {
+		panic(err) // The defaults always compile
+	}
+	return r
+}
+
+// configure sets which fields and patterns are masked, on top of the defaults
+func (r *redactor) configure(enabled bool, fields, patterns []string) error {
+	fields = append(append([]string{}, DefaultRedactedFields...), fields...)
+	var quoted []string
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			quoted = append(quoted, regexp.QuoteMeta(strings.ToLower(field)))
+		}
+	}
+	names := strings.Join(quoted, "|")
+
+	rules := []redactionRule{
+		// "field": "value" in JSON
+		{regexp.MustCompile(`(?i)("[^"]*(?:` + names + `)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`), `${1}"` + redactedMask + `"`},
+		// field=value, field: value and Go's map[field:value]
+		{regexp.MustCompile(`(?i)\b([\w-]*(?:` + names + `)[\w-]*(?:=|:\s*))\[?(?:(?:Bearer|Basic)\s+)?[^\s&,;"'\]]+`), "${1}" + redactedMask},
+		// Authorization header values anywhere else
+		{regexp.MustCompile(`(?i)\b((?:Bearer|Basic)\s+)[A-Za-z0-9._~+/=-]+`), "${1}" + redactedMask},
+	}
+	for _, pattern := range append(append([]string{}, defaultRedactedPatterns...), patterns...) {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		rules = append(rules, redactionRule{compiled, redactedMask})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enabled = enabled
+	r.fields = fields
+	r.rules = rules
+	return nil
+}
+
+// addValues masks literal values from now on
+func (r *redactor) addValues(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, value := range values {
+		if len(value) >= minRedactedValueLength {
+			r.values[value] = true
+		}
+	}
+
+	// Longest first, so a value containing another is masked whole
+	sorted := make([]string, 0, len(r.values))
+	for value := range r.values {
+		sorted = append(sorted, value)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, value := range sorted {
+		pairs = append(pairs, value, redactedMask)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// secretName reports whether a variable or field name looks like it holds a secret
+func (r *redactor) secretName(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name = strings.ToLower(name)
+	for _, field := range r.fields {
+		if field != "" && strings.Contains(name, strings.ToLower(field)) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact masks the secrets in message
+func (r *redactor) redact(message string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.enabled {
+		return message
+	}
+	if r.replacer != nil {
+		message = r.replacer.Replace(message)
+	}
+	for _, rule := range r.rules {
+		message = rule.pattern.ReplaceAllString(message, rule.replacement)
+	}
+	return message
+}
+
+// Redact masks secrets in a message the way log files do
+func Redact(message string) string {
+	return redaction.redact(message)
+}
+
+// RedactValues masks literal values, such as configured API keys, in every log message
+// Values shorter than six characters are ignored.
+func RedactValues(values ...string) {
+	redaction.addValues(values...)
+}
+
+// RedactEnv masks the values of variables whose names look secret, such as GITHUB_TOKEN
+func RedactEnv(env map[string]string) {
+	var values []string
+	for name, value := range env {
+		if redaction.secretName(name) {
+			values = append(values, value)
+		}
+	}
+	redaction.addValues(values...)
+}
+
+// configureRedaction reads LOG_REDACT, LOG_REDACT_FIELDS and LOG_REDACT_PATTERNS
+// The proxy's own secret environment variables are masked too.
+func configureRedaction() error {
+	enabled := !strings.EqualFold(os.Getenv("LOG_REDACT"), "false")
+	fields := strings.Split(os.Getenv("LOG_REDACT_FIELDS"), ",")
+	patterns := strings.Split(os.Getenv("LOG_REDACT_PATTERNS"), ",")
+	if err := redaction.configure(enabled, fields, patterns); err != nil {
+		return err
+	}
+
+	env := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			env[name] = value
+		}
+	}
+	RedactEnv(env)
+	return nil
+}
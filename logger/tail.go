@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// tailChunkSize is how much of the file is read per step when scanning backwards
+const tailChunkSize = 32 * 1024
+
+// TailFile returns the last n lines of a file and the file size they were read at.
+// The size can be used as the starting offset when following the file.
+func TailFile(filename string, n int) ([]string, int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+
+	// Read backwards until enough newlines have been seen
+	var data []byte
+	offset := size
+	for offset > 0 && bytes.Count(data, []byte{'\n'}) <= n {
+		readSize := int64(tailChunkSize)
+		if offset < readSize {
+			readSize = offset
+		}
+		offset -= readSize
+
+		chunk := make([]byte, readSize)
+		if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		data = append(chunk, data...)
+	}
+
+	lines := splitLines(data)
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, size, nil
+}
+
+// GrepFile returns the last n lines of a file containing substr (all of them when n is 0)
+func GrepFile(filename, substr string, n int) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.Contains(line, substr) {
+			lines = append(lines, line)
+			if n > 0 && len(lines) > 2*n {
+				lines = append(lines[:0], lines[len(lines)-n:]...)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// ReadFrom returns complete lines appended to a file since offset and the offset after them.
+// A file smaller than offset is treated as truncated and read from the start.
+func ReadFrom(filename string, offset int64) ([]string, int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, offset, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, offset, err
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if info.Size() == offset {
+		return nil, offset, nil
+	}
+
+	data := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(data, offset); err != nil && err != io.EOF {
+		return nil, offset, err
+	}
+
+	// Only consume up to the last complete line; a partial line is picked up next time
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return nil, offset, nil
+	}
+	return splitLines(data[:end+1]), offset + int64(end+1), nil
+}
+
+// splitLines splits data into lines without trailing newline characters
+func splitLines(data []byte) []string {
+	lines := []string{}
+	if len(data) == 0 {
+		return lines
+	}
+	for _, line := range bytes.Split(bytes.TrimRight(data, "\n"), []byte{'\n'}) {
+		lines = append(lines, string(bytes.TrimRight(line, "\r")))
+	}
+	return lines
+}
@@ -2,21 +2,28 @@ package main
 
 import (
 	"context"
-	"net/http"
+	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 	"time"
 
+	"remote-mcp-proxy/api"
 	"remote-mcp-proxy/config"
-	"remote-mcp-proxy/health"
 	"remote-mcp-proxy/logger"
-	"remote-mcp-proxy/mcp"
-	"remote-mcp-proxy/monitoring"
-	"remote-mcp-proxy/proxy"
 )
 
 func main() {
+	configPath := os.Getenv("CONFIG_FILE")
+	if configPath == "" {
+		configPath = "/app/config.json"
+	}
+	os.Exit(runCommand(configPath, os.Args[1:]))
+}
+
+// serve runs the proxy until SIGINT or SIGTERM
+func serve(configPath string) int {
 	// Initialize logger system
 	loggerManager := logger.GetManager()
 	defer loggerManager.Close()
@@ -25,75 +32,108 @@ func main() {
 	sysLog.Info("Starting Remote MCP Proxy...")
 
 	// Load configuration
-	configPath := os.Getenv("CONFIG_FILE")
-	if configPath == "" {
-		configPath = "/app/config.json"
-	}
-	cfg, err := config.Load(configPath)
+	cfg, err := api.LoadConfig(configPath)
 	if err != nil {
 		sysLog.Error("Failed to load configuration: %v", err)
-		os.Exit(1)
+		exitStopped(err)
 	}
+	logger.Lifecycle(logger.PhaseConfigLoaded, map[string]interface{}{"servers": len(cfg.MCPServers)})
 
-	// Create MCP manager
-	mcpManager := mcp.NewManager(cfg.MCPServers)
-
-	// Start MCP servers
-	if err := mcpManager.StartAll(); err != nil {
-		sysLog.Error("Failed to start MCP servers: %v", err)
-		os.Exit(1)
+	// Create proxy (MCP manager, monitoring and HTTP routes)
+	proxy, err := api.New(cfg)
+	if err != nil {
+		sysLog.Error("Failed to create proxy: %v", err)
+		exitStopped(err)
 	}
 
-	// Initialize health checker and resource monitor
-	healthChecker := health.NewHealthChecker(mcpManager)
-	resourceMonitor := monitoring.NewResourceMonitor()
-
-	// Start monitoring services
-	healthChecker.Start()
-	resourceMonitor.Start()
-	sysLog.Info("Health checker and resource monitor started")
-
-	// Create proxy server with configuration
-	proxyServer := proxy.NewServerWithConfig(mcpManager, cfg, healthChecker, resourceMonitor)
+	// Serve before starting MCP servers, so /live and /ready answer probes during a slow startup
+	if err := proxy.Listen(); err != nil {
+		sysLog.Error("Server failed: %v", err)
+		exitStopped(err)
+	}
+	go func() {
+		if err := proxy.Serve(); err != nil {
+			sysLog.Error("Server failed: %v", err)
+			exitStopped(err)
+		}
+	}()
 
-	// Start HTTP server on configured port
-	addr := ":" + cfg.GetPort()
-	server := &http.Server{
-		Addr:    addr,
-		Handler: proxyServer.Router(),
+	// Start MCP servers and monitoring services
+	if err := proxy.Start(); err != nil {
+		sysLog.Error("Failed to start proxy: %v", err)
+		exitStopped(err)
+	}
+	servers := proxy.Manager().Servers()
+	started := 0
+	failed := []string{}
+	for _, server := range servers {
+		if server.Running {
+			started++
+		} else if server.Error != "" {
+			failed = append(failed, server.Name)
+		}
 	}
+	sort.Strings(failed)
+	logger.Lifecycle(logger.PhaseServersStarted, map[string]interface{}{"started": started, "failed": failed, "total": len(servers)})
+	logger.Lifecycle(logger.PhaseReady, map[string]interface{}{"port": cfg.GetPort()})
 
-	// Start server in goroutine
+	// SIGUSR1 toggles TRACE logging, e.g. while reproducing an SSE issue
+	trace := make(chan os.Signal, 1)
+	signal.Notify(trace, syscall.SIGUSR1)
 	go func() {
-		sysLog.Info("Server starting on %s (Domain: %s)", addr, cfg.GetDomain())
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			sysLog.Error("Server failed: %v", err)
-			os.Exit(1)
+		for range trace {
+			if loggerManager.ToggleTrace() {
+				sysLog.Info("SIGUSR1: TRACE logging enabled, send SIGUSR1 again to restore the previous levels")
+			} else {
+				sysLog.Info("SIGUSR1: previous log levels restored")
+			}
 		}
 	}()
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	sig := <-quit
 
 	sysLog.Info("Shutting down server...")
+	logger.Lifecycle(logger.PhaseDraining, map[string]interface{}{"signal": sig.String()})
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
+	if err := proxy.Shutdown(ctx); err != nil {
 		sysLog.Warn("Server forced to shutdown: %v", err)
 	}
 
-	// Stop monitoring services
-	healthChecker.Stop()
-	resourceMonitor.Stop()
-	sysLog.Info("Monitoring services stopped")
+	sysLog.Info("Server exited")
+	logger.Lifecycle(logger.PhaseStopped, nil)
+	return 0
+}
+
+// runImport imports a Claude Desktop config into the proxy's configuration and returns the exit code
+func runImport(source, target string) int {
+	result, err := config.ImportClaudeDesktop(source, target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
 
-	// Stop MCP servers
-	mcpManager.StopAll()
+	for _, name := range result.Imported {
+		fmt.Printf("Imported %s\n", name)
+	}
+	for _, name := range result.Skipped {
+		fmt.Printf("Skipped %s: already configured\n", name)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	fmt.Printf("%d imported, %d skipped into %s\n", len(result.Imported), len(result.Skipped), target)
+	return 0
+}
 
-	sysLog.Info("Server exited")
+// exitStopped announces the stopped phase with the fatal error and exits
+func exitStopped(err error) {
+	logger.Lifecycle(logger.PhaseStopped, map[string]interface{}{"error": err.Error()})
+	os.Exit(1)
 }
@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// startupProbeRequest is the ping a startup probe sends until the server answers
+var startupProbeRequest = []byte(`{"jsonrpc":"2.0","id":"startup-probe","method":"ping"}`)
+
+// startOrder returns the global servers' names with every server after its dependencies
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) startOrder() []string {
+	configs := make(map[string]config.MCPServer, len(m.servers))
+	for name, server := range m.servers {
+		configs[name] = server.Config
+	}
+	order := config.StartOrder(configs)
+
+	// Servers in a dependency cycle, which config validation rejects, start last
+	ordered := make(map[string]bool, len(order))
+	for _, name := range order {
+		ordered[name] = true
+	}
+	var rest []string
+	for name := range configs {
+		if !ordered[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(order, rest...)
+}
+
+// unreadyDependency returns a dependency of the server that is not running, or "" when all are
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) unreadyDependency(name string) string {
+	for _, dependency := range m.servers[name].Config.DependsOn {
+		server, exists := m.servers[dependency]
+		if !exists {
+			continue
+		}
+		if _, failed := m.startErrors[dependency]; failed || !server.IsRunning() {
+			return dependency
+		}
+	}
+	return ""
+}
+
+// startWhenReady starts a server once its dependencies run, then waits for its startup probe
+// A server whose dependency failed to start, or whose own probe failed, is
+// reported as failed to start and retried like any other.
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) startWhenReady(name string) error {
+	if dependency := m.unreadyDependency(name); dependency != "" {
+		return m.failStart(name, fmt.Errorf("dependency %s is not ready", dependency))
+	}
+	server := m.servers[name]
+	if err := m.startServer(name, server.Config); err != nil {
+		return err
+	}
+	if err := server.awaitStartup(); err != nil {
+		server.Stop()
+		return m.failStart(name, err)
+	}
+	return nil
+}
+
+// failStart records why a server failed to start and schedules a retry
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) failStart(name string, err error) error {
+	m.startErrors[name] = err.Error()
+	m.scheduleStartRetry(name)
+	return err
+}
+
+// awaitStartup pings a server with a startup probe until it answers or the probe times out
+// Servers without a startup probe are ready once started.
+func (s *Server) awaitStartup() error {
+	probe := s.Config.StartupProbe
+	if probe == nil {
+		return nil
+	}
+
+	timeout := probe.TimeoutDuration()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	for {
+		_, err := s.SendAndReceive(ctx, startupProbeRequest)
+		if err == nil {
+			logger.System().Info("MCP server %s is ready after %v", s.Name, time.Since(start).Round(time.Millisecond))
+			return nil
+		}
+		s.logger.Debug("Startup probe of server %s failed: %v", s.Name, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("startup probe got no answer within %v: %w", timeout, err)
+		case <-time.After(probe.IntervalDuration()):
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestStartAllDependencies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	db := answeringServerConfig()
+	db.StartupProbe = &config.StartupProbe{Timeout: "5s"}
+	query := answeringServerConfig()
+	query.DependsOn = []string{"db"}
+	manager := NewManager(map[string]config.MCPServer{
+		"db":    db,
+		"query": query,
+		"hung": {
+			Command:      "/bin/sh",
+			Args:         []string{"-c", "while true; do sleep 60; done"},
+			StartupProbe: &config.StartupProbe{Timeout: "200ms", Interval: "50ms"},
+		},
+		"reporter": {Command: "cat", DependsOn: []string{"hung"}},
+	})
+	manager.SetStopGracePeriod(time.Second)
+	defer manager.StopAll()
+
+	if err := manager.StartAll(); err == nil {
+		t.Fatal("Expected the servers behind the hung one to fail to start")
+	}
+
+	statuses := make(map[string]ServerStatus)
+	for _, status := range manager.GetAllServers() {
+		statuses[status.Name] = status
+	}
+	for _, name := range []string{"db", "query"} {
+		if status := statuses[name]; !status.Running || status.Error != "" {
+			t.Fatalf("Expected %s to be running, got %+v", name, status)
+		}
+	}
+	if !statuses["query"].StartedAt.After(*statuses["db"].StartedAt) {
+		t.Error("Expected query to start after its dependency")
+	}
+	if status := statuses["hung"]; status.Running || !strings.Contains(status.Error, "startup probe") {
+		t.Errorf("Expected the hung server to fail its startup probe, got %+v", status)
+	}
+	if status := statuses["reporter"]; status.Running || status.Error != "dependency hung is not ready" {
+		t.Errorf("Expected the dependent of the hung server not to start, got %+v", status)
+	}
+}
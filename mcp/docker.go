@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// containerRemoveTimeout bounds how long stopping a server waits for docker rm
+const containerRemoveTimeout = 15 * time.Second
+
+// invalidContainerChars matches characters docker doesn't accept in container names
+var invalidContainerChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// containerName returns a unique name for a new container of a server instance
+func containerName(instanceName string) string {
+	return fmt.Sprintf("mcp-%s-%s", invalidContainerChars.ReplaceAllString(instanceName, "-"), strconv.FormatInt(time.Now().UnixNano(), 36))
+}
+
+// DockerTransport runs a "docker" server in a container attached over stdio
+// The container is removed once the docker client has exited.
+type DockerTransport struct {
+	StdioTransport
+	container  string
+	removeOnce sync.Once
+}
+
+// NewDockerTransport creates the transport of a "docker" server
+func NewDockerTransport(opts TransportOptions) Transport {
+	return &DockerTransport{StdioTransport: StdioTransport{opts: opts}}
+}
+
+// Start runs a new container of the server's image
+func (t *DockerTransport) Start(ctx context.Context) (io.WriteCloser, io.ReadCloser, error) {
+	t.container = containerName(t.opts.InstanceName)
+	return t.startCommand(dockerCommand(ctx, t.container, t.opts.Config, t.opts.WorkDir))
+}
+
+// Wait waits for the docker client to exit, then removes the container
+func (t *DockerTransport) Wait() error {
+	err := t.StdioTransport.Wait()
+	t.removeOnce.Do(func() {
+		removeContainer(t.container)
+	})
+	return err
+}
+
+// Describe identifies the docker client process and its container
+func (t *DockerTransport) Describe() string {
+	return fmt.Sprintf("PID: %d, container: %s", t.PID(), t.container)
+}
+
+// dockerCommand builds the docker client command running a container of the server
+func dockerCommand(ctx context.Context, container string, cfg config.MCPServer, workDir string) *exec.Cmd {
+	argv := dockerRunArgs(container, cfg)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = serverEnvironment(config.MCPServer{Env: cfg.Env}, "") // The docker client needs the proxy's DOCKER_HOST and the like
+	cmd.Dir = workDir
+	return cmd
+}
+
+// dockerRunArgs builds the docker command line running a "docker" server in a container
+// The container keeps stdin open (-i) and is removed when it exits. Environment
+// values are passed by name so they stay out of the process list; docker reads
+// them from its own environment.
+func dockerRunArgs(container string, cfg config.MCPServer) []string {
+	args := []string{"docker", "run", "--rm", "-i", "--name", container}
+	if cfg.Network != "" {
+		args = append(args, "--network", cfg.Network)
+	}
+	for _, volume := range cfg.Volumes {
+		args = append(args, "-v", volume)
+	}
+
+	if sandbox := cfg.Sandbox; sandbox != nil {
+		if sandbox.UID != 0 {
+			args = append(args, "--user", fmt.Sprintf("%d:%d", sandbox.UID, sandbox.Group()))
+		}
+		if sandbox.NoNewPrivileges {
+			args = append(args, "--security-opt", "no-new-privileges")
+		}
+		if sandbox.ReadOnlyWorkDir {
+			args = append(args, "--read-only")
+		}
+		for _, name := range sandbox.EnvAllowlist {
+			args = append(args, "-e", name)
+		}
+	}
+	for _, name := range cfg.EnvAllowlist {
+		args = append(args, "-e", name)
+	}
+	keys := make([]string, 0, len(cfg.Env))
+	for key := range cfg.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "-e", key)
+	}
+
+	args = append(args, cfg.Image)
+	if cfg.Command != "" {
+		args = append(args, cfg.Command)
+	}
+	return append(args, cfg.Args...)
+}
+
+// removeContainer force-removes a server's container
+// Killing the docker client does not always stop the container, so stopped
+// servers remove theirs explicitly. Containers already gone are not an error.
+func removeContainer(container string) {
+	ctx, cancel := context.WithTimeout(context.Background(), containerRemoveTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "docker", "rm", "-f", container).CombinedOutput()
+	if err != nil && strings.Contains(string(output), "No such container") {
+		return // Removed on exit already
+	}
+	if err != nil {
+		logger.System().Warn("Failed to remove container %s: %v (%s)", container, err, output)
+		return
+	}
+	logger.System().Debug("Removed container %s", container)
+}
@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestDockerRunArgs(t *testing.T) {
+	cfg := config.MCPServer{
+		Type:    config.TypeDocker,
+		Image:   "mcp/fetch:latest",
+		Args:    []string{"--ignore-robots-txt"},
+		Env:     map[string]string{"USER_AGENT": "proxy", "API_KEY": "abc"},
+		Volumes: []string{"/srv/data:/data:ro"},
+		Network: "none",
+		Sandbox: &config.Sandbox{UID: 1001, NoNewPrivileges: true, ReadOnlyWorkDir: true},
+	}
+
+	want := []string{
+		"docker", "run", "--rm", "-i", "--name", "mcp-fetch-1",
+		"--network", "none", "-v", "/srv/data:/data:ro",
+		"--user", "1001:1001", "--security-opt", "no-new-privileges", "--read-only",
+		"-e", "API_KEY", "-e", "USER_AGENT",
+		"mcp/fetch:latest", "--ignore-robots-txt",
+	}
+	if got := dockerRunArgs("mcp-fetch-1", cfg); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// The command overrides the image's and keeps environment values off the command line
+	cfg.Command, cfg.Sandbox = "python", nil
+	cmd := dockerCommand(context.Background(), "mcp-fetch-1", cfg, "")
+	if got := cmd.Args[len(cmd.Args)-3:]; !slices.Equal(got, []string{"mcp/fetch:latest", "python", "--ignore-robots-txt"}) {
+		t.Errorf("Expected the image followed by the command, got %v", got)
+	}
+	if slices.Contains(cmd.Args, "abc") || !slices.Contains(cmd.Env, "API_KEY=abc") {
+		t.Errorf("Expected env values in the docker client's environment only, got args %v", cmd.Args)
+	}
+}
+
+func TestContainerName(t *testing.T) {
+	name := containerName("my server/1")
+	if !strings.HasPrefix(name, "mcp-my-server-1-") {
+		t.Errorf("Expected invalid characters replaced, got %s", name)
+	}
+}
@@ -0,0 +1,98 @@
+package mcp
+
+import (
+	"fmt"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// newFallbackServer creates the warm standby process of a server with a fallback configuration
+// The standby is shared by every session routed to it while the primary is failed over.
+func newFallbackServer(name string, cfg config.MCPServer) *Server {
+	instanceName := name + "-fallback"
+	mcpLogger, err := logger.MCPSession(name, "fallback")
+	if err != nil {
+		logger.System().Error("Failed to create MCP logger for %s: %v", instanceName, err)
+		mcpLogger = logger.System()
+	}
+
+	return &Server{
+		Name:                instanceName,
+		Config:              cfg,
+		requestQueue:        make(chan RequestResponse, 100),
+		logger:              mcpLogger,
+		activeOperations:    make(map[string]*OperationInfo),
+		operationTimeoutSec: defaultOperationTimeoutSec,
+		stderr:              NewStderrCapture(instanceName, mcpLogger, defaultStderrLines),
+		configName:          name,
+		multiplexed:         true,
+	}
+}
+
+// startFallbacks starts the warm standby of every server with a fallback configuration
+// A standby that fails to start is logged but does not prevent the primary from serving.
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) startFallbacks() {
+	for name, fallback := range m.fallbacks {
+		if err := m.startProcess(fallback, fallback.Config); err != nil {
+			logger.System().Warn(" Failed to start fallback for MCP server %s: %v", name, err)
+		}
+	}
+}
+
+// HasFallback reports whether a server has a fallback configuration
+func (m *Manager) HasFallback(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, exists := m.fallbacks[name]
+	return exists
+}
+
+// SetFailover routes a server's new requests to its fallback, or back to the primary
+// It returns whether the routing changed.
+func (m *Manager) SetFailover(name string, active bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fallback, exists := m.fallbacks[name]
+	if !exists {
+		return false, fmt.Errorf("server %s has no fallback", name)
+	}
+	if m.failedOver[name] == active {
+		return false, nil
+	}
+
+	if active {
+		if !fallback.IsRunning() {
+			if err := m.startProcess(fallback, fallback.Config); err != nil {
+				return false, fmt.Errorf("fallback not available: %w", err)
+			}
+		}
+		m.failedOver[name] = true
+		logger.System().Warn("MCP server %s failed over to its fallback", name)
+	} else {
+		delete(m.failedOver, name)
+		logger.System().Info("MCP server %s failed back to its primary", name)
+	}
+	return true, nil
+}
+
+// FailedOver reports whether a server's requests currently go to its fallback
+func (m *Manager) FailedOver(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.failedOver[name]
+}
+
+// GetActiveServer returns the global server requests should use: the fallback while failed over
+func (m *Manager) GetActiveServer(name string) (*Server, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.failedOver[name] {
+		return m.fallbacks[name], true
+	}
+	server, exists := m.servers[name]
+	return server, exists
+}
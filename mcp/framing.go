@@ -0,0 +1,241 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"remote-mcp-proxy/config"
+)
+
+// ErrInvalidMessage is returned when a message from an MCP server is not valid JSON
+var ErrInvalidMessage = errors.New("invalid JSON from MCP server")
+
+// maxFrameHeaderBytes bounds each header line of a Content-Length framed message
+const maxFrameHeaderBytes = 1024
+
+// maxFrameBodyBytes bounds a Content-Length framed body even when messages are unlimited
+// No server means to send a bigger one, so it's refused rather than skipped.
+const maxFrameBodyBytes = 1 << 30
+
+// contentLengthHeader starts the header block of LSP-style framed messages
+var contentLengthHeader = []byte("content-length:")
+
+// readMessage reads the next message from a server, newline-delimited or Content-Length framed
+// The framing is detected per message: a line starting with a Content-Length
+// header is followed by more headers, a blank line and exactly that many bytes.
+// JSON pretty-printed over several lines is read to its end and compacted.
+// Lines that can't start a message, such as banners and npm warnings, are
+// handed to noise (when set) and skipped. framed reports a Content-Length
+// framed message. Messages that aren't valid JSON fail with ErrInvalidMessage,
+// once read to their end so the next read starts at the next message.
+func readMessage(reader *bufio.Reader, limit int64, noise func(line []byte)) (data []byte, framed bool, err error) {
+	var line []byte
+	for {
+		line, err = readBoundedLine(reader, limit)
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, false, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		length, ok, err := parseContentLength(line)
+		if err != nil {
+			return nil, true, err
+		}
+		if ok {
+			data, err := readFramedBody(reader, length, limit)
+			return data, true, err
+		}
+		if !isOutputNoise(line) {
+			data, err := readJSONLines(reader, line, limit)
+			return data, false, err
+		}
+		if noise != nil {
+			noise(line)
+		}
+	}
+}
+
+// isOutputNoise reports whether a line printed by a server can't start a JSON-RPC message
+// Messages are objects, or arrays for batches. Lines in brackets that aren't
+// JSON, like "[INFO] Listening", are log output; a lone "[" opens a pretty-printed batch.
+func isOutputNoise(line []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	switch {
+	case len(trimmed) == 0:
+		return true
+	case trimmed[0] == '{':
+		return false
+	case trimmed[0] == '[':
+		return len(bytes.Trim(trimmed[1:], " \t{")) > 0 && !json.Valid(trimmed)
+	}
+	return true
+}
+
+// readFramedBody reads the rest of a Content-Length framed message, after its first header
+func readFramedBody(reader *bufio.Reader, length, limit int64) ([]byte, error) {
+	// Other headers (Content-Type) run up to a blank line
+	for {
+		header, err := readBoundedLine(reader, maxFrameHeaderBytes)
+		if err != nil {
+			return nil, fmt.Errorf("incomplete Content-Length framed message: %w", err)
+		}
+		if len(bytes.TrimRight(header, "\r\n")) == 0 {
+			break
+		}
+	}
+
+	if length > maxFrameBodyBytes {
+		return nil, fmt.Errorf("%w: Content-Length %d exceeds %d bytes", ErrInvalidMessage, length, maxFrameBodyBytes)
+	}
+	// Like an oversized line, an oversized body is skipped so the next read starts at the next message
+	if limit > 0 && length > limit {
+		if _, err := reader.Discard(int(length)); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %d bytes (limit %d)", ErrMessageTooLarge, length, limit)
+	}
+	// The buffer grows as the body arrives, so a length the server never sends isn't allocated up front
+	var buffer bytes.Buffer
+	if _, err := io.CopyN(&buffer, reader, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("incomplete Content-Length framed message: %w", err)
+	}
+	body := bytes.TrimRight(buffer.Bytes(), "\r\n")
+	if !json.Valid(body) {
+		return nil, invalidMessage(body)
+	}
+	return body, nil
+}
+
+// readJSONLines completes a message whose first line opens a JSON value it doesn't close
+// Lines are added until the value is closed; like a single line, a message
+// over limit is still read to its end but not kept.
+func readJSONLines(reader *bufio.Reader, first []byte, limit int64) ([]byte, error) {
+	var scanner jsonScanner
+	scanner.scan(first)
+	if scanner.depth <= 0 {
+		if !json.Valid(first) {
+			return nil, invalidMessage(first)
+		}
+		return first, nil
+	}
+
+	message := append([]byte{}, first...)
+	size := int64(len(first))
+	for scanner.depth > 0 {
+		chunk, err := reader.ReadSlice('\n')
+		scanner.scan(chunk)
+		size += int64(len(chunk))
+		if limit <= 0 || size <= limit {
+			message = append(message, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: message ends before its JSON value: %v", ErrInvalidMessage, err)
+		}
+	}
+	if limit > 0 && size > limit {
+		return nil, fmt.Errorf("%w: %d bytes (limit %d)", ErrMessageTooLarge, size, limit)
+	}
+
+	// Compacted, a message fits on one line again, as SSE data and in logs
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, message); err != nil {
+		return nil, invalidMessage(message)
+	}
+	return compacted.Bytes(), nil
+}
+
+// jsonScanner tracks how deeply nested the JSON read so far is, ignoring brackets in strings
+type jsonScanner struct {
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+// scan advances the scanner over data
+func (s *jsonScanner) scan(data []byte) {
+	for _, c := range data {
+		switch {
+		case s.escaped:
+			s.escaped = false
+		case s.inString:
+			if c == '\\' {
+				s.escaped = true
+			} else if c == '"' {
+				s.inString = false
+			}
+		case c == '"':
+			s.inString = true
+		case c == '{' || c == '[':
+			s.depth++
+		case c == '}' || c == ']':
+			s.depth--
+		}
+	}
+}
+
+// invalidMessage describes a message that isn't valid JSON, quoting its start
+func invalidMessage(message []byte) error {
+	const quoted = 100
+	if len(message) > quoted {
+		return fmt.Errorf("%w: %q...", ErrInvalidMessage, message[:quoted])
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidMessage, message)
+}
+
+// parseContentLength returns the length given by a Content-Length header line
+// ok is false for lines that aren't a Content-Length header, such as JSON messages.
+func parseContentLength(line []byte) (length int64, ok bool, err error) {
+	if len(line) < len(contentLengthHeader) || !bytes.EqualFold(line[:len(contentLengthHeader)], contentLengthHeader) {
+		return 0, false, nil
+	}
+	value := string(bytes.TrimSpace(line[len(contentLengthHeader):]))
+	length, err = strconv.ParseInt(value, 10, 64)
+	if err != nil || length < 0 {
+		return 0, true, fmt.Errorf("invalid Content-Length header %q", value)
+	}
+	return length, true, nil
+}
+
+// frameMessage delimits a message sent to a server
+// It always returns a copy: the same message may be sent to several servers at
+// once, so appending to it in place would race.
+func frameMessage(message []byte, contentLength bool) []byte {
+	framed := make([]byte, 0, len(message)+32)
+	if !contentLength {
+		framed = append(framed, message...)
+		return append(framed, '\n')
+	}
+	framed = fmt.Appendf(framed, "Content-Length: %d\r\n\r\n", len(message))
+	return append(framed, message...)
+}
+
+// usesContentLength reports whether messages to the server's current process are Content-Length framed
+// In "auto" framing, the server chooses by framing its own messages that way.
+func (s *Server) usesContentLength() bool {
+	switch s.Config.Framing {
+	case config.FramingContentLength:
+		return true
+	case config.FramingNewline:
+		return false
+	}
+	return s.framedByServer.Load()
+}
+
+// logNoise records a line a server printed on stdout besides its messages in its MCP log
+func (s *Server) logNoise(line []byte) {
+	s.logger.Info("Skipped non-JSON output from server %s: %s", s.Name, line)
+}
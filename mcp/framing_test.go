@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestReadMessageFraming(t *testing.T) {
+	input := `{"jsonrpc":"2.0","id":1,"result":{}}` + "\n" +
+		"Content-Length: 37\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n" +
+		`{"jsonrpc":"2.0","id":2,"result":{}}` + "\n" +
+		"content-length: 36\r\n\r\n" + `{"jsonrpc":"2.0","id":3,"result":{}}` +
+		"Content-Length: 100\r\n\r\n" + strings.Repeat("x", 100) +
+		`{"jsonrpc":"2.0","id":5,"result":{}}` + "\n"
+	reader := bufio.NewReader(strings.NewReader(input))
+
+	expect := func(id string, wantFramed bool) {
+		t.Helper()
+		data, framed, err := readMessage(reader, 50, nil)
+		if err != nil || framed != wantFramed || !strings.Contains(string(data), `"id":`+id) || strings.HasSuffix(string(data), "\n") {
+			t.Fatalf("Expected message %s (framed %v), got %q (framed %v, %v)", id, wantFramed, data, framed, err)
+		}
+	}
+
+	expect("1", false)
+	expect("2", true)
+	expect("3", true)
+
+	// An oversized body is skipped without losing the next message
+	if _, _, err := readMessage(reader, 50, nil); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("Expected ErrMessageTooLarge, got %v", err)
+	}
+	expect("5", false)
+
+	if _, _, err := readMessage(bufio.NewReader(strings.NewReader("Content-Length: ten\r\n\r\n")), 0, nil); err == nil {
+		t.Error("Expected an invalid Content-Length header to fail")
+	}
+
+	// Even without a limit, an absurd length is refused instead of allocated
+	bogus := bufio.NewReader(strings.NewReader("Content-Length: 9999999999999\r\n\r\n{}"))
+	if _, _, err := readMessage(bogus, 0, nil); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("Expected ErrInvalidMessage for an absurd Content-Length, got %v", err)
+	}
+	short := bufio.NewReader(strings.NewReader("Content-Length: 1000000\r\n\r\n{}"))
+	if _, _, err := readMessage(short, 0, nil); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected a body shorter than its Content-Length to fail, got %v", err)
+	}
+}
+
+func TestFrameMessage(t *testing.T) {
+	message := []byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	if framed := string(frameMessage(message, false)); framed != string(message)+"\n" {
+		t.Errorf("Expected a newline-delimited message, got %q", framed)
+	}
+	if framed := string(frameMessage(message, true)); framed != "Content-Length: 40\r\n\r\n"+string(message) {
+		t.Errorf("Expected a Content-Length framed message, got %q", framed)
+	}
+
+	// Messages shared by concurrent sends are never written to
+	shared := make([]byte, len(message), len(message)+1)
+	copy(shared, message)
+	framed := frameMessage(shared, false)
+	framed[len(framed)-1] = 'x'
+	if shared[:cap(shared)][len(message)] == 'x' {
+		t.Error("Expected the framed message to be a copy")
+	}
+
+	server := &Server{}
+	if server.usesContentLength() {
+		t.Error("Expected auto framing to start with newlines")
+	}
+	server.framedByServer.Store(true)
+	if !server.usesContentLength() {
+		t.Error("Expected auto framing to follow the server")
+	}
+	server.Config.Framing = config.FramingNewline
+	if server.usesContentLength() {
+		t.Error("Expected newline framing to ignore the server")
+	}
+	server.Config.Framing = config.FramingContentLength
+	server.framedByServer.Store(false)
+	if !server.usesContentLength() {
+		t.Error("Expected content-length framing from the start")
+	}
+}
+
+func TestReadMessageMultiline(t *testing.T) {
+	input := "{\n  \"jsonrpc\": \"2.0\",\n  \"id\": 1,\n  \"result\": {\"text\": \"a } in a string\\n\"}\n}\n" +
+		"\n" +
+		`{"jsonrpc":"2.0","id":2,"result":{}}` + "\n" +
+		"{\n  \"jsonrpc\": \"2.0\",\n  \"result\": \"" + strings.Repeat("x", 100) + "\"\n}\n" +
+		"{\"jsonrpc\": \"2.0\", \"id\": 4,}\n" +
+		`{"jsonrpc":"2.0","id":6,"result":{}}` + "\n"
+	reader := bufio.NewReaderSize(strings.NewReader(input), 16) // Splits lines over several reads
+
+	data, _, err := readMessage(reader, 80, nil)
+	if err != nil || string(data) != `{"jsonrpc":"2.0","id":1,"result":{"text":"a } in a string\n"}}` {
+		t.Fatalf("Expected the compacted pretty-printed message, got %q (%v)", data, err)
+	}
+	if data, _, err = readMessage(reader, 80, nil); err != nil || string(data) != `{"jsonrpc":"2.0","id":2,"result":{}}` {
+		t.Fatalf("Expected the next message after the blank line, got %q (%v)", data, err)
+	}
+	if _, _, err = readMessage(reader, 80, nil); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("Expected an oversized multi-line message to fail, got %v", err)
+	}
+	if _, _, err = readMessage(reader, 80, nil); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("Expected invalid JSON to fail, got %v", err)
+	}
+	if data, _, err = readMessage(reader, 80, nil); err != nil || !strings.Contains(string(data), `"id":6`) {
+		t.Fatalf("Expected the last message, got %q (%v)", data, err)
+	}
+
+	if _, _, err = readMessage(bufio.NewReader(strings.NewReader("{\n  \"jsonrpc\": \"2.0\",\n")), 0, nil); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("Expected a message cut short to fail, got %v", err)
+	}
+}
+
+func TestReadMessageSkipsNoise(t *testing.T) {
+	input := "Secure MCP Filesystem Server running on stdio\n" +
+		"npm WARN deprecated glob@7.2.3: Glob versions prior to v9 are no longer supported\n" +
+		"[INFO] Allowed directories: [ '/app' ]\n" +
+		"42\n" +
+		`{"jsonrpc":"2.0","id":1,"result":{}}` + "\n" +
+		"[\n" + `{"jsonrpc":"2.0","id":2,"result":{}}` + "\n]\n" +
+		`[{"jsonrpc":"2.0","id":3,"result":{}}]` + "\n"
+	reader := bufio.NewReader(strings.NewReader(input))
+
+	var skipped []string
+	noise := func(line []byte) { skipped = append(skipped, string(line)) }
+
+	if data, _, err := readMessage(reader, 0, noise); err != nil || !strings.Contains(string(data), `"id":1`) {
+		t.Fatalf("Expected the first message after the banners, got %q (%v)", data, err)
+	}
+	if len(skipped) != 4 || skipped[2] != "[INFO] Allowed directories: [ '/app' ]" {
+		t.Errorf("Expected the 4 banner lines to be skipped, got %q", skipped)
+	}
+	for _, id := range []string{"2", "3"} {
+		if data, _, err := readMessage(reader, 0, noise); err != nil || !strings.HasPrefix(string(data), "[") || !strings.Contains(string(data), `"id":`+id) {
+			t.Fatalf("Expected batch %s, got %q (%v)", id, data, err)
+		}
+	}
+	if len(skipped) != 4 {
+		t.Errorf("Expected batches not to be skipped, got %q", skipped)
+	}
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+)
+
+// ErrMessageTooLarge is returned when a message from an MCP server exceeds its size limit
+var ErrMessageTooLarge = errors.New("message from MCP server exceeds size limit")
+
+// SetMaxResponseBytes limits the size of messages read from every server, including future session instances
+// Servers with maxResponseBytes in their config keep their own limit. 0 means unlimited.
+// It should be called during startup, before requests are being served.
+func (m *Manager) SetMaxResponseBytes(limit int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxResponseBytes = limit
+	for _, server := range m.servers {
+		server.maxResponseBytes.Store(limit)
+	}
+	for _, server := range m.fallbacks {
+		server.maxResponseBytes.Store(limit)
+	}
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			server.maxResponseBytes.Store(limit)
+		}
+	}
+}
+
+// responseLimit returns the largest message accepted from the server (unlimited when 0)
+func (s *Server) responseLimit() int64 {
+	if s.Config.MaxResponseBytes > 0 {
+		return s.Config.MaxResponseBytes
+	}
+	return s.maxResponseBytes.Load()
+}
+
+// readBoundedLine reads one newline-terminated message of at most limit bytes (unlimited when 0)
+// An oversized message is still read to its end, so the next read starts at the
+// next message, but only limit bytes of it are ever held in memory.
+func readBoundedLine(reader *bufio.Reader, limit int64) ([]byte, error) {
+	var line []byte
+	var size int64
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		size += int64(len(chunk))
+		if limit <= 0 || size <= limit+1 { // The newline does not count
+			line = append(line, chunk...)
+		} else {
+			line = nil
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if limit > 0 && size > limit+1 {
+			return nil, fmt.Errorf("%w: %d bytes or more (limit %d)", ErrMessageTooLarge, size, limit)
+		}
+		return line, err
+	}
+}
@@ -0,0 +1,36 @@
+package mcp
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadBoundedLine(t *testing.T) {
+	input := "short\n" + strings.Repeat("x", 100) + "\nnext\n"
+	reader := bufio.NewReaderSize(strings.NewReader(input), 16) // Forces oversized lines across several reads
+
+	line, err := readBoundedLine(reader, 20)
+	if err != nil || string(line) != "short\n" {
+		t.Fatalf("Expected the short line, got %q (%v)", line, err)
+	}
+
+	line, err = readBoundedLine(reader, 20)
+	if !errors.Is(err, ErrMessageTooLarge) || line != nil {
+		t.Fatalf("Expected ErrMessageTooLarge, got %q (%v)", line, err)
+	}
+
+	// The oversized line was consumed, so the next read stays in sync
+	line, err = readBoundedLine(reader, 20)
+	if err != nil || string(line) != "next\n" {
+		t.Fatalf("Expected the next line, got %q (%v)", line, err)
+	}
+
+	// No limit reads whole lines of any size
+	reader = bufio.NewReaderSize(strings.NewReader(input), 16)
+	readBoundedLine(reader, 0)
+	if line, err = readBoundedLine(reader, 0); err != nil || len(line) != 101 {
+		t.Errorf("Expected the long line without a limit, got %d bytes (%v)", len(line), err)
+	}
+}
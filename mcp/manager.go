@@ -2,19 +2,24 @@ package mcp
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"strings"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 
 	"remote-mcp-proxy/config"
 	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/state"
+	"remote-mcp-proxy/webhook"
 )
 
 // RequestResponse represents a paired request/response for serialization
@@ -37,19 +42,21 @@ type OperationInfo struct {
 	StartTime time.Time // When the operation started
 	SessionID string    // Session that initiated the operation
 	ToolName  string    // Name of tool being called (for tools/call operations)
+
+	cancel context.CancelCauseFunc // Ends the request, see CancelOperation
 }
 
 // Server represents a running MCP server process
 type Server struct {
-	Name    string
-	Config  config.MCPServer
-	Process *exec.Cmd
-	Stdin   io.WriteCloser
-	Stdout  io.ReadCloser
-	ctx     context.Context
-	cancel  context.CancelFunc
-	mu      sync.RWMutex
-	logger  *logger.Logger
+	Name      string
+	Config    config.MCPServer
+	Transport Transport // Process, container or remote connection; nil when stopped
+	Stdin     io.WriteCloser
+	Stdout    io.ReadCloser
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mu        sync.RWMutex
+	logger    *logger.Logger
 
 	// CRITICAL FIX: Dedicated mutex for stdout reading to prevent stdio deadlocks
 	//
@@ -64,6 +71,15 @@ type Server struct {
 	// DO NOT REMOVE - this is essential for concurrent request handling
 	readMu sync.Mutex
 
+	// Buffered stdout reader kept across reads, and a read abandoned on timeout (guarded by readMu)
+	// An abandoned read is handed to the next reader instead of being lost.
+	stdoutReader  *bufio.Reader
+	stdoutSource  io.ReadCloser
+	abandonedRead chan lineResult
+
+	// The current process sent a Content-Length framed message, so "auto" framing answers in kind
+	framedByServer atomic.Bool
+
 	// CONCURRENCY FIX: Request serialization to prevent response mismatching
 	//
 	// This channel-based queue ensures that requests to the same MCP server
@@ -82,15 +98,99 @@ type Server struct {
 	activeOperations    map[string]*OperationInfo // requestID -> operation info
 	operationsMu        sync.RWMutex              // Protects activeOperations map
 	lastOperationTime   time.Time                 // Last time an operation started
-	operationTimeoutSec int                       // Server-specific operation timeout
+	operationTimeoutSec int                       // Operation timeout when the config sets none, see OperationTimeout
+
+	// Captured stderr output, kept across restarts for crash diagnostics
+	stderr *StderrCapture
+
+	// Uptime tracking: when the current process started, and how often it was started again
+	startedAt time.Time
+	restarts  int
+
+	// Recycle policy: requests the current process answered (pings excluded), and the restarts it caused
+	handled  atomic.Int64
+	recycles int // Included in restarts (guarded by the manager's mutex)
+
+	// Optional wire capture of JSON-RPC traffic (nil when disabled)
+	tracer *TraceRecorder
+
+	// Incident history recording unexpected exits (nil when disabled)
+	incidents  *state.Store
+	webhooks   *webhook.Notifier // Told about timed out requests (nil when disabled)
+	configName string            // Configured server name, without the session suffix
+	sessionID  string            // Session owning the instance; empty for processes serving several sessions
+
+	// Receives notifications and requests the server sends while a request is in flight (nil to drop them)
+	notificationHandler NotificationHandler
+
+	// Initialize result answered to clients without reaching the process: set on
+	// pre-warmed instances and after the first handshake of multiplexed ones
+	initResult json.RawMessage
+	workDir    string // Working directory of instances started before their session was known
+	prewarmed  bool   // Claimed from the warm pool
+
+	// Set for processes shared by several sessions ("shared" and "pool" modes)
+	multiplexed bool
+	sessionRefs int // Sessions assigned to a "pool" mode instance (guarded by the manager's mutex)
+
+	// Request timeouts for cold and warmed-up processes, and whether the current one answered yet
+	timeouts TimeoutTiers
+	warm     bool
+
+	maxResponseBytes atomic.Int64 // Default message size limit when the config sets none (unlimited when 0); read by the reader goroutine
+
+	stopGrace time.Duration // Default time between SIGTERM and SIGKILL when the config sets none
 }
 
+// NotificationHandler receives JSON-RPC notifications and requests sent by an MCP server
+// serverName is the configured name, also for session instances. sessionID is
+// the session owning the instance, or empty when the process is not bound to one.
+type NotificationHandler func(serverName, sessionID string, message []byte)
+
 // Manager manages multiple MCP server processes
 type Manager struct {
 	servers        map[string]*Server            // Global servers (legacy mode)
 	sessionServers map[string]map[string]*Server // sessionID -> serverName -> Server
 	configs        map[string]config.MCPServer   // Server configurations
+	tracer         *TraceRecorder                // Wire capture recorder (nil when disabled)
+	incidents      *state.Store                  // Incident history (nil when disabled)
+	webhooks       *webhook.Notifier             // Webhook event delivery (nil when disabled)
+	startupDone    atomic.Bool                   // StartAll has returned; atomic so probes don't wait on mu during startup
+	notifications  NotificationHandler           // Server notification handler (nil when unset)
+	maintenance    map[string]bool               // Servers refusing new requests during maintenance
+	warmPools      map[string]*warmPool          // Pre-initialized instances per server with warmPool set
+	instancePools  map[string][]*Server          // Running instances of "pool" mode servers
+	fallbacks      map[string]*Server            // Warm standby per server with a fallback configuration
+	failedOver     map[string]bool               // Servers whose requests go to their fallback
+	startErrors    map[string]string             // Servers whose last start failed, with the error
+	startRetry     StartRetryPolicy              // Backoff of background start retries (off when zero)
+	retrying       map[string]bool               // Servers with a start retry scheduled
+	retryCtx       context.Context               // Context of start retries (nil until SetStartRetry)
+	retryCancel    context.CancelFunc            // Called by StopAll to end start retries
+	draining       map[*Server]bool              // Replaced global processes finishing their requests
+	timeouts       TimeoutTiers                  // Request timeout tiers for new instances
+	preinstallOpts PreinstallOptions             // Which servers StartAll preinstalls
 	mu             sync.RWMutex
+
+	preinstalls  map[string]*PreinstallStatus // Preinstall progress per server (guarded by preinstallMu)
+	preinstallMu sync.Mutex
+
+	sessionDataOpts   SessionDataOptions           // Quota and persistence of session directories (guarded by mu)
+	sessionIdentities map[string]string            // Client identity per session, keying persistent data (guarded by mu)
+	sessionHeaders    map[string]map[string]string // Request headers used as template variables per session (guarded by mu)
+	templateHeaders   []string                     // Headers named in headerVariables, set once by NewManager
+	sessionUsage      map[string]*sessionUsage     // Measured session directory sizes (guarded by sessionDataMu)
+	sessionDataMu     sync.Mutex
+	sessionRoots      map[string]string // Root of sessions whose directory is not in sessionsRoot (guarded by sessionRootsMu)
+	sessionRootsMu    sync.Mutex
+
+	maxResponseBytes int64         // Message size limit for new instances (unlimited when 0)
+	stopGrace        time.Duration // Time between SIGTERM and SIGKILL for new instances
+	operationTimeout time.Duration // Operation timeout of new instances whose config sets none
+
+	drainPollInterval time.Duration // How often replaced servers are checked for requests in flight
+
+	ports portAllocator // Ports of "tcp" servers and {PORT}
 }
 
 // NewManager creates a new MCP manager
@@ -99,11 +199,37 @@ func NewManager(configs map[string]config.MCPServer) *Manager {
 		servers:        make(map[string]*Server),
 		sessionServers: make(map[string]map[string]*Server),
 		configs:        make(map[string]config.MCPServer),
+		maintenance:    make(map[string]bool),
+		warmPools:      make(map[string]*warmPool),
+		instancePools:  make(map[string][]*Server),
+		fallbacks:      make(map[string]*Server),
+		failedOver:     make(map[string]bool),
+		startErrors:    make(map[string]string),
+		retrying:       make(map[string]bool),
+		draining:       make(map[*Server]bool),
+		preinstalls:    make(map[string]*PreinstallStatus),
+
+		sessionIdentities: make(map[string]string),
+		sessionHeaders:    make(map[string]map[string]string),
+		templateHeaders:   templateHeaderNames(configs),
+		sessionUsage:      make(map[string]*sessionUsage),
+		sessionRoots:      make(map[string]string),
+
+		drainPollInterval: defaultDrainPollInterval,
 	}
 
 	// Store configurations for later use
 	for name, cfg := range configs {
 		m.configs[name] = cfg
+		if cfg.WarmPool > 0 {
+			m.warmPools[name] = &warmPool{size: cfg.WarmPool}
+		}
+		if cfg.SessionMode() != config.ModePerSession {
+			logger.System().Info("MCP server %s runs in %s mode", name, describeMode(cfg))
+		}
+		if cfg.Fallback != nil {
+			m.fallbacks[name] = newFallbackServer(name, *cfg.Fallback)
+		}
 	}
 
 	// Initialize global servers from configs (legacy mode)
@@ -119,7 +245,8 @@ func NewManager(configs map[string]config.MCPServer) *Manager {
 		// Set reasonable default operation timeout for all MCP servers
 		// Since we have real-time operation monitoring and intelligent cleanup
 		// that protects active operations, we only need a timeout for truly stuck operations
-		operationTimeout := 300 // 5 minutes default - reasonable for any MCP operation
+		// Servers with operationTimeout in their config, or SetOperationTimeout, override it.
+		operationTimeout := defaultOperationTimeoutSec
 
 		m.servers[name] = &Server{
 			Name:                name,
@@ -130,6 +257,9 @@ func NewManager(configs map[string]config.MCPServer) *Manager {
 			activeOperations:    make(map[string]*OperationInfo),
 			lastOperationTime:   time.Time{}, // Zero time initially
 			operationTimeoutSec: operationTimeout,
+			stderr:              NewStderrCapture(name, mcpLogger, defaultStderrLines),
+			configName:          name,
+			multiplexed:         cfg.SessionMode() == config.ModeShared,
 		}
 	}
 
@@ -137,17 +267,138 @@ func NewManager(configs map[string]config.MCPServer) *Manager {
 }
 
 // StartAll starts all configured MCP servers
+// Servers are started independently: one failing doesn't stop the others from
+// starting. The returned error lists every server that failed, and their
+// status reports the failure until they start. Preinstall commands run first.
 func (m *Manager) StartAll() error {
+	m.preinstallAll()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	defer m.startupDone.Store(true)
 
-	for name, server := range m.servers {
-		if err := m.startServer(name, server.Config); err != nil {
-			return fmt.Errorf("failed to start server %s: %w", name, err)
+	// Servers start one at a time, after the servers they depend on are ready
+	names := m.startOrder()
+
+	var errs []error
+	for _, name := range names {
+		if err := m.startWhenReady(name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to start server %s: %w", name, err))
 		}
 	}
+	if len(errs) > 0 {
+		logger.System().Error("%d of %d MCP servers failed to start", len(errs), len(names))
+	}
 
-	return nil
+	m.startWarmPools()
+	m.startFallbacks()
+	return errors.Join(errs...)
+}
+
+// StartupComplete reports whether StartAll has finished
+func (m *Manager) StartupComplete() bool {
+	return m.startupDone.Load()
+}
+
+// EnableWireCapture records every request/response through SendAndReceive on all servers
+// It should be called during startup, before requests are being served.
+func (m *Manager) EnableWireCapture(recorder *TraceRecorder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tracer = recorder
+	for _, server := range m.servers {
+		server.tracer = recorder
+	}
+	for _, server := range m.fallbacks {
+		server.tracer = recorder
+	}
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			server.tracer = recorder
+		}
+	}
+}
+
+// EnableIncidentHistory records unexpected server exits in the incident history
+// It should be called during startup, before servers are started.
+func (m *Manager) EnableIncidentHistory(store *state.Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.incidents = store
+	for _, server := range m.servers {
+		server.incidents = store
+	}
+	for _, server := range m.fallbacks {
+		server.incidents = store
+	}
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			server.incidents = store
+		}
+	}
+}
+
+// EnableWebhooks reports timed out requests of every server to notifier
+// It should be called during startup, before servers are started.
+func (m *Manager) EnableWebhooks(notifier *webhook.Notifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.webhooks = notifier
+	for _, server := range m.servers {
+		server.webhooks = notifier
+	}
+	for _, server := range m.fallbacks {
+		server.webhooks = notifier
+	}
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			server.webhooks = notifier
+		}
+	}
+}
+
+// SetNotificationHandler passes notifications from every server to handler
+// It should be called during startup, before requests are being served.
+func (m *Manager) SetNotificationHandler(handler NotificationHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.notifications = handler
+	for _, server := range m.servers {
+		server.notificationHandler = handler
+	}
+	for _, server := range m.fallbacks {
+		server.notificationHandler = handler
+	}
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			server.notificationHandler = handler
+		}
+	}
+}
+
+// GetIncidentStore returns the incident history, or nil when it is disabled
+func (m *Manager) GetIncidentStore() *state.Store {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.incidents
+}
+
+// GetWebhooks returns the webhook notifier, or nil when no webhooks are configured
+func (m *Manager) GetWebhooks() *webhook.Notifier {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.webhooks
+}
+
+// GetTraceRecorder returns the wire capture recorder, or nil when capture is disabled
+func (m *Manager) GetTraceRecorder() *TraceRecorder {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tracer
 }
 
 // GetServer returns a server by name (legacy global mode)
@@ -185,11 +436,37 @@ func (m *Manager) GetServerForSession(sessionID, serverName string) (*Server, bo
 		return nil, false
 	}
 
+	// FAILOVER: While the primary is unhealthy new instances come from the warm standby
+	if m.failedOver[serverName] {
+		return m.fallbacks[serverName], true
+	}
+
+	switch cfg.SessionMode() {
+	case config.ModeShared:
+		// Every session multiplexes onto the global process; it is not owned by the session
+		server, exists := m.servers[serverName]
+		return server, exists
+	case config.ModePool:
+		server, err := m.assignPooledServer(sessionID, serverName, cfg)
+		if err != nil {
+			logger.System().Error("Failed to assign pooled server %s to session %s: %v", serverName, sessionID, err)
+			return nil, false
+		}
+		sessionMap[serverName] = server
+		return server, true
+	}
+
+	// Hand out a pre-warmed instance when one is ready
+	if server := m.claimWarmServer(sessionID, serverName); server != nil {
+		sessionMap[serverName] = server
+		return server, true
+	}
+
 	// Create session-aware configuration
 	sessionCfg := m.createSessionConfig(sessionID, serverName, cfg)
 
 	// Create new server instance for this session
-	mcpLogger, err := logger.MCP(fmt.Sprintf("%s-%s", serverName, sessionID[:8]))
+	mcpLogger, err := logger.MCPSession(serverName, sessionID)
 	if err != nil {
 		logger.System().Error("Failed to create MCP logger for %s-%s: %v", serverName, sessionID[:8], err)
 		mcpLogger = logger.System()
@@ -201,7 +478,22 @@ func (m *Manager) GetServerForSession(sessionID, serverName string) (*Server, bo
 		requestQueue: make(chan RequestResponse, 100),
 		queueStarted: false,
 		logger:       mcpLogger,
+		stderr:       NewStderrCapture(fmt.Sprintf("%s-%s", serverName, sessionID[:8]), mcpLogger, defaultStderrLines),
+		tracer:       m.tracer,
+		incidents:    m.incidents,
+		webhooks:     m.webhooks,
+		configName:   serverName,
+		sessionID:    sessionID,
+		timeouts:     m.timeouts,
+
+		stopGrace: m.stopGrace,
+
+		notificationHandler: m.notifications,
+
+		activeOperations:    make(map[string]*OperationInfo),
+		operationTimeoutSec: m.operationTimeoutSec(), // Same default as global servers
 	}
+	server.maxResponseBytes.Store(m.maxResponseBytes)
 
 	// Start the server
 	if err := m.startServerForSession(sessionID, serverName, server); err != nil {
@@ -217,89 +509,89 @@ func (m *Manager) GetServerForSession(sessionID, serverName string) (*Server, bo
 
 // createSessionConfig creates a session-aware configuration with template substitution
 func (m *Manager) createSessionConfig(sessionID, serverName string, baseCfg config.MCPServer) config.MCPServer {
-	// Create a copy of the base config
-	sessionCfg := config.MCPServer{
-		Command: baseCfg.Command,
-		Args:    make([]string, len(baseCfg.Args)),
-		Env:     make(map[string]string),
-	}
+	// Create a copy of the base config, keeping its per-server settings
+	sessionCfg := baseCfg
+	sessionCfg.Args = make([]string, len(baseCfg.Args))
+	sessionCfg.Env = make(map[string]string)
+
+	variables := strings.NewReplacer(m.templateVariables(sessionID, serverName, baseCfg)...)
 
 	// Copy and substitute args with template variables
 	for i, arg := range baseCfg.Args {
-		arg = strings.ReplaceAll(arg, "{SESSION_ID}", sessionID)
-		arg = strings.ReplaceAll(arg, "{SERVER_NAME}", serverName)
-		sessionCfg.Args[i] = arg
+		sessionCfg.Args[i] = variables.Replace(arg)
+	}
+
+	// Copy and substitute container volumes
+	if len(baseCfg.Volumes) > 0 {
+		sessionCfg.Volumes = make([]string, len(baseCfg.Volumes))
+		for i, volume := range baseCfg.Volumes {
+			sessionCfg.Volumes[i] = variables.Replace(volume)
+		}
 	}
 
 	// Copy and substitute environment variables
 	for key, value := range baseCfg.Env {
-		// Replace template variables
-		value = strings.ReplaceAll(value, "{SESSION_ID}", sessionID)
-		value = strings.ReplaceAll(value, "{SERVER_NAME}", serverName)
-		sessionCfg.Env[key] = value
+		sessionCfg.Env[key] = variables.Replace(value)
 	}
+	sessionCfg.Cwd = variables.Replace(baseCfg.Cwd)
 
-	return sessionCfg
+	return m.assignPort(serverName, sessionCfg)
 }
 
 // startServerForSession starts a server for a specific session with session-aware directory setup
 func (m *Manager) startServerForSession(sessionID, serverName string, server *Server) error {
 	// Create session directory
-	sessionDir := fmt.Sprintf("/app/sessions/%s", sessionID)
-	if err := m.ensureSessionDirectory(sessionDir); err != nil {
+	sessionDir, err := m.prepareSessionDirectory(sessionID)
+	if err != nil {
 		return fmt.Errorf("failed to create session directory: %w", err)
 	}
 
+	// Sandboxed servers get a private directory inside the session directory,
+	// and servers with user workspaces one kept across the client's sessions
+	workDir := sessionDir
+	if workspace := m.userWorkspace(sessionID, serverName); workspace != "" {
+		dir, err := m.prepareUserWorkspace(workspace, serverName, server.Config)
+		if err != nil {
+			return fmt.Errorf("failed to create workspace: %w", err)
+		}
+		workDir = dir
+	} else if server.Config.Sandbox != nil && server.Config.RunsCommand() {
+		dir, err := m.sandboxWorkDir(sessionDir, serverName, server.Config.Sandbox)
+		if err != nil {
+			return fmt.Errorf("failed to create sandbox directory: %w", err)
+		}
+		workDir = dir
+	}
+	if dir, err := configuredWorkDir(server.Config); err != nil {
+		return err
+	} else if dir != "" {
+		workDir = dir
+	}
+
 	logger.System().Info("Starting MCP server %s for session %s", serverName, sessionID[:8])
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	cmd := exec.CommandContext(ctx, server.Config.Command, server.Config.Args...)
-
-	// Set environment variables
-	cmd.Env = os.Environ()
-	for key, value := range server.Config.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
-	}
-
-	// Set working directory to session directory
-	cmd.Dir = sessionDir
-
-	// Set up pipes for communication
-	stdin, err := cmd.StdinPipe()
+	// Start the process, container or remote connection behind the server through its transport
+	transport, err := newTransport(TransportOptions{InstanceName: server.Name, Config: server.Config, WorkDir: workDir, Stderr: server.stderr})
 	if err != nil {
 		cancel()
-		logger.System().Error("Failed to create stdin pipe for server %s-%s: %v", serverName, sessionID[:8], err)
-		return fmt.Errorf("failed to create stdin pipe: %w", err)
+		return err
 	}
-
-	stdout, err := cmd.StdoutPipe()
+	stdin, stdout, err := transport.Start(ctx)
 	if err != nil {
 		cancel()
-		if stdin != nil {
-			stdin.Close()
-		}
-		logger.System().Error("Failed to create stdout pipe for server %s-%s: %v", serverName, sessionID[:8], err)
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-
-	// Start the process
-	if err := cmd.Start(); err != nil {
-		cancel()
-		if stdin != nil {
-			stdin.Close()
-		}
-		if stdout != nil {
-			stdout.Close()
-		}
-		logger.System().Error("Failed to start process for server %s-%s: %v", serverName, sessionID[:8], err)
-		return fmt.Errorf("failed to start process: %w", err)
+		logger.System().Error("Failed to start server %s-%s: %v", serverName, sessionID[:8], err)
+		return err
 	}
 
 	// Update the server with process information
-	server.Process = cmd
+	server.Transport = transport
+	server.startedAt = time.Now()
+	server.handled.Store(0)
 	server.Stdin = stdin
 	server.Stdout = stdout
+	server.framedByServer.Store(false)
 	server.ctx = ctx
 	server.cancel = cancel
 
@@ -310,9 +602,9 @@ func (m *Manager) startServerForSession(sessionID, serverName string, server *Se
 	}
 
 	// Start monitoring the process
-	go server.monitor()
+	go server.monitor(ctx, transport)
 
-	logger.System().Info("Successfully started MCP server %s-%s (PID: %d)", serverName, sessionID[:8], cmd.Process.Pid)
+	logger.System().Info("Successfully started MCP server %s-%s (%s)", serverName, sessionID[:8], transport.Describe())
 	return nil
 }
 
@@ -323,8 +615,7 @@ func (m *Manager) ensureSessionDirectory(sessionDir string) error {
 	}
 
 	// Create common subdirectories that MCP servers might need
-	subdirs := []string{"data", "cache", "temp"}
-	for _, subdir := range subdirs {
+	for _, subdir := range sessionSubdirs {
 		fullPath := fmt.Sprintf("%s/%s", sessionDir, subdir)
 		if err := os.MkdirAll(fullPath, 0755); err != nil {
 			logger.System().Warn("Failed to create subdirectory %s: %v", fullPath, err)
@@ -335,6 +626,8 @@ func (m *Manager) ensureSessionDirectory(sessionDir string) error {
 }
 
 // CleanupSession stops all servers for a session and cleans up resources
+// The servers are stopped at once, even mid-operation. Callers that can wait
+// check SessionOperations first, as the proxy does when a session ends.
 func (m *Manager) CleanupSession(sessionID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -347,22 +640,32 @@ func (m *Manager) CleanupSession(sessionID string) {
 
 	logger.System().Info("Cleaning up session %s with %d servers", sessionID[:8], len(sessionMap))
 
+	sessionDir := m.SessionDirectory(sessionID)
+
 	// Stop all servers for this session
 	for serverName, server := range sessionMap {
+		if m.configs[serverName].SessionMode() == config.ModePool {
+			m.releasePooledServer(sessionID, serverName, server)
+			continue
+		}
+
 		logger.System().Info("Stopping server %s for session %s", serverName, sessionID[:8])
 		server.Stop()
+
+		// Pre-warmed instances ran in a directory of their own
+		if server.workDir != "" && server.workDir != sessionDir {
+			if err := os.RemoveAll(server.workDir); err != nil {
+				logger.System().Warn("Failed to clean up directory %s: %v", server.workDir, err)
+			}
+		}
 	}
 
 	// Remove session from tracking
 	delete(m.sessionServers, sessionID)
 
-	// Clean up session directory (optional - could be kept for persistence)
-	sessionDir := fmt.Sprintf("/app/sessions/%s", sessionID)
-	if err := os.RemoveAll(sessionDir); err != nil {
-		logger.System().Warn("Failed to clean up session directory %s: %v", sessionDir, err)
-	} else {
-		logger.System().Info("Cleaned up session directory for session %s", sessionID[:8])
-	}
+	// Clean up session directory; persistent data is kept for the client's next session
+	m.removeSessionDirectory(sessionID)
+	delete(m.sessionHeaders, sessionID)
 }
 
 // GetSessionServers returns information about all servers for a specific session
@@ -384,12 +687,13 @@ func (m *Manager) GetSessionServers(sessionID string) []ServerStatus {
 		}
 
 		server.mu.RLock()
-		if server.Process != nil && server.Process.Process != nil {
+		if server.Transport != nil {
 			status.Running = true
-			status.PID = server.Process.Process.Pid
+			status.PID = server.Transport.PID()
 		} else {
 			status.Running = false
 		}
+		status.Prewarmed = server.prewarmed
 		server.mu.RUnlock()
 
 		statuses = append(statuses, status)
@@ -398,6 +702,18 @@ func (m *Manager) GetSessionServers(sessionID string) []ServerStatus {
 	return statuses
 }
 
+// GetSessionIDs returns the IDs of all sessions that currently own server instances
+func (m *Manager) GetSessionIDs() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessionIDs := make([]string, 0, len(m.sessionServers))
+	for sessionID := range m.sessionServers {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	return sessionIDs
+}
+
 // GetSessionServerMap returns the actual server objects for a session (for operation tracking)
 func (m *Manager) GetSessionServerMap(sessionID string) map[string]*Server {
 	m.mu.RLock()
@@ -418,12 +734,26 @@ func (m *Manager) GetSessionServerMap(sessionID string) map[string]*Server {
 
 // ServerStatus represents the status of an MCP server
 type ServerStatus struct {
-	Name    string   `json:"name"`
-	Running bool     `json:"running"`
-	PID     int      `json:"pid,omitempty"`
-	Command string   `json:"command"`
-	Args    []string `json:"args,omitempty"`
-	Error   string   `json:"error,omitempty"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"displayName,omitempty"` // Config key the name was derived from, when different
+	Running     bool     `json:"running"`
+	PID         int      `json:"pid,omitempty"`
+	Command     string   `json:"command"`
+	Args        []string `json:"args,omitempty"`
+	Error       string   `json:"error,omitempty"` // Why the last start failed, until the server starts
+
+	Maintenance bool `json:"maintenance,omitempty"`
+	Prewarmed   bool `json:"prewarmed,omitempty"` // Session instance claimed from the warm pool
+
+	WarmInstances int  `json:"warmInstances,omitempty"` // Idle pre-warmed instances ready for new sessions
+	FailedOver    bool `json:"failedOver,omitempty"`    // New requests go to the fallback configuration
+
+	StartedAt     *time.Time `json:"startedAt,omitempty"`     // When the current (or last) process was started
+	UptimeSeconds int64      `json:"uptimeSeconds,omitempty"` // Seconds since StartedAt, while running
+	Restarts      int        `json:"restarts"`                // Times the server was started again since the proxy started
+
+	Requests int64 `json:"requests,omitempty"` // Requests the current process answered, pings excluded
+	Recycles int   `json:"recycles,omitempty"` // Restarts made by the recycle policy, included in Restarts
 }
 
 // GetAllServers returns status information for all configured servers
@@ -434,18 +764,36 @@ func (m *Manager) GetAllServers() []ServerStatus {
 	var statuses []ServerStatus
 	for name, server := range m.servers {
 		status := ServerStatus{
-			Name:    name,
-			Command: server.Config.Command,
-			Args:    server.Config.Args,
+			Name:        name,
+			DisplayName: server.Config.DisplayName,
+			Command:     server.Config.Command,
+			Args:        server.Config.Args,
+
+			Maintenance: m.maintenance[name],
 		}
+		if pool, exists := m.warmPools[name]; exists {
+			status.WarmInstances = len(pool.instances)
+		}
+		status.FailedOver = m.failedOver[name]
+		status.Error = m.startErrors[name]
 
 		server.mu.RLock()
-		if server.Process != nil && server.Process.Process != nil {
+		if server.Transport != nil {
 			status.Running = true
-			status.PID = server.Process.Process.Pid
+			status.PID = server.Transport.PID()
 		} else {
 			status.Running = false
 		}
+		if !server.startedAt.IsZero() {
+			startedAt := server.startedAt
+			status.StartedAt = &startedAt
+			if status.Running {
+				status.UptimeSeconds = int64(time.Since(startedAt).Seconds())
+			}
+		}
+		status.Restarts = server.restarts
+		status.Requests = server.handled.Load()
+		status.Recycles = server.recycles
 		server.mu.RUnlock()
 
 		statuses = append(statuses, status)
@@ -454,11 +802,50 @@ func (m *Manager) GetAllServers() []ServerStatus {
 	return statuses
 }
 
+// StderrLines returns up to the last n lines the server wrote to stderr
+func (s *Server) StderrLines(n int) []string {
+	if s.stderr == nil {
+		return []string{}
+	}
+	return s.stderr.Lines(n)
+}
+
+// GetServerStderr returns recent stderr lines for a global server or a session
+// server instance (e.g. "memory-1a2b3c4d")
+func (m *Manager) GetServerStderr(name string, n int) ([]string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if server, exists := m.servers[name]; exists {
+		return server.StderrLines(n), true
+	}
+
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			if server.Name == name {
+				return server.StderrLines(n), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// PID returns the process ID of the running server, or 0 when it is not running
+func (s *Server) PID() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Transport == nil {
+		return 0
+	}
+	return s.Transport.PID()
+}
+
 // IsRunning checks if the server is currently running
 func (s *Server) IsRunning() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.Process != nil && s.Process.Process != nil
+	return s.Transport != nil
 }
 
 // StopAll stops all running MCP servers
@@ -466,76 +853,97 @@ func (m *Manager) StopAll() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.retryCancel != nil {
+		m.retryCancel()
+	}
+
 	for name, server := range m.servers {
 		logger.System().Info("Stopping MCP server: %s", name)
 		server.Stop()
 	}
+	for name := range m.warmPools {
+		m.drainWarmPool(name)
+	}
+	for name := range m.instancePools {
+		m.stopInstancePool(name)
+	}
+	for name, fallback := range m.fallbacks {
+		logger.System().Info("Stopping fallback for MCP server: %s", name)
+		fallback.Stop()
+	}
+	for server := range m.draining {
+		logger.System().Info("Stopping replaced MCP server: %s", server.Name)
+		server.Stop()
+		delete(m.draining, server)
+	}
 }
 
 // startServer starts a single MCP server
 // NOTE: This method must be called with m.mu locked
 func (m *Manager) startServer(name string, cfg config.MCPServer) error {
+	server := m.servers[name]
+	server.initResult = nil // A new process needs its own handshake
+	if !server.startedAt.IsZero() {
+		server.restarts++
+	}
+	if err := m.startProcess(server, cfg); err != nil {
+		return m.failStart(name, err)
+	}
+	delete(m.startErrors, name)
+	return nil
+}
+
+// startProcess starts the process of a global or standby server
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) startProcess(server *Server, cfg config.MCPServer) error {
+	name := server.Name
 	logger.System().Info("Starting MCP server: %s", name)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
-
-	// Set environment variables
-	cmd.Env = os.Environ()
-	for key, value := range cfg.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	// Start the process, container or remote connection behind the server through its transport
+	cfg = m.assignPort(name, cfg)
+	workDir, err := configuredWorkDir(cfg)
+	if err != nil {
+		cancel()
+		return err
 	}
-
-	// Set up pipes for communication
-	stdin, err := cmd.StdinPipe()
+	transport, err := newTransport(TransportOptions{InstanceName: name, Config: cfg, WorkDir: workDir, Stderr: server.stderr})
 	if err != nil {
 		cancel()
-		logger.System().Error("Failed to create stdin pipe for server %s: %v", name, err)
-		return fmt.Errorf("failed to create stdin pipe: %w", err)
+		return err
 	}
-
-	stdout, err := cmd.StdoutPipe()
+	stdin, stdout, err := transport.Start(ctx)
 	if err != nil {
 		cancel()
-		if stdin != nil {
-			stdin.Close()
-		}
-		logger.System().Error("Failed to create stdout pipe for server %s: %v", name, err)
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+		logger.System().Error("Failed to start server %s: %v", name, err)
+		return err
 	}
 
-	// Start the process
-	if err := cmd.Start(); err != nil {
-		cancel()
-		if stdin != nil {
-			stdin.Close()
-		}
-		if stdout != nil {
-			stdout.Close()
-		}
-		logger.System().Error("Failed to start process for server %s: %v", name, err)
-		return fmt.Errorf("failed to start process: %w", err)
+	// Release goroutines still bound to a previous process of this server
+	if server.cancel != nil {
+		server.cancel()
 	}
 
 	// Update the existing server with process information (mutex is already held by caller)
-	server := m.servers[name]
-	server.Process = cmd
+	server.Transport = transport
+	server.startedAt = time.Now()
+	server.handled.Store(0)
+	server.warm = false
 	server.Stdin = stdin
 	server.Stdout = stdout
+	server.framedByServer.Store(false)
 	server.ctx = ctx
 	server.cancel = cancel
 
-	// Start the request processor if not already started
-	if !server.queueStarted {
-		go server.processRequests()
-		server.queueStarted = true
-	}
+	// Start a request processor for this process; the previous one exits with its context
+	go server.processRequests()
+	server.queueStarted = true
 
 	// Start monitoring the process
-	go server.monitor()
+	go server.monitor(ctx, transport)
 
-	logger.System().Info("Successfully started MCP server %s (PID: %d)", name, cmd.Process.Pid)
+	logger.System().Info("Successfully started MCP server %s (%s)", name, transport.Describe())
 	return nil
 }
 
@@ -546,7 +954,7 @@ func (s *Server) Stop() {
 
 	s.logger.Info("Stopping MCP server: %s", s.Name)
 
-	if s.Process == nil {
+	if s.Transport == nil {
 		s.logger.Warn("Server %s already stopped or not started", s.Name)
 		// Still clean up pipes even if no process
 		if s.Stdin != nil {
@@ -564,7 +972,7 @@ func (s *Server) Stop() {
 		return
 	}
 
-	// Cancel context to signal shutdown
+	// Cancel context to signal shutdown; local processes get SIGTERM
 	if s.cancel != nil {
 		s.cancel()
 	}
@@ -589,6 +997,7 @@ func (s *Server) Stop() {
 	}
 
 	// Wait for process to exit gracefully
+	transport := s.Transport
 	done := make(chan error, 1)
 	go func() {
 		defer func() {
@@ -598,7 +1007,7 @@ func (s *Server) Stop() {
 			}
 		}()
 
-		err := s.Process.Wait()
+		err := transport.Wait()
 		done <- err
 	}()
 
@@ -609,15 +1018,13 @@ func (s *Server) Stop() {
 		} else {
 			s.logger.Info("MCP server %s exited gracefully", s.Name)
 		}
-	case <-time.After(10 * time.Second):
+	case <-time.After(s.stopGracePeriod()):
 		// Force kill if graceful shutdown takes too long
-		s.logger.Warn("Force killing MCP server %s after timeout", s.Name)
-		if s.Process.Process != nil {
-			if err := s.Process.Process.Signal(syscall.SIGKILL); err != nil {
-				s.logger.Error("Failed to kill process for server %s: %v", s.Name, err)
-			} else {
-				s.logger.Info("Sent SIGKILL to server %s", s.Name)
-			}
+		s.logger.Warn("Force killing MCP server %s after %v grace period", s.Name, s.stopGracePeriod())
+		if err := transport.Kill(); err != nil {
+			s.logger.Error("Failed to kill process for server %s: %v", s.Name, err)
+		} else {
+			s.logger.Info("Sent SIGKILL to server %s", s.Name)
 		}
 
 		// Wait a bit more for the forced kill to take effect
@@ -633,7 +1040,7 @@ func (s *Server) Stop() {
 		}
 	}
 
-	s.Process = nil
+	s.Transport = nil
 	s.logger.Info("Server %s stop completed", s.Name)
 }
 
@@ -648,18 +1055,92 @@ func (s *Server) processRequests() {
 
 	s.logger.Info("Starting request processor for server %s", s.Name)
 
+	// Bound to the process it was started for; a restart starts a new processor
+	s.mu.RLock()
+	ctx := s.ctx
+	s.mu.RUnlock()
+
 	for {
-		select {
-		case req := <-s.requestQueue:
-			// Process the request synchronously
-			s.processRequest(req)
-		case <-s.ctx.Done():
+		// Relay messages the server sends on its own until a request is queued
+		req, ok := s.listenIdle(ctx)
+		if !ok {
 			s.logger.Info("Request processor context cancelled for server %s", s.Name)
 			return
 		}
+		// Process the request synchronously
+		s.processRequest(req)
 	}
 }
 
+// listenIdle reads server-initiated messages (e.g. resource updates) while no request is in flight
+// It returns the next queued request, or false once ctx is done. A line being read when the
+// request arrives is left for the request's own read, so no response is lost.
+func (s *Server) listenIdle(ctx context.Context) (RequestResponse, bool) {
+	idleCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queued := make(chan RequestResponse, 1)
+	go func() {
+		select {
+		case req := <-s.requestQueue:
+			queued <- req
+			cancel()
+		case <-idleCtx.Done():
+		}
+	}()
+
+	for {
+		message, err := s.readIdleMessage(idleCtx)
+		if errors.Is(err, ErrInvalidMessage) || errors.Is(err, ErrMessageTooLarge) {
+			s.logger.Warn("Discarding unreadable message from idle server %s: %v", s.Name, err)
+			continue
+		}
+		if err != nil {
+			// Interrupted by a request, or the process is gone; either way wait for the next request
+			break
+		}
+		if isServerMessage(message) {
+			s.dispatchServerMessage(message)
+		} else {
+			s.logger.Warn("Discarding unexpected message from idle server %s: %s", s.Name, string(message))
+		}
+	}
+
+	select {
+	case req := <-queued:
+		return req, true
+	case <-ctx.Done():
+		return RequestResponse{}, false
+	}
+}
+
+// readIdleMessage reads the next message from stdout while no request is in flight
+func (s *Server) readIdleMessage(ctx context.Context) ([]byte, error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	s.mu.RLock()
+	stdout := s.Stdout
+	s.mu.RUnlock()
+
+	if stdout == nil {
+		return nil, fmt.Errorf("server not running")
+	}
+	return s.readLine(ctx, stdout)
+}
+
+// dispatchServerMessage hands a notification or server-initiated request to the notification handler
+func (s *Server) dispatchServerMessage(message []byte) {
+	s.logger.Debug("Message from server %s: %s", s.Name, string(message))
+	if s.notificationHandler == nil {
+		return
+	}
+	s.mu.RLock()
+	sessionID := s.sessionID
+	s.mu.RUnlock()
+	s.notificationHandler(s.configName, sessionID, message)
+}
+
 // processRequest handles a single request/response cycle
 func (s *Server) processRequest(req RequestResponse) {
 	defer func() {
@@ -670,15 +1151,119 @@ func (s *Server) processRequest(req RequestResponse) {
 		close(req.ResponseCh)
 	}()
 
+	// A caller that gave up while the request was queued never reaches the server
+	if err := req.Ctx.Err(); err != nil {
+		req.ResponseCh <- RequestResult{nil, err}
+		return
+	}
+
 	// Send the request
 	if err := s.sendMessageDirect(req.Request); err != nil {
 		req.ResponseCh <- RequestResult{nil, err}
 		return
 	}
 
-	// Read the response
-	response, err := s.readMessageDirect(req.Ctx)
-	req.ResponseCh <- RequestResult{response, err}
+	// Read the response, handing notifications and server-initiated requests
+	// (sampling, roots, elicitation) that arrive before it to the handler
+	requestID := messageID(req.Request)
+	for {
+		response, err := s.readMessageDirect(req.Ctx)
+		if err == nil && isServerMessage(response) {
+			s.dispatchServerMessage(response)
+			continue
+		}
+		if responseID := messageID(response); err == nil && requestID != nil && responseID != nil && !bytes.Equal(responseID, requestID) {
+			// The late answer to a request that timed out or was cancelled
+			s.logger.Warn("Discarding late message from server %s: %s", s.Name, string(response))
+			continue
+		}
+		if err == nil {
+			s.markWarm()
+		} else if req.Ctx.Err() != nil {
+			if errors.Is(req.Ctx.Err(), context.DeadlineExceeded) {
+				s.notifyTimeout(req.Request)
+			}
+			s.sendCancelled(req.Request, context.Cause(req.Ctx))
+		}
+		req.ResponseCh <- RequestResult{response, err}
+		return
+	}
+}
+
+// notifyTimeout reports a request the server did not answer in time to the webhooks
+func (s *Server) notifyTimeout(request []byte) {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	json.Unmarshal(request, &msg)
+
+	s.mu.RLock()
+	sessionID := s.sessionID
+	s.mu.RUnlock()
+	s.webhooks.Notify(webhook.Event{
+		Type:    config.WebhookOperationTimeout,
+		Server:  s.configName,
+		Session: sessionID,
+		Reason:  "Request timed out",
+		Details: map[string]interface{}{"method": msg.Method, "requestId": msg.ID, "instance": s.Name},
+	})
+}
+
+// sendCancelled tells the server to stop working on a request its caller gave up on
+// initialize is never cancelled, as the protocol requires.
+func (s *Server) sendCancelled(request []byte, cause error) {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.Unmarshal(request, &msg); err != nil || len(msg.ID) == 0 || msg.Method == "initialize" {
+		return
+	}
+
+	reason := "Request cancelled by client"
+	if errors.Is(cause, context.DeadlineExceeded) {
+		reason = "Request timed out"
+	} else if errors.Is(cause, ErrOperationCancelled) {
+		reason = "Request cancelled by an administrator"
+	}
+	notification, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/cancelled",
+		"params": map[string]interface{}{
+			"requestId": msg.ID,
+			"reason":    reason,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	if err := s.sendMessageDirect(notification); err != nil {
+		s.logger.Warn("Failed to cancel request %s (%s) on server %s: %v", string(msg.ID), msg.Method, s.Name, err)
+		return
+	}
+	s.logger.Info("Cancelled request %s (%s) on server %s: %s", string(msg.ID), msg.Method, s.Name, reason)
+}
+
+// messageID returns the raw JSON-RPC ID of a message, or nil when it has none
+func messageID(message []byte) json.RawMessage {
+	var msg struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil || len(msg.ID) == 0 || string(msg.ID) == "null" {
+		return nil
+	}
+	return msg.ID
+}
+
+// isServerMessage reports whether a message was initiated by the server rather than answering a request
+// Notifications and requests carry a method; responses never do.
+func isServerMessage(message []byte) bool {
+	var msg struct {
+		Method string `json:"method"`
+	}
+	return json.Unmarshal(message, &msg) == nil && msg.Method != ""
 }
 
 // sendMessageDirect sends a message directly (internal use by request processor)
@@ -693,7 +1278,7 @@ func (s *Server) sendMessageDirect(message []byte) error {
 		return fmt.Errorf("server not running")
 	}
 
-	_, err := s.Stdin.Write(append(message, '\n'))
+	_, err := s.Stdin.Write(frameMessage(message, s.usesContentLength()))
 	if err != nil {
 		s.logger.Error("Failed to send message to server %s: %v", s.Name, err)
 		s.logger.Debug("<<< %s FAILED", s.Name)
@@ -706,13 +1291,77 @@ func (s *Server) sendMessageDirect(message []byte) error {
 
 // SendAndReceive sends a request and waits for the response using the serialized queue
 func (s *Server) SendAndReceive(ctx context.Context, message []byte) ([]byte, error) {
+	// WARM POOL: Pre-warmed instances already completed the handshake
+	if response, ok := s.preinitializedResponse(message); ok {
+		return response, nil
+	}
+
+	// TIMEOUT TIERS: Cold processes get a longer budget, warm ones fail fast
+	ctx, cancel := s.tierContext(ctx, message)
+	defer cancel()
+
 	// OPERATION TRACKING: Parse request to extract operation information
 	operationInfo := s.parseOperationInfo(message, ctx)
 	if operationInfo != nil {
+		var endOperation context.CancelFunc
+		ctx, endOperation = withOperation(ctx, operationInfo)
+		defer endOperation()
 		s.startOperation(operationInfo)
 		defer s.endOperation(operationInfo.RequestID)
 	}
 
+	// WIRE CAPTURE: Record the exchange when debugging is enabled
+	var response []byte
+	var err error
+	if s.tracer != nil {
+		start := time.Now()
+		s.traceMessage(ctx, "request", message, nil, 0)
+		response, err = s.sendAndReceiveQueued(ctx, message)
+		s.traceMessage(ctx, "response", response, err, time.Since(start))
+	} else {
+		response, err = s.sendAndReceiveQueued(ctx, message)
+	}
+
+	// RECYCLE POLICY: Count the requests the process answered
+	if err == nil && operationInfo != nil && operationInfo.Method != "ping" {
+		s.handled.Add(1)
+	}
+
+	// SHARED MODES: Later sessions reuse the first handshake of a multiplexed process
+	if err == nil && s.multiplexed {
+		s.rememberInitialize(message, response)
+	}
+	return response, operationError(ctx, err)
+}
+
+// traceMessage records one side of an exchange in the wire capture
+func (s *Server) traceMessage(ctx context.Context, direction string, message []byte, err error, duration time.Duration) {
+	entry := TraceEntry{
+		Timestamp:  time.Now(),
+		Server:     s.Name,
+		Direction:  direction,
+		DurationMs: duration.Milliseconds(),
+	}
+	if sessionID, ok := ctx.Value("sessionID").(string); ok {
+		entry.SessionID = sessionID
+	}
+	if err != nil {
+		entry.Direction = "error"
+		entry.Error = err.Error()
+	}
+	if len(message) > 0 {
+		if json.Valid(message) {
+			entry.Message = json.RawMessage(append([]byte(nil), message...))
+		} else {
+			quoted, _ := json.Marshal(string(message))
+			entry.Message = quoted
+		}
+	}
+	s.tracer.Record(entry)
+}
+
+// sendAndReceiveQueued passes a request through the serialized queue and waits for its response
+func (s *Server) sendAndReceiveQueued(ctx context.Context, message []byte) ([]byte, error) {
 	// Create response channel
 	responseCh := make(chan RequestResult, 1)
 
@@ -760,13 +1409,16 @@ func (s *Server) parseOperationInfo(message []byte, ctx context.Context) *Operat
 		return nil // No method field, skip tracking
 	}
 
-	requestID, ok := jsonrpcMsg["id"]
-	if !ok {
+	if _, ok := jsonrpcMsg["id"]; !ok {
 		return nil // No request ID, skip tracking
 	}
 
-	// Generate unique operation ID
-	opID := fmt.Sprintf("%s-%v-%d", s.Name, requestID, time.Now().UnixNano())
+	// Generate unique operation ID, safe to use in /admin/operations/{id}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil
+	}
+	opID := hex.EncodeToString(suffix)
 
 	// Extract session ID from context if available
 	sessionID := ""
@@ -837,10 +1489,11 @@ func (s *Server) GetActiveOperationCount() int {
 
 // IsOperationExpired checks if any operation has exceeded the server's timeout
 func (s *Server) IsOperationExpired() bool {
+	timeout := s.OperationTimeout()
+
 	s.operationsMu.RLock()
 	defer s.operationsMu.RUnlock()
 
-	timeout := time.Duration(s.operationTimeoutSec) * time.Second
 	now := time.Now()
 
 	for _, info := range s.activeOperations {
@@ -853,7 +1506,7 @@ func (s *Server) IsOperationExpired() bool {
 
 // GetOperationTimeoutSec returns the server's operation timeout in seconds
 func (s *Server) GetOperationTimeoutSec() int {
-	return s.operationTimeoutSec
+	return int(s.OperationTimeout() / time.Second)
 }
 
 // SendMessage sends a JSON-RPC message to the MCP server using the request queue
@@ -890,55 +1543,81 @@ func (s *Server) readMessageDirect(ctx context.Context) ([]byte, error) {
 		return nil, fmt.Errorf("server not running")
 	}
 
-	// Use a channel to communicate the result from the reading goroutine
-	type readResult struct {
-		data []byte
-		err  error
+	data, err := s.readLine(ctx, stdout)
+	if err != nil && err != io.EOF {
+		if ctx.Err() != nil {
+			s.logger.Warn("readMessageDirect timeout/cancellation for server %s: %v", serverName, err)
+		} else {
+			s.logger.Error("Failed to read message from server %s: %v", serverName, err)
+		}
+	}
+	return data, err
+}
+
+// lineResult is the outcome of reading one line from a server's stdout
+type lineResult struct {
+	data []byte
+	err  error
+}
+
+// readLine reads the next message from the server's stdout with context timeout
+//
+// Messages are lines, or Content-Length framed for servers framing them that way.
+// One buffered reader is kept per process so lines the server writes in a single
+// burst (e.g. a notification followed by a response) are not lost between calls.
+// A read abandoned by a timed out or cancelled caller is finished before the next
+// one starts and its line returned to the next caller; processRequest drops late
+// responses meant for an earlier request.
+// NOTE: This method must be called with s.readMu locked
+func (s *Server) readLine(ctx context.Context, stdout io.ReadCloser) ([]byte, error) {
+	if s.stdoutSource != stdout {
+		s.stdoutSource = stdout
+		s.stdoutReader = bufio.NewReader(stdout)
+		s.abandonedRead = nil
+	}
+
+	if s.abandonedRead != nil {
+		select {
+		case stale := <-s.abandonedRead:
+			s.abandonedRead = nil
+			return stale.data, stale.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 
-	// CRITICAL FIX: Use a timeout-aware buffered reader instead of Scanner
-	// This prevents indefinite blocking when MCP servers become unresponsive
-	resultChan := make(chan readResult, 1)
+	reader := s.stdoutReader
+	limit := s.responseLimit()
+	resultChan := make(chan lineResult, 1)
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				s.logger.Error("Panic in readMessageDirect goroutine for server %s: %v", s.Name, r)
-				resultChan <- readResult{nil, fmt.Errorf("panic in read operation: %v", r)}
+				s.logger.Error("Panic in read goroutine for server %s: %v", s.Name, r)
+				resultChan <- lineResult{nil, fmt.Errorf("panic in read operation: %v", r)}
 			}
 		}()
 
-		// Use line-by-line reading with timeout awareness
-		reader := bufio.NewReader(stdout)
-		line, _, err := reader.ReadLine()
+		data, framed, err := readMessage(reader, limit, s.logNoise)
 		if err != nil {
 			if err == io.EOF {
-				s.logger.Debug("EOF reached for server %s", serverName)
-				resultChan <- readResult{nil, io.EOF}
-			} else {
-				s.logger.Error("Read error for server %s: %v", serverName, err)
-				resultChan <- readResult{nil, err}
+				s.logger.Debug("EOF reached for server %s", s.Name)
 			}
+			resultChan <- lineResult{nil, err}
 			return
 		}
+		if framed && !s.framedByServer.Swap(true) {
+			s.logger.Info("Server %s uses Content-Length framing", s.Name)
+		}
 
-		// Successfully read line
-		data := make([]byte, len(line))
-		copy(data, line)
-		s.logger.Debug("Read message from server %s: %s", serverName, string(data))
-		resultChan <- readResult{data, nil}
+		s.logger.Debug("Read message from server %s: %s", s.Name, data)
+		resultChan <- lineResult{data, nil}
 	}()
 
-	// Wait for either the read to complete or the context to be cancelled
 	select {
 	case result := <-resultChan:
-		if result.err != nil && result.err != io.EOF {
-			s.logger.Error("Failed to read message from server %s: %v", serverName, result.err)
-		} else if result.err == nil {
-			// Message read successfully - no need to log at INFO level
-		}
 		return result.data, result.err
 	case <-ctx.Done():
-		s.logger.Warn("readMessageDirect timeout/cancellation for server %s: %v", serverName, ctx.Err())
+		s.abandonedRead = resultChan
 		return nil, ctx.Err()
 	}
 }
@@ -969,61 +1648,21 @@ func (s *Server) ReadMessage(ctx context.Context) ([]byte, error) {
 		return nil, fmt.Errorf("server not running")
 	}
 
-	// Use a channel to communicate the result from the reading goroutine
-	type readResult struct {
-		data []byte
-		err  error
-	}
-
-	// CRITICAL FIX: Use a timeout-aware buffered reader instead of Scanner
-	// This prevents indefinite blocking when MCP servers become unresponsive
-	resultChan := make(chan readResult, 1)
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				s.logger.Error("Panic in ReadMessage goroutine for server %s: %v", s.Name, r)
-				resultChan <- readResult{nil, fmt.Errorf("panic in read operation: %v", r)}
-			}
-		}()
-
-		// Use line-by-line reading with timeout awareness
-		reader := bufio.NewReader(stdout)
-		line, _, err := reader.ReadLine()
-		if err != nil {
-			if err == io.EOF {
-				s.logger.Debug("EOF reached for server %s", serverName)
-				resultChan <- readResult{nil, io.EOF}
-			} else {
-				s.logger.Error("Read error for server %s: %v", serverName, err)
-				resultChan <- readResult{nil, err}
-			}
-			return
-		}
-
-		// Successfully read line
-		data := make([]byte, len(line))
-		copy(data, line)
-		s.logger.Debug("Read message from server %s: %s", serverName, string(data))
-		resultChan <- readResult{data, nil}
-	}()
-
-	// Wait for either the read to complete or the context to be cancelled
-	select {
-	case result := <-resultChan:
-		if result.err != nil && result.err != io.EOF {
-			s.logger.Error("Failed to read message from server %s: %v", serverName, result.err)
-		} else if result.err == nil {
-			// Message read successfully - no need to log at INFO level
+	data, err := s.readLine(ctx, stdout)
+	if err != nil && err != io.EOF {
+		if ctx.Err() != nil {
+			s.logger.Warn("ReadMessage timeout/cancellation for server %s: %v", serverName, err)
+		} else {
+			s.logger.Error("Failed to read message from server %s: %v", serverName, err)
 		}
-		return result.data, result.err
-	case <-ctx.Done():
-		s.logger.Warn("ReadMessage timeout/cancellation for server %s: %v", serverName, ctx.Err())
-		return nil, ctx.Err()
 	}
+	return data, err
 }
 
 // monitor watches the process and handles restarts if needed
-func (s *Server) monitor() {
+// It is given the transport and context of the process it was started for,
+// since Stop and restarts replace the server's own while it runs.
+func (s *Server) monitor(ctx context.Context, transport Transport) {
 	defer func() {
 		if r := recover(); r != nil {
 			s.logger.Error("Panic in monitor goroutine for server %s: %v", s.Name, r)
@@ -1031,12 +1670,12 @@ func (s *Server) monitor() {
 		s.logger.Info("Monitor goroutine exiting for server %s", s.Name)
 	}()
 
-	if s.Process == nil {
+	if transport == nil {
 		s.logger.Error("No process to monitor for server %s", s.Name)
 		return
 	}
 
-	s.logger.Info("Starting monitor for server %s (PID: %d)", s.Name, s.Process.Process.Pid)
+	s.logger.Info("Starting monitor for server %s (%s)", s.Name, transport.Describe())
 
 	// Create a channel to receive the process exit status
 	done := make(chan error, 1)
@@ -1048,32 +1687,71 @@ func (s *Server) monitor() {
 			}
 		}()
 
-		err := s.Process.Wait()
+		err := transport.Wait()
 		done <- err
 	}()
 
 	// Wait for either the process to exit or context to be cancelled
 	select {
 	case err := <-done:
+		if s.stderr != nil {
+			s.stderr.Flush()
+		}
 		if err != nil {
 			s.logger.Error("MCP server %s exited with error: %v", s.Name, err)
 		} else {
 			s.logger.Info("MCP server %s exited cleanly", s.Name)
 		}
+		if ctx.Err() == nil {
+			s.recordCrash(err)
+		}
 		// TODO: Implement restart logic here if desired
 		return
-	case <-s.ctx.Done():
+	case <-ctx.Done():
 		s.logger.Info("Monitor context cancelled for server %s", s.Name)
 		// Process will be terminated by the Stop() method
 		return
 	}
 }
 
+// recordCrash adds an unexpected process exit to the incident history
+func (s *Server) recordCrash(exitErr error) {
+	reason := "process exited"
+	if exitErr != nil {
+		reason = exitErr.Error()
+	}
+	details := map[string]interface{}{"instance": s.Name}
+	if lines := s.StderrLines(10); len(lines) > 0 {
+		details["stderr"] = lines
+	}
+
+	s.incidents.RecordIncident(state.Incident{
+		Kind:    state.IncidentCrash,
+		Server:  s.configName,
+		Actor:   "monitor",
+		Reason:  reason,
+		Details: details,
+	})
+}
+
 // RestartServer restarts a specific MCP server by name
+// A running server is replaced blue/green, see replaceServer, so requests
+// arriving meanwhile don't fail. A stopped server, or one listening on a fixed
+// port its replacement couldn't bind, is stopped and started again.
 func (m *Manager) RestartServer(name string) error {
 	m.mu.Lock()
+	server, exists := m.servers[name]
+	if exists && server.replaceable() {
+		m.mu.Unlock()
+		return m.replaceServer(name, server)
+	}
 	defer m.mu.Unlock()
+	return m.restartServer(name)
+}
 
+// restartServer stops a global MCP server and starts it again
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) restartServer(name string) error {
 	server, exists := m.servers[name]
 	if !exists {
 		return fmt.Errorf("server %s not found", name)
@@ -1082,9 +1760,79 @@ func (m *Manager) RestartServer(name string) error {
 	logger.System().Info("Stopping MCP server %s for restart", name)
 	server.Stop()
 
+	// Warm instances are replaced too so none predates the restart
+	if _, pooled := m.warmPools[name]; pooled {
+		m.drainWarmPool(name)
+		go m.fillWarmPool(name)
+	}
+
 	// Wait a moment for clean shutdown
 	time.Sleep(500 * time.Millisecond)
 
 	logger.System().Info("Restarting MCP server %s", name)
 	return m.startServer(name, server.Config)
 }
+
+// StopServer stops a global MCP server and every session instance of it
+// Session instances are forgotten so the next request for them starts a fresh process.
+func (m *Manager) StopServer(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	server, exists := m.servers[name]
+	if !exists {
+		return fmt.Errorf("server %s not found", name)
+	}
+
+	logger.System().Info("Stopping MCP server %s", name)
+	server.Stop()
+	m.drainWarmPool(name)
+	delete(m.startErrors, name) // A stopped server is not retried
+
+	pooled := m.configs[name].SessionMode() == config.ModePool
+	for sessionID, sessionMap := range m.sessionServers {
+		if sessionServer, exists := sessionMap[name]; exists {
+			if !pooled {
+				logger.System().Info("Stopping server %s for session %s", name, sessionID[:8])
+				sessionServer.Stop()
+			}
+			delete(sessionMap, name)
+		}
+	}
+	if pooled {
+		m.stopInstancePool(name)
+	}
+	if fallback, exists := m.fallbacks[name]; exists {
+		fallback.Stop()
+		delete(m.failedOver, name)
+	}
+	return nil
+}
+
+// SetMaintenance puts a server into or out of maintenance mode
+// Servers in maintenance keep running but the proxy refuses new requests for them
+// and the health checker leaves them alone.
+func (m *Manager) SetMaintenance(name string, enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.configs[name]; !exists {
+		return fmt.Errorf("server %s not found", name)
+	}
+
+	if enabled {
+		m.maintenance[name] = true
+		logger.System().Info("MCP server %s entered maintenance mode", name)
+	} else {
+		delete(m.maintenance, name)
+		logger.System().Info("MCP server %s left maintenance mode", name)
+	}
+	return nil
+}
+
+// InMaintenance reports whether a server is in maintenance mode
+func (m *Manager) InMaintenance(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.maintenance[name]
+}
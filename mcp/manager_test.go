@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -275,7 +276,7 @@ func TestManagerStartAll_Integration(t *testing.T) {
 			continue
 		}
 
-		if server.Process == nil {
+		if server.Transport == nil {
 			t.Errorf("Expected server %s to have a process", name)
 			continue
 		}
@@ -299,7 +300,7 @@ func TestManagerStartAll_Integration(t *testing.T) {
 			continue
 		}
 
-		if server.Process != nil {
+		if server.Transport != nil {
 			t.Errorf("Expected server %s process to be nil after stop", name)
 		}
 	}
@@ -364,11 +365,39 @@ func TestErrorHandling(t *testing.T) {
 		t.Error("Expected server to exist even if start failed")
 	}
 
-	if server.Process != nil {
+	if server.Transport != nil {
 		t.Error("Expected process to be nil for failed start")
 	}
 }
 
+func TestStartAllIsolatesFailures(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	manager := NewManager(map[string]config.MCPServer{
+		"a-broken": {Command: "/this/command/does/not/exist"},
+		"cat":      {Command: "cat"},
+		"z-broken": {Command: "/this/command/does/not/exist/either"},
+	})
+	defer manager.StopAll()
+
+	err := manager.StartAll()
+	if err == nil || !strings.Contains(err.Error(), "a-broken") || !strings.Contains(err.Error(), "z-broken") {
+		t.Fatalf("Expected an error naming both broken servers, got %v", err)
+	}
+	if !manager.StartupComplete() {
+		t.Error("Expected startup to be complete despite failures")
+	}
+
+	for _, status := range manager.GetAllServers() {
+		failed := strings.HasSuffix(status.Name, "-broken")
+		if status.Running == failed || (status.Error != "") != failed {
+			t.Errorf("Unexpected status for %s: running=%v error=%q", status.Name, status.Running, status.Error)
+		}
+	}
+}
+
 func TestContextCancellation(t *testing.T) {
 	server := &Server{
 		Name: "test-server",
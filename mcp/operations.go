@@ -0,0 +1,211 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrOperationCancelled is returned for a request an administrator cancelled through CancelOperation
+var ErrOperationCancelled = errors.New("operation cancelled by an administrator")
+
+// OperationStatus describes a request an MCP server is working on, for /admin/operations
+type OperationStatus struct {
+	ID         string    `json:"id"`
+	Server     string    `json:"server"`
+	Instance   string    `json:"instance,omitempty"` // Session or pooled instance, when not the shared server
+	Method     string    `json:"method"`
+	Tool       string    `json:"tool,omitempty"`
+	Session    string    `json:"session,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	Expired    bool      `json:"expired,omitempty"` // Running longer than the server's operation timeout
+}
+
+// ActiveOperations returns the requests the server is working on, oldest first
+func (s *Server) ActiveOperations() []OperationStatus {
+	timeout := s.OperationTimeout()
+
+	s.operationsMu.RLock()
+	defer s.operationsMu.RUnlock()
+
+	now := time.Now()
+	operations := make([]OperationStatus, 0, len(s.activeOperations))
+	for _, info := range s.activeOperations {
+		status := OperationStatus{
+			ID:         info.RequestID,
+			Server:     s.configName,
+			Method:     info.Method,
+			Tool:       info.ToolName,
+			Session:    info.SessionID,
+			StartedAt:  info.StartTime,
+			DurationMs: now.Sub(info.StartTime).Milliseconds(),
+			Expired:    now.Sub(info.StartTime) > timeout,
+		}
+		if s.Name != s.configName {
+			status.Instance = s.Name
+		}
+		operations = append(operations, status)
+	}
+	sortOperations(operations)
+	return operations
+}
+
+// CancelOperation cancels one of the server's requests and reports whether it was in flight
+// The caller waiting on it gets ErrOperationCancelled, and the server is sent
+// notifications/cancelled as for any abandoned request. The operation is no
+// longer listed from then on.
+func (s *Server) CancelOperation(id string) bool {
+	s.operationsMu.Lock()
+	info, exists := s.activeOperations[id]
+	if exists && info.cancel != nil {
+		delete(s.activeOperations, id)
+	}
+	s.operationsMu.Unlock()
+
+	if !exists || info.cancel == nil {
+		return false
+	}
+	info.cancel(ErrOperationCancelled)
+	s.logger.Info("OPERATION CANCEL: %s %s (tool: %s) on server %s", info.Method, id[:8], info.ToolName, s.Name)
+	return true
+}
+
+// withOperation derives the context a tracked request runs under, so CancelOperation can end it
+func withOperation(ctx context.Context, info *OperationInfo) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	info.cancel = cancel
+	return ctx, func() { cancel(nil) }
+}
+
+// operationError reports an operator's cancellation as ErrOperationCancelled instead of context.Canceled
+func operationError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(context.Cause(ctx), ErrOperationCancelled) {
+		return ErrOperationCancelled
+	}
+	return err
+}
+
+// defaultOperationTimeoutSec is how long an operation may run, in seconds, when nothing sets it
+const defaultOperationTimeoutSec = 300
+
+// SetOperationTimeout sets how long operations may run before session cleanup stops waiting for them
+// Servers with operationTimeout in their config keep their own. It applies to
+// every server, including future session instances, and should be called
+// during startup, before servers are started.
+func (m *Manager) SetOperationTimeout(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.operationTimeout = timeout
+	seconds := m.operationTimeoutSec()
+	for _, server := range m.servers {
+		server.operationTimeoutSec = seconds
+	}
+	for _, server := range m.fallbacks {
+		server.operationTimeoutSec = seconds
+	}
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			server.operationTimeoutSec = seconds
+		}
+	}
+}
+
+// operationTimeoutSec returns the operation timeout of new instances, in seconds
+func (m *Manager) operationTimeoutSec() int {
+	if seconds := int(m.operationTimeout / time.Second); seconds > 0 {
+		return seconds
+	}
+	return defaultOperationTimeoutSec
+}
+
+// OperationTimeout returns how long the server's operations may run before cleanup stops waiting for them
+func (s *Server) OperationTimeout() time.Duration {
+	if d, err := time.ParseDuration(s.Config.OperationTimeout); err == nil && d > 0 {
+		return d
+	}
+	if s.operationTimeoutSec > 0 {
+		return time.Duration(s.operationTimeoutSec) * time.Second
+	}
+	return defaultOperationTimeoutSec * time.Second
+}
+
+// runningServers returns every distinct server instance: shared, fallback, draining, pooled and session-owned
+func (m *Manager) runningServers() []*Server {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[*Server]bool)
+	var servers []*Server
+	add := func(server *Server) {
+		if server != nil && !seen[server] {
+			seen[server] = true
+			servers = append(servers, server)
+		}
+	}
+	for _, server := range m.servers {
+		add(server)
+	}
+	for _, server := range m.fallbacks {
+		add(server)
+	}
+	for server := range m.draining {
+		add(server)
+	}
+	for _, pool := range m.instancePools {
+		for _, server := range pool {
+			add(server)
+		}
+	}
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			add(server)
+		}
+	}
+	return servers
+}
+
+// ActiveOperations returns the requests in flight on every server, oldest first
+func (m *Manager) ActiveOperations() []OperationStatus {
+	var operations []OperationStatus
+	for _, server := range m.runningServers() {
+		operations = append(operations, server.ActiveOperations()...)
+	}
+	sortOperations(operations)
+	return operations
+}
+
+// SessionOperations returns the requests in flight for a session, on its own servers and shared ones
+func (m *Manager) SessionOperations(sessionID string) []OperationStatus {
+	var operations []OperationStatus
+	for _, operation := range m.ActiveOperations() {
+		if operation.Session == sessionID {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+// CancelOperation cancels a request by the ID shown in ActiveOperations, reporting whether it was found
+func (m *Manager) CancelOperation(id string) (OperationStatus, bool) {
+	for _, server := range m.runningServers() {
+		for _, operation := range server.ActiveOperations() {
+			if operation.ID == id && server.CancelOperation(id) {
+				return operation, true
+			}
+		}
+	}
+	return OperationStatus{}, false
+}
+
+// sortOperations orders operations by start time, then ID
+func sortOperations(operations []OperationStatus) {
+	sort.Slice(operations, func(i, j int) bool {
+		if !operations[i].StartedAt.Equal(operations[j].StartedAt) {
+			return operations[i].StartedAt.Before(operations[j].StartedAt)
+		}
+		return operations[i].ID < operations[j].ID
+	})
+}
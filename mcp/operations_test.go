@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestOperationTimeout(t *testing.T) {
+	manager := NewManager(map[string]config.MCPServer{
+		"thinking": {Command: "echo", OperationTimeout: "30m"},
+		"memory":   {Command: "echo"},
+	})
+	thinking, _ := manager.GetServer("thinking")
+	memory, _ := manager.GetServer("memory")
+
+	if got := memory.OperationTimeout(); got != 5*time.Minute {
+		t.Errorf("Expected the default operation timeout, got %v", got)
+	}
+	manager.SetOperationTimeout(10 * time.Minute)
+	if got := memory.OperationTimeout(); got != 10*time.Minute {
+		t.Errorf("Expected the manager's operation timeout, got %v", got)
+	}
+	if got := thinking.OperationTimeout(); got != 30*time.Minute {
+		t.Errorf("Expected the configured operation timeout, got %v", got)
+	}
+	if got := memory.GetOperationTimeoutSec(); got != 600 {
+		t.Errorf("Expected 600 seconds, got %d", got)
+	}
+
+	// Operations are expired once they run longer than their server's timeout
+	for _, server := range []*Server{thinking, memory} {
+		server.startOperation(&OperationInfo{RequestID: "op-" + server.Name, Method: "tools/call", StartTime: time.Now().Add(-20 * time.Minute)})
+	}
+	for _, operation := range manager.ActiveOperations() {
+		if want := operation.Server == "memory"; operation.Expired != want {
+			t.Errorf("%s: expected expired %v, got %v", operation.Server, want, operation.Expired)
+		}
+	}
+	if !memory.IsOperationExpired() || thinking.IsOperationExpired() {
+		t.Error("Expected only the memory server's operation to be expired")
+	}
+}
@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// portReservation is how long an allocated port isn't handed out again, time for its server to listen on it
+const portReservation = 2 * time.Minute
+
+// portAllocator hands out free local TCP ports
+// The OS picks ports nothing listens on; recently handed out ports are skipped
+// too, as their server may not have started listening yet.
+type portAllocator struct {
+	mu       sync.Mutex
+	reserved map[int]time.Time // Allocated ports and when their reservation ends
+}
+
+// allocate returns a free port and reserves it
+func (a *portAllocator) allocate() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.reserved == nil {
+		a.reserved = make(map[int]time.Time)
+	}
+	for port, until := range a.reserved {
+		if now.After(until) {
+			delete(a.reserved, port)
+		}
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return 0, fmt.Errorf("failed to listen on a free port: %w", err)
+		}
+		port := listener.Addr().(*net.TCPAddr).Port
+		listener.Close()
+		if _, taken := a.reserved[port]; !taken {
+			a.reserved[port] = now.Add(portReservation)
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port found")
+}
+
+// assignPort gives an instance the port it listens on
+// "tcp" servers without a fixed port and servers using {PORT} get a free port,
+// which replaces {PORT} in their args, env and volumes and is set as Port.
+// When no port is free the configuration is returned unchanged and starting
+// the server fails.
+func (m *Manager) assignPort(name string, cfg config.MCPServer) config.MCPServer {
+	if cfg.Port != 0 || (cfg.ServerType() != config.TypeTCP && !usesVariable(cfg, "{PORT}")) {
+		return cfg
+	}
+	port, err := m.ports.allocate()
+	if err != nil {
+		logger.System().Error("Failed to allocate a port for MCP server %s: %v", name, err)
+		return cfg
+	}
+
+	value := strconv.Itoa(port)
+	cfg.Port = port
+	cfg.Args = replaceAll(cfg.Args, "{PORT}", value)
+	cfg.Volumes = replaceAll(cfg.Volumes, "{PORT}", value)
+	if len(cfg.Env) > 0 {
+		env := make(map[string]string, len(cfg.Env))
+		for key, v := range cfg.Env {
+			env[key] = strings.ReplaceAll(v, "{PORT}", value)
+		}
+		cfg.Env = env
+	}
+	logger.System().Debug("Allocated port %d to MCP server %s", port, name)
+	return cfg
+}
+
+// replaceAll returns a copy of values with old replaced by new in each
+func replaceAll(values []string, old, new string) []string {
+	if values == nil {
+		return nil
+	}
+	replaced := make([]string, len(values))
+	for i, v := range values {
+		replaced[i] = strings.ReplaceAll(v, old, new)
+	}
+	return replaced
+}
@@ -0,0 +1,218 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// maxConcurrentPreinstalls bounds the preinstall commands running at once
+const maxConcurrentPreinstalls = 4
+
+// Preinstall states
+const (
+	PreinstallPending = "pending"
+	PreinstallRunning = "running"
+	PreinstallDone    = "done"
+	PreinstallFailed  = "failed"
+)
+
+// PreinstallStatus reports the package installation run before a server first starts
+type PreinstallStatus struct {
+	State      string     `json:"state"`
+	Command    []string   `json:"command"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	DurationMs int64      `json:"durationMs,omitempty"`
+	Error      string     `json:"error,omitempty"` // Why the command failed, with the end of its output
+}
+
+// PreinstallOptions select which servers are preinstalled
+type PreinstallOptions struct {
+	Auto    bool          // Derive a command for npx, uvx and docker servers without one
+	Timeout time.Duration // How long one command may run (unbounded when 0)
+}
+
+// SetPreinstall runs preinstall commands at the beginning of StartAll
+// It should be called during startup, before StartAll. Servers with a
+// preinstall command in their configuration are preinstalled either way.
+func (m *Manager) SetPreinstall(opts PreinstallOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.preinstallOpts = opts
+}
+
+// PreinstallStatus returns the preinstall progress of every preinstalled server
+func (m *Manager) PreinstallStatus() map[string]PreinstallStatus {
+	m.preinstallMu.Lock()
+	defer m.preinstallMu.Unlock()
+
+	statuses := make(map[string]PreinstallStatus, len(m.preinstalls))
+	for name, status := range m.preinstalls {
+		statuses[name] = *status
+	}
+	return statuses
+}
+
+// preinstallAll runs the preinstall command of every server, a few at a time
+// A failed command is logged and reported but doesn't prevent the server from starting.
+// NOTE: This method must be called without m.mu held, so status endpoints answer meanwhile
+func (m *Manager) preinstallAll() {
+	m.mu.RLock()
+	opts := m.preinstallOpts
+	commands := make(map[string][]string)
+	configs := make(map[string]config.MCPServer)
+	for name, server := range m.servers {
+		if command := preinstallCommand(server.Config, opts.Auto); len(command) > 0 {
+			commands[name] = command
+			configs[name] = server.Config
+		}
+	}
+	m.mu.RUnlock()
+	if len(commands) == 0 {
+		return
+	}
+
+	m.preinstallMu.Lock()
+	for name, command := range commands {
+		m.preinstalls[name] = &PreinstallStatus{State: PreinstallPending, Command: command}
+	}
+	m.preinstallMu.Unlock()
+
+	logger.System().Info("Preinstalling %d MCP servers", len(commands))
+	slots := make(chan struct{}, maxConcurrentPreinstalls)
+	var wg sync.WaitGroup
+	for name, command := range commands {
+		wg.Add(1)
+		go func(name string, command []string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			m.preinstall(name, command, configs[name], opts.Timeout)
+		}(name, command)
+	}
+	wg.Wait()
+}
+
+// preinstall runs one server's preinstall command and records its progress
+func (m *Manager) preinstall(name string, command []string, cfg config.MCPServer, timeout time.Duration) {
+	startedAt := time.Now()
+	m.preinstallMu.Lock()
+	status := m.preinstalls[name]
+	status.State = PreinstallRunning
+	status.StartedAt = &startedAt
+	m.preinstallMu.Unlock()
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// The command runs like the server itself, with its environment and sandbox,
+	// and stdin at EOF so a server started by mistake exits at once
+	cfg.Command, cfg.Args = command[0], command[1:]
+	if cfg.ServerType() == config.TypeDocker {
+		cfg.Sandbox = nil
+	}
+	cmd := serverCommand(ctx, cfg, "")
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	logger.System().Info("Preinstalling MCP server %s: %s", name, strings.Join(command, " "))
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+
+	m.preinstallMu.Lock()
+	defer m.preinstallMu.Unlock()
+	status.DurationMs = time.Since(startedAt).Milliseconds()
+	if err != nil {
+		status.State = PreinstallFailed
+		status.Error = err.Error()
+		if tail := lastLines(output.String(), 5); tail != "" {
+			status.Error += ": " + tail
+		}
+		logger.System().Warn("Preinstall of MCP server %s failed after %dms: %s", name, status.DurationMs, status.Error)
+		return
+	}
+	status.State = PreinstallDone
+	logger.System().Info("Preinstalled MCP server %s in %dms", name, status.DurationMs)
+}
+
+// preinstallCommand returns the command preinstalling a server, or nil when there is none
+// Without a configured command, auto mode derives one: "docker pull" for docker
+// servers, and for npx and uvx servers the package's --help, which downloads it.
+func preinstallCommand(cfg config.MCPServer, auto bool) []string {
+	if len(cfg.Preinstall) > 0 {
+		return cfg.Preinstall
+	}
+	if !auto {
+		return nil
+	}
+
+	switch cfg.ServerType() {
+	case config.TypeDocker:
+		if cfg.Image != "" {
+			return []string{"docker", "pull", cfg.Image}
+		}
+	case config.TypeStdio, config.TypeTCP:
+		switch filepath.Base(cfg.Command) {
+		case "npx":
+			if prefix := packagePrefix(cfg.Args, "-p", "--package"); prefix != nil {
+				if !containsString(prefix, "-y") && !containsString(prefix, "--yes") {
+					prefix = append([]string{"-y"}, prefix...)
+				}
+				return append(append([]string{cfg.Command}, prefix...), "--help")
+			}
+		case "uvx":
+			if prefix := packagePrefix(cfg.Args, "--from", "--with", "--python", "-p", "--index-url"); prefix != nil {
+				return append(append([]string{cfg.Command}, prefix...), "--help")
+			}
+		}
+	}
+	return nil
+}
+
+// packagePrefix returns args up to and including the package name, the first argument
+// that is neither a flag nor the value of one of valueFlags
+func packagePrefix(args []string, valueFlags ...string) []string {
+	for i := 0; i < len(args); i++ {
+		if containsString(valueFlags, args[i]) {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(args[i], "-") {
+			return append([]string{}, args[:i+1]...)
+		}
+	}
+	return nil
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// lastLines returns the last n non-empty lines of output, joined by " | "
+func lastLines(output string, n int) string {
+	lines := strings.FieldsFunc(output, func(r rune) bool { return r == '\n' || r == '\r' })
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, " | ")
+}
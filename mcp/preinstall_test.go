@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestPreinstallCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.MCPServer
+		auto bool
+		want []string
+	}{
+		{"configured", config.MCPServer{Command: "node", Preinstall: []string{"npm", "ci"}}, false, []string{"npm", "ci"}},
+		{"off", config.MCPServer{Command: "npx", Args: []string{"-y", "server-memory"}}, false, nil},
+		{"npx", config.MCPServer{Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-filesystem", "/data"}}, true,
+			[]string{"npx", "-y", "@modelcontextprotocol/server-filesystem", "--help"}},
+		{"npx without -y", config.MCPServer{Command: "/usr/bin/npx", Args: []string{"-p", "pkg", "bin"}}, true,
+			[]string{"/usr/bin/npx", "-y", "-p", "pkg", "bin", "--help"}},
+		{"uvx", config.MCPServer{Command: "uvx", Args: []string{"--from", "git+https://example.com/repo", "mcp-server-git", "--repository", "."}}, true,
+			[]string{"uvx", "--from", "git+https://example.com/repo", "mcp-server-git", "--help"}},
+		{"docker", config.MCPServer{Type: config.TypeDocker, Image: "mcp/fetch:latest"}, true, []string{"docker", "pull", "mcp/fetch:latest"}},
+		{"other command", config.MCPServer{Command: "python3", Args: []string{"server.py"}}, true, nil},
+		{"remote", config.MCPServer{Type: config.TypeSSE, URL: "https://mcp.example.com/sse"}, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preinstallCommand(tt.cfg, tt.auto); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPreinstall(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	manager := NewManager(map[string]config.MCPServer{
+		"installed": {Command: "cat", Preinstall: []string{"true"}},
+		"broken":    {Command: "cat", Preinstall: []string{"/bin/sh", "-c", "echo registry unreachable >&2; exit 3"}},
+		"slow":      {Command: "cat", Preinstall: []string{"sleep", "10"}},
+		"plain":     {Command: "cat"},
+	})
+	manager.SetPreinstall(PreinstallOptions{Timeout: 200 * time.Millisecond})
+	defer manager.StopAll()
+
+	// Failed preinstalls don't prevent servers from starting
+	if err := manager.StartAll(); err != nil {
+		t.Fatalf("Unexpected error starting servers: %v", err)
+	}
+
+	statuses := manager.PreinstallStatus()
+	if len(statuses) != 3 {
+		t.Fatalf("Expected 3 preinstalled servers, got %v", statuses)
+	}
+	if statuses["installed"].State != PreinstallDone {
+		t.Errorf("Expected installed to be done, got %+v", statuses["installed"])
+	}
+	if broken := statuses["broken"]; broken.State != PreinstallFailed || !strings.Contains(broken.Error, "registry unreachable") {
+		t.Errorf("Expected broken to fail with its output, got %+v", broken)
+	}
+	if slow := statuses["slow"]; slow.State != PreinstallFailed || !strings.Contains(slow.Error, "timed out") {
+		t.Errorf("Expected slow to time out, got %+v", slow)
+	}
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+// recycleDue returns why the server's recycle policy asks for a restart, or "" when it doesn't
+func (s *Server) recycleDue() string {
+	policy := s.Config.Recycle
+	if policy == nil {
+		return ""
+	}
+
+	s.mu.RLock()
+	running, age := s.Transport != nil, time.Since(s.startedAt)
+	s.mu.RUnlock()
+	if !running {
+		return ""
+	}
+
+	if maxAge := policy.MaxAgeDuration(); maxAge > 0 && age >= maxAge {
+		return fmt.Sprintf("process running for %v (maxAge %s)", age.Round(time.Second), policy.MaxAge)
+	}
+	if handled := s.handled.Load(); policy.MaxRequests > 0 && handled >= policy.MaxRequests {
+		return fmt.Sprintf("%d requests handled (maxRequests %d)", handled, policy.MaxRequests)
+	}
+	return ""
+}
+
+// RecycleIfDue restarts a server whose recycle policy is due, provided no request is in flight
+// It returns why the server was recycled, or "" when it was not due or still busy;
+// a busy server is recycled by a later call. Servers in maintenance are left alone.
+func (m *Manager) RecycleIfDue(name string) (string, error) {
+	m.mu.Lock()
+	server, exists := m.servers[name]
+	if !exists || m.maintenance[name] {
+		m.mu.Unlock()
+		return "", nil
+	}
+	reason := server.recycleDue()
+	if reason == "" {
+		m.mu.Unlock()
+		return "", nil
+	}
+	if server.HasActiveOperations() {
+		m.mu.Unlock()
+		logger.System().Debug("Recycling MCP server %s postponed: %d requests in flight", name, server.GetActiveOperationCount())
+		return "", nil
+	}
+
+	logger.System().Info("Recycling MCP server %s: %s", name, reason)
+	server.recycles++
+	m.mu.Unlock()
+	return reason, m.RestartServer(name)
+}
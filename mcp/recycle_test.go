@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestRecycleIfDue(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	leaky := answeringServerConfig()
+	leaky.Recycle = &config.Recycle{MaxAge: "1h", MaxRequests: 100}
+	manager := NewManager(map[string]config.MCPServer{"leaky": leaky})
+	manager.SetStopGracePeriod(time.Second)
+	if err := manager.StartAll(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer manager.StopAll()
+	server, _ := manager.GetServer("leaky")
+
+	if reason, err := manager.RecycleIfDue("leaky"); reason != "" || err != nil {
+		t.Fatalf("Expected a fresh server not to be recycled, got %q (%v)", reason, err)
+	}
+
+	// A server due for recycling waits for its requests to finish
+	server.handled.Store(100)
+	server.startOperation(&OperationInfo{RequestID: "operation-1", Method: "tools/call", StartTime: time.Now()})
+	if reason, _ := manager.RecycleIfDue("leaky"); reason != "" {
+		t.Fatalf("Expected a busy server not to be recycled, got %q", reason)
+	}
+	server.endOperation("operation-1")
+
+	pid := manager.GetAllServers()[0].PID
+	reason, err := manager.RecycleIfDue("leaky")
+	if reason == "" || err != nil {
+		t.Fatalf("Expected the idle server to be recycled, got %q (%v)", reason, err)
+	}
+	status := manager.GetAllServers()[0]
+	if !status.Running || status.PID == pid || status.Recycles != 1 || status.Restarts != 1 || status.Requests != 0 {
+		t.Errorf("Expected a new process counted as recycled, got %+v (old PID %d)", status, pid)
+	}
+
+	// Process age counts too
+	server, _ = manager.GetServer("leaky")
+	server.mu.Lock()
+	server.startedAt = time.Now().Add(-2 * time.Hour)
+	server.mu.Unlock()
+	if reason := server.recycleDue(); reason == "" {
+		t.Error("Expected a process older than maxAge to be due")
+	}
+}
@@ -0,0 +1,408 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// remoteConnectTimeout bounds how long an "sse" transport waits for its endpoint event
+const remoteConnectTimeout = 30 * time.Second
+
+// remoteSessionHeader carries the session of a Streamable HTTP server
+const remoteSessionHeader = "Mcp-Session-Id"
+
+// errTransportClosed ends a remote transport that was stopped on purpose
+var errTransportClosed = errors.New("transport closed")
+
+// HTTPTransport reaches an MCP server already running elsewhere, over HTTP
+//
+// Messages written to the transport are POSTed to the server; its answers, whether
+// JSON bodies or SSE events, come out of the reader one line each. "sse" servers
+// push everything on the stream opened at start, whose endpoint event names the
+// URL messages are posted to. "streamable-http" servers answer on each POST and
+// may push server-initiated messages on a GET stream once a session exists.
+type HTTPTransport struct {
+	cfg    config.MCPServer
+	client *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	out    *io.PipeWriter
+
+	mu            sync.Mutex
+	endpoint      string        // URL messages are POSTed to
+	endpointReady chan struct{} // Closed once endpoint is known
+	sessionID     string        // Session assigned by a Streamable HTTP server
+
+	done     chan struct{}
+	doneOnce sync.Once
+	err      error
+}
+
+// NewHTTPTransport creates the transport of an "sse" or "streamable-http" server
+func NewHTTPTransport(opts TransportOptions) Transport {
+	cfg := opts.Config
+	t := &HTTPTransport{
+		cfg:           cfg,
+		client:        &http.Client{},
+		endpointReady: make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if cfg.Type == config.TypeStreamableHTTP {
+		t.endpoint = cfg.URL
+		close(t.endpointReady)
+	}
+	return t
+}
+
+// Start connects to the server; "sse" transports wait for the endpoint event
+func (t *HTTPTransport) Start(ctx context.Context) (io.WriteCloser, io.ReadCloser, error) {
+	t.ctx, t.cancel = context.WithCancel(ctx)
+	reader, writer := io.Pipe()
+	t.out = writer
+
+	if t.cfg.Type == config.TypeSSE {
+		if err := t.connectSSE(); err != nil {
+			t.finish(err)
+			return nil, nil, err
+		}
+	}
+	return &httpWriter{transport: t}, reader, nil
+}
+
+// connectSSE opens the event stream of an "sse" server and waits for its endpoint
+func (t *HTTPTransport) connectSSE() error {
+	resp, err := t.do(t.ctx, http.MethodGet, t.cfg.URL, nil, "text/event-stream")
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", t.cfg.URL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fmt.Errorf("failed to connect to %s: HTTP %d", t.cfg.URL, resp.StatusCode)
+	}
+
+	go func() {
+		defer resp.Body.Close()
+		err := readSSEEvents(resp.Body, func(event, data string) {
+			if event == "endpoint" {
+				t.setEndpoint(data)
+				return
+			}
+			t.deliver([]byte(data))
+		})
+		if err == nil {
+			err = fmt.Errorf("event stream of %s closed", t.cfg.URL)
+		}
+		t.finish(err)
+	}()
+
+	select {
+	case <-t.endpointReady:
+		return nil
+	case <-t.done:
+		return t.err
+	case <-time.After(remoteConnectTimeout):
+		return fmt.Errorf("no endpoint event from %s within %v", t.cfg.URL, remoteConnectTimeout)
+	}
+}
+
+// setEndpoint records the message URL announced by an "sse" server
+// The data is the URI itself, or a JSON object with a "uri" key as some proxies send.
+func (t *HTTPTransport) setEndpoint(data string) {
+	var object struct {
+		URI string `json:"uri"`
+	}
+	if json.Unmarshal([]byte(data), &object) == nil && object.URI != "" {
+		data = object.URI
+	}
+	base, _ := url.Parse(t.cfg.URL)
+	ref, err := url.Parse(strings.TrimSpace(data))
+	if err != nil {
+		logger.System().Warn("Ignoring invalid endpoint %q from %s", data, t.cfg.URL)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.endpoint == "" {
+		t.endpoint = base.ResolveReference(ref).String()
+		close(t.endpointReady)
+	}
+}
+
+// send POSTs one message to the server and delivers what it answers
+func (t *HTTPTransport) send(message []byte) {
+	select {
+	case <-t.endpointReady:
+	case <-t.done:
+		return
+	}
+
+	resp, err := t.do(t.ctx, http.MethodPost, t.endpoint, message, "application/json, text/event-stream")
+	if err != nil {
+		if t.ctx.Err() == nil {
+			t.failRequest(message, fmt.Sprintf("Failed to reach remote MCP server: %v", err))
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	streamable := t.cfg.Type == config.TypeStreamableHTTP
+	if sessionID := resp.Header.Get(remoteSessionHeader); streamable && sessionID != "" && t.setSessionID(sessionID) {
+		go t.listen()
+	}
+
+	switch {
+	case streamable && resp.StatusCode == http.StatusNotFound && t.currentSessionID() != "":
+		t.finish(fmt.Errorf("session expired on %s", t.cfg.URL))
+		return
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		t.failRequest(message, fmt.Sprintf("Remote MCP server returned HTTP %d", resp.StatusCode))
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		readSSEEvents(resp.Body, func(event, data string) {
+			if event == "message" {
+				t.deliver([]byte(data))
+			}
+		})
+	case "application/json":
+		body, err := io.ReadAll(resp.Body)
+		if err == nil && len(bytes.TrimSpace(body)) > 0 {
+			t.deliver(body)
+		}
+	}
+}
+
+// listen reads the GET stream of a Streamable HTTP server for messages it initiates
+// Servers without one answer 405, which is fine: they only talk in responses.
+func (t *HTTPTransport) listen() {
+	resp, err := t.do(t.ctx, http.MethodGet, t.cfg.URL, nil, "text/event-stream")
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logger.System().Debug("No server-initiated message stream on %s: HTTP %d", t.cfg.URL, resp.StatusCode)
+		return
+	}
+	readSSEEvents(resp.Body, func(event, data string) {
+		if event == "message" {
+			t.deliver([]byte(data))
+		}
+	})
+}
+
+// do sends a request with the configured headers and the current session
+func (t *HTTPTransport) do(ctx context.Context, method, target string, body []byte, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if sessionID := t.currentSessionID(); sessionID != "" {
+		req.Header.Set(remoteSessionHeader, sessionID)
+	}
+	for key, value := range t.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	return t.client.Do(req)
+}
+
+// setSessionID records the server's session, reporting whether it is new
+func (t *HTTPTransport) setSessionID(sessionID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID == sessionID {
+		return false
+	}
+	t.sessionID = sessionID
+	return true
+}
+
+// currentSessionID returns the server's session, empty before initialize
+func (t *HTTPTransport) currentSessionID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID
+}
+
+// deliver hands messages to the Server's reader, one line each
+// Batches are split so every line holds a single message.
+func (t *HTTPTransport) deliver(data []byte) {
+	data = bytes.TrimSpace(data)
+	var messages []json.RawMessage
+	if len(data) > 0 && data[0] == '[' && json.Unmarshal(data, &messages) == nil {
+		for _, message := range messages {
+			t.deliver(message)
+		}
+		return
+	}
+
+	var line bytes.Buffer
+	if err := json.Compact(&line, data); err != nil {
+		logger.System().Warn("Dropping invalid message from %s: %v", t.cfg.URL, err)
+		return
+	}
+	line.WriteByte('\n')
+	t.out.Write(line.Bytes()) // Fails only once the transport has ended
+}
+
+// failRequest answers a request the server could not be reached for with a JSON-RPC error
+// Without it the request would wait for its timeout; notifications are just dropped.
+func (t *HTTPTransport) failRequest(message []byte, reason string) {
+	logger.System().Warn("%s (%s)", reason, t.cfg.URL)
+	var request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if json.Unmarshal(message, &request) != nil || len(request.ID) == 0 || request.Method == "" {
+		return
+	}
+	response, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      request.ID,
+		"error":   map[string]interface{}{"code": -32603, "message": reason},
+	})
+	if err == nil {
+		t.deliver(response)
+	}
+}
+
+// finish ends the transport once, closing the reader and the server's session
+func (t *HTTPTransport) finish(err error) {
+	t.doneOnce.Do(func() {
+		t.err = err
+		if sessionID := t.currentSessionID(); sessionID != "" {
+			// Let the server free the session; it is gone either way
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if resp, err := t.do(ctx, http.MethodDelete, t.cfg.URL, nil, ""); err == nil {
+				resp.Body.Close()
+			}
+			cancel()
+		}
+		if t.cancel != nil {
+			t.cancel()
+		}
+		if t.out != nil {
+			t.out.Close()
+		}
+		close(t.done)
+	})
+}
+
+// Wait blocks until the transport has ended
+func (t *HTTPTransport) Wait() error {
+	<-t.done
+	if errors.Is(t.err, errTransportClosed) {
+		return nil
+	}
+	return t.err
+}
+
+// Kill ends the transport right away
+func (t *HTTPTransport) Kill() error {
+	t.finish(errTransportClosed)
+	return nil
+}
+
+// PID returns 0: there is no local process
+func (t *HTTPTransport) PID() int {
+	return 0
+}
+
+// Describe identifies the remote server
+func (t *HTTPTransport) Describe() string {
+	return "URL: " + t.cfg.URL
+}
+
+// httpWriter turns the lines the Server writes into POSTs
+type httpWriter struct {
+	transport *HTTPTransport
+	pending   []byte
+}
+
+// Write sends every complete line as a message, each on its own request
+// Requests run concurrently so a slow call doesn't hold up the next one, as with stdio.
+func (w *httpWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.transport.done:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			break
+		}
+		message := bytes.TrimSpace(w.pending[:end])
+		w.pending = w.pending[end+1:]
+		if len(message) > 0 {
+			go w.transport.send(append([]byte(nil), message...))
+		}
+	}
+	return len(p), nil
+}
+
+// Close ends the transport, as closing a process's stdin asks it to exit
+func (w *httpWriter) Close() error {
+	w.transport.finish(errTransportClosed)
+	return nil
+}
+
+// readSSEEvents calls handle for each event of an SSE stream until it ends
+// Events without an event field are "message" events, as in browsers.
+func readSSEEvents(r io.Reader, handle func(event, data string)) error {
+	reader := bufio.NewReader(r)
+	event, data := "", []string(nil)
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "" && err == nil:
+			if len(data) > 0 {
+				if event == "" {
+					event = "message"
+				}
+				handle(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment, used for keep-alives
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
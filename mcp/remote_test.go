@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+// echoResult answers a JSON-RPC request with its method as the result
+func echoResult(t *testing.T, body io.Reader) []byte {
+	t.Helper()
+	var request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		t.Errorf("Invalid request: %v", err)
+	}
+	return []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"method":%q}}`, request.ID, request.Method))
+}
+
+// readRemoteLine reads the next message a transport delivers
+func readRemoteLine(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := reader.ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		return strings.TrimSpace(line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a message from the remote transport")
+		return ""
+	}
+}
+
+func TestStreamableHTTPTransport(t *testing.T) {
+	deleted := make(chan string, 1)
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			http.Error(w, "no stream", http.StatusMethodNotAllowed)
+		case http.MethodDelete:
+			deleted <- r.Header.Get("Mcp-Session-Id")
+		case http.MethodPost:
+			response := echoResult(t, r.Body)
+			if strings.Contains(string(response), `"initialize"`) {
+				w.Header().Set("Mcp-Session-Id", "remote-session")
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(response)
+				return
+			}
+			if r.Header.Get("Mcp-Session-Id") != "remote-session" {
+				http.Error(w, "missing session", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\ndata: %s\n\n", response)
+		}
+	}))
+	defer remote.Close()
+
+	transport := NewHTTPTransport(TransportOptions{Config: config.MCPServer{
+		Type:    config.TypeStreamableHTTP,
+		URL:     remote.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	}})
+	stdin, stdout, err := transport.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to start transport: %v", err)
+	}
+	reader := bufio.NewReader(stdout)
+
+	stdin.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize"}` + "\n"))
+	if line := readRemoteLine(t, reader); line != `{"jsonrpc":"2.0","id":1,"result":{"method":"initialize"}}` {
+		t.Errorf("Unexpected initialize response: %s", line)
+	}
+
+	stdin.Write([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}` + "\n"))
+	if line := readRemoteLine(t, reader); !strings.Contains(line, "notifications/progress") {
+		t.Errorf("Expected the streamed notification first, got %s", line)
+	}
+	if line := readRemoteLine(t, reader); line != `{"jsonrpc":"2.0","id":2,"result":{"method":"tools/list"}}` {
+		t.Errorf("Unexpected streamed response: %s", line)
+	}
+
+	stdin.Close()
+	if err := transport.Wait(); err != nil {
+		t.Errorf("Expected a clean end after closing, got %v", err)
+	}
+	select {
+	case sessionID := <-deleted:
+		if sessionID != "remote-session" {
+			t.Errorf("Expected the remote session deleted, got %q", sessionID)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the remote session to be deleted on close")
+	}
+}
+
+func TestSSETransport(t *testing.T) {
+	messages := make(chan []byte, 4)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: endpoint\ndata: /messages?session=abc\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case message := <-messages:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", message)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session") != "abc" {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		messages <- echoResult(t, r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	remote := httptest.NewServer(mux)
+	defer remote.Close()
+
+	manager := NewManager(map[string]config.MCPServer{
+		"legacy": {Type: config.TypeSSE, URL: remote.URL + "/sse"},
+	})
+	if err := manager.StartAll(); err != nil {
+		t.Fatalf("Failed to start servers: %v", err)
+	}
+	defer manager.StopAll()
+
+	server, _ := manager.GetServer("legacy")
+	if !server.IsRunning() || server.PID() != 0 {
+		t.Errorf("Expected a running server without a process, got PID %d", server.PID())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	response, err := server.SendAndReceive(ctx, []byte(`{"jsonrpc":"2.0","id":7,"method":"tools/list"}`))
+	if err != nil {
+		t.Fatalf("Request through the SSE transport failed: %v", err)
+	}
+	if string(response) != `{"jsonrpc":"2.0","id":7,"result":{"method":"tools/list"}}` {
+		t.Errorf("Unexpected response: %s", response)
+	}
+}
+
+func TestHTTPTransportError(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer remote.Close()
+
+	transport := NewHTTPTransport(TransportOptions{Config: config.MCPServer{Type: config.TypeStreamableHTTP, URL: remote.URL}})
+	stdin, stdout, err := transport.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to start transport: %v", err)
+	}
+	defer transport.Kill()
+
+	stdin.Write([]byte(`{"jsonrpc":"2.0","id":"a","method":"tools/list"}` + "\n"))
+	line := readRemoteLine(t, bufio.NewReader(stdout))
+	if !strings.Contains(line, `"id":"a"`) || !strings.Contains(line, "HTTP 500") {
+		t.Errorf("Expected a JSON-RPC error for the failed request, got %s", line)
+	}
+}
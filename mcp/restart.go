@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+// defaultDrainPollInterval is how often a replaced server is checked for requests still in flight
+const defaultDrainPollInterval = 100 * time.Millisecond
+
+// replaceable reports whether the server can be restarted blue/green
+// Both processes run side by side for a while, so a fixed port rules it out.
+func (s *Server) replaceable() bool {
+	return s.IsRunning() && s.Config.Port == 0
+}
+
+// successor returns a global server's replacement, not started yet
+// Settings, restart counts and the stderr capture carry over.
+// NOTE: This method must be called with the manager's mutex locked
+func (s *Server) successor() *Server {
+	next := &Server{
+		Name:         s.Name,
+		Config:       s.Config,
+		requestQueue: make(chan RequestResponse, 100),
+		logger:       s.logger,
+		stderr:       s.stderr,
+		restarts:     s.restarts + 1,
+		recycles:     s.recycles,
+		tracer:       s.tracer,
+		incidents:    s.incidents,
+		webhooks:     s.webhooks,
+		configName:   s.configName,
+		multiplexed:  s.multiplexed,
+		timeouts:     s.timeouts,
+
+		stopGrace: s.stopGrace,
+
+		notificationHandler: s.notificationHandler,
+
+		activeOperations:    make(map[string]*OperationInfo),
+		operationTimeoutSec: s.operationTimeoutSec,
+	}
+	next.maxResponseBytes.Store(s.maxResponseBytes.Load())
+	return next
+}
+
+// replaceServer restarts a running global server without a gap in service
+// A replacement process is started and initialized while the old one keeps
+// serving, then swapped in for new requests. The old process stops once the
+// requests it is working on finish, see drainServer. When the replacement
+// fails, the old process keeps serving and the error is returned.
+func (m *Manager) replaceServer(name string, old *Server) error {
+	m.mu.Lock()
+	replacement := old.successor()
+	err := m.startProcess(replacement, replacement.Config)
+	m.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to start replacement for server %s: %w", name, err)
+	}
+
+	// Clients' initialize requests are answered from this handshake, as for a warm instance
+	if err := replacement.initializeWarm(); err != nil {
+		replacement.Stop()
+		return fmt.Errorf("replacement for server %s failed to initialize: %w", name, err)
+	}
+	replacement.handled.Store(0) // The handshake doesn't count towards a recycle policy
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current := m.servers[name]; current != old {
+		logger.System().Info("MCP server %s was restarted concurrently; discarding replacement", name)
+		go replacement.Stop()
+		return nil
+	}
+	if !old.IsRunning() {
+		go replacement.Stop()
+		return fmt.Errorf("server %s was stopped during its restart", name)
+	}
+
+	m.servers[name] = replacement
+	delete(m.startErrors, name)
+	m.draining[old] = true
+	logger.System().Info("Swapped in replacement for MCP server %s; draining the old process", name)
+
+	// Warm instances are replaced too so none predates the restart
+	if _, pooled := m.warmPools[name]; pooled {
+		m.drainWarmPool(name)
+		go m.fillWarmPool(name)
+	}
+
+	go m.drainServer(old)
+	return nil
+}
+
+// drainServer stops a replaced server once its in-flight requests finish
+// Requests still running after the server's operation timeout are abandoned.
+func (m *Manager) drainServer(server *Server) {
+	deadline := time.Now().Add(server.OperationTimeout())
+	for {
+		// Also lets callers that fetched the server just before the swap send their request
+		time.Sleep(m.drainPollInterval)
+		if !server.HasActiveOperations() {
+			break
+		}
+		if time.Now().After(deadline) {
+			logger.System().Warn("Replaced MCP server %s still has %d requests in flight after %v; stopping it",
+				server.Name, server.GetActiveOperationCount(), server.OperationTimeout())
+			break
+		}
+	}
+
+	m.mu.Lock()
+	draining := m.draining[server]
+	delete(m.draining, server)
+	m.mu.Unlock()
+
+	if draining { // Otherwise StopAll already stopped it
+		logger.System().Info("Stopping replaced MCP server %s", server.Name)
+		server.Stop()
+	}
+}
@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+// answeringServerConfig returns a config for a shell MCP server answering every request with an empty result
+func answeringServerConfig() config.MCPServer {
+	return config.MCPServer{
+		Command: "/bin/sh",
+		Args: []string{"-c", `while read -r line; do
+			id=$(echo "$line" | sed -n 's/.*"id":\([^,}]*\).*/\1/p')
+			[ -n "$id" ] && echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{}}"
+		done`},
+	}
+}
+
+func TestBlueGreenRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+	manager := NewManager(map[string]config.MCPServer{"echo": answeringServerConfig()})
+	manager.drainPollInterval = 10 * time.Millisecond
+	manager.SetStopGracePeriod(time.Second)
+	if err := manager.StartAll(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer manager.StopAll()
+	old, _ := manager.GetServer("echo")
+	pid := manager.GetAllServers()[0].PID
+
+	// A request in flight on the old process keeps it running after the swap
+	old.startOperation(&OperationInfo{RequestID: "operation-1", Method: "tools/call", StartTime: time.Now()})
+	if err := manager.RestartServer("echo"); err != nil {
+		t.Fatalf("RestartServer failed: %v", err)
+	}
+	replacement, _ := manager.GetServer("echo")
+	status := manager.GetAllServers()[0]
+	if replacement == old || !status.Running || status.PID == pid || status.Restarts != 1 {
+		t.Fatalf("Expected a new process swapped in, got %+v (old PID %d)", status, pid)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !old.IsRunning() {
+		t.Fatal("Expected the old process to keep running while its request is in flight")
+	}
+	if operations := manager.ActiveOperations(); len(operations) != 1 || operations[0].ID != "operation-1" {
+		t.Errorf("Expected the draining request to be listed, got %+v", operations)
+	}
+
+	// The replacement answers requests, and initialize from its own handshake
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	response, err := replacement.SendAndReceive(ctx, []byte(`{"jsonrpc":"2.0","id":7,"method":"tools/list"}`))
+	if err != nil || !strings.Contains(string(response), `"id":7`) {
+		t.Errorf("Expected the replacement to answer, got %s (%v)", response, err)
+	}
+	if _, ok := replacement.preinitializedResponse([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`)); !ok {
+		t.Error("Expected the replacement to be initialized before the swap")
+	}
+
+	// The old process stops once its request finishes
+	old.endOperation("operation-1")
+	deadline := time.Now().Add(5 * time.Second)
+	for old.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if old.IsRunning() {
+		t.Error("Expected the old process to stop once drained")
+	}
+	if !replacement.IsRunning() {
+		t.Error("Expected the replacement to keep running")
+	}
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"remote-mcp-proxy/config"
+)
+
+// serverCommand builds the command starting an MCP server process, applying its sandbox
+// workDir is the process's working directory, empty for processes started outside a session.
+func serverCommand(ctx context.Context, cfg config.MCPServer, workDir string) *exec.Cmd {
+	argv := append([]string{cfg.Command}, cfg.Args...)
+	sandbox := cfg.Sandbox
+	if sandbox != nil {
+		argv = append(append([]string{}, sandbox.Wrapper...), argv...)
+		if sandbox.NoNewPrivileges {
+			argv = append([]string{"setpriv", "--no-new-privs", "--"}, argv...)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = serverEnvironment(cfg, workDir)
+	cmd.Dir = workDir
+	if sandbox != nil && sandbox.UID != 0 {
+		// Supplementary groups are cleared along with the switch
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Credential: &syscall.Credential{Uid: uint32(sandbox.UID), Gid: uint32(sandbox.Group())},
+		}
+	}
+	return cmd
+}
+
+// serverEnvironment returns the environment of an MCP server process
+// Servers inherit the proxy's whole environment, which holds its secrets, unless
+// sandboxed or restricted by inheritEnv or envAllowlist; those only get the
+// variables they name. The server's own env is added last so it wins.
+func serverEnvironment(cfg config.MCPServer, workDir string) []string {
+	var env []string
+	if cfg.InheritsEnv() {
+		env = os.Environ()
+	} else {
+		for _, name := range cfg.InheritedEnv() {
+			if value, exists := os.LookupEnv(name); exists {
+				env = append(env, fmt.Sprintf("%s=%s", name, value))
+			}
+		}
+		if cfg.Sandbox != nil && cfg.Sandbox.UID != 0 && workDir != "" {
+			env = append(env, fmt.Sprintf("HOME=%s", workDir)) // The proxy's home isn't readable by the sandbox user
+		}
+	}
+
+	for key, value := range cfg.Env {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+	return env
+}
+
+// sandboxWorkDir creates the private working directory of a sandboxed server inside a session directory
+// The session directory is left traversable but not listable, and the server's own
+// directory is readable only by its sandbox user, so a compromised server can't read
+// what other servers of the session store. A read-only directory stays owned by the
+// proxy and empty.
+func (m *Manager) sandboxWorkDir(sessionDir, serverName string, sandbox *config.Sandbox) (string, error) {
+	if err := os.Chmod(sessionDir, 0711); err != nil {
+		return "", err
+	}
+
+	workDir := filepath.Join(sessionDir, serverName)
+	if sandbox.ReadOnlyWorkDir {
+		if err := os.MkdirAll(workDir, 0555); err != nil {
+			return "", err
+		}
+		return workDir, os.Chmod(workDir, 0555)
+	}
+
+	if err := m.ensureSessionDirectory(workDir); err != nil {
+		return "", err
+	}
+	dirs := []string{workDir}
+	for _, subdir := range []string{"data", "cache", "temp"} {
+		dirs = append(dirs, filepath.Join(workDir, subdir))
+	}
+	for _, dir := range dirs {
+		if sandbox.UID != 0 {
+			if err := os.Chown(dir, sandbox.UID, sandbox.Group()); err != nil {
+				return "", err
+			}
+		}
+		if err := os.Chmod(dir, 0700); err != nil {
+			return "", err
+		}
+	}
+	return workDir, nil
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestServerCommand(t *testing.T) {
+	t.Setenv("PROXY_SECRET", "hunter2")
+	t.Setenv("HTTPS_PROXY", "http://proxy:3128")
+	t.Setenv("HOME", "/home/proxy")
+
+	cfg := config.MCPServer{Command: "echo", Args: []string{"hello"}, Env: map[string]string{"API_KEY": "abc"}}
+	cmd := serverCommand(context.Background(), cfg, "/app/sessions/abc")
+	if !slices.Contains(cmd.Env, "PROXY_SECRET=hunter2") || !slices.Contains(cmd.Env, "API_KEY=abc") {
+		t.Error("Expected an unsandboxed server to inherit the proxy environment")
+	}
+	if cmd.SysProcAttr != nil || cmd.Dir != "/app/sessions/abc" {
+		t.Errorf("Expected no credentials and the session directory, got %+v in %s", cmd.SysProcAttr, cmd.Dir)
+	}
+
+	inherit := false
+	cfg.InheritEnv = &inherit
+	cmd = serverCommand(context.Background(), cfg, "/app/sessions/abc")
+	if want := []string{"PATH=" + os.Getenv("PATH"), "HOME=/home/proxy", "API_KEY=abc"}; !slices.Equal(cmd.Env, want) {
+		t.Errorf("Expected inheritEnv false to pass on only PATH, HOME and the server's env, got %v", cmd.Env)
+	}
+	cfg.InheritEnv = nil
+	cfg.EnvAllowlist = []string{"HTTPS_PROXY"}
+	cmd = serverCommand(context.Background(), cfg, "/app/sessions/abc")
+	if slices.Contains(cmd.Env, "PROXY_SECRET=hunter2") || !slices.Contains(cmd.Env, "HTTPS_PROXY=http://proxy:3128") {
+		t.Errorf("Expected envAllowlist to pass on only the variables it names, got %v", cmd.Env)
+	}
+	cfg.EnvAllowlist = nil
+
+	cfg.Sandbox = &config.Sandbox{
+		UID:             1001,
+		GID:             2002,
+		EnvAllowlist:    []string{"HTTPS_PROXY"},
+		NoNewPrivileges: true,
+		Wrapper:         []string{"bwrap", "--unshare-net"},
+	}
+	cmd = serverCommand(context.Background(), cfg, "/app/sessions/abc/memory")
+
+	want := []string{"setpriv", "--no-new-privs", "--", "bwrap", "--unshare-net", "echo", "hello"}
+	if !slices.Equal(cmd.Args, want) {
+		t.Errorf("Expected args %v, got %v", want, cmd.Args)
+	}
+	if slices.Contains(cmd.Env, "PROXY_SECRET=hunter2") {
+		t.Error("Expected a sandboxed server not to inherit variables outside the allowlist")
+	}
+	for _, variable := range []string{"HTTPS_PROXY=http://proxy:3128", "API_KEY=abc", "HOME=/app/sessions/abc/memory"} {
+		if !slices.Contains(cmd.Env, variable) {
+			t.Errorf("Expected %s in the sandboxed environment, got %v", variable, cmd.Env)
+		}
+	}
+	if credential := cmd.SysProcAttr.Credential; credential.Uid != 1001 || credential.Gid != 2002 {
+		t.Errorf("Expected to run as 1001:2002, got %d:%d", credential.Uid, credential.Gid)
+	}
+}
+
+func TestSandboxWorkDir(t *testing.T) {
+	manager := NewManager(map[string]config.MCPServer{})
+	sessionDir := t.TempDir()
+
+	workDir, err := manager.sandboxWorkDir(sessionDir, "memory", &config.Sandbox{})
+	if err != nil {
+		t.Fatalf("Failed to create sandbox directory: %v", err)
+	}
+	if workDir != filepath.Join(sessionDir, "memory") {
+		t.Errorf("Expected a directory per server, got %s", workDir)
+	}
+	for dir, mode := range map[string]os.FileMode{sessionDir: 0711, workDir: 0700, filepath.Join(workDir, "data"): 0700} {
+		if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != mode {
+			t.Errorf("Expected %s to have mode %o, got %v (%v)", dir, mode, info.Mode().Perm(), err)
+		}
+	}
+
+	workDir, err = manager.sandboxWorkDir(sessionDir, "fetch", &config.Sandbox{ReadOnlyWorkDir: true})
+	if err != nil {
+		t.Fatalf("Failed to create read-only sandbox directory: %v", err)
+	}
+	if info, err := os.Stat(workDir); err != nil || info.Mode().Perm() != 0555 {
+		t.Errorf("Expected a read-only directory, got %v (%v)", info.Mode().Perm(), err)
+	}
+}
@@ -0,0 +1,294 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+// sessionsRoot holds one directory per session, and the persistent directories of clients
+var sessionsRoot = "/app/sessions"
+
+// sessionUsageTTL is how long a measured session directory size is reused before measuring again
+const sessionUsageTTL = 10 * time.Second
+
+// sessionSubdirs are created in every session directory for MCP servers that need them
+var sessionSubdirs = []string{"data", "cache", "temp"}
+
+// SessionDataOptions control the files MCP servers write in session directories
+type SessionDataOptions struct {
+	Quota   int64 // Largest size of a session directory, in bytes (unlimited when 0)
+	Persist bool  // Keep data across sessions of the same client identity instead of deleting it
+}
+
+// QuotaExceededError is returned by CheckSessionQuota for a session directory over its quota
+type QuotaExceededError struct {
+	Used  int64 // Size of the session directory in bytes
+	Quota int64 // Configured quota in bytes
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("session data uses %d bytes, over the %d byte quota", e.Used, e.Quota)
+}
+
+// SessionData describes a session's directory for operators
+type SessionData struct {
+	Directory  string `json:"directory"`
+	Persistent bool   `json:"persistent"`         // The directory outlives the session
+	Identity   string `json:"identity,omitempty"` // Client identity the data is kept for
+	UsedBytes  int64  `json:"usedBytes"`
+	QuotaBytes int64  `json:"quotaBytes,omitempty"`
+	OverQuota  bool   `json:"overQuota"`
+}
+
+// sessionUsage is a cached session directory size
+type sessionUsage struct {
+	bytes      int64
+	measuredAt time.Time
+}
+
+// SetSessionData sets the quota and persistence of session directories
+// It should be called during startup, before sessions are created.
+func (m *Manager) SetSessionData(opts SessionDataOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessionDataOpts = opts
+}
+
+// NeedsSessionIdentity reports whether session data, workspaces or {USER} depend on the client
+// and the session's client isn't known yet
+func (m *Manager) NeedsSessionIdentity(sessionID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return (m.sessionDataOpts.Persist || m.usesClientIdentity()) && m.sessionIdentities[sessionID] == ""
+}
+
+// SetSessionIdentity records the client a session belongs to
+// With persistence enabled, sessions of the same identity share one directory,
+// which is kept when they end, and servers with user workspaces work in the
+// identity's workspace. It only takes effect before the session's servers start.
+func (m *Manager) SetSessionIdentity(sessionID, identity string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if identity == "" {
+		return
+	}
+	m.sessionIdentities[sessionID] = identity
+}
+
+// SetSessionRoot places a session's directory, and the persistent directories of its client, in root
+// Tenants use it to keep their session data apart. It only takes effect
+// before the session's servers start.
+func (m *Manager) SetSessionRoot(sessionID, root string) {
+	m.sessionRootsMu.Lock()
+	defer m.sessionRootsMu.Unlock()
+	if root == "" {
+		return
+	}
+	m.sessionRoots[sessionID] = root
+}
+
+// sessionRoot returns the directory holding a session's directory
+func (m *Manager) sessionRoot(sessionID string) string {
+	m.sessionRootsMu.Lock()
+	defer m.sessionRootsMu.Unlock()
+	if root, exists := m.sessionRoots[sessionID]; exists {
+		return root
+	}
+	return sessionsRoot
+}
+
+// SessionDirectory returns the directory of a session
+func (m *Manager) SessionDirectory(sessionID string) string {
+	return filepath.Join(m.sessionRoot(sessionID), sessionID)
+}
+
+// clientDirectory returns the persistent directory of a client identity in root
+func clientDirectory(root, identity string) string {
+	sum := sha256.Sum256([]byte(identity))
+	return filepath.Join(root, "clients", hex.EncodeToString(sum[:16]))
+}
+
+// prepareSessionDirectory creates the session directory and its common subdirectories
+// With persistence and a known identity, the session directory is a link to the
+// client's directory, so paths built from {SESSION_ID} reach the kept data.
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) prepareSessionDirectory(sessionID string) (string, error) {
+	sessionDir := m.SessionDirectory(sessionID)
+	if _, err := os.Lstat(sessionDir); os.IsNotExist(err) {
+		if identity := m.sessionIdentities[sessionID]; m.sessionDataOpts.Persist && identity != "" {
+			clientDir := clientDirectory(m.sessionRoot(sessionID), identity)
+			if err := os.MkdirAll(clientDir, 0755); err != nil {
+				return "", err
+			}
+			if err := os.Symlink(clientDir, sessionDir); err != nil {
+				return "", err
+			}
+			logger.System().Info("Session %s uses the persistent directory %s", sessionID[:8], clientDir)
+		}
+	}
+	return sessionDir, m.ensureSessionDirectory(sessionDir)
+}
+
+// removeSessionDirectory deletes a session directory, keeping the client's data when it is persistent
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) removeSessionDirectory(sessionID string) {
+	sessionDir := m.SessionDirectory(sessionID)
+	persistent := isSymlink(sessionDir)
+
+	// RemoveAll deletes a link without following it
+	if err := os.RemoveAll(sessionDir); err != nil {
+		logger.System().Warn("Failed to clean up session directory %s: %v", sessionDir, err)
+	} else if persistent {
+		logger.System().Info("Kept persistent data of session %s", sessionID[:8])
+	} else {
+		logger.System().Info("Cleaned up session directory for session %s", sessionID[:8])
+	}
+
+	delete(m.sessionIdentities, sessionID)
+	m.sessionDataMu.Lock()
+	delete(m.sessionUsage, sessionID)
+	m.sessionDataMu.Unlock()
+	m.sessionRootsMu.Lock()
+	delete(m.sessionRoots, sessionID)
+	m.sessionRootsMu.Unlock()
+}
+
+// CheckSessionQuota returns a *QuotaExceededError when a session's directory is over the quota
+// Sizes are measured at most every few seconds, so a session can briefly exceed
+// its quota before further tool calls are refused.
+func (m *Manager) CheckSessionQuota(sessionID string) error {
+	m.mu.RLock()
+	quota := m.sessionDataOpts.Quota
+	_, active := m.sessionServers[sessionID]
+	m.mu.RUnlock()
+	if quota <= 0 || !active {
+		return nil
+	}
+
+	m.sessionDataMu.Lock()
+	usage, cached := m.sessionUsage[sessionID]
+	m.sessionDataMu.Unlock()
+	if !cached || time.Since(usage.measuredAt) > sessionUsageTTL {
+		usage = &sessionUsage{bytes: directorySize(m.SessionDirectory(sessionID)), measuredAt: time.Now()}
+		m.sessionDataMu.Lock()
+		m.sessionUsage[sessionID] = usage
+		m.sessionDataMu.Unlock()
+	}
+
+	if usage.bytes > quota {
+		return &QuotaExceededError{Used: usage.bytes, Quota: quota}
+	}
+	return nil
+}
+
+// GetSessionData describes a session's directory, measuring its current size
+func (m *Manager) GetSessionData(sessionID string) SessionData {
+	m.mu.RLock()
+	opts := m.sessionDataOpts
+	identity := m.sessionIdentities[sessionID]
+	m.mu.RUnlock()
+
+	sessionDir := m.SessionDirectory(sessionID)
+	data := SessionData{
+		Directory:  sessionDir,
+		Persistent: isSymlink(sessionDir),
+		UsedBytes:  directorySize(sessionDir),
+		QuotaBytes: opts.Quota,
+	}
+	if data.Persistent {
+		data.Identity = identity
+	}
+	data.OverQuota = opts.Quota > 0 && data.UsedBytes > opts.Quota
+
+	m.sessionDataMu.Lock()
+	m.sessionUsage[sessionID] = &sessionUsage{bytes: data.UsedBytes, measuredAt: time.Now()}
+	m.sessionDataMu.Unlock()
+	return data
+}
+
+// ClearSessionData deletes the files in a session's directory and returns how many bytes were freed
+// Top-level directories, such as the working directories of sandboxed servers,
+// are emptied but kept, so running servers keep their working directory.
+func (m *Manager) ClearSessionData(sessionID string) (int64, error) {
+	sessionDir, err := filepath.EvalSymlinks(m.SessionDirectory(sessionID))
+	if err != nil {
+		return 0, err
+	}
+	freed := directorySize(sessionDir)
+
+	entries, err := os.ReadDir(sessionDir)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(sessionDir, entry.Name())
+		if !entry.IsDir() {
+			err = os.Remove(path)
+		} else {
+			err = emptyDirectory(path)
+		}
+		if err != nil {
+			return freed - directorySize(sessionDir), err
+		}
+	}
+	for _, subdir := range sessionSubdirs {
+		if err := os.MkdirAll(filepath.Join(sessionDir, subdir), 0755); err != nil {
+			logger.System().Warn("Failed to recreate subdirectory %s: %v", subdir, err)
+		}
+	}
+
+	m.sessionDataMu.Lock()
+	delete(m.sessionUsage, sessionID)
+	m.sessionDataMu.Unlock()
+
+	logger.System().Info("Cleared %d bytes of data of session %s", freed, sessionID[:8])
+	return freed, nil
+}
+
+// emptyDirectory deletes everything inside dir
+func emptyDirectory(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// directorySize returns the total size of the files under dir, following a link at dir itself
+func directorySize(dir string) int64 {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	var size int64
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries, e.g. of a sandbox user, are skipped
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// isSymlink reports whether path is a symbolic link
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestSessionData(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	root := sessionsRoot
+	sessionsRoot = t.TempDir()
+	defer func() { sessionsRoot = root }()
+
+	manager := NewManager(map[string]config.MCPServer{"notes": {Command: "cat"}})
+	manager.SetSessionData(SessionDataOptions{Quota: 1000, Persist: true})
+	defer manager.StopAll()
+
+	// The first session of a client writes past the quota
+	first := "session-one-0001"
+	if !manager.NeedsSessionIdentity(first) {
+		t.Fatal("Expected the session to need an identity")
+	}
+	manager.SetSessionIdentity(first, "api-key:alice")
+	if _, ok := manager.GetServerForSession(first, "notes"); !ok {
+		t.Fatal("Expected the session server to start")
+	}
+	notes := filepath.Join(manager.SessionDirectory(first), "data", "notes.txt")
+	if err := os.WriteFile(notes, make([]byte, 2000), 0644); err != nil {
+		t.Fatalf("Failed to write session data: %v", err)
+	}
+
+	var quotaErr *QuotaExceededError
+	if err := manager.CheckSessionQuota(first); !errors.As(err, &quotaErr) || quotaErr.Used != 2000 {
+		t.Fatalf("Expected the quota to be exceeded by 2000 bytes, got %v", err)
+	}
+	if data := manager.GetSessionData(first); !data.Persistent || !data.OverQuota || data.Identity != "api-key:alice" {
+		t.Errorf("Expected persistent data over quota, got %+v", data)
+	}
+
+	// Persistent data survives the session and is found by the client's next session
+	manager.CleanupSession(first)
+	second := "session-two-0002"
+	manager.SetSessionIdentity(second, "api-key:alice")
+	if _, ok := manager.GetServerForSession(second, "notes"); !ok {
+		t.Fatal("Expected the session server to start")
+	}
+	if _, err := os.Stat(filepath.Join(manager.SessionDirectory(second), "data", "notes.txt")); err != nil {
+		t.Fatalf("Expected the first session's data to be kept: %v", err)
+	}
+
+	// Clearing the data lifts the quota and keeps the directory layout
+	freed, err := manager.ClearSessionData(second)
+	if err != nil || freed != 2000 {
+		t.Fatalf("Expected 2000 bytes to be freed, got %d, %v", freed, err)
+	}
+	if err := manager.CheckSessionQuota(second); err != nil {
+		t.Errorf("Expected the quota to be lifted, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(manager.SessionDirectory(second), "data")); err != nil {
+		t.Errorf("Expected the data directory to be recreated: %v", err)
+	}
+
+	// Sessions without an identity are deleted as before
+	anonymous := "session-three-03"
+	if _, ok := manager.GetServerForSession(anonymous, "notes"); !ok {
+		t.Fatal("Expected the session server to start")
+	}
+	manager.CleanupSession(anonymous)
+	if _, err := os.Lstat(manager.SessionDirectory(anonymous)); !os.IsNotExist(err) {
+		t.Errorf("Expected the anonymous session directory to be deleted, got %v", err)
+	}
+}
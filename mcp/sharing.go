@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// assignPooledServer gives a session an instance of a "pool" mode server
+//
+// New instances are started until the server's cap is reached; after that
+// the session joins the running instance with the fewest sessions.
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) assignPooledServer(sessionID, name string, cfg config.MCPServer) (*Server, error) {
+	maxInstances := cfg.MaxInstances
+	if maxInstances == 0 {
+		maxInstances = config.DefaultMaxInstances
+	}
+
+	// Forget instances that exited; their sessions keep them until cleanup
+	running := m.instancePools[name][:0]
+	for _, server := range m.instancePools[name] {
+		if server.IsRunning() {
+			running = append(running, server)
+		}
+	}
+	m.instancePools[name] = running
+
+	var server *Server
+	if len(running) < maxInstances {
+		started, err := m.startDetachedServer(name, "pool")
+		if err != nil {
+			return nil, err
+		}
+		started.multiplexed = true
+		server = started
+		m.instancePools[name] = append(m.instancePools[name], server)
+		logger.System().Info("Started pooled MCP server %s (%d/%d instances)", server.Name, len(m.instancePools[name]), maxInstances)
+	} else {
+		server = running[0]
+		for _, candidate := range running[1:] {
+			if candidate.sessionRefs < server.sessionRefs {
+				server = candidate
+			}
+		}
+	}
+
+	server.sessionRefs++
+	logger.System().Info("Session %s assigned to pooled MCP server %s (%d sessions)", sessionID[:8], server.Name, server.sessionRefs)
+	return server, nil
+}
+
+// releasePooledServer drops a session from a pooled instance, stopping it when it is no longer used
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) releasePooledServer(sessionID, name string, server *Server) {
+	server.sessionRefs--
+	if server.sessionRefs > 0 {
+		logger.System().Info("Session %s released pooled MCP server %s (%d sessions left)", sessionID[:8], server.Name, server.sessionRefs)
+		return
+	}
+
+	instances := m.instancePools[name]
+	for i, instance := range instances {
+		if instance == server {
+			m.instancePools[name] = append(instances[:i], instances[i+1:]...)
+			break
+		}
+	}
+	logger.System().Info("Stopping idle pooled MCP server %s", server.Name)
+	server.stopDetached()
+}
+
+// stopInstancePool stops every pooled instance of a server
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) stopInstancePool(name string) {
+	for _, server := range m.instancePools[name] {
+		server.stopDetached()
+	}
+	delete(m.instancePools, name)
+}
+
+// rememberInitialize keeps the result of a multiplexed process's first successful initialize
+// Answering later sessions from it spares servers that reject a second handshake.
+func (s *Server) rememberInitialize(request, response []byte) {
+	var req struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(request, &req); err != nil || req.Method != "initialize" {
+		return
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(response, &resp); err != nil || len(resp.Result) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	if s.initResult == nil {
+		s.initResult = resp.Result
+	}
+	s.mu.Unlock()
+}
+
+// describeMode summarizes how a server's sessions map to processes, for logs
+func describeMode(cfg config.MCPServer) string {
+	if cfg.SessionMode() != config.ModePool {
+		return cfg.SessionMode()
+	}
+	maxInstances := cfg.MaxInstances
+	if maxInstances == 0 {
+		maxInstances = config.DefaultMaxInstances
+	}
+	return fmt.Sprintf("%s (max %d instances)", config.ModePool, maxInstances)
+}
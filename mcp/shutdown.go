@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// defaultStopGracePeriod is how long a stopped server gets to exit after SIGTERM when nothing sets it
+const defaultStopGracePeriod = 10 * time.Second
+
+// SetStopGracePeriod sets how long stopped servers get to exit after SIGTERM before SIGKILL
+// Servers with stopGracePeriod in their config keep their own. It applies to
+// every server, including future session instances, and should be called
+// during startup, before servers are started.
+func (m *Manager) SetStopGracePeriod(grace time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stopGrace = grace
+	for _, server := range m.servers {
+		server.stopGrace = grace
+	}
+	for _, server := range m.fallbacks {
+		server.stopGrace = grace
+	}
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			server.stopGrace = grace
+		}
+	}
+}
+
+// stopGracePeriod returns how long the server gets to exit after SIGTERM before SIGKILL
+func (s *Server) stopGracePeriod() time.Duration {
+	if d, err := time.ParseDuration(s.Config.StopGracePeriod); err == nil && d > 0 {
+		return d
+	}
+	if s.stopGrace > 0 {
+		return s.stopGrace
+	}
+	return defaultStopGracePeriod
+}
+
+// signalGroup sends sig to the process group led by process
+func signalGroup(process *os.Process, sig syscall.Signal) error {
+	if process == nil {
+		return nil
+	}
+	if err := syscall.Kill(-process.Pid, sig); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestStopSendsSIGTERM(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// The server flushes its state on SIGTERM; its child sleep gets SIGTERM too
+	flushed := filepath.Join(t.TempDir(), "flushed")
+	manager := NewManager(map[string]config.MCPServer{
+		"stateful": {
+			Command: "/bin/sh",
+			Args:    []string{"-c", `trap 'echo done > "$FLUSHED"; exit 0' TERM; while true; do sleep 60; done`},
+			Env:     map[string]string{"FLUSHED": flushed},
+		},
+	})
+	manager.SetStopGracePeriod(5 * time.Second)
+	if err := manager.StartAll(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // Let the shell install its trap
+
+	started := time.Now()
+	manager.StopAll()
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("Expected the server to exit on SIGTERM, stopping took %v", elapsed)
+	}
+	if data, err := os.ReadFile(flushed); err != nil || string(data) != "done\n" {
+		t.Errorf("Expected the server to flush its state on SIGTERM, got %q (%v)", data, err)
+	}
+}
+
+func TestStopGracePeriod(t *testing.T) {
+	server := &Server{}
+	if got := server.stopGracePeriod(); got != defaultStopGracePeriod {
+		t.Errorf("Expected the default grace period, got %v", got)
+	}
+	server.stopGrace = 20 * time.Second
+	if got := server.stopGracePeriod(); got != 20*time.Second {
+		t.Errorf("Expected the manager's grace period, got %v", got)
+	}
+	server.Config.StopGracePeriod = "1m"
+	if got := server.stopGracePeriod(); got != time.Minute {
+		t.Errorf("Expected the configured grace period, got %v", got)
+	}
+}
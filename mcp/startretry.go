@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/state"
+)
+
+// StartRetryPolicy bounds the backoff between attempts to start a server that failed to start
+//
+// The delay doubles after every failed attempt, from Initial up to Max. A
+// zero Initial disables retries.
+type StartRetryPolicy struct {
+	Initial time.Duration // Delay before the first retry
+	Max     time.Duration // Longest delay between two retries
+}
+
+// next returns the delay following delay
+func (p StartRetryPolicy) next(delay time.Duration) time.Duration {
+	delay *= 2
+	if p.Max > 0 && delay > p.Max {
+		delay = p.Max
+	}
+	return delay
+}
+
+// SetStartRetry retries servers that failed to start in the background with policy
+// It should be called during startup, before StartAll. StopAll ends the retries.
+func (m *Manager) SetStartRetry(policy StartRetryPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.startRetry = policy
+	if m.retryCancel == nil {
+		m.retryCtx, m.retryCancel = context.WithCancel(context.Background())
+	}
+}
+
+// scheduleStartRetry retries starting a server until it starts, unless a retry is already scheduled
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) scheduleStartRetry(name string) {
+	if m.startRetry.Initial <= 0 || m.retryCtx == nil || m.retrying[name] {
+		return
+	}
+	m.retrying[name] = true
+	go m.retryStart(m.retryCtx, name)
+}
+
+// retryStart starts a server that failed to start, backing off between attempts
+// It returns once the server started, was started by someone else, or retries were stopped.
+func (m *Manager) retryStart(ctx context.Context, name string) {
+	defer func() {
+		m.mu.Lock()
+		delete(m.retrying, name)
+		m.mu.Unlock()
+	}()
+
+	delay := m.startRetry.Initial
+	for attempt := 1; ; attempt++ {
+		logger.System().Info("Retrying to start MCP server %s in %v (attempt %d)", name, delay, attempt)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+
+		m.mu.Lock()
+		if _, failed := m.startErrors[name]; !failed || ctx.Err() != nil {
+			m.mu.Unlock()
+			return
+		}
+		if m.maintenance[name] {
+			// Operators working on the server start it themselves; check again later
+			m.mu.Unlock()
+			continue
+		}
+		if dependency := m.unreadyDependency(name); dependency != "" {
+			// The dependency's own retries may bring it up; check again later
+			m.mu.Unlock()
+			logger.System().Info("MCP server %s waits for its dependency %s", name, dependency)
+			continue
+		}
+		server := m.servers[name]
+		err := m.startServer(name, server.Config)
+		m.mu.Unlock()
+
+		if err == nil {
+			if err = server.awaitStartup(); err != nil {
+				m.mu.Lock()
+				server.Stop()
+				m.startErrors[name] = err.Error()
+				m.mu.Unlock()
+			}
+		}
+
+		if err == nil {
+			logger.System().Info("MCP server %s started after %d retries", name, attempt)
+			m.incidents.RecordIncident(state.Incident{
+				Kind:    state.IncidentRestart,
+				Server:  name,
+				Actor:   "startup-retry",
+				Action:  "start",
+				Reason:  "server failed to start",
+				Success: true,
+				Details: map[string]interface{}{"attempts": attempt},
+			})
+			return
+		}
+
+		logger.System().Warn("Retry %d to start MCP server %s failed: %v", attempt, name, err)
+		delay = m.startRetry.next(delay)
+	}
+}
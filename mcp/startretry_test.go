@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestStartRetryPolicy(t *testing.T) {
+	policy := StartRetryPolicy{Initial: time.Second, Max: 5 * time.Second}
+	delays := []time.Duration{}
+	for delay := policy.Initial; len(delays) < 5; delay = policy.next(delay) {
+		delays = append(delays, delay)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("Expected delays %v, got %v", want, delays)
+		}
+	}
+}
+
+func TestStartRetry(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	// The command only appears after startup, like a package that failed to download
+	command := filepath.Join(t.TempDir(), "server")
+	manager := NewManager(map[string]config.MCPServer{"late": {Command: command}})
+	manager.SetStartRetry(StartRetryPolicy{Initial: 10 * time.Millisecond, Max: 20 * time.Millisecond})
+	defer manager.StopAll()
+
+	if err := manager.StartAll(); err == nil {
+		t.Fatal("Expected the server to fail to start")
+	}
+	if err := os.WriteFile(command, []byte("#!/bin/sh\ncat\n"), 0755); err != nil {
+		t.Fatalf("Failed to write server: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := manager.GetAllServers()[0]
+		if status.Running && status.Error == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the server to be started by a retry, got %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"bytes"
+	"sync"
+
+	"remote-mcp-proxy/logger"
+)
+
+// defaultStderrLines is how many stderr lines are retained per server
+const defaultStderrLines = 500
+
+// maxStderrLineBytes bounds a single buffered stderr line so a process that
+// never writes a newline cannot grow memory without limit
+const maxStderrLineBytes = 64 * 1024
+
+// StderrCapture is an io.Writer that collects an MCP process's stderr output
+//
+// Complete lines are written to the per-server MCP log and kept in a bounded
+// ring buffer so crash diagnostics from npm/python servers survive the process
+// and can be retrieved over HTTP. It is assigned to exec.Cmd.Stderr, which lets
+// exec copy the stream and guarantees all output is flushed before Wait returns.
+type StderrCapture struct {
+	serverName string
+	logger     *logger.Logger
+	lines      []string
+	next       int  // Index where the next line is written once the ring is full
+	full       bool // Whether the ring has wrapped
+	partial    []byte
+	mu         sync.Mutex
+}
+
+// NewStderrCapture creates a capture retaining up to maxLines lines
+func NewStderrCapture(serverName string, log *logger.Logger, maxLines int) *StderrCapture {
+	if maxLines <= 0 {
+		maxLines = defaultStderrLines
+	}
+	return &StderrCapture{
+		serverName: serverName,
+		logger:     log,
+		lines:      make([]string, 0, maxLines),
+	}
+}
+
+// Write splits the stream into lines, logging and retaining each complete line
+func (c *StderrCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := append(c.partial, p...)
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		c.addLine(string(bytes.TrimRight(data[:idx], "\r")))
+		data = data[idx+1:]
+	}
+
+	if len(data) > maxStderrLineBytes {
+		c.addLine(string(data))
+		data = nil
+	}
+	c.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+// Flush records any buffered partial line, used when the process exits
+func (c *StderrCapture) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.partial) > 0 {
+		c.addLine(string(c.partial))
+		c.partial = nil
+	}
+}
+
+// addLine stores a line in the ring buffer and writes it to the MCP log
+// NOTE: This method must be called with c.mu locked
+func (c *StderrCapture) addLine(line string) {
+	if line == "" {
+		return
+	}
+
+	if c.logger != nil {
+		c.logger.Info("[stderr] %s: %s", c.serverName, line)
+	}
+
+	if !c.full && len(c.lines) < cap(c.lines) {
+		c.lines = append(c.lines, line)
+		return
+	}
+	c.full = true
+	c.lines[c.next] = line
+	c.next = (c.next + 1) % len(c.lines)
+}
+
+// Lines returns up to the last n captured lines in chronological order (n <= 0 returns all)
+func (c *StderrCapture) Lines(n int) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ordered := make([]string, 0, len(c.lines))
+	if c.full {
+		ordered = append(ordered, c.lines[c.next:]...)
+		ordered = append(ordered, c.lines[:c.next]...)
+	} else {
+		ordered = append(ordered, c.lines...)
+	}
+
+	if n > 0 && n < len(ordered) {
+		ordered = ordered[len(ordered)-n:]
+	}
+	return ordered
+}
@@ -0,0 +1,48 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStderrCaptureLines(t *testing.T) {
+	capture := NewStderrCapture("test-server", nil, 3)
+
+	// Lines may arrive split across writes
+	capture.Write([]byte("first\nsec"))
+	capture.Write([]byte("ond\r\nthird\n"))
+
+	if got := capture.Lines(0); !reflect.DeepEqual(got, []string{"first", "second", "third"}) {
+		t.Errorf("Unexpected lines: %v", got)
+	}
+
+	// Ring buffer keeps only the most recent lines
+	capture.Write([]byte("fourth\nfifth"))
+	capture.Flush()
+
+	if got := capture.Lines(0); !reflect.DeepEqual(got, []string{"third", "fourth", "fifth"}) {
+		t.Errorf("Unexpected lines after wrap: %v", got)
+	}
+	if got := capture.Lines(2); !reflect.DeepEqual(got, []string{"fourth", "fifth"}) {
+		t.Errorf("Unexpected tail: %v", got)
+	}
+}
+
+func TestGetServerStderr(t *testing.T) {
+	manager := NewManager(createTestConfig())
+
+	server, _ := manager.GetServer("test-server")
+	server.stderr.Write([]byte("boom\n"))
+
+	lines, exists := manager.GetServerStderr("test-server", 10)
+	if !exists {
+		t.Fatal("Expected server to be found")
+	}
+	if !reflect.DeepEqual(lines, []string{"boom"}) {
+		t.Errorf("Unexpected lines: %v", lines)
+	}
+
+	if _, exists := manager.GetServerStderr("missing", 10); exists {
+		t.Error("Expected missing server to be reported")
+	}
+}
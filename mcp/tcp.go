@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tcpConnectTimeout bounds how long a "tcp" server has to start listening on its port
+const tcpConnectTimeout = 30 * time.Second
+
+// tcpDialInterval is the delay between attempts to connect to a starting "tcp" server
+const tcpDialInterval = 100 * time.Millisecond
+
+// TCPTransport runs a "tcp" server as a local process and exchanges messages over a connection to its port
+// The process's stdout is logged like its stderr, as messages travel over the socket.
+type TCPTransport struct {
+	StdioTransport
+	conn      net.Conn
+	closeOnce sync.Once
+}
+
+// NewTCPTransport creates the transport of a "tcp" server
+func NewTCPTransport(opts TransportOptions) Transport {
+	return &TCPTransport{StdioTransport: StdioTransport{opts: opts}}
+}
+
+// Start starts the configured command and connects to its port once it listens
+func (t *TCPTransport) Start(ctx context.Context) (io.WriteCloser, io.ReadCloser, error) {
+	if t.opts.Config.Port == 0 {
+		return nil, nil, fmt.Errorf("no port assigned to %s", t.opts.InstanceName)
+	}
+
+	// stdin stays open, as some servers exit when it closes
+	_, stdout, err := t.startCommand(serverCommand(ctx, t.opts.Config, t.opts.WorkDir))
+	if err != nil {
+		return nil, nil, err
+	}
+	go t.logOutput(stdout)
+
+	exited := make(chan struct{})
+	go func() {
+		t.StdioTransport.Wait()
+		close(exited)
+	}()
+
+	conn, err := dialPort(ctx, t.opts.Config.Port, tcpConnectTimeout, exited)
+	if err != nil {
+		t.StdioTransport.Kill()
+		<-exited
+		return nil, nil, err
+	}
+	// Both streams are the connection; closing the writer closes it
+	t.conn = conn
+	return conn, io.NopCloser(conn), nil
+}
+
+// logOutput copies what the process writes on stdout to the server's stderr log
+func (t *TCPTransport) logOutput(stdout io.Reader) {
+	if t.opts.Stderr == nil {
+		io.Copy(io.Discard, stdout)
+		return
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		fmt.Fprintln(t.opts.Stderr, scanner.Text())
+	}
+}
+
+// dialPort connects to a local port, retrying until it listens, the timeout
+// expires, ctx is cancelled or the process exits
+func dialPort(ctx context.Context, port int, timeout time.Duration, exited <-chan struct{}) (net.Conn, error) {
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("server did not listen on port %d within %v: %w", port, timeout, err)
+		}
+		select {
+		case <-time.After(tcpDialInterval):
+		case <-exited:
+			return nil, fmt.Errorf("server exited before listening on port %d", port)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Wait waits for the process to exit, then closes the connection so readers see EOF
+func (t *TCPTransport) Wait() error {
+	err := t.StdioTransport.Wait()
+	t.closeConn()
+	return err
+}
+
+// Kill closes the connection and sends SIGKILL to the process and its children
+func (t *TCPTransport) Kill() error {
+	t.closeConn()
+	return t.StdioTransport.Kill()
+}
+
+// closeConn closes the connection to the server once
+func (t *TCPTransport) closeConn() {
+	t.closeOnce.Do(func() {
+		if t.conn != nil {
+			t.conn.Close()
+		}
+	})
+}
+
+// Describe identifies the process and its port
+func (t *TCPTransport) Describe() string {
+	return fmt.Sprintf("PID: %d, port: %d", t.PID(), t.opts.Config.Port)
+}
@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+// TestHelperTCPServer is not a real test: it is the "tcp" server process of TestTCPTransport
+// It listens on MCP_LISTEN and answers every line of the first connection with a result.
+func TestHelperTCPServer(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_TCP_SERVER") != "1" {
+		return
+	}
+
+	fmt.Println("starting up") // Logged, not read as a message
+	time.Sleep(200 * time.Millisecond)
+	listener, err := net.Listen("tcp", os.Getenv("MCP_LISTEN"))
+	if err != nil {
+		os.Exit(2)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		os.Exit(2)
+	}
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		response := bytes.Replace(scanner.Bytes(), []byte(`"method":"tools/list"`), []byte(`"result":{}`), 1)
+		conn.Write(append(response, '\n'))
+	}
+	os.Exit(0)
+}
+
+func TestTCPTransport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	manager := NewManager(map[string]config.MCPServer{
+		"tcp": {
+			Type:    config.TypeTCP,
+			Command: os.Args[0],
+			Args:    []string{"-test.run=TestHelperTCPServer"},
+			Env:     map[string]string{"GO_WANT_HELPER_TCP_SERVER": "1", "MCP_LISTEN": "127.0.0.1:{PORT}"},
+		},
+		"silent": {Type: config.TypeTCP, Command: "true"},
+	})
+	defer manager.StopAll()
+
+	// A server exiting without listening fails to start
+	if err := manager.StartAll(); err == nil || !strings.Contains(err.Error(), "exited before listening") {
+		t.Fatalf("Expected the silent server to fail to start, got %v", err)
+	}
+
+	server, _ := manager.GetServer("tcp")
+	if !strings.Contains(server.Transport.Describe(), "port: ") {
+		t.Errorf("Expected the transport to describe its port, got %s", server.Transport.Describe())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	request := `{"jsonrpc":"2.0","id":7,"method":"tools/list"}`
+	response, err := server.SendAndReceive(ctx, []byte(request))
+	if err != nil || string(response) != `{"jsonrpc":"2.0","id":7,"result":{}}` {
+		t.Errorf("Expected the request to be bridged over TCP, got %s (%v)", response, err)
+	}
+}
+
+func TestPortAllocator(t *testing.T) {
+	var ports portAllocator
+	seen := make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		port, err := ports.allocate()
+		if err != nil {
+			t.Fatalf("Failed to allocate a port: %v", err)
+		}
+		if seen[port] {
+			t.Fatalf("Port %d was handed out twice", port)
+		}
+		seen[port] = true
+	}
+}
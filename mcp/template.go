@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// headerValuePattern matches header values safe to substitute, which can't climb out of a directory
+var headerValuePattern = regexp.MustCompile(`^[A-Za-z0-9_@+=,-][A-Za-z0-9._@+=,-]*$`)
+
+// SetSessionHeaders records the request headers a session's servers use as template variables
+// Only headers named in a server's headerVariables are kept, from the session's
+// first request; like the identity, they only affect servers started afterwards.
+func (m *Manager) SetSessionHeaders(sessionID string, header http.Header) {
+	if len(m.templateHeaders) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, known := m.sessionHeaders[sessionID]; known {
+		return
+	}
+	values := make(map[string]string)
+	for _, name := range m.templateHeaders {
+		value := header.Get(name)
+		if value != "" && !headerValuePattern.MatchString(value) {
+			logger.System().Warn("Ignoring header %s of session %s: value not usable as a template variable", name, sessionID[:8])
+			continue
+		}
+		values[name] = value
+	}
+	m.sessionHeaders[sessionID] = values
+}
+
+// templateHeaderNames returns the request headers used by the headerVariables of configs
+func templateHeaderNames(configs map[string]config.MCPServer) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, cfg := range configs {
+		for _, header := range cfg.HeaderVariables {
+			header = http.CanonicalHeaderKey(header)
+			if !seen[header] {
+				seen[header] = true
+				names = append(names, header)
+			}
+		}
+	}
+	return names
+}
+
+// templateVariables returns the replacements of a session instance's template variables, in strings.NewReplacer order
+// {PORT} is left to assignPort, which also reserves the port.
+// NOTE: This method must be called with m.mu locked (read or write)
+func (m *Manager) templateVariables(sessionID, serverName string, cfg config.MCPServer) []string {
+	user := "anonymous"
+	if identity := m.sessionIdentities[sessionID]; identity != "" {
+		user = identity[strings.Index(identity, ":")+1:]
+	}
+
+	replacements := []string{
+		"{SESSION_ID}", sessionID,
+		"{SERVER_NAME}", serverName,
+		"{SESSION_DIR}", m.SessionDirectory(sessionID),
+		"{WORKSPACE}", m.serverWorkDir(sessionID, serverName, cfg),
+		"{USER}", user,
+		"{DATE}", time.Now().Format("2006-01-02"),
+	}
+
+	// Header variables missing from the request are empty
+	for variable, header := range cfg.HeaderVariables {
+		replacements = append(replacements, "{"+variable+"}", m.sessionHeaders[sessionID][http.CanonicalHeaderKey(header)])
+	}
+	return replacements
+}
+
+// usesVariable reports whether a template variable appears in a server's args, env, volumes or cwd
+func usesVariable(cfg config.MCPServer, variable string) bool {
+	if strings.Contains(cfg.Cwd, variable) {
+		return true
+	}
+	for _, arg := range cfg.Args {
+		if strings.Contains(arg, variable) {
+			return true
+		}
+	}
+	for _, value := range cfg.Env {
+		if strings.Contains(value, variable) {
+			return true
+		}
+	}
+	for _, volume := range cfg.Volumes {
+		if strings.Contains(volume, variable) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestCreateSessionConfigVariables(t *testing.T) {
+	cfg := config.MCPServer{
+		Command:         "server",
+		Args:            []string{"--dir", "{SESSION_DIR}/{USER}", "--log", "{DATE}.log", "--port", "{PORT}"},
+		Env:             map[string]string{"TENANT": "{TENANT}", "REGION": "{REGION}", "LISTEN": "127.0.0.1:{PORT}"},
+		HeaderVariables: map[string]string{"TENANT": "X-Tenant-Id", "REGION": "x-region"},
+		Cwd:             "/data/{TENANT}",
+	}
+	manager := NewManager(map[string]config.MCPServer{"notes": cfg})
+
+	sessionID := "session-one-0001"
+	manager.SetSessionIdentity(sessionID, "oidc:user-42")
+	manager.SetSessionHeaders(sessionID, http.Header{"X-Tenant-Id": {"acme"}, "X-Region": {"../etc"}})
+	manager.SetSessionHeaders(sessionID, http.Header{"X-Tenant-Id": {"other"}})
+
+	sessionCfg := manager.createSessionConfig(sessionID, "notes", cfg)
+	if want := filepath.Join(sessionsRoot, sessionID, "user-42"); sessionCfg.Args[1] != want {
+		t.Errorf("Expected {SESSION_DIR}/{USER} to be %s, got %s", want, sessionCfg.Args[1])
+	}
+	if want := time.Now().Format("2006-01-02") + ".log"; sessionCfg.Args[3] != want {
+		t.Errorf("Expected {DATE} to be today, got %s", sessionCfg.Args[3])
+	}
+	port, err := strconv.Atoi(sessionCfg.Args[5])
+	if err != nil || port <= 0 {
+		t.Errorf("Expected {PORT} to be a port, got %s", sessionCfg.Args[5])
+	}
+	if sessionCfg.Env["LISTEN"] != "127.0.0.1:"+sessionCfg.Args[5] {
+		t.Errorf("Expected every {PORT} of an instance to be the same port, got %s", sessionCfg.Env["LISTEN"])
+	}
+
+	// Headers come from the session's first request, and unsafe values are dropped
+	if sessionCfg.Env["TENANT"] != "acme" || sessionCfg.Env["REGION"] != "" {
+		t.Errorf("Expected TENANT=acme and an empty REGION, got %v", sessionCfg.Env)
+	}
+	if sessionCfg.Cwd != "/data/acme" {
+		t.Errorf("Expected variables to be substituted in cwd, got %s", sessionCfg.Cwd)
+	}
+
+	// Sessions without an identity are anonymous
+	anonymous := manager.createSessionConfig("session-two-0002", "notes", cfg)
+	if want := filepath.Join(sessionsRoot, "session-two-0002", "anonymous"); anonymous.Args[1] != want {
+		t.Errorf("Expected %s, got %s", want, anonymous.Args[1])
+	}
+}
@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// TimeoutTiers bound requests depending on whether the server process has warmed up
+//
+// The first request after a spawn may wait for an npm install or a slow
+// runtime start, while a warmed-up server that stops answering should fail
+// fast. Zero durations leave the caller's deadline untouched.
+type TimeoutTiers struct {
+	ColdStart   time.Duration // Until the process has answered its first request
+	SteadyState time.Duration // Afterwards, for every method but tools/call
+}
+
+// SetTimeoutTiers applies timeout tiers to every server, including future session instances
+// It should be called during startup, before requests are being served.
+func (m *Manager) SetTimeoutTiers(tiers TimeoutTiers) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.timeouts = tiers
+	for _, server := range m.servers {
+		server.timeouts = tiers
+	}
+	for _, server := range m.fallbacks {
+		server.timeouts = tiers
+	}
+	for _, sessionMap := range m.sessionServers {
+		for _, server := range sessionMap {
+			server.timeouts = tiers
+		}
+	}
+}
+
+// configuredTimeoutKey marks contexts whose deadline comes from a configured per-method timeout
+type configuredTimeoutKey struct{}
+
+// WithConfiguredTimeout bounds a request by a timeout configured for its method
+// Unlike other deadlines, it is not shortened by the steady-state tier.
+func WithConfiguredTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithValue(ctx, configuredTimeoutKey{}, true), timeout)
+}
+
+// IsWarm reports whether the current process has answered a request yet
+func (s *Server) IsWarm() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.warm
+}
+
+// markWarm records that the current process answered a request
+func (s *Server) markWarm() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.warm {
+		s.warm = true
+		s.logger.Debug("Server %s warmed up", s.Name)
+	}
+}
+
+// tierContext applies the cold-start or steady-state timeout to a request's context
+//
+// A cold process gets the full cold-start budget even when the caller asked
+// for less, so it is not restarted for being slow to boot. A warm one gets
+// the shorter of the caller's deadline and the steady-state timeout; tool
+// calls and configured per-method timeouts keep the caller's budget.
+func (s *Server) tierContext(ctx context.Context, message []byte) (context.Context, context.CancelFunc) {
+	s.mu.RLock()
+	warm, tiers := s.warm, s.timeouts
+	s.mu.RUnlock()
+
+	if !warm {
+		if tiers.ColdStart <= 0 {
+			return ctx, func() {}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) >= tiers.ColdStart {
+			return ctx, func() {}
+		}
+		return context.WithTimeout(context.WithoutCancel(ctx), tiers.ColdStart)
+	}
+
+	if tiers.SteadyState <= 0 || ctx.Value(configuredTimeoutKey{}) != nil {
+		return ctx, func() {}
+	}
+	var request struct {
+		Method string `json:"method"`
+	}
+	if json.Unmarshal(message, &request) == nil && request.Method == "tools/call" {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, tiers.SteadyState)
+}
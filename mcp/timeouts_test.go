@@ -0,0 +1,64 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+func TestTierContext(t *testing.T) {
+	server := &Server{
+		Name:     "test",
+		logger:   logger.System(),
+		timeouts: TimeoutTiers{ColdStart: time.Minute, SteadyState: 5 * time.Second},
+	}
+	list := []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	call := []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"x"}}`)
+
+	remaining := func(ctx context.Context) time.Duration {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Fatal("Expected a deadline")
+		}
+		return time.Until(deadline)
+	}
+
+	caller, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Cold processes get the cold-start budget even when the caller asked for less
+	ctx, release := server.tierContext(caller, list)
+	if d := remaining(ctx); d < 50*time.Second {
+		t.Errorf("Expected cold-start deadline, got %v", d)
+	}
+	release()
+
+	server.markWarm()
+
+	long, cancelLong := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancelLong()
+
+	// Warm processes fail fast, except for tool calls
+	ctx, release = server.tierContext(long, list)
+	if d := remaining(ctx); d > 5*time.Second {
+		t.Errorf("Expected steady-state deadline, got %v", d)
+	}
+	release()
+
+	ctx, release = server.tierContext(long, call)
+	if d := remaining(ctx); d < time.Minute {
+		t.Errorf("Expected tools/call to keep the caller's deadline, got %v", d)
+	}
+	release()
+
+	// A timeout configured for the method is not capped either
+	configured, cancelConfigured := WithConfiguredTimeout(context.Background(), 2*time.Minute)
+	defer cancelConfigured()
+	ctx, release = server.tierContext(configured, list)
+	if d := remaining(ctx); d < time.Minute {
+		t.Errorf("Expected the configured timeout to be kept, got %v", d)
+	}
+	release()
+}
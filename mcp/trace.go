@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+// TraceEntry is a single JSON-RPC message captured on the wire
+type TraceEntry struct {
+	Timestamp  time.Time       `json:"timestamp"`
+	SessionID  string          `json:"sessionId"`
+	Server     string          `json:"server"`
+	Direction  string          `json:"direction"` // request, response, error
+	Message    json.RawMessage `json:"message,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"durationMs,omitempty"`
+}
+
+// TraceRecorder captures JSON-RPC traffic through SendAndReceive per session
+//
+// Entries are kept in a bounded ring buffer per session so protocol mismatches
+// with Claude.ai can be inspected via the debug API, and are optionally appended
+// to a JSONL file per session so they can be replayed offline.
+type TraceRecorder struct {
+	maxEntries  int
+	maxSessions int
+	dir         string // JSONL output directory, empty for memory only
+	sessions    map[string][]TraceEntry
+	order       []string // Session IDs in first-seen order for eviction
+	mu          sync.Mutex
+}
+
+// NewTraceRecorder creates a recorder keeping maxEntries per session for up to
+// maxSessions sessions. When dir is non-empty, entries are also appended to
+// {dir}/{sessionID}.jsonl.
+func NewTraceRecorder(maxEntries, maxSessions int, dir string) (*TraceRecorder, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create trace directory: %w", err)
+		}
+	}
+	return &TraceRecorder{
+		maxEntries:  maxEntries,
+		maxSessions: maxSessions,
+		dir:         dir,
+		sessions:    make(map[string][]TraceEntry),
+	}, nil
+}
+
+// Record stores an entry for its session
+func (tr *TraceRecorder) Record(entry TraceEntry) {
+	if entry.SessionID == "" {
+		entry.SessionID = "global"
+	}
+
+	tr.mu.Lock()
+	entries, exists := tr.sessions[entry.SessionID]
+	if !exists {
+		tr.order = append(tr.order, entry.SessionID)
+		if len(tr.order) > tr.maxSessions {
+			delete(tr.sessions, tr.order[0])
+			tr.order = tr.order[1:]
+		}
+	}
+	entries = append(entries, entry)
+	if len(entries) > tr.maxEntries {
+		entries = entries[len(entries)-tr.maxEntries:]
+	}
+	tr.sessions[entry.SessionID] = entries
+	tr.mu.Unlock()
+
+	if tr.dir != "" {
+		tr.appendToFile(entry)
+	}
+}
+
+// appendToFile writes an entry to the session's JSONL file
+func (tr *TraceRecorder) appendToFile(entry TraceEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	filename := filepath.Join(tr.dir, filepath.Base(entry.SessionID)+".jsonl")
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		logger.System().Warn("Failed to open trace file %s: %v", filename, err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		logger.System().Warn("Failed to write trace file %s: %v", filename, err)
+	}
+}
+
+// Entries returns a copy of the captured entries for a session
+func (tr *TraceRecorder) Entries(sessionID string) ([]TraceEntry, bool) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	entries, exists := tr.sessions[sessionID]
+	if !exists {
+		return nil, false
+	}
+	result := make([]TraceEntry, len(entries))
+	copy(result, entries)
+	return result, true
+}
+
+// Sessions returns the IDs of all sessions with captured traffic
+func (tr *TraceRecorder) Sessions() []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	result := make([]string, len(tr.order))
+	copy(result, tr.order)
+	return result
+}
@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTraceRecorderRingBuffer(t *testing.T) {
+	recorder, err := NewTraceRecorder(2, 2, "")
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		recorder.Record(TraceEntry{SessionID: "session-1", Direction: "request", Message: json.RawMessage(`{"id":1}`)})
+	}
+	entries, exists := recorder.Entries("session-1")
+	if !exists || len(entries) != 2 {
+		t.Fatalf("Expected 2 entries for session-1, got %d", len(entries))
+	}
+
+	// Oldest session is evicted once maxSessions is exceeded
+	recorder.Record(TraceEntry{SessionID: "session-2"})
+	recorder.Record(TraceEntry{SessionID: "session-3"})
+	if _, exists := recorder.Entries("session-1"); exists {
+		t.Error("Expected session-1 to be evicted")
+	}
+
+	// Entries without a session are grouped under "global"
+	recorder.Record(TraceEntry{Direction: "request"})
+	if _, exists := recorder.Entries("global"); !exists {
+		t.Error("Expected entries without session to be stored under 'global'")
+	}
+}
+
+func TestTraceRecorderFileOutput(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewTraceRecorder(10, 10, dir)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	recorder.Record(TraceEntry{SessionID: "abc", Server: "memory", Direction: "request", Message: json.RawMessage(`{"method":"tools/list"}`)})
+	recorder.Record(TraceEntry{SessionID: "abc", Server: "memory", Direction: "response", Message: json.RawMessage(`{"result":{}}`)})
+
+	file, err := os.Open(filepath.Join(dir, "abc.jsonl"))
+	if err != nil {
+		t.Fatalf("Expected trace file to exist: %v", err)
+	}
+	defer file.Close()
+
+	var directions []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry TraceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid JSONL line: %v", err)
+		}
+		directions = append(directions, entry.Direction)
+	}
+	if len(directions) != 2 || directions[0] != "request" || directions[1] != "response" {
+		t.Errorf("Unexpected trace file contents: %v", directions)
+	}
+}
@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"remote-mcp-proxy/config"
+)
+
+// Transport connects a Server to the MCP server implementation it fronts
+//
+// Whatever carries the messages (a child process's stdio, a container, HTTP), a
+// started transport exchanges newline-delimited JSON-RPC messages through the
+// writer and reader returned by Start. The Server builds request correlation
+// (SendAndReceive), the request queue, notification routing, tracing and size
+// limits on top of these streams, so every transport gets them unchanged.
+type Transport interface {
+	// Start launches the implementation and returns its message streams; it ends when ctx is cancelled
+	Start(ctx context.Context) (io.WriteCloser, io.ReadCloser, error)
+
+	Lifecycle
+}
+
+// Lifecycle reports on and ends a started transport
+type Lifecycle interface {
+	// Wait blocks until the transport has ended; it may be called several times
+	Wait() error
+
+	// Kill ends the transport without waiting for it to shut down on its own
+	Kill() error
+
+	// PID returns the local process ID, or 0 for transports without one
+	PID() int
+
+	// Describe identifies the transport in logs, e.g. "PID: 1234"
+	Describe() string
+}
+
+// TransportOptions describe the instance a transport is created for
+type TransportOptions struct {
+	InstanceName string           // Server name, with the session suffix for session instances
+	Config       config.MCPServer // Configuration with session templates already substituted
+	WorkDir      string           // Working directory of local processes, empty outside a session
+	Stderr       io.Writer        // Receives what local processes write on stderr
+}
+
+// TransportFactory creates the transport of one server instance
+type TransportFactory func(opts TransportOptions) Transport
+
+var (
+	transportsMu sync.RWMutex
+	transports   = map[string]TransportFactory{
+		config.TypeStdio:          NewStdioTransport,
+		config.TypeTCP:            NewTCPTransport,
+		config.TypeDocker:         NewDockerTransport,
+		config.TypeSSE:            NewHTTPTransport,
+		config.TypeStreamableHTTP: NewHTTPTransport,
+	}
+)
+
+// RegisterTransport makes servers of a type use the given factory
+// It should be called during startup, before servers are started.
+func RegisterTransport(serverType string, factory TransportFactory) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transports[serverType] = factory
+}
+
+// newTransport creates the transport for a server instance from its type
+func newTransport(opts TransportOptions) (Transport, error) {
+	transportsMu.RLock()
+	factory, exists := transports[opts.Config.ServerType()]
+	transportsMu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("no transport for server type %q", opts.Config.ServerType())
+	}
+	return factory(opts), nil
+}
+
+// StdioTransport runs a server as a child process speaking MCP over stdin and stdout
+type StdioTransport struct {
+	opts TransportOptions
+	cmd  *exec.Cmd
+
+	waitOnce sync.Once
+	waitErr  error
+}
+
+// NewStdioTransport creates the transport of a "stdio" server
+func NewStdioTransport(opts TransportOptions) Transport {
+	return &StdioTransport{opts: opts}
+}
+
+// Start starts the configured command, applying its sandbox
+func (t *StdioTransport) Start(ctx context.Context) (io.WriteCloser, io.ReadCloser, error) {
+	return t.startCommand(serverCommand(ctx, t.opts.Config, t.opts.WorkDir))
+}
+
+// startCommand starts a command with pipes on its stdin and stdout
+func (t *StdioTransport) startCommand(cmd *exec.Cmd) (io.WriteCloser, io.ReadCloser, error) {
+	t.cmd = cmd
+
+	// The process leads a process group, so stopping it reaches the children of
+	// npx, uvx and shell wrappers too: cancelling the context sends the group
+	// SIGTERM, and Kill sends it SIGKILL
+	if t.cmd.SysProcAttr == nil {
+		t.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	t.cmd.SysProcAttr.Setpgid = true
+	t.cmd.Cancel = func() error {
+		return signalGroup(t.cmd.Process, syscall.SIGTERM)
+	}
+
+	// Capture stderr so crash diagnostics end up in the MCP log
+	t.cmd.Stderr = t.opts.Stderr
+
+	stdin, err := t.cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := t.cmd.StdoutPipe()
+	if err != nil {
+		stdin.Close()
+		return nil, nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := t.cmd.Start(); err != nil {
+		stdin.Close()
+		stdout.Close()
+		return nil, nil, fmt.Errorf("failed to start process: %w", err)
+	}
+	return stdin, stdout, nil
+}
+
+// Wait waits for the process to exit
+func (t *StdioTransport) Wait() error {
+	t.waitOnce.Do(func() {
+		t.waitErr = t.cmd.Wait()
+	})
+	return t.waitErr
+}
+
+// Kill sends SIGKILL to the process and its children
+func (t *StdioTransport) Kill() error {
+	if t.cmd == nil || t.cmd.Process == nil {
+		return nil
+	}
+	return signalGroup(t.cmd.Process, syscall.SIGKILL)
+}
+
+// PID returns the process ID
+func (t *StdioTransport) PID() int {
+	if t.cmd == nil || t.cmd.Process == nil {
+		return 0
+	}
+	return t.cmd.Process.Pid
+}
+
+// Describe identifies the process
+func (t *StdioTransport) Describe() string {
+	return fmt.Sprintf("PID: %d", t.PID())
+}
@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+// loopbackTransport answers every request itself, standing in for a new server type
+type loopbackTransport struct {
+	done chan struct{}
+}
+
+func (t *loopbackTransport) Start(ctx context.Context) (io.WriteCloser, io.ReadCloser, error) {
+	requests, requestWriter := io.Pipe()
+	responses, responseWriter := io.Pipe()
+	go func() {
+		defer responseWriter.Close()
+		scanner := bufio.NewScanner(requests)
+		for scanner.Scan() {
+			response := bytes.Replace(scanner.Bytes(), []byte(`"method":"tools/list"`), []byte(`"result":{}`), 1)
+			responseWriter.Write(append(response, '\n'))
+		}
+		close(t.done)
+	}()
+	return requestWriter, responses, nil
+}
+
+func (t *loopbackTransport) Wait() error      { <-t.done; return nil }
+func (t *loopbackTransport) Kill() error      { return nil }
+func (t *loopbackTransport) PID() int         { return 0 }
+func (t *loopbackTransport) Describe() string { return "loopback" }
+
+// unregisterTransport removes a transport registered by a test, so tests can run again in one process
+func unregisterTransport(serverType string) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	delete(transports, serverType)
+}
+
+func TestRegisterTransport(t *testing.T) {
+	if _, err := newTransport(TransportOptions{Config: config.MCPServer{Type: "loopback"}}); err == nil {
+		t.Fatal("Expected an error for a type without a transport")
+	}
+
+	RegisterTransport("loopback", func(opts TransportOptions) Transport {
+		return &loopbackTransport{done: make(chan struct{})}
+	})
+	t.Cleanup(func() { unregisterTransport("loopback") })
+	manager := NewManager(map[string]config.MCPServer{"echo": {Type: "loopback"}})
+	if err := manager.StartAll(); err != nil {
+		t.Fatalf("Failed to start servers: %v", err)
+	}
+	defer manager.StopAll()
+
+	server, _ := manager.GetServer("echo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	request := `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`
+	response, err := server.SendAndReceive(ctx, []byte(request))
+	if err != nil || string(response) != `{"jsonrpc":"2.0","id":3,"result":{}}` {
+		t.Errorf("Expected the registered transport to carry the request, got %s (%v)", response, err)
+	}
+}
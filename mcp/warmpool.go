@@ -0,0 +1,296 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+)
+
+// warmInitTimeout bounds the initialize round trip of a pre-warmed instance
+// npm-based servers can take most of this on a cold start.
+const warmInitTimeout = 30 * time.Second
+
+// warmPool holds pre-initialized instances of one configured server
+//
+// New sessions claim an instance instead of paying for process startup and
+// the initialize round trip; the pool is refilled in the background.
+type warmPool struct {
+	size       int
+	instances  []*Server
+	filling    bool
+	generation int // Bumped when the pool is drained so in-flight fills discard their instances
+}
+
+// startWarmPools fills every configured warm pool in the background
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) startWarmPools() {
+	for name := range m.warmPools {
+		go m.fillWarmPool(name)
+	}
+}
+
+// fillWarmPool starts instances until the pool of a server is full
+func (m *Manager) fillWarmPool(name string) {
+	m.mu.Lock()
+	pool, exists := m.warmPools[name]
+	if !exists || pool.filling {
+		m.mu.Unlock()
+		return
+	}
+	pool.filling = true
+	generation := pool.generation
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		pool.filling = false
+		m.mu.Unlock()
+	}()
+
+	for {
+		m.mu.RLock()
+		missing := pool.size - len(pool.instances)
+		drained := generation != pool.generation
+		m.mu.RUnlock()
+		if missing <= 0 || drained {
+			return
+		}
+
+		server, err := m.startWarmServer(name)
+		if err != nil {
+			logger.System().Warn(" Failed to pre-warm MCP server %s: %v", name, err)
+			return
+		}
+
+		m.mu.Lock()
+		if generation != pool.generation {
+			m.mu.Unlock()
+			server.stopDetached()
+			return
+		}
+		pool.instances = append(pool.instances, server)
+		count := len(pool.instances)
+		m.mu.Unlock()
+
+		logger.System().Info("Pre-warmed MCP server %s ready (%d/%d)", server.Name, count, pool.size)
+	}
+}
+
+// startWarmServer starts and initializes an instance that is not yet bound to a session
+func (m *Manager) startWarmServer(name string) (*Server, error) {
+	m.mu.RLock()
+	server, err := m.startDetachedServer(name, "warm")
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.initializeWarm(); err != nil {
+		server.stopDetached()
+		return nil, err
+	}
+	return server, nil
+}
+
+// startDetachedServer starts an instance that is not owned by a single session
+// It runs in a directory of its own named after kind and a random suffix, as
+// the sessions it will serve are not known yet.
+// NOTE: This method must be called with m.mu locked (read or write)
+func (m *Manager) startDetachedServer(name, kind string) (*Server, error) {
+	cfg, exists := m.configs[name]
+	if !exists {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+
+	// Detached instances get a placeholder session ID for their working directory
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate instance ID: %w", err)
+	}
+	instanceID := kind + "-" + hex.EncodeToString(suffix)
+	instanceName := fmt.Sprintf("%s-%s", name, instanceID)
+
+	mcpLogger, err := logger.MCPSession(name, instanceID)
+	if err != nil {
+		logger.System().Error("Failed to create MCP logger for %s: %v", instanceName, err)
+		mcpLogger = logger.System()
+	}
+
+	server := &Server{
+		Name:         instanceName,
+		Config:       m.createSessionConfig(instanceID, name, cfg),
+		requestQueue: make(chan RequestResponse, 100),
+		queueStarted: false,
+		logger:       mcpLogger,
+		stderr:       NewStderrCapture(instanceName, mcpLogger, defaultStderrLines),
+		tracer:       m.tracer,
+		incidents:    m.incidents,
+		webhooks:     m.webhooks,
+		configName:   name,
+		timeouts:     m.timeouts,
+
+		stopGrace: m.stopGrace,
+		workDir:   m.SessionDirectory(instanceID),
+
+		notificationHandler: m.notifications,
+
+		activeOperations:    make(map[string]*OperationInfo),
+		operationTimeoutSec: m.operationTimeoutSec(),
+	}
+	server.maxResponseBytes.Store(m.maxResponseBytes)
+
+	if err := m.startServerForSession(instanceID, name, server); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// initializeWarm runs the MCP handshake on behalf of the session that will claim the instance
+func (s *Server) initializeWarm() error {
+	request, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      "warm-initialize",
+		"method":  "initialize",
+		"params": protocol.InitializeParams{
+			ProtocolVersion: protocol.MCPProtocolVersion,
+			Capabilities:    map[string]interface{}{},
+			ClientInfo:      protocol.ClientInfo{Name: "remote-mcp-proxy", Version: "1.0.0"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal initialize request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), warmInitTimeout)
+	defer cancel()
+
+	response, err := s.SendAndReceive(ctx, request)
+	if err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+
+	var message struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(response, &message); err != nil {
+		return fmt.Errorf("invalid initialize response: %w", err)
+	}
+	if len(message.Result) == 0 {
+		return fmt.Errorf("initialize rejected: %s", string(message.Error))
+	}
+
+	if err := s.SendMessage([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); err != nil {
+		return fmt.Errorf("failed to send initialized notification: %w", err)
+	}
+
+	s.mu.Lock()
+	s.initResult = message.Result
+	s.mu.Unlock()
+	return nil
+}
+
+// claimWarmServer hands a pre-warmed instance to a session and schedules a refill
+// It returns nil when the server has no warm pool or the pool is empty.
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) claimWarmServer(sessionID, name string) *Server {
+	pool, exists := m.warmPools[name]
+	if !exists {
+		return nil
+	}
+	defer func() { go m.fillWarmPool(name) }()
+
+	for len(pool.instances) > 0 {
+		server := pool.instances[0]
+		pool.instances = pool.instances[1:]
+
+		if !server.IsRunning() {
+			go server.stopDetached() // Exited while waiting; release its pipes
+			continue
+		}
+
+		server.mu.Lock()
+		server.Name = fmt.Sprintf("%s-%s", name, sessionID[:8])
+		server.sessionID = sessionID
+		server.prewarmed = true
+		server.mu.Unlock()
+		server.logger.Info("Pre-warmed instance claimed by %s", logger.SessionField(sessionID))
+
+		logger.System().Info("Session %s claimed pre-warmed MCP server %s (%d left)", sessionID[:8], name, len(pool.instances))
+		return server
+	}
+
+	logger.System().Debug("Warm pool for MCP server %s is empty, starting instance on demand", name)
+	return nil
+}
+
+// drainWarmPool stops every idle instance of a server's pool
+// Fills in progress discard their instances; the pool refills on the next claim.
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) drainWarmPool(name string) {
+	pool, exists := m.warmPools[name]
+	if !exists {
+		return
+	}
+
+	pool.generation++
+	for _, server := range pool.instances {
+		server.stopDetached()
+	}
+	pool.instances = nil
+}
+
+// stopDetached stops a detached instance and removes its working directory
+func (s *Server) stopDetached() {
+	s.Stop()
+	if err := os.RemoveAll(s.workDir); err != nil {
+		logger.System().Warn("Failed to clean up directory %s: %v", s.workDir, err)
+	}
+}
+
+// WarmInstances returns the number of idle pre-warmed instances of a server
+func (m *Manager) WarmInstances(name string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if pool, exists := m.warmPools[name]; exists {
+		return len(pool.instances)
+	}
+	return 0
+}
+
+// preinitializedResponse answers a client's initialize from the handshake a warm instance already made
+func (s *Server) preinitializedResponse(message []byte) ([]byte, bool) {
+	s.mu.RLock()
+	result := s.initResult
+	s.mu.RUnlock()
+	if result == nil {
+		return nil, false
+	}
+
+	var request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.Unmarshal(message, &request); err != nil || request.Method != "initialize" || len(request.ID) == 0 {
+		return nil, false
+	}
+
+	response, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      request.ID,
+		"result":  result,
+	})
+	if err != nil {
+		return nil, false
+	}
+	s.logger.Debug("Answered initialize for server %s from its earlier handshake", s.Name)
+	return response, true
+}
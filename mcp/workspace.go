@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// workspacesRoot holds the directories of servers with "user" workspaces, one per client identity
+var workspacesRoot = "/app/workspaces"
+
+// usesClientIdentity reports whether any server keeps a workspace per client identity or uses {USER}
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) usesClientIdentity() bool {
+	for _, cfg := range m.configs {
+		if cfg.Workspace == config.WorkspaceUser || usesVariable(cfg, "{USER}") {
+			return true
+		}
+	}
+	return false
+}
+
+// userWorkspace returns the directory of a client's workspaces, or "" when the
+// server's workspace is per session or the session's client is unknown
+// NOTE: This method must be called with m.mu locked
+func (m *Manager) userWorkspace(sessionID, serverName string) string {
+	if m.configs[serverName].Workspace != config.WorkspaceUser {
+		return ""
+	}
+	identity := m.sessionIdentities[sessionID]
+	if identity == "" {
+		return ""
+	}
+	return filepath.Join(workspacesRoot, workspaceName(identity))
+}
+
+// workspaceName turns a client identity into a directory name
+// The readable part keeps directories recognizable; the hash keeps identities
+// that only differ in replaced characters apart.
+func workspaceName(identity string) string {
+	readable := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, identity)
+	if len(readable) > 64 {
+		readable = readable[:64]
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return readable + "-" + hex.EncodeToString(sum[:4])
+}
+
+// serverWorkDir returns the working directory a session's instance of a server gets
+// NOTE: This method must be called with m.mu locked (read or write)
+func (m *Manager) serverWorkDir(sessionID, serverName string, cfg config.MCPServer) string {
+	if workspace := m.userWorkspace(sessionID, serverName); workspace != "" {
+		return filepath.Join(workspace, serverName)
+	}
+	if cfg.Sandbox != nil && cfg.RunsCommand() {
+		return filepath.Join(m.SessionDirectory(sessionID), serverName)
+	}
+	return m.SessionDirectory(sessionID)
+}
+
+// prepareUserWorkspace creates a server's working directory in a client's workspace directory
+// Sandboxed servers get the same private directory as inside a session directory.
+func (m *Manager) prepareUserWorkspace(workspace, serverName string, cfg config.MCPServer) (string, error) {
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		return "", err
+	}
+	if cfg.Sandbox != nil && cfg.RunsCommand() {
+		return m.sandboxWorkDir(workspace, serverName, cfg.Sandbox)
+	}
+
+	workDir := filepath.Join(workspace, serverName)
+	if err := m.ensureSessionDirectory(workDir); err != nil {
+		return "", err
+	}
+	logger.System().Debug("Using workspace %s for MCP server %s", workDir, serverName)
+	return workDir, nil
+}
+
+// configuredWorkDir checks the directory a server's cwd names, returning "" when it sets none
+// Directories inside the session and workspace directories are created; others,
+// typically mounted data directories, must exist, so a missing volume fails the
+// start instead of leaving the server in an empty directory.
+func configuredWorkDir(cfg config.MCPServer) (string, error) {
+	if cfg.Cwd == "" {
+		return "", nil
+	}
+	dir := filepath.Clean(cfg.Cwd)
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("cwd %q is not an absolute path", cfg.Cwd)
+	}
+
+	if isWithin(dir, sessionsRoot) || isWithin(dir, workspacesRoot) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create cwd %s: %w", dir, err)
+		}
+		return dir, nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("cwd %s is not available (is its volume mounted?): %w", dir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("cwd %s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// isWithin reports whether path is root or inside it
+func isWithin(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestWorkspaceName(t *testing.T) {
+	name := workspaceName("oidc:auth0|abc")
+	if !strings.HasPrefix(name, "oidc_auth0_abc-") {
+		t.Errorf("Expected a readable directory name, got %q", name)
+	}
+	if workspaceName("oidc:auth0_abc") == name {
+		t.Error("Expected identities differing in replaced characters to get different directories")
+	}
+	if long := workspaceName(strings.Repeat("a", 200)); len(long) > 80 {
+		t.Errorf("Expected long identities to be shortened, got %d characters", len(long))
+	}
+}
+
+func TestUserWorkspace(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	sessions, workspaces := sessionsRoot, workspacesRoot
+	sessionsRoot, workspacesRoot = t.TempDir(), t.TempDir()
+	defer func() { sessionsRoot, workspacesRoot = sessions, workspaces }()
+
+	manager := NewManager(map[string]config.MCPServer{
+		"memory":  {Command: "cat", Workspace: config.WorkspaceUser, Env: map[string]string{"MEMORY_FILE_PATH": "{WORKSPACE}/memory.json"}},
+		"scratch": {Command: "cat"},
+	})
+	defer manager.StopAll()
+
+	first := "session-one-0001"
+	if !manager.NeedsSessionIdentity(first) {
+		t.Fatal("Expected user workspaces to need the session's identity")
+	}
+	manager.SetSessionIdentity(first, "api-key:alice")
+	server, ok := manager.GetServerForSession(first, "memory")
+	if !ok {
+		t.Fatal("Expected the session server to start")
+	}
+	memoryFile := server.Config.Env["MEMORY_FILE_PATH"]
+	want := filepath.Join(workspacesRoot, workspaceName("api-key:alice"), "memory", "memory.json")
+	if memoryFile != want {
+		t.Fatalf("Expected {WORKSPACE} to be the user's workspace %s, got %s", want, memoryFile)
+	}
+	if err := os.WriteFile(memoryFile, []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write to the workspace: %v", err)
+	}
+
+	// Other servers keep working in the session directory
+	if scratch, _ := manager.GetServerForSession(first, "scratch"); scratch == nil {
+		t.Fatal("Expected the scratch server to start")
+	}
+
+	// The workspace outlives the session and is shared with the client's next one
+	manager.CleanupSession(first)
+	second := "session-two-0002"
+	manager.SetSessionIdentity(second, "api-key:alice")
+	server, ok = manager.GetServerForSession(second, "memory")
+	if !ok || server.Config.Env["MEMORY_FILE_PATH"] != memoryFile {
+		t.Fatalf("Expected the second session to use the same workspace, got %v", server)
+	}
+	if _, err := os.Stat(memoryFile); err != nil {
+		t.Errorf("Expected the workspace data to be kept: %v", err)
+	}
+
+	// Without an identity the session directory is used
+	anonymous := "session-three-03"
+	server, _ = manager.GetServerForSession(anonymous, "memory")
+	if want := filepath.Join(sessionsRoot, anonymous, "memory.json"); server == nil || server.Config.Env["MEMORY_FILE_PATH"] != want {
+		t.Errorf("Expected the anonymous session to use %s, got %v", want, server)
+	}
+}
+
+func TestConfiguredWorkDir(t *testing.T) {
+	sessions := sessionsRoot
+	sessionsRoot = t.TempDir()
+	defer func() { sessionsRoot = sessions }()
+
+	if dir, err := configuredWorkDir(config.MCPServer{Command: "server"}); err != nil || dir != "" {
+		t.Errorf("Expected no directory without cwd, got %q (%v)", dir, err)
+	}
+
+	// Directories in a session directory are created, others must exist
+	inSession := filepath.Join(sessionsRoot, "abc", "repo")
+	if dir, err := configuredWorkDir(config.MCPServer{Cwd: inSession + "/"}); err != nil || dir != inSession {
+		t.Fatalf("Expected %s, got %q (%v)", inSession, dir, err)
+	}
+	if info, err := os.Stat(inSession); err != nil || !info.IsDir() {
+		t.Errorf("Expected %s to be created, got %v", inSession, err)
+	}
+
+	data := t.TempDir()
+	if dir, err := configuredWorkDir(config.MCPServer{Cwd: data}); err != nil || dir != data {
+		t.Errorf("Expected the existing directory %s, got %q (%v)", data, dir, err)
+	}
+	file := filepath.Join(data, "notes.txt")
+	os.WriteFile(file, nil, 0644)
+	for _, cwd := range []string{filepath.Join(data, "unmounted"), file, "relative/dir"} {
+		if _, err := configuredWorkDir(config.MCPServer{Cwd: cwd}); err == nil {
+			t.Errorf("Expected cwd %s to be rejected", cwd)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// capabilityForMethod returns the server capability a method belongs to ("" for core methods)
+func capabilityForMethod(method string) string {
+	if strings.HasPrefix(method, "completion/") {
+		return "completions"
+	}
+	for _, capability := range []string{"resources", "prompts", "tools", "logging"} {
+		if strings.HasPrefix(method, capability+"/") {
+			return capability
+		}
+	}
+	return ""
+}
+
+// SetBackendCapabilities records the capabilities the session's MCP server announced
+// The capabilities announced to the client are negotiated from them.
+func (t *Translator) SetBackendCapabilities(sessionID string, capabilities map[string]interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, exists := t.connections[sessionID]; exists {
+		state.BackendCapabilities = capabilities
+		state.Capabilities = negotiateCapabilities(capabilities)
+	}
+}
+
+// negotiateCapabilities returns the capabilities to announce to the client for a backend's own
+// They are the backend's, so clients only use what the server really supports, plus an empty
+// tools capability when missing: Claude.ai only discovers tools on servers announcing it.
+func negotiateCapabilities(backend map[string]interface{}) map[string]interface{} {
+	capabilities := make(map[string]interface{}, len(backend)+1)
+	for name, value := range backend {
+		capabilities[name] = value
+	}
+	if _, exists := capabilities["tools"]; !exists {
+		capabilities["tools"] = map[string]interface{}{}
+	}
+	return capabilities
+}
+
+// NegotiatedInitializeResponse returns a backend's initialize response with the session's negotiated capabilities
+// The response is returned unchanged when it is an error or the session has no backend capabilities yet.
+func (t *Translator) NegotiatedInitializeResponse(sessionID string, initializeResponse []byte) []byte {
+	t.mu.RLock()
+	state, exists := t.connections[sessionID]
+	var capabilities map[string]interface{}
+	if exists && state.BackendCapabilities != nil {
+		capabilities = state.Capabilities
+	}
+	t.mu.RUnlock()
+	if capabilities == nil {
+		return initializeResponse
+	}
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(initializeResponse, &response); err != nil {
+		return initializeResponse
+	}
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(response["result"], &result); err != nil || result == nil {
+		return initializeResponse
+	}
+
+	encoded, err := json.Marshal(capabilities)
+	if err != nil {
+		return initializeResponse
+	}
+	result["capabilities"] = encoded
+	if response["result"], err = json.Marshal(result); err != nil {
+		return initializeResponse
+	}
+	negotiated, err := json.Marshal(response)
+	if err != nil {
+		return initializeResponse
+	}
+	return negotiated
+}
+
+// RecordBackendCapabilities records the capabilities from an MCP server's initialize response
+// Error responses and responses without capabilities leave the session unchanged.
+func (t *Translator) RecordBackendCapabilities(sessionID string, initializeResponse []byte) {
+	var response struct {
+		Result *struct {
+			Capabilities map[string]interface{} `json:"capabilities"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(initializeResponse, &response); err != nil || response.Result == nil || response.Result.Capabilities == nil {
+		return
+	}
+	t.SetBackendCapabilities(sessionID, response.Result.Capabilities)
+}
+
+// BackendSupports reports whether the session's MCP server announced the capability a method belongs to
+// It returns true while the capabilities are unknown, so requests are forwarded rather than faked.
+func (t *Translator) BackendSupports(sessionID, method string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	state, exists := t.connections[sessionID]
+	return !exists || state.backendSupports(method)
+}
+
+// backendSupports reports whether the session's MCP server supports a method, true while unknown
+func (state *ConnectionState) backendSupports(method string) bool {
+	capability := capabilityForMethod(method)
+	if capability == "" || state.BackendCapabilities == nil {
+		return true
+	}
+	if _, supported := state.BackendCapabilities[capability]; supported {
+		return true
+	}
+	if capability == "completions" {
+		// Servers written before the completions capability existed offer
+		// completion for their prompts and resource templates
+		_, prompts := state.BackendCapabilities["prompts"]
+		_, resources := state.BackendCapabilities["resources"]
+		return prompts || resources
+	}
+	return false
+}
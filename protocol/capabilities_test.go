@@ -0,0 +1,100 @@
+package protocol
+
+import "testing"
+
+func TestShouldProvideFallback(t *testing.T) {
+	translator := NewTranslator()
+	translator.RegisterSession("session-1")
+
+	// Unknown capabilities: forward rather than fake an answer
+	if translator.ShouldProvideFallback("session-1", "resources/list") {
+		t.Error("Expected resources/list to be forwarded while capabilities are unknown")
+	}
+
+	translator.RecordBackendCapabilities("session-1", []byte(`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"tools":{},"resources":{"subscribe":true}}}}`))
+
+	tests := []struct {
+		method   string
+		fallback bool
+	}{
+		{"resources/list", false},
+		{"resources/read", false},
+		{"prompts/list", true},
+		{"prompts/get", true},
+		{"completion/complete", false}, // Offered alongside resources by older servers
+		{"tools/list", false},
+		{"ping", false},
+	}
+	for _, tt := range tests {
+		if got := translator.ShouldProvideFallback("session-1", tt.method); got != tt.fallback {
+			t.Errorf("ShouldProvideFallback(%s) = %v, expected %v", tt.method, got, tt.fallback)
+		}
+	}
+
+	// Error responses leave the recorded capabilities untouched
+	translator.RecordBackendCapabilities("session-1", []byte(`{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"boom"}}`))
+	if !translator.BackendSupports("session-1", "resources/subscribe") {
+		t.Error("Expected resources support to be kept after an error response")
+	}
+}
+
+func TestCompletionFallback(t *testing.T) {
+	translator := NewTranslator()
+	translator.RegisterSession("tools-only")
+	translator.RegisterSession("completions")
+
+	translator.RecordBackendCapabilities("tools-only", []byte(`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"tools":{}}}}`))
+	translator.RecordBackendCapabilities("completions", []byte(`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"tools":{},"completions":{}}}}`))
+
+	if translator.ShouldProvideFallback("completions", "completion/complete") {
+		t.Error("Expected completion/complete to be forwarded to a server announcing completions")
+	}
+	if !translator.ShouldProvideFallback("tools-only", "completion/complete") {
+		t.Fatal("Expected a fallback for a server without prompts, resources or completions")
+	}
+
+	response, err := translator.CreateFallbackResponse(7, "completion/complete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"jsonrpc":"2.0","id":7,"result":{"completion":{"hasMore":false,"values":[]}}}`; string(response) != want {
+		t.Errorf("Expected %s, got %s", want, response)
+	}
+}
+
+func TestNegotiatedInitializeResponse(t *testing.T) {
+	translator := NewTranslator()
+	result, err := translator.HandleInitialize("session-1", InitializeParams{ProtocolVersion: MCPProtocolVersion})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Before the backend answers only tools are announced
+	if _, exists := result.Capabilities["resources"]; exists || result.Capabilities["tools"] == nil {
+		t.Errorf("Expected only the tools capability by default, got %v", result.Capabilities)
+	}
+
+	backend := []byte(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","capabilities":{"resources":{"subscribe":true}},"serverInfo":{"name":"files","version":"2.0.0"}}}`)
+	if got := translator.NegotiatedInitializeResponse("session-1", backend); string(got) != string(backend) {
+		t.Errorf("Expected the response to be unchanged before capabilities are recorded, got %s", got)
+	}
+
+	translator.RecordBackendCapabilities("session-1", backend)
+	want := `{"id":1,"jsonrpc":"2.0","result":{"capabilities":{"resources":{"subscribe":true},"tools":{}},"protocolVersion":"2024-11-05","serverInfo":{"name":"files","version":"2.0.0"}}}`
+	if got := translator.NegotiatedInitializeResponse("session-1", backend); string(got) != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	// A repeated handshake keeps announcing what the backend supports
+	result, err = translator.HandleInitialize("session-1", InitializeParams{ProtocolVersion: MCPProtocolVersion})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := result.Capabilities["resources"]; !exists {
+		t.Errorf("Expected the backend's capabilities after re-initialize, got %v", result.Capabilities)
+	}
+
+	errorResponse := []byte(`{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"boom"}}`)
+	if got := translator.NegotiatedInitializeResponse("session-1", errorResponse); string(got) != string(errorResponse) {
+		t.Errorf("Expected error responses to be unchanged, got %s", got)
+	}
+}
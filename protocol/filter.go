@@ -0,0 +1,119 @@
+package protocol
+
+import (
+	"encoding/json"
+	"path"
+)
+
+// ToolFilter restricts which tools of a server are exposed to remote clients
+// Patterns use path.Match syntax (e.g. "write_*") and are matched against both
+// the original and the normalized tool name. Blocked patterns win over allowed
+// ones; an empty allow list allows every tool that is not blocked.
+type ToolFilter struct {
+	Allowed []string
+	Blocked []string
+}
+
+// Allows reports whether the filter exposes the named tool
+func (f ToolFilter) Allows(toolName string) bool {
+	if matchesAnyTool(f.Blocked, toolName) {
+		return false
+	}
+	return len(f.Allowed) == 0 || matchesAnyTool(f.Allowed, toolName)
+}
+
+// matchesAnyTool reports whether a tool name matches one of the patterns
+func matchesAnyTool(patterns []string, toolName string) bool {
+	normalized := NormalizeToolName(toolName)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, toolName); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, normalized); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// SetToolFilter configures the allowed and blocked tool patterns for a server
+func (t *Translator) SetToolFilter(serverName string, allowed, blocked []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(allowed) == 0 && len(blocked) == 0 {
+		delete(t.toolFilters, serverName)
+		return
+	}
+	t.toolFilters[serverName] = ToolFilter{Allowed: allowed, Blocked: blocked}
+}
+
+// IsToolAllowed reports whether a server's tool may be listed and called
+func (t *Translator) IsToolAllowed(serverName, toolName string) bool {
+	t.mu.RLock()
+	filter, exists := t.toolFilters[serverName]
+	t.mu.RUnlock()
+
+	return !exists || filter.Allows(toolName)
+}
+
+// FilterTools removes the tools a server's filter does not expose
+func (t *Translator) FilterTools(serverName string, tools []interface{}) []interface{} {
+	t.mu.RLock()
+	filter, exists := t.toolFilters[serverName]
+	t.mu.RUnlock()
+	if !exists {
+		return tools
+	}
+
+	filtered := make([]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		if filter.Allows(toolName(tool)) {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
+}
+
+// FilterToolsListResponse applies a server's tool filter to a tools/list response
+// Responses without a tools result, or servers without a filter, are returned unchanged.
+func (t *Translator) FilterToolsListResponse(serverName string, response []byte) []byte {
+	t.mu.RLock()
+	_, exists := t.toolFilters[serverName]
+	t.mu.RUnlock()
+	if !exists {
+		return response
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(response, &msg); err != nil {
+		return response
+	}
+	result, ok := msg["result"].(map[string]interface{})
+	if !ok {
+		return response
+	}
+	tools, ok := result["tools"].([]interface{})
+	if !ok {
+		return response
+	}
+
+	result["tools"] = t.FilterTools(serverName, tools)
+	filtered, err := json.Marshal(msg)
+	if err != nil {
+		return response
+	}
+	return filtered
+}
+
+// ParseToolCall returns the tool name and arguments of a tools/call request
+// The name is "" for other messages.
+func ParseToolCall(request []byte) (string, interface{}) {
+	var msg JSONRPCMessage
+	if err := json.Unmarshal(request, &msg); err != nil || msg.Method != "tools/call" {
+		return "", nil
+	}
+	params, _ := msg.Params.(map[string]interface{})
+	name, _ := params["name"].(string)
+	return name, params["arguments"]
+}
@@ -0,0 +1,81 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToolFilter(t *testing.T) {
+	translator := NewTranslator()
+	translator.SetToolFilter("filesystem", []string{"read_*", "list_*"}, []string{"read_secret*"})
+	translator.SetToolFilter("memory", nil, []string{"delete-*"})
+
+	tests := []struct {
+		server  string
+		tool    string
+		allowed bool
+	}{
+		{"filesystem", "read_file", true},
+		{"filesystem", "list_directory", true},
+		{"filesystem", "write_file", false},
+		{"filesystem", "read_secrets", false},
+		{"memory", "create_entities", true},
+		{"memory", "delete-entities", false},
+		{"memory", "Delete Entities", true},
+		{"notion", "anything", true},
+	}
+	for _, tt := range tests {
+		if got := translator.IsToolAllowed(tt.server, tt.tool); got != tt.allowed {
+			t.Errorf("IsToolAllowed(%s, %s) = %v, want %v", tt.server, tt.tool, got, tt.allowed)
+		}
+	}
+
+	// Patterns also match the normalized name Claude.ai sees
+	translator.SetToolFilter("github", nil, []string{"create_*"})
+	if translator.IsToolAllowed("github", "create-issue") {
+		t.Error("Expected create-issue to be blocked through its normalized name")
+	}
+
+	// Clearing both lists removes the filter
+	translator.SetToolFilter("filesystem", nil, nil)
+	if !translator.IsToolAllowed("filesystem", "write_file") {
+		t.Error("Expected write_file to be allowed after clearing the filter")
+	}
+}
+
+func TestFilterToolsListResponse(t *testing.T) {
+	translator := NewTranslator()
+	translator.SetToolFilter("filesystem", nil, []string{"write_*"})
+
+	response := []byte(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"read_file"},{"name":"write_file"}]}}`)
+	filtered := translator.FilterToolsListResponse("filesystem", response)
+
+	var msg struct {
+		Result struct {
+			Tools []map[string]interface{} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(filtered, &msg); err != nil {
+		t.Fatalf("Invalid filtered response: %v", err)
+	}
+	if len(msg.Result.Tools) != 1 || msg.Result.Tools[0]["name"] != "read_file" {
+		t.Errorf("Expected only read_file, got %+v", msg.Result.Tools)
+	}
+
+	if unchanged := translator.FilterToolsListResponse("memory", response); string(unchanged) != string(response) {
+		t.Error("Expected response of unfiltered server to be unchanged")
+	}
+}
+
+func TestParseToolCall(t *testing.T) {
+	name, arguments := ParseToolCall([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"write_file","arguments":{"path":"/tmp/x"}}}`))
+	if name != "write_file" {
+		t.Errorf("Expected write_file, got %q", name)
+	}
+	if args, _ := arguments.(map[string]interface{}); args["path"] != "/tmp/x" {
+		t.Errorf("Expected arguments to be returned, got %+v", arguments)
+	}
+	if name, _ := ParseToolCall([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)); name != "" {
+		t.Errorf("Expected no tool name for tools/list, got %q", name)
+	}
+}
@@ -0,0 +1,155 @@
+package protocol
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"remote-mcp-proxy/logger"
+)
+
+// Tool namespacing modes for tool lists merged from several MCP servers
+const (
+	NamespaceAlways     = "always"     // Prefix every tool with its server name
+	NamespaceCollisions = "collisions" // Prefix only tools whose normalized name is exposed by several servers
+)
+
+// DefaultNamespaceSeparator joins server and tool names: notion + search → notion__search
+const DefaultNamespaceSeparator = "__"
+
+// ToolRoute identifies the backend tool behind an exposed tool name
+type ToolRoute struct {
+	Server string `json:"server"`
+	Tool   string `json:"tool"`
+}
+
+// SetToolNamespacing configures how merged tool lists are namespaced
+// Empty or unknown values keep the current setting.
+func (t *Translator) SetToolNamespacing(mode, separator string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if mode == NamespaceAlways || mode == NamespaceCollisions {
+		t.namespaceMode = mode
+	}
+	if separator != "" {
+		t.namespaceSeparator = separator
+	}
+}
+
+// NamespaceSeparator returns the separator between server and tool names
+func (t *Translator) NamespaceSeparator() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.namespaceSeparator
+}
+
+// NamespaceTools merges the tools of several servers into one list
+//
+// Tool names are prefixed with their server name according to the namespacing
+// mode, and the reverse mapping is recorded for the session so ResolveTool can
+// route tools/call. The returned collisions map each normalized tool name that
+// more than one server exposes to those servers.
+func (t *Translator) NamespaceTools(sessionID string, toolsByServer map[string][]interface{}) ([]interface{}, map[string][]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	servers := make([]string, 0, len(toolsByServer))
+	for server := range toolsByServer {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+
+	// Find normalized names exposed by more than one server
+	owners := make(map[string][]string)
+	for _, server := range servers {
+		seen := make(map[string]bool)
+		for _, tool := range toolsByServer[server] {
+			normalized := NormalizeToolName(toolName(tool))
+			if normalized == "" || seen[normalized] {
+				continue
+			}
+			seen[normalized] = true
+			owners[normalized] = append(owners[normalized], server)
+		}
+	}
+	collisions := make(map[string][]string)
+	for name, owning := range owners {
+		if len(owning) > 1 {
+			collisions[name] = owning
+		}
+	}
+
+	routes := make(map[string]ToolRoute)
+	merged := []interface{}{}
+	for _, server := range servers {
+		for _, tool := range toolsByServer[server] {
+			toolMap, ok := tool.(map[string]interface{})
+			name := toolName(tool)
+			if !ok || name == "" {
+				continue
+			}
+
+			exposed := name
+			if _, collides := collisions[NormalizeToolName(name)]; collides || t.namespaceMode == NamespaceAlways {
+				exposed = server + t.namespaceSeparator + name
+			}
+			if existing, taken := routes[exposed]; taken {
+				// Only possible when server or tool names contain the separator
+				logger.System().Warn("Tool name %s from server %s clashes with server %s, skipping", exposed, server, existing.Server)
+				collisions[NormalizeToolName(exposed)] = []string{existing.Server, server}
+				continue
+			}
+			routes[exposed] = ToolRoute{Server: server, Tool: name}
+
+			namespaced := make(map[string]interface{}, len(toolMap))
+			for k, v := range toolMap {
+				namespaced[k] = v
+			}
+			namespaced["name"] = exposed
+			merged = append(merged, namespaced)
+		}
+	}
+
+	if sessionID != "" {
+		t.toolRoutes[sessionID] = routes
+	}
+	return merged, collisions
+}
+
+// ResolveTool returns the backend tool for a name exposed by NamespaceTools
+func (t *Translator) ResolveTool(sessionID, name string) (ToolRoute, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	route, exists := t.toolRoutes[sessionID][name]
+	return route, exists
+}
+
+// SplitNamespacedTool splits "{server}{separator}{tool}" for one of the given servers
+// The longest matching server name wins, so server names may contain the separator.
+// It is the fallback when no mapping was recorded, e.g. tools/call before tools/list.
+func (t *Translator) SplitNamespacedTool(name string, servers []string) (ToolRoute, error) {
+	separator := t.NamespaceSeparator()
+
+	var route ToolRoute
+	for _, server := range servers {
+		if strings.HasPrefix(name, server+separator) && len(server) > len(route.Server) {
+			route = ToolRoute{Server: server, Tool: strings.TrimPrefix(name, server+separator)}
+		}
+	}
+	if route.Server == "" {
+		return route, fmt.Errorf("tool '%s' does not name a server (expected {server}%s{tool})", name, separator)
+	}
+	return route, nil
+}
+
+// toolName returns the name of a tool from a tools/list result
+func toolName(tool interface{}) string {
+	toolMap, ok := tool.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	name, _ := toolMap["name"].(string)
+	return name
+}
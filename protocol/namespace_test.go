@@ -0,0 +1,100 @@
+package protocol
+
+import (
+	"testing"
+)
+
+func namespaceTestTools() map[string][]interface{} {
+	tool := func(name string) interface{} {
+		return map[string]interface{}{"name": name, "description": name + " tool"}
+	}
+	return map[string][]interface{}{
+		"notion": {tool("search"), tool("create-page")},
+		"github": {tool("Search"), tool("list_issues")},
+	}
+}
+
+func TestNamespaceToolsAlways(t *testing.T) {
+	translator := NewTranslator()
+
+	tools, collisions := translator.NamespaceTools("session-1", namespaceTestTools())
+
+	var names []string
+	for _, tool := range tools {
+		names = append(names, toolName(tool))
+	}
+	expected := []string{"github__Search", "github__list_issues", "notion__search", "notion__create-page"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, names)
+			break
+		}
+	}
+
+	// "search" and "Search" normalize to the same name
+	if servers := collisions["search"]; len(servers) != 2 || servers[0] != "github" || servers[1] != "notion" {
+		t.Errorf("Expected search collision between github and notion, got %+v", collisions)
+	}
+
+	route, exists := translator.ResolveTool("session-1", "notion__create-page")
+	if !exists || route.Server != "notion" || route.Tool != "create-page" {
+		t.Errorf("Unexpected route: %+v (exists: %v)", route, exists)
+	}
+
+	translator.RemoveConnection("session-1")
+	if _, exists := translator.ResolveTool("session-1", "notion__create-page"); exists {
+		t.Error("Expected tool routes to be removed with the connection")
+	}
+}
+
+func TestNamespaceToolsCollisionsOnly(t *testing.T) {
+	translator := NewTranslator()
+	translator.SetToolNamespacing(NamespaceCollisions, ".")
+
+	tools, _ := translator.NamespaceTools("session-1", namespaceTestTools())
+
+	exposed := make(map[string]bool)
+	for _, tool := range tools {
+		exposed[toolName(tool)] = true
+	}
+	for _, name := range []string{"github.Search", "notion.search", "list_issues", "create-page"} {
+		if !exposed[name] {
+			t.Errorf("Expected tool %s to be exposed, got %v", name, exposed)
+		}
+	}
+
+	route, exists := translator.ResolveTool("session-1", "list_issues")
+	if !exists || route.Server != "github" || route.Tool != "list_issues" {
+		t.Errorf("Unexpected route for un-prefixed tool: %+v", route)
+	}
+}
+
+func TestSplitNamespacedTool(t *testing.T) {
+	translator := NewTranslator()
+	servers := []string{"memory", "notion", "notion__api"}
+
+	tests := []struct {
+		name     string
+		toolName string
+		want     ToolRoute
+		wantErr  bool
+	}{
+		{"simple", "memory__read_graph", ToolRoute{Server: "memory", Tool: "read_graph"}, false},
+		{"tool with separator", "memory__a__b", ToolRoute{Server: "memory", Tool: "a__b"}, false},
+		{"longest server wins", "notion__api__search", ToolRoute{Server: "notion__api", Tool: "search"}, false},
+		{"unknown server", "github__search", ToolRoute{}, true},
+		{"no prefix", "read_graph", ToolRoute{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, err := translator.SplitNamespacedTool(tt.toolName, servers)
+			if (err != nil) != tt.wantErr || route != tt.want {
+				t.Errorf("SplitNamespacedTool(%q) = %+v, %v; want %+v (error: %v)", tt.toolName, route, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
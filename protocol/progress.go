@@ -0,0 +1,76 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// progressRoute remembers which session asked for progress under a proxy-issued token
+type progressRoute struct {
+	sessionID string
+	token     interface{} // The client's own progress token
+}
+
+// TrackProgress gives a request's progress token a proxy-wide unique replacement
+//
+// Clients choose progress tokens freely, so two sessions sharing a server instance
+// may pick the same one. The request is returned with params._meta.progressToken
+// replaced, along with a func that forgets the token once the request is answered.
+// Requests without a progress token are returned unchanged.
+func (t *Translator) TrackProgress(sessionID string, request []byte) ([]byte, func()) {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(request, &msg); err != nil {
+		return request, func() {}
+	}
+	params, _ := msg["params"].(map[string]interface{})
+	meta, _ := params["_meta"].(map[string]interface{})
+	token, exists := meta["progressToken"]
+	if !exists || token == nil {
+		return request, func() {}
+	}
+
+	t.mu.Lock()
+	t.progressSeq++
+	proxyToken := fmt.Sprintf("progress-%d", t.progressSeq)
+	t.progressTokens[proxyToken] = progressRoute{sessionID: sessionID, token: token}
+	t.mu.Unlock()
+	forget := func() {
+		t.mu.Lock()
+		delete(t.progressTokens, proxyToken)
+		t.mu.Unlock()
+	}
+
+	meta["progressToken"] = proxyToken
+	rewritten, err := json.Marshal(msg)
+	if err != nil {
+		forget()
+		return request, func() {}
+	}
+	return rewritten, forget
+}
+
+// RouteProgress finds the session a notifications/progress message is for
+// It returns the session and the notification carrying the client's own token, or
+// false when the token belongs to no request in flight.
+func (t *Translator) RouteProgress(notification []byte) (string, []byte, bool) {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(notification, &msg); err != nil {
+		return "", nil, false
+	}
+	params, _ := msg["params"].(map[string]interface{})
+	proxyToken, _ := params["progressToken"].(string)
+
+	t.mu.RLock()
+	route, exists := t.progressTokens[proxyToken]
+	t.mu.RUnlock()
+	if !exists {
+		return "", nil, false
+	}
+
+	params["progressToken"] = route.token
+	restored, err := json.Marshal(msg)
+	if err != nil {
+		return "", nil, false
+	}
+	return route.sessionID, restored, true
+}
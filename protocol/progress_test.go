@@ -0,0 +1,69 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// progressToken returns the progress token in a request's params._meta
+func progressToken(t *testing.T, request []byte) interface{} {
+	t.Helper()
+	var msg struct {
+		Params struct {
+			Meta map[string]interface{} `json:"_meta"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(request, &msg); err != nil {
+		t.Fatalf("Invalid request: %v", err)
+	}
+	return msg.Params.Meta["progressToken"]
+}
+
+func TestProgressRouting(t *testing.T) {
+	translator := NewTranslator()
+
+	// Two sessions pick the same token
+	request := []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"crawl","_meta":{"progressToken":1}}}`)
+	first, forgetFirst := translator.TrackProgress("session-1", request)
+	second, forgetSecond := translator.TrackProgress("session-2", request)
+	defer forgetSecond()
+
+	firstToken, secondToken := progressToken(t, first), progressToken(t, second)
+	if firstToken == secondToken || firstToken == float64(1) {
+		t.Fatalf("Expected distinct proxy tokens, got %v and %v", firstToken, secondToken)
+	}
+
+	notification, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/progress",
+		"params":  map[string]interface{}{"progressToken": secondToken, "progress": 5},
+	})
+	sessionID, restored, ok := translator.RouteProgress(notification)
+	if !ok || sessionID != "session-2" {
+		t.Fatalf("Expected progress routed to session-2, got %q (%v)", sessionID, ok)
+	}
+	var msg struct {
+		Params map[string]interface{} `json:"params"`
+	}
+	if err := json.Unmarshal(restored, &msg); err != nil || msg.Params["progressToken"] != float64(1) || msg.Params["progress"] != float64(5) {
+		t.Errorf("Expected the client's token restored, got %s", restored)
+	}
+
+	forgetFirst()
+	notification, _ = json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/progress",
+		"params":  map[string]interface{}{"progressToken": firstToken},
+	})
+	if _, _, ok := translator.RouteProgress(notification); ok {
+		t.Error("Expected progress for an answered request to be dropped")
+	}
+
+	// Requests without a token are forwarded unchanged
+	plain := []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	if got, forget := translator.TrackProgress("session-1", plain); string(got) != string(plain) {
+		t.Errorf("Expected request without progress token unchanged, got %s", got)
+	} else {
+		forget()
+	}
+}
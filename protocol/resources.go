@@ -0,0 +1,175 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ResourcePathPrefix starts the proxy URLs serving rewritten resources: /resources/{server}/{encoded}
+const ResourcePathPrefix = "/resources/"
+
+// EncodeResourceURI encodes a backend resource URI as one URL path segment
+func EncodeResourceURI(uri string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(uri))
+}
+
+// DecodeResourceURI reverses EncodeResourceURI
+func DecodeResourceURI(encoded string) (string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid resource reference: %w", err)
+	}
+	return string(decoded), nil
+}
+
+// SetResourceBaseURL records the proxy URL the session's client reaches the proxy at, e.g. "https://mcp.example.com"
+// Resource URIs are only rewritten for sessions with a base URL.
+func (t *Translator) SetResourceBaseURL(sessionID, baseURL string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, exists := t.connections[sessionID]; exists {
+		state.ResourceBaseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// resourceBaseURL returns the session's proxy base URL ("" when URIs are not rewritten)
+func (t *Translator) resourceBaseURL(sessionID string) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if state, exists := t.connections[sessionID]; exists {
+		return state.ResourceBaseURL
+	}
+	return ""
+}
+
+// Methods whose responses (or, for notifications, params) carry resource URIs to rewrite
+var rewrittenResourceMethods = map[string]bool{
+	"resources/list":                  true,
+	"resources/read":                  true,
+	"tools/call":                      true, // Embedded resources and resource links
+	"notifications/resources/updated": true,
+}
+
+// Requests whose params name a resource the backend must see under its own URI
+var restoredResourceMethods = map[string]bool{
+	"resources/read":        true,
+	"resources/subscribe":   true,
+	"resources/unsubscribe": true,
+}
+
+// RewriteResourceURIs replaces the resource URIs in a backend message with proxy URLs
+//
+// method is the request the message answers, or the notification's own method.
+// Every "uri" string in the result or params is rewritten. The message is
+// returned unchanged for other methods and when the session has no base URL.
+func (t *Translator) RewriteResourceURIs(sessionID, serverName, method string, message []byte) []byte {
+	if !rewrittenResourceMethods[method] {
+		return message
+	}
+	baseURL := t.resourceBaseURL(sessionID)
+	if baseURL == "" {
+		return message
+	}
+	prefix := baseURL + ResourcePathPrefix + serverName + "/"
+
+	return rewriteMessageURIs(message, func(uri string) string {
+		if strings.HasPrefix(uri, prefix) {
+			return uri // Already a proxy URL
+		}
+		return prefix + EncodeResourceURI(uri)
+	})
+}
+
+// RestoreResourceURIs maps proxy URLs in a resources/read, subscribe or unsubscribe request back to backend URIs
+func (t *Translator) RestoreResourceURIs(serverName, method string, request []byte) []byte {
+	if !restoredResourceMethods[method] {
+		return request
+	}
+	return rewriteMessageURIs(request, func(uri string) string {
+		return t.RestoreResourceURI(serverName, uri)
+	})
+}
+
+// RestoreResourceURI maps one proxy URL back to the backend's resource URI
+// URLs for other servers and URIs that are not proxy URLs are returned unchanged.
+func (t *Translator) RestoreResourceURI(serverName, uri string) string {
+	marker := ResourcePathPrefix + serverName + "/"
+	index := strings.Index(uri, marker)
+	if index < 0 || !strings.Contains(uri[:index], "://") {
+		return uri
+	}
+	original, err := DecodeResourceURI(uri[index+len(marker):])
+	if err != nil {
+		return uri
+	}
+	return original
+}
+
+// rewriteMessageURIs applies rewrite to every "uri" string in a message's result and params
+func rewriteMessageURIs(message []byte, rewrite func(string) string) []byte {
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return message
+	}
+
+	changed := false
+	for _, field := range []string{"result", "params"} {
+		raw, exists := msg[field]
+		if !exists {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			continue
+		}
+		if !rewriteURIs(value, rewrite) {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return message
+		}
+		msg[field] = encoded
+		changed = true
+	}
+	if !changed {
+		return message
+	}
+
+	rewritten, err := json.Marshal(msg)
+	if err != nil {
+		return message
+	}
+	return rewritten
+}
+
+// rewriteURIs rewrites the "uri" strings in a decoded JSON value in place, reporting whether any changed
+func rewriteURIs(value interface{}, rewrite func(string) string) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if uri, ok := child.(string); ok && key == "uri" {
+				if rewritten := rewrite(uri); rewritten != uri {
+					v[key] = rewritten
+					changed = true
+				}
+				continue
+			}
+			if rewriteURIs(child, rewrite) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if rewriteURIs(child, rewrite) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
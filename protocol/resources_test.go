@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResourceURIRewriting(t *testing.T) {
+	translator := NewTranslator()
+	translator.RegisterSession("session-1")
+
+	list := []byte(`{"jsonrpc":"2.0","id":1,"result":{"resources":[{"uri":"file:///notes/todo.md","name":"todo"}]}}`)
+	if got := translator.RewriteResourceURIs("session-1", "files", "resources/list", list); string(got) != string(list) {
+		t.Errorf("Expected no rewriting without a base URL, got %s", got)
+	}
+
+	translator.SetResourceBaseURL("session-1", "https://files.mcp.example.com/")
+	proxyURL := "https://files.mcp.example.com/resources/files/" + EncodeResourceURI("file:///notes/todo.md")
+
+	rewritten := string(translator.RewriteResourceURIs("session-1", "files", "resources/list", list))
+	if !strings.Contains(rewritten, `"uri":"`+proxyURL+`"`) || strings.Contains(rewritten, "file:///") {
+		t.Errorf("Expected the URI to be rewritten to %s, got %s", proxyURL, rewritten)
+	}
+
+	updated := []byte(`{"jsonrpc":"2.0","method":"notifications/resources/updated","params":{"uri":"file:///notes/todo.md"}}`)
+	if got := string(translator.RewriteResourceURIs("session-1", "files", "notifications/resources/updated", updated)); !strings.Contains(got, proxyURL) {
+		t.Errorf("Expected the update notification to carry the proxy URL, got %s", got)
+	}
+
+	// Tool schemas are not resources
+	tools := []byte(`{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"open","inputSchema":{"properties":{"uri":"string"}}}]}}`)
+	if got := translator.RewriteResourceURIs("session-1", "files", "tools/list", tools); string(got) != string(tools) {
+		t.Errorf("Expected tools/list to be left alone, got %s", got)
+	}
+
+	read := []byte(`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"` + proxyURL + `"}}`)
+	if got := string(translator.RestoreResourceURIs("files", "resources/read", read)); !strings.Contains(got, `"uri":"file:///notes/todo.md"`) {
+		t.Errorf("Expected the backend URI to be restored, got %s", got)
+	}
+	// A proxy URL of another server is not this server's resource
+	if got := translator.RestoreResourceURI("notes", proxyURL); got != proxyURL {
+		t.Errorf("Expected URLs of other servers to be kept, got %s", got)
+	}
+}
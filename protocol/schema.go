@@ -0,0 +1,238 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxSchemaErrors bounds how many violations are reported for one call
+const maxSchemaErrors = 10
+
+// SchemaError lists the ways tools/call arguments violate a tool's inputSchema
+type SchemaError struct {
+	Tool       string
+	Violations []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("Invalid arguments for tool '%s': %s", e.Tool, strings.Join(e.Violations, "; "))
+}
+
+// CacheToolSchemas records the inputSchema of every tool in a tools/list response
+// Schemas are keyed by server and by both the original and normalized tool name.
+func (t *Translator) CacheToolSchemas(serverName string, tools []interface{}) {
+	schemas := make(map[string]map[string]interface{})
+	for _, tool := range tools {
+		toolMap, ok := tool.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := toolName(tool)
+		schema, ok := toolMap["inputSchema"].(map[string]interface{})
+		if name == "" || !ok {
+			continue
+		}
+		schemas[name] = schema
+		schemas[NormalizeToolName(name)] = schema
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.toolSchemas[serverName] = schemas
+}
+
+// CacheToolSchemasFromResponse records the tool schemas of a raw tools/list response
+func (t *Translator) CacheToolSchemasFromResponse(serverName string, response []byte) {
+	var msg struct {
+		Result struct {
+			Tools []interface{} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(response, &msg); err != nil || msg.Result.Tools == nil {
+		return
+	}
+	t.CacheToolSchemas(serverName, msg.Result.Tools)
+}
+
+// ValidateToolArguments checks tools/call arguments against the tool's cached inputSchema
+// Tools without a cached schema are not validated. The returned error is a *SchemaError.
+func (t *Translator) ValidateToolArguments(serverName, toolName string, arguments interface{}) error {
+	t.mu.RLock()
+	schema, exists := t.toolSchemas[serverName][toolName]
+	t.mu.RUnlock()
+	if !exists {
+		return nil
+	}
+
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+
+	var violations []string
+	validateSchema(schema, arguments, "arguments", &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &SchemaError{Tool: toolName, Violations: violations}
+}
+
+// validateSchema appends the violations of value against a JSON Schema subset
+//
+// Supported keywords: type, enum, required, properties, additionalProperties,
+// items, minimum, maximum, minLength, maxLength, minItems and maxItems. Other
+// keywords (e.g. $ref, anyOf) are ignored so unusual schemas never reject a call.
+func validateSchema(schema map[string]interface{}, value interface{}, path string, violations *[]string) {
+	report := func(format string, args ...interface{}) {
+		if len(*violations) < maxSchemaErrors {
+			*violations = append(*violations, path+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesSchemaType(types, value) {
+		report("expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if jsonEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("value is not one of the allowed values")
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if key, ok := name.(string); ok {
+					if _, present := v[key]; !present {
+						report("missing required property '%s'", key)
+					}
+				}
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+				validateSchema(propertySchema, v[key], path+"."+key, violations)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					report("unexpected property '%s'", key)
+				}
+			case map[string]interface{}:
+				validateSchema(additional, v[key], path+"."+key, violations)
+			}
+		}
+
+	case []interface{}:
+		if minItems, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < minItems {
+			report("expected at least %v items, got %d", minItems, len(v))
+		}
+		if maxItems, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > maxItems {
+			report("expected at most %v items, got %d", maxItems, len(v))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+
+	case string:
+		length := float64(len([]rune(v)))
+		if minLength, ok := schemaNumber(schema["minLength"]); ok && length < minLength {
+			report("expected at least %v characters", minLength)
+		}
+		if maxLength, ok := schemaNumber(schema["maxLength"]); ok && length > maxLength {
+			report("expected at most %v characters", maxLength)
+		}
+
+	case float64:
+		if minimum, ok := schemaNumber(schema["minimum"]); ok && v < minimum {
+			report("expected a value >= %v, got %v", minimum, v)
+		}
+		if maximum, ok := schemaNumber(schema["maximum"]); ok && v > maximum {
+			report("expected a value <= %v, got %v", maximum, v)
+		}
+	}
+}
+
+// schemaTypes returns the type keyword as a list ("string" or ["string", "null"])
+func schemaTypes(typeValue interface{}) []string {
+	switch v := typeValue.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var types []string
+		for _, item := range v {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// matchesSchemaType reports whether a decoded JSON value has one of the given types
+func matchesSchemaType(types []string, value interface{}) bool {
+	actual := jsonTypeName(value)
+	for _, expected := range types {
+		if expected == actual || (expected == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeName returns the JSON Schema type of a decoded JSON value
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaNumber reads a numeric schema keyword
+func schemaNumber(value interface{}) (float64, bool) {
+	number, ok := value.(float64)
+	return number, ok
+}
+
+// jsonEqual compares two decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateToolArguments(t *testing.T) {
+	translator := NewTranslator()
+	translator.CacheToolSchemas("filesystem", []interface{}{
+		map[string]interface{}{
+			"name": "write-file",
+			"inputSchema": map[string]interface{}{
+				"type":                 "object",
+				"required":             []interface{}{"path", "content"},
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"path":    map[string]interface{}{"type": "string", "minLength": float64(1)},
+					"content": map[string]interface{}{"type": "string"},
+					"mode":    map[string]interface{}{"type": "string", "enum": []interface{}{"overwrite", "append"}},
+					"tags":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+					"retries": map[string]interface{}{"type": "integer", "minimum": float64(0), "maximum": float64(5)},
+				},
+			},
+		},
+		map[string]interface{}{"name": "list_files"},
+	})
+
+	tests := []struct {
+		name      string
+		tool      string
+		arguments interface{}
+		wantError []string
+	}{
+		{
+			name:      "valid arguments",
+			tool:      "write-file",
+			arguments: map[string]interface{}{"path": "/tmp/a", "content": "x", "mode": "append", "tags": []interface{}{"a"}, "retries": float64(2)},
+		},
+		{
+			name:      "normalized tool name",
+			tool:      "write_file",
+			arguments: map[string]interface{}{"path": "/tmp/a", "content": "x"},
+		},
+		{
+			name:      "missing required properties",
+			tool:      "write-file",
+			arguments: nil,
+			wantError: []string{"missing required property 'path'", "missing required property 'content'"},
+		},
+		{
+			name:      "wrong types and values",
+			tool:      "write-file",
+			arguments: map[string]interface{}{"path": "", "content": float64(1), "mode": "truncate", "tags": []interface{}{true}, "retries": 1.5, "force": true},
+			wantError: []string{
+				"arguments.path: expected at least 1 characters",
+				"arguments.content: expected string, got integer",
+				"arguments.mode: value is not one of the allowed values",
+				"arguments.tags[0]: expected string, got boolean",
+				"arguments.retries: expected integer, got number",
+				"arguments: unexpected property 'force'",
+			},
+		},
+		{
+			name:      "tool without schema",
+			tool:      "list_files",
+			arguments: "anything",
+		},
+		{
+			name:      "unknown tool",
+			tool:      "delete_file",
+			arguments: "anything",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translator.ValidateToolArguments("filesystem", tt.tool, tt.arguments)
+			if len(tt.wantError) == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected validation error")
+			}
+			for _, want := range tt.wantError {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error to contain %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}
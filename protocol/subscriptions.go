@@ -0,0 +1,61 @@
+package protocol
+
+import "sort"
+
+// Subscribe records that a session subscribed to a resource on an MCP server
+func (t *Translator) Subscribe(sessionID, serverName, uri string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, exists := t.connections[sessionID]
+	if !exists {
+		return
+	}
+	if state.Subscriptions == nil {
+		state.Subscriptions = make(map[string]string)
+	}
+	state.Subscriptions[uri] = serverName
+}
+
+// Unsubscribe removes a session's subscription to a resource
+func (t *Translator) Unsubscribe(sessionID, uri string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, exists := t.connections[sessionID]; exists {
+		delete(state.Subscriptions, uri)
+	}
+}
+
+// Subscriptions returns the resource URIs a session is subscribed to, sorted
+func (t *Translator) Subscriptions(sessionID string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	state, exists := t.connections[sessionID]
+	if !exists {
+		return nil
+	}
+	uris := make([]string, 0, len(state.Subscriptions))
+	for uri := range state.Subscriptions {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// SubscribedSessions returns the sessions subscribed to a resource on an MCP server, sorted
+// Used to route updates from a server instance shared by several sessions.
+func (t *Translator) SubscribedSessions(serverName, uri string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var sessions []string
+	for sessionID, state := range t.connections {
+		if subscribed, exists := state.Subscriptions[uri]; exists && subscribed == serverName {
+			sessions = append(sessions, sessionID)
+		}
+	}
+	sort.Strings(sessions)
+	return sessions
+}
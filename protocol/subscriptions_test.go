@@ -0,0 +1,39 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubscriptions(t *testing.T) {
+	translator := NewTranslator()
+	translator.RegisterSession("session-1")
+	translator.RegisterSession("session-2")
+
+	translator.Subscribe("session-1", "files", "file:///b.txt")
+	translator.Subscribe("session-1", "files", "file:///a.txt")
+	translator.Subscribe("session-2", "files", "file:///a.txt")
+	translator.Subscribe("session-2", "notes", "file:///b.txt")
+	translator.Subscribe("session-unknown", "files", "file:///a.txt")
+
+	if got := translator.Subscriptions("session-1"); !reflect.DeepEqual(got, []string{"file:///a.txt", "file:///b.txt"}) {
+		t.Errorf("Expected both URIs for session-1, got %v", got)
+	}
+	if got := translator.SubscribedSessions("files", "file:///a.txt"); !reflect.DeepEqual(got, []string{"session-1", "session-2"}) {
+		t.Errorf("Expected both sessions subscribed to a.txt, got %v", got)
+	}
+	// The same URI on another server is a different resource
+	if got := translator.SubscribedSessions("files", "file:///b.txt"); !reflect.DeepEqual(got, []string{"session-1"}) {
+		t.Errorf("Expected only session-1 subscribed to files b.txt, got %v", got)
+	}
+
+	translator.Unsubscribe("session-1", "file:///a.txt")
+	if got := translator.SubscribedSessions("files", "file:///a.txt"); !reflect.DeepEqual(got, []string{"session-2"}) {
+		t.Errorf("Expected session-1 to be unsubscribed, got %v", got)
+	}
+
+	translator.RemoveConnection("session-2")
+	if got := translator.SubscribedSessions("files", "file:///a.txt"); len(got) != 0 {
+		t.Errorf("Expected subscriptions to end with the session, got %v", got)
+	}
+}
@@ -76,18 +76,36 @@ type ConnectionState struct {
 	Capabilities    map[string]interface{}
 	SessionID       string
 	PendingRequests map[interface{}]*PendingRequest // Maps request ID to request info
+
+	BackendCapabilities map[string]interface{} // Announced by the MCP server's initialize result (nil until known)
+	Subscriptions       map[string]string      // Subscribed resource URI → server name
+	ResourceBaseURL     string                 // Proxy URL resource URIs are rewritten to ("" when not rewritten)
 }
 
 // Translator handles protocol translation between Remote MCP and local MCP
 type Translator struct {
 	connections map[string]*ConnectionState
 	mu          sync.RWMutex
+
+	namespaceMode      string                                       // How aggregated tool names are prefixed
+	namespaceSeparator string                                       // Joins server and tool name, e.g. "__"
+	toolRoutes         map[string]map[string]ToolRoute              // Session ID → exposed tool name → backend tool
+	toolFilters        map[string]ToolFilter                        // Server name → allowed/blocked tool patterns
+	toolSchemas        map[string]map[string]map[string]interface{} // Server name → tool name → inputSchema
+	progressTokens     map[string]progressRoute                     // Proxy progress token → requesting session
+	progressSeq        uint64
 }
 
 // NewTranslator creates a new protocol translator
 func NewTranslator() *Translator {
 	return &Translator{
-		connections: make(map[string]*ConnectionState),
+		connections:        make(map[string]*ConnectionState),
+		namespaceMode:      NamespaceAlways,
+		namespaceSeparator: DefaultNamespaceSeparator,
+		toolRoutes:         make(map[string]map[string]ToolRoute),
+		toolFilters:        make(map[string]ToolFilter),
+		toolSchemas:        make(map[string]map[string]map[string]interface{}),
+		progressTokens:     make(map[string]progressRoute),
 	}
 }
 
@@ -134,7 +152,7 @@ func (t *Translator) MCPToRemote(mcpData []byte) ([]byte, error) {
 	if messageType == "response" && jsonrpcMsg.ID != nil {
 		logger.System().Debug("=== TOOL DISCOVERY DEBUG ===")
 		logger.System().Debug("DEBUG: Processing MCP response - ID: %v, Method: %s", jsonrpcMsg.ID, jsonrpcMsg.Method)
-		logger.System().Debug("DEBUG: Raw MCP response: %s", string(mcpData))
+		logger.System().Debug("DEBUG: Raw MCP response: %s", mcpData)
 		logger.System().Debug("DEBUG: Has result: %v, Has error: %v", jsonrpcMsg.Result != nil, jsonrpcMsg.Error != nil)
 	}
 
@@ -181,7 +199,7 @@ func (t *Translator) MCPToRemote(mcpData []byte) ([]byte, error) {
 
 	// Enhanced logging for Remote MCP message format validation
 	if messageType == "response" && jsonrpcMsg.ID != nil {
-		logger.System().Debug("DEBUG: Final Remote MCP message: %s", string(remoteMsgBytes))
+		logger.System().Debug("DEBUG: Final Remote MCP message: %s", remoteMsgBytes)
 		logger.System().Debug("DEBUG: Remote MCP message type: %s, ID: %v", remoteMsg.Type, remoteMsg.ID)
 		logger.System().Debug("=== TOOL DISCOVERY DEBUG END ===")
 	}
@@ -211,9 +229,15 @@ func (t *Translator) ValidateMessage(data []byte, isRemoteMCP bool) error {
 
 // CreateErrorResponse creates an error response message
 func (t *Translator) CreateErrorResponse(id interface{}, code int, message string, isRemoteMCP bool) ([]byte, error) {
+	return t.CreateErrorResponseWithData(id, code, message, nil, isRemoteMCP)
+}
+
+// CreateErrorResponseWithData creates an error response message carrying structured error data
+func (t *Translator) CreateErrorResponseWithData(id interface{}, code int, message string, data interface{}, isRemoteMCP bool) ([]byte, error) {
 	rpcError := &RPCError{
 		Code:    code,
 		Message: message,
+		Data:    data,
 	}
 
 	if isRemoteMCP {
@@ -242,6 +266,14 @@ const (
 	InternalError  = -32603
 )
 
+// Implementation-defined server error codes (JSON-RPC reserves -32000 to -32099)
+const (
+	SessionBusy          = -32001 // Session exceeded its concurrent tool call limit
+	SessionQuotaExceeded = -32002 // Session directory exceeded its disk quota
+	UsageQuotaExceeded   = -32003 // Principal used up a daily usage quota
+	RequestCancelled     = -32800 // Request cancelled by the client before it was answered
+)
+
 // MCP Protocol constants
 const (
 	MCPProtocolVersion = "2024-11-05"
@@ -292,18 +324,18 @@ func (t *Translator) HandleInitialize(sessionID string, params InitializeParams)
 		PendingRequests: make(map[interface{}]*PendingRequest),
 	}
 
-	// Set enhanced capabilities for Remote MCP compatibility
-	// Claude.ai expects specific capability indicators to enable tool discovery
-	state.Capabilities = map[string]interface{}{
-		"tools": map[string]interface{}{
-			"listChanged": true, // Indicates tools can be discovered via tools/list
-		},
-		"resources": map[string]interface{}{
-			"listChanged": true, // Indicates resources can be discovered
-		},
-		"prompts": map[string]interface{}{
-			"listChanged": true, // Indicates prompts can be discovered
-		},
+	// Announce the backend's capabilities when a previous handshake recorded them
+	if previous, exists := t.connections[sessionID]; exists && previous.BackendCapabilities != nil {
+		state.BackendCapabilities = previous.BackendCapabilities
+		state.Capabilities = negotiateCapabilities(previous.BackendCapabilities)
+	} else {
+		// Until then only tools are announced: Claude.ai expects this indicator to enable
+		// tool discovery, while resources and prompts would be answered by empty fallbacks
+		state.Capabilities = map[string]interface{}{
+			"tools": map[string]interface{}{
+				"listChanged": true, // Indicates tools can be discovered via tools/list
+			},
+		}
 	}
 
 	t.connections[sessionID] = state
@@ -356,6 +388,7 @@ func (t *Translator) RemoveConnection(sessionID string) {
 	defer t.mu.Unlock()
 
 	delete(t.connections, sessionID)
+	delete(t.toolRoutes, sessionID)
 }
 
 // IsHandshakeMessage checks if a message is part of the handshake process
@@ -364,12 +397,19 @@ func (t *Translator) IsHandshakeMessage(method string) bool {
 }
 
 // ShouldProvideFallback checks if we should provide a fallback response for unsupported methods
-func (t *Translator) ShouldProvideFallback(method string) bool {
+// Methods of a capability the session's MCP server announced are always forwarded.
+func (t *Translator) ShouldProvideFallback(sessionID, method string) bool {
+	return isFallbackMethod(method) && !t.BackendSupports(sessionID, method)
+}
+
+// isFallbackMethod reports whether the proxy can synthesize a response for a method
+func isFallbackMethod(method string) bool {
 	fallbackMethods := []string{
 		"resources/list",
 		"resources/read",
 		"prompts/list",
 		"prompts/get",
+		"completion/complete",
 	}
 
 	for _, fm := range fallbackMethods {
@@ -397,6 +437,13 @@ func (t *Translator) CreateFallbackResponse(id interface{}, method string) ([]by
 		}
 	case "prompts/get":
 		return t.CreateErrorResponse(id, MethodNotFound, "Prompt not found", false)
+	case "completion/complete":
+		result = map[string]interface{}{
+			"completion": map[string]interface{}{
+				"values":  []interface{}{},
+				"hasMore": false,
+			},
+		}
 	default:
 		return t.CreateErrorResponse(id, MethodNotFound, "Method not found", false)
 	}
@@ -451,7 +498,7 @@ func (t *Translator) HandleMethodNotFoundError(sessionID string, response []byte
 
 	// Get the original method for this request ID
 	method, found := t.GetAndClearPendingMethod(sessionID, mcpResponse.ID)
-	if !found || !t.ShouldProvideFallback(method) {
+	if !found || !t.ShouldProvideFallback(sessionID, method) {
 		return response, false
 	}
 
@@ -464,6 +511,11 @@ func (t *Translator) HandleMethodNotFoundError(sessionID string, response []byte
 	return fallbackResponse, true
 }
 
+// NormalizeToolName converts a tool name to the snake_case form exposed to Claude.ai
+func NormalizeToolName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "-", "_"))
+}
+
 // normalizeToolNames transforms tool names to be Claude.ai compatible (snake_case)
 func (t *Translator) normalizeToolNames(result interface{}) interface{} {
 	// Handle tools/list response format
@@ -482,8 +534,7 @@ func (t *Translator) normalizeToolNames(result interface{}) interface{} {
 						// Transform the tool name: convert hyphens to underscores and lowercase
 						if name, exists := normalizedTool["name"]; exists {
 							if nameStr, ok := name.(string); ok {
-								normalizedName := strings.ToLower(strings.ReplaceAll(nameStr, "-", "_"))
-								normalizedTool["name"] = normalizedName
+								normalizedTool["name"] = NormalizeToolName(nameStr)
 							}
 						}
 						normalizedTools[i] = normalizedTool
@@ -568,7 +619,7 @@ func (t *Translator) CheckTimeouts(sessionID string, timeoutDuration time.Durati
 
 	// Find expired requests
 	for requestID, request := range state.PendingRequests {
-		if now.Sub(request.Timestamp) > timeoutDuration && t.ShouldProvideFallback(request.Method) {
+		if now.Sub(request.Timestamp) > timeoutDuration && isFallbackMethod(request.Method) && !state.backendSupports(request.Method) {
 			expiredRequests = append(expiredRequests, requestID)
 		}
 	}
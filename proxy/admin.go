@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"remote-mcp-proxy/logger"
+)
+
+// requireAdmin protects operator endpoints with the configured ADMIN_TOKEN
+//
+// The token may be sent as "Authorization: Bearer <token>" or in the
+// X-Admin-Token header. When no admin token is configured the endpoints are
+// refused: besides the changes they make, they expose traces, logs, session
+// data and tool arguments, and /selftest starts servers.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
+		if s.config == nil || s.config.AdminToken == "" {
+			logger.System().Warn(" Refused %s %s from %s: ADMIN_TOKEN is not set", r.Method, r.URL.Path, r.RemoteAddr)
+			writeError(w, http.StatusForbidden, ErrorFeatureDisabled, "This endpoint requires ADMIN_TOKEN to be set")
+			return
+		}
+
+		presented := r.Header.Get("X-Admin-Token")
+		if presented == "" {
+			presented = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(s.config.AdminToken)) != 1 {
+			logger.System().Warn(" Admin authentication failed for %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", "Bearer realm=\"Remote MCP Proxy Admin\"")
+			writeAuthError(w, http.StatusUnauthorized, ErrorUnauthorized, "Admin token required")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// tokenFingerprint returns a short, non-reversible identifier for the request's bearer token
+// so operators can correlate sessions by client without the token itself being exposed
+func tokenFingerprint(r *http.Request) string {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])[:12]
+}
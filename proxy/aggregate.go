@@ -0,0 +1,390 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+)
+
+// AggregateServerName is the virtual server exposing every configured MCP server
+// under one endpoint (all.mcp.{domain}/sse or /all/sse)
+const AggregateServerName = "all"
+
+// aggregateResult is one backend's answer to a fanned-out request
+type aggregateResult struct {
+	server   string
+	response *protocol.JSONRPCMessage
+	err      error
+}
+
+// isAggregateServer reports whether serverName refers to the virtual aggregate server
+// A configured MCP server with the same name always takes precedence.
+func (s *Server) isAggregateServer(serverName string) bool {
+	if s.config == nil || !s.config.AggregateServer || serverName != AggregateServerName {
+		return false
+	}
+	_, configured := s.config.MCPServers[serverName]
+	return !configured
+}
+
+// aggregateServerNames returns the backend servers behind the aggregate server in stable order,
+// leaving out servers in maintenance mode and those the tenant may not use
+func (s *Server) aggregateServerNames(tenant string) []string {
+	var names []string
+	for name := range s.config.MCPServers {
+		if !s.mcpManager.InMaintenance(name) && s.config.TenantServes(tenant, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// handleAggregateMessage handles a JSON-RPC request sent to the aggregate server
+//
+// The proxy answers initialize itself and fans initialize and tools/list out to
+// every backend, prefixing tool names with the backend server name. tools/call
+// is routed to the backend named by the prefix. Backends that fail are skipped
+// so one broken server does not take down the whole integration.
+func (s *Server) handleAggregateMessage(w http.ResponseWriter, r *http.Request, sessionID string) {
+	body, err := s.readMessageBody(w, r, AggregateServerName)
+	if err != nil {
+		logger.System().Error(" Failed to read aggregate request body: %v", err)
+		s.sendBodyReadError(w, err, false)
+		return
+	}
+
+	var jsonrpcMsg protocol.JSONRPCMessage
+	if err := json.Unmarshal(body, &jsonrpcMsg); err != nil {
+		logger.System().Error(" Invalid aggregate JSON-RPC message: %v", err)
+		writeError(w, http.StatusBadRequest, ErrorInvalidMessage, fmt.Sprintf("Invalid JSON-RPC message: %v", err))
+		return
+	}
+	logger.System().Info("INFO: Aggregate request %s (ID: %v) for session %s", jsonrpcMsg.Method, jsonrpcMsg.ID, sessionID)
+	s.connectionManager.RecordRequest(sessionID, jsonrpcMsg.Method)
+
+	switch jsonrpcMsg.Method {
+	case "initialize":
+		s.handleAggregateInitialize(w, r, sessionID, &jsonrpcMsg)
+		return
+	case "notifications/initialized":
+		s.handleInitialized(w, sessionID, &jsonrpcMsg)
+		return
+	}
+
+	if !s.translator.IsInitialized(sessionID) {
+		logger.System().Error(" Session %s not initialized for aggregate method %s", sessionID, jsonrpcMsg.Method)
+		s.sendErrorResponse(w, jsonrpcMsg.ID, protocol.InvalidRequest, "Connection not initialized", false)
+		return
+	}
+
+	switch jsonrpcMsg.Method {
+	case "ping":
+		s.writeAggregateResult(w, sessionID, jsonrpcMsg.ID, map[string]interface{}{})
+	case "tools/list":
+		s.handleAggregateToolsList(w, r, sessionID, &jsonrpcMsg)
+	case "tools/call":
+		s.handleAggregateToolCall(w, r, sessionID, &jsonrpcMsg)
+	default:
+		if s.translator.ShouldProvideFallback(sessionID, jsonrpcMsg.Method) {
+			fallbackResponse, err := s.translator.CreateFallbackResponse(jsonrpcMsg.ID, jsonrpcMsg.Method)
+			if err == nil {
+				s.writeAggregateResponse(w, sessionID, fallbackResponse)
+				return
+			}
+		}
+		s.sendErrorResponse(w, jsonrpcMsg.ID, protocol.MethodNotFound,
+			fmt.Sprintf("Method %s not supported by the aggregate server", jsonrpcMsg.Method), false)
+	}
+}
+
+// handleAggregateInitialize initializes every backend for the session and answers as the proxy
+func (s *Server) handleAggregateInitialize(w http.ResponseWriter, r *http.Request, sessionID string, msg *protocol.JSONRPCMessage) {
+	var params protocol.InitializeParams
+	if msg.Params != nil {
+		paramsBytes, _ := json.Marshal(msg.Params)
+		if err := json.Unmarshal(paramsBytes, &params); err != nil {
+			s.sendErrorResponse(w, msg.ID, protocol.InvalidParams, "Invalid initialize parameters", false)
+			return
+		}
+	}
+	if params.ClientInfo.Name == "" {
+		params.ClientInfo = protocol.ClientInfo{Name: protocol.ProxyServerName, Version: protocol.ProxyServerVersion}
+	}
+
+	// The proxy negotiates its own protocol version on behalf of all backends
+	params.ProtocolVersion = protocol.MCPProtocolVersion
+
+	// Like every aggregate request, bound to the client's HTTP request and cancellable by ID
+	ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), "sessionID", sessionID), 30*time.Second)
+	defer cancel()
+	defer s.inFlight.Track(sessionID, msg.ID, cancel)()
+
+	results := s.aggregateFanOut(ctx, sessionID, s.aggregateServerNames(contextTenant(r.Context())), msg.ID, "initialize", params)
+	ready := 0
+	for _, result := range results {
+		if result.err != nil {
+			logger.System().Warn(" Aggregate backend %s failed to initialize for session %s: %v", result.server, shortID(sessionID), result.err)
+			continue
+		}
+		ready++
+	}
+
+	initResult, err := s.translator.HandleInitialize(sessionID, params)
+	if err != nil {
+		logger.System().Error(" Failed to store aggregate connection state: %v", err)
+		s.sendTransportError(w, msg.ID, ErrorInternal, "Failed to initialize aggregate session", false)
+		return
+	}
+	if err := s.translator.HandleInitialized(sessionID); err != nil {
+		logger.System().Error(" Failed to mark aggregate session as initialized: %v", err)
+	}
+	// Only tools are merged across backends; resources and prompts are answered by the proxy
+	s.translator.SetBackendCapabilities(sessionID, map[string]interface{}{"tools": map[string]interface{}{}})
+
+	logger.System().Info("INFO: Aggregate session %s initialized with %d/%d backends", sessionID, ready, len(results))
+
+	w.Header().Set("X-Session-ID", sessionID)
+	s.writeAggregateResult(w, sessionID, msg.ID, initResult)
+}
+
+// handleAggregateToolsList merges the tools of every backend, namespaced by the translator
+func (s *Server) handleAggregateToolsList(w http.ResponseWriter, r *http.Request, sessionID string, msg *protocol.JSONRPCMessage) {
+	ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), "sessionID", sessionID), 10*time.Second)
+	defer cancel()
+	defer s.inFlight.Track(sessionID, msg.ID, cancel)()
+
+	results := s.aggregateFanOut(ctx, sessionID, s.aggregateServerNames(contextTenant(r.Context())), msg.ID, "tools/list", msg.Params)
+	toolsByServer, failed := s.aggregateTools(results, sessionID)
+	if len(results) > 0 && failed == len(results) {
+		if s.finishCancelledRequest(ctx, w, r, msg.ID, results[0].err, false) {
+			return
+		}
+		s.sendTransportError(w, msg.ID, ErrorUpstreamFailed, "No MCP server answered tools/list", false)
+		return
+	}
+
+	tools, collisions := s.translator.NamespaceTools(sessionID, toolsByServer)
+	for name, servers := range collisions {
+		logger.System().Warn(" Tool %s is exposed by several servers %v, namespacing it", name, servers)
+	}
+
+	logger.System().Info("INFO: Aggregate tools/list returned %d tools from %d/%d backends", len(tools), len(results)-failed, len(results))
+	s.writeAggregateResult(w, sessionID, msg.ID, map[string]interface{}{"tools": tools})
+}
+
+// aggregateTools collects the tools/list results per backend, applying each
+// backend's tool filter, and counts failed backends
+func (s *Server) aggregateTools(results []aggregateResult, sessionID string) (map[string][]interface{}, int) {
+	toolsByServer := make(map[string][]interface{})
+	failed := 0
+	for _, result := range results {
+		if result.err != nil {
+			logger.System().Warn(" Aggregate backend %s failed tools/list for session %s: %v", result.server, shortID(sessionID), result.err)
+			failed++
+			continue
+		}
+		resultMap, _ := result.response.Result.(map[string]interface{})
+		serverTools, _ := resultMap["tools"].([]interface{})
+		s.translator.CacheToolSchemas(result.server, serverTools)
+		toolsByServer[result.server] = s.translator.FilterTools(result.server, serverTools)
+	}
+	return toolsByServer, failed
+}
+
+// handleAggregateToolCall routes a namespaced tool call to its backend server
+func (s *Server) handleAggregateToolCall(w http.ResponseWriter, r *http.Request, sessionID string, msg *protocol.JSONRPCMessage) {
+	params, _ := msg.Params.(map[string]interface{})
+	name, _ := params["name"].(string)
+
+	route, exists := s.translator.ResolveTool(sessionID, name)
+	if !exists {
+		var err error
+		if route, err = s.translator.SplitNamespacedTool(name, s.aggregateServerNames(contextTenant(r.Context()))); err != nil {
+			s.sendErrorResponse(w, msg.ID, protocol.InvalidParams, err.Error(), false)
+			return
+		}
+	}
+
+	if !s.translator.IsToolAllowed(route.Server, route.Tool) || !s.config.TenantServes(contextTenant(r.Context()), route.Server) {
+		logger.System().Warn(" Blocked aggregate call to tool %s on server %s by tool filter", route.Tool, route.Server)
+		s.sendErrorResponse(w, msg.ID, protocol.InvalidParams, fmt.Sprintf("Tool '%s' is not available", name), false)
+		return
+	}
+	if err := s.translator.ValidateToolArguments(route.Server, route.Tool, params["arguments"]); err != nil {
+		logger.System().Warn(" Rejected aggregate call to tool %s on server %s: %v", route.Tool, route.Server, err)
+		s.sendErrorResponse(w, msg.ID, protocol.InvalidParams, err.Error(), false)
+		return
+	}
+	if s.refusedByPolicy(w, r, newPolicyCall(r, sessionID, route.Server, route.Tool, params), msg.ID, false) {
+		return
+	}
+	if s.refusedByQuota(w, r, route.Server, route.Tool, msg.ID, false) {
+		return
+	}
+
+	backendParams := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		backendParams[k] = v
+	}
+	backendParams["name"] = route.Tool
+
+	// The request context carries the principal the result bytes are counted against, see recordUsage
+	callCtx, cancelCall := context.WithCancel(r.Context())
+	defer cancelCall()
+	defer s.inFlight.Track(sessionID, msg.ID, cancelCall)()
+
+	release, err := s.acquireToolCallSlot(callCtx, sessionID, msg.Method)
+	if err != nil {
+		s.sendToolCallLimitError(w, msg.ID, sessionID, err, false)
+		return
+	}
+	defer release()
+
+	// The timeout starts once the call has a tool call slot
+	ctx, cancel := s.requestContext(callCtx, sessionID, route.Server, msg.Method, 2*time.Minute)
+	defer cancel()
+
+	results := s.aggregateFanOut(ctx, sessionID, []string{route.Server}, msg.ID, msg.Method, backendParams)
+	if results[0].err != nil && s.finishCancelledRequest(ctx, w, r, msg.ID, results[0].err, false) {
+		return
+	}
+	if results[0].err != nil {
+		logger.System().Error(" Aggregate tools/call to %s failed: %v", route.Server, results[0].err)
+		s.sendTransportError(w, msg.ID, ErrorUpstreamFailed, fmt.Sprintf("Failed to communicate with MCP server '%s'", route.Server), false)
+		return
+	}
+
+	responseBytes, err := json.Marshal(results[0].response)
+	if err != nil {
+		s.sendTransportError(w, msg.ID, ErrorInternal, "Failed to encode MCP server response", false)
+		return
+	}
+	s.writeAggregateResponse(w, sessionID, responseBytes)
+}
+
+// handleAggregateListTools serves /listtools/all with the merged tools and their collisions
+func (s *Server) handleAggregateListTools(w http.ResponseWriter, r *http.Request, sessionID string) {
+	ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), "sessionID", sessionID), 30*time.Second)
+	defer cancel()
+
+	// Backends started only for this listing are stopped afterwards
+	if r.Header.Get("Mcp-Session-Id") == "" && r.Header.Get("X-Session-ID") == "" {
+		defer func() { go s.mcpManager.CleanupSession(sessionID) }()
+	}
+
+	servers := s.aggregateServerNames(s.config.HostTenant(r.Host))
+	results := s.aggregateFanOut(ctx, sessionID, servers, fmt.Sprintf("listtools-%d", time.Now().UnixNano()), "tools/list", map[string]interface{}{})
+	toolsByServer, _ := s.aggregateTools(results, sessionID)
+
+	// Listing must not replace the tool routes of a live session
+	tools, collisions := s.translator.NamespaceTools("", toolsByServer)
+
+	var unavailable []string
+	for _, result := range results {
+		if result.err != nil {
+			unavailable = append(unavailable, result.server)
+		}
+	}
+
+	response := map[string]interface{}{
+		"server":      AggregateServerName,
+		"response":    map[string]interface{}{"result": map[string]interface{}{"tools": tools}},
+		"collisions":  collisions,
+		"unavailable": unavailable,
+	}
+	if checkNotModified(w, r, weakETag(response)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.System().Error(" Failed to encode aggregate listtools response: %v", err)
+	}
+}
+
+// aggregateFanOut sends the same request to each backend in parallel
+// Results are returned in the order of servers. JSON-RPC errors returned by a
+// backend are reported as err so callers can skip that backend.
+func (s *Server) aggregateFanOut(ctx context.Context, sessionID string, servers []string, id interface{}, method string, params interface{}) []aggregateResult {
+	requestBytes, err := json.Marshal(protocol.JSONRPCMessage{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	})
+
+	results := make([]aggregateResult, len(servers))
+	var wg sync.WaitGroup
+	for i, serverName := range servers {
+		results[i].server = serverName
+		if err != nil {
+			results[i].err = fmt.Errorf("failed to marshal request: %w", err)
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *aggregateResult) {
+			defer wg.Done()
+
+			mcpServer, exists := s.mcpManager.GetServerForSession(sessionID, result.server)
+			if !exists {
+				result.err = fmt.Errorf("MCP server '%s' not available", result.server)
+				return
+			}
+
+			// forwardRequest answers from the cache, with the hooks around it
+			responseBytes, err := s.forwardRequest(ctx, result.server, mcpServer, requestBytes)
+			if err != nil {
+				result.err = err
+				return
+			}
+
+			var response protocol.JSONRPCMessage
+			if err := json.Unmarshal(responseBytes, &response); err != nil {
+				result.err = fmt.Errorf("invalid response: %w", err)
+				return
+			}
+			if response.Error != nil && method != "tools/call" {
+				result.err = fmt.Errorf("%s failed: %s (code %d)", method, response.Error.Message, response.Error.Code)
+				return
+			}
+			result.response = &response
+		}(&results[i])
+	}
+	wg.Wait()
+
+	return results
+}
+
+// writeAggregateResult writes a successful JSON-RPC response from the aggregate server
+func (s *Server) writeAggregateResult(w http.ResponseWriter, sessionID string, id interface{}, result interface{}) {
+	responseBytes, err := json.Marshal(protocol.JSONRPCMessage{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result:  result,
+	})
+	if err != nil {
+		logger.System().Error(" Failed to marshal aggregate response: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to create response")
+		return
+	}
+	s.writeAggregateResponse(w, sessionID, responseBytes)
+}
+
+// writeAggregateResponse writes an encoded JSON-RPC response from the aggregate server
+func (s *Server) writeAggregateResponse(w http.ResponseWriter, sessionID string, responseBytes []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Mcp-Session-Id", sessionID)
+	if err := s.writeResponse(w, responseBytes); err != nil {
+		logger.System().Error(" Failed to write aggregate response: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/protocol"
+)
+
+func TestIsAggregateServer(t *testing.T) {
+	s := &Server{config: &config.Config{
+		AggregateServer: true,
+		MCPServers:      map[string]config.MCPServer{"memory": {Command: "echo"}},
+	}}
+	if !s.isAggregateServer(AggregateServerName) {
+		t.Error("Expected 'all' to be the aggregate server")
+	}
+	if s.isAggregateServer("memory") {
+		t.Error("Expected configured server not to be the aggregate server")
+	}
+
+	// A real server named "all" takes precedence
+	s.config.MCPServers[AggregateServerName] = config.MCPServer{Command: "echo"}
+	if s.isAggregateServer(AggregateServerName) {
+		t.Error("Expected configured 'all' server to take precedence")
+	}
+
+	s.config = &config.Config{AggregateServer: false}
+	if s.isAggregateServer(AggregateServerName) {
+		t.Error("Expected aggregate server to be disabled")
+	}
+}
+
+func TestAggregateServerFanOut(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping aggregate fan-out test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		AggregateServer: true,
+		MCPServers: map[string]config.MCPServer{
+			"alpha": helperMCPServerConfig(),
+			"beta":  helperMCPServerConfig(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	client := embedded.NewClient(AggregateServerName)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	initResponse, err := client.Initialize(ctx)
+	if err != nil || initResponse.Error != nil {
+		t.Fatalf("Initialize failed: %v %+v", err, initResponse)
+	}
+
+	toolsResponse, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("tools/list failed: %v", err)
+	}
+	result, _ := toolsResponse.Result.(map[string]interface{})
+	tools, _ := result["tools"].([]interface{})
+
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	if strings.Join(names, ",") != "alpha__echo,beta__echo" {
+		t.Fatalf("Expected prefixed tools from both servers, got %v", names)
+	}
+
+	callResponse, err := client.CallTool(ctx, "beta__echo", map[string]interface{}{"text": "hi"})
+	if err != nil {
+		t.Fatalf("tools/call failed: %v", err)
+	}
+	encoded, _ := json.Marshal(callResponse.Result)
+	if want := `echo:{\"text\":\"hi\"}`; !strings.Contains(string(encoded), want) {
+		t.Errorf("Expected backend tool name to be unprefixed, got %s", encoded)
+	}
+
+	// Both servers expose "echo", which /listtools/all reports as a collision
+	req := httptest.NewRequest("GET", "/listtools/all", nil)
+	recorder := httptest.NewRecorder()
+	embedded.Handler.ServeHTTP(recorder, req)
+	var listing struct {
+		Collisions map[string][]string `json:"collisions"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &listing); err != nil {
+		t.Fatalf("Invalid /listtools/all response: %v (%s)", err, recorder.Body.String())
+	}
+	if servers := listing.Collisions["echo"]; len(servers) != 2 {
+		t.Errorf("Expected echo collision between alpha and beta, got %+v", listing.Collisions)
+	}
+
+	unknownResponse, err := client.CallTool(ctx, "gamma__echo", nil)
+	if err != nil {
+		t.Fatalf("tools/call failed: %v", err)
+	}
+	if unknownResponse.Error == nil {
+		t.Error("Expected error for tool without a known server prefix")
+	}
+}
+
+func TestAggregateCacheHitsRunHooks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping aggregate fan-out test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		AggregateServer:  true,
+		ResponseCacheTTL: time.Minute,
+		MCPServers: map[string]config.MCPServer{
+			"alpha": helperMCPServerConfig(),
+			"beta":  helperMCPServerConfig(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+	counter := &countingHook{}
+	embedded.Server.AddHook("alpha", counter)
+
+	client := embedded.NewClient(AggregateServerName)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// The second tools/list is answered from the cache, and still goes through the hooks
+	if _, err := client.ListTools(ctx); err != nil {
+		t.Fatalf("tools/list failed: %v", err)
+	}
+	before := counter.responses
+	if _, err := client.ListTools(ctx); err != nil {
+		t.Fatalf("tools/list failed: %v", err)
+	}
+	if counter.responses != before+1 {
+		t.Errorf("Expected the cached response to go through the hooks, they saw %d responses then %d", before, counter.responses)
+	}
+}
+
+func TestAggregateToolCallCancellation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping aggregate cancellation test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		AggregateServer: true,
+		MCPServers:      map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	client := embedded.NewClient(AggregateServerName)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if _, err := client.Initialize(ctx); err != nil { // Request 1
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// Aggregate calls are in flight like any other, so the client can cancel them by ID
+	go func() {
+		for ctx.Err() == nil && !embedded.Server.inFlight.Cancel(client.SessionID(), "2") {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	response, err := client.CallTool(ctx, "helper__slow", nil) // Request 2
+	if err != nil || response.Error == nil || response.Error.Code != protocol.RequestCancelled {
+		t.Fatalf("Expected a request cancelled error, got %+v (%v)", response, err)
+	}
+}
@@ -0,0 +1,495 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/state"
+)
+
+// apiKeysFile holds the keys created through the admin API inside STATE_DIR
+const apiKeysFile = "api-keys.json"
+
+// API key sources
+const (
+	apiKeySourceConfig = "config" // Defined in the configuration file
+	apiKeySourceAdmin  = "admin"  // Created through POST /admin/api-keys
+	apiKeySourceClient = "client" // Issued to an in-process client, neither listed nor saved
+)
+
+// rateLimitError is returned for API keys over their requests per minute
+type rateLimitError struct {
+	name       string
+	retryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("API key %s is over its rate limit", e.name)
+}
+
+// apiKey is an API key known to the proxy
+// Only the key's hash is kept, in memory and in the state file.
+type apiKey struct {
+	Name      string     `json:"name"`
+	Server    string     `json:"server,omitempty"` // Only valid for this server ("" for every server)
+	Hash      string     `json:"hash"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RateLimit int        `json:"rateLimit,omitempty"` // Requests per minute (unlimited when 0)
+	CreatedAt time.Time  `json:"createdAt"`
+
+	source  string
+	revoked bool // Config keys revoked through the admin API stay known so they can be listed
+
+	windowStart time.Time // Start of the current one-minute rate limit window
+	windowCount int       // Requests in that window
+}
+
+// APIKeyInfo describes an API key in admin responses, without the key itself
+type APIKeyInfo struct {
+	Name      string     `json:"name"`
+	Server    string     `json:"server,omitempty"`
+	Source    string     `json:"source"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RateLimit int        `json:"rateLimit,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	Expired   bool       `json:"expired"`
+	Revoked   bool       `json:"revoked"`
+}
+
+// apiKeyState is the content of the state file
+type apiKeyState struct {
+	Keys    []*apiKey `json:"keys"`              // Keys created through the admin API
+	Revoked []string  `json:"revoked,omitempty"` // Hashes of config keys revoked through the admin API
+}
+
+// APIKeyStore holds the static API keys accepted in place of OAuth access tokens
+//
+// Keys come from the configuration file (global and per server) and from the
+// admin API. Keys created or revoked through the API are saved to
+// {STATE_DIR}/api-keys.json when a state directory is configured, so they
+// survive restarts; otherwise they last until the proxy stops. The file is
+// encrypted with the state encryption key when one is set.
+type APIKeyStore struct {
+	path   string // State file ("" for memory only)
+	cipher *state.Cipher
+	keys   map[string]*apiKey // Key hash -> key
+	mu     sync.Mutex
+}
+
+// NewAPIKeyStore loads the keys of the configuration and the state directory
+func NewAPIKeyStore(cfg *config.Config) *APIKeyStore {
+	store := &APIKeyStore{keys: make(map[string]*apiKey)}
+	if cfg == nil {
+		return store
+	}
+
+	addConfigKeys := func(server string, keys []config.APIKey) {
+		for _, key := range keys {
+			logger.RedactValues(key.Key)
+			store.keys[hashToken(key.Key)] = &apiKey{
+				Name:      key.Name,
+				Server:    server,
+				Hash:      hashToken(key.Key),
+				ExpiresAt: key.ExpiresAt,
+				RateLimit: key.RateLimit,
+				source:    apiKeySourceConfig,
+			}
+		}
+	}
+	addConfigKeys("", cfg.APIKeys)
+	for name, server := range cfg.MCPServers {
+		addConfigKeys(name, server.APIKeys)
+	}
+
+	if cfg.StateDir != "" {
+		keys, err := cfg.StateEncryptionKeys()
+		if err == nil {
+			store.cipher, err = state.NewCipher(keys...)
+		}
+		if err != nil {
+			logger.System().Error("API keys created through the admin API are kept in memory only: %v", err)
+			return store
+		}
+		store.path = filepath.Join(cfg.StateDir, apiKeysFile)
+		if err := store.load(); err != nil {
+			logger.System().Error("Failed to load API keys: %v", err)
+		}
+	}
+	return store
+}
+
+// load reads the keys created and revoked through the admin API
+// A file in plaintext or sealed with a previous key is rewritten with the current one.
+func (k *APIKeyStore) load() error {
+	data, err := os.ReadFile(k.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, current, err := k.cipher.Open(data)
+	if err != nil {
+		path := k.path
+		k.path = "" // Not overwritten with the keys of this run
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var saved apiKeyState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("invalid %s: %w", k.path, err)
+	}
+
+	for _, hash := range saved.Revoked {
+		if key, exists := k.keys[hash]; exists {
+			key.revoked = true
+		}
+	}
+	for _, key := range saved.Keys {
+		if k.findLocked(key.Name) != nil {
+			logger.System().Warn("Ignoring saved API key %s: the configuration defines a key with the same name", key.Name)
+			continue
+		}
+		key.source = apiKeySourceAdmin
+		k.keys[key.Hash] = key
+	}
+
+	if !current {
+		if err := k.saveLocked(); err != nil {
+			logger.System().Warn("Failed to re-encrypt API keys: %v", err)
+		}
+	}
+	return nil
+}
+
+// saveLocked writes the keys created and revoked through the admin API
+func (k *APIKeyStore) saveLocked() error {
+	if k.path == "" {
+		return nil
+	}
+	saved := apiKeyState{Keys: []*apiKey{}}
+	for hash, key := range k.keys {
+		switch {
+		case key.source == apiKeySourceAdmin:
+			saved.Keys = append(saved.Keys, key)
+		case key.revoked:
+			saved.Revoked = append(saved.Revoked, hash)
+		}
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err == nil {
+		data, err = k.cipher.Seal(data)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(k.path), 0755); err != nil {
+		return err
+	}
+	temp := k.path + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, k.path)
+}
+
+// findLocked returns the key with the given name
+func (k *APIKeyStore) findLocked(name string) *apiKey {
+	for _, key := range k.keys {
+		if key.Name == name && key.source != apiKeySourceClient {
+			return key
+		}
+	}
+	return nil
+}
+
+// Authenticate checks a bearer token against the API keys for a request to serverName
+// found is false when the token is no API key at all; otherwise err tells why
+// the key is refused, a *rateLimitError when it is over its rate limit.
+func (k *APIKeyStore) Authenticate(token, serverName string) (name string, found bool, err error) {
+	now := time.Now()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, exists := k.keys[hashToken(token)]
+	if !exists {
+		return "", false, nil
+	}
+	switch {
+	case key.revoked:
+		return key.Name, true, fmt.Errorf("API key %s was revoked", key.Name)
+	case key.ExpiresAt != nil && now.After(*key.ExpiresAt):
+		return key.Name, true, fmt.Errorf("API key %s expired at %s", key.Name, key.ExpiresAt.Format(time.RFC3339))
+	case key.Server != "" && key.Server != serverName:
+		return key.Name, true, fmt.Errorf("API key %s is only valid for server %s", key.Name, key.Server)
+	}
+
+	if key.RateLimit > 0 {
+		if now.Sub(key.windowStart) >= time.Minute {
+			key.windowStart, key.windowCount = now, 0
+		}
+		if key.windowCount >= key.RateLimit {
+			return key.Name, true, &rateLimitError{name: key.Name, retryAfter: key.windowStart.Add(time.Minute).Sub(now)}
+		}
+		key.windowCount++
+	}
+	return key.Name, true, nil
+}
+
+// KeyName returns the name of the API key token, without checking or counting it
+func (k *APIKeyStore) KeyName(token string) (string, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, exists := k.keys[hashToken(token)]
+	if !exists {
+		return "", false
+	}
+	return key.Name, true
+}
+
+// Create adds a key and returns it; this is the only time the key is visible
+func (k *APIKeyStore) Create(name, server string, expiresAt *time.Time, rateLimit int) (string, error) {
+	token := "mcp_" + generateRandomString(48)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.findLocked(name) != nil {
+		return "", fmt.Errorf("an API key named %s already exists", name)
+	}
+	key := &apiKey{
+		Name:      name,
+		Server:    server,
+		Hash:      hashToken(token),
+		ExpiresAt: expiresAt,
+		RateLimit: rateLimit,
+		CreatedAt: time.Now().UTC(),
+		source:    apiKeySourceAdmin,
+	}
+	k.keys[key.Hash] = key
+	if err := k.saveLocked(); err != nil {
+		delete(k.keys, key.Hash)
+		return "", fmt.Errorf("failed to save API keys: %w", err)
+	}
+	return token, nil
+}
+
+// IssueClientKey creates an unlisted key for an in-process client of serverName
+func (k *APIKeyStore) IssueClientKey(serverName string) string {
+	token := "mcp_" + generateRandomString(48)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[hashToken(token)] = &apiKey{Name: "in-process client", Server: serverName, Hash: hashToken(token), source: apiKeySourceClient}
+	return token
+}
+
+// ForgetClientKey drops a key issued by IssueClientKey; other keys are left alone
+func (k *APIKeyStore) ForgetClientKey(token string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, exists := k.keys[hashToken(token)]; exists && key.source == apiKeySourceClient {
+		delete(k.keys, key.Hash)
+	}
+}
+
+// Revoke disables the key with the given name, reporting whether it exists
+// Keys created through the admin API are forgotten; config keys are remembered
+// as revoked until they are removed from the configuration.
+func (k *APIKeyStore) Revoke(name string) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := k.findLocked(name)
+	if key == nil {
+		return false, nil
+	}
+	if key.source == apiKeySourceAdmin {
+		delete(k.keys, key.Hash)
+	} else {
+		key.revoked = true
+	}
+	if err := k.saveLocked(); err != nil {
+		return true, fmt.Errorf("failed to save API keys: %w", err)
+	}
+	return true, nil
+}
+
+// List describes every key, sorted by name
+func (k *APIKeyStore) List() []APIKeyInfo {
+	now := time.Now()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	infos := make([]APIKeyInfo, 0, len(k.keys))
+	for _, key := range k.keys {
+		if key.source == apiKeySourceClient {
+			continue
+		}
+		info := APIKeyInfo{
+			Name:      key.Name,
+			Server:    key.Server,
+			Source:    key.source,
+			ExpiresAt: key.ExpiresAt,
+			RateLimit: key.RateLimit,
+			Expired:   key.ExpiresAt != nil && now.After(*key.ExpiresAt),
+			Revoked:   key.revoked,
+		}
+		if !key.CreatedAt.IsZero() {
+			createdAt := key.CreatedAt
+			info.CreatedAt = &createdAt
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// authMode returns which bearer tokens MCP endpoints accept
+func (s *Server) authMode() string {
+	if s.config == nil || s.config.AuthMode == "" {
+		return config.AuthModeOAuth
+	}
+	return s.config.AuthMode
+}
+
+// requestServer returns the MCP server a request addresses, for checking server-scoped API keys
+func (s *Server) requestServer(r *http.Request) string {
+	if serverName, ok := r.Context().Value("mcpServer").(string); ok {
+		return serverName
+	}
+	vars := mux.Vars(r)
+	if serverName := vars["server"]; serverName != "" {
+		return serverName
+	}
+	if conn, exists := s.connectionManager.GetConnections()[vars["sessionId"]]; exists {
+		return conn.ServerName
+	}
+	return ""
+}
+
+// writeRateLimited answers a request refused because its API key is over its rate limit
+// It reports whether err was such a refusal.
+func writeRateLimited(w http.ResponseWriter, err error) bool {
+	var limited *rateLimitError
+	if !errors.As(err, &limited) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.retryAfter.Seconds()))))
+	writeAuthError(w, http.StatusTooManyRequests, ErrorRateLimited, limited.Error())
+	return true
+}
+
+// handleAPIKeys lists the API keys (GET) or creates one (POST)
+//
+// POST takes {"name", "server", "expiresIn" or "expiresAt", "rateLimit"} and
+// returns the new key, which cannot be retrieved again. Creating keys requires
+// ADMIN_TOKEN: an open endpoint would let anyone mint a key for themselves.
+func (s *Server) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet && (s.config == nil || s.config.AdminToken == "") {
+		writeError(w, http.StatusForbidden, ErrorFeatureDisabled, "Creating API keys requires ADMIN_TOKEN to be set")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == http.MethodGet {
+		keys := s.apiKeys.List()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys":     keys,
+			"count":    len(keys),
+			"authMode": s.authMode(),
+		})
+		return
+	}
+
+	var request struct {
+		Name      string     `json:"name"`
+		Server    string     `json:"server"`
+		ExpiresIn string     `json:"expiresIn"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+		RateLimit int        `json:"rateLimit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Invalid JSON body")
+		return
+	}
+	knownServer := false
+	if s.config != nil {
+		_, knownServer = s.config.MCPServers[request.Server]
+	}
+	switch {
+	case request.Name == "":
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "name is required")
+		return
+	case request.RateLimit < 0:
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "rateLimit cannot be negative")
+		return
+	case request.Server != "" && !knownServer:
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, fmt.Sprintf("unknown server %s", request.Server))
+		return
+	}
+	if request.ExpiresIn != "" {
+		ttl, err := time.ParseDuration(request.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, fmt.Sprintf("invalid expiresIn %q", request.ExpiresIn))
+			return
+		}
+		expiresAt := time.Now().Add(ttl).UTC()
+		request.ExpiresAt = &expiresAt
+	}
+
+	token, err := s.apiKeys.Create(request.Name, request.Server, request.ExpiresAt, request.RateLimit)
+	if err != nil {
+		logger.System().Warn("API key creation failed: %v", err)
+		writeError(w, http.StatusConflict, ErrorConflict, err.Error())
+		return
+	}
+	logger.System().Info("API key %s created by %s", request.Name, adminActor(r))
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":      request.Name,
+		"key":       token,
+		"server":    request.Server,
+		"expiresAt": request.ExpiresAt,
+		"rateLimit": request.RateLimit,
+	})
+}
+
+// handleRevokeAPIKey revokes an API key: DELETE /admin/api-keys/{name}
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if s.config == nil || s.config.AdminToken == "" {
+		writeError(w, http.StatusForbidden, ErrorFeatureDisabled, "Revoking API keys requires ADMIN_TOKEN to be set")
+		return
+	}
+	name := mux.Vars(r)["name"]
+
+	exists, err := s.apiKeys.Revoke(name)
+	if !exists {
+		writeError(w, http.StatusNotFound, ErrorNotFound, fmt.Sprintf("API key '%s' not found", name))
+		return
+	}
+	if err != nil {
+		logger.System().Error("API key %s revoked but not saved: %v", name, err)
+	}
+	logger.System().Info("API key %s revoked by %s", name, adminActor(r))
+	w.WriteHeader(http.StatusNoContent)
+}
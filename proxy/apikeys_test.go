@@ -0,0 +1,255 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+// keyRequest returns a request to serverName authenticated with token
+func keyRequest(serverName, token string) *http.Request {
+	req := httptest.NewRequest("POST", "/mcp", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req.WithContext(context.WithValue(req.Context(), "mcpServer", serverName))
+}
+
+// apiKeyConfig returns a configuration with a global, a server-scoped, an expired and a rate-limited key
+func apiKeyConfig(mode string) *config.Config {
+	expired := time.Now().Add(-time.Hour)
+	return &config.Config{
+		AuthMode: mode,
+		APIKeys: []config.APIKey{
+			{Name: "ci", Key: "global-key"},
+			{Name: "old", Key: "expired-key", ExpiresAt: &expired},
+		},
+		MCPServers: map[string]config.MCPServer{
+			"memory": {Command: "echo", APIKeys: []config.APIKey{{Name: "agent", Key: "memory-key", RateLimit: 2}}},
+			"files":  {Command: "echo"},
+		},
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	server, _ := newOAuthTestServer(t, apiKeyConfig(config.AuthModeAPIKey))
+
+	tests := []struct {
+		name   string
+		server string
+		token  string
+		valid  bool
+	}{
+		{"global key", "files", "global-key", true},
+		{"server key", "memory", "memory-key", true},
+		{"server key on another server", "files", "memory-key", false},
+		{"expired key", "memory", "expired-key", false},
+		{"unknown token", "memory", "some-oauth-token", false},
+		{"proxy OAuth token", "memory", server.oauth.IssueToken(time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := server.validateAuthentication(keyRequest(tt.server, tt.token)); got != tt.valid {
+				t.Errorf("validateAuthentication = %v, want %v", got, tt.valid)
+			}
+		})
+	}
+
+	// "agent" allows two requests a minute, and one was used above
+	if err := server.authenticate(keyRequest("memory", "memory-key")); err != nil {
+		t.Fatalf("Expected the second request to pass, got %v", err)
+	}
+	err := server.authenticate(keyRequest("memory", "memory-key"))
+	rr := httptest.NewRecorder()
+	if !writeRateLimited(rr, err) || rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected a 429 with Retry-After for the third request, got %v (%d)", err, rr.Code)
+	}
+	if writeRateLimited(httptest.NewRecorder(), errUnauthorized) {
+		t.Error("Expected other errors not to be reported as rate limiting")
+	}
+}
+
+func TestAPIKeysAlongsideOAuth(t *testing.T) {
+	cfg := apiKeyConfig(config.AuthModeOAuth)
+	cfg.OAuthConsent = config.ConsentPassword
+	server, _ := newOAuthTestServer(t, cfg)
+
+	for token, want := range map[string]bool{
+		"global-key":                       true,
+		"expired-key":                      false,
+		server.oauth.IssueToken(time.Hour): true,
+		"some-other-token-1234567890":      false,
+	} {
+		if got := server.validateAuthentication(keyRequest("memory", token)); got != want {
+			t.Errorf("validateAuthentication(%q) = %v, want %v", token, got, want)
+		}
+	}
+}
+
+func TestInProcessClientCredentials(t *testing.T) {
+	for _, mode := range []string{config.AuthModeOAuth, config.AuthModeAPIKey} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := apiKeyConfig(mode)
+			server := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil)
+			client := server.newClient(server.Router(), "memory")
+
+			// Only the credential the mode uses is minted
+			wantOAuthTokens := 1
+			if mode == config.AuthModeAPIKey {
+				wantOAuthTokens = 0
+			}
+			_, isKey := server.apiKeys.KeyName(client.token)
+			if len(server.oauth.tokens) != wantOAuthTokens || isKey != (mode == config.AuthModeAPIKey) {
+				t.Fatalf("Expected %d OAuth tokens and an API key only in api-key mode, got %d tokens, API key %v", wantOAuthTokens, len(server.oauth.tokens), isKey)
+			}
+
+			client.Close()
+			if _, isKey := server.apiKeys.KeyName(client.token); isKey || server.oauth.ValidToken(client.token) {
+				t.Error("Expected the credential to be revoked when the client is closed")
+			}
+		})
+	}
+}
+
+func TestAPIKeyAdminEndpoints(t *testing.T) {
+	cfg := apiKeyConfig(config.AuthModeAPIKey)
+	cfg.AdminToken = "admin"
+	cfg.StateDir = t.TempDir()
+	server, handler := newOAuthTestServer(t, cfg)
+
+	admin := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := admin("POST", "/admin/api-keys", `{"name":"robot","server":"memory","expiresIn":"1h","rateLimit":10}`)
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil || rr.Code != http.StatusCreated || created.Key == "" {
+		t.Fatalf("Expected a new key, got %d (%v)", rr.Code, err)
+	}
+	if !server.validateAuthentication(keyRequest("memory", created.Key)) {
+		t.Error("Expected the new key to be accepted")
+	}
+	for body, status := range map[string]int{
+		`{"name":"robot"}`:                    http.StatusConflict,
+		`{"name":"ci"}`:                       http.StatusConflict,
+		`{"name":"x","server":"missing"}`:     http.StatusBadRequest,
+		`{"name":"x","expiresIn":"tomorrow"}`: http.StatusBadRequest,
+		`{"server":"memory"}`:                 http.StatusBadRequest,
+	} {
+		if rr := admin("POST", "/admin/api-keys", body); rr.Code != status {
+			t.Errorf("POST %s: expected %d, got %d", body, status, rr.Code)
+		}
+	}
+
+	// Keys survive a restart, and the listing never shows them
+	restarted := NewAPIKeyStore(cfg)
+	if _, found, err := restarted.Authenticate(created.Key, "memory"); !found || err != nil {
+		t.Errorf("Expected the saved key to be accepted after a restart, got %v %v", found, err)
+	}
+	rr = admin("GET", "/admin/api-keys", "")
+	if strings.Contains(rr.Body.String(), created.Key) || strings.Contains(rr.Body.String(), "global-key") {
+		t.Error("Expected the listing not to contain keys")
+	}
+	var listing struct {
+		Keys []APIKeyInfo `json:"keys"`
+	}
+	json.NewDecoder(rr.Body).Decode(&listing)
+	if len(listing.Keys) != 4 {
+		t.Errorf("Expected 4 keys, got %+v", listing.Keys)
+	}
+
+	// Revoking works for created and configured keys
+	for _, name := range []string{"robot", "ci"} {
+		if rr := admin("DELETE", "/admin/api-keys/"+name, ""); rr.Code != http.StatusNoContent {
+			t.Errorf("Expected %s to be revoked, got %d", name, rr.Code)
+		}
+	}
+	if rr := admin("DELETE", "/admin/api-keys/missing", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown key, got %d", rr.Code)
+	}
+	restarted = NewAPIKeyStore(cfg)
+	for _, token := range []string{created.Key, "global-key"} {
+		if server.validateAuthentication(keyRequest("memory", token)) {
+			t.Errorf("Expected revoked key %s to be refused", token)
+		}
+		if _, found, err := restarted.Authenticate(token, "memory"); found && err == nil {
+			t.Errorf("Expected revoked key %s to stay refused after a restart", token)
+		}
+	}
+}
+
+func TestAPIKeyAdminEndpointsWithoutAdminToken(t *testing.T) {
+	server, handler := newOAuthTestServer(t, apiKeyConfig(config.AuthModeAPIKey))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("POST", "/admin/api-keys", strings.NewReader(`{"name":"robot"}`)),
+		httptest.NewRequest("DELETE", "/admin/api-keys/ci", nil),
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), ErrorFeatureDisabled) {
+			t.Errorf("%s %s: expected 403 %s without ADMIN_TOKEN, got %d", req.Method, req.URL.Path, ErrorFeatureDisabled, rr.Code)
+		}
+	}
+
+	// The handlers refuse on their own too, whatever guards the route
+	rr := httptest.NewRecorder()
+	server.handleAPIKeys(rr, httptest.NewRequest("POST", "/admin/api-keys", strings.NewReader(`{"name":"robot"}`)))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected key creation to be refused without ADMIN_TOKEN, got %d", rr.Code)
+	}
+	if keys := server.apiKeys.List(); len(keys) != 3 {
+		t.Errorf("Expected no key to be created or revoked, got %+v", keys)
+	}
+	if !server.validateAuthentication(keyRequest("files", "global-key")) {
+		t.Error("Expected the configured key to stay valid")
+	}
+}
+
+func TestAPIKeysEncryptedAtRest(t *testing.T) {
+	cfg := apiKeyConfig(config.AuthModeAPIKey)
+	cfg.StateDir = t.TempDir()
+	cfg.StateEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+	key, err := NewAPIKeyStore(cfg).Create("robot", "", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(cfg.StateDir, apiKeysFile))
+	if !strings.HasPrefix(string(data), "enc:v1:") || strings.Contains(string(data), "robot") {
+		t.Fatalf("Expected the state file to be encrypted, got %s", data)
+	}
+
+	// After a rotation the key is still known, and the file is sealed with the new key
+	cfg.StateEncryptionPreviousKeys = []string{cfg.StateEncryptionKey}
+	cfg.StateEncryptionKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+	if _, found, err := NewAPIKeyStore(cfg).Authenticate(key, "memory"); !found || err != nil {
+		t.Errorf("Expected the saved key to be accepted after a rotation, got %v %v", found, err)
+	}
+	cfg.StateEncryptionPreviousKeys = nil
+	if _, found, _ := NewAPIKeyStore(cfg).Authenticate(key, "memory"); !found {
+		t.Error("Expected the state file to be re-encrypted with the new key")
+	}
+
+	// Without the key the file is neither read nor overwritten
+	cfg.StateEncryptionKey = ""
+	if _, found, _ := NewAPIKeyStore(cfg).Authenticate(key, "memory"); found {
+		t.Error("Expected the encrypted state file to be unreadable without its key")
+	}
+	if after, _ := os.ReadFile(filepath.Join(cfg.StateDir, apiKeysFile)); !strings.HasPrefix(string(after), "enc:v1:") {
+		t.Error("Expected the encrypted state file to be left untouched")
+	}
+}
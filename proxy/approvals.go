@@ -0,0 +1,292 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/state"
+	"remote-mcp-proxy/webhook"
+)
+
+// approvalProgressInterval is how often a client waiting for an approval is sent progress
+var approvalProgressInterval = 10 * time.Second
+
+// ToolApproval is a tool call held until an approver allows or denies it
+type ToolApproval struct {
+	ID          string      `json:"id"`
+	Server      string      `json:"server"`
+	Tool        string      `json:"tool"`
+	Arguments   interface{} `json:"arguments,omitempty"`
+	Principal   string      `json:"principal,omitempty"`
+	Session     string      `json:"session,omitempty"`
+	Rule        int         `json:"rule"`             // Policy rule requiring the approval
+	Reason      string      `json:"reason,omitempty"` // Reason of that rule
+	RequestedAt time.Time   `json:"requestedAt"`
+	ExpiresAt   time.Time   `json:"expiresAt"`
+
+	decided chan approvalDecision
+}
+
+// approvalDecision is an approver's answer to a held tool call
+type approvalDecision struct {
+	approved bool
+	approver string
+	note     string
+}
+
+// ToolApprovals holds the tool calls waiting for an approver
+type ToolApprovals struct {
+	mu      sync.Mutex
+	pending map[string]*ToolApproval
+	timeout time.Duration
+}
+
+// NewToolApprovals creates an empty set of held calls, denied after timeout unless decided
+func NewToolApprovals(timeout time.Duration) *ToolApprovals {
+	if timeout <= 0 {
+		timeout = config.DefaultApprovalTimeout
+	}
+	return &ToolApprovals{pending: make(map[string]*ToolApproval), timeout: timeout}
+}
+
+// hold adds a tool call to the pending approvals, filling in its ID and times
+func (a *ToolApprovals) hold(approval *ToolApproval) {
+	approval.ID = generateRandomString(16)
+	approval.RequestedAt = time.Now()
+	approval.ExpiresAt = approval.RequestedAt.Add(a.timeout)
+	approval.decided = make(chan approvalDecision, 1)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending[approval.ID] = approval
+}
+
+// Pending returns the tool calls waiting for an approver, oldest first
+func (a *ToolApprovals) Pending() []ToolApproval {
+	a.mu.Lock()
+	pending := make([]ToolApproval, 0, len(a.pending))
+	for _, approval := range a.pending {
+		pending = append(pending, *approval)
+	}
+	a.mu.Unlock()
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].RequestedAt.Before(pending[j].RequestedAt)
+	})
+	return pending
+}
+
+// Decide allows or denies a held tool call, returning it, or false when it is no longer waiting
+func (a *ToolApprovals) Decide(id string, approved bool, approver, note string) (ToolApproval, bool) {
+	a.mu.Lock()
+	approval, exists := a.pending[id]
+	delete(a.pending, id)
+	a.mu.Unlock()
+	if !exists {
+		return ToolApproval{}, false
+	}
+
+	approval.decided <- approvalDecision{approved: approved, approver: approver, note: note}
+	return *approval, true
+}
+
+// Lookup returns a held tool call, or false when it is no longer waiting
+func (a *ToolApprovals) Lookup(id string) (ToolApproval, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	approval, exists := a.pending[id]
+	if !exists {
+		return ToolApproval{}, false
+	}
+	return *approval, true
+}
+
+// release stops holding a tool call nobody decided, reporting false when a decision just came in
+func (a *ToolApprovals) release(id string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, exists := a.pending[id]
+	delete(a.pending, id)
+	return exists
+}
+
+// wait blocks until the held tool call is decided, times out, or ctx is done
+// A call nobody decided in time is denied. The tick function is called every
+// approvalProgressInterval while waiting.
+func (a *ToolApprovals) wait(ctx context.Context, approval *ToolApproval, tick func()) (approvalDecision, error) {
+	timeout := time.NewTimer(time.Until(approval.ExpiresAt))
+	defer timeout.Stop()
+	ticker := time.NewTicker(approvalProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case decision := <-approval.decided:
+			return decision, nil
+		case <-ticker.C:
+			tick()
+		case <-timeout.C:
+			if !a.release(approval.ID) {
+				return <-approval.decided, nil
+			}
+			return approvalDecision{}, fmt.Errorf("no approver answered within %v", a.timeout)
+		case <-ctx.Done():
+			if !a.release(approval.ID) {
+				return <-approval.decided, nil
+			}
+			return approvalDecision{}, ctx.Err()
+		}
+	}
+}
+
+// awaitApproval holds a tool call until an approver decides it
+// Approvers are notified through the approval.request webhook, and the client
+// is sent progress while it waits when its request has a progress token. A call
+// that is not approved returns the error message answering it, which is empty
+// when the client stopped waiting.
+func (s *Server) awaitApproval(r *http.Request, call policyCall, decision PolicyDecision) (approved bool, refusal string) {
+	approval := &ToolApproval{
+		Server:    call.server,
+		Tool:      call.tool,
+		Arguments: call.arguments,
+		Principal: call.principal,
+		Session:   call.session,
+		Rule:      decision.Rule,
+		Reason:    decision.Reason,
+	}
+	s.approvals.hold(approval)
+	logger.System().Warn("Holding call to tool %s on server %s by %q for approval %s", call.tool, call.server, call.principal, approval.ID)
+	s.mcpManager.GetWebhooks().Notify(webhook.Event{
+		Type:    config.WebhookApprovalRequest,
+		Server:  call.server,
+		Session: call.session,
+		Actor:   call.principal,
+		Reason:  decision.Reason,
+		Details: map[string]interface{}{"approval": approval.ID, "tool": call.tool, "arguments": call.arguments, "expiresAt": approval.ExpiresAt},
+	})
+
+	progress := 0
+	notify := func() {
+		if call.progressToken == nil || call.session == "" {
+			return
+		}
+		progress++
+		notification, err := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "notifications/progress",
+			"params": map[string]interface{}{
+				"progressToken": call.progressToken,
+				"progress":      progress,
+				"message":       fmt.Sprintf("Waiting for an approver to allow tool '%s' (approval %s)", call.tool, approval.ID),
+			},
+		})
+		if err == nil {
+			s.sseEvents.Append(call.session, "message", string(notification), "")
+		}
+	}
+	notify()
+
+	answer, err := s.approvals.wait(r.Context(), approval, notify)
+	switch {
+	case err != nil && r.Context().Err() != nil:
+		logger.System().Info("Client stopped waiting for approval %s of tool %s", approval.ID, call.tool)
+		return false, ""
+	case err != nil:
+		logger.System().Warn("Approval %s of tool %s on server %s expired: %v", approval.ID, call.tool, call.server, err)
+		s.mcpManager.GetIncidentStore().RecordIncident(state.Incident{
+			Kind:    state.IncidentPolicy,
+			Server:  call.server,
+			Actor:   call.principal,
+			Action:  "approval-expired",
+			Reason:  err.Error(),
+			Success: true,
+			Details: map[string]interface{}{"tool": call.tool, "approval": approval.ID},
+		})
+		return false, fmt.Sprintf("Tool '%s' requires approval, and no approver answered in time", call.tool)
+	case !answer.approved:
+		refusal = fmt.Sprintf("Tool '%s' was denied by an approver", call.tool)
+		if answer.note != "" {
+			refusal += ": " + answer.note
+		}
+		return false, refusal
+	}
+	logger.System().Info("Approval %s of tool %s on server %s granted by %s", approval.ID, call.tool, call.server, answer.approver)
+	return true, ""
+}
+
+// handleApprovals lists the tool calls waiting for an approver: GET /admin/approvals
+func (s *Server) handleApprovals(w http.ResponseWriter, r *http.Request) {
+	pending := s.approvals.Pending()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"approvals": pending,
+		"count":     len(pending),
+	}); err != nil {
+		logger.System().Error("Failed to encode approvals response: %v", err)
+	}
+}
+
+// handleDecideApproval allows or denies a held tool call:
+// POST /admin/approvals/{id}/approve and POST /admin/approvals/{id}/deny
+// An optional JSON body {"note": "..."} is recorded, and returned to the client of a denied call.
+// Requires ADMIN_TOKEN, or the client whose call is held could approve it itself.
+func (s *Server) handleDecideApproval(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil || s.config.AdminToken == "" {
+		writeError(w, http.StatusForbidden, ErrorFeatureDisabled, "Deciding approvals requires ADMIN_TOKEN to be set")
+		return
+	}
+	vars := mux.Vars(r)
+	approved := vars["decision"] == "approve"
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+	}
+
+	actor := adminActor(r)
+	approval, decided := s.approvals.Decide(vars["id"], approved, actor, body.Note)
+	if !decided {
+		writeError(w, http.StatusNotFound, ErrorNotFound, fmt.Sprintf("Approval '%s' not pending", vars["id"]))
+		return
+	}
+
+	action := "approve"
+	if !approved {
+		action = "deny"
+	}
+	logger.System().Info("Approval %s of tool %s on server %s: %s by %s", approval.ID, approval.Tool, approval.Server, action, actor)
+	s.mcpManager.GetIncidentStore().RecordIncident(state.Incident{
+		Kind:    state.IncidentPolicy,
+		Server:  approval.Server,
+		Actor:   actor,
+		Action:  action,
+		Reason:  body.Note,
+		Success: true,
+		Details: map[string]interface{}{"tool": approval.Tool, "approval": approval.ID, "principal": approval.Principal},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"approval": approval,
+		"approved": approved,
+	}); err != nil {
+		logger.System().Error("Failed to encode approval response: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/state"
+)
+
+// awaitPendingApproval waits for a tool call to be held and returns it
+func awaitPendingApproval(t *testing.T, approvals *ToolApprovals) ToolApproval {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if pending := approvals.Pending(); len(pending) > 0 {
+			return pending[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Expected a tool call to be held for approval")
+	return ToolApproval{}
+}
+
+func TestToolApprovalsInProcess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		AdminToken: testAdminToken,
+		MCPServers: map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+		Policy: &config.Policy{Rules: []config.PolicyRule{
+			{Effect: config.PolicyRequireApproval, Tools: []string{"echo"}, Arguments: map[string]string{"text": "^deploy"}, Reason: "Deploys need a second pair of eyes"},
+		}},
+		ApprovalTimeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+	incidents, _ := state.NewStore("", 100, time.Hour)
+	embedded.Server.mcpManager.EnableIncidentHistory(incidents)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// call makes a held call in the background and returns its outcome
+	call := func(text string) chan string {
+		outcome := make(chan string, 1)
+		go func() {
+			response, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": text})
+			switch {
+			case err != nil:
+				outcome <- err.Error()
+			case response.Error != nil:
+				outcome <- response.Error.Message
+			default:
+				outcome <- fmt.Sprint(response.Result)
+			}
+		}()
+		return outcome
+	}
+
+	// An approved call is forwarded
+	outcome := call("deploy production")
+	held := awaitPendingApproval(t, embedded.Server.approvals)
+	recorder := adminRequest(embedded.Handler, "GET", "/admin/approvals")
+	var listed struct {
+		Approvals []ToolApproval `json:"approvals"`
+	}
+	json.NewDecoder(recorder.Body).Decode(&listed)
+	if len(listed.Approvals) != 1 || listed.Approvals[0].Tool != "echo" || listed.Approvals[0].Reason != "Deploys need a second pair of eyes" {
+		t.Errorf("Expected the held call to be listed, got %s", recorder.Body.String())
+	}
+	if recorder := adminRequest(embedded.Handler, "POST", "/admin/approvals/"+held.ID+"/approve"); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the approval to succeed, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if result := <-outcome; !strings.Contains(result, `echo:{"text":"deploy production"}`) {
+		t.Errorf("Expected the approved call to be forwarded, got %s", result)
+	}
+
+	// A denied call gets the approver's note
+	outcome = call("deploy staging")
+	held = awaitPendingApproval(t, embedded.Server.approvals)
+	req := httptest.NewRequest("POST", "/admin/approvals/"+held.ID+"/deny", strings.NewReader(`{"note":"freeze until Monday"}`))
+	req.Host = "localhost"
+	req.Header.Set("X-Admin-Token", testAdminToken)
+	embedded.Handler.ServeHTTP(httptest.NewRecorder(), req)
+	if result := <-outcome; !strings.Contains(result, "denied by an approver: freeze until Monday") {
+		t.Errorf("Expected the denied call to be refused, got %s", result)
+	}
+
+	// A call nobody decides expires, and can no longer be decided
+	outcome = call("deploy canary")
+	held = awaitPendingApproval(t, embedded.Server.approvals)
+	if result := <-outcome; !strings.Contains(result, "no approver answered in time") {
+		t.Errorf("Expected the call to expire, got %s", result)
+	}
+	if recorder := adminRequest(embedded.Handler, "POST", "/admin/approvals/"+held.ID+"/approve"); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected an expired approval to be gone, got %d", recorder.Code)
+	}
+
+	// Calls the policy doesn't hold are not delayed
+	response, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "status"})
+	if err != nil || response.Error != nil {
+		t.Errorf("Expected the call to pass, got %+v (%v)", response, err)
+	}
+
+	if recorded := incidents.Incidents(state.IncidentFilter{Kind: state.IncidentPolicy}); len(recorded) != 3 {
+		t.Errorf("Expected the approval, denial and expiry in the incident history, got %+v", recorded)
+	}
+}
+
+func TestApprovalDecisionRefusals(t *testing.T) {
+	cfg := apiKeyConfig(config.AuthModeAPIKey)
+	cfg.AdminToken = testAdminToken
+	server, handler := newOAuthTestServer(t, cfg)
+	held := &ToolApproval{Server: "files", Tool: "deploy", Principal: "api-key:ci"}
+	server.approvals.hold(held)
+
+	decide := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/approvals/"+held.ID+"/approve", nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Only ADMIN_TOKEN decides, not the client whose call is held
+	if recorder := decide(map[string]string{"Authorization": "Bearer global-key"}); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the client's own key to be refused, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if _, pending := server.approvals.Lookup(held.ID); !pending {
+		t.Fatal("Expected the call to stay held after a refused decision")
+	}
+
+	if recorder := decide(map[string]string{"X-Admin-Token": testAdminToken, "X-Admin-Actor": "alice"}); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the admin to decide the call, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Without ADMIN_TOKEN nobody can decide, not even through the handler itself
+	open, _ := newOAuthTestServer(t, apiKeyConfig(config.AuthModeAPIKey))
+	held = &ToolApproval{Server: "files", Tool: "deploy", Principal: "api-key:ci"}
+	open.approvals.hold(held)
+	recorder := httptest.NewRecorder()
+	open.handleDecideApproval(recorder, httptest.NewRequest("POST", "/admin/approvals/"+held.ID+"/approve", nil))
+	if recorder.Code != http.StatusForbidden || !strings.Contains(recorder.Body.String(), ErrorFeatureDisabled) {
+		t.Errorf("Expected decisions to require ADMIN_TOKEN, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/state"
+)
+
+// Batch operations accepted by POST /admin/servers:batch
+const (
+	BatchOpRestart        = "restart"
+	BatchOpStop           = "stop"
+	BatchOpSetMaintenance = "set-maintenance"
+)
+
+// maxBatchOperations bounds the size of a single batch request
+const maxBatchOperations = 100
+
+// BatchOperation is one operation of a server batch request
+type BatchOperation struct {
+	Op      string `json:"op"`
+	Server  string `json:"server"`
+	Enabled *bool  `json:"enabled,omitempty"` // Required for set-maintenance
+}
+
+// BatchResult is the outcome of one batch operation
+type BatchResult struct {
+	Op         string `json:"op"`
+	Server     string `json:"server"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// handleServerBatch runs restart/stop/set-maintenance operations concurrently
+//
+// Results are returned in request order. The response is 200 even when some
+// operations fail; callers inspect the per-item results and the failed count.
+func (s *Server) handleServerBatch(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Operations []BatchOperation `json:"operations"`
+		Reason     string           `json:"reason,omitempty"` // Recorded in the incident history
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeBatchError(w, fmt.Sprintf("Invalid batch request: %v", err))
+		return
+	}
+	if len(request.Operations) == 0 {
+		writeBatchError(w, "Batch request has no operations")
+		return
+	}
+	if len(request.Operations) > maxBatchOperations {
+		writeBatchError(w, fmt.Sprintf("Batch request has %d operations, maximum is %d", len(request.Operations), maxBatchOperations))
+		return
+	}
+
+	actor := adminActor(r)
+	logger.System().Info("Running batch of %d server operations for %s", len(request.Operations), actor)
+
+	results := make([]BatchResult, len(request.Operations))
+	var wg sync.WaitGroup
+	for i, op := range request.Operations {
+		wg.Add(1)
+		go func(i int, op BatchOperation) {
+			defer wg.Done()
+			start := time.Now()
+			err := s.runBatchOperation(op)
+
+			results[i] = BatchResult{Op: op.Op, Server: op.Server, Success: err == nil, DurationMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				results[i].Error = err.Error()
+				logger.System().Warn(" Batch operation %s on %s failed: %v", op.Op, op.Server, err)
+			}
+			s.recordBatchIncident(op, actor, request.Reason, err)
+		}(i, op)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"results":   results,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+	}); err != nil {
+		logger.System().Error("Failed to encode batch response: %v", err)
+	}
+}
+
+// recordBatchIncident adds a batch operation to the incident history
+func (s *Server) recordBatchIncident(op BatchOperation, actor, reason string, err error) {
+	incident := state.Incident{
+		Kind:    state.IncidentAdmin,
+		Server:  op.Server,
+		Actor:   actor,
+		Action:  op.Op,
+		Reason:  reason,
+		Success: err == nil,
+		Details: map[string]interface{}{},
+	}
+	if op.Op == BatchOpRestart {
+		incident.Kind = state.IncidentRestart
+	}
+	if op.Enabled != nil {
+		incident.Details["enabled"] = *op.Enabled
+	}
+	if err != nil {
+		incident.Details["error"] = err.Error()
+	}
+	s.mcpManager.GetIncidentStore().RecordIncident(incident)
+}
+
+// runBatchOperation executes a single batch operation against the MCP manager
+func (s *Server) runBatchOperation(op BatchOperation) error {
+	if op.Server == "" {
+		return fmt.Errorf("server is required")
+	}
+
+	switch op.Op {
+	case BatchOpRestart:
+		s.responseCache.Invalidate(op.Server, "")
+		return s.mcpManager.RestartServer(op.Server)
+	case BatchOpStop:
+		s.responseCache.Invalidate(op.Server, "")
+		return s.mcpManager.StopServer(op.Server)
+	case BatchOpSetMaintenance:
+		if op.Enabled == nil {
+			return fmt.Errorf("enabled is required for %s", BatchOpSetMaintenance)
+		}
+		return s.mcpManager.SetMaintenance(op.Server, *op.Enabled)
+	default:
+		return fmt.Errorf("unknown operation '%s' (expected %s, %s or %s)", op.Op, BatchOpRestart, BatchOpStop, BatchOpSetMaintenance)
+	}
+}
+
+// writeBatchError rejects a malformed batch request
+func writeBatchError(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusBadRequest, ErrorInvalidRequest, message)
+}
+
+// writeMaintenanceResponse tells a client that a server is in maintenance mode
+func writeMaintenanceResponse(w http.ResponseWriter, serverName string) {
+	logger.System().Info("Refusing request for MCP server %s in maintenance mode", serverName)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", "60")
+	writeServerError(w, http.StatusServiceUnavailable, ErrorServerMaintenance, serverName,
+		fmt.Sprintf("MCP server '%s' is in maintenance mode", serverName))
+}
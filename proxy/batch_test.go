@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/state"
+)
+
+func TestServerBatch(t *testing.T) {
+	servers := map[string]config.MCPServer{
+		"memory": {Command: "echo"},
+		"notion": {Command: "echo"},
+	}
+	mcpManager := mcp.NewManager(servers)
+	incidents, _ := state.NewStore("", 100, time.Hour)
+	mcpManager.EnableIncidentHistory(incidents)
+	router := NewServerWithConfig(mcpManager, &config.Config{AdminToken: testAdminToken, MCPServers: servers}, nil, nil).Router()
+
+	body := `{"reason":"nightly maintenance","operations":[
+		{"op":"set-maintenance","server":"memory","enabled":true},
+		{"op":"stop","server":"notion"},
+		{"op":"restart","server":"missing"},
+		{"op":"set-maintenance","server":"notion"},
+		{"op":"reboot","server":"memory"}
+	]}`
+	req := httptest.NewRequest("POST", "/admin/servers:batch", strings.NewReader(body))
+	req.Header.Set("X-Admin-Token", testAdminToken)
+	req.Header.Set("X-Admin-Actor", "cron")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Results   []BatchResult `json:"results"`
+		Succeeded int           `json:"succeeded"`
+		Failed    int           `json:"failed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse batch response: %v", err)
+	}
+	if response.Succeeded != 2 || response.Failed != 3 {
+		t.Errorf("Expected 2 succeeded and 3 failed, got %+v", response)
+	}
+
+	expected := []bool{true, true, false, false, false}
+	for i, result := range response.Results {
+		if result.Success != expected[i] {
+			t.Errorf("Operation %d (%s %s): expected success=%v, got %+v", i, result.Op, result.Server, expected[i], result)
+		}
+	}
+
+	if !mcpManager.InMaintenance("memory") {
+		t.Error("Expected memory to be in maintenance mode")
+	}
+
+	// Every operation is recorded with who and why
+	req = httptest.NewRequest("GET", "/admin/incidents?server=memory&since=1h", nil)
+	req.Header.Set("X-Admin-Token", testAdminToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var history struct {
+		Incidents []state.Incident `json:"incidents"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil {
+		t.Fatalf("Failed to parse incidents: %v (%s)", err, w.Body.String())
+	}
+	if len(history.Incidents) != 2 {
+		t.Fatalf("Expected 2 memory incidents, got %+v", history.Incidents)
+	}
+	for _, incident := range history.Incidents {
+		if incident.Actor != "cron" || incident.Reason != "nightly maintenance" {
+			t.Errorf("Expected actor and reason to be recorded, got %+v", incident)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/admin/incidents?since=yesterday", nil)
+	req.Header.Set("X-Admin-Token", testAdminToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid since, got %d", w.Code)
+	}
+
+	// Requests for a server in maintenance are refused
+	req = httptest.NewRequest("POST", "/memory/sse", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 503 with Retry-After for server in maintenance, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/admin/servers:batch", strings.NewReader(`{"operations":[]}`))
+	req.Header.Set("X-Admin-Token", testAdminToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty batch, got %d", w.Code)
+	}
+}
@@ -0,0 +1,227 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/mcp"
+)
+
+// cacheableMethods maps the idempotent discovery methods whose responses are
+// cached to the notification that invalidates them
+var cacheableMethods = map[string]string{
+	"tools/list":     "notifications/tools/list_changed",
+	"resources/list": "notifications/resources/list_changed",
+	"prompts/list":   "notifications/prompts/list_changed",
+}
+
+// cachedResponse is a successful response stored without its request ID
+type cachedResponse struct {
+	message   map[string]json.RawMessage
+	expiresAt time.Time
+}
+
+// ResponseCache caches responses to discovery methods per server, scope, method and params
+//
+// Claude.ai repeats tools/list and friends on every connection; answering them
+// from memory avoids waking slow npm-based servers. Entries expire after the
+// TTL and are dropped as soon as the server announces a list change.
+// The scope names the instance that answered and the tenant it answered, so
+// a per-session instance's lists are never served to another session, nor a
+// tenant's to another tenant. A nil *ResponseCache caches nothing.
+type ResponseCache struct {
+	ttl     time.Duration
+	entries map[string]map[string]cachedResponse // Server name → method + scope + params hash → response
+	mu      sync.Mutex
+}
+
+// NewResponseCache creates a cache keeping responses for ttl
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:     ttl,
+		entries: make(map[string]map[string]cachedResponse),
+	}
+}
+
+// cacheScope returns the scope of the responses mcpServer gives to the request in ctx
+func cacheScope(ctx context.Context, mcpServer *mcp.Server) string {
+	return mcpServer.Name + "@" + contextTenant(ctx)
+}
+
+// cacheKey returns the cache key for a cacheable request within scope, or "" for other requests
+func cacheKey(scope string, request []byte) (string, interface{}) {
+	var msg struct {
+		ID     interface{}     `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(request, &msg); err != nil || msg.ID == nil {
+		return "", nil
+	}
+	if _, cacheable := cacheableMethods[msg.Method]; !cacheable {
+		return "", nil
+	}
+
+	// Re-encode params so key order and whitespace don't split entries
+	var params interface{}
+	json.Unmarshal(msg.Params, &params)
+	canonical, _ := json.Marshal(params)
+	sum := sha256.Sum256(canonical)
+	return msg.Method + " " + scope + " " + hex.EncodeToString(sum[:8]), msg.ID
+}
+
+// Lookup returns the cached response to request within scope with the request's ID, if any
+func (c *ResponseCache) Lookup(serverName, scope string, request []byte) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	key, id := cacheKey(scope, request)
+	if key == "" {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	entry, exists := c.entries[serverName][key]
+	if exists && time.Now().After(entry.expiresAt) {
+		delete(c.entries[serverName], key)
+		exists = false
+	}
+	c.mu.Unlock()
+	if !exists {
+		return nil, false
+	}
+
+	encodedID, err := json.Marshal(id)
+	if err != nil {
+		return nil, false
+	}
+	message := make(map[string]json.RawMessage, len(entry.message))
+	for k, v := range entry.message {
+		message[k] = v
+	}
+	message["id"] = encodedID
+
+	response, err := json.Marshal(message)
+	if err != nil {
+		return nil, false
+	}
+	logger.System().Debug("Answered %s for server %s from response cache", key, serverName)
+	return response, true
+}
+
+// Store caches a successful response to a cacheable request within scope
+func (c *ResponseCache) Store(serverName, scope string, request, response []byte) {
+	if c == nil {
+		return
+	}
+	key, _ := cacheKey(scope, request)
+	if key == "" {
+		return
+	}
+
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(response, &message); err != nil {
+		return
+	}
+	if _, hasResult := message["result"]; !hasResult {
+		return // Errors are never cached
+	}
+	delete(message, "id")
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[serverName] == nil {
+		c.entries[serverName] = make(map[string]cachedResponse)
+	}
+	// Entries of instances that are gone are never looked up again, so expired ones are swept here
+	for k, entry := range c.entries[serverName] {
+		if now.After(entry.expiresAt) {
+			delete(c.entries[serverName], k)
+		}
+	}
+	c.entries[serverName][key] = cachedResponse{message: message, expiresAt: now.Add(c.ttl)}
+}
+
+// Invalidate drops the cached responses of a server for one method, or all when method is ""
+func (c *ResponseCache) Invalidate(serverName, method string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if method == "" {
+		delete(c.entries, serverName)
+		return
+	}
+	for key := range c.entries[serverName] {
+		if strings.HasPrefix(key, method+" ") {
+			delete(c.entries[serverName], key)
+		}
+	}
+}
+
+// HandleNotification invalidates cached lists when a server announces that they changed
+func (c *ResponseCache) HandleNotification(serverName string, message []byte) {
+	if c == nil {
+		return
+	}
+
+	var msg struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return
+	}
+	for method, notification := range cacheableMethods {
+		if msg.Method == notification {
+			logger.System().Info("Server %s sent %s, invalidating cached %s", serverName, notification, method)
+			c.Invalidate(serverName, method)
+		}
+	}
+}
+
+// forwardRequest sends a request to an MCP server, answering discovery methods
+// from the response cache when possible
+// A progress token in the request is tracked until the response arrives, so the
+// server's progress notifications reach the session in ctx. The server's hooks
+// see the request before the cache and the response after it.
+func (s *Server) forwardRequest(ctx context.Context, serverName string, mcpServer *mcp.Server, request []byte) ([]byte, error) {
+	sessionID, _ := ctx.Value("sessionID").(string)
+	header, _ := ctx.Value("responseHeader").(http.Header)
+	hookMsg := newHookMessage(sessionID, serverName, request, header)
+	if err := s.hooks.BeforeRequest(hookMsg); err != nil {
+		return s.blockedResponse(request, err.(*BlockedError))
+	}
+	request = hookMsg.Message
+
+	scope := cacheScope(ctx, mcpServer)
+	response, hit := s.responseCache.Lookup(serverName, scope, request)
+	if !hit {
+		tracked, forgetProgress := s.translator.TrackProgress(sessionID, request)
+		defer forgetProgress()
+
+		var err error
+		if response, err = mcpServer.SendAndReceive(ctx, tracked); err != nil {
+			return response, err
+		}
+		s.responseCache.Store(serverName, scope, request, response)
+	}
+
+	hookMsg.Message = response
+	err := s.hooks.AfterResponse(hookMsg)
+	s.recordFindings(hookMsg, err != nil)
+	if err != nil {
+		hookMsg.Message, err = s.blockedResponse(request, err.(*BlockedError))
+	}
+	s.recordUsage(ctx, hookMsg)
+	return hookMsg.Message, err
+}
@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	cache := NewResponseCache(time.Minute)
+	request := []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{"a":1,"b":2}}`)
+	response := []byte(`{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`)
+
+	if _, hit := cache.Lookup("memory", "memory@", request); hit {
+		t.Fatal("Expected miss on empty cache")
+	}
+	cache.Store("memory", "memory@", request, response)
+
+	// Same params in another order hit, with the new request's ID
+	cached, hit := cache.Lookup("memory", "memory@", []byte(`{"jsonrpc":"2.0","id":"x","method":"tools/list","params":{"b":2,"a":1}}`))
+	if !hit {
+		t.Fatal("Expected hit for reordered params")
+	}
+	if !strings.Contains(string(cached), `"id":"x"`) {
+		t.Errorf("Expected cached response to carry the request ID, got %s", cached)
+	}
+	if _, hit := cache.Lookup("notion", "notion@", request); hit {
+		t.Error("Expected cache to be per server")
+	}
+	for _, scope := range []string{"memory-3f2a9c1e@", "memory@acme"} {
+		if _, hit := cache.Lookup("memory", scope, request); hit {
+			t.Errorf("Expected the list of memory@ not to be served to %s", scope)
+		}
+	}
+
+	// Errors and non-discovery methods are never cached
+	call := []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"x"}}`)
+	cache.Store("memory", "memory@", call, []byte(`{"jsonrpc":"2.0","id":2,"result":{}}`))
+	if _, hit := cache.Lookup("memory", "memory@", call); hit {
+		t.Error("Expected tools/call not to be cached")
+	}
+	prompts := []byte(`{"jsonrpc":"2.0","id":3,"method":"prompts/list"}`)
+	cache.Store("memory", "memory@", prompts, []byte(`{"jsonrpc":"2.0","id":3,"error":{"code":-32601,"message":"nope"}}`))
+	if _, hit := cache.Lookup("memory", "memory@", prompts); hit {
+		t.Error("Expected error responses not to be cached")
+	}
+
+	// list_changed drops only the matching method
+	cache.Store("memory", "memory@", prompts, []byte(`{"jsonrpc":"2.0","id":3,"result":{"prompts":[]}}`))
+	cache.Store("memory", "memory@acme", request, response)
+	cache.HandleNotification("memory", []byte(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`))
+	if _, hit := cache.Lookup("memory", "memory@", request); hit {
+		t.Error("Expected tools/list to be invalidated")
+	}
+	if _, hit := cache.Lookup("memory", "memory@acme", request); hit {
+		t.Error("Expected tools/list to be invalidated in every scope")
+	}
+	if _, hit := cache.Lookup("memory", "memory@", prompts); !hit {
+		t.Error("Expected prompts/list to survive tools/list_changed")
+	}
+
+	// Nil cache caches nothing
+	var disabled *ResponseCache
+	disabled.Store("memory", "memory@", request, response)
+	if _, hit := disabled.Lookup("memory", "memory@", request); hit {
+		t.Error("Expected nil cache to miss")
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	cache := NewResponseCache(10 * time.Millisecond)
+	request := []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`)
+	cache.Store("memory", "memory@", request, []byte(`{"jsonrpc":"2.0","id":1,"result":{"resources":[]}}`))
+
+	time.Sleep(20 * time.Millisecond)
+	if _, hit := cache.Lookup("memory", "memory@", request); hit {
+		t.Error("Expected expired entry to miss")
+	}
+}
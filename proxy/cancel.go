@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/protocol"
+)
+
+// InFlightRequests tracks the requests each session is waiting on so they can be cancelled
+// Cancelling a request's context abandons the wait and notifies the MCP server with
+// notifications/cancelled.
+type InFlightRequests struct {
+	mu       sync.Mutex
+	requests map[string]context.CancelFunc // sessionID + "\x00" + JSON request ID
+}
+
+// NewInFlightRequests creates an empty in-flight request registry
+func NewInFlightRequests() *InFlightRequests {
+	return &InFlightRequests{requests: make(map[string]context.CancelFunc)}
+}
+
+// inFlightKey identifies a request by session and JSON-encoded ID, so 7 and "7" stay distinct
+func inFlightKey(sessionID, requestID string) string {
+	return sessionID + "\x00" + requestID
+}
+
+// encodeRequestID returns the JSON encoding of a decoded request ID ("" when there is none)
+func encodeRequestID(id interface{}) string {
+	if id == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(id)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// Track registers a request's cancel function and returns a func that forgets it once answered
+func (f *InFlightRequests) Track(sessionID string, id interface{}, cancel context.CancelFunc) func() {
+	requestID := encodeRequestID(id)
+	if requestID == "" {
+		return func() {}
+	}
+	key := inFlightKey(sessionID, requestID)
+
+	f.mu.Lock()
+	f.requests[key] = cancel
+	f.mu.Unlock()
+
+	return func() {
+		f.mu.Lock()
+		delete(f.requests, key)
+		f.mu.Unlock()
+	}
+}
+
+// Cancel cancels a session's in-flight request, reporting whether it was found
+// requestID is JSON-encoded, e.g. `7` or `"abc"`.
+func (f *InFlightRequests) Cancel(sessionID, requestID string) bool {
+	f.mu.Lock()
+	cancel, exists := f.requests[inFlightKey(sessionID, requestID)]
+	delete(f.requests, inFlightKey(sessionID, requestID))
+	f.mu.Unlock()
+
+	if exists {
+		cancel()
+	}
+	return exists
+}
+
+// CancelSession cancels every in-flight request of a session and returns how many there were
+func (f *InFlightRequests) CancelSession(sessionID string) int {
+	prefix := inFlightKey(sessionID, "")
+	var cancels []context.CancelFunc
+
+	f.mu.Lock()
+	for key, cancel := range f.requests {
+		if strings.HasPrefix(key, prefix) {
+			cancels = append(cancels, cancel)
+			delete(f.requests, key)
+		}
+	}
+	f.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return len(cancels)
+}
+
+// Client methods that cancel an earlier request, and the param naming it
+var cancellationMethods = map[string]string{
+	"notifications/cancelled": "requestId", // MCP
+	"$/cancelRequest":         "id",        // LSP-style clients
+}
+
+// handleClientCancellation cancels the request named by a client's cancellation notification
+// It returns false when the message is not a cancellation, leaving it to the caller.
+// The MCP server is notified by the cancelled request itself, under the ID it knows.
+func (s *Server) handleClientCancellation(w http.ResponseWriter, sessionID string, msg *protocol.JSONRPCMessage) bool {
+	param, isCancellation := cancellationMethods[msg.Method]
+	if !isCancellation {
+		return false
+	}
+
+	params, _ := msg.Params.(map[string]interface{})
+	requestID := encodeRequestID(params[param])
+	if requestID != "" && s.inFlight.Cancel(sessionID, requestID) {
+		logger.System().Info("Session %s cancelled request %s", sessionID, requestID)
+	} else {
+		logger.System().Debug("Session %s cancelled request %s, which is not in flight", sessionID, requestID)
+	}
+	w.WriteHeader(http.StatusAccepted)
+	return true
+}
+
+// handleCancelRequest cancels an in-flight request: POST /sessions/{sessionId}/requests/{requestId}/cancel
+// Numeric request IDs match numeric JSON-RPC IDs; anything else matches a string ID.
+func (s *Server) handleCancelRequest(w http.ResponseWriter, r *http.Request) {
+	if err := s.authenticate(r); err != nil {
+		if writeRateLimited(w, err) {
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer realm=\"Remote MCP Server\"")
+		writeError(w, http.StatusUnauthorized, ErrorUnauthorized, "Unauthorized")
+		return
+	}
+
+	vars := mux.Vars(r)
+	sessionID := vars["sessionId"]
+	requestID := vars["requestId"]
+	if _, err := strconv.ParseFloat(requestID, 64); err != nil {
+		requestID = encodeRequestID(requestID)
+	}
+
+	if !s.inFlight.Cancel(sessionID, requestID) {
+		writeError(w, http.StatusNotFound, ErrorNotFound, "Request not in flight")
+		return
+	}
+
+	logger.System().Info("Cancelled request %s of session %s via cancel endpoint", requestID, sessionID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// finishCancelledRequest ends a request whose wait for the MCP server was cancelled
+// It returns false when the request was not cancelled. A client that aborted its HTTP
+// request has nobody left to answer; one cancelled by ID, or by an administrator
+// through /admin/operations, gets a cancellation error.
+func (s *Server) finishCancelledRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, id interface{}, err error, isRemoteMCP bool) bool {
+	if r.Context().Err() != nil {
+		logger.System().Info("Client abandoned request %v; not answering", id)
+		return true
+	}
+	if errors.Is(err, mcp.ErrOperationCancelled) {
+		s.sendErrorResponse(w, id, protocol.RequestCancelled, "Request cancelled by an administrator", isRemoteMCP)
+		return true
+	}
+	if ctx.Err() != context.Canceled {
+		return false
+	}
+	s.sendErrorResponse(w, id, protocol.RequestCancelled, "Request cancelled", isRemoteMCP)
+	return true
+}
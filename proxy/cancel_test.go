@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/protocol"
+)
+
+func TestInFlightRequests(t *testing.T) {
+	inFlight := NewInFlightRequests()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	untrack := inFlight.Track("session-1", float64(7), cancel)
+
+	if inFlight.Cancel("session-2", "7") {
+		t.Error("Expected requests of other sessions not to be cancelled")
+	}
+	if inFlight.Cancel("session-1", `"7"`) {
+		t.Error("Expected string ID \"7\" not to match numeric ID 7")
+	}
+	if !inFlight.Cancel("session-1", "7") {
+		t.Fatal("Expected the in-flight request to be cancelled")
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Expected the request context to be cancelled, got %v", ctx.Err())
+	}
+	if inFlight.Cancel("session-1", "7") {
+		t.Error("Expected a cancelled request to be forgotten")
+	}
+	untrack()
+
+	// Notifications have no ID to cancel by
+	inFlight.Track("session-1", nil, cancel)()
+	if len(inFlight.requests) != 0 {
+		t.Errorf("Expected requests without ID not to be tracked, got %d", len(inFlight.requests))
+	}
+}
+
+// waitStarted waits until the helper server reports a slow call started
+func waitStarted(t *testing.T, started <-chan struct{}) {
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Error("The slow call never reached the server")
+	}
+}
+
+// postCancel posts to the proxy as the client and returns the HTTP status
+func postCancel(handler http.Handler, client *Client, path, body string) int {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Host = "localhost"
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+client.token)
+	req.Header.Set("Mcp-Session-Id", client.SessionID())
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestRequestCancellation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil { // Request 1
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	started := embedded.Server.sseEvents.Attach(client.SessionID())
+
+	// An aborted HTTP request stops the wait and cancels the call on the server
+	abortCtx, abort := context.WithCancel(ctx)
+	go func() {
+		waitStarted(t, started)
+		abort()
+	}()
+	if _, err := client.CallTool(abortCtx, "slow", nil); err == nil { // Request 2
+		t.Fatal("Expected the aborted call to fail")
+	}
+
+	// The cancel endpoint answers the waiting client with a cancellation error
+	go func() {
+		waitStarted(t, started)
+		path := fmt.Sprintf("/helper/sessions/%s/requests/3/cancel", client.SessionID())
+		if code := postCancel(embedded.Handler, client, path, ""); code != http.StatusAccepted {
+			t.Errorf("Expected HTTP 202 from the cancel endpoint, got %d", code)
+		}
+	}()
+	response, err := client.CallTool(ctx, "slow", nil) // Request 3
+	if err != nil || response.Error == nil || response.Error.Code != protocol.RequestCancelled {
+		t.Fatalf("Expected a request cancelled error, got %+v (%v)", response, err)
+	}
+
+	// Clients can also cancel with notifications/cancelled
+	go func() {
+		waitStarted(t, started)
+		body := `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":4}}`
+		if code := postCancel(embedded.Handler, client, "/helper/sse", body); code != http.StatusAccepted {
+			t.Errorf("Expected HTTP 202 for notifications/cancelled, got %d", code)
+		}
+	}()
+	response, err = client.CallTool(ctx, "slow", nil) // Request 4
+	if err != nil || response.Error == nil || response.Error.Code != protocol.RequestCancelled {
+		t.Fatalf("Expected a request cancelled error, got %+v (%v)", response, err)
+	}
+
+	path := fmt.Sprintf("/helper/sessions/%s/requests/3/cancel", client.SessionID())
+	if code := postCancel(embedded.Handler, client, path, ""); code != http.StatusNotFound {
+		t.Errorf("Expected HTTP 404 for a request no longer in flight, got %d", code)
+	}
+
+	// The server was told about every cancellation, with the reason
+	response, err = client.CallTool(ctx, "cancelled", nil)
+	if err != nil || response.Error != nil {
+		t.Fatalf("tools/call failed: %+v (%v)", response, err)
+	}
+	received := fmt.Sprint(response.Result)
+	for _, id := range []string{`"requestId":2`, `"requestId":3`, `"requestId":4`} {
+		if !strings.Contains(received, id) {
+			t.Errorf("Expected the server to receive a cancellation with %s, got %s", id, received)
+		}
+	}
+	if !strings.Contains(received, "Request cancelled by client") {
+		t.Errorf("Expected the cancellation reason, got %s", received)
+	}
+}
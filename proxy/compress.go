@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// compressionThreshold returns the response size above which bodies are compressed (off when 0)
+func (s *Server) compressionThreshold() int {
+	if s.config == nil {
+		return config.DefaultCompressionThreshold
+	}
+	return s.config.CompressionThreshold
+}
+
+// compressMiddleware compresses large POST responses and /listtools output for clients that accept it
+// SSE streams are never compressed: their events must reach the client as
+// they are written.
+func (s *Server) compressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		threshold := s.compressionThreshold()
+		if threshold <= 0 || (r.Method != "POST" && !strings.HasPrefix(r.URL.Path, "/listtools/")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, threshold: threshold, status: http.StatusOK}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header, or "" for neither
+// gzip is preferred when both are accepted with the same weight; "*" accepts gzip.
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "*" {
+			name = "gzip"
+		}
+		if name != "gzip" && name != "deflate" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ || (q == bestQ && name == "gzip") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter holds a response back until it is known to exceed the threshold
+// Larger bodies are compressed; smaller ones, event streams, bodies the handler
+// encoded itself and responses flushed early are written as they are.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding  string
+	threshold int
+
+	status      int
+	buffer      bytes.Buffer
+	wroteHeader bool           // The handler called WriteHeader
+	decided     bool           // The header went out, compressed or not
+	compressor  io.WriteCloser // Set once compressing
+}
+
+// WriteHeader records the status, sent once the body shows whether it is compressed
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader || cw.decided {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified || !cw.compressible() {
+		cw.passThrough()
+	}
+}
+
+// Write buffers the body until it passes the threshold, then compresses it
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	if !cw.decided {
+		if !cw.compressible() {
+			cw.passThrough()
+		} else {
+			cw.buffer.Write(data)
+			if cw.buffer.Len() <= cw.threshold {
+				return len(data), nil
+			}
+			if err := cw.startCompressing(); err != nil {
+				return 0, err
+			}
+			return len(data), nil
+		}
+	}
+	if cw.compressor != nil {
+		return cw.compressor.Write(data)
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+// Flush sends what was written so far, deciding on compression if the handler hasn't filled the buffer yet
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.passThrough()
+	}
+	if flusher, ok := cw.compressor.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close finishes the response: it writes a body that stayed under the threshold, or ends the compressed stream
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		return cw.passThrough()
+	}
+	if cw.compressor != nil {
+		return cw.compressor.Close()
+	}
+	return nil
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible reports whether the response headers allow compressing the body
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	return header.Get("Content-Encoding") == "" && !strings.HasPrefix(header.Get("Content-Type"), "text/event-stream")
+}
+
+// passThrough sends the header and anything buffered uncompressed
+func (cw *compressWriter) passThrough() error {
+	cw.decided = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buffer.Len() == 0 {
+		return nil
+	}
+	_, err := cw.ResponseWriter.Write(cw.buffer.Bytes())
+	cw.buffer.Reset()
+	return err
+}
+
+// startCompressing sends the header of a compressed response and compresses the buffered body
+func (cw *compressWriter) startCompressing() error {
+	cw.decided = true
+	header := cw.Header()
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if cw.encoding == "gzip" {
+		cw.compressor = gzip.NewWriter(cw.ResponseWriter)
+	} else {
+		cw.compressor = zlib.NewWriter(cw.ResponseWriter)
+	}
+	logger.System().Debug("Compressing response with %s (over %d bytes)", cw.encoding, cw.threshold)
+
+	_, err := cw.compressor.Write(cw.buffer.Bytes())
+	cw.buffer.Reset()
+	return err
+}
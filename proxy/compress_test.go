@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                        "",
+		"br":                      "",
+		"gzip, deflate, br":       "gzip",
+		"deflate":                 "deflate",
+		"gzip;q=0.5, deflate":     "deflate",
+		"gzip;q=0, deflate;q=0":   "",
+		"*":                       "gzip",
+		"identity, GZIP;q=0.8":    "gzip",
+		"gzip;q=bogus, deflate":   "deflate",
+		"deflate;q=1.0, gzip;q=1": "gzip",
+	} {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("Accept-Encoding %q: expected %q, got %q", header, want, got)
+		}
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	server := NewServerWithConfig(mcp.NewManager(nil), &config.Config{CompressionThreshold: 100}, nil, nil)
+	large := `{"jsonrpc":"2.0","id":1,"result":{"text":"` + strings.Repeat("page content ", 100) + `"}}`
+
+	serve := func(method, path, acceptEncoding, contentType, body string, chunked bool) *httptest.ResponseRecorder {
+		handler := server.compressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			if !chunked {
+				w.Write([]byte(body))
+				return
+			}
+			// Like writeResponse, in chunks larger than the threshold
+			for start := 0; start < len(body); start += 150 {
+				w.Write([]byte(body[start:min(start+150, len(body))]))
+				w.(http.Flusher).Flush()
+			}
+		}))
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for _, tt := range []struct {
+		name, method, path, acceptEncoding, contentType, body string
+		chunked                                               bool
+		want                                                  string
+	}{
+		{"gzip", "POST", "/sessions/abc", "gzip, deflate", "application/json", large, false, "gzip"},
+		{"deflate", "POST", "/sse", "deflate", "application/json", large, false, "deflate"},
+		{"listtools", "GET", "/listtools/memory", "gzip", "application/json", large, false, "gzip"},
+		{"chunked", "POST", "/sse", "gzip", "application/json", large, true, "gzip"},
+		{"small", "POST", "/sse", "gzip", "application/json", `{"jsonrpc":"2.0","id":1,"result":{}}`, false, ""},
+		{"not accepted", "POST", "/sse", "br", "application/json", large, false, ""},
+		{"event stream", "POST", "/sse", "gzip", "text/event-stream", large, true, ""},
+		{"other GET", "GET", "/listmcp", "gzip", "application/json", large, false, ""},
+	} {
+		recorder := serve(tt.method, tt.path, tt.acceptEncoding, tt.contentType, tt.body, tt.chunked)
+		if encoding := recorder.Header().Get("Content-Encoding"); encoding != tt.want {
+			t.Errorf("%s: expected Content-Encoding %q, got %q", tt.name, tt.want, encoding)
+			continue
+		}
+
+		var reader io.Reader = recorder.Body
+		switch tt.want {
+		case "gzip":
+			gz, err := gzip.NewReader(recorder.Body)
+			if err != nil {
+				t.Fatalf("%s: invalid gzip body: %v", tt.name, err)
+			}
+			reader = gz
+		case "deflate":
+			zr, err := zlib.NewReader(recorder.Body)
+			if err != nil {
+				t.Fatalf("%s: invalid deflate body: %v", tt.name, err)
+			}
+			reader = zr
+		}
+		if body, err := io.ReadAll(reader); err != nil || string(body) != tt.body {
+			t.Errorf("%s: expected the body back, got %d bytes (%v)", tt.name, len(body), err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ConnectionMetrics is a snapshot of what a connection carried, shown by the session health endpoints
+type ConnectionMetrics struct {
+	BytesSent     int64            `json:"bytesSent"`             // SSE stream and responses to the session's requests
+	EventsEmitted int64            `json:"eventsEmitted"`         // SSE events, heartbeats excluded
+	Requests      map[string]int64 `json:"requests"`              // Client messages by JSON-RPC method; "response" for answers to server requests
+	LastRequest   *time.Time       `json:"lastRequest,omitempty"` // When the client last sent a message
+}
+
+// connectionMetrics accumulates a connection's metrics
+type connectionMetrics struct {
+	mu            sync.Mutex
+	bytesSent     int64
+	eventsEmitted int64
+	requests      map[string]int64
+	lastRequest   time.Time
+}
+
+// Metrics returns a snapshot of the connection's metrics
+func (c *ConnectionInfo) Metrics() ConnectionMetrics {
+	c.metrics.mu.Lock()
+	defer c.metrics.mu.Unlock()
+
+	snapshot := ConnectionMetrics{
+		BytesSent:     c.metrics.bytesSent,
+		EventsEmitted: c.metrics.eventsEmitted,
+		Requests:      make(map[string]int64, len(c.metrics.requests)),
+	}
+	for method, count := range c.metrics.requests {
+		snapshot.Requests[method] = count
+	}
+	if !c.metrics.lastRequest.IsZero() {
+		lastRequest := c.metrics.lastRequest
+		snapshot.LastRequest = &lastRequest
+	}
+	return snapshot
+}
+
+// connection returns a session's connection, or nil when it has none
+func (cm *ConnectionManager) connection(sessionID string) *ConnectionInfo {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.connections[sessionID]
+}
+
+// SetPrincipal records who authenticated the connection, e.g. "api-key:ci"
+func (cm *ConnectionManager) SetPrincipal(sessionID, principal string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if conn, exists := cm.connections[sessionID]; exists {
+		conn.Principal = principal
+	}
+}
+
+// RecordRequest counts a client message on a session's connection, which also keeps it from going idle
+func (cm *ConnectionManager) RecordRequest(sessionID, method string) {
+	conn := cm.connection(sessionID)
+	if conn == nil {
+		return
+	}
+	if method == "" {
+		method = "response"
+	}
+
+	conn.metrics.mu.Lock()
+	if conn.metrics.requests == nil {
+		conn.metrics.requests = make(map[string]int64)
+	}
+	conn.metrics.requests[method]++
+	conn.metrics.lastRequest = time.Now()
+	conn.metrics.mu.Unlock()
+	conn.touch()
+}
+
+// RecordEvents counts SSE events sent on a session's connection
+func (cm *ConnectionManager) RecordEvents(sessionID string, count int) {
+	if conn := cm.connection(sessionID); conn != nil && count > 0 {
+		conn.metrics.mu.Lock()
+		conn.metrics.eventsEmitted += int64(count)
+		conn.metrics.mu.Unlock()
+	}
+}
+
+// Meter returns a writer counting the bytes sent to a session's client
+// w is returned as is when the session has no connection.
+func (cm *ConnectionManager) Meter(sessionID string, w http.ResponseWriter) http.ResponseWriter {
+	conn := cm.connection(sessionID)
+	if conn == nil {
+		return w
+	}
+	return &meteredWriter{ResponseWriter: w, metrics: &conn.metrics}
+}
+
+// meteredWriter adds the bytes written through it to a connection's metrics
+type meteredWriter struct {
+	http.ResponseWriter
+	metrics *connectionMetrics
+}
+
+// Write writes data and counts the bytes written
+func (mw *meteredWriter) Write(data []byte) (int, error) {
+	n, err := mw.ResponseWriter.Write(data)
+	mw.metrics.mu.Lock()
+	mw.metrics.bytesSent += int64(n)
+	mw.metrics.mu.Unlock()
+	return n, err
+}
+
+// Flush sends buffered data to the client
+func (mw *meteredWriter) Flush() {
+	if flusher, ok := mw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (mw *meteredWriter) Unwrap() http.ResponseWriter {
+	return mw.ResponseWriter
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"remote-mcp-proxy/mcp"
+)
+
+func TestConnectionMetrics(t *testing.T) {
+	server := NewServer(mcp.NewManager(nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.connectionManager.AddConnection("session-abcdef123", "memory", ctx, cancel)
+	server.connectionManager.SetPrincipal("session-abcdef123", "api-key:ci")
+
+	server.connectionManager.RecordRequest("session-abcdef123", "tools/call")
+	server.connectionManager.RecordRequest("session-abcdef123", "tools/call")
+	server.connectionManager.RecordRequest("session-abcdef123", "")
+	server.connectionManager.RecordEvents("session-abcdef123", 3)
+	w := server.connectionManager.Meter("session-abcdef123", httptest.NewRecorder())
+	w.Write([]byte("event: message\n\n"))
+
+	// Sessions without a connection are ignored
+	server.connectionManager.RecordRequest("unknown", "ping")
+	if recorder := httptest.NewRecorder(); server.connectionManager.Meter("unknown", recorder) != recorder {
+		t.Error("Expected writers of unknown sessions to be left as they are")
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleSessionHealth(recorder, httptest.NewRequest("GET", "/health/sessions", nil))
+	var body struct {
+		Sessions map[string]struct {
+			Principal string            `json:"principal"`
+			Metrics   ConnectionMetrics `json:"metrics"`
+		} `json:"sessions"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid session health response: %v", err)
+	}
+	session := body.Sessions["session-"]
+	if session.Principal != "api-key:ci" {
+		t.Errorf("Expected the principal, got %q", session.Principal)
+	}
+	metrics := session.Metrics
+	if metrics.Requests["tools/call"] != 2 || metrics.Requests["response"] != 1 || metrics.LastRequest == nil {
+		t.Errorf("Expected requests by method and the last request time, got %+v", metrics)
+	}
+	if metrics.EventsEmitted != 3 || metrics.BytesSent != int64(len("event: message\n\n")) {
+		t.Errorf("Expected 3 events and the metered bytes, got %+v", metrics)
+	}
+}
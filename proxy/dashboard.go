@@ -0,0 +1,222 @@
+package proxy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/state"
+)
+
+// defaultErrorLimit bounds /admin/errors responses without ?limit
+const defaultErrorLimit = 50
+
+//go:embed ui/index.html
+var dashboardHTML []byte
+
+// handleDashboard serves the operator dashboard at /ui
+//
+// The page itself holds no data. It calls /health, /listmcp and the admin
+// APIs from the browser, sending the ADMIN_TOKEN the operator enters.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+	w.Write(dashboardHTML)
+}
+
+// handleKillSession disconnects a session and stops its MCP servers at once,
+// without waiting for the resume grace period
+//
+// Sessions already disconnected but still in their grace period can be killed
+// too. The response reports what was cleaned up.
+func (s *Server) handleKillSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, serverName, found := s.lookupSession(w, mux.Vars(r)["sessionId"])
+	if !found {
+		return
+	}
+
+	actor := adminActor(r)
+	logger.System().Info("Killing session %s for %s", sessionID[:8], actor)
+
+	connectionClosed := s.connectionManager.connection(sessionID) != nil
+	resumeCancelled := s.sessionResumer.Resume(sessionID)
+	cancelledRequests := s.inFlight.CancelSession(sessionID)
+	_, translatorState := s.translator.GetConnectionState(sessionID)
+	eventsDropped := s.sseEvents.Known(sessionID)
+	stoppedServers := s.mcpManager.GetSessionServers(sessionID)
+
+	s.connectionManager.RemoveConnection(sessionID)
+	s.translator.RemoveConnection(sessionID)
+	s.mcpManager.CleanupSession(sessionID)
+	s.sseEvents.Forget(sessionID)
+	s.notifySession(config.WebhookSessionClosed, serverName, sessionID)
+
+	s.mcpManager.GetIncidentStore().RecordIncident(state.Incident{
+		Kind:    state.IncidentAdmin,
+		Actor:   actor,
+		Action:  "kill-session",
+		Success: true,
+		Details: map[string]interface{}{"session": sessionID, "servers": len(stoppedServers)},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"session":                sessionID,
+		"server":                 serverName,
+		"killed":                 true,
+		"connectionClosed":       connectionClosed,
+		"resumeCancelled":        resumeCancelled,
+		"cancelledRequests":      cancelledRequests,
+		"stoppedServers":         stoppedServers,
+		"translatorStateRemoved": translatorState,
+		"eventsDropped":          eventsDropped,
+	}); err != nil {
+		logger.System().Error("Failed to encode kill session response: %v", err)
+	}
+}
+
+// minSessionPrefix is the shortest session ID prefix admin endpoints accept,
+// the length of the short ID shown in listings
+const minSessionPrefix = 8
+
+// findSession returns the session whose ID starts with prefix, and its server
+// Like /health/sessions/{id}, the short ID shown in listings is accepted.
+// Connected sessions are looked up, and disconnected ones whose MCP servers are
+// kept running for the resume grace period. A prefix matching several sessions
+// returns none of them, but their IDs as candidates.
+func (s *Server) findSession(prefix string) (sessionID, serverName string, candidates []string) {
+	servers := make(map[string]string)
+	for fullID, conn := range s.connectionManager.GetConnections() {
+		if strings.HasPrefix(fullID, prefix) {
+			servers[fullID] = conn.ServerName
+		}
+	}
+	for _, fullID := range s.mcpManager.GetSessionIDs() {
+		if _, connected := servers[fullID]; connected || !strings.HasPrefix(fullID, prefix) {
+			continue
+		}
+		names := make([]string, 0, 1)
+		for name := range s.mcpManager.GetSessionServerMap(fullID) {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		servers[fullID] = ""
+		if len(names) > 0 {
+			servers[fullID] = names[0]
+		}
+	}
+
+	for fullID, name := range servers {
+		sessionID, serverName = fullID, name
+		candidates = append(candidates, fullID)
+	}
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		return "", "", candidates
+	}
+	return sessionID, serverName, nil
+}
+
+// lookupSession finds the single session an admin request names by prefix
+// It answers prefixes shorter than the short ID, unknown ones, and ambiguous
+// ones, listing the sessions they match so the operator can pick one: acting
+// on an arbitrary one of them could kill or wipe the wrong session.
+func (s *Server) lookupSession(w http.ResponseWriter, prefix string) (sessionID, serverName string, found bool) {
+	if len(prefix) < minSessionPrefix {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest,
+			fmt.Sprintf("Session ID '%s' is too short, use at least %d characters", prefix, minSessionPrefix))
+		return "", "", false
+	}
+	sessionID, serverName, candidates := s.findSession(prefix)
+	if len(candidates) > 1 {
+		logger.System().Warn("Session prefix %s is ambiguous: %d sessions match", prefix, len(candidates))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":      ErrorConflict,
+			"message":    fmt.Sprintf("Session ID '%s' matches %d sessions, use a longer prefix", prefix, len(candidates)),
+			"requestId":  w.Header().Get(requestIDHeader),
+			"candidates": candidates,
+		}); err != nil {
+			logger.System().Error("Failed to write session conflict: %v", err)
+		}
+		return "", "", false
+	}
+	if sessionID == "" {
+		writeError(w, http.StatusNotFound, ErrorSessionNotFound, fmt.Sprintf("Session '%s' not found", prefix))
+		return "", "", false
+	}
+	return sessionID, serverName, true
+}
+
+// logError is one ERROR line from the system or an MCP server log
+type logError struct {
+	Log  string `json:"log"`
+	Line string `json:"line"`
+}
+
+// handleRecentErrors returns the last ?limit=N ERROR lines of the system log
+// and of each MCP server's log
+func (s *Server) handleRecentErrors(w http.ResponseWriter, r *http.Request) {
+	limit := defaultErrorLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		n, err := strconv.Atoi(limitParam)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	manager := logger.GetManager()
+	files := map[string]string{}
+	if filename, exists := manager.SystemLogFile(); exists {
+		files["system"] = filename
+	}
+	if s.config != nil {
+		for name := range s.config.MCPServers {
+			if filename, exists := manager.MCPLogFile(name); exists {
+				files[name] = filename
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errors := []logError{}
+	for _, name := range names {
+		lines, err := logger.GrepFile(files[name], "[ERROR]", limit)
+		if err != nil {
+			logger.System().Warn("Failed to read log file %s: %v", files[name], err)
+			continue
+		}
+		for _, line := range lines {
+			errors = append(errors, logError{Log: name, Line: line})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors":    errors,
+		"count":     len(errors),
+		"timestamp": time.Now(),
+	}); err != nil {
+		logger.System().Error("Failed to encode errors response: %v", err)
+	}
+}
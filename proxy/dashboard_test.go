@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+func TestDashboard(t *testing.T) {
+	mcpManager := mcp.NewManager(map[string]config.MCPServer{
+		"memory": {Command: "echo"},
+	})
+	server := NewServerWithConfig(mcpManager, &config.Config{AdminToken: testAdminToken}, nil, nil)
+	router := server.Router()
+
+	req := httptest.NewRequest("GET", "/ui", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/admin/servers:batch") {
+		t.Fatalf("Expected the dashboard page, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `api("/admin/topology")`) {
+		t.Error("Expected the dashboard to render the topology graph")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.connectionManager.AddConnection("session-abcdef123", "memory", ctx, cancel)
+	server.translator.RegisterSession("session-abcdef123")
+	requestCtx, cancelRequest := context.WithCancel(context.Background())
+	defer server.inFlight.Track("session-abcdef123", 7, cancelRequest)()
+	otherCtx, cancelOther := context.WithCancel(context.Background())
+	defer cancelOther()
+	server.connectionManager.AddConnection("session-abcdef456", "memory", otherCtx, cancelOther)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"unknown session", "/admin/sessions/other-session", http.StatusNotFound},
+		{"too short", "/admin/sessions/sess", http.StatusBadRequest},
+		{"ambiguous", "/admin/sessions/session-", http.StatusConflict},
+		{"short ID", "/admin/sessions/session-abcdef1", http.StatusOK},
+		{"already killed", "/admin/sessions/session-abcdef1", http.StatusNotFound},
+		{"no longer ambiguous", "/admin/sessions/session-", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("DELETE", tt.path, nil)
+		req.Header.Set("X-Admin-Token", testAdminToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected the killed session's context to be cancelled")
+	}
+	if requestCtx.Err() == nil {
+		t.Error("Expected the killed session's in-flight request to be cancelled")
+	}
+	if otherCtx.Err() == nil {
+		t.Error("Expected the remaining session to be killed once its prefix was unique")
+	}
+}
+
+func TestFindSessionAmbiguous(t *testing.T) {
+	server := NewServer(mcp.NewManager(map[string]config.MCPServer{"memory": {Command: "echo"}}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.connectionManager.AddConnection("session-abcdef123", "memory", ctx, cancel)
+	server.connectionManager.AddConnection("session-abcdef456", "memory", ctx, cancel)
+
+	w := httptest.NewRecorder()
+	if _, _, found := server.lookupSession(w, "session-abc"); found || w.Code != http.StatusConflict {
+		t.Fatalf("Expected an ambiguous prefix to be refused with 409, got %d", w.Code)
+	}
+	var conflict struct {
+		Error      string   `json:"error"`
+		Candidates []string `json:"candidates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	if conflict.Error != ErrorConflict || len(conflict.Candidates) != 2 || conflict.Candidates[0] != "session-abcdef123" {
+		t.Errorf("Expected both sessions as candidates, got %+v", conflict)
+	}
+
+	if sessionID, serverName, found := server.lookupSession(httptest.NewRecorder(), "session-abcdef4"); !found || sessionID != "session-abcdef456" || serverName != "memory" {
+		t.Errorf("Expected a unique prefix to find its session, got %q %q %v", sessionID, serverName, found)
+	}
+}
+
+func TestKillSessionReport(t *testing.T) {
+	server := NewServerWithConfig(mcp.NewManager(map[string]config.MCPServer{
+		"memory": {Command: "echo"},
+	}), &config.Config{AdminToken: testAdminToken}, nil, nil)
+	router := server.Router()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.connectionManager.AddConnection("session-abcdef123", "memory", ctx, cancel)
+	server.translator.RegisterSession("session-abcdef123")
+	_, cancelRequest := context.WithCancel(context.Background())
+	server.inFlight.Track("session-abcdef123", "a", cancelRequest)
+
+	req := httptest.NewRequest("DELETE", "/admin/sessions/session-abc", nil)
+	req.Header.Set("X-Admin-Token", testAdminToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var report struct {
+		Session                string        `json:"session"`
+		Server                 string        `json:"server"`
+		Killed                 bool          `json:"killed"`
+		ConnectionClosed       bool          `json:"connectionClosed"`
+		ResumeCancelled        bool          `json:"resumeCancelled"`
+		CancelledRequests      int           `json:"cancelledRequests"`
+		StoppedServers         []interface{} `json:"stoppedServers"`
+		TranslatorStateRemoved bool          `json:"translatorStateRemoved"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid report: %v", err)
+	}
+	if report.Session != "session-abcdef123" || report.Server != "memory" || !report.Killed {
+		t.Errorf("Unexpected session in report: %+v", report)
+	}
+	if !report.ConnectionClosed || report.ResumeCancelled || report.CancelledRequests != 1 || !report.TranslatorStateRemoved {
+		t.Errorf("Unexpected cleanup in report: %+v", report)
+	}
+	if report.StoppedServers == nil {
+		t.Error("Expected stoppedServers to be a list")
+	}
+	if _, exists := server.translator.GetConnectionState("session-abcdef123"); exists {
+		t.Error("Expected the translator state to be removed")
+	}
+}
+
+func TestAdminWithoutToken(t *testing.T) {
+	router := NewServer(mcp.NewManager(map[string]config.MCPServer{
+		"memory": {Command: "echo"},
+	})).Router()
+
+	// Without ADMIN_TOKEN admin endpoints are refused, reads included
+	for _, path := range []string{"/admin/incidents", "/admin/api-keys", "/admin/approvals", "/usage", "/logs/system", "/selftest/memory"} {
+		w := adminRequest(router, "GET", path)
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), ErrorFeatureDisabled) {
+			t.Errorf("GET %s: expected 403 %s without ADMIN_TOKEN, got %d: %s", path, ErrorFeatureDisabled, w.Code, w.Body.String())
+		}
+	}
+	for _, path := range []string{"/admin/servers:batch", "/admin/approvals/abc/approve", "/admin/loglevel"} {
+		w := adminRequest(router, "POST", path)
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), ErrorFeatureDisabled) {
+			t.Errorf("POST %s: expected 403 %s without ADMIN_TOKEN, got %d: %s", path, ErrorFeatureDisabled, w.Code, w.Body.String())
+		}
+	}
+	if w := adminRequest(router, "DELETE", "/admin/sessions/session-abc"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected session kills to be refused without ADMIN_TOKEN, got %d", w.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"remote-mcp-proxy/logger"
+)
+
+// handleSessionTrace returns the captured JSON-RPC traffic for a session
+//
+// The session may be given by its full ID or a unique prefix (as shown by
+// /health/sessions). ?format=jsonl returns one entry per line, which is the
+// same format as the trace files written in WIRE_CAPTURE=file mode.
+func (s *Server) handleSessionTrace(w http.ResponseWriter, r *http.Request) {
+	sessionID := mux.Vars(r)["sessionId"]
+
+	recorder := s.mcpManager.GetTraceRecorder()
+	if recorder == nil {
+		writeError(w, http.StatusNotFound, ErrorFeatureDisabled, "Wire capture is disabled (set WIRE_CAPTURE=memory or WIRE_CAPTURE=file)")
+		return
+	}
+
+	entries, exists := recorder.Entries(sessionID)
+	if !exists {
+		var matches []string
+		for _, captured := range recorder.Sessions() {
+			if strings.HasPrefix(captured, sessionID) {
+				matches = append(matches, captured)
+			}
+		}
+		if len(matches) != 1 {
+			writeError(w, http.StatusNotFound, ErrorSessionNotFound, fmt.Sprintf("No unique trace found for session '%s'", sessionID))
+			return
+		}
+		sessionID = matches[0]
+		entries, _ = recorder.Entries(sessionID)
+	}
+
+	if r.URL.Query().Get("format") == "jsonl" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				logger.System().Error("Failed to encode trace entry: %v", err)
+				return
+			}
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessionId": sessionID,
+		"entries":   entries,
+		"count":     len(entries),
+		"timestamp": time.Now(),
+	}); err != nil {
+		logger.System().Error("Failed to encode session trace response: %v", err)
+	}
+}
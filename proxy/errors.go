@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+)
+
+// requestIDHeader carries the ID of each request, from the client or generated by the proxy
+const requestIDHeader = "X-Request-Id"
+
+// validRequestID matches the request IDs accepted from clients and reverse proxies
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Error codes of ErrorResponse
+// They are stable: clients may act on them, so a code is never renamed or
+// reused for another failure, and new failures get new codes.
+const (
+	ErrorInvalidRequest        = "invalid_request"         // Malformed parameters or body
+	ErrorInvalidMessage        = "invalid_message"         // Body is no valid JSON-RPC message
+	ErrorRequestTooLarge       = "request_too_large"       // Body over MAX_REQUEST_BYTES
+	ErrorUnauthorized          = "unauthorized"            // Missing or invalid credentials
+	ErrorForbidden             = "forbidden"               // Credentials valid, but not for this request
+	ErrorOriginNotAllowed      = "origin_not_allowed"      // Browser origin refused
+	ErrorInvalidReconnectToken = "invalid_reconnect_token" // Resuming a session without its reconnect token
+	ErrorRateLimited           = "rate_limited"            // API key over its rate limit, see Retry-After
+	ErrorTooManyConnections    = "too_many_connections"    // Connection limit reached
+	ErrorMethodNotAllowed      = "method_not_allowed"      // HTTP method not served by the endpoint
+	ErrorNoRoute               = "no_route"                // No server or endpoint matches the URL
+	ErrorNotFound              = "not_found"               // Named item (approval, operation, API key...) unknown
+	ErrorConflict              = "conflict"                // Item already exists
+	ErrorServerNotFound        = "server_not_found"        // MCP server not configured, or not for this tenant
+	ErrorServerUnavailable     = "server_unavailable"      // MCP server configured but could not be started
+	ErrorServerNotRunning      = "server_not_running"      // MCP server process not running
+	ErrorServerMaintenance     = "server_maintenance"      // MCP server in maintenance mode, see Retry-After
+	ErrorSessionNotFound       = "session_not_found"       // Session unknown or ended
+	ErrorSessionNotInitialized = "session_not_initialized" // Session has not completed the initialize handshake
+	ErrorUpstreamFailed        = "upstream_failed"         // MCP server or identity provider failed to answer
+	ErrorUpstreamInvalid       = "upstream_invalid"        // MCP server answered with something unusable
+	ErrorTimeout               = "timeout"                 // Gave up waiting, e.g. for a tool call slot
+	ErrorFeatureDisabled       = "feature_disabled"        // Endpoint needs a feature the configuration leaves off
+	ErrorInternal              = "internal_error"          // Failure inside the proxy
+)
+
+// ErrorResponse is the JSON body of the proxy's HTTP error responses
+// Endpoints may add fields describing the failure, such as the routing
+// diagnostics of no_route. JSON-RPC requests answered by an MCP server, or
+// refused by the proxy on its behalf, get JSON-RPC errors instead.
+type ErrorResponse struct {
+	Error     string   `json:"error"`               // Stable code, one of the Error* constants or an OAuth 2.0 code
+	Message   string   `json:"message"`             // What went wrong, for people
+	RequestID string   `json:"requestId,omitempty"` // Also in the X-Request-Id header
+	Hints     []string `json:"hints,omitempty"`     // What the client can do about it
+	Server    string   `json:"server,omitempty"`    // MCP server the error concerns
+
+	// Description repeats Message on authentication errors for OAuth 2.0 clients (RFC 6749, RFC 6750)
+	Description string `json:"error_description,omitempty"`
+}
+
+// writeError writes an ErrorResponse with a stable code
+func writeError(w http.ResponseWriter, status int, code, message string, hints ...string) {
+	writeErrorResponse(w, status, ErrorResponse{Error: code, Message: message, Hints: hints})
+}
+
+// writeServerError writes an ErrorResponse about an MCP server
+func writeServerError(w http.ResponseWriter, status int, code, serverName, message string, hints ...string) {
+	writeErrorResponse(w, status, ErrorResponse{Error: code, Message: message, Hints: hints, Server: serverName})
+}
+
+// writeServerUnavailable answers a request for an MCP server that has no instance for it
+// Servers missing from the configuration are not found; configured ones
+// failed to start.
+func (s *Server) writeServerUnavailable(w http.ResponseWriter, serverName string) {
+	code, hints := ErrorServerUnavailable, []string{"GET /listmcp shows the status of the MCP servers"}
+	if s.config != nil {
+		if _, configured := s.config.MCPServers[serverName]; !configured {
+			code, hints = ErrorServerNotFound, []string{"GET /listmcp lists the MCP servers this host serves"}
+		}
+	}
+	writeServerError(w, http.StatusNotFound, code, serverName, fmt.Sprintf("MCP server '%s' not available", serverName), hints...)
+}
+
+// writeAuthError writes an ErrorResponse refusing the credentials of a request
+// The message is repeated in error_description for OAuth 2.0 clients.
+func writeAuthError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorResponse(w, status, ErrorResponse{Error: code, Message: message, Description: message})
+}
+
+// writeErrorResponse writes body as an error response, filling in the request ID
+func writeErrorResponse(w http.ResponseWriter, status int, body ErrorResponse) {
+	body.RequestID = w.Header().Get(requestIDHeader)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.System().Error("Failed to write error response: %v", err)
+	}
+}
+
+// transportError is how a failure to deliver a JSON-RPC request is answered
+type transportError struct {
+	status  int // HTTP status
+	rpcCode int // JSON-RPC error code
+}
+
+// transportErrors maps the codes of failures to deliver a JSON-RPC request to their HTTP status
+// Errors of the request itself, such as an unknown method, invalid params or
+// a call the policy refuses, are protocol errors answered with HTTP 200.
+var transportErrors = map[string]transportError{
+	ErrorRequestTooLarge:       {http.StatusRequestEntityTooLarge, protocol.InvalidRequest},
+	ErrorSessionNotInitialized: {http.StatusBadRequest, protocol.InvalidRequest},
+	ErrorServerNotRunning:      {http.StatusServiceUnavailable, protocol.InternalError},
+	ErrorUpstreamFailed:        {http.StatusBadGateway, protocol.InternalError},
+	ErrorUpstreamInvalid:       {http.StatusBadGateway, protocol.InternalError},
+	ErrorTimeout:               {http.StatusGatewayTimeout, protocol.InternalError},
+	ErrorInternal:              {http.StatusInternalServerError, protocol.InternalError},
+}
+
+// sendTransportError answers a JSON-RPC request the proxy could not deliver, with the HTTP status of code
+// The body is a JSON-RPC error, so clients can match it to their request, and
+// its data is the ErrorResponse of the failure.
+func (s *Server) sendTransportError(w http.ResponseWriter, id interface{}, code, message string, isRemoteMCP bool, hints ...string) {
+	mapped, known := transportErrors[code]
+	if !known {
+		mapped = transportErrors[ErrorInternal]
+	}
+	logger.System().Error(" Sending %d transport error %s: %s", mapped.status, code, message)
+
+	data := ErrorResponse{Error: code, Message: message, RequestID: w.Header().Get(requestIDHeader), Hints: hints}
+	errorResponse, err := s.translator.CreateErrorResponseWithData(id, mapped.rpcCode, message, data, isRemoteMCP)
+	if err != nil {
+		logger.System().Error(" Failed to create error response: %v", err)
+		writeError(w, mapped.status, code, message, hints...)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(mapped.status)
+	if _, err := w.Write(errorResponse); err != nil {
+		logger.System().Error(" Failed to write error response: %v", err)
+	}
+}
+
+// requestIDMiddleware gives each request an ID, sent back in the X-Request-Id header
+// IDs from clients or reverse proxies are kept when they look like IDs, so a
+// request can be followed across them; others are replaced with a new one.
+func (s *Server) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = generateRandomString(32)
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
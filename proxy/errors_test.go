@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/protocol"
+)
+
+func TestErrorResponses(t *testing.T) {
+	_, handler := newOAuthTestServer(t, &config.Config{
+		AdminToken: "admin",
+		MCPServers: map[string]config.MCPServer{"memory": {Command: "echo"}},
+	})
+
+	for _, tt := range []struct {
+		method, path, requestID string
+		status                  int
+		code                    string
+	}{
+		{method: "GET", path: "/listtools/missing", status: http.StatusNotFound, code: ErrorServerNotFound},
+		{method: "GET", path: "/health/sessions/unknown", status: http.StatusNotFound, code: ErrorSessionNotFound, requestID: "trace-42"},
+		{method: "GET", path: "/admin/incidents", status: http.StatusUnauthorized, code: ErrorUnauthorized},
+		{method: "GET", path: "/nowhere/at/all", status: http.StatusNotFound, code: ErrorNoRoute, requestID: "not a valid id"},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Host = "localhost"
+		if tt.requestID != "" {
+			req.Header.Set(requestIDHeader, tt.requestID)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		var body ErrorResponse
+		if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+			t.Errorf("%s %s: expected a JSON error body, got %v", tt.method, tt.path, err)
+			continue
+		}
+		if recorder.Code != tt.status || body.Error != tt.code || body.Message == "" {
+			t.Errorf("%s %s: expected %d %s, got %d %+v", tt.method, tt.path, tt.status, tt.code, recorder.Code, body)
+		}
+
+		requestID := recorder.Header().Get(requestIDHeader)
+		if requestID == "" || body.RequestID != requestID {
+			t.Errorf("%s %s: expected the request ID %q in the body, got %q", tt.method, tt.path, requestID, body.RequestID)
+		}
+		if validRequestID.MatchString(tt.requestID) && requestID != tt.requestID {
+			t.Errorf("%s %s: expected the client's request ID to be kept, got %q", tt.method, tt.path, requestID)
+		}
+		if tt.requestID != "" && !validRequestID.MatchString(tt.requestID) && requestID == tt.requestID {
+			t.Errorf("%s %s: expected an invalid request ID to be replaced", tt.method, tt.path)
+		}
+	}
+}
+
+func TestTransportErrors(t *testing.T) {
+	server, _ := newOAuthTestServer(t, &config.Config{})
+
+	for _, tt := range []struct {
+		code   string
+		status int
+		rpc    int
+	}{
+		{ErrorUpstreamFailed, http.StatusBadGateway, protocol.InternalError},
+		{ErrorServerNotRunning, http.StatusServiceUnavailable, protocol.InternalError},
+		{ErrorSessionNotInitialized, http.StatusBadRequest, protocol.InvalidRequest},
+		{ErrorRequestTooLarge, http.StatusRequestEntityTooLarge, protocol.InvalidRequest},
+	} {
+		recorder := httptest.NewRecorder()
+		recorder.Header().Set(requestIDHeader, "trace-7")
+		server.sendTransportError(recorder, 7, tt.code, "it failed", false)
+
+		var response struct {
+			ID    int `json:"id"`
+			Error struct {
+				Code int           `json:"code"`
+				Data ErrorResponse `json:"data"`
+			} `json:"error"`
+		}
+		json.NewDecoder(recorder.Body).Decode(&response)
+		if recorder.Code != tt.status || response.ID != 7 || response.Error.Code != tt.rpc {
+			t.Errorf("%s: expected HTTP %d with JSON-RPC error %d, got %d %+v", tt.code, tt.status, tt.rpc, recorder.Code, response)
+		}
+		if response.Error.Data.Error != tt.code || response.Error.Data.RequestID != "trace-7" {
+			t.Errorf("%s: expected the error code and request ID in the error data, got %+v", tt.code, response.Error.Data)
+		}
+	}
+
+	// Protocol errors keep JSON-RPC semantics
+	recorder := httptest.NewRecorder()
+	server.sendErrorResponse(recorder, 8, protocol.InvalidParams, "bad arguments", false)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected a protocol error to be sent with HTTP 200, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"remote-mcp-proxy/logger"
+)
+
+// pollCacheControl lets clients keep polled listings but makes them revalidate with If-None-Match
+const pollCacheControl = "no-cache"
+
+// weakETag returns a weak entity tag for the parts of a response a poller cares about
+// Weak, because fields that change on every request (uptimes, JSON-RPC ids) are
+// left out: a match means nothing worth redrawing changed, not identical bytes.
+// It returns "" when a part can't be encoded, so the response goes without one.
+func weakETag(parts ...interface{}) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		data, err := json.Marshal(part)
+		if err != nil {
+			logger.System().Warn("Failed to compute ETag: %v", err)
+			return ""
+		}
+		hash.Write(data)
+		hash.Write([]byte{0})
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// checkNotModified sets the ETag and Cache-Control headers of a polled listing
+// When the request's If-None-Match names the ETag, it answers 304 Not Modified
+// and returns true; the caller then writes nothing more.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("Cache-Control", pollCacheControl)
+	if etag == "" {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing weakly (RFC 9110)
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
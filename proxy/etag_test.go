@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+func TestListMCPETag(t *testing.T) {
+	cfg := &config.Config{MCPServers: map[string]config.MCPServer{
+		"memory": {Command: "echo"},
+		"notion": {Command: "echo"},
+	}}
+	router := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil).Router()
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/listmcp", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("Expected a 200 with an ETag and Cache-Control, got %d %v", first.Code, first.Header())
+	}
+	if again := get(""); again.Header().Get("ETag") != etag {
+		t.Errorf("Expected the same ETag for an unchanged list, got %s and %s", etag, again.Header().Get("ETag"))
+	}
+
+	for _, ifNoneMatch := range []string{etag, `"other", ` + etag, "*"} {
+		if recorder := get(ifNoneMatch); recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: expected an empty 304, got %d", ifNoneMatch, recorder.Code)
+		}
+	}
+	if recorder := get(`W/"stale"`); recorder.Code != http.StatusOK {
+		t.Errorf("Expected a stale ETag to get the list, got %d", recorder.Code)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	for header, want := range map[string]bool{
+		"":                false,
+		`W/"abc"`:         true,
+		`"abc"`:           true,
+		`"xyz", W/"abc"`:  true,
+		`"xyz"`:           false,
+		"*":               true,
+		`W/"abcd", "ab"`:  false,
+		` W/"abc" , "x" `: true,
+	} {
+		if got := etagMatches(header, etag); got != want {
+			t.Errorf("If-None-Match %q: expected %v, got %v", header, want, got)
+		}
+	}
+}
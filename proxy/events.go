@@ -0,0 +1,348 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+)
+
+// sseEvent is one event delivered on a session's SSE stream
+type sseEvent struct {
+	seq       uint64
+	event     string
+	data      string
+	requestID string // JSON-RPC ID of a server-initiated request the client has not answered yet
+}
+
+// SSEEventLog numbers the events sent to each session and keeps the latest for replay
+//
+// Event IDs have the form "<sessionID>-<seq>". A browser EventSource sends the
+// last one it saw as Last-Event-ID when it reconnects, which both identifies
+// the session and tells the proxy which events the client missed. Unanswered
+// server-initiated requests are kept past the capacity, as the server is
+// blocked until the client answers them.
+type SSEEventLog struct {
+	capacity int
+	sessions map[string]*sessionEvents
+	mu       sync.Mutex
+}
+
+// sessionEvents holds the numbered events of one session
+type sessionEvents struct {
+	lastSeq uint64
+	events  []sseEvent
+	notify  chan struct{} // Signalled on append while a stream is attached
+}
+
+// NewSSEEventLog creates a log keeping capacity events per session
+func NewSSEEventLog(capacity int) *SSEEventLog {
+	if capacity <= 0 {
+		capacity = config.DefaultSSEReplayEvents
+	}
+	return &SSEEventLog{
+		capacity: capacity,
+		sessions: make(map[string]*sessionEvents),
+	}
+}
+
+// session returns the events of a session, creating them if needed
+// NOTE: This method must be called with l.mu locked
+func (l *SSEEventLog) session(sessionID string) *sessionEvents {
+	events, exists := l.sessions[sessionID]
+	if !exists {
+		events = &sessionEvents{}
+		l.sessions[sessionID] = events
+	}
+	return events
+}
+
+// Append adds an event for a session and wakes its attached stream
+// requestID is set for server-initiated requests awaiting the client's answer.
+// Events of sessions that never opened a stream are dropped.
+func (l *SSEEventLog) Append(sessionID, event, data, requestID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	events, exists := l.sessions[sessionID]
+	if !exists {
+		return
+	}
+	events.lastSeq++
+	events.events = append(events.events, sseEvent{seq: events.lastSeq, event: event, data: data, requestID: requestID})
+	if len(events.events) > l.capacity {
+		events.events = evictOldest(events.events)
+	}
+	if events.notify != nil {
+		select {
+		case events.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// evictOldest drops the oldest event, sparing unanswered requests unless nothing else is left
+func evictOldest(events []sseEvent) []sseEvent {
+	for i, event := range events {
+		if event.requestID == "" {
+			return append(events[:i], events[i+1:]...)
+		}
+	}
+	return events[1:]
+}
+
+// Answer marks a server-initiated request as answered so it can leave the buffer
+func (l *SSEEventLog) Answer(sessionID, requestID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if events, exists := l.sessions[sessionID]; exists {
+		for i := range events.events {
+			if events.events[i].requestID == requestID {
+				events.events[i].requestID = ""
+			}
+		}
+	}
+}
+
+// Since returns the session's events after seq, oldest first
+func (l *SSEEventLog) Since(sessionID string, seq uint64) []sseEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var result []sseEvent
+	if events, exists := l.sessions[sessionID]; exists {
+		for _, event := range events.events {
+			if event.seq > seq {
+				result = append(result, event)
+			}
+		}
+	}
+	return result
+}
+
+// LastSeq returns the sequence number of the session's latest event
+func (l *SSEEventLog) LastSeq(sessionID string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if events, exists := l.sessions[sessionID]; exists {
+		return events.lastSeq
+	}
+	return 0
+}
+
+// Known reports whether the log has seen a stream for the session
+func (l *SSEEventLog) Known(sessionID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, exists := l.sessions[sessionID]
+	return exists
+}
+
+// Attach returns a channel signalled whenever an event is appended for the session
+func (l *SSEEventLog) Attach(sessionID string) <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	events := l.session(sessionID)
+	events.notify = make(chan struct{}, 1)
+	return events.notify
+}
+
+// Detach stops signalling the session's stream; its events are kept for replay
+func (l *SSEEventLog) Detach(sessionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if events, exists := l.sessions[sessionID]; exists {
+		events.notify = nil
+	}
+}
+
+// Forget drops every event of a session
+func (l *SSEEventLog) Forget(sessionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.sessions, sessionID)
+}
+
+// writeEndpointData writes the data line of the endpoint event in the given format
+// Claude.ai reads a JSON object with a "uri" key; the MCP SDKs, and so MCP
+// Inspector, take the data as the bare URI.
+func writeEndpointData(w io.Writer, format, uri string) error {
+	data := uri
+	if format != config.EndpointFormatString {
+		endpointJSON, err := json.Marshal(map[string]interface{}{"uri": uri})
+		if err != nil {
+			return err
+		}
+		data = string(endpointJSON)
+	}
+	logger.System().Info("INFO: Endpoint data: %s", data)
+	_, err := fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// formatEventID builds the SSE event ID of a session's event
+func formatEventID(sessionID string, seq uint64) string {
+	return fmt.Sprintf("%s-%d", sessionID, seq)
+}
+
+// parseEventID splits a Last-Event-ID into its session and sequence number
+func parseEventID(id string) (string, uint64, bool) {
+	sep := strings.LastIndex(id, "-")
+	if sep <= 0 {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseUint(id[sep+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return id[:sep], seq, true
+}
+
+// writeSSEEvent writes one numbered event to an SSE stream
+func writeSSEEvent(w io.Writer, sessionID string, event sseEvent) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", formatEventID(sessionID, event.seq), event.event, event.data)
+	return err
+}
+
+// replayFrom returns the sequence number after which a new stream should start sending events
+// Clients resuming with Last-Event-ID get what they missed; others only new events.
+func (s *Server) replayFrom(r *http.Request, sessionID string) uint64 {
+	if lastSessionID, seq, ok := parseEventID(r.Header.Get("Last-Event-ID")); ok && lastSessionID == sessionID {
+		if missed := len(s.sseEvents.Since(sessionID, seq)); missed > 0 {
+			logger.System().Info("Replaying %d missed SSE events to session %s", missed, sessionID[:8])
+		}
+		return seq
+	}
+	return s.sseEvents.LastSeq(sessionID)
+}
+
+// writePendingEvents writes the session's events after lastSent and returns the new last sequence number
+func (s *Server) writePendingEvents(w io.Writer, sessionID string, lastSent uint64) (uint64, error) {
+	written := 0
+	defer func() { s.connectionManager.RecordEvents(sessionID, written) }()
+
+	for _, event := range s.sseEvents.Since(sessionID, lastSent) {
+		if err := writeSSEEvent(w, sessionID, event); err != nil {
+			return lastSent, err
+		}
+		lastSent = event.seq
+		written++
+	}
+	return lastSent, nil
+}
+
+// handleServerNotification receives notifications and requests from every MCP server
+// Messages from a session's own instance are forwarded on its SSE stream.
+func (s *Server) handleServerNotification(serverName, sessionID string, message []byte) {
+	s.responseCache.HandleNotification(serverName, message)
+
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return
+	}
+
+	if msg.Method == "notifications/progress" {
+		s.relayProgress(serverName, message)
+		return
+	}
+
+	if sessionID == "" {
+		// A shared instance serves many sessions: route resource updates to their subscribers
+		if msg.Method == "notifications/resources/updated" {
+			for _, subscriber := range s.translator.SubscribedSessions(serverName, msg.Params.URI) {
+				s.sseEvents.Append(subscriber, "message", string(s.translator.RewriteResourceURIs(subscriber, serverName, msg.Method, message)), "")
+			}
+		}
+		return
+	}
+	requestID := ""
+	if len(msg.ID) > 0 {
+		requestID = string(msg.ID)
+		logger.System().Info("Server %s sent request %s (%s) to session %s", serverName, requestID, msg.Method, sessionID[:8])
+	}
+	s.sseEvents.Append(sessionID, "message", string(s.translator.RewriteResourceURIs(sessionID, serverName, msg.Method, message)), requestID)
+}
+
+// relayProgress forwards a progress notification to the session whose request carries its token
+// Progress is routed by token rather than by instance, so updates from shared instances
+// reach the right session too.
+func (s *Server) relayProgress(serverName string, message []byte) {
+	sessionID, notification, ok := s.translator.RouteProgress(message)
+	if !ok {
+		logger.System().Debug("Dropping progress from server %s for a request no longer in flight: %s", serverName, string(message))
+		return
+	}
+	s.sseEvents.Append(sessionID, "message", string(notification), "")
+}
+
+// recordSubscription tracks a successful resources/subscribe or resources/unsubscribe for the session
+func (s *Server) recordSubscription(serverName, sessionID string, request *protocol.JSONRPCMessage, response []byte) {
+	if request.Method != "resources/subscribe" && request.Method != "resources/unsubscribe" {
+		return
+	}
+
+	var result protocol.JSONRPCMessage
+	if err := json.Unmarshal(response, &result); err != nil || result.Error != nil {
+		return
+	}
+	params, _ := request.Params.(map[string]interface{})
+	uri, _ := params["uri"].(string)
+	if uri == "" {
+		return
+	}
+	uri = s.translator.RestoreResourceURI(serverName, uri) // Tracked as the server knows it
+
+	if request.Method == "resources/subscribe" {
+		s.translator.Subscribe(sessionID, serverName, uri)
+		logger.System().Info("Session %s subscribed to %s on server %s", sessionID[:8], uri, serverName)
+	} else {
+		s.translator.Unsubscribe(sessionID, uri)
+		logger.System().Info("Session %s unsubscribed from %s on server %s", sessionID[:8], uri, serverName)
+	}
+}
+
+// forwardClientResponse relays the client's answer to a server-initiated request
+// It returns false when the message is not a response, leaving it to the caller.
+// The answer goes to the session's own instance, which sent the request.
+func (s *Server) forwardClientResponse(w http.ResponseWriter, serverName, sessionID string, msg *protocol.JSONRPCMessage, body []byte) bool {
+	if msg.Method != "" || msg.ID == nil {
+		return false
+	}
+
+	mcpServer, exists := s.mcpManager.GetServerForSession(sessionID, serverName)
+	if !exists {
+		s.writeServerUnavailable(w, serverName)
+		return true
+	}
+
+	requestID, err := json.Marshal(msg.ID)
+	if err == nil {
+		s.sseEvents.Answer(sessionID, string(requestID))
+	}
+	if err := mcpServer.SendMessage(body); err != nil {
+		logger.System().Error(" Failed to forward response %v from session %s to server %s: %v", msg.ID, sessionID, mcpServer.Name, err)
+		writeError(w, http.StatusBadGateway, ErrorUpstreamFailed, "Failed to communicate with MCP server")
+		return true
+	}
+
+	logger.System().Debug("Forwarded response %v from session %s to server %s", msg.ID, sessionID, mcpServer.Name)
+	w.WriteHeader(http.StatusAccepted)
+	return true
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestSSEEventLogReplay(t *testing.T) {
+	log := NewSSEEventLog(2)
+
+	log.Append("session-1", "message", "dropped", "")
+	if log.Known("session-1") {
+		t.Fatal("Expected events of sessions without a stream to be dropped")
+	}
+
+	notify := log.Attach("session-1")
+	log.Append("session-1", "message", "one", "")
+	select {
+	case <-notify:
+	default:
+		t.Error("Expected attached stream to be signalled")
+	}
+
+	log.Detach("session-1")
+	log.Append("session-1", "message", "two", "")
+	log.Append("session-1", "message", "three", "")
+
+	// Only the latest events are kept for replay
+	events := log.Since("session-1", 1)
+	if len(events) != 2 || events[0].data != "two" || events[1].data != "three" {
+		t.Fatalf("Expected events two and three after seq 1, got %+v", events)
+	}
+
+	sessionID, seq, ok := parseEventID(formatEventID("abc-def", 7))
+	if !ok || sessionID != "abc-def" || seq != 7 {
+		t.Errorf("Expected event ID to round-trip, got %q %d %v", sessionID, seq, ok)
+	}
+	if _, _, ok := parseEventID("no-sequence-x"); ok {
+		t.Error("Expected malformed event ID to be rejected")
+	}
+
+	var out strings.Builder
+	if err := writeSSEEvent(&out, "session-1", events[1]); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id: session-1-3\nevent: message\ndata: three\n\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	log.Forget("session-1")
+	if log.LastSeq("session-1") != 0 {
+		t.Error("Expected forgotten session to have no events")
+	}
+}
+
+func TestSSEEventLogKeepsPendingRequests(t *testing.T) {
+	log := NewSSEEventLog(2)
+	log.Attach("session-1")
+
+	log.Append("session-1", "message", "sampling", "7")
+	log.Append("session-1", "message", "one", "")
+	log.Append("session-1", "message", "two", "")
+
+	// The unanswered request outlives newer notifications
+	events := log.Since("session-1", 0)
+	if len(events) != 2 || events[0].data != "sampling" || events[1].data != "two" {
+		t.Fatalf("Expected pending request and latest event, got %+v", events)
+	}
+
+	log.Answer("session-1", "7")
+	log.Append("session-1", "message", "three", "")
+	events = log.Since("session-1", 0)
+	if len(events) != 2 || events[0].data != "two" || events[1].data != "three" {
+		t.Errorf("Expected answered request to be evicted, got %+v", events)
+	}
+}
+
+func TestSessionResumer(t *testing.T) {
+	resumer := NewSessionResumer(20 * time.Millisecond)
+
+	cleaned := make(chan string, 2)
+	resumer.Schedule("session-resumed", func() { cleaned <- "session-resumed" })
+	resumer.Schedule("session-dropped", func() { cleaned <- "session-dropped" })
+
+	if !resumer.Resume("session-resumed") {
+		t.Error("Expected pending cleanup to be cancelled on resume")
+	}
+	if resumer.Resume("session-unknown") {
+		t.Error("Expected nothing to resume for an unknown session")
+	}
+
+	select {
+	case sessionID := <-cleaned:
+		if sessionID != "session-dropped" {
+			t.Errorf("Expected only the dropped session to be cleaned up, got %s", sessionID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected session to be cleaned up after the grace period")
+	}
+	select {
+	case sessionID := <-cleaned:
+		t.Errorf("Expected resumed session to be kept, but %s was cleaned up", sessionID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Without a grace period cleanup runs at once
+	immediate := false
+	NewSessionResumer(0).Schedule("session-1", func() { immediate = true })
+	if !immediate {
+		t.Error("Expected immediate cleanup without a grace period")
+	}
+}
+
+func TestWriteEndpointData(t *testing.T) {
+	const uri = "https://memory.mcp.example.com/sessions/abc123"
+	tests := []struct {
+		client string
+		format string
+		want   string
+	}{
+		{"Claude.ai", config.EndpointFormatObject, `data: {"uri":"` + uri + `"}` + "\n\n"},
+		{"default", "", `data: {"uri":"` + uri + `"}` + "\n\n"},
+		{"MCP Inspector", config.EndpointFormatString, "data: " + uri + "\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.client, func(t *testing.T) {
+			var out strings.Builder
+			if err := writeEndpointData(&out, tt.format, uri); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, out.String())
+			}
+		})
+	}
+}
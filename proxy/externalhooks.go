@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+)
+
+// maxDecisionBytes bounds what an external hook may answer
+const maxDecisionBytes = 16 << 20
+
+// externalRequest is what an external hook is sent for each message
+type externalRequest struct {
+	Phase     string          `json:"phase"` // config.HookPhaseRequest or config.HookPhaseResponse
+	SessionID string          `json:"sessionId,omitempty"`
+	Server    string          `json:"server"`
+	Method    string          `json:"method"`
+	Tool      string          `json:"tool,omitempty"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// externalDecision is what an external hook answers about a message
+// An empty answer lets the message through unchanged.
+type externalDecision struct {
+	Action  string          `json:"action"`            // decisionAllow (default), decisionReplace or decisionReject
+	Message json.RawMessage `json:"message,omitempty"` // decisionReplace: the message sent on instead
+	Code    int             `json:"code,omitempty"`    // decisionReject: JSON-RPC error code (protocol.InvalidRequest when 0)
+	Reason  string          `json:"reason,omitempty"`  // decisionReject: error message returned to the client
+}
+
+// External hook decisions
+const (
+	decisionAllow   = "allow"
+	decisionReplace = "replace"
+	decisionReject  = "reject"
+)
+
+// externalCaller asks an external program or endpoint about a message, returning its raw answer
+type externalCaller func(ctx context.Context, request []byte) ([]byte, error)
+
+// externalHook hands messages to an external program or endpoint, which allows, replaces or rejects them
+type externalHook struct {
+	hookMatch
+	name     string // Command or URL, for logs
+	phase    string // Phase the hook applies to (both when empty)
+	timeout  time.Duration
+	failOpen bool
+	call     externalCaller
+}
+
+// newExternalHook returns the "command" or "http" hook described by a server's config
+func newExternalHook(match hookMatch, cfg config.Hook) *externalHook {
+	hook := &externalHook{
+		hookMatch: match,
+		phase:     cfg.Phase,
+		timeout:   cfg.TimeoutDuration(),
+		failOpen:  cfg.FailOpen,
+	}
+	if cfg.Type == config.HookCommand {
+		hook.name = cfg.Command
+		hook.call = commandCaller(cfg.Command, cfg.Args)
+	} else {
+		hook.name = cfg.URL
+		hook.call = httpCaller(cfg.URL, cfg.Headers)
+	}
+	return hook
+}
+
+func (h *externalHook) BeforeRequest(msg *HookMessage) error {
+	return h.ask(config.HookPhaseRequest, msg)
+}
+
+func (h *externalHook) AfterResponse(msg *HookMessage) error {
+	return h.ask(config.HookPhaseResponse, msg)
+}
+
+// ask sends a message to the external hook and applies its decision
+func (h *externalHook) ask(phase string, msg *HookMessage) error {
+	if (h.phase != "" && h.phase != phase) || !h.matches(msg) {
+		return nil
+	}
+
+	decision, err := h.decide(phase, msg)
+	if err != nil {
+		if h.failOpen {
+			return fmt.Errorf("hook %s failed: %w", h.name, err)
+		}
+		logger.System().Error("Hook %s failed for %s on server %s, refusing the %s: %v", h.name, msg.Method, msg.Server, phase, err)
+		return &BlockedError{Code: protocol.InternalError, Message: "Refused by the proxy: a policy hook is unavailable"}
+	}
+
+	switch decision.Action {
+	case "", decisionAllow:
+	case decisionReplace:
+		msg.Message = decision.Message
+	case decisionReject:
+		logger.System().Warn("Hook %s rejected the %s of %s on server %s: %s", h.name, phase, msg.Method, msg.Server, decision.Reason)
+		reason := decision.Reason
+		if reason == "" {
+			reason = "Request rejected by the proxy"
+		}
+		return &BlockedError{Code: decision.Code, Message: reason}
+	}
+	return nil
+}
+
+// decide calls the external hook and validates its answer
+func (h *externalHook) decide(phase string, msg *HookMessage) (*externalDecision, error) {
+	request, err := json.Marshal(externalRequest{
+		Phase:     phase,
+		SessionID: msg.SessionID,
+		Server:    msg.Server,
+		Method:    msg.Method,
+		Tool:      msg.Tool,
+		Message:   msg.Message,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	answer, err := h.call(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &externalDecision{}
+	if len(bytes.TrimSpace(answer)) == 0 {
+		return decision, nil
+	}
+	if err := json.Unmarshal(answer, decision); err != nil {
+		return nil, fmt.Errorf("invalid decision: %w", err)
+	}
+	switch decision.Action {
+	case "", decisionAllow, decisionReject:
+	case decisionReplace:
+		if !json.Valid(decision.Message) {
+			return nil, fmt.Errorf("replace decision without a valid message")
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", decision.Action)
+	}
+	return decision, nil
+}
+
+// commandCaller runs a program for each message, writing it on stdin and reading the answer from stdout
+func commandCaller(command string, args []string) externalCaller {
+	return func(ctx context.Context, request []byte) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, command, args...)
+		cmd.Stdin = bytes.NewReader(request)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if detail := strings.TrimSpace(stderr.String()); detail != "" {
+				return nil, fmt.Errorf("%w: %s", err, detail)
+			}
+			return nil, err
+		}
+		if stdout.Len() > maxDecisionBytes {
+			return nil, fmt.Errorf("answer exceeds %d bytes", maxDecisionBytes)
+		}
+		return stdout.Bytes(), nil
+	}
+}
+
+// httpCaller POSTs each message to an endpoint, reading the answer from the response body
+func httpCaller(url string, headers map[string]string) externalCaller {
+	client := &http.Client{}
+	return func(ctx context.Context, request []byte) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(request))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+		}
+		answer, err := io.ReadAll(io.LimitReader(resp.Body, maxDecisionBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(answer) > maxDecisionBytes {
+			return nil, fmt.Errorf("answer exceeds %d bytes", maxDecisionBytes)
+		}
+		return answer, nil
+	}
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+// heartbeatSettings returns the SSE heartbeat style and interval for a server
+func (s *Server) heartbeatSettings(serverName string) (string, time.Duration) {
+	if s.config == nil {
+		return config.HeartbeatComment, config.DefaultHeartbeatInterval
+	}
+	return s.config.GetHeartbeat(serverName)
+}
+
+// writeHeartbeat writes one SSE heartbeat in the given style
+//
+// Only the named "event" style carries the rolling reconnect token: comments
+// are invisible to EventSource clients, so rotating the token there would
+// invalidate the one the client received in the Mcp-Reconnect-Token header.
+func (s *Server) writeHeartbeat(w io.Writer, style, sessionID string) error {
+	switch style {
+	case config.HeartbeatEvent:
+		keepAlive, _ := json.Marshal(map[string]interface{}{
+			"timestamp":      time.Now().Unix(),
+			"reconnectToken": s.reconnectTokens.Rotate(sessionID),
+		})
+		_, err := fmt.Fprintf(w, "event: keep-alive\ndata: %s\n\n", keepAlive)
+		return err
+	case config.HeartbeatPing:
+		_, err := fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"ping\"}\n\n")
+		return err
+	default:
+		_, err := fmt.Fprintf(w, ": keep-alive %d\n\n", time.Now().Unix())
+		return err
+	}
+}
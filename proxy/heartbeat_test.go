@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestWriteHeartbeat(t *testing.T) {
+	s := &Server{reconnectTokens: NewReconnectTokenStore(3, time.Minute)}
+
+	tests := []struct {
+		style      string
+		wantPrefix string
+		wantToken  bool
+	}{
+		{config.HeartbeatComment, ": keep-alive ", false},
+		{"", ": keep-alive ", false},
+		{config.HeartbeatEvent, "event: keep-alive\ndata: {", true},
+		{config.HeartbeatPing, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"ping\"}", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := s.writeHeartbeat(&buf, tt.style, "session-1"); err != nil {
+				t.Fatalf("writeHeartbeat failed: %v", err)
+			}
+			out := buf.String()
+			if !strings.HasPrefix(out, tt.wantPrefix) || !strings.HasSuffix(out, "\n\n") {
+				t.Errorf("Unexpected heartbeat for style %q: %q", tt.style, out)
+			}
+			if hasToken := strings.Contains(out, "reconnectToken"); hasToken != tt.wantToken {
+				t.Errorf("Style %q: reconnect token present = %v, want %v", tt.style, hasToken, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestHeartbeatSettings(t *testing.T) {
+	s := &Server{}
+	if style, interval := s.heartbeatSettings("memory"); style != config.HeartbeatComment || interval != config.DefaultHeartbeatInterval {
+		t.Errorf("Expected defaults without config, got %s every %v", style, interval)
+	}
+
+	s.config = &config.Config{
+		HeartbeatStyle:    config.HeartbeatEvent,
+		HeartbeatInterval: 15 * time.Second,
+		MCPServers: map[string]config.MCPServer{
+			"memory":  {Command: "echo"},
+			"legacy":  {Command: "echo", Heartbeat: &config.Heartbeat{Style: config.HeartbeatPing, Interval: "5s"}},
+			"partial": {Command: "echo", Heartbeat: &config.Heartbeat{Interval: "1m"}},
+		},
+	}
+
+	tests := []struct {
+		server       string
+		wantStyle    string
+		wantInterval time.Duration
+	}{
+		{"memory", config.HeartbeatEvent, 15 * time.Second},
+		{"legacy", config.HeartbeatPing, 5 * time.Second},
+		{"partial", config.HeartbeatEvent, time.Minute},
+		{AggregateServerName, config.HeartbeatEvent, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		style, interval := s.heartbeatSettings(tt.server)
+		if style != tt.wantStyle || interval != tt.wantInterval {
+			t.Errorf("heartbeatSettings(%s) = %s, %v; want %s, %v", tt.server, style, interval, tt.wantStyle, tt.wantInterval)
+		}
+	}
+}
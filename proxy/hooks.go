@@ -0,0 +1,357 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+)
+
+// HookMessage is a request on its way to an MCP server, or the response on its way back
+type HookMessage struct {
+	SessionID string
+	Server    string
+	Method    string      // Method of the request, also for its response
+	Tool      string      // Tool of a tools/call request, also for its response
+	Message   []byte      // The JSON-RPC message; hooks rewriting it replace it
+	Header    http.Header // Headers of the HTTP response to the client (nil when the request has no client)
+
+	Findings []ScanFinding // Sensitive content hooks found in the response, recorded in the incident history
+}
+
+// Hook transforms the messages exchanged with an MCP server
+//
+// BeforeRequest sees each request before it is sent, AfterResponse each response
+// before it is returned to the client; both may change msg.Message and msg.Header.
+// A BeforeRequest returning a *BlockedError answers the request with that error
+// instead of sending it, and an AfterResponse returning one replaces the response
+// with it. Any other error is logged and the message passes on as it was before
+// the hook.
+type Hook interface {
+	BeforeRequest(msg *HookMessage) error
+	AfterResponse(msg *HookMessage) error
+}
+
+// BlockedError refuses a request, or withholds its response, from a hook
+type BlockedError struct {
+	Code    int // JSON-RPC error code, protocol.InvalidRequest when 0
+	Message string
+}
+
+func (e *BlockedError) Error() string {
+	return e.Message
+}
+
+// Hooks holds the hooks of each server, in the order they run
+type Hooks struct {
+	mu    sync.RWMutex
+	hooks map[string][]Hook
+}
+
+// NewHooks creates the hooks configured for each server
+func NewHooks(cfg *config.Config) *Hooks {
+	h := &Hooks{hooks: make(map[string][]Hook)}
+	if cfg == nil {
+		return h
+	}
+	for name, server := range cfg.MCPServers {
+		for _, hookConfig := range server.Hooks {
+			h.Add(name, newConfiguredHook(hookConfig))
+		}
+	}
+	return h
+}
+
+// Add appends a hook to a server's hooks
+func (h *Hooks) Add(serverName string, hook Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks[serverName] = append(h.hooks[serverName], hook)
+}
+
+// forServer returns a server's hooks
+func (h *Hooks) forServer(serverName string) []Hook {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.hooks[serverName]
+}
+
+// BeforeRequest runs a server's hooks on a request, returning the *BlockedError of a hook refusing it
+func (h *Hooks) BeforeRequest(msg *HookMessage) error {
+	for _, hook := range h.forServer(msg.Server) {
+		original := msg.Message
+		if err := hook.BeforeRequest(msg); err != nil {
+			var blocked *BlockedError
+			if errors.As(err, &blocked) {
+				return blocked
+			}
+			logger.System().Error("Request hook failed for %s on server %s: %v", msg.Method, msg.Server, err)
+			msg.Message = original
+		}
+	}
+	return nil
+}
+
+// AfterResponse runs a server's hooks on a response, returning the *BlockedError of a hook withholding it
+func (h *Hooks) AfterResponse(msg *HookMessage) error {
+	for _, hook := range h.forServer(msg.Server) {
+		original := msg.Message
+		if err := hook.AfterResponse(msg); err != nil {
+			var blocked *BlockedError
+			if errors.As(err, &blocked) {
+				return blocked
+			}
+			logger.System().Error("Response hook failed for %s on server %s: %v", msg.Method, msg.Server, err)
+			msg.Message = original
+		}
+	}
+	return nil
+}
+
+// AddHook adds a hook to a server, after its configured ones
+// Embedders use it to patch a server's messages in code. It should be called
+// before requests are being served.
+func (s *Server) AddHook(serverName string, hook Hook) {
+	s.hooks.Add(serverName, hook)
+}
+
+// newHookMessage describes a request to a server for its hooks
+func newHookMessage(sessionID, serverName string, request []byte, header http.Header) *HookMessage {
+	var parsed struct {
+		Method string `json:"method"`
+	}
+	json.Unmarshal(request, &parsed)
+	tool, _ := protocol.ParseToolCall(request)
+	return &HookMessage{
+		SessionID: sessionID,
+		Server:    serverName,
+		Method:    parsed.Method,
+		Tool:      tool,
+		Message:   request,
+		Header:    header,
+	}
+}
+
+// blockedResponse is the JSON-RPC error answering a request whose request or response a hook blocked
+func (s *Server) blockedResponse(request []byte, blocked *BlockedError) ([]byte, error) {
+	var parsed struct {
+		ID interface{} `json:"id"`
+	}
+	json.Unmarshal(request, &parsed)
+	code := blocked.Code
+	if code == 0 {
+		code = protocol.InvalidRequest
+	}
+	return s.translator.CreateErrorResponse(parsed.ID, code, blocked.Message, false)
+}
+
+// newConfiguredHook returns the hook described by a server's config
+func newConfiguredHook(cfg config.Hook) Hook {
+	match := hookMatch{methods: cfg.Methods, tools: cfg.Tools}
+	switch cfg.Type {
+	case config.HookCommand, config.HookHTTP:
+		return newExternalHook(match, cfg)
+	case config.HookScan:
+		return newScanHook(match, cfg)
+	case config.HookHeader:
+		return &headerHook{hookMatch: match, headers: cfg.Headers}
+	case config.HookRewrite:
+		return &rewriteHook{hookMatch: match, request: cfg.Phase == config.HookPhaseRequest, set: cfg.Set, remove: cfg.Remove}
+	default:
+		message := cfg.Message
+		if message == "" {
+			message = "Request blocked by the proxy"
+		}
+		return &blockHook{hookMatch: match, message: message}
+	}
+}
+
+// hookMatch selects the messages a built-in hook applies to
+type hookMatch struct {
+	methods []string // Method patterns (all when empty)
+	tools   []string // Tool name patterns of tools/call requests (any message when empty)
+}
+
+// matches reports whether a message is one the hook applies to
+func (m hookMatch) matches(msg *HookMessage) bool {
+	if len(m.methods) > 0 && !matchesAny(m.methods, msg.Method) {
+		return false
+	}
+	if len(m.tools) > 0 && (msg.Tool == "" || !matchesAny(m.tools, msg.Tool)) {
+		return false
+	}
+	return true
+}
+
+// matchesAny reports whether name matches one of the path.Match patterns
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// headerHook sets HTTP headers on the responses to clients
+type headerHook struct {
+	hookMatch
+	headers map[string]string
+}
+
+func (h *headerHook) BeforeRequest(msg *HookMessage) error {
+	return nil
+}
+
+func (h *headerHook) AfterResponse(msg *HookMessage) error {
+	if msg.Header == nil || !h.matches(msg) {
+		return nil
+	}
+	for name, value := range h.headers {
+		msg.Header.Set(name, value)
+	}
+	return nil
+}
+
+// blockHook answers matching requests with an error
+type blockHook struct {
+	hookMatch
+	message string
+}
+
+func (h *blockHook) BeforeRequest(msg *HookMessage) error {
+	if !h.matches(msg) {
+		return nil
+	}
+	logger.System().Warn("Blocked %s on server %s by hook", msg.Method, msg.Server)
+	return &BlockedError{Message: h.message}
+}
+
+func (h *blockHook) AfterResponse(msg *HookMessage) error {
+	return nil
+}
+
+// rewriteHook sets and removes values at dotted paths of requests or responses
+type rewriteHook struct {
+	hookMatch
+	request bool // Rewrites requests rather than responses
+	set     map[string]interface{}
+	remove  []string
+}
+
+func (h *rewriteHook) BeforeRequest(msg *HookMessage) error {
+	if !h.request || !h.matches(msg) {
+		return nil
+	}
+	return h.rewrite(msg)
+}
+
+func (h *rewriteHook) AfterResponse(msg *HookMessage) error {
+	if h.request || !h.matches(msg) {
+		return nil
+	}
+	return h.rewrite(msg)
+}
+
+// rewrite applies the hook's changes to the message
+func (h *rewriteHook) rewrite(msg *HookMessage) error {
+	decoder := json.NewDecoder(bytes.NewReader(msg.Message))
+	decoder.UseNumber() // Large integers such as IDs survive the round trip
+	var message interface{}
+	if err := decoder.Decode(&message); err != nil {
+		return err
+	}
+
+	for dotted, value := range h.set {
+		message = setPath(message, strings.Split(dotted, "."), value)
+	}
+	for _, dotted := range h.remove {
+		removePath(message, strings.Split(dotted, "."))
+	}
+
+	rewritten, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	msg.Message = rewritten
+	return nil
+}
+
+// setPath sets value at keys inside node and returns the updated node
+// Missing object members along the way are created; "*" matches every member
+// or element, and numbers index arrays.
+func setPath(node interface{}, keys []string, value interface{}) interface{} {
+	if len(keys) == 0 {
+		return value
+	}
+	key, rest := keys[0], keys[1:]
+
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		if key == "*" {
+			for member, child := range typed {
+				typed[member] = setPath(child, rest, value)
+			}
+			return typed
+		}
+		child, exists := typed[key]
+		if !exists && len(rest) > 0 {
+			child = map[string]interface{}{}
+		}
+		typed[key] = setPath(child, rest, value)
+		return typed
+	case []interface{}:
+		for i := range typed {
+			if key == "*" || key == strconv.Itoa(i) {
+				typed[i] = setPath(typed[i], rest, value)
+			}
+		}
+		return typed
+	default:
+		return node // Not a container: nothing to set below it
+	}
+}
+
+// removePath removes the object member at keys inside node, with "*" and indexes as in setPath
+func removePath(node interface{}, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	key, rest := keys[0], keys[1:]
+
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			if key == "*" {
+				for member := range typed {
+					delete(typed, member)
+				}
+			} else {
+				delete(typed, key)
+			}
+			return
+		}
+		for member, child := range typed {
+			if key == "*" || key == member {
+				removePath(child, rest)
+			}
+		}
+	case []interface{}:
+		for i := range typed {
+			if key == "*" || key == strconv.Itoa(i) {
+				removePath(typed[i], rest)
+			}
+		}
+	}
+}
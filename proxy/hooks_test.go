@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/protocol"
+)
+
+func TestRewritePaths(t *testing.T) {
+	hook := newConfiguredHook(config.Hook{
+		Type:    config.HookRewrite,
+		Methods: []string{"tools/list"},
+		Set:     map[string]interface{}{"result.tools.*.annotations.readOnlyHint": true, "result.tools.0.title": "First"},
+		Remove:  []string{"result.tools.*.outputSchema", "result._meta"},
+	})
+	msg := &HookMessage{
+		Method:  "tools/list",
+		Message: []byte(`{"jsonrpc":"2.0","id":12345678901234567,"result":{"_meta":{"page":1},"tools":[{"name":"a","outputSchema":{}},{"name":"b"}]}}`),
+	}
+	if err := hook.AfterResponse(msg); err != nil {
+		t.Fatalf("AfterResponse failed: %v", err)
+	}
+	want := `{"id":12345678901234567,"jsonrpc":"2.0","result":{"tools":[{"annotations":{"readOnlyHint":true},"name":"a","title":"First"},{"annotations":{"readOnlyHint":true},"name":"b"}]}}`
+	if string(msg.Message) != want {
+		t.Errorf("Unexpected rewrite:\n got %s\nwant %s", msg.Message, want)
+	}
+
+	// Other methods, and requests, are left alone
+	other := &HookMessage{Method: "tools/call", Message: []byte(`{"result":{}}`)}
+	hook.AfterResponse(other)
+	hook.BeforeRequest(msg)
+	if string(other.Message) != `{"result":{}}` || string(msg.Message) != want {
+		t.Error("Expected the hook to apply to tools/list responses only")
+	}
+}
+
+func TestHooks(t *testing.T) {
+	hooks := NewHooks(&config.Config{MCPServers: map[string]config.MCPServer{"files": {Hooks: []config.Hook{
+		{Type: config.HookBlock, Tools: []string{"delete_*"}, Message: "Deleting is disabled"},
+		{Type: config.HookHeader, Methods: []string{"tools/*"}, Headers: map[string]string{"Cache-Control": "no-store"}},
+	}}}})
+
+	request := []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"delete_file","arguments":{}}}`)
+	err := hooks.BeforeRequest(newHookMessage("session", "files", request, nil))
+	if blocked, ok := err.(*BlockedError); !ok || blocked.Message != "Deleting is disabled" {
+		t.Errorf("Expected the call to be blocked, got %v", err)
+	}
+	if err := hooks.BeforeRequest(newHookMessage("session", "other", request, nil)); err != nil {
+		t.Errorf("Expected another server's calls to pass, got %v", err)
+	}
+
+	header := http.Header{}
+	msg := newHookMessage("session", "files", []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`), header)
+	if err := hooks.BeforeRequest(msg); err != nil {
+		t.Errorf("Expected tools/list to pass, got %v", err)
+	}
+	hooks.AfterResponse(msg)
+	if header.Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected the header hook to set Cache-Control, got %v", header)
+	}
+}
+
+// countingHook counts the messages it sees, as an embedder's hook would
+type countingHook struct {
+	requests, responses int
+}
+
+func (h *countingHook) BeforeRequest(msg *HookMessage) error {
+	h.requests++
+	return nil
+}
+
+func (h *countingHook) AfterResponse(msg *HookMessage) error {
+	h.responses++
+	return fmt.Errorf("failing hooks leave the message unchanged")
+}
+
+func TestHooksInProcess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	helper := helperMCPServerConfig()
+	helper.Hooks = []config.Hook{
+		{Type: config.HookBlock, Tools: []string{"slow"}, Message: "The slow tool is disabled"},
+		{Type: config.HookRewrite, Phase: config.HookPhaseRequest, Tools: []string{"echo"}, Set: map[string]interface{}{"params.arguments.text": "patched"}},
+		{Type: config.HookRewrite, Methods: []string{"tools/list"}, Remove: []string{"result._meta"}},
+	}
+	embedded, err := NewInProcess(&config.Config{MCPServers: map[string]config.MCPServer{"helper": helper}})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+	counter := &countingHook{}
+	embedded.Server.AddHook("helper", counter)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	response, err := client.CallTool(ctx, "slow", nil)
+	if err != nil || response.Error == nil || response.Error.Code != protocol.InvalidRequest || response.Error.Message != "The slow tool is disabled" {
+		t.Fatalf("Expected the blocked call to be refused, got %+v (%v)", response, err)
+	}
+
+	response, err = client.CallTool(ctx, "echo", map[string]interface{}{"text": "original"})
+	if err != nil || !strings.Contains(fmt.Sprint(response.Result), `echo:{"text":"patched"}`) {
+		t.Errorf("Expected the request to be rewritten, got %+v (%v)", response, err)
+	}
+
+	response, err = client.ListTools(ctx)
+	if err != nil || strings.Contains(fmt.Sprint(response.Result), "listCalls") {
+		t.Errorf("Expected _meta to be removed from tools/list, got %+v (%v)", response, err)
+	}
+
+	// The blocked call never reached the embedder's hook, which runs after the configured ones
+	if counter.requests != 2 || counter.responses != 2 {
+		t.Errorf("Expected the added hook to see 2 requests and responses, got %d and %d", counter.requests, counter.responses)
+	}
+}
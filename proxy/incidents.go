@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/state"
+)
+
+// defaultIncidentLimit bounds /admin/incidents responses without ?limit
+const defaultIncidentLimit = 100
+
+// handleIncidents returns the restart/crash/health/admin history, newest first
+//
+// Query parameters: server, kind, since and until (RFC 3339 time or a
+// duration ago such as "24h") and limit.
+func (s *Server) handleIncidents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := state.IncidentFilter{
+		Server: query.Get("server"),
+		Kind:   query.Get("kind"),
+		Limit:  defaultIncidentLimit,
+	}
+
+	var err error
+	if filter.Since, err = parseIncidentTime(query.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, err.Error())
+		return
+	}
+	if filter.Until, err = parseIncidentTime(query.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, err.Error())
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, fmt.Sprintf("Invalid limit %q", limit))
+			return
+		}
+	}
+
+	store := s.mcpManager.GetIncidentStore()
+	incidents := store.Incidents(filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"incidents": incidents,
+		"count":     len(incidents),
+		"enabled":   store != nil,
+	}); err != nil {
+		logger.System().Error("Failed to encode incidents response: %v", err)
+	}
+}
+
+// parseIncidentTime accepts an RFC 3339 timestamp or a duration before now
+func parseIncidentTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (expected RFC 3339 or a duration such as 24h)", value)
+}
+
+// adminActor identifies who performed an admin action for the incident history
+// Scripts can name themselves with the X-Admin-Actor header.
+func adminActor(r *http.Request) string {
+	if actor := r.Header.Get("X-Admin-Actor"); actor != "" {
+		return actor
+	}
+	if fingerprint := tokenFingerprint(r); fingerprint != "" {
+		return "admin:" + fingerprint
+	}
+	return "admin"
+}
@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/protocol"
+)
+
+// InProcess is a proxy running inside the calling Go program
+//
+// It exposes the same routes as the standalone binary through Handler, so it
+// can be mounted on an existing http.ServeMux, and hands out Clients that talk
+// to the proxy by invoking the handler directly — no sockets, ports or Docker
+// are involved, which makes it suitable for embedding and for fast tests.
+type InProcess struct {
+	Handler http.Handler
+	Server  *Server
+	Manager *mcp.Manager
+}
+
+// NewInProcess creates and starts an embedded proxy for the given configuration
+func NewInProcess(cfg *config.Config) (*InProcess, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("configuration is required")
+	}
+
+	mcpManager := mcp.NewManager(cfg.MCPServers)
+	if err := mcpManager.StartAll(); err != nil {
+		mcpManager.StopAll()
+		return nil, fmt.Errorf("failed to start MCP servers: %w", err)
+	}
+
+	server := NewServerWithConfig(mcpManager, cfg, nil, nil)
+
+	return &InProcess{
+		Handler: server.Router(),
+		Server:  server,
+		Manager: mcpManager,
+	}, nil
+}
+
+// Close stops all MCP servers started by the embedded proxy
+func (p *InProcess) Close() {
+	p.Manager.StopAll()
+}
+
+// NewClient returns a client bound to one MCP server with a fresh session
+func (p *InProcess) NewClient(serverName string) *Client {
+	return p.Server.newClient(p.Handler, serverName)
+}
+
+// inProcessClientID is the OAuth client of the tokens issued to in-process clients
+// In oidc mode they are the only proxy-issued tokens accepted, see authenticatePrincipal.
+const inProcessClientID = "in-process"
+
+// newClient returns a client of handler bound to one MCP server with a fresh session
+// The client authenticates with a token of its own, an API key in api-key mode
+// and an OAuth token otherwise, revoked when it is closed.
+func (s *Server) newClient(handler http.Handler, serverName string) *Client {
+	var token string
+	var revoke func(token string)
+	if s.authMode() == config.AuthModeAPIKey {
+		token, revoke = s.apiKeys.IssueClientKey(serverName), s.apiKeys.ForgetClientKey
+	} else {
+		token, revoke = s.oauth.IssueClientToken(inProcessClientID, s.tokenTTL()), s.oauth.RevokeToken
+	}
+
+	// Address the server the way the routing mode allows
+	path, host := "/"+serverName+"/sse", "localhost"
+	if !s.routeByPath() {
+		domain := "localhost"
+		if s.config != nil && s.config.GetDomain() != "" {
+			domain = s.config.GetDomain()
+		}
+		path, host = "/sse", serverName+".mcp."+domain
+	}
+
+	return &Client{
+		handler:    handler,
+		serverName: serverName,
+		path:       path,
+		host:       host,
+		sessionID:  generateRandomString(32),
+		token:      token,
+		onClose: func(sessionID string) {
+			s.translator.RemoveConnection(sessionID)
+			s.mcpManager.CleanupSession(sessionID)
+			revoke(token)
+		},
+	}
+}
+
+// Client is a programmatic Remote MCP client for an in-process proxy
+type Client struct {
+	handler    http.Handler
+	serverName string
+	path       string // Streamable HTTP endpoint of the server
+	host       string
+	sessionID  string
+	token      string
+	onClose    func(sessionID string)
+	nextID     int
+	mu         sync.Mutex
+}
+
+// SessionID returns the session this client uses
+func (c *Client) SessionID() string {
+	return c.sessionID
+}
+
+// Initialize performs the MCP initialize handshake
+func (c *Client) Initialize(ctx context.Context) (*protocol.JSONRPCMessage, error) {
+	return c.Call(ctx, "initialize", protocol.InitializeParams{
+		ProtocolVersion: protocol.MCPProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      protocol.ClientInfo{Name: "remote-mcp-proxy-inprocess", Version: protocol.ProxyServerVersion},
+	})
+}
+
+// ListTools calls tools/list
+func (c *Client) ListTools(ctx context.Context) (*protocol.JSONRPCMessage, error) {
+	return c.Call(ctx, "tools/list", map[string]interface{}{})
+}
+
+// CallTool calls tools/call with the given tool name and arguments
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*protocol.JSONRPCMessage, error) {
+	return c.Call(ctx, "tools/call", map[string]interface{}{
+		"name":      name,
+		"arguments": arguments,
+	})
+}
+
+// Call sends a JSON-RPC request through the proxy and returns the response
+func (c *Client) Call(ctx context.Context, method string, params interface{}) (*protocol.JSONRPCMessage, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.mu.Unlock()
+
+	body, err := json.Marshal(protocol.JSONRPCMessage{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.path, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Host = c.host
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Mcp-Session-Id", c.sessionID)
+
+	recorder := httptest.NewRecorder()
+	c.handler.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		return nil, fmt.Errorf("proxy returned HTTP %d: %s", recorder.Code, strings.TrimSpace(recorder.Body.String()))
+	}
+
+	var response protocol.JSONRPCMessage
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &response, nil
+}
+
+// Close ends the client's session and stops its server instances
+func (c *Client) Close() {
+	if c.onClose != nil {
+		c.onClose(c.sessionID)
+	}
+}
@@ -0,0 +1,811 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/protocol"
+)
+
+// helperMCPServerConfig returns a config that runs this test binary as a minimal stdio MCP server
+func helperMCPServerConfig() config.MCPServer {
+	return config.MCPServer{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperMCPServer"},
+		Env:     map[string]string{"GO_WANT_HELPER_MCP_SERVER": "1"},
+	}
+}
+
+// TestHelperMCPServer is not a real test: it is the MCP server process used by helperMCPServerConfig
+func TestHelperMCPServer(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_MCP_SERVER") != "1" {
+		return
+	}
+
+	listCalls := 0
+	var cancelled []interface{}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			continue
+		}
+		if _, hasID := request["id"]; !hasID {
+			if request["method"] == "notifications/cancelled" {
+				cancelled = append(cancelled, request["params"])
+			}
+			continue // Notifications get no response
+		}
+
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": request["id"]}
+		params, _ := request["params"].(map[string]interface{})
+		var after []byte
+
+		switch request["method"] {
+		case "initialize":
+			capabilities := map[string]interface{}{"tools": map[string]interface{}{}}
+			if os.Getenv("GO_HELPER_RESOURCES") == "1" {
+				capabilities["resources"] = map[string]interface{}{}
+			}
+			if os.Getenv("GO_HELPER_PROMPTS") == "1" {
+				capabilities["prompts"] = map[string]interface{}{}
+				capabilities["completions"] = map[string]interface{}{}
+			}
+			response["result"] = map[string]interface{}{
+				"protocolVersion": "2024-11-05",
+				"capabilities":    capabilities,
+				"serverInfo":      map[string]interface{}{"name": "helper", "version": "1.0.0"},
+			}
+		case "resources/list":
+			response["result"] = map[string]interface{}{
+				"resources": []interface{}{map[string]interface{}{"uri": "helper://greeting", "name": "greeting"}},
+			}
+		case "prompts/list":
+			response["result"] = map[string]interface{}{
+				"prompts": []interface{}{map[string]interface{}{
+					"name":      "greet",
+					"arguments": []interface{}{map[string]interface{}{"name": "language", "required": true}},
+				}},
+			}
+		case "prompts/get":
+			arguments, _ := params["arguments"].(map[string]interface{})
+			response["result"] = map[string]interface{}{
+				"messages": []interface{}{map[string]interface{}{
+					"role":    "user",
+					"content": map[string]interface{}{"type": "text", "text": fmt.Sprintf("Greet me in %v", arguments["language"])},
+				}},
+			}
+		case "completion/complete":
+			argument, _ := params["argument"].(map[string]interface{})
+			var values []interface{}
+			for _, language := range []string{"English", "Esperanto", "French"} {
+				if strings.HasPrefix(language, fmt.Sprint(argument["value"])) {
+					values = append(values, language)
+				}
+			}
+			response["result"] = map[string]interface{}{
+				"completion": map[string]interface{}{"values": values, "hasMore": false},
+			}
+		case "resources/read":
+			if params["uri"] == "helper://greeting" {
+				response["result"] = map[string]interface{}{
+					"contents": []interface{}{map[string]interface{}{"uri": "helper://greeting", "mimeType": "text/plain", "text": "Hello"}},
+				}
+			} else {
+				response["error"] = map[string]interface{}{"code": -32002, "message": "Resource not found"}
+			}
+		case "resources/subscribe":
+			// Report a change once the subscription is answered, while no request is in flight
+			response["result"] = map[string]interface{}{}
+			after, _ = json.Marshal(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"method":  "notifications/resources/updated",
+				"params":  map[string]interface{}{"uri": params["uri"]},
+			})
+		case "resources/unsubscribe", "ping":
+			response["result"] = map[string]interface{}{}
+		case "tools/list":
+			listCalls++
+			response["result"] = map[string]interface{}{
+				"_meta": map[string]interface{}{"listCalls": listCalls},
+				"tools": []interface{}{
+					map[string]interface{}{"name": "echo", "description": "Echo the arguments", "inputSchema": map[string]interface{}{
+						"type":       "object",
+						"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+					}},
+				},
+			}
+		case "tools/call":
+			if params["name"] == "slow" {
+				// Report the call started, then never answer; only a cancellation ends the wait
+				os.Stdout.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/message","params":{"level":"info","data":"started"}}` + "\n"))
+				continue
+			}
+			if params["name"] == "cancelled" {
+				// Report the cancellations received so far
+				received, _ := json.Marshal(cancelled)
+				response["result"] = map[string]interface{}{
+					"content": []interface{}{map[string]interface{}{"type": "text", "text": string(received)}},
+				}
+				break
+			}
+			if meta, _ := params["_meta"].(map[string]interface{}); params["name"] == "progress" && meta != nil {
+				// Report halfway progress ahead of the response
+				progress, _ := json.Marshal(map[string]interface{}{
+					"jsonrpc": "2.0",
+					"method":  "notifications/progress",
+					"params":  map[string]interface{}{"progressToken": meta["progressToken"], "progress": 1, "total": 2},
+				})
+				os.Stdout.Write(append(progress, '\n'))
+			}
+			if params["name"] == "changed" {
+				// Announce a tool list change ahead of the response
+				os.Stdout.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}` + "\n"))
+			}
+			arguments, _ := json.Marshal(params["arguments"])
+			response["result"] = map[string]interface{}{
+				"content": []interface{}{
+					map[string]interface{}{"type": "text", "text": fmt.Sprintf("%v:%s", params["name"], arguments)},
+				},
+			}
+		default:
+			response["error"] = map[string]interface{}{"code": -32601, "message": "Method not found"}
+		}
+
+		line, _ := json.Marshal(response)
+		os.Stdout.Write(append(line, '\n'))
+		if after != nil {
+			os.Stdout.Write(append(after, '\n'))
+		}
+	}
+	os.Exit(0)
+}
+
+func TestInProcessClient(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := client.ListTools(ctx); err != nil {
+		// Requests before initialize are rejected with a JSON-RPC error, not a transport error
+		t.Fatalf("Expected JSON-RPC response before initialize, got %v", err)
+	}
+
+	initResponse, err := client.Initialize(ctx)
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if initResponse.Error != nil || initResponse.Result == nil {
+		t.Fatalf("Unexpected initialize response: %+v", initResponse)
+	}
+
+	toolsResponse, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("tools/list failed: %v", err)
+	}
+	result, _ := toolsResponse.Result.(map[string]interface{})
+	tools, _ := result["tools"].([]interface{})
+	if len(tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %+v", toolsResponse.Result)
+	}
+
+	callResponse, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "hi"})
+	if err != nil {
+		t.Fatalf("tools/call failed: %v", err)
+	}
+	encoded, _ := json.Marshal(callResponse.Result)
+	if want := `echo:{\"text\":\"hi\"}`; !strings.Contains(string(encoded), want) {
+		t.Errorf("Expected tool result to contain %s, got %s", want, encoded)
+	}
+
+	// Arguments violating the cached inputSchema never reach the server
+	invalidResponse, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": 42})
+	if err != nil {
+		t.Fatalf("tools/call failed: %v", err)
+	}
+	if invalidResponse.Error == nil || invalidResponse.Error.Code != protocol.InvalidParams ||
+		!strings.Contains(invalidResponse.Error.Message, "arguments.text: expected string, got integer") {
+		t.Errorf("Expected InvalidParams error with details, got %+v", invalidResponse)
+	}
+}
+
+func TestToolFilterHidesBlockedTools(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	helper := helperMCPServerConfig()
+	helper.BlockedTools = []string{"ec*"}
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"helper": helper},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	toolsResponse, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("tools/list failed: %v", err)
+	}
+	result, _ := toolsResponse.Result.(map[string]interface{})
+	if tools, _ := result["tools"].([]interface{}); len(tools) != 0 {
+		t.Errorf("Expected blocked tool to be hidden, got %+v", tools)
+	}
+
+	callResponse, err := client.CallTool(ctx, "echo", nil)
+	if err != nil {
+		t.Fatalf("tools/call failed: %v", err)
+	}
+	if callResponse.Error == nil || callResponse.Error.Code != protocol.InvalidParams {
+		t.Errorf("Expected InvalidParams error for blocked tool, got %+v", callResponse)
+	}
+}
+
+func TestResponseCacheInvalidation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		ResponseCacheTTL: time.Minute,
+		MCPServers:       map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	listCalls := func() float64 {
+		response, err := client.ListTools(ctx)
+		if err != nil {
+			t.Fatalf("tools/list failed: %v", err)
+		}
+		result, _ := response.Result.(map[string]interface{})
+		meta, _ := result["_meta"].(map[string]interface{})
+		calls, _ := meta["listCalls"].(float64)
+		return calls
+	}
+
+	if first, second := listCalls(), listCalls(); first != 1 || second != 1 {
+		t.Errorf("Expected second tools/list to be served from cache, server saw %v then %v calls", first, second)
+	}
+
+	// The list_changed notification arrives ahead of the tool result and is not mistaken for it
+	callResponse, err := client.CallTool(ctx, "changed", nil)
+	if err != nil || callResponse.Error != nil || callResponse.Result == nil {
+		t.Fatalf("Expected tool result after notification, got %+v (%v)", callResponse, err)
+	}
+
+	if calls := listCalls(); calls != 2 {
+		t.Errorf("Expected tools/list to reach the server after list_changed, server saw %v calls", calls)
+	}
+}
+
+func TestResourcesPassthrough(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	withResources := helperMCPServerConfig()
+	withResources.Env = map[string]string{"GO_WANT_HELPER_MCP_SERVER": "1", "GO_HELPER_RESOURCES": "1"}
+
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{
+			"helper":    helperMCPServerConfig(),
+			"resources": withResources,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	listResources := func(serverName string) []interface{} {
+		client := embedded.NewClient(serverName)
+		defer client.Close()
+
+		initialized, err := client.Initialize(ctx)
+		if err != nil {
+			t.Fatalf("Initialize of %s failed: %v", serverName, err)
+		}
+		// Clients are told what the server really supports
+		initResult, _ := initialized.Result.(map[string]interface{})
+		capabilities, _ := initResult["capabilities"].(map[string]interface{})
+		if _, announced := capabilities["resources"]; announced != (serverName == "resources") {
+			t.Errorf("Expected %s to announce resources only if its server does, got %v", serverName, capabilities)
+		}
+
+		response, err := client.Call(ctx, "resources/list", nil)
+		if err != nil || response.Error != nil {
+			t.Fatalf("resources/list on %s failed: %+v (%v)", serverName, response, err)
+		}
+		result, _ := response.Result.(map[string]interface{})
+		resources, _ := result["resources"].([]interface{})
+		return resources
+	}
+
+	// A server announcing resources answers for itself
+	if resources := listResources("resources"); len(resources) != 1 {
+		t.Errorf("Expected the server's resource to be forwarded, got %v", resources)
+	}
+	// One that does not gets the proxy's empty list
+	if resources := listResources("helper"); len(resources) != 0 {
+		t.Errorf("Expected an empty fallback list, got %v", resources)
+	}
+}
+
+func TestPromptsPassthrough(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	withPrompts := helperMCPServerConfig()
+	withPrompts.Env = map[string]string{"GO_WANT_HELPER_MCP_SERVER": "1", "GO_HELPER_PROMPTS": "1"}
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{
+			"helper":  helperMCPServerConfig(),
+			"prompts": withPrompts,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("prompts")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	response, err := client.Call(ctx, "prompts/get", map[string]interface{}{
+		"name":      "greet",
+		"arguments": map[string]interface{}{"language": "French"},
+	})
+	if err != nil || response.Error != nil {
+		t.Fatalf("prompts/get failed: %+v (%v)", response, err)
+	}
+	if encoded, _ := json.Marshal(response.Result); !strings.Contains(string(encoded), "Greet me in French") {
+		t.Errorf("Expected the server's prompt, got %s", encoded)
+	}
+
+	completions := func(client *Client) []interface{} {
+		response, err := client.Call(ctx, "completion/complete", map[string]interface{}{
+			"ref":      map[string]interface{}{"type": "ref/prompt", "name": "greet"},
+			"argument": map[string]interface{}{"name": "language", "value": "E"},
+		})
+		if err != nil || response.Error != nil {
+			t.Fatalf("completion/complete failed: %+v (%v)", response, err)
+		}
+		result, _ := response.Result.(map[string]interface{})
+		completion, _ := result["completion"].(map[string]interface{})
+		values, _ := completion["values"].([]interface{})
+		return values
+	}
+
+	if values := completions(client); len(values) != 2 || values[0] != "English" || values[1] != "Esperanto" {
+		t.Errorf("Expected the server's completions, got %v", values)
+	}
+
+	// A server without prompts gets an empty completion rather than an error
+	plain := embedded.NewClient("helper")
+	defer plain.Close()
+	if _, err := plain.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if values := completions(plain); len(values) != 0 {
+		t.Errorf("Expected no completions from the fallback, got %v", values)
+	}
+}
+
+func TestResourceURIRewriting(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	withResources := helperMCPServerConfig()
+	withResources.Env = map[string]string{"GO_WANT_HELPER_MCP_SERVER": "1", "GO_HELPER_RESOURCES": "1"}
+	withResources.RewriteResourceURIs = true
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"resources": withResources},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("resources")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	response, err := client.Call(ctx, "resources/list", nil)
+	if err != nil || response.Error != nil {
+		t.Fatalf("resources/list failed: %+v (%v)", response, err)
+	}
+	result, _ := response.Result.(map[string]interface{})
+	resources, _ := result["resources"].([]interface{})
+	resource, _ := resources[0].(map[string]interface{})
+	proxyURL, _ := resource["uri"].(string)
+	if !strings.HasPrefix(proxyURL, "http://localhost/resources/resources/") {
+		t.Fatalf("Expected a proxy URL, got %q", proxyURL)
+	}
+
+	// The proxy URL reads the backend's resource
+	response, err = client.Call(ctx, "resources/read", map[string]interface{}{"uri": proxyURL})
+	if err != nil || response.Error != nil {
+		t.Fatalf("resources/read of %s failed: %+v (%v)", proxyURL, response, err)
+	}
+	if encoded, _ := json.Marshal(response.Result); !strings.Contains(string(encoded), "Hello") || strings.Contains(string(encoded), "helper://") {
+		t.Errorf("Expected the resource with a proxy URL, got %s", encoded)
+	}
+
+	// And is served over plain HTTP to authenticated clients
+	path := strings.TrimPrefix(proxyURL, "http://localhost")
+	for _, tt := range []struct {
+		token  string
+		status int
+	}{{"", http.StatusUnauthorized}, {"token", http.StatusOK}} {
+		req := httptest.NewRequest("GET", path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rr := httptest.NewRecorder()
+		embedded.Handler.ServeHTTP(rr, req)
+		if rr.Code != tt.status {
+			t.Fatalf("Expected HTTP %d for %s, got %d: %s", tt.status, path, rr.Code, rr.Body.String())
+		}
+		if rr.Code == http.StatusOK && (rr.Body.String() != "Hello" || rr.Header().Get("Content-Type") != "text/plain") {
+			t.Errorf("Expected the text resource, got %q (%s)", rr.Body.String(), rr.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestResourceSubscription(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	withResources := helperMCPServerConfig()
+	withResources.Env = map[string]string{"GO_WANT_HELPER_MCP_SERVER": "1", "GO_HELPER_RESOURCES": "1"}
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"resources": withResources},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("resources")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// Stand in for the session's SSE stream
+	notify := embedded.Server.sseEvents.Attach(client.SessionID())
+
+	response, err := client.Call(ctx, "resources/subscribe", map[string]interface{}{"uri": "helper://greeting"})
+	if err != nil || response.Error != nil {
+		t.Fatalf("resources/subscribe failed: %+v (%v)", response, err)
+	}
+	if got := embedded.Server.translator.Subscriptions(client.SessionID()); len(got) != 1 || got[0] != "helper://greeting" {
+		t.Errorf("Expected the subscription to be tracked, got %v", got)
+	}
+
+	// The update arrives after the response, while the server is idle
+	select {
+	case <-notify:
+	case <-ctx.Done():
+		t.Fatal("Expected the resource update to be relayed to the session")
+	}
+	events := embedded.Server.sseEvents.Since(client.SessionID(), 0)
+	if len(events) != 1 || !strings.Contains(events[0].data, "notifications/resources/updated") {
+		t.Fatalf("Expected one resources/updated event, got %+v", events)
+	}
+
+	response, err = client.Call(ctx, "resources/unsubscribe", map[string]interface{}{"uri": "helper://greeting"})
+	if err != nil || response.Error != nil {
+		t.Fatalf("resources/unsubscribe failed: %+v (%v)", response, err)
+	}
+	if got := embedded.Server.translator.Subscriptions(client.SessionID()); len(got) != 0 {
+		t.Errorf("Expected the subscription to be dropped, got %v", got)
+	}
+}
+
+func TestWarmPool(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	helper := helperMCPServerConfig()
+	helper.WarmPool = 1
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"helper": helper},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	waitForWarm := func() {
+		deadline := time.Now().Add(10 * time.Second)
+		for embedded.Manager.WarmInstances("helper") != 1 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Warm pool was not filled")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitForWarm()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response, err := client.Initialize(ctx)
+	if err != nil || response.Error != nil {
+		t.Fatalf("Initialize failed: %+v (%v)", response, err)
+	}
+
+	statuses := embedded.Manager.GetSessionServers(client.SessionID())
+	if len(statuses) != 1 || !statuses[0].Prewarmed || !statuses[0].Running {
+		t.Errorf("Expected session to claim a running pre-warmed instance, got %+v", statuses)
+	}
+
+	if _, err := client.ListTools(ctx); err != nil {
+		t.Errorf("tools/list on pre-warmed instance failed: %v", err)
+	}
+
+	// The claimed instance is replaced in the background
+	waitForWarm()
+}
+
+func TestSessionModes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	shared := helperMCPServerConfig()
+	shared.Mode = config.ModeShared
+	pooled := helperMCPServerConfig()
+	pooled.Mode = config.ModePool
+	pooled.MaxInstances = 1
+
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"shared": shared, "pooled": pooled},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Two sessions per server, each completing its own handshake and a tool call
+	sessionPIDs := func(serverName string) []int {
+		var pids []int
+		for i := 0; i < 2; i++ {
+			client := embedded.NewClient(serverName)
+			defer client.Close()
+
+			if response, err := client.Initialize(ctx); err != nil || response.Error != nil {
+				t.Fatalf("Initialize on %s failed: %+v (%v)", serverName, response, err)
+			}
+			if response, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "hi"}); err != nil || response.Error != nil {
+				t.Fatalf("tools/call on %s failed: %+v (%v)", serverName, response, err)
+			}
+
+			server, exists := embedded.Manager.GetServerForSession(client.SessionID(), serverName)
+			if !exists {
+				t.Fatalf("No %s server for session", serverName)
+			}
+			pids = append(pids, server.PID())
+		}
+		return pids
+	}
+
+	global, _ := embedded.Manager.GetServer("shared")
+	if pids := sessionPIDs("shared"); pids[0] != global.PID() || pids[1] != global.PID() {
+		t.Errorf("Expected shared sessions to use the global process %d, got %v", global.PID(), pids)
+	}
+	if pids := sessionPIDs("pooled"); pids[0] != pids[1] || pids[0] == 0 {
+		t.Errorf("Expected pooled sessions to share one instance, got %v", pids)
+	}
+}
+
+func TestFailoverToFallback(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	helper := helperMCPServerConfig()
+	fallback := helperMCPServerConfig()
+	helper.Fallback = &fallback
+
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"helper": helper},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if changed, err := embedded.Manager.SetFailover("helper", true); !changed || err != nil {
+		t.Fatalf("Expected failover, got changed=%v err=%v", changed, err)
+	}
+	standby, _ := embedded.Manager.GetActiveServer("helper")
+	if primary, _ := embedded.Manager.GetServer("helper"); standby == primary {
+		t.Fatalf("Expected active server to be the fallback while failed over")
+	}
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if response, err := client.Initialize(ctx); err != nil || response.Error != nil {
+		t.Fatalf("Initialize failed: %+v (%v)", response, err)
+	}
+	if response, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "hi"}); err != nil || response.Error != nil {
+		t.Fatalf("tools/call failed: %+v (%v)", response, err)
+	}
+	if server, _ := embedded.Manager.GetServerForSession(client.SessionID(), "helper"); server != standby {
+		t.Errorf("Expected new session to be routed to the fallback")
+	}
+
+	if changed, err := embedded.Manager.SetFailover("helper", false); !changed || err != nil {
+		t.Fatalf("Expected failback, got changed=%v err=%v", changed, err)
+	}
+	other := embedded.NewClient("helper")
+	defer other.Close()
+	if server, _ := embedded.Manager.GetServerForSession(other.SessionID(), "helper"); server == standby {
+		t.Errorf("Expected new sessions to use the primary after failback")
+	}
+}
+
+func TestProgressPassthrough(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// Stand in for the session's SSE stream
+	embedded.Server.sseEvents.Attach(client.SessionID())
+
+	response, err := client.Call(ctx, "tools/call", map[string]interface{}{
+		"name":      "progress",
+		"arguments": map[string]interface{}{},
+		"_meta":     map[string]interface{}{"progressToken": "crawl-1"},
+	})
+	if err != nil || response.Error != nil {
+		t.Fatalf("tools/call failed: %+v (%v)", response, err)
+	}
+
+	events := embedded.Server.sseEvents.Since(client.SessionID(), 0)
+	if len(events) != 1 || !strings.Contains(events[0].data, "notifications/progress") {
+		t.Fatalf("Expected one progress event, got %+v", events)
+	}
+	if !strings.Contains(events[0].data, `"progressToken":"crawl-1"`) {
+		t.Errorf("Expected the client's own progress token, got %s", events[0].data)
+	}
+}
+
+func TestMessageSizeLimits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	limited := helperMCPServerConfig()
+	limited.MaxRequestBytes = 2000
+	limited.MaxResponseBytes = 500
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"helper": limited},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// The echoed text makes the response too large, but not the request
+	response, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": strings.Repeat("x", 1000)})
+	if err != nil || response.Error == nil || !strings.Contains(response.Error.Message, "size limit") {
+		t.Fatalf("Expected a size limit error for the response, got %+v (%v)", response, err)
+	}
+
+	// The oversized response was skipped, so the next one is read intact
+	response, err = client.CallTool(ctx, "echo", map[string]interface{}{"text": "small"})
+	if err != nil || response.Error != nil {
+		t.Fatalf("Expected the next call to succeed, got %+v (%v)", response, err)
+	}
+
+	_, err = client.CallTool(ctx, "echo", map[string]interface{}{"text": strings.Repeat("x", 3000)})
+	if err == nil || !strings.Contains(err.Error(), "HTTP 413") || !strings.Contains(err.Error(), "2000 byte size limit") {
+		t.Errorf("Expected the oversized request to be rejected with HTTP 413, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+
+	"remote-mcp-proxy/logger"
+)
+
+// landingServer is one integration listed on the landing page
+type landingServer struct {
+	Name     string
+	Endpoint string
+	Health   string
+}
+
+var landingTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 46rem; margin: 3rem auto; padding: 0 1rem; color: #222; }
+code { background: #f3f3f3; padding: .2rem .4rem; border-radius: 4px; user-select: all; }
+li { margin: .8rem 0; }
+.healthy { color: #1a7f37; } .unhealthy { color: #cf222e; } .unknown { color: #9a6700; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Add an integration in Claude.ai (Settings &rarr; Integrations) with the endpoint below.
+The same URL accepts SSE (GET) and Streamable HTTP (POST) clients.</p>
+<ul>
+{{range .Servers}}<li><strong>{{.Name}}</strong> <span class="{{.Health}}">{{.Health}}</span><br><code>{{.Endpoint}}</code></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// handleLanding serves the informational page at / with connection instructions
+//
+// On a server subdomain only that server is listed; on the apex domain (or
+// localhost) every configured server is. Disabled with LANDING_PAGE=false.
+func (s *Server) handleLanding(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil || !s.config.LandingPage {
+		http.NotFound(w, r)
+		return
+	}
+
+	baseURL := s.publicBaseURL(r)
+
+	var names []string
+	title := "Remote MCP Proxy"
+	serverName, onSubdomain := r.Context().Value("mcpServer").(string)
+	if onSubdomain && serverName != "" {
+		names = []string{serverName}
+		title = fmt.Sprintf("%s MCP Server", serverName)
+	} else {
+		for name := range s.config.MCPServers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if s.isAggregateServer(AggregateServerName) {
+			names = append(names, AggregateServerName)
+		}
+	}
+
+	var servers []landingServer
+	for _, name := range names {
+		display := name
+		if serverConfig, exists := s.config.MCPServers[name]; exists && serverConfig.DisplayName != "" {
+			display = serverConfig.DisplayName
+		}
+		servers = append(servers, landingServer{
+			Name:     display,
+			Endpoint: s.landingEndpoint(baseURL, name, onSubdomain),
+			Health:   s.landingHealth(name),
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := landingTemplate.Execute(w, map[string]interface{}{
+		"Title":   title,
+		"Servers": servers,
+	}); err != nil {
+		logger.System().Error("Failed to render landing page: %v", err)
+	}
+}
+
+// landingEndpoint returns the URL to paste into Claude.ai for a server
+func (s *Server) landingEndpoint(baseURL, serverName string, onSubdomain bool) string {
+	if onSubdomain {
+		return baseURL + "/sse"
+	}
+	if hostnames := s.config.MCPServers[serverName].Hostnames; len(hostnames) > 0 && s.routeBySubdomain() {
+		return fmt.Sprintf("https://%s/sse", hostnames[0])
+	}
+	if basePath := s.config.MCPServers[serverName].BasePath; basePath != "" {
+		return baseURL + basePath + "/sse"
+	}
+	if domain := s.config.GetDomain(); domain != "" && domain != "localhost" && s.routeBySubdomain() {
+		return fmt.Sprintf("https://%s.mcp.%s/sse", serverName, domain)
+	}
+	return fmt.Sprintf("%s/%s/sse", baseURL, serverName)
+}
+
+// landingHealth summarizes a server's health as healthy, unhealthy or unknown
+func (s *Server) landingHealth(serverName string) string {
+	if serverName == AggregateServerName && s.isAggregateServer(serverName) {
+		return "healthy"
+	}
+	if s.healthChecker != nil {
+		if health, exists := s.healthChecker.GetServerHealth(serverName); exists {
+			return health.Status
+		}
+	}
+	if server, exists := s.mcpManager.GetServer(serverName); exists {
+		if server.IsRunning() {
+			return "healthy"
+		}
+		return "unhealthy"
+	}
+	return "unknown"
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+func TestLandingPage(t *testing.T) {
+	cfg := &config.Config{
+		Domain:          "example.com",
+		LandingPage:     true,
+		AggregateServer: true,
+		MCPServers: map[string]config.MCPServer{
+			"memory": {Command: "echo"},
+			"notion": {Command: "echo"},
+		},
+	}
+	router := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil).Router()
+
+	tests := []struct {
+		name     string
+		host     string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "apex lists every server",
+			host:     "example.com",
+			contains: []string{"https://memory.mcp.example.com/sse", "https://notion.mcp.example.com/sse", "https://all.mcp.example.com/sse"},
+		},
+		{
+			name:     "subdomain lists its server",
+			host:     "memory.mcp.example.com",
+			contains: []string{"memory MCP Server", "http://memory.mcp.example.com/sse"},
+			excludes: []string{"notion"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Host = tt.host
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rr.Code)
+			}
+			body := rr.Body.String()
+			for _, want := range tt.contains {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected landing page to contain %q", want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(body, unwanted) {
+					t.Errorf("Expected landing page not to contain %q", unwanted)
+				}
+			}
+		})
+	}
+
+	cfg.LandingPage = false
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 with landing page disabled, got %d", rr.Code)
+	}
+}
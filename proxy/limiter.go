@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSessionBusy is returned when a session already has the maximum number of
+// tool calls running and its wait queue is full
+var ErrSessionBusy = errors.New("session busy: too many concurrent tool calls")
+
+// toolCallSlotWait bounds how long a tools/call waits in its session's queue
+// Its request timeout only starts once it has a slot.
+const toolCallSlotWait = 30 * time.Second
+
+// ToolCallLimiter bounds the number of concurrent tools/call requests per session
+//
+// A single session issuing many parallel tool calls can otherwise monopolise a
+// shared MCP backend. Requests beyond maxConcurrent wait in a per-session queue;
+// once maxQueued requests are already waiting, new requests are rejected with
+// ErrSessionBusy so the client gets a structured error instead of a hang.
+type ToolCallLimiter struct {
+	maxConcurrent int
+	maxQueued     int
+	sessions      map[string]*sessionSlots
+	mu            sync.Mutex
+}
+
+// sessionSlots tracks the running and waiting tool calls of one session
+type sessionSlots struct {
+	slots   chan struct{}
+	waiting int
+	refs    int // running + waiting, used to drop idle sessions
+}
+
+// NewToolCallLimiter creates a limiter allowing maxConcurrent running and maxQueued waiting calls per session
+func NewToolCallLimiter(maxConcurrent, maxQueued int) *ToolCallLimiter {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	return &ToolCallLimiter{
+		maxConcurrent: maxConcurrent,
+		maxQueued:     maxQueued,
+		sessions:      make(map[string]*sessionSlots),
+	}
+}
+
+// Acquire reserves a tool call slot for the session, waiting in the queue if necessary.
+// The returned release function must be called once the call has completed.
+func (l *ToolCallLimiter) Acquire(ctx context.Context, sessionID string) (func(), error) {
+	l.mu.Lock()
+	state, exists := l.sessions[sessionID]
+	if !exists {
+		state = &sessionSlots{slots: make(chan struct{}, l.maxConcurrent)}
+		l.sessions[sessionID] = state
+	}
+
+	// Fast path: a slot is free
+	select {
+	case state.slots <- struct{}{}:
+		state.refs++
+		l.mu.Unlock()
+		return l.releaseFunc(sessionID, state), nil
+	default:
+	}
+
+	// Slow path: queue if there is room, otherwise reject
+	if state.waiting >= l.maxQueued {
+		if state.refs == 0 {
+			delete(l.sessions, sessionID)
+		}
+		l.mu.Unlock()
+		return nil, ErrSessionBusy
+	}
+	state.waiting++
+	state.refs++
+	l.mu.Unlock()
+
+	select {
+	case state.slots <- struct{}{}:
+		l.mu.Lock()
+		state.waiting--
+		l.mu.Unlock()
+		return l.releaseFunc(sessionID, state), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		state.waiting--
+		l.unref(sessionID, state)
+		l.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// releaseFunc returns an idempotent function that frees the slot held by a call
+func (l *ToolCallLimiter) releaseFunc(sessionID string, state *sessionSlots) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-state.slots
+			l.mu.Lock()
+			l.unref(sessionID, state)
+			l.mu.Unlock()
+		})
+	}
+}
+
+// unref drops a reference and forgets the session once nothing is running or waiting
+// NOTE: This method must be called with l.mu locked
+func (l *ToolCallLimiter) unref(sessionID string, state *sessionSlots) {
+	state.refs--
+	if state.refs == 0 && l.sessions[sessionID] == state {
+		delete(l.sessions, sessionID)
+	}
+}
+
+// Stats returns the number of running and waiting tool calls for a session
+func (l *ToolCallLimiter) Stats(sessionID string) (running, waiting int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, exists := l.sessions[sessionID]
+	if !exists {
+		return 0, 0
+	}
+	return len(state.slots), state.waiting
+}
+
+// MaxConcurrent returns the per-session concurrency limit
+func (l *ToolCallLimiter) MaxConcurrent() int {
+	return l.maxConcurrent
+}
+
+// MaxQueued returns the per-session queue depth
+func (l *ToolCallLimiter) MaxQueued() int {
+	return l.maxQueued
+}
@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/protocol"
+)
+
+func TestToolCallLimiterConcurrency(t *testing.T) {
+	limiter := NewToolCallLimiter(2, 1)
+	ctx := context.Background()
+
+	release1, err := limiter.Acquire(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Expected first acquire to succeed, got %v", err)
+	}
+	release2, err := limiter.Acquire(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("Expected second acquire to succeed, got %v", err)
+	}
+
+	// Other sessions are not affected by session-1's usage
+	releaseOther, err := limiter.Acquire(ctx, "session-2")
+	if err != nil {
+		t.Fatalf("Expected acquire for another session to succeed, got %v", err)
+	}
+	releaseOther()
+
+	// Third call queues until a slot is released
+	acquired := make(chan func(), 1)
+	go func() {
+		release, err := limiter.Acquire(ctx, "session-1")
+		if err != nil {
+			t.Errorf("Expected queued acquire to succeed, got %v", err)
+			return
+		}
+		acquired <- release
+	}()
+
+	// Wait for the goroutine to be queued
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, waiting := limiter.Stats("session-1"); waiting == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected one queued tool call")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Queue is full, so a fourth call is rejected
+	if _, err := limiter.Acquire(ctx, "session-1"); err != ErrSessionBusy {
+		t.Errorf("Expected ErrSessionBusy, got %v", err)
+	}
+
+	release1()
+	select {
+	case release3 := <-acquired:
+		release3()
+	case <-time.After(time.Second):
+		t.Fatal("Expected queued call to acquire after release")
+	}
+
+	release2()
+	release2() // release is idempotent
+
+	if running, waiting := limiter.Stats("session-1"); running != 0 || waiting != 0 {
+		t.Errorf("Expected idle session, got %d running, %d waiting", running, waiting)
+	}
+	if len(limiter.sessions) != 0 {
+		t.Errorf("Expected idle sessions to be dropped, got %d", len(limiter.sessions))
+	}
+}
+
+func TestToolCallLimiterContextCancellation(t *testing.T) {
+	limiter := NewToolCallLimiter(1, 4)
+
+	release, err := limiter.Acquire(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("Expected acquire to succeed, got %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := limiter.Acquire(ctx, "session-1"); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if _, waiting := limiter.Stats("session-1"); waiting != 0 {
+		t.Errorf("Expected cancelled call to leave the queue, got %d waiting", waiting)
+	}
+}
+
+func TestToolCallSlotWaitDoesNotShortenTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		AggregateServer:        true,
+		MCPServers:             map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+		Timeouts:               map[string]string{"tools/call": "500ms"},
+		MaxConcurrentToolCalls: 1,
+		MaxQueuedToolCalls:     1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	// callTool sends the call through the SSE endpoint, or the session endpoint when session is set
+	callTool := func(ctx context.Context, client *Client, name string, session bool) (*protocol.JSONRPCMessage, error) {
+		if !session {
+			return client.CallTool(ctx, name, map[string]interface{}{"text": "hi"})
+		}
+		body := `{"jsonrpc":"2.0","id":"queued","method":"tools/call","params":{"name":"` + name + `","arguments":{"text":"hi"}}}`
+		req := httptest.NewRequest("POST", "/helper/sessions/"+client.SessionID(), strings.NewReader(body)).WithContext(ctx)
+		req.Host = "localhost"
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+client.token)
+		recorder := httptest.NewRecorder()
+		embedded.Handler.ServeHTTP(recorder, req)
+		var response protocol.JSONRPCMessage
+		err := json.Unmarshal(recorder.Body.Bytes(), &response)
+		return &response, err
+	}
+
+	tests := []struct {
+		name    string
+		server  string
+		tool    string
+		session bool
+	}{
+		{"sse endpoint", "helper", "echo", false},
+		{"session endpoint", "helper", "echo", true},
+		{"aggregate", AggregateServerName, "helper__echo", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			client := embedded.NewClient(tt.server)
+			defer client.Close()
+			if _, err := client.Initialize(ctx); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+
+			// Hold the session's only slot for longer than the tools/call timeout
+			release, err := embedded.Server.toolCallLimiter.Acquire(ctx, client.SessionID())
+			if err != nil {
+				t.Fatalf("Failed to hold the slot: %v", err)
+			}
+			go func() {
+				time.Sleep(700 * time.Millisecond)
+				release()
+			}()
+
+			response, err := callTool(ctx, client, tt.tool, tt.session)
+			if err != nil || response.Error != nil || response.Result == nil {
+				t.Fatalf("Expected the queued call to get its whole timeout once it had a slot, got %+v (%v)", response, err)
+			}
+		})
+	}
+}
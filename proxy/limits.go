@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/protocol"
+)
+
+// maxRequestBytes returns the size limit for client messages to a server (unlimited when 0)
+func (s *Server) maxRequestBytes(serverName string) int64 {
+	if s.config == nil {
+		return 0
+	}
+	return s.config.GetMaxRequestBytes(serverName)
+}
+
+// readMessageBody reads a client's JSON-RPC message, enforcing the server's request size limit
+// Reading stops at the limit, so an oversized body is never held in memory.
+func (s *Server) readMessageBody(w http.ResponseWriter, r *http.Request, serverName string) ([]byte, error) {
+	body := r.Body
+	if limit := s.maxRequestBytes(serverName); limit > 0 {
+		body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	return io.ReadAll(body)
+}
+
+// sendBodyReadError answers a message whose body could not be read
+// Oversized messages get a JSON-RPC error with HTTP 413; other failures an invalid_request 400.
+func (s *Server) sendBodyReadError(w http.ResponseWriter, err error, isRemoteMCP bool) {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Failed to read request body")
+		return
+	}
+
+	s.sendTransportError(w, nil, ErrorRequestTooLarge, fmt.Sprintf("Request exceeds the %d byte size limit", tooLarge.Limit), isRemoteMCP)
+}
+
+// rejectUnreadableResponse answers a request whose MCP server response was oversized or not valid JSON
+// The error carries the reason as structured data. It returns false for other
+// errors, leaving them to the caller.
+func (s *Server) rejectUnreadableResponse(w http.ResponseWriter, err error, id interface{}, isRemoteMCP bool) bool {
+	var reason, message string
+	switch {
+	case errors.Is(err, mcp.ErrMessageTooLarge):
+		reason, message = "response_too_large", "Response from MCP server exceeds the size limit"
+	case errors.Is(err, mcp.ErrInvalidMessage):
+		reason, message = "invalid_response", "Response from MCP server is not valid JSON"
+	default:
+		return false
+	}
+	logger.System().Warn(" Dropped unreadable response to request %v: %v", id, err)
+
+	errorResponse, createErr := s.translator.CreateErrorResponseWithData(id, protocol.InternalError, message,
+		map[string]interface{}{"reason": reason, "detail": err.Error()}, isRemoteMCP)
+	if createErr != nil {
+		logger.System().Error(" Failed to create error response: %v", createErr)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to create error response")
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(errorResponse); err != nil {
+		logger.System().Error(" Failed to write error response: %v", err)
+	}
+	return true
+}
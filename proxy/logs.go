@@ -0,0 +1,258 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"remote-mcp-proxy/logger"
+)
+
+// logFollowInterval is how often a followed log file is checked for new lines
+const logFollowInterval = 500 * time.Millisecond
+
+// handleSystemLogs serves the system log file managed by logger.Manager
+func (s *Server) handleSystemLogs(w http.ResponseWriter, r *http.Request) {
+	filename, exists := logger.GetManager().SystemLogFile()
+	if !exists {
+		writeError(w, http.StatusServiceUnavailable, ErrorFeatureDisabled, "System log not available")
+		return
+	}
+	s.serveLogFile(w, r, "system", filename)
+}
+
+// handleMCPLogs serves the log file of an MCP server managed by logger.Manager
+func (s *Server) handleMCPLogs(w http.ResponseWriter, r *http.Request) {
+	serverName := mux.Vars(r)["server"]
+
+	filename, exists := logger.GetManager().MCPLogFile(serverName)
+	if !exists {
+		writeError(w, http.StatusNotFound, ErrorNotFound, fmt.Sprintf("No log file for MCP server '%s'", serverName))
+		return
+	}
+	s.serveLogFile(w, r, serverName, filename)
+}
+
+// handleSessionLogs returns the log lines of one session: the lines its MCP servers tagged
+// with session=<id> and the system log lines mentioning its short ID
+func (s *Server) handleSessionLogs(w http.ResponseWriter, r *http.Request) {
+	sessionID := mux.Vars(r)["sessionId"]
+
+	lines := 100
+	if linesParam := r.URL.Query().Get("lines"); linesParam != "" {
+		n, err := strconv.Atoi(linesParam)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "lines must be a positive integer")
+			return
+		}
+		lines = n
+	}
+
+	logs := []map[string]interface{}{}
+	addLog := func(name, filename, match string) {
+		matched, err := logger.GrepFile(filename, match, lines)
+		if err != nil {
+			logger.System().Error("Failed to read log file %s: %v", filename, err)
+			return
+		}
+		if len(matched) > 0 {
+			logs = append(logs, map[string]interface{}{
+				"log":   name,
+				"file":  filename,
+				"lines": matched,
+				"count": len(matched),
+			})
+		}
+	}
+
+	files := logger.GetManager().SessionLogFiles(sessionID)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		addLog(name, files[name], logger.SessionField(sessionID))
+	}
+
+	// The system log refers to sessions by the first 8 characters of their ID
+	if filename, exists := logger.GetManager().SystemLogFile(); exists && len(sessionID) >= 8 {
+		addLog("system", filename, sessionID[:8])
+	}
+
+	if len(logs) == 0 {
+		writeError(w, http.StatusNotFound, ErrorSessionNotFound, fmt.Sprintf("No logs for session '%s'", sessionID))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"session":   sessionID,
+		"logs":      logs,
+		"timestamp": time.Now(),
+	}); err != nil {
+		logger.System().Error("Failed to encode session logs response: %v", err)
+	}
+}
+
+// serveLogFile returns the last ?lines=N lines of a log file as JSON, or streams
+// them followed by new lines as Server-Sent Events when ?follow=true
+func (s *Server) serveLogFile(w http.ResponseWriter, r *http.Request, name, filename string) {
+	lines := 100
+	if linesParam := r.URL.Query().Get("lines"); linesParam != "" {
+		n, err := strconv.Atoi(linesParam)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "lines must be a positive integer")
+			return
+		}
+		lines = n
+	}
+
+	tail, offset, err := logger.TailFile(filename, lines)
+	if err != nil {
+		logger.System().Error("Failed to read log file %s: %v", filename, err)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to read log file")
+		return
+	}
+
+	if follow, _ := strconv.ParseBool(r.URL.Query().Get("follow")); !follow {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"log":       name,
+			"file":      filename,
+			"lines":     tail,
+			"count":     len(tail),
+			"timestamp": time.Now(),
+		}); err != nil {
+			logger.System().Error("Failed to encode logs response: %v", err)
+		}
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeLines := func(lines []string) error {
+		for _, line := range lines {
+			if _, err := fmt.Fprintf(w, "event: log\ndata: %s\n\n", line); err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	if err := writeLines(tail); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			newLines, newOffset, err := logger.ReadFrom(filename, offset)
+			if err != nil {
+				logger.System().Warn("Stopped following log file %s: %v", filename, err)
+				return
+			}
+			offset = newOffset
+			if len(newLines) > 0 {
+				if err := writeLines(newLines); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// handleLogLevel shows (GET) or changes (POST) log levels at runtime
+//
+// POST takes {"system": "TRACE", "mcp": "DEBUG", "servers": {"memory": "TRACE"}}.
+// Omitted fields keep their level, a server set to "" goes back to the "mcp"
+// level, and "duration" (e.g. "15m") restores the previous levels afterwards.
+func (s *Server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	manager := logger.GetManager()
+
+	if r.Method == http.MethodPost {
+		var request struct {
+			System   string            `json:"system"`
+			MCP      string            `json:"mcp"`
+			Servers  map[string]string `json:"servers"`
+			Duration string            `json:"duration"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Invalid JSON body")
+			return
+		}
+
+		levels := manager.Levels()
+		parse := func(name string, into *logger.LogLevel) bool {
+			if name == "" {
+				return true
+			}
+			level, ok := logger.LookupLogLevel(name)
+			if !ok {
+				writeError(w, http.StatusBadRequest, ErrorInvalidRequest, fmt.Sprintf("Invalid log level %q (expected TRACE, DEBUG, INFO, WARN or ERROR)", name))
+				return false
+			}
+			*into = level
+			return true
+		}
+		if !parse(request.System, &levels.System) || !parse(request.MCP, &levels.MCP) {
+			return
+		}
+		for server, name := range request.Servers {
+			if name == "" {
+				delete(levels.Servers, server)
+				continue
+			}
+			level := levels.MCP
+			if !parse(name, &level) {
+				return
+			}
+			levels.Servers[server] = level
+		}
+
+		if request.Duration == "" {
+			manager.SetLevels(levels)
+		} else {
+			duration, err := time.ParseDuration(request.Duration)
+			if err != nil || duration <= 0 {
+				writeError(w, http.StatusBadRequest, ErrorInvalidRequest, fmt.Sprintf("Invalid duration %q", request.Duration))
+				return
+			}
+			manager.SetLevelsFor(levels, duration)
+		}
+		logger.System().Info("Log levels changed by %s: system %s, mcp %s, %d server overrides (duration: %s)",
+			adminActor(r), levels.System, levels.MCP, len(levels.Servers), request.Duration)
+	}
+
+	response := map[string]interface{}{"levels": manager.Levels()}
+	if revertAt := manager.RevertAt(); !revertAt.IsZero() {
+		response["revertAt"] = revertAt.UTC().Format(time.RFC3339)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/mcp"
+)
+
+func TestSystemLogsEndpoint(t *testing.T) {
+	if _, exists := logger.GetManager().SystemLogFile(); !exists {
+		t.Skip("System log file not available in this environment")
+	}
+
+	server := NewServerWithConfig(mcp.NewManager(map[string]config.MCPServer{}), &config.Config{AdminToken: testAdminToken}, nil, nil)
+	logger.System().Info("log endpoint test marker")
+
+	w := adminRequest(server.Router(), "GET", "/logs/system?lines=5")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Log   string   `json:"log"`
+		Lines []string `json:"lines"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Log != "system" {
+		t.Errorf("Expected log 'system', got %s", response.Log)
+	}
+	if len(response.Lines) == 0 || len(response.Lines) > 5 {
+		t.Errorf("Expected between 1 and 5 lines, got %d", len(response.Lines))
+	}
+}
+
+func TestLogEndpointsValidation(t *testing.T) {
+	server := NewServerWithConfig(mcp.NewManager(map[string]config.MCPServer{}), &config.Config{AdminToken: testAdminToken}, nil, nil)
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/logs/system?lines=abc", http.StatusBadRequest},
+		{"/logs/mcp/unknown-server", http.StatusNotFound},
+		{"/logs/unknown-server", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		w := adminRequest(server.Router(), "GET", tt.path)
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.expected, w.Code)
+		}
+	}
+}
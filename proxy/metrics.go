@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/mcp"
+)
+
+// uptimeSeconds returns how long the proxy has been running, in whole seconds
+func (s *Server) uptimeSeconds() int64 {
+	return int64(time.Since(s.startedAt).Seconds())
+}
+
+// handleMetrics exposes uptime and restart counters in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	logger.System().Debug("Handling metrics request")
+
+	servers := s.mcpManager.GetAllServers()
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeMetrics(w, s.startedAt, servers); err != nil {
+		logger.System().Error(" Failed to write metrics response: %v", err)
+	}
+}
+
+// writeMetrics writes the proxy and per-server uptime metrics
+func writeMetrics(w io.Writer, startedAt time.Time, servers []mcp.ServerStatus) error {
+	var b strings.Builder
+
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("remote_mcp_proxy_start_time_seconds", "gauge", "Unix time the proxy was started.")
+	fmt.Fprintf(&b, "remote_mcp_proxy_start_time_seconds %d\n", startedAt.Unix())
+	metric("remote_mcp_proxy_uptime_seconds", "gauge", "Seconds since the proxy was started.")
+	fmt.Fprintf(&b, "remote_mcp_proxy_uptime_seconds %d\n", int64(time.Since(startedAt).Seconds()))
+
+	metric("remote_mcp_server_up", "gauge", "Whether the MCP server process is running.")
+	for _, server := range servers {
+		up := 0
+		if server.Running {
+			up = 1
+		}
+		fmt.Fprintf(&b, "remote_mcp_server_up{server=%q} %d\n", server.Name, up)
+	}
+	metric("remote_mcp_server_start_time_seconds", "gauge", "Unix time the MCP server process was last started.")
+	for _, server := range servers {
+		if server.StartedAt != nil {
+			fmt.Fprintf(&b, "remote_mcp_server_start_time_seconds{server=%q} %d\n", server.Name, server.StartedAt.Unix())
+		}
+	}
+	metric("remote_mcp_server_uptime_seconds", "gauge", "Seconds since the running MCP server process was started.")
+	for _, server := range servers {
+		fmt.Fprintf(&b, "remote_mcp_server_uptime_seconds{server=%q} %d\n", server.Name, server.UptimeSeconds)
+	}
+	metric("remote_mcp_server_restarts_total", "counter", "Times the MCP server was started again since the proxy started.")
+	for _, server := range servers {
+		fmt.Fprintf(&b, "remote_mcp_server_restarts_total{server=%q} %d\n", server.Name, server.Restarts)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/mcp"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	serverStarted := time.Unix(1700000600, 0)
+	servers := []mcp.ServerStatus{
+		{Name: "memory", Running: true, StartedAt: &serverStarted, UptimeSeconds: 42, Restarts: 2},
+		{Name: "notes"},
+	}
+
+	var out strings.Builder
+	if err := writeMetrics(&out, time.Unix(1700000000, 0), servers); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"remote_mcp_proxy_start_time_seconds 1700000000",
+		`remote_mcp_server_up{server="memory"} 1`,
+		`remote_mcp_server_up{server="notes"} 0`,
+		`remote_mcp_server_start_time_seconds{server="memory"} 1700000600`,
+		`remote_mcp_server_uptime_seconds{server="memory"} 42`,
+		`remote_mcp_server_restarts_total{server="memory"} 2`,
+		"# TYPE remote_mcp_server_restarts_total counter",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, out.String())
+		}
+	}
+	// A server that never started has no start time
+	if strings.Contains(out.String(), `remote_mcp_server_start_time_seconds{server="notes"}`) {
+		t.Error("Expected no start time for a server that never started")
+	}
+}
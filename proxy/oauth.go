@@ -0,0 +1,707 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// OAuth lifetimes
+const (
+	authorizationCodeTTL = 5 * time.Minute  // How long a client has to exchange an authorization code
+	approvalTokenTTL     = 15 * time.Minute // How long an operator-minted approval token can be used
+)
+
+// consentFailureDelay slows down guessing on the consent page
+var consentFailureDelay = time.Second
+
+// authorizationCode is an issued, not yet exchanged authorization code
+type authorizationCode struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string // PKCE S256 challenge the token request's code_verifier must match
+	scope         string // Space-separated scopes granted
+	expiresAt     time.Time
+	upstream      *upstreamTokens // Provider tokens handed out for the code in "oidc" mode
+}
+
+// errUnknownCode is returned for codes the store never issued or already forgot
+var errUnknownCode = errors.New("unknown or expired authorization code")
+
+// pkceVerifierPattern matches RFC 7636 code verifiers
+var pkceVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+
+// pkceChallenge returns the S256 challenge of a code verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// OAuthStore keeps the authorization codes, access tokens and approval tokens the proxy issued
+// Tokens are kept as SHA-256 hashes and live in memory only, so clients
+// authorize again after a restart.
+type OAuthStore struct {
+	codes     map[string]authorizationCode
+	tokens    map[string]issuedToken // Token hash -> token
+	approvals map[string]time.Time   // Approval token hash -> expiry
+	mu        sync.Mutex
+}
+
+// issuedToken is an access token the store issued
+type issuedToken struct {
+	clientID  string // Client the token was issued to (empty when unknown)
+	expiresAt time.Time
+}
+
+// NewOAuthStore creates an empty store
+func NewOAuthStore() *OAuthStore {
+	return &OAuthStore{
+		codes:     make(map[string]authorizationCode),
+		tokens:    make(map[string]issuedToken),
+		approvals: make(map[string]time.Time),
+	}
+}
+
+// hashToken returns the key a token is stored under
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueCode creates a single-use authorization code for a grant
+func (o *OAuthStore) IssueCode(grant authorizationCode) string {
+	code := generateRandomString(32)
+	grant.expiresAt = time.Now().Add(authorizationCodeTTL)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.codes[code] = grant
+	return code
+}
+
+// RedeemCode consumes an authorization code issued to clientID
+//
+// redirectURI, when given, must match the one the code was issued for, and
+// codes issued with a PKCE challenge need the matching verifier.
+func (o *OAuthStore) RedeemCode(code, clientID, redirectURI, verifier string) (authorizationCode, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	issued, exists := o.codes[code]
+	if !exists {
+		return authorizationCode{}, errUnknownCode
+	}
+	delete(o.codes, code)
+
+	switch {
+	case time.Now().After(issued.expiresAt):
+		return authorizationCode{}, errUnknownCode
+	case issued.clientID != clientID:
+		return authorizationCode{}, errors.New("authorization code was issued to another client")
+	case redirectURI != "" && redirectURI != issued.redirectURI:
+		return authorizationCode{}, errors.New("redirect_uri does not match the authorization request")
+	case issued.codeChallenge != "" && verifier == "":
+		return authorizationCode{}, errors.New("code_verifier is required")
+	case issued.codeChallenge != "" && !pkceVerifierPattern.MatchString(verifier):
+		return authorizationCode{}, errors.New("invalid code_verifier")
+	case issued.codeChallenge != "" && subtle.ConstantTimeCompare([]byte(pkceChallenge(verifier)), []byte(issued.codeChallenge)) != 1:
+		return authorizationCode{}, errors.New("code_verifier does not match the code_challenge")
+	}
+	return issued, nil
+}
+
+// IssueToken creates an access token valid for ttl
+func (o *OAuthStore) IssueToken(ttl time.Duration) string {
+	return o.IssueClientToken("", ttl)
+}
+
+// IssueClientToken creates an access token of an OAuth client valid for ttl
+func (o *OAuthStore) IssueClientToken(clientID string, ttl time.Duration) string {
+	token := generateRandomString(64)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.tokens[hashToken(token)] = issuedToken{clientID: clientID, expiresAt: time.Now().Add(ttl)}
+	return token
+}
+
+// RevokeToken drops an access token issued by the store
+func (o *OAuthStore) RevokeToken(token string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.tokens, hashToken(token))
+}
+
+// ValidToken reports whether token is an unexpired access token issued by the store
+func (o *OAuthStore) ValidToken(token string) bool {
+	_, valid := o.TokenClient(token)
+	return valid
+}
+
+// TokenClient returns the client an unexpired access token was issued to, and whether the token is valid
+func (o *OAuthStore) TokenClient(token string) (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	issued, exists := o.tokens[hashToken(token)]
+	if !exists || !time.Now().Before(issued.expiresAt) {
+		return "", false
+	}
+	return issued.clientID, true
+}
+
+// IssueApprovalToken creates a single-use token that approves one authorization on the consent page
+func (o *OAuthStore) IssueApprovalToken() (string, time.Time) {
+	token := generateRandomString(32)
+	expiresAt := time.Now().Add(approvalTokenTTL)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.approvals[hashToken(token)] = expiresAt
+	return token, expiresAt
+}
+
+// RedeemApprovalToken consumes an approval token
+func (o *OAuthStore) RedeemApprovalToken(token string) bool {
+	key := hashToken(token)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	expiresAt, exists := o.approvals[key]
+	if !exists {
+		return false
+	}
+	delete(o.approvals, key)
+	return time.Now().Before(expiresAt)
+}
+
+// CleanupExpired forgets expired codes and tokens and returns how many were removed
+func (o *OAuthStore) CleanupExpired() int {
+	now := time.Now()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	removed := 0
+	for code, issued := range o.codes {
+		if now.After(issued.expiresAt) {
+			delete(o.codes, code)
+			removed++
+		}
+	}
+	for key, issued := range o.tokens {
+		if now.After(issued.expiresAt) {
+			delete(o.tokens, key)
+			removed++
+		}
+	}
+	for key, expiresAt := range o.approvals {
+		if now.After(expiresAt) {
+			delete(o.approvals, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// consentField is a hidden authorization parameter carried through the consent form
+type consentField struct {
+	Name  string
+	Value string
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Authorize access</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 28rem; margin: 4rem auto; padding: 0 1rem; color: #222; }
+input[type=password] { width: 100%; padding: .5rem; margin: .5rem 0 1rem; box-sizing: border-box; }
+button { padding: .5rem 1rem; margin-right: .5rem; }
+code { background: #f3f3f3; padding: .2rem .4rem; border-radius: 4px; word-break: break-all; }
+.error { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Authorize access</h1>
+<p><code>{{.Client}}</code> is asking to use the MCP servers of <strong>{{.Host}}</strong>.
+It will be sent back to <code>{{.RedirectURI}}</code>.</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth/authorize">
+{{range .Fields}}<input type="hidden" name="{{.Name}}" value="{{.Value}}">
+{{end}}<label for="credential">{{.Prompt}}</label>
+<input type="password" id="credential" name="{{.Credential}}" autocomplete="current-password" autofocus>
+<button type="submit" name="action" value="approve">Approve</button>
+<button type="submit" name="action" value="deny">Deny</button>
+</form>
+</body>
+</html>
+`))
+
+// consentCredentials are the form fields holding what the user typed, never carried as hidden fields
+var consentCredentials = map[string]bool{"password": true, "approval_token": true, "action": true}
+
+// consentMode returns how authorization requests are approved
+func (s *Server) consentMode() string {
+	if s.config == nil || s.config.OAuthConsent == "" {
+		return config.ConsentOff
+	}
+	return s.config.OAuthConsent
+}
+
+// tokenTTL returns the lifetime of issued access tokens
+func (s *Server) tokenTTL() time.Duration {
+	if s.config == nil || s.config.OAuthTokenTTL <= 0 {
+		return config.DefaultOAuthTokenTTL
+	}
+	return s.config.OAuthTokenTTL
+}
+
+// renderConsent shows the consent page for the authorization request in r
+func (s *Server) renderConsent(w http.ResponseWriter, r *http.Request, status int, message string) {
+	var fields []consentField
+	for name, values := range r.Form {
+		if !consentCredentials[name] && len(values) > 0 {
+			fields = append(fields, consentField{Name: name, Value: values[0]})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+
+	prompt, credential := "Password", "password"
+	if s.consentMode() == config.ConsentApprovalToken {
+		prompt, credential = "Approval token (ask an operator for one)", "approval_token"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := consentTemplate.Execute(w, map[string]interface{}{
+		"Client":      r.Form.Get("client_id"),
+		"Host":        r.Host,
+		"RedirectURI": r.Form.Get("redirect_uri"),
+		"Error":       message,
+		"Fields":      fields,
+		"Prompt":      prompt,
+		"Credential":  credential,
+	}); err != nil {
+		logger.System().Error("Failed to render consent page: %v", err)
+	}
+}
+
+// checkConsent reports whether the consent form carries a valid password or approval token
+func (s *Server) checkConsent(r *http.Request) (bool, string) {
+	switch s.consentMode() {
+	case config.ConsentApprovalToken:
+		if token := r.PostForm.Get("approval_token"); token != "" && s.oauth.RedeemApprovalToken(token) {
+			return true, ""
+		}
+		return false, "Invalid or expired approval token."
+	default:
+		password := r.PostForm.Get("password")
+		if s.config.OAuthConsentPassword != "" && subtle.ConstantTimeCompare([]byte(password), []byte(s.config.OAuthConsentPassword)) == 1 {
+			return true, ""
+		}
+		return false, "Incorrect password."
+	}
+}
+
+// handleAuthorize handles OAuth authorization requests
+//
+// With OAUTH_CONSENT set, GET shows a consent page and the code is only issued
+// once the form is posted back with the password or an approval token. In
+// "oidc" mode the user signs in with the provider instead, see handleOIDCCallback.
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Invalid authorization request")
+		return
+	}
+	clientID := r.Form.Get("client_id")
+	redirectURI := r.Form.Get("redirect_uri")
+	state := r.Form.Get("state")
+	responseType := r.Form.Get("response_type")
+
+	callback, err := url.Parse(redirectURI)
+	if clientID == "" || redirectURI == "" || responseType != "code" || err != nil || !callback.IsAbs() {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Invalid authorization request")
+		return
+	}
+
+	logger.System().Info("OAuth authorization request - Client: %s, Redirect: %s", clientID, redirectURI)
+
+	// Never redirect to a URI outside the subdomain's allowlist
+	serverName := oauthServer(r)
+	if !s.redirectURIAllowed(serverName, redirectURI) {
+		logger.System().Warn("OAuth authorization request refused - Client: %s: redirect_uri %s is not allowed", clientID, redirectURI)
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "redirect_uri is not allowed")
+		return
+	}
+
+	params := callback.Query()
+	if state != "" {
+		params.Set("state", state)
+	}
+	refuse := func(code, message string) {
+		logger.System().Warn("OAuth authorization request refused - Client: %s: %s", clientID, message)
+		params.Set("error", code)
+		params.Set("error_description", message)
+		callback.RawQuery = params.Encode()
+		http.Redirect(w, r, callback.String(), http.StatusFound)
+	}
+
+	scope, ok := grantedScope(r.Form.Get("scope"), s.oauthScopes(serverName))
+	if !ok {
+		refuse("invalid_scope", "unsupported scope requested")
+		return
+	}
+
+	// PKCE: only S256 challenges, required with OAUTH_REQUIRE_PKCE
+	grant := authorizationCode{clientID: clientID, redirectURI: redirectURI, codeChallenge: r.Form.Get("code_challenge"), scope: scope}
+	if message := s.checkCodeChallenge(grant.codeChallenge, r.Form.Get("code_challenge_method")); message != "" {
+		refuse("invalid_request", message)
+		return
+	}
+
+	switch s.consentMode() {
+	case config.ConsentOff:
+	case config.ConsentOIDC:
+		if s.oidc == nil {
+			writeError(w, http.StatusServiceUnavailable, ErrorFeatureDisabled, "OIDC provider is not configured")
+			return
+		}
+		target, err := s.oidc.StartLogin(r.Context(), s.oidcCallbackURL(r), grant, state)
+		if err != nil {
+			logger.System().Error("Failed to start OIDC sign-in: %v", err)
+			writeError(w, http.StatusBadGateway, ErrorUpstreamFailed, "Identity provider unavailable")
+			return
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	default:
+		if r.Method != http.MethodPost {
+			s.renderConsent(w, r, http.StatusOK, "")
+			return
+		}
+		if r.PostForm.Get("action") == "deny" {
+			logger.System().Info("OAuth authorization denied by the user - Client: %s", clientID)
+			params.Set("error", "access_denied")
+			callback.RawQuery = params.Encode()
+			http.Redirect(w, r, callback.String(), http.StatusFound)
+			return
+		}
+		if ok, message := s.checkConsent(r); !ok {
+			logger.System().Warn("OAuth consent failed for client %s from %s", clientID, r.RemoteAddr)
+			time.Sleep(consentFailureDelay)
+			s.renderConsent(w, r, http.StatusUnauthorized, message)
+			return
+		}
+		logger.System().Info("OAuth authorization approved - Client: %s", clientID)
+	}
+
+	// Redirect with authorization code
+	params.Set("code", s.oauth.IssueCode(grant))
+	callback.RawQuery = params.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// checkCodeChallenge validates the PKCE parameters of an authorization request
+// It returns why they are refused, or "" when they are fine.
+func (s *Server) checkCodeChallenge(challenge, method string) string {
+	switch {
+	case challenge == "" && s.config != nil && s.config.OAuthRequirePKCE:
+		return "code_challenge is required"
+	case challenge == "":
+		return ""
+	case method != "S256":
+		return "code_challenge_method must be S256"
+	case len(challenge) != 43:
+		return "invalid code_challenge"
+	}
+	return ""
+}
+
+// handleToken handles OAuth token exchange
+// With OAUTH_CONSENT set, only codes issued after consent are accepted.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	grantType := r.FormValue("grant_type")
+	code := r.FormValue("code")
+	clientID := r.FormValue("client_id")
+
+	if grantType == "refresh_token" && s.consentMode() == config.ConsentOIDC && s.oidc != nil {
+		tokens, err := s.oidc.Refresh(r.Context(), r.FormValue("refresh_token"))
+		if err != nil {
+			logger.System().Warn("OIDC token refresh failed - Client: %s: %v", clientID, err)
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "The OIDC provider refused the refresh token")
+			return
+		}
+		s.writeUpstreamTokens(w, r, clientID, tokens, strings.Join(s.oauthScopes(oauthServer(r)), " "))
+		return
+	}
+
+	if grantType != "authorization_code" || code == "" || clientID == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Invalid token request")
+		return
+	}
+
+	// Without consent, codes the proxy doesn't know are still exchanged as before,
+	// unless PKCE is required: an unknown code has no challenge to verify
+	issued, err := s.oauth.RedeemCode(code, clientID, r.FormValue("redirect_uri"), r.FormValue("code_verifier"))
+	if err != nil && (err != errUnknownCode || s.consentMode() != config.ConsentOff || (s.config != nil && s.config.OAuthRequirePKCE)) {
+		logger.System().Warn("OAuth token request refused - Client: %s: %v", clientID, err)
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+	if issued.scope == "" {
+		issued.scope = strings.Join(s.oauthScopes(oauthServer(r)), " ")
+	}
+	if issued.upstream != nil {
+		s.writeUpstreamTokens(w, r, clientID, issued.upstream, issued.scope)
+		return
+	}
+
+	// Generate access token
+	ttl := s.tokenTTL()
+	accessToken := s.oauth.IssueClientToken(clientID, ttl)
+
+	tokenResponse := map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+		"scope":        issued.scope,
+	}
+
+	logger.System().Info("OAuth token issued - Client: %s, Token: %s...", clientID, accessToken[:10])
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(tokenResponse)
+}
+
+// handleApprovalToken mints a single-use approval token for the consent page
+// Requires ADMIN_TOKEN: an open endpoint would let anyone approve themselves.
+func (s *Server) handleApprovalToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if s.config == nil || s.config.AdminToken == "" {
+		writeError(w, http.StatusForbidden, ErrorFeatureDisabled, "Approval tokens require ADMIN_TOKEN to be set")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	token, expiresAt := s.oauth.IssueApprovalToken()
+	logger.System().Info("OAuth approval token issued, valid until %s", expiresAt.Format(time.RFC3339))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"approval_token": token,
+		"expires_at":     expiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// oauthIssuer returns the authorization server the client reached, as advertised in the metadata
+func oauthIssuer(r *http.Request) string {
+	return "https://" + r.Host
+}
+
+// handleProtectedResourceMetadata serves the OAuth protected resource metadata (RFC 9728)
+//
+// The resource is the MCP endpoint the document's path names: /sse on a server
+// subdomain, /{server}/sse with path routing, {basePath}/sse, or the whole proxy at the bare
+// well-known URL. Clients find the document through the resource_metadata
+// parameter of 401 responses.
+func (s *Server) handleProtectedResourceMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	issuer := oauthIssuer(r)
+	resource := issuer
+	serverName := oauthServer(r)
+	if pathServer, exists := mux.Vars(r)["server"]; exists {
+		serverName = pathServer
+		resource = issuer + "/" + pathServer + "/sse"
+	} else if baseServer, basePath := s.config.BasePathServer(strings.TrimPrefix(r.URL.Path, "/.well-known/oauth-protected-resource")); baseServer != "" {
+		serverName = baseServer
+		resource = issuer + basePath + "/sse"
+	} else if strings.HasSuffix(r.URL.Path, "/sse") {
+		resource = issuer + "/sse"
+	}
+
+	metadata := map[string]interface{}{
+		"resource":                 resource,
+		"authorization_servers":    []string{issuer},
+		"scopes_supported":         s.oauthScopes(serverName),
+		"bearer_methods_supported": []string{"header"},
+	}
+	if serverName != "" && resource != issuer {
+		var serverConfig config.MCPServer
+		exists := false
+		if s.config != nil {
+			serverConfig, exists = s.config.MCPServers[serverName]
+		}
+		if !exists && !s.isAggregateServer(serverName) {
+			http.NotFound(w, r)
+			return
+		}
+		metadata["resource_name"] = serverName
+		if serverConfig.DisplayName != "" {
+			metadata["resource_name"] = serverConfig.DisplayName
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(metadata)
+}
+
+// oauthServer returns the MCP server whose subdomain or path the OAuth request reached ("" for the bare domain)
+func oauthServer(r *http.Request) string {
+	serverName, _ := r.Context().Value("mcpServer").(string)
+	return serverName
+}
+
+// oauthScopes returns the scopes advertised and granted for a server
+func (s *Server) oauthScopes(serverName string) []string {
+	if s.config == nil {
+		return []string{config.DefaultOAuthScope}
+	}
+	return s.config.GetOAuthScopes(serverName)
+}
+
+// redirectURIAllowed reports whether clients of a server may be sent back to uri
+func (s *Server) redirectURIAllowed(serverName, uri string) bool {
+	if s.config == nil {
+		return true
+	}
+	patterns := s.config.GetOAuthRedirectURIs(serverName)
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if redirectURIMatches(pattern, uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectURIMatches reports whether uri matches a redirect URI pattern
+// The parts of both are compared separately, so wildcards can't reach across
+// them: the scheme exactly, the host label by label, the port, then the path
+// and query. URIs with userinfo or a fragment never match, since either can
+// make a URI that looks like an allowed one point somewhere else.
+func redirectURIMatches(pattern, uri string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.User != nil || strings.Contains(uri, "#") || parsed.Opaque != "" {
+		return false
+	}
+	scheme, rest, found := strings.Cut(pattern, "://")
+	if !found || !strings.EqualFold(scheme, parsed.Scheme) {
+		return false
+	}
+
+	authority, patternPath := rest, ""
+	if i := strings.Index(rest, "/"); i >= 0 {
+		authority, patternPath = rest[:i], rest[i:]
+	}
+	hostPattern, portPattern := authority, ""
+	if i := strings.LastIndex(authority, ":"); i > strings.LastIndex(authority, "]") {
+		hostPattern, portPattern = authority[:i], authority[i+1:]
+	}
+	if !hostMatches(strings.ToLower(strings.Trim(hostPattern, "[]")), strings.ToLower(parsed.Hostname())) {
+		return false
+	}
+	if matched, _ := path.Match(portPattern, parsed.Port()); !matched {
+		return false
+	}
+
+	uriPath := parsed.EscapedPath()
+	if parsed.RawQuery != "" || parsed.ForceQuery {
+		uriPath += "?" + parsed.RawQuery
+	}
+	matched, _ := path.Match(patternPath, uriPath)
+	return matched
+}
+
+// hostMatches matches a host against a pattern label by label, so a wildcard stays within its label
+func hostMatches(pattern, host string) bool {
+	patternLabels, labels := strings.Split(pattern, "."), strings.Split(host, ".")
+	if host == "" || len(patternLabels) != len(labels) {
+		return false
+	}
+	for i, label := range labels {
+		if matched, _ := path.Match(patternLabels[i], label); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultRedirectURIs returns the redirect URIs registered for clients that don't ask for any
+// These are the allowlist entries without wildcards, or Claude's callbacks when there are none.
+func (s *Server) defaultRedirectURIs(serverName string) []string {
+	var uris []string
+	if s.config != nil {
+		for _, pattern := range s.config.GetOAuthRedirectURIs(serverName) {
+			if !strings.ContainsAny(pattern, `*?[\`) {
+				uris = append(uris, pattern)
+			}
+		}
+	}
+	if len(uris) == 0 {
+		uris = []string{
+			"https://claude.ai/oauth/callback",
+			"https://www.claude.ai/oauth/callback",
+		}
+	}
+	return uris
+}
+
+// grantedScope checks a requested scope against the supported ones
+// An empty request is granted every supported scope.
+func grantedScope(requested string, supported []string) (string, bool) {
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		return strings.Join(supported, " "), true
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, candidate := range supported {
+			known = known || scope == candidate
+		}
+		if !known {
+			return "", false
+		}
+	}
+	return strings.Join(scopes, " "), true
+}
+
+// resourceMetadataURL returns where the protected resource metadata of the requested MCP endpoint is served
+func resourceMetadataURL(r *http.Request) string {
+	return oauthIssuer(r) + "/.well-known/oauth-protected-resource" + r.URL.Path
+}
+
+// writeOAuthError writes an OAuth 2.0 error response, with an OAuth error code such as invalid_grant
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	writeAuthError(w, status, code, description)
+}
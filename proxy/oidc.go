@@ -0,0 +1,602 @@
+package proxy
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// OIDC timings
+const (
+	oidcLoginTTL       = 10 * time.Minute // How long a user has to sign in with the provider
+	oidcKeyRefetch     = time.Minute      // Minimum time between JWKS fetches for unknown key IDs
+	oidcClockLeeway    = time.Minute      // Tolerated clock difference when checking exp and nbf
+	oidcRequestTimeout = 10 * time.Second // Timeout of discovery, JWKS and token requests
+)
+
+// oidcDiscovery is the part of the provider's openid-configuration the proxy uses
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// upstreamTokens are the tokens returned by the provider's token endpoint
+type upstreamTokens struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// jwtClaims are the registered claims checked on provider tokens
+type jwtClaims struct {
+	Issuer    string      `json:"iss"`
+	Subject   string      `json:"sub"`
+	Audience  jwtAudience `json:"aud"`
+	ExpiresAt int64       `json:"exp"`
+	NotBefore int64       `json:"nbf"`
+	Nonce     string      `json:"nonce"`
+	Email     string      `json:"email"`
+}
+
+// jwtAudience is the aud claim, a string or an array of strings
+type jwtAudience []string
+
+// UnmarshalJSON accepts both forms of the aud claim
+func (a *jwtAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// oidcLogin is an authorization request waiting for the user to sign in with the provider
+type oidcLogin struct {
+	grant     authorizationCode // Code issued to the client once signed in
+	state     string
+	nonce     string
+	callback  string // Redirect URI sent to the provider
+	expiresAt time.Time
+}
+
+// OIDCProvider delegates authentication to an upstream OpenID Connect provider
+//
+// The provider's configuration and signing keys are fetched on first use and
+// cached; keys are fetched again when a token is signed with an unknown key.
+type OIDCProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	audience     string
+	scopes       []string
+	client       *http.Client
+
+	discovery   *oidcDiscovery
+	keys        map[string]crypto.PublicKey // By key ID
+	keysFetched time.Time
+	logins      map[string]oidcLogin // By the state sent to the provider
+	mu          sync.Mutex
+}
+
+// NewOIDCProvider creates a provider from the OIDC_* settings
+func NewOIDCProvider(cfg *config.Config) *OIDCProvider {
+	return &OIDCProvider{
+		issuer:       strings.TrimSuffix(cfg.OIDCIssuer, "/"),
+		clientID:     cfg.OIDCClientID,
+		clientSecret: cfg.OIDCClientSecret,
+		audience:     cfg.OIDCAudience,
+		scopes:       cfg.OIDCScopes,
+		client:       &http.Client{Timeout: oidcRequestTimeout},
+		logins:       make(map[string]oidcLogin),
+	}
+}
+
+// discover returns the provider's configuration, fetching it once
+// The fetch runs without the lock, so a slow provider doesn't hold up logins
+// and token checks; concurrent first calls may each fetch it.
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	cached := p.discovery
+	p.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", discovery.Issuer, p.issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery of %s lacks an authorization, token or JWKS endpoint", p.issuer)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery == nil {
+		p.discovery = &discovery
+	}
+	return p.discovery, nil
+}
+
+// getJSON fetches a JSON document
+func (p *OIDCProvider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: HTTP %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// StartLogin remembers an authorization request and returns the provider URL the user signs in at
+func (p *OIDCProvider) StartLogin(ctx context.Context, callback string, grant authorizationCode, state string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	login := oidcLogin{
+		grant:     grant,
+		state:     state,
+		nonce:     generateRandomString(32),
+		callback:  callback,
+		expiresAt: time.Now().Add(oidcLoginTTL),
+	}
+	providerState := generateRandomString(32)
+	p.mu.Lock()
+	p.logins[providerState] = login
+	p.mu.Unlock()
+
+	target, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid OIDC authorization endpoint: %w", err)
+	}
+	params := target.Query()
+	params.Set("response_type", "code")
+	params.Set("client_id", p.clientID)
+	params.Set("redirect_uri", callback)
+	params.Set("scope", strings.Join(p.scopes, " "))
+	params.Set("state", providerState)
+	params.Set("nonce", login.nonce)
+	if p.audience != "" {
+		params.Set("audience", p.audience) // Auth0 issues JWT access tokens for an API audience
+	}
+	target.RawQuery = params.Encode()
+	return target.String(), nil
+}
+
+// finishLogin consumes the authorization request a provider callback belongs to
+func (p *OIDCProvider) finishLogin(providerState string) (oidcLogin, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	login, exists := p.logins[providerState]
+	delete(p.logins, providerState)
+	return login, exists && time.Now().Before(login.expiresAt)
+}
+
+// Exchange redeems the provider's code and checks the ID token it returns
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, login oidcLogin) (*upstreamTokens, error) {
+	tokens, err := p.tokenRequest(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {login.callback},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("OIDC provider returned no ID token")
+	}
+	claims, err := p.Verify(ctx, tokens.IDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if claims.Nonce != login.nonce {
+		return nil, errors.New("ID token nonce does not match the login")
+	}
+	return tokens, nil
+}
+
+// Refresh trades a provider refresh token for new tokens
+func (p *OIDCProvider) Refresh(ctx context.Context, refreshToken string) (*upstreamTokens, error) {
+	return p.tokenRequest(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+}
+
+// tokenRequest calls the provider's token endpoint with the proxy's client credentials
+func (p *OIDCProvider) tokenRequest(ctx context.Context, form url.Values) (*upstreamTokens, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form.Set("client_id", p.clientID)
+	form.Set("client_secret", p.clientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OIDC token request failed: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var tokens upstreamTokens
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("invalid OIDC token response: %w", err)
+	}
+	return &tokens, nil
+}
+
+// BearerToken picks the token clients present to the proxy
+// A JWT access token for the proxy is preferred; providers issuing opaque
+// access tokens (Google, for one) fall back to the ID token.
+func (p *OIDCProvider) BearerToken(ctx context.Context, tokens *upstreamTokens) string {
+	if tokens.AccessToken != "" {
+		if _, err := p.Verify(ctx, tokens.AccessToken); err == nil {
+			return tokens.AccessToken
+		}
+	}
+	return tokens.IDToken
+}
+
+// Verify checks a JWT's signature, issuer, audience and lifetime
+func (p *OIDCProvider) Verify(ctx context.Context, token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("not a JWT")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid JWT header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT signature encoding: %w", err)
+	}
+	key, err := p.signingKey(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Algorithm, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid JWT claims: %w", err)
+	}
+	now := time.Now()
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != p.issuer:
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	case !p.acceptsAudience(claims.Audience):
+		return nil, fmt.Errorf("unexpected audience %v", []string(claims.Audience))
+	case claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(oidcClockLeeway)):
+		return nil, errors.New("token expired")
+	case claims.NotBefore != 0 && now.Add(oidcClockLeeway).Before(time.Unix(claims.NotBefore, 0)):
+		return nil, errors.New("token not valid yet")
+	}
+	return &claims, nil
+}
+
+// acceptsAudience reports whether a token is meant for the proxy
+func (p *OIDCProvider) acceptsAudience(audience jwtAudience) bool {
+	for _, aud := range audience {
+		if aud == p.clientID || (p.audience != "" && aud == p.audience) {
+			return true
+		}
+	}
+	return false
+}
+
+// signingKey returns the provider key with the given ID, refreshing the JWKS when it is unknown
+func (p *OIDCProvider) signingKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if key := p.lookupKey(keyID); key != nil {
+		p.mu.Unlock()
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < oidcKeyRefetch {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+	// Claimed under the lock so concurrent callers don't fetch too, then fetched without it
+	p.keysFetched = time.Now()
+	p.mu.Unlock()
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			logger.System().Warn("Ignoring OIDC signing key %q: %v", jwk.KeyID, err)
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+	if key := p.lookupKey(keyID); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+// lookupKey returns a cached key; a token without key ID matches a single cached key
+func (p *OIDCProvider) lookupKey(keyID string) crypto.PublicKey {
+	if key, exists := p.keys[keyID]; exists {
+		return key
+	}
+	if keyID == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// CleanupExpired forgets abandoned logins and returns how many were removed
+func (p *OIDCProvider) CleanupExpired() int {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	removed := 0
+	for state, login := range p.logins {
+		if now.After(login.expiresAt) {
+			delete(p.logins, state)
+			removed++
+		}
+	}
+	return removed
+}
+
+// jsonWebKey is an RSA or EC public key of a JWKS
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// publicKey decodes the key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+// verifySignature checks a JWS signature for the RS* and ES* algorithms
+func verifySignature(algorithm string, key crypto.PublicKey, signed string, signature []byte) error {
+	var hasher hash.Hash
+	var hashID crypto.Hash
+	switch algorithm {
+	case "RS256", "ES256":
+		hasher, hashID = sha256.New(), crypto.SHA256
+	case "RS384", "ES384":
+		hasher, hashID = sha512.New384(), crypto.SHA384
+	case "RS512":
+		hasher, hashID = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+	}
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(algorithm, "RS") {
+			return fmt.Errorf("algorithm %s does not match an RSA key", algorithm)
+		}
+		if err := rsa.VerifyPKCS1v15(key, hashID, digest, signature); err != nil {
+			return errors.New("invalid JWT signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(algorithm, "ES") || len(signature) != 2*size {
+			return fmt.Errorf("algorithm %s does not match an EC key", algorithm)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("invalid JWT signature")
+		}
+		return nil
+	}
+	return errors.New("unsupported signing key")
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decodeBigInt decodes a base64url big-endian integer of a JWK
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("invalid key parameter %q", value)
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// oidcCallbackURL returns the redirect URI registered with the provider
+func (s *Server) oidcCallbackURL(r *http.Request) string {
+	if s.config.OIDCRedirectURL != "" {
+		return s.config.OIDCRedirectURL
+	}
+	return s.publicBaseURL(r) + "/oauth/callback"
+}
+
+// handleOIDCCallback finishes a sign-in with the provider and sends the client its authorization code
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	login, exists := s.oidc.finishLogin(query.Get("state"))
+	if !exists {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Unknown or expired sign-in, start again from your MCP client")
+		return
+	}
+
+	callback, _ := url.Parse(login.grant.redirectURI) // Checked by handleAuthorize
+	params := callback.Query()
+	if login.state != "" {
+		params.Set("state", login.state)
+	}
+
+	if providerError := query.Get("error"); providerError != "" {
+		logger.System().Warn("OIDC sign-in failed for client %s: %s %s", login.grant.clientID, providerError, query.Get("error_description"))
+		params.Set("error", "access_denied")
+		callback.RawQuery = params.Encode()
+		http.Redirect(w, r, callback.String(), http.StatusFound)
+		return
+	}
+
+	tokens, err := s.oidc.Exchange(r.Context(), query.Get("code"), login)
+	if err != nil {
+		logger.System().Error("OIDC code exchange failed for client %s: %v", login.grant.clientID, err)
+		params.Set("error", "server_error")
+		callback.RawQuery = params.Encode()
+		http.Redirect(w, r, callback.String(), http.StatusFound)
+		return
+	}
+
+	logger.System().Info("OIDC sign-in completed - Client: %s", login.grant.clientID)
+	login.grant.upstream = tokens
+	params.Set("code", s.oauth.IssueCode(login.grant))
+	callback.RawQuery = params.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// writeUpstreamTokens answers a token request with the provider's tokens
+func (s *Server) writeUpstreamTokens(w http.ResponseWriter, r *http.Request, clientID string, tokens *upstreamTokens, scope string) {
+	bearer := s.oidc.BearerToken(r.Context(), tokens)
+	if bearer == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "The OIDC provider returned no usable token")
+		return
+	}
+	response := map[string]interface{}{
+		"access_token": bearer,
+		"token_type":   "Bearer",
+		"scope":        scope,
+	}
+	if tokens.ExpiresIn > 0 {
+		response["expires_in"] = tokens.ExpiresIn
+	}
+	if tokens.RefreshToken != "" {
+		response["refresh_token"] = tokens.RefreshToken
+	}
+
+	logger.System().Info("OAuth token issued from the OIDC provider - Client: %s", clientID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
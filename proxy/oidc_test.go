@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+)
+
+// testProvider is a minimal OpenID Connect provider signing tokens with an RSA key
+type testProvider struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	nonce string // Nonce of the last authorization request, echoed in ID tokens
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &testProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 provider.URL,
+			"authorization_endpoint": provider.URL + "/authorize",
+			"token_endpoint":         provider.URL + "/token",
+			"jwks_uri":               provider.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_secret") != "secret" || r.FormValue("code") != "provider-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "opaque-access-token",
+			"id_token":      provider.sign(t, map[string]interface{}{"aud": "proxy", "nonce": provider.nonce}),
+			"refresh_token": "refresh",
+			"expires_in":    3600,
+		})
+	})
+	provider.Server = httptest.NewServer(mux)
+	t.Cleanup(provider.Close)
+	return provider
+}
+
+// sign issues a JWT with default claims overridden by claims
+func (p *testProvider) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	payload := map[string]interface{}{"iss": p.URL, "sub": "user-1", "aud": "proxy", "exp": time.Now().Add(time.Hour).Unix()}
+	for name, value := range claims {
+		payload[name] = value
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	body, _ := json.Marshal(payload)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCDelegation(t *testing.T) {
+	provider := newTestProvider(t)
+	server, handler := newOAuthTestServer(t, &config.Config{
+		OAuthConsent:     config.ConsentOIDC,
+		OIDCIssuer:       provider.URL + "/",
+		OIDCClientID:     "proxy",
+		OIDCClientSecret: "secret",
+		OIDCScopes:       []string{"openid", "email"},
+	})
+
+	// The client is sent to the provider
+	req := httptest.NewRequest("GET", "/oauth/authorize?"+authorizeQuery.Encode(), nil)
+	req.Host = "memory.mcp.example.com"
+	req.RemoteAddr = "10.0.0.2:41234"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	login := redirectParams(t, rr)
+	if login.Get("client_id") != "proxy" || login.Get("redirect_uri") != "https://memory.mcp.example.com/oauth/callback" || login.Get("scope") != "openid email" {
+		t.Fatalf("Unexpected provider authorization request: %v", login)
+	}
+	provider.nonce = login.Get("nonce")
+
+	// The provider sends the user back, and the client gets a code of its own
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/oauth/callback?"+url.Values{"state": {login.Get("state")}, "code": {"provider-code"}}.Encode(), nil))
+	params := redirectParams(t, rr)
+	if params.Get("code") == "" || params.Get("state") != "xyz" {
+		t.Fatalf("Expected a code and the client's state, got %v", params)
+	}
+
+	rr = exchangeCode(handler, params.Get("code"))
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&tokens); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Token exchange failed: %d (%v)", rr.Code, err)
+	}
+	if strings.Count(tokens.AccessToken, ".") != 2 || tokens.RefreshToken != "refresh" || tokens.ExpiresIn != 3600 {
+		t.Errorf("Expected the ID token in place of the opaque access token, got %+v", tokens)
+	}
+
+	for name, tt := range map[string]struct {
+		token string
+		want  bool
+	}{
+		"provider token":          {tokens.AccessToken, true},
+		"proxy token":             {server.oauth.IssueToken(time.Hour), false},
+		"in-process client token": {server.newClient(handler, "memory").token, true},
+	} {
+		req := httptest.NewRequest("GET", "/memory/sse", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		if got := server.validateAuthentication(req); got != tt.want {
+			t.Errorf("%s: validateAuthentication = %v, want %v", name, got, tt.want)
+		}
+	}
+
+	// A replayed callback is refused
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/oauth/callback?"+url.Values{"state": {login.Get("state")}, "code": {"provider-code"}}.Encode(), nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected a replayed callback to be refused, got %d", rr.Code)
+	}
+}
+
+func TestOIDCVerify(t *testing.T) {
+	provider := newTestProvider(t)
+	oidc := NewOIDCProvider(&config.Config{OIDCIssuer: provider.URL, OIDCClientID: "proxy", OIDCAudience: "https://mcp.example.com"})
+	ctx := context.Background()
+
+	valid := provider.sign(t, nil)
+	parts := strings.Split(valid, ".")
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid", valid, true},
+		{"api audience", provider.sign(t, map[string]interface{}{"aud": []string{"other", "https://mcp.example.com"}}), true},
+		{"wrong audience", provider.sign(t, map[string]interface{}{"aud": "other"}), false},
+		{"wrong issuer", provider.sign(t, map[string]interface{}{"iss": "https://evil.example.com"}), false},
+		{"expired", provider.sign(t, map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}), false},
+		{"not yet valid", provider.sign(t, map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()}), false},
+		{"tampered claims", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"`+provider.URL+`","aud":"proxy","exp":9999999999,"sub":"admin"}`)) + "." + parts[2], false},
+		{"unsigned", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"test"}`)) + "." + parts[1] + ".", false},
+		{"not a JWT", "opaque-access-token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := oidc.Verify(ctx, tt.token)
+			if (err == nil) != tt.valid {
+				t.Errorf("Verify() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestOIDCSlowProviderDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.NotFound(w, r)
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	oidc := NewOIDCProvider(&config.Config{OIDCIssuer: slow.URL, OIDCClientID: "proxy"})
+	go oidc.StartLogin(context.Background(), "https://mcp.example.com/oauth/callback", authorizationCode{}, "state")
+	time.Sleep(50 * time.Millisecond) // Let the login wait on discovery
+
+	done := make(chan struct{})
+	go func() {
+		oidc.finishLogin("unknown")
+		oidc.CleanupExpired()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected logins not to wait for a slow provider")
+	}
+}
+
+func TestVerifyECSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := "header.payload"
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	if err := verifySignature("ES256", &key.PublicKey, signed, signature); err != nil {
+		t.Errorf("Expected a valid ES256 signature, got %v", err)
+	}
+	if err := verifySignature("ES256", &key.PublicKey, "header.other", signature); err == nil {
+		t.Error("Expected a signature over other data to be rejected")
+	}
+	if err := verifySignature("RS256", &key.PublicKey, signed, signature); err == nil {
+		t.Error("Expected an RSA algorithm with an EC key to be rejected")
+	}
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/state"
+	"remote-mcp-proxy/webhook"
+)
+
+// handleOperations lists the requests MCP servers are working on: GET /admin/operations
+// ?server= keeps one server's operations; ?session= keeps a session's, by full or short ID.
+func (s *Server) handleOperations(w http.ResponseWriter, r *http.Request) {
+	serverName := r.URL.Query().Get("server")
+	session := r.URL.Query().Get("session")
+
+	operations := make([]mcp.OperationStatus, 0)
+	for _, operation := range s.mcpManager.ActiveOperations() {
+		if serverName != "" && operation.Server != serverName {
+			continue
+		}
+		if session != "" && !strings.HasPrefix(operation.Session, session) {
+			continue
+		}
+		operations = append(operations, operation)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"operations": operations,
+		"count":      len(operations),
+	}); err != nil {
+		logger.System().Error("Failed to encode operations response: %v", err)
+	}
+}
+
+// handleCancelOperation cancels an operation listed by /admin/operations: DELETE /admin/operations/{id}
+// The client waiting on it gets a "Request cancelled" error and the MCP server
+// is sent notifications/cancelled.
+func (s *Server) handleCancelOperation(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	operation, cancelled := s.mcpManager.CancelOperation(id)
+	if !cancelled {
+		writeError(w, http.StatusNotFound, ErrorNotFound, fmt.Sprintf("Operation '%s' not in flight", id))
+		return
+	}
+
+	actor := adminActor(r)
+	logger.System().Info("Cancelled operation %s (%s on %s) for %s", id, operation.Method, operation.Server, actor)
+	s.mcpManager.GetIncidentStore().RecordIncident(state.Incident{
+		Kind:    state.IncidentAdmin,
+		Server:  operation.Server,
+		Actor:   actor,
+		Action:  "cancel-operation",
+		Success: true,
+		Details: map[string]interface{}{"operation": id, "method": operation.Method, "tool": operation.Tool, "session": operation.Session},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"operation": operation,
+		"cancelled": true,
+	}); err != nil {
+		logger.System().Error("Failed to encode cancel operation response: %v", err)
+	}
+}
+
+// operationPollInterval is how often a deferred session cleanup checks whether the session's operations finished
+var operationPollInterval = time.Second
+
+// sessionOperations splits a session's operations into those still running within
+// their server's operation timeout and those past it
+func (cm *ConnectionManager) sessionOperations(sessionID string) (active, timedOut []mcp.OperationStatus) {
+	if cm.mcpManager == nil {
+		return nil, nil
+	}
+	for _, operation := range cm.mcpManager.SessionOperations(sessionID) {
+		if operation.Expired {
+			timedOut = append(timedOut, operation)
+		} else {
+			active = append(active, operation)
+		}
+	}
+	return active, timedOut
+}
+
+// reportExpiredOperations tells the webhooks a session is cleaned up despite operations that exceeded their timeout
+func (cm *ConnectionManager) reportExpiredOperations(sessionID string, timedOut []mcp.OperationStatus) {
+	for _, operation := range timedOut {
+		logger.System().Warn("OPERATION TIMEOUT: %s on server %s ran for %v, allowing cleanup of session %s",
+			operation.Method, operation.Server, time.Duration(operation.DurationMs)*time.Millisecond, sessionID[:8])
+		cm.mcpManager.GetWebhooks().Notify(webhook.Event{
+			Type:    config.WebhookOperationTimeout,
+			Server:  operation.Server,
+			Session: sessionID,
+			Reason:  "Operation exceeded the server's operation timeout, session cleaned up",
+			Details: map[string]interface{}{"method": operation.Method, "tool": operation.Tool, "durationMs": operation.DurationMs},
+		})
+	}
+}
+
+// cleanupWhenIdle wraps a session's cleanup so it waits for the session's operations to finish
+// Operations past their server's operation timeout don't hold it back. The
+// cleanup is dropped if the client resumes the session while it waits.
+func (s *Server) cleanupWhenIdle(sessionID string, cleanup func()) func() {
+	deferred := false
+	var attempt func()
+	attempt = func() {
+		active, timedOut := s.connectionManager.sessionOperations(sessionID)
+		if len(active) > 0 {
+			if !deferred {
+				logger.System().Info("OPERATION PROTECTION: Deferring cleanup of session %s until its %d operations finish", sessionID[:8], len(active))
+				deferred = true
+			}
+			s.sessionResumer.Defer(sessionID, operationPollInterval, attempt)
+			return
+		}
+		s.connectionManager.reportExpiredOperations(sessionID, timedOut)
+		cleanup()
+	}
+	return attempt
+}
@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/protocol"
+)
+
+// testAdminToken is the ADMIN_TOKEN of tests calling admin endpoints that change anything
+const testAdminToken = "admin"
+
+// adminRequest sends an admin API request to the proxy and returns the recorder
+func adminRequest(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = "localhost"
+	req.Header.Set("X-Admin-Token", testAdminToken)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestOperationCancellation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		AdminToken: testAdminToken,
+		MCPServers: map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	started := embedded.Server.sseEvents.Attach(client.SessionID())
+
+	// The slow call is listed while it runs, then cancelled by its ID
+	go func() {
+		waitStarted(t, started)
+
+		recorder := adminRequest(embedded.Handler, "GET", "/admin/operations?server=helper&session="+client.SessionID()[:8])
+		var listing struct {
+			Operations []mcp.OperationStatus `json:"operations"`
+			Count      int                   `json:"count"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &listing); err != nil || listing.Count != 1 {
+			t.Errorf("Expected one listed operation, got %d (%v): %s", listing.Count, err, recorder.Body.String())
+			return
+		}
+		operation := listing.Operations[0]
+		if operation.Method != "tools/call" || operation.Tool != "slow" || operation.Session != client.SessionID() {
+			t.Errorf("Unexpected operation: %+v", operation)
+		}
+
+		if recorder := adminRequest(embedded.Handler, "DELETE", "/admin/operations/"+operation.ID); recorder.Code != http.StatusOK {
+			t.Errorf("Expected HTTP 200 cancelling the operation, got %d", recorder.Code)
+		}
+		if recorder := adminRequest(embedded.Handler, "DELETE", "/admin/operations/"+operation.ID); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected HTTP 404 for an operation no longer in flight, got %d", recorder.Code)
+		}
+	}()
+	response, err := client.CallTool(ctx, "slow", nil) // Request 2
+	if err != nil || response.Error == nil || response.Error.Code != protocol.RequestCancelled {
+		t.Fatalf("Expected a request cancelled error, got %+v (%v)", response, err)
+	}
+
+	if recorder := adminRequest(embedded.Handler, "GET", "/admin/operations?server=other"); !strings.Contains(recorder.Body.String(), `"count":0`) {
+		t.Errorf("Expected no operations for another server, got %s", recorder.Body.String())
+	}
+
+	// The server was told, with the administrator as the reason
+	response, err = client.CallTool(ctx, "cancelled", nil)
+	if err != nil || response.Error != nil {
+		t.Fatalf("tools/call failed: %+v (%v)", response, err)
+	}
+	received := fmt.Sprint(response.Result)
+	if !strings.Contains(received, `"requestId":2`) || !strings.Contains(received, "Request cancelled by an administrator") {
+		t.Errorf("Expected the server to receive the cancellation, got %s", received)
+	}
+}
+
+func TestOperationAwareCleanup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+	defer func(interval time.Duration) { operationPollInterval = interval }(operationPollInterval)
+	operationPollInterval = 10 * time.Millisecond
+
+	embedded, err := NewInProcess(&config.Config{
+		AdminToken: testAdminToken,
+		MCPServers: map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+	server := embedded.Server
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	sessionID := client.SessionID()
+	started := server.sseEvents.Attach(sessionID)
+
+	// An idle connection is kept while its session runs an operation
+	connCtx, connCancel := context.WithCancel(context.Background())
+	defer connCancel()
+	server.connectionManager.AddConnection(sessionID, "helper", connCtx, connCancel)
+
+	callCtx, abortCall := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.CallTool(callCtx, "slow", nil)
+	}()
+	waitStarted(t, started)
+
+	server.connectionManager.CleanupStaleConnections(0, 0)
+	if server.connectionManager.connection(sessionID) == nil {
+		t.Error("Expected the connection to be kept while its operation runs")
+	}
+
+	// The session's cleanup waits for the operation to finish
+	cleaned := make(chan struct{})
+	server.sessionResumer.Schedule(sessionID, server.cleanupWhenIdle(sessionID, func() { close(cleaned) }))
+	select {
+	case <-cleaned:
+		t.Fatal("Expected the cleanup to be deferred while the operation runs")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if server.sessionResumer.Pending() != 1 {
+		t.Errorf("Expected the deferred cleanup to be pending, got %d", server.sessionResumer.Pending())
+	}
+
+	abortCall()
+	<-done
+	select {
+	case <-cleaned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the cleanup to run once the operation finished")
+	}
+
+	server.connectionManager.CleanupStaleConnections(0, 0)
+	if server.connectionManager.connection(sessionID) != nil {
+		t.Error("Expected the idle connection to be removed once the operation finished")
+	}
+}
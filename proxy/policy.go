@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+	"remote-mcp-proxy/state"
+)
+
+// Policy decides which tool calls remote clients may make, see config.Policy
+type Policy struct {
+	defaultEffect string
+	rules         []policyRule
+}
+
+// policyRule is a config.PolicyRule with its argument expressions compiled
+type policyRule struct {
+	config.PolicyRule
+	arguments map[string]*regexp.Regexp
+}
+
+// PolicyDecision is the outcome of a tool call's evaluation
+type PolicyDecision struct {
+	Effect string // config.PolicyAllow, config.PolicyDeny or config.PolicyRequireApproval
+	Rule   int    // Number of the deciding rule, counting from 1 (0 for the default effect)
+	Reason string // Reason of the deciding rule
+}
+
+// NewPolicy compiles the configured policy (nil, allowing every call, when there is none)
+func NewPolicy(cfg *config.Policy) *Policy {
+	if cfg == nil {
+		return nil
+	}
+	policy := &Policy{defaultEffect: cfg.Default}
+	if policy.defaultEffect == "" {
+		policy.defaultEffect = config.PolicyAllow
+	}
+	for _, rule := range cfg.Rules {
+		compiled := policyRule{PolicyRule: rule, arguments: make(map[string]*regexp.Regexp, len(rule.Arguments))}
+		for dotted, expression := range rule.Arguments {
+			compiled.arguments[dotted] = regexp.MustCompile(expression) // Checked by config validation
+		}
+		policy.rules = append(policy.rules, compiled)
+	}
+	return policy
+}
+
+// Evaluate decides a tool call by the first rule matching it
+func (p *Policy) Evaluate(principal, serverName, toolName string, arguments interface{}) PolicyDecision {
+	if p == nil {
+		return PolicyDecision{Effect: config.PolicyAllow}
+	}
+	for i, rule := range p.rules {
+		if rule.matches(principal, serverName, toolName, arguments) {
+			return PolicyDecision{Effect: rule.Effect, Rule: i + 1, Reason: rule.Reason}
+		}
+	}
+	return PolicyDecision{Effect: p.defaultEffect}
+}
+
+// matches reports whether a rule applies to a tool call
+func (r policyRule) matches(principal, serverName, toolName string, arguments interface{}) bool {
+	if len(r.Principals) > 0 && !matchesAny(r.Principals, principal) {
+		return false
+	}
+	if len(r.Servers) > 0 && !matchesAny(r.Servers, serverName) {
+		return false
+	}
+	// Tool patterns match the original and the normalized name, as tool filters do
+	if len(r.Tools) > 0 && !(protocol.ToolFilter{Allowed: r.Tools}).Allows(toolName) {
+		return false
+	}
+	for dotted, expression := range r.arguments {
+		value, found := argumentAt(arguments, strings.Split(dotted, "."))
+		if !found || !expression.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// argumentAt returns the argument at keys as a string, JSON encoded unless it is one
+// Numbers index arrays.
+func argumentAt(node interface{}, keys []string) (string, bool) {
+	for _, key := range keys {
+		switch typed := node.(type) {
+		case map[string]interface{}:
+			child, exists := typed[key]
+			if !exists {
+				return "", false
+			}
+			node = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(typed) {
+				return "", false
+			}
+			node = typed[index]
+		default:
+			return "", false
+		}
+	}
+	if text, ok := node.(string); ok {
+		return text, true
+	}
+	encoded, err := json.Marshal(node)
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+// policyCall is a tool call submitted to the policy
+type policyCall struct {
+	principal     string
+	session       string
+	server        string
+	tool          string
+	arguments     interface{}
+	progressToken interface{} // Of the client's request, nil when it asked for no progress
+}
+
+// newPolicyCall describes a tools/call request of a session for the policy
+func newPolicyCall(r *http.Request, sessionID, serverName, toolName string, params map[string]interface{}) policyCall {
+	principal, _ := r.Context().Value("mcpPrincipal").(string)
+	meta, _ := params["_meta"].(map[string]interface{})
+	return policyCall{
+		principal:     principal,
+		session:       sessionID,
+		server:        serverName,
+		tool:          toolName,
+		arguments:     params["arguments"],
+		progressToken: meta["progressToken"],
+	}
+}
+
+// refusedByPolicy answers a tool call the policy doesn't allow with an error, and reports whether it did so
+// Calls requiring approval are held until an approver decides them, see
+// awaitApproval. Denials are recorded in the incident history.
+func (s *Server) refusedByPolicy(w http.ResponseWriter, r *http.Request, call policyCall, id interface{}, isRemoteMCP bool) bool {
+	decision := s.policy.Evaluate(call.principal, call.server, call.tool, call.arguments)
+	switch decision.Effect {
+	case config.PolicyAllow:
+		return false
+	case config.PolicyRequireApproval:
+		approved, refusal := s.awaitApproval(r, call, decision)
+		if approved {
+			return false
+		}
+		if refusal != "" {
+			s.sendErrorResponse(w, id, protocol.InvalidRequest, refusal, isRemoteMCP)
+		}
+		return true
+	}
+
+	message := decision.Reason
+	if message == "" {
+		message = fmt.Sprintf("Tool '%s' is not allowed by the proxy policy", call.tool)
+	}
+	logger.System().Warn("Policy denied call to tool %s on server %s by %q (rule %d)", call.tool, call.server, call.principal, decision.Rule)
+	s.mcpManager.GetIncidentStore().RecordIncident(state.Incident{
+		Kind:    state.IncidentPolicy,
+		Server:  call.server,
+		Actor:   call.principal,
+		Action:  decision.Effect,
+		Reason:  message,
+		Success: true,
+		Details: map[string]interface{}{"tool": call.tool, "rule": decision.Rule},
+	})
+	s.sendErrorResponse(w, id, protocol.InvalidRequest, message, isRemoteMCP)
+	return true
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/protocol"
+	"remote-mcp-proxy/state"
+)
+
+func TestPolicyEvaluate(t *testing.T) {
+	policy := NewPolicy(&config.Policy{Rules: []config.PolicyRule{
+		{Effect: config.PolicyDeny, Tools: []string{"delete_*"}, Arguments: map[string]string{"path": "^/etc/", "options.recursive": "^true$"}, Reason: "Recursive deletes under /etc are forbidden"},
+		{Effect: config.PolicyAllow, Principals: []string{"api-key:ops"}},
+		{Effect: config.PolicyRequireApproval, Servers: []string{"prod-*"}},
+		{Effect: config.PolicyDeny, Tools: []string{"drop_table"}},
+	}})
+
+	recursive := map[string]interface{}{"path": "/etc/nginx", "options": map[string]interface{}{"recursive": true}}
+	for _, tc := range []struct {
+		principal, server, tool string
+		arguments               interface{}
+		effect                  string
+		rule                    int
+	}{
+		{"api-key:ops", "files", "delete_file", recursive, config.PolicyDeny, 1},
+		{"api-key:ops", "files", "delete_file", map[string]interface{}{"path": "/etc/nginx"}, config.PolicyAllow, 2},
+		{"oidc:alice", "prod-db", "query", nil, config.PolicyRequireApproval, 3},
+		{"oidc:alice", "db", "drop_table", nil, config.PolicyDeny, 4},
+		{"oidc:alice", "db", "query", nil, config.PolicyAllow, 0},
+	} {
+		decision := policy.Evaluate(tc.principal, tc.server, tc.tool, tc.arguments)
+		if decision.Effect != tc.effect || decision.Rule != tc.rule {
+			t.Errorf("%s calling %s/%s: expected %s by rule %d, got %+v", tc.principal, tc.server, tc.tool, tc.effect, tc.rule, decision)
+		}
+	}
+
+	if decision := (*Policy)(nil).Evaluate("", "db", "drop_table", nil); decision.Effect != config.PolicyAllow {
+		t.Errorf("Expected no policy to allow every call, got %+v", decision)
+	}
+	strict := NewPolicy(&config.Policy{Default: config.PolicyDeny})
+	if decision := strict.Evaluate("oidc:alice", "db", "query", nil); decision.Effect != config.PolicyDeny {
+		t.Errorf("Expected the default effect, got %+v", decision)
+	}
+}
+
+func TestPolicyInProcess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+		Policy: &config.Policy{Rules: []config.PolicyRule{
+			{Effect: config.PolicyAllow, Principals: []string{"api-key:ops"}},
+			{Effect: config.PolicyDeny, Tools: []string{"echo"}, Arguments: map[string]string{"text": "^rm "}},
+			{Effect: config.PolicyDeny, Tools: []string{"slow"}, Reason: "The slow tool is disabled"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+	incidents, _ := state.NewStore("", 100, time.Hour)
+	embedded.Server.mcpManager.EnableIncidentHistory(incidents)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	response, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "rm -rf /"})
+	if err != nil || response.Error == nil || response.Error.Code != protocol.InvalidRequest {
+		t.Fatalf("Expected the call to be denied, got %+v (%v)", response, err)
+	}
+	response, err = client.CallTool(ctx, "slow", nil)
+	if err != nil || response.Error == nil || response.Error.Message != "The slow tool is disabled" {
+		t.Errorf("Expected the rule's reason, got %+v (%v)", response, err)
+	}
+	response, err = client.CallTool(ctx, "echo", map[string]interface{}{"text": "ls"})
+	if err != nil || !strings.Contains(fmt.Sprint(response.Result), `echo:{"text":"ls"}`) {
+		t.Errorf("Expected the call to be allowed, got %+v (%v)", response, err)
+	}
+
+	if refusals := incidents.Incidents(state.IncidentFilter{Kind: state.IncidentPolicy}); len(refusals) != 2 {
+		t.Errorf("Expected both refusals in the incident history, got %+v", refusals)
+	}
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+// handleLive answers liveness probes: the process is up and serving HTTP
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	logger.System().Trace("Liveness probe from %s", r.RemoteAddr)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "HEAD" {
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "alive",
+		"uptimeSeconds": s.uptimeSeconds(),
+	})
+}
+
+// handleReady answers readiness probes: 200 once startup has finished and every
+// configured server has started at least once or failed to, 503 before
+//
+// Servers in maintenance mode or that failed to start don't hold readiness
+// back, so the others are served. A server crashing later keeps the proxy
+// ready too; /health/servers and alerts report it instead.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	startupComplete := s.mcpManager.StartupComplete()
+
+	// StartAll holds the manager lock until it returns, so only list servers afterwards
+	pending := []string{}
+	failed := []string{}
+	if startupComplete {
+		for _, server := range s.mcpManager.GetAllServers() {
+			switch {
+			case server.StartedAt != nil || server.Maintenance:
+			case server.Error != "":
+				failed = append(failed, server.Name)
+			default:
+				pending = append(pending, server.Name)
+			}
+		}
+		sort.Strings(pending)
+		sort.Strings(failed)
+	}
+
+	ready := startupComplete && len(pending) == 0
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+		logger.System().Debug("Readiness probe from %s: not ready (startup complete: %v, pending servers: %v)", r.RemoteAddr, startupComplete, pending)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if r.Method == "HEAD" {
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ready":           ready,
+		"startupComplete": startupComplete,
+		"pendingServers":  pending,
+		"failedServers":   failed,
+		"timestamp":       time.Now(),
+	})
+}
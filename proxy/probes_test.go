@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+func TestProbes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	mcpManager := mcp.NewManager(map[string]config.MCPServer{
+		"helper": helperMCPServerConfig(),
+		"broken": {Command: "/this/command/does/not/exist"},
+	})
+	defer mcpManager.StopAll()
+	router := NewServer(mcpManager).Router()
+
+	probe := func(path string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to parse %s response: %v", path, err)
+		}
+		return w.Code, body
+	}
+
+	if code, body := probe("/live"); code != http.StatusOK || body["status"] != "alive" {
+		t.Errorf("Expected /live to answer alive before startup, got %d: %v", code, body)
+	}
+	if code, body := probe("/ready"); code != http.StatusServiceUnavailable || body["startupComplete"] != false {
+		t.Errorf("Expected /ready to answer 503 before startup, got %d: %v", code, body)
+	}
+
+	// A server failing to start doesn't keep the others from being served
+	if err := mcpManager.StartAll(); err == nil {
+		t.Fatal("Expected the broken server to fail to start")
+	}
+	code, body := probe("/ready")
+	if code != http.StatusOK || body["ready"] != true {
+		t.Errorf("Expected /ready to answer 200 after startup, got %d: %v", code, body)
+	}
+	if failed, _ := body["failedServers"].([]interface{}); len(failed) != 1 || failed[0] != "broken" {
+		t.Errorf("Expected the broken server in failedServers, got %v", body["failedServers"])
+	}
+}
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+// ReconnectTokenStore issues rolling reconnect tokens for SSE sessions
+//
+// Every keep-alive event carries a fresh token. A client reconnecting with an
+// existing Mcp-Session-Id presents the most recent token it saw, proving it is
+// the same client that held the previous connection rather than someone who
+// merely learned the session ID. The last few tokens stay valid so a single
+// missed keep-alive does not lock a client out, and history survives a
+// disconnect for a grace period so the session can be resumed.
+type ReconnectTokenStore struct {
+	sessions    map[string]*reconnectHistory
+	historySize int
+	grace       time.Duration
+	mu          sync.Mutex
+}
+
+// reconnectHistory holds the recently issued tokens of a session
+type reconnectHistory struct {
+	tokens         []string
+	disconnectedAt time.Time // Zero while a connection is active
+}
+
+// NewReconnectTokenStore creates a store keeping historySize tokens per session
+// and forgetting disconnected sessions after grace
+func NewReconnectTokenStore(historySize int, grace time.Duration) *ReconnectTokenStore {
+	if historySize <= 0 {
+		historySize = 1
+	}
+	return &ReconnectTokenStore{
+		sessions:    make(map[string]*reconnectHistory),
+		historySize: historySize,
+		grace:       grace,
+	}
+}
+
+// Rotate issues a new token for the session and returns it
+func (rs *ReconnectTokenStore) Rotate(sessionID string) string {
+	token := generateRandomString(32)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	history, exists := rs.sessions[sessionID]
+	if !exists {
+		history = &reconnectHistory{}
+		rs.sessions[sessionID] = history
+	}
+	history.tokens = append(history.tokens, token)
+	if len(history.tokens) > rs.historySize {
+		history.tokens = history.tokens[len(history.tokens)-rs.historySize:]
+	}
+	history.disconnectedAt = time.Time{}
+	return token
+}
+
+// Validate reports whether the session has token history and whether token matches it
+func (rs *ReconnectTokenStore) Validate(sessionID, token string) (known bool, valid bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	history, exists := rs.sessions[sessionID]
+	if !exists {
+		return false, false
+	}
+	if token == "" {
+		return true, false
+	}
+	for _, issued := range history.tokens {
+		if subtle.ConstantTimeCompare([]byte(issued), []byte(token)) == 1 {
+			return true, true
+		}
+	}
+	return true, false
+}
+
+// MarkDisconnected starts the grace period after which the session's tokens expire
+func (rs *ReconnectTokenStore) MarkDisconnected(sessionID string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if history, exists := rs.sessions[sessionID]; exists {
+		history.disconnectedAt = time.Now()
+	}
+}
+
+// CleanupExpired forgets sessions that have been disconnected longer than the grace period
+func (rs *ReconnectTokenStore) CleanupExpired() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for sessionID, history := range rs.sessions {
+		if !history.disconnectedAt.IsZero() && now.Sub(history.disconnectedAt) > rs.grace {
+			delete(rs.sessions, sessionID)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectTokenRotation(t *testing.T) {
+	store := NewReconnectTokenStore(2, time.Minute)
+
+	if known, _ := store.Validate("session-1", "anything"); known {
+		t.Error("Expected unknown session before any token is issued")
+	}
+
+	first := store.Rotate("session-1")
+	second := store.Rotate("session-1")
+	if first == second {
+		t.Fatal("Expected rotated tokens to differ")
+	}
+
+	// The last two tokens are accepted
+	for _, token := range []string{first, second} {
+		if known, valid := store.Validate("session-1", token); !known || !valid {
+			t.Errorf("Expected token %s to be valid", token)
+		}
+	}
+
+	// A third rotation pushes the oldest token out of the history
+	store.Rotate("session-1")
+	if _, valid := store.Validate("session-1", first); valid {
+		t.Error("Expected oldest token to be rejected after rotation")
+	}
+
+	if known, valid := store.Validate("session-1", ""); !known || valid {
+		t.Error("Expected empty token to be rejected for a known session")
+	}
+	if _, valid := store.Validate("session-1", "forged"); valid {
+		t.Error("Expected forged token to be rejected")
+	}
+}
+
+func TestReconnectTokenExpiry(t *testing.T) {
+	store := NewReconnectTokenStore(3, 10*time.Millisecond)
+
+	token := store.Rotate("session-1")
+	store.Rotate("session-2")
+
+	// Active sessions never expire
+	store.MarkDisconnected("session-1")
+	time.Sleep(20 * time.Millisecond)
+
+	if removed := store.CleanupExpired(); removed != 1 {
+		t.Errorf("Expected 1 expired session, got %d", removed)
+	}
+	if known, _ := store.Validate("session-1", token); known {
+		t.Error("Expected disconnected session to be forgotten after grace period")
+	}
+	if known, _ := store.Validate("session-2", ""); !known {
+		t.Error("Expected connected session to be kept")
+	}
+}
@@ -0,0 +1,178 @@
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+)
+
+// publicBaseURL returns the scheme and host the client reached the proxy at, e.g. "https://memory.mcp.example.com"
+// X-Forwarded-Proto and X-Forwarded-Host are honored only from trusted proxies
+// (TRUSTED_PROXIES); other clients get the listener's own scheme and Host, so
+// they can't point session URLs elsewhere.
+func (s *Server) publicBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+
+	if r.Header.Get("X-Forwarded-Proto") != "" || r.Header.Get("X-Forwarded-Host") != "" {
+		if !s.config.TrustsProxy(r.RemoteAddr) {
+			logger.System().Debug("Ignoring forwarded headers from untrusted peer %s", r.RemoteAddr)
+			return scheme + "://" + host
+		}
+		if proto := forwardedValue(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwarded := forwardedValue(r.Header.Get("X-Forwarded-Host")); forwarded != "" {
+			host = forwarded
+		}
+	}
+	return scheme + "://" + host
+}
+
+// forwardedValue returns the first value of a forwarded header, the one set by the proxy nearest the client
+func forwardedValue(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	return strings.ToLower(strings.TrimSpace(first))
+}
+
+// rewritesResourceURIs reports whether a server's resource URIs are served through proxy URLs
+func (s *Server) rewritesResourceURIs(serverName string) bool {
+	if s.config == nil {
+		return false
+	}
+	server, exists := s.config.MCPServers[serverName]
+	return exists && server.RewriteResourceURIs
+}
+
+// restoreResourceURIs prepares resource URI rewriting for a request to a server that has it enabled
+// It records the client's base URL for the session's responses and notifications, and maps
+// proxy URLs in the request back to the server's own URIs.
+func (s *Server) restoreResourceURIs(r *http.Request, sessionID, serverName, method string, request []byte) []byte {
+	if !s.rewritesResourceURIs(serverName) {
+		return request
+	}
+	s.translator.SetResourceBaseURL(sessionID, s.publicBaseURL(r))
+	return s.translator.RestoreResourceURIs(serverName, method, request)
+}
+
+// handleResourceContent serves a backend resource behind a rewritten URI: /resources/{server}/{encoded}
+// The resource is read with resources/read and returned with its MIME type; binary
+// contents are decoded from base64.
+func (s *Server) handleResourceContent(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	serverName := vars["server"]
+
+	principal, err := s.authenticatePrincipal(r)
+	if err != nil {
+		if writeRateLimited(w, err) {
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer realm=\"Remote MCP Server\"")
+		writeError(w, http.StatusUnauthorized, ErrorUnauthorized, "Unauthorized")
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), "mcpPrincipal", principal))
+
+	// Resources of other tenants' servers are as unknown as the servers
+	var admitted bool
+	if r, admitted = s.admitTenant(w, r, principal, serverName); !admitted {
+		return
+	}
+	if !s.rewritesResourceURIs(serverName) {
+		writeError(w, http.StatusNotFound, ErrorNotFound, fmt.Sprintf("MCP server '%s' does not serve resources through the proxy", serverName))
+		return
+	}
+	uri, err := protocol.DecodeResourceURI(vars["resource"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	sessionID := s.getSessionID(r)
+	mcpServer, exists := s.mcpManager.GetServerForSession(sessionID, serverName)
+	if !exists {
+		s.writeServerUnavailable(w, serverName)
+		return
+	}
+
+	request, err := json.Marshal(protocol.JSONRPCMessage{
+		JSONRPC: "2.0",
+		ID:      fmt.Sprintf("resource-%d", time.Now().UnixNano()),
+		Method:  "resources/read",
+		Params:  map[string]interface{}{"uri": uri},
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to create resources/read request")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), "sessionID", sessionID), 30*time.Second)
+	defer cancel()
+
+	responseBytes, err := s.forwardRequest(ctx, serverName, mcpServer, request)
+	if err != nil {
+		logger.System().Error(" Failed to read resource %s from server %s: %v", uri, serverName, err)
+		writeError(w, http.StatusBadGateway, ErrorUpstreamFailed, "Failed to communicate with MCP server")
+		return
+	}
+
+	var response struct {
+		Result *struct {
+			Contents []struct {
+				MimeType string  `json:"mimeType"`
+				Text     *string `json:"text"`
+				Blob     string  `json:"blob"`
+			} `json:"contents"`
+		} `json:"result"`
+		Error *protocol.RPCError `json:"error"`
+	}
+	if err := json.Unmarshal(responseBytes, &response); err != nil {
+		writeError(w, http.StatusBadGateway, ErrorUpstreamInvalid, "Invalid response from MCP server")
+		return
+	}
+	if response.Error != nil || response.Result == nil || len(response.Result.Contents) == 0 {
+		message := "Resource not found"
+		if response.Error != nil {
+			message = response.Error.Message
+		}
+		writeError(w, http.StatusNotFound, ErrorNotFound, message)
+		return
+	}
+
+	content := response.Result.Contents[0]
+	data := []byte(content.Blob)
+	contentType := content.MimeType
+	if content.Text != nil {
+		data = []byte(*content.Text)
+		if contentType == "" {
+			contentType = "text/plain; charset=utf-8"
+		}
+	} else {
+		if data, err = base64.StdEncoding.DecodeString(content.Blob); err != nil {
+			writeError(w, http.StatusBadGateway, ErrorUpstreamInvalid, "Invalid binary resource from MCP server")
+			return
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+	}
+
+	logger.System().Debug("Serving resource %s of server %s (%d bytes)", uri, serverName, len(data))
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		logger.System().Error(" Failed to write resource response: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+// SessionResumer keeps a disconnected session's state around until its client reconnects
+//
+// Claude.ai and browser EventSource clients reconnect after network blips.
+// Tearing the session down the moment its SSE stream closes would restart its
+// MCP server processes and lose their state, so cleanup is deferred for a
+// grace period and cancelled when the session is resumed.
+type SessionResumer struct {
+	grace   time.Duration
+	pending map[string]*time.Timer // sessionID -> scheduled cleanup
+	mu      sync.Mutex
+}
+
+// NewSessionResumer creates a resumer waiting grace before cleaning up (at once when 0)
+func NewSessionResumer(grace time.Duration) *SessionResumer {
+	return &SessionResumer{
+		grace:   grace,
+		pending: make(map[string]*time.Timer),
+	}
+}
+
+// Schedule runs cleanup once the grace period passes without the session being resumed
+func (sr *SessionResumer) Schedule(sessionID string, cleanup func()) {
+	if sr.grace <= 0 {
+		cleanup()
+		return
+	}
+	sr.schedule(sessionID, sr.grace, func() {
+		logger.System().Info("Session %s was not resumed within %v, cleaning up", sessionID[:8], sr.grace)
+		cleanup()
+	})
+}
+
+// Defer runs cleanup after delay unless the session is resumed first, whatever the grace period
+// It retries a cleanup that found the session still busy.
+func (sr *SessionResumer) Defer(sessionID string, delay time.Duration, cleanup func()) {
+	sr.schedule(sessionID, delay, cleanup)
+}
+
+// schedule replaces the session's pending cleanup with one running after delay
+func (sr *SessionResumer) schedule(sessionID string, delay time.Duration, cleanup func()) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if timer, exists := sr.pending[sessionID]; exists {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		sr.mu.Lock()
+		if sr.pending[sessionID] != timer {
+			sr.mu.Unlock()
+			return // Resumed or rescheduled meanwhile
+		}
+		delete(sr.pending, sessionID)
+		sr.mu.Unlock()
+
+		cleanup()
+	})
+	sr.pending[sessionID] = timer
+}
+
+// Resume cancels a session's scheduled cleanup and reports whether one was pending
+func (sr *SessionResumer) Resume(sessionID string) bool {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	timer, exists := sr.pending[sessionID]
+	if !exists {
+		return false
+	}
+	timer.Stop()
+	delete(sr.pending, sessionID)
+	return true
+}
+
+// Pending returns the number of disconnected sessions awaiting their client
+func (sr *SessionResumer) Pending() int {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return len(sr.pending)
+}
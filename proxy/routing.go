@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// routeAttempt records one way the router tried to select a request's server
+type routeAttempt struct {
+	Strategy  string `json:"strategy"`            // "basePath", "subdomain" (including server hostnames) or "path"
+	Candidate string `json:"candidate,omitempty"` // Server name taken from the host or path
+	Matched   bool   `json:"matched"`
+	Reason    string `json:"reason,omitempty"` // Why the attempt selected no server
+}
+
+// resolveServer selects the server a request is for, following the routing mode
+// A configured base path is tried first, on any host but another server's.
+// Then the host is tried; the path only when the host doesn't look like a
+// server's, so a subdomain can't reach another server's path. fromHost reports
+// a server selected by the host. attempts describe every strategy tried.
+func (s *Server) resolveServer(r *http.Request) (serverName string, fromHost bool, attempts []routeAttempt) {
+	if name, _ := s.config.BasePathServer(r.URL.Path); name != "" {
+		attempt := routeAttempt{Strategy: "basePath", Candidate: name, Matched: true}
+		if host := s.config.HostServer(r.Host); s.routeBySubdomain() && host != "" && host != name {
+			attempt.Matched = false
+			attempt.Reason = fmt.Sprintf("host selects server %s", host)
+		}
+		attempts = append(attempts, attempt)
+		if attempt.Matched {
+			return name, false, attempts
+		}
+		return "", false, attempts
+	}
+
+	if s.routeBySubdomain() {
+		attempt := routeAttempt{Strategy: "subdomain", Candidate: s.config.HostServer(r.Host)}
+		switch {
+		case attempt.Candidate == "":
+			attempt.Reason = "host does not match {server}.mcp.{domain} or a server hostname"
+		case !s.isRoutableServer(attempt.Candidate):
+			attempt.Reason = "no server with this name"
+		default:
+			attempt.Matched = true
+		}
+		attempts = append(attempts, attempt)
+		if attempt.Matched {
+			return attempt.Candidate, true, attempts
+		}
+		if attempt.Candidate != "" {
+			return "", false, attempts
+		}
+	}
+
+	if s.routeByPath() {
+		attempt := routeAttempt{Strategy: "path"}
+		segment := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[0]
+		switch {
+		case segment == "":
+			attempt.Reason = "path has no server segment"
+		case config.IsReservedName(segment):
+			attempt.Reason = "path is a proxy endpoint"
+		case s.config == nil:
+			attempt.Candidate = segment
+			attempt.Reason = "no configuration to check the server against"
+		case !s.isRoutableServer(segment):
+			attempt.Candidate = segment
+			attempt.Reason = "no server with this name"
+		default:
+			attempt.Candidate = segment
+			attempt.Matched = true
+		}
+		attempts = append(attempts, attempt)
+		if attempt.Matched {
+			return segment, false, attempts
+		}
+	}
+	return "", false, attempts
+}
+
+// isRoutableServer reports whether requests may select a server by name
+// Without a configuration every name is accepted, as before servers were configured.
+func (s *Server) isRoutableServer(serverName string) bool {
+	if s.config == nil {
+		return true
+	}
+	_, exists := s.config.MCPServers[serverName]
+	return exists || s.isAggregateServer(serverName)
+}
+
+// describeAttempts summarizes routing attempts for the log, e.g. "subdomain: memory"
+func describeAttempts(attempts []routeAttempt) string {
+	if len(attempts) == 0 {
+		return "no routing strategy enabled"
+	}
+	parts := make([]string, len(attempts))
+	for i, attempt := range attempts {
+		switch {
+		case attempt.Matched:
+			parts[i] = fmt.Sprintf("%s: %s", attempt.Strategy, attempt.Candidate)
+		case attempt.Candidate != "":
+			parts[i] = fmt.Sprintf("%s: %q, %s", attempt.Strategy, attempt.Candidate, attempt.Reason)
+		default:
+			parts[i] = fmt.Sprintf("%s: %s", attempt.Strategy, attempt.Reason)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// handleRouteNotFound answers requests matching no endpoint with routing diagnostics
+func (s *Server) handleRouteNotFound(w http.ResponseWriter, r *http.Request) {
+	s.sendRouteError(w, r, http.StatusNotFound)
+}
+
+// sendRouteError answers a request that selects no server or endpoint
+// The JSON body lists how routing was attempted, the servers that can be
+// reached and the URL formats the routing mode accepts.
+func (s *Server) sendRouteError(w http.ResponseWriter, r *http.Request, status int) {
+	_, _, attempts := s.resolveServer(r)
+	logger.System().Info("No route for %s %s%s (%s)", r.Method, r.Host, r.URL.Path, describeAttempts(attempts))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":           ErrorNoRoute,
+		"message":         fmt.Sprintf("No MCP server or endpoint matches %s %s%s", r.Method, r.Host, r.URL.Path),
+		"requestId":       w.Header().Get(requestIDHeader),
+		"routingMode":     s.routingMode(),
+		"attempts":        attempts,
+		"servers":         s.routableServerNames(r),
+		"expectedFormats": s.expectedURLFormats(r),
+	}); err != nil {
+		logger.System().Error("Failed to write route error: %v", err)
+	}
+}
+
+// routableServerNames returns the names requests to r's host can select, sorted
+// With tenants, only the servers of the tenant the host names are listed.
+func (s *Server) routableServerNames(r *http.Request) []string {
+	names := []string{}
+	if s.config == nil {
+		return names
+	}
+	tenant := s.config.HostTenant(r.Host)
+	for name := range s.config.MCPServers {
+		if s.config.TenantServes(tenant, name) {
+			names = append(names, name)
+		}
+	}
+	if s.isAggregateServer(AggregateServerName) {
+		names = append(names, AggregateServerName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// basePaths returns the base paths configured for servers, sorted
+func (s *Server) basePaths() []string {
+	var paths []string
+	if s.config == nil {
+		return paths
+	}
+	for _, server := range s.config.MCPServers {
+		if server.BasePath != "" {
+			paths = append(paths, server.BasePath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// expectedURLFormats returns the endpoint URL formats the routing mode accepts
+func (s *Server) expectedURLFormats(r *http.Request) []string {
+	var formats []string
+	if s.routeBySubdomain() {
+		domains := []string{"{domain}"}
+		if s.config != nil && s.config.GetDomain() != "" {
+			domains = append([]string{s.config.GetDomain()}, s.config.Domains...)
+		}
+		for _, domain := range domains {
+			formats = append(formats, fmt.Sprintf("https://{server}.mcp.%s/sse", domain))
+		}
+		if s.config != nil {
+			for _, name := range s.routableServerNames(r) {
+				for _, hostname := range s.config.MCPServers[name].Hostnames {
+					formats = append(formats, fmt.Sprintf("https://%s/sse", hostname))
+				}
+			}
+		}
+	}
+	if s.routeByPath() {
+		formats = append(formats, s.publicBaseURL(r)+"/{server}/sse")
+	}
+	for _, name := range s.routableServerNames(r) {
+		if basePath := s.config.MCPServers[name].BasePath; basePath != "" {
+			formats = append(formats, s.publicBaseURL(r)+basePath+"/sse")
+		}
+	}
+	return formats
+}
@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/state"
+)
+
+// ScanFinding counts the matches of one detector in a message
+type ScanFinding struct {
+	Detector string `json:"detector"`
+	Count    int    `json:"count"`
+}
+
+// scanDetector finds one kind of sensitive content
+type scanDetector struct {
+	name    string
+	pattern *regexp.Regexp
+	valid   func(match string) bool // Confirms a match, e.g. a checksum (nil accepts every match)
+}
+
+// builtinDetectors are the detectors of config.ScanDetectors
+var builtinDetectors = map[string]scanDetector{
+	"credit-card":    {pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: luhnValid},
+	"aws-access-key": {pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	"private-key":    {pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	"github-token":   {pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	"slack-token":    {pattern: regexp.MustCompile(`\bxox[abeoprs]-[A-Za-z0-9-]{10,}`)},
+	"jwt":            {pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+}
+
+// luhnValid reports whether the digits of a card number candidate pass the Luhn check
+func luhnValid(match string) bool {
+	sum, digits := 0, 0
+	for i := len(match) - 1; i >= 0; i-- {
+		c := match[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+// scanHook redacts or withholds tool results with sensitive content
+type scanHook struct {
+	hookMatch
+	detectors []scanDetector
+	block     bool
+	message   string
+}
+
+// newScanHook returns the "scan" hook described by a server's config
+// It applies to tools/call results unless the config names other methods.
+func newScanHook(match hookMatch, cfg config.Hook) *scanHook {
+	if len(match.methods) == 0 {
+		match.methods = []string{"tools/call"}
+	}
+	hook := &scanHook{hookMatch: match, block: cfg.Action == config.ScanBlock, message: cfg.Message}
+	if hook.message == "" {
+		hook.message = "Response withheld by the proxy: it contains sensitive content"
+	}
+
+	names := make(map[string]bool)
+	for _, name := range cfg.Detectors {
+		if name == config.ScanSecrets {
+			for _, secret := range config.ScanSecretDetectors {
+				names[secret] = true
+			}
+			continue
+		}
+		names[name] = true
+	}
+	for _, name := range config.ScanDetectors { // In a fixed order, so overlapping matches redact alike
+		if names[name] {
+			detector := builtinDetectors[name]
+			detector.name = name
+			hook.detectors = append(hook.detectors, detector)
+		}
+	}
+	custom := make([]string, 0, len(cfg.Patterns))
+	for name := range cfg.Patterns {
+		custom = append(custom, name)
+	}
+	sort.Strings(custom)
+	for _, name := range custom {
+		hook.detectors = append(hook.detectors, scanDetector{name: name, pattern: regexp.MustCompile(cfg.Patterns[name])}) // Checked by config validation
+	}
+	return hook
+}
+
+func (h *scanHook) BeforeRequest(msg *HookMessage) error {
+	return nil
+}
+
+func (h *scanHook) AfterResponse(msg *HookMessage) error {
+	if !h.matches(msg) {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(msg.Message))
+	decoder.UseNumber()
+	var response map[string]interface{}
+	if err := decoder.Decode(&response); err != nil {
+		if h.block { // A response that can't be inspected must not get past a blocking scan
+			logger.System().Warn("Withholding %s response of server %s that could not be scanned: %v", msg.Method, msg.Server, err)
+			return &BlockedError{Message: "Response withheld by the proxy: it could not be scanned"}
+		}
+		return err
+	}
+	result, exists := response["result"]
+	if !exists {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	response["result"] = h.scan(result, counts)
+	if len(counts) == 0 {
+		return nil
+	}
+	for _, detector := range h.detectors {
+		if counts[detector.name] > 0 {
+			msg.Findings = append(msg.Findings, ScanFinding{Detector: detector.name, Count: counts[detector.name]})
+		}
+	}
+
+	if h.block {
+		return &BlockedError{Message: h.message}
+	}
+	redacted, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	msg.Message = redacted
+	return nil
+}
+
+// scan counts the detectors' matches in every string inside node, returning it with the matches redacted
+func (h *scanHook) scan(node interface{}, counts map[string]int) interface{} {
+	switch typed := node.(type) {
+	case string:
+		for _, detector := range h.detectors {
+			typed = detector.pattern.ReplaceAllStringFunc(typed, func(match string) string {
+				if detector.valid != nil && !detector.valid(match) {
+					return match
+				}
+				counts[detector.name]++
+				return "[REDACTED:" + detector.name + "]"
+			})
+		}
+		return typed
+	case map[string]interface{}:
+		for key, child := range typed {
+			typed[key] = h.scan(child, counts)
+		}
+	case []interface{}:
+		for i, child := range typed {
+			typed[i] = h.scan(child, counts)
+		}
+	}
+	return node
+}
+
+// recordFindings adds the sensitive content hooks found in a response to the incident history
+// Only the detectors and their counts are recorded, never the content itself.
+func (s *Server) recordFindings(msg *HookMessage, blocked bool) {
+	if len(msg.Findings) == 0 {
+		return
+	}
+	action := config.ScanRedact
+	if blocked {
+		action = config.ScanBlock
+	}
+	logger.System().Warn("Scan found sensitive content in %s %s of server %s (%s): %v", msg.Method, msg.Tool, msg.Server, action, msg.Findings)
+	s.mcpManager.GetIncidentStore().RecordIncident(state.Incident{
+		Kind:    state.IncidentScan,
+		Server:  msg.Server,
+		Actor:   "scan-hook",
+		Action:  action,
+		Reason:  "Sensitive content in a " + msg.Method + " result",
+		Success: true,
+		Details: map[string]interface{}{"tool": msg.Tool, "session": msg.SessionID, "findings": msg.Findings},
+	})
+}
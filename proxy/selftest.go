@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+)
+
+// selfTestTimeout bounds a whole self-test, including a cold start of the server
+const selfTestTimeout = 60 * time.Second
+
+// SelfTestStep is the outcome of one request of a self-test
+type SelfTestStep struct {
+	Name       string      `json:"name"`
+	Success    bool        `json:"success"`
+	DurationMs int64       `json:"durationMs"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// handleSelfTest probes a server end to end through the proxy's own MCP endpoint:
+// initialize (starting an instance when the session mode needs one), tools/list
+// and the server's configured selfTest tool call
+//
+// The response is 200 when every step succeeded and 502 otherwise.
+func (s *Server) handleSelfTest(w http.ResponseWriter, r *http.Request) {
+	serverName := mux.Vars(r)["server"]
+	if s.config == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrorInternal, "Configuration not available")
+		return
+	}
+	serverConfig, exists := s.config.MCPServers[serverName]
+	if !exists {
+		writeServerError(w, http.StatusNotFound, ErrorServerNotFound, serverName, fmt.Sprintf("MCP server '%s' not found", serverName),
+			"GET /listmcp lists the MCP servers this host serves")
+		return
+	}
+
+	logger.System().Info("Running self-test of server %s for %s", serverName, adminActor(r))
+
+	ctx, cancel := context.WithTimeout(r.Context(), selfTestTimeout)
+	defer cancel()
+
+	client := s.newClient(s.Router(), serverName)
+	defer client.Close()
+
+	keepResult := func(result interface{}) interface{} { return result }
+	start := time.Now()
+	steps := []SelfTestStep{}
+	run := func(name string, call func() (*protocol.JSONRPCMessage, error), summarize func(result interface{}) interface{}) bool {
+		stepStart := time.Now()
+		response, err := call()
+		step := SelfTestStep{Name: name, DurationMs: time.Since(stepStart).Milliseconds()}
+		switch {
+		case err != nil:
+			step.Error = err.Error()
+		case response.Error != nil:
+			step.Error = fmt.Sprintf("JSON-RPC error %d: %s", response.Error.Code, response.Error.Message)
+		case toolResultIsError(response.Result):
+			step.Error = "Tool returned an error"
+			step.Result = response.Result
+		default:
+			step.Success = true
+			step.Result = summarize(response.Result)
+		}
+		steps = append(steps, step)
+		return step.Success
+	}
+
+	success := run("initialize", func() (*protocol.JSONRPCMessage, error) {
+		return client.Initialize(ctx)
+	}, keepResult) && run("tools/list", func() (*protocol.JSONRPCMessage, error) {
+		return client.ListTools(ctx)
+	}, summarizeToolsList)
+
+	if success && serverConfig.SelfTest != nil {
+		success = run("tools/call", func() (*protocol.JSONRPCMessage, error) {
+			return client.CallTool(ctx, serverConfig.SelfTest.Tool, serverConfig.SelfTest.Arguments)
+		}, keepResult)
+	}
+
+	status := http.StatusOK
+	if !success {
+		status = http.StatusBadGateway
+		logger.System().Warn("Self-test of server %s failed: %s", serverName, steps[len(steps)-1].Error)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"server":     serverName,
+		"success":    success,
+		"durationMs": time.Since(start).Milliseconds(),
+		"steps":      steps,
+		"timestamp":  time.Now(),
+	}); err != nil {
+		logger.System().Error("Failed to encode self-test response: %v", err)
+	}
+}
+
+// summarizeToolsList reduces a tools/list result to the tool count and names
+func summarizeToolsList(result interface{}) interface{} {
+	fields, _ := result.(map[string]interface{})
+	tools, _ := fields["tools"].([]interface{})
+	names := []string{}
+	for _, tool := range tools {
+		toolFields, _ := tool.(map[string]interface{})
+		if name, ok := toolFields["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	return map[string]interface{}{"count": len(names), "tools": names}
+}
+
+// toolResultIsError reports whether a tools/call result has isError set
+func toolResultIsError(result interface{}) bool {
+	fields, ok := result.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	isError, _ := fields["isError"].(bool)
+	return isError
+}
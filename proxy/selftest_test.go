@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestSelfTest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	working := helperMCPServerConfig()
+	working.SelfTest = &config.SelfTest{Tool: "echo", Arguments: map[string]interface{}{"text": "ping"}}
+	failing := helperMCPServerConfig()
+	failing.SelfTest = &config.SelfTest{Tool: "echo", Arguments: map[string]interface{}{"text": 42}}
+
+	embedded, err := NewInProcess(&config.Config{
+		AdminToken: testAdminToken,
+		MCPServers: map[string]config.MCPServer{"helper": working, "broken": failing},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	tests := []struct {
+		server     string
+		wantStatus int
+		wantSteps  int
+	}{
+		{"helper", http.StatusOK, 3},
+		{"broken", http.StatusBadGateway, 3},
+		{"missing", http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			w := adminRequest(embedded.Handler, "GET", "/selftest/"+tt.server)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantSteps == 0 {
+				return
+			}
+
+			var report struct {
+				Success bool           `json:"success"`
+				Steps   []SelfTestStep `json:"steps"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to parse report: %v", err)
+			}
+			if len(report.Steps) != tt.wantSteps || report.Success != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("Unexpected report: %s", w.Body.String())
+			}
+			if !report.Steps[0].Success || !report.Steps[1].Success {
+				t.Errorf("Expected initialize and tools/list to succeed, got %+v", report.Steps)
+			}
+		})
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -19,6 +23,8 @@ import (
 	"remote-mcp-proxy/mcp"
 	"remote-mcp-proxy/monitoring"
 	"remote-mcp-proxy/protocol"
+	"remote-mcp-proxy/state"
+	"remote-mcp-proxy/webhook"
 )
 
 // Server represents the HTTP proxy server
@@ -29,6 +35,21 @@ type Server struct {
 	config            *config.Config
 	healthChecker     *health.HealthChecker
 	resourceMonitor   *monitoring.ResourceMonitor
+	toolCallLimiter   *ToolCallLimiter
+	reconnectTokens   *ReconnectTokenStore
+	responseCache     *ResponseCache // nil when RESPONSE_CACHE_TTL is unset
+	sseEvents         *SSEEventLog
+	sessionResumer    *SessionResumer
+	inFlight          *InFlightRequests
+	oauth             *OAuthStore
+	oidc              *OIDCProvider // nil unless OAUTH_CONSENT=oidc
+	apiKeys           *APIKeyStore
+	hooks             *Hooks
+	policy            *Policy // nil when every tool call is allowed
+	approvals         *ToolApprovals
+	usage             *state.Usage // Tool calls counted per principal, see EnableUsageAccounting
+	quotaMu           sync.Mutex   // Held from a quota check to the reservation of the call it admits
+	startedAt         time.Time
 }
 
 // ConnectionManager manages active SSE connections
@@ -46,6 +67,22 @@ type ConnectionInfo struct {
 	ConnectedAt time.Time
 	Context     context.Context
 	Cancel      context.CancelFunc
+
+	TokenFingerprint string // Short hash of the bearer token that opened the connection
+	Principal        string // Who the token authenticates, e.g. "api-key:ci" or "oidc:alice"
+
+	lastActivity atomic.Int64 // Unix nanoseconds of the last request or keep-alive
+	metrics      connectionMetrics
+}
+
+// LastActivity returns when the client last sent a request or the connection last carried a keep-alive
+func (c *ConnectionInfo) LastActivity() time.Time {
+	return time.Unix(0, c.lastActivity.Load())
+}
+
+// touch records activity on the connection
+func (c *ConnectionInfo) touch() {
+	c.lastActivity.Store(time.Now().UnixNano())
 }
 
 // NewConnectionManager creates a new connection manager
@@ -69,13 +106,15 @@ func (cm *ConnectionManager) AddConnection(sessionID, serverName string, ctx con
 	}
 
 	// Add connection
-	cm.connections[sessionID] = &ConnectionInfo{
+	conn := &ConnectionInfo{
 		SessionID:   sessionID,
 		ServerName:  serverName,
 		ConnectedAt: time.Now(),
 		Context:     ctx,
 		Cancel:      cancel,
 	}
+	conn.touch()
+	cm.connections[sessionID] = conn
 
 	logger.System().Info("Added connection for session %s (total: %d/%d)", sessionID, len(cm.connections), cm.maxConnections)
 	return nil
@@ -96,6 +135,26 @@ func (cm *ConnectionManager) RemoveConnection(sessionID string) {
 	}
 }
 
+// SetTokenFingerprint records which bearer token opened a connection
+func (cm *ConnectionManager) SetTokenFingerprint(sessionID, fingerprint string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if conn, exists := cm.connections[sessionID]; exists {
+		conn.TokenFingerprint = fingerprint
+	}
+}
+
+// Touch records a request or keep-alive on a session's connection, keeping it from going idle
+func (cm *ConnectionManager) Touch(sessionID string) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if conn, exists := cm.connections[sessionID]; exists {
+		conn.touch()
+	}
+}
+
 // GetConnectionCount returns the current number of active connections
 func (cm *ConnectionManager) GetConnectionCount() int {
 	cm.mu.RLock()
@@ -118,8 +177,10 @@ func (cm *ConnectionManager) GetConnections() map[string]*ConnectionInfo {
 }
 
 // CleanupStaleConnections removes connections that have been inactive for too long
+// A connection is stale once it has seen no request or keep-alive for
+// idleTimeout, or when it has been open for more than maxAge (unlimited when 0).
 // OPERATION-AWARE CLEANUP: Respects active operations and server-specific timeouts
-func (cm *ConnectionManager) CleanupStaleConnections(maxAge time.Duration) {
+func (cm *ConnectionManager) CleanupStaleConnections(idleTimeout, maxAge time.Duration) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
@@ -128,40 +189,22 @@ func (cm *ConnectionManager) CleanupStaleConnections(maxAge time.Duration) {
 	var protected []string
 
 	for sessionID, conn := range cm.connections {
-		connectionAge := now.Sub(conn.ConnectedAt)
+		idle := now.Sub(conn.LastActivity())
+		expired := maxAge > 0 && now.Sub(conn.ConnectedAt) > maxAge
 
-		// Basic age check - if connection is newer than maxAge, skip
-		if connectionAge <= maxAge {
+		// Connections still in use and within their lifetime are kept
+		if idle <= idleTimeout && !expired {
 			continue
 		}
 
-		// OPERATION-AWARE LOGIC: Check if associated MCP servers have active operations
-		shouldProtect := false
-		if cm.mcpManager != nil {
-			// Get all servers for this session
-			sessionServers := cm.mcpManager.GetSessionServerMap(sessionID)
-
-			for serverName, server := range sessionServers {
-				if server.HasActiveOperations() {
-					// Check if operations are within server-specific timeout
-					if !server.IsOperationExpired() {
-						shouldProtect = true
-						protected = append(protected, fmt.Sprintf("%s:%s", sessionID[:8], serverName))
-						break
-					} else {
-						// Operations have expired, allow cleanup but log warning
-						logger.System().Warn("OPERATION TIMEOUT: Server %s operations exceeded %d seconds, allowing cleanup",
-							serverName, server.GetOperationTimeoutSec())
-					}
-				}
-			}
-		}
-
-		// Protect connections with active operations
-		if shouldProtect {
+		// OPERATION-AWARE LOGIC: Keep connections whose session has operations running
+		active, timedOut := cm.sessionOperations(sessionID)
+		if len(active) > 0 {
+			protected = append(protected, fmt.Sprintf("%s:%s", sessionID[:8], active[0].Server))
 			logger.System().Debug("OPERATION PROTECTION: Preserving connection %s with active operations", sessionID[:8])
 			continue
 		}
+		cm.reportExpiredOperations(sessionID, timedOut)
 
 		// Safe to remove - no active operations or operations have expired
 		if conn.Cancel != nil {
@@ -179,6 +222,15 @@ func (cm *ConnectionManager) CleanupStaleConnections(maxAge time.Duration) {
 	}
 }
 
+// notifySession reports a session starting or ending to the webhooks
+func (s *Server) notifySession(eventType, serverName, sessionID string) {
+	s.mcpManager.GetWebhooks().Notify(webhook.Event{
+		Type:    eventType,
+		Server:  serverName,
+		Session: sessionID,
+	})
+}
+
 // NewServer creates a new proxy server (backward compatibility)
 func NewServer(mcpManager *mcp.Manager) *Server {
 	return NewServerWithConfig(mcpManager, nil, nil, nil)
@@ -188,132 +240,216 @@ func NewServer(mcpManager *mcp.Manager) *Server {
 func NewServerWithConfig(mcpManager *mcp.Manager, cfg *config.Config, healthChecker *health.HealthChecker, resourceMonitor *monitoring.ResourceMonitor) *Server {
 	const maxConnections = 100 // Configurable connection limit
 
+	maxToolCalls, maxQueuedToolCalls := config.DefaultMaxConcurrentToolCalls, config.DefaultMaxQueuedToolCalls
+	if cfg != nil {
+		maxToolCalls, maxQueuedToolCalls = cfg.GetMaxConcurrentToolCalls(), cfg.GetMaxQueuedToolCalls()
+	}
+
 	server := &Server{
+		startedAt:         time.Now(),
 		mcpManager:        mcpManager,
 		translator:        protocol.NewTranslator(),
 		connectionManager: NewConnectionManager(maxConnections, mcpManager),
 		config:            cfg,
 		healthChecker:     healthChecker,
 		resourceMonitor:   resourceMonitor,
+		toolCallLimiter:   NewToolCallLimiter(maxToolCalls, maxQueuedToolCalls),
+		reconnectTokens:   NewReconnectTokenStore(3, 10*time.Minute), // Last 3 keep-alive tokens, 10 minute resume window
+		sseEvents:         NewSSEEventLog(config.DefaultSSEReplayEvents),
+		sessionResumer:    NewSessionResumer(0),
+		inFlight:          NewInFlightRequests(),
+		oauth:             NewOAuthStore(),
+		apiKeys:           NewAPIKeyStore(cfg),
+		hooks:             NewHooks(cfg),
+		approvals:         NewToolApprovals(0),
+	}
+	server.usage, _ = state.NewUsage("", config.DefaultUsageRetention)
+
+	if cfg != nil {
+		server.translator.SetToolNamespacing(cfg.ToolNamespacing, cfg.ToolNamespaceSeparator)
+		server.policy = NewPolicy(cfg.Policy)
+		server.approvals = NewToolApprovals(cfg.ApprovalTimeout)
+		for name, serverConfig := range cfg.MCPServers {
+			server.translator.SetToolFilter(name, serverConfig.AllowedTools, serverConfig.BlockedTools)
+		}
+		if cfg.ResponseCacheTTL > 0 {
+			server.responseCache = NewResponseCache(cfg.ResponseCacheTTL)
+			logger.System().Info("Caching discovery responses for %v", cfg.ResponseCacheTTL)
+		}
+		mcpManager.SetTimeoutTiers(mcp.TimeoutTiers{ColdStart: cfg.ColdStartTimeout, SteadyState: cfg.SteadyStateTimeout})
+		mcpManager.SetMaxResponseBytes(cfg.MaxResponseBytes)
+		mcpManager.SetStopGracePeriod(cfg.StopGracePeriod)
+		mcpManager.SetOperationTimeout(cfg.OperationTimeout)
+		mcpManager.SetSessionData(mcp.SessionDataOptions{Quota: cfg.SessionDiskQuota, Persist: cfg.SessionPersistence})
+		server.sessionResumer = NewSessionResumer(cfg.SessionResumeGrace)
+		server.sseEvents = NewSSEEventLog(cfg.SSEReplayEvents)
+
+		switch {
+		case cfg.OAuthConsent == config.ConsentPassword && cfg.OAuthConsentPassword == "":
+			logger.System().Warn("OAUTH_CONSENT is %s but OAUTH_CONSENT_PASSWORD is empty: no authorization can be approved", cfg.OAuthConsent)
+		case cfg.OAuthConsent == config.ConsentApprovalToken && cfg.AdminToken == "":
+			logger.System().Warn("OAUTH_CONSENT is approval-token but ADMIN_TOKEN is empty: no approval token can be issued")
+		case cfg.OAuthConsent == config.ConsentOIDC && (cfg.OIDCIssuer == "" || cfg.OIDCClientID == ""):
+			logger.System().Warn("OAUTH_CONSENT is oidc but OIDC_ISSUER or OIDC_CLIENT_ID is empty: no authorization can be approved")
+		case cfg.OAuthConsent == config.ConsentOIDC:
+			server.oidc = NewOIDCProvider(cfg)
+			logger.System().Info("OAuth authorizations are delegated to OIDC provider %s", cfg.OIDCIssuer)
+		case cfg.OAuthConsent != "" && cfg.OAuthConsent != config.ConsentOff:
+			logger.System().Info("OAuth authorizations require consent (%s)", cfg.OAuthConsent)
+		}
+		if cfg.AuthMode == config.AuthModeAPIKey {
+			logger.System().Info("MCP endpoints accept API keys only (%d configured)", len(server.apiKeys.List()))
+		}
 	}
+	mcpManager.SetNotificationHandler(server.handleServerNotification)
 
 	// Start background cleanup routine
 	go server.startConnectionCleanup()
 
 	logger.System().Info("Created proxy server with max %d connections", maxConnections)
+	logger.System().Info("Tool call limit per session: %d concurrent, %d queued", maxToolCalls, maxQueuedToolCalls)
 	if cfg != nil {
 		logger.System().Info("Configured domain: %s", cfg.GetDomain())
 	}
 	return server
 }
 
+// Translator returns the protocol translator holding session state
+func (s *Server) Translator() *protocol.Translator {
+	return s.translator
+}
+
+// sessionIdlePolicy returns how long connections may stay idle, and open at all (unlimited when 0)
+func (s *Server) sessionIdlePolicy() (idleTimeout, maxAge time.Duration) {
+	if s.config == nil || s.config.SessionIdleTimeout <= 0 {
+		return config.DefaultSessionIdleTimeout, 0
+	}
+	return s.config.SessionIdleTimeout, s.config.SessionMaxAge
+}
+
 // startConnectionCleanup starts a background goroutine to clean up stale connections
 func (s *Server) startConnectionCleanup() {
 	ticker := time.NewTicker(30 * time.Second) // Cleanup every 30 seconds
 	defer ticker.Stop()
 
-	maxAge := 2 * time.Minute // Remove connections older than 2 minutes for faster cleanup
+	idleTimeout, maxAge := s.sessionIdlePolicy()
 
-	logger.System().Info("Started automatic connection cleanup (interval: 30s, max age: %v)", maxAge)
+	logger.System().Info("Started automatic connection cleanup (interval: 30s, idle timeout: %v, max age: %v)", idleTimeout, maxAge)
 
 	for {
 		select {
 		case <-ticker.C:
 			beforeCount := s.connectionManager.GetConnectionCount()
-			s.connectionManager.CleanupStaleConnections(maxAge)
+			s.connectionManager.CleanupStaleConnections(idleTimeout, maxAge)
 			afterCount := s.connectionManager.GetConnectionCount()
 
 			if beforeCount != afterCount {
 				logger.System().Info("Automatic cleanup removed %d stale connections (%d -> %d active)",
 					beforeCount-afterCount, beforeCount, afterCount)
 			}
+
+			if expired := s.reconnectTokens.CleanupExpired(); expired > 0 {
+				logger.System().Debug("Expired reconnect tokens for %d disconnected sessions", expired)
+			}
+			if expired := s.oauth.CleanupExpired(); expired > 0 {
+				logger.System().Debug("Forgot %d expired OAuth codes and tokens", expired)
+			}
+			if s.oidc != nil {
+				s.oidc.CleanupExpired()
+			}
 		}
 	}
 }
 
-// subdomainMiddleware extracts MCP server name from subdomain or path
+// subdomainMiddleware extracts MCP server name from subdomain, custom hostname or path
 func (s *Server) subdomainMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Extract server name from subdomain: memory.mcp.domain.com → "memory"
-		host := r.Host
-
-		// Remove port if present
-		if colonIndex := strings.Index(host, ":"); colonIndex != -1 {
-			host = host[:colonIndex]
-		}
-
-		parts := strings.Split(host, ".")
+		serverName, fromHost, attempts := s.resolveServer(r)
+		logger.System().Debug(" Routing %s %s%s: %s", r.Method, r.Host, r.URL.Path, describeAttempts(attempts))
 
-		// Expected format: {server}.mcp.{domain}
-		if len(parts) >= 3 && parts[1] == "mcp" {
-			serverName := parts[0]
-			logger.System().Debug(" Extracted server name '%s' from host '%s'", serverName, r.Host)
-
-			// Validate server exists in configuration (if config is available)
-			if s.config != nil {
-				if _, exists := s.config.MCPServers[serverName]; !exists {
-					logger.System().Debug(" Server '%s' not found in configuration", serverName)
-					// Don't add to context if server doesn't exist
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
-
-			// Add server name to request context
+		if serverName != "" {
+			// Add server name to request context, noting whether the host selected it
 			ctx := context.WithValue(r.Context(), "mcpServer", serverName)
-			r = r.WithContext(ctx)
-		} else {
-			// If subdomain doesn't match, try to extract from path for fallback
-			// Pattern: /{server}/sse or /{server}/sessions/{sessionId}
-			pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-			if len(pathParts) >= 1 && pathParts[0] != "" {
-				// Check if it's a valid server name and not a utility endpoint
-				serverName := pathParts[0]
-				if serverName != "health" && serverName != "listmcp" && serverName != "listtools" &&
-					serverName != "cleanup" && serverName != "oauth" && serverName != ".well-known" {
-
-					// Validate server exists in configuration (if config is available)
-					if s.config != nil {
-						if _, exists := s.config.MCPServers[serverName]; exists {
-							logger.System().Debug(" Extracted server name '%s' from path '%s' (subdomain fallback)", serverName, r.URL.Path)
-							// Add server name to request context
-							ctx := context.WithValue(r.Context(), "mcpServer", serverName)
-							r = r.WithContext(ctx)
-						} else {
-							logger.System().Debug(" Path-based server '%s' not found in configuration", serverName)
-						}
-					}
-				}
-			}
-
-			if r.Context().Value("mcpServer") == nil {
-				logger.System().Debug(" Host '%s' doesn't match subdomain pattern {server}.mcp.{domain} and no valid server in path", r.Host)
+			if fromHost {
+				ctx = context.WithValue(ctx, "mcpServerFromHost", true)
 			}
+			r = r.WithContext(ctx)
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// routingMode returns how requests select a server (ROUTING_MODE)
+func (s *Server) routingMode() string {
+	if s.config == nil {
+		return config.RoutingBoth
+	}
+	return s.config.RoutingMode()
+}
+
+// routeBySubdomain reports whether {server}.mcp.{domain} hosts select a server
+func (s *Server) routeBySubdomain() bool {
+	return s.routingMode() != config.RoutingPath
+}
+
+// routeByPath reports whether /{server}/... paths select a server
+// Production deployments disable it so a subdomain cannot reach another server's path.
+func (s *Server) routeByPath() bool {
+	return s.routingMode() != config.RoutingSubdomain
+}
+
 // Router returns the HTTP router with all routes configured
 func (s *Server) Router() http.Handler {
+	// Every response, including routing errors, carries the request ID
+	return s.requestIDMiddleware(s.routes())
+}
+
+// routes registers the proxy's endpoints
+// The first path segment of each endpoint must be reserved in config, so that
+// no server name collides with it under path-based routing.
+func (s *Server) routes() *mux.Router {
 	r := mux.NewRouter()
 
 	// Apply subdomain detection middleware
 	r.Use(s.subdomainMiddleware)
 
 	// Root-level endpoints (standard Remote MCP format - subdomain-based)
-	r.HandleFunc("/sse", s.handleMCPRequest).Methods("GET", "POST")
-	r.HandleFunc("/sessions/{sessionId:[^/]+}", s.handleSessionMessage).Methods("POST")
+	if s.routeBySubdomain() {
+		r.HandleFunc("/sse", s.handleMCPRequest).Methods("GET", "POST")
+		r.HandleFunc("/sessions/{sessionId:[^/]+}", s.handleSessionMessage).Methods("POST")
+		r.HandleFunc("/sessions/{sessionId:[^/]+}/requests/{requestId:[^/]+}/cancel", s.handleCancelRequest).Methods("POST")
+	}
 
 	// Path-based endpoints (fallback for localhost and development)
-	r.HandleFunc("/{server:[^/]+}/sse", s.handleMCPRequest).Methods("GET", "POST")
-	r.HandleFunc("/{server:[^/]+}/sessions/{sessionId:[^/]+}", s.handleSessionMessage).Methods("POST")
+	if s.routeByPath() {
+		r.HandleFunc("/{server:[^/]+}/sse", s.handleMCPRequest).Methods("GET", "POST")
+		r.HandleFunc("/{server:[^/]+}/sessions/{sessionId:[^/]+}", s.handleSessionMessage).Methods("POST")
+		r.HandleFunc("/{server:[^/]+}/sessions/{sessionId:[^/]+}/requests/{requestId:[^/]+}/cancel", s.handleCancelRequest).Methods("POST")
+	}
+
+	// Base path endpoints (servers with a configured basePath, on any host)
+	for _, basePath := range s.basePaths() {
+		r.HandleFunc(basePath+"/sse", s.handleMCPRequest).Methods("GET", "POST")
+		r.HandleFunc(basePath+"/sessions/{sessionId:[^/]+}", s.handleSessionMessage).Methods("POST")
+		r.HandleFunc(basePath+"/sessions/{sessionId:[^/]+}/requests/{requestId:[^/]+}/cancel", s.handleCancelRequest).Methods("POST")
+		r.HandleFunc("/.well-known/oauth-protected-resource"+basePath+"/sse", s.handleProtectedResourceMetadata).Methods("GET", "OPTIONS")
+	}
+
+	// Browser and uptime checker probes (logged below INFO)
+	r.HandleFunc("/", s.handleRootProbe).Methods("HEAD")
+	r.HandleFunc("/", s.handleLanding).Methods("GET")
+	r.HandleFunc("/favicon.ico", s.handleFavicon).Methods("GET", "HEAD")
 
 	// Utility endpoints
-	r.HandleFunc("/health", s.handleHealth).Methods("GET", "OPTIONS")
+	r.HandleFunc("/health", s.handleHealth).Methods("GET", "HEAD", "OPTIONS")
+	r.HandleFunc("/live", s.handleLive).Methods("GET", "HEAD")
+	r.HandleFunc("/ready", s.handleReady).Methods("GET", "HEAD")
 	r.HandleFunc("/listmcp", s.handleListMCP).Methods("GET", "OPTIONS")
+	r.HandleFunc("/metrics", s.handleMetrics).Methods("GET", "OPTIONS")
 	r.HandleFunc("/listtools/{server:[^/]+}", s.handleListTools).Methods("GET", "OPTIONS")
+	r.HandleFunc("/selftest/{server:[^/]+}", s.requireAdmin(s.handleSelfTest)).Methods("GET", "POST", "OPTIONS")
+	r.HandleFunc("/resources/{server:[^/]+}/{resource:[^/]+}", s.handleResourceContent).Methods("GET", "OPTIONS")
 	r.HandleFunc("/cleanup", s.handleCleanup).Methods("POST", "OPTIONS")
 
 	// Health and monitoring endpoints
@@ -322,15 +458,56 @@ func (s *Server) Router() http.Handler {
 	r.HandleFunc("/health/sessions", s.handleSessionHealth).Methods("GET", "OPTIONS")
 	r.HandleFunc("/health/sessions/{sessionId:[^/]+}", s.handleSessionDetail).Methods("GET", "OPTIONS")
 
+	// Operator endpoints
+	r.HandleFunc("/admin/topology", s.requireAdmin(s.handleTopology)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/servers:batch", s.requireAdmin(s.handleServerBatch)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/admin/incidents", s.requireAdmin(s.handleIncidents)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/sessions/{sessionId:[^/]+}", s.requireAdmin(s.handleKillSession)).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/admin/sessions/{sessionId:[^/]+}/data", s.requireAdmin(s.handleSessionData)).Methods("GET", "DELETE", "OPTIONS")
+	r.HandleFunc("/admin/errors", s.requireAdmin(s.handleRecentErrors)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/operations", s.requireAdmin(s.handleOperations)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/operations/{id:[^/]+}", s.requireAdmin(s.handleCancelOperation)).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/admin/approvals", s.requireAdmin(s.handleApprovals)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/approvals/{id:[^/]+}/{decision:approve|deny}", s.requireAdmin(s.handleDecideApproval)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/usage", s.requireAdmin(s.handleUsage)).Methods("GET", "OPTIONS")
+
+	// Operator dashboard; its data comes from the endpoints above
+	r.HandleFunc("/ui", s.handleDashboard).Methods("GET")
+	r.HandleFunc("/ui/", s.handleDashboard).Methods("GET")
+
+	// Debug wire capture
+	r.HandleFunc("/debug/sessions/{sessionId:[^/]+}/trace", s.requireAdmin(s.handleSessionTrace)).Methods("GET", "OPTIONS")
+
+	// Log files and MCP subprocess stderr output
+	r.HandleFunc("/logs/system", s.requireAdmin(s.handleSystemLogs)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/admin/loglevel", s.requireAdmin(s.handleLogLevel)).Methods("GET", "POST", "OPTIONS")
+	r.HandleFunc("/logs/mcp/{server:[^/]+}", s.requireAdmin(s.handleMCPLogs)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/logs/sessions/{sessionId:[^/]+}", s.requireAdmin(s.handleSessionLogs)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/logs/{server:[^/]+}", s.requireAdmin(s.handleServerLogs)).Methods("GET", "OPTIONS")
+
 	// OAuth 2.0 Dynamic Client Registration endpoints
 	r.HandleFunc("/.well-known/oauth-authorization-server", s.handleOAuthMetadata).Methods("GET")
+	r.HandleFunc("/.well-known/oauth-protected-resource", s.handleProtectedResourceMetadata).Methods("GET", "OPTIONS")
+	r.HandleFunc("/.well-known/oauth-protected-resource/sse", s.handleProtectedResourceMetadata).Methods("GET", "OPTIONS")
+	r.HandleFunc("/.well-known/oauth-protected-resource/{server:[^/]+}/sse", s.handleProtectedResourceMetadata).Methods("GET", "OPTIONS")
 	r.HandleFunc("/oauth/register", s.handleClientRegistration).Methods("POST", "OPTIONS")
 	r.HandleFunc("/oauth/authorize", s.handleAuthorize).Methods("GET", "POST")
 	r.HandleFunc("/oauth/token", s.handleToken).Methods("POST", "OPTIONS")
+	r.HandleFunc("/oauth/callback", s.handleOIDCCallback).Methods("GET")
+	r.HandleFunc("/admin/oauth/approval-tokens", s.requireAdmin(s.handleApprovalToken)).Methods("POST", "OPTIONS")
+
+	// API keys
+	r.HandleFunc("/admin/api-keys", s.requireAdmin(s.handleAPIKeys)).Methods("GET", "POST", "OPTIONS")
+	r.HandleFunc("/admin/api-keys/{name}", s.requireAdmin(s.handleRevokeAPIKey)).Methods("DELETE", "OPTIONS")
+
+	// Anything else gets routing diagnostics
+	r.NotFoundHandler = http.HandlerFunc(s.handleRouteNotFound)
 
 	// Add CORS middleware
 	r.Use(s.corsMiddleware)
 
+	// Compress large responses for clients that accept it
+	r.Use(s.compressMiddleware)
 	return r
 }
 
@@ -339,28 +516,63 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	if _, err := w.Write([]byte(`{"status":"healthy"}`)); err != nil {
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "healthy",
+		"startedAt":     s.startedAt,
+		"uptimeSeconds": s.uptimeSeconds(),
+	}); err != nil {
 		logger.System().Error(" Failed to write health response: %v", err)
 	} else {
 		logger.System().Debug(" Health check response sent successfully")
 	}
 }
 
+// handleRootProbe answers HEAD / from uptime checkers without a body
+func (s *Server) handleRootProbe(w http.ResponseWriter, r *http.Request) {
+	logger.System().Trace("HEAD / probe from %s", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleFavicon answers browser favicon requests with an empty, cacheable response
+func (s *Server) handleFavicon(w http.ResponseWriter, r *http.Request) {
+	logger.System().Trace("Favicon request from %s", r.RemoteAddr)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // handleListMCP returns the list of all configured MCP servers and their status
 func (s *Server) handleListMCP(w http.ResponseWriter, r *http.Request) {
 	logger.System().Info("Handling listmcp request")
 
+	// With tenants, the servers of the tenant the host names (see config.Tenant)
+	tenant := s.config.HostTenant(r.Host)
 	servers := s.mcpManager.GetAllServers()
+	servers = slices.DeleteFunc(servers, func(server mcp.ServerStatus) bool { return !s.config.TenantServes(tenant, server.Name) })
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+
+	// Uptimes and request counts change all the time, so they don't count towards the ETag
+	stable := make([]mcp.ServerStatus, len(servers))
+	copy(stable, servers)
+	for i := range stable {
+		stable[i].UptimeSeconds = 0
+		stable[i].Requests = 0
+	}
+	if checkNotModified(w, r, weakETag(stable, s.startedAt)) {
+		logger.System().Debug("listmcp not modified")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	if err := json.NewEncoder(w).Encode(map[string]interface{}{
-		"servers": servers,
-		"count":   len(servers),
+		"servers":       servers,
+		"count":         len(servers),
+		"startedAt":     s.startedAt,
+		"uptimeSeconds": s.uptimeSeconds(),
 	}); err != nil {
 		logger.System().Error(" Failed to encode listmcp response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to encode response")
 	} else {
 		logger.System().Info("Successfully returned list of %d MCP servers", len(servers))
 	}
@@ -374,8 +586,8 @@ func (s *Server) handleCleanup(w http.ResponseWriter, r *http.Request) {
 	connectionsBefore := s.connectionManager.GetConnections()
 	countBefore := len(connectionsBefore)
 
-	// Force cleanup of all stale connections (older than 1 second for immediate cleanup)
-	s.connectionManager.CleanupStaleConnections(1 * time.Second)
+	// Force cleanup of all stale connections (idle for more than 1 second for immediate cleanup)
+	s.connectionManager.CleanupStaleConnections(1*time.Second, 0)
 
 	// Get connections after cleanup
 	connectionsAfter := s.connectionManager.GetConnections()
@@ -405,7 +617,7 @@ func (s *Server) handleCleanup(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		logger.System().Error(" Failed to encode cleanup response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to encode response")
 	} else {
 		logger.System().Info("Manual cleanup completed - cleaned %d connections, %d remaining", cleanedCount, countAfter)
 	}
@@ -431,6 +643,10 @@ func (s *Server) handleSessionHealth(w http.ResponseWriter, r *http.Request) {
 			"serverName":    conn.ServerName,
 			"connectedAt":   conn.ConnectedAt,
 			"duration":      time.Since(conn.ConnectedAt).String(),
+			"lastActivity":  conn.LastActivity(),
+			"idle":          time.Since(conn.LastActivity()).Round(time.Second).String(),
+			"principal":     conn.Principal,
+			"metrics":       conn.Metrics(),
 			"servers":       sessionServers,
 			"serverCount":   len(sessionServers),
 		}
@@ -447,7 +663,7 @@ func (s *Server) handleSessionHealth(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		logger.System().Error("Failed to encode session health response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to encode response")
 	} else {
 		logger.System().Info("Successfully returned session health for %d sessions", len(sessions))
 	}
@@ -475,7 +691,7 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 
 	if connection == nil {
 		logger.System().Error("Session '%s' not found", sessionID)
-		http.Error(w, fmt.Sprintf("Session '%s' not found", sessionID), http.StatusNotFound)
+		writeError(w, http.StatusNotFound, ErrorSessionNotFound, fmt.Sprintf("Session '%s' not found", sessionID))
 		return
 	}
 
@@ -488,9 +704,13 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 		"serverName":       connection.ServerName,
 		"connectedAt":      connection.ConnectedAt,
 		"duration":         time.Since(connection.ConnectedAt).String(),
+		"lastActivity":     connection.LastActivity(),
+		"idle":             time.Since(connection.LastActivity()).Round(time.Second).String(),
+		"principal":        connection.Principal,
+		"metrics":          connection.Metrics(),
 		"servers":          sessionServers,
 		"serverCount":      len(sessionServers),
-		"sessionDirectory": fmt.Sprintf("/app/sessions/%s", fullSessionID),
+		"sessionDirectory": s.mcpManager.SessionDirectory(fullSessionID),
 		"timestamp":        time.Now(),
 	}
 
@@ -499,12 +719,52 @@ func (s *Server) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		logger.System().Error("Failed to encode session detail response: %v", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to encode response")
 	} else {
 		logger.System().Info("Successfully returned session detail for session %s", sessionID)
 	}
 }
 
+// handleServerLogs returns the most recent stderr lines captured from an MCP server process
+func (s *Server) handleServerLogs(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	serverName := vars["server"]
+
+	lines := 100
+	if linesParam := r.URL.Query().Get("lines"); linesParam != "" {
+		n, err := strconv.Atoi(linesParam)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "lines must be a positive integer")
+			return
+		}
+		lines = n
+	}
+
+	logger.System().Debug("Handling logs request for server: %s (lines: %d)", serverName, lines)
+
+	stderrLines, exists := s.mcpManager.GetServerStderr(serverName, lines)
+	if !exists {
+		writeServerError(w, http.StatusNotFound, ErrorServerNotFound, serverName, fmt.Sprintf("MCP server '%s' not found", serverName),
+			"GET /listmcp lists the MCP servers this host serves")
+		return
+	}
+
+	response := map[string]interface{}{
+		"server":    serverName,
+		"stream":    "stderr",
+		"lines":     stderrLines,
+		"count":     len(stderrLines),
+		"timestamp": time.Now(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.System().Error("Failed to encode logs response: %v", err)
+	}
+}
+
 // handleListTools returns the available tools for a specific MCP server
 func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -521,24 +781,31 @@ func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
 	}
 	logger.System().Debug("Using session ID: %s for listtools", sessionIDShort)
 
+	if s.isAggregateServer(serverName) {
+		s.handleAggregateListTools(w, r, sessionID)
+		return
+	}
+
+	// Servers of other tenants than the host's are not listed
+	if !s.config.TenantServes(s.config.HostTenant(r.Host), serverName) {
+		writeServerError(w, http.StatusNotFound, ErrorServerNotFound, serverName, fmt.Sprintf("MCP server '%s' not available", serverName),
+			"GET /listmcp lists the MCP servers this host serves")
+		return
+	}
+
 	// Get the session-aware MCP server
 	mcpServer, exists := s.mcpManager.GetServerForSession(sessionID, serverName)
 	if !exists {
 		logger.System().Error(" MCP server '%s' not found or failed to create for session %s", serverName, sessionID[:8])
-		http.Error(w, fmt.Sprintf("MCP server '%s' not available", serverName), http.StatusNotFound)
+		s.writeServerUnavailable(w, serverName)
 		return
 	}
 
 	// Check if server is running
 	if !mcpServer.IsRunning() {
 		logger.System().Error(" MCP server '%s' is not running", serverName)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":   "server_not_running",
-			"message": fmt.Sprintf("MCP server '%s' is not running", serverName),
-			"server":  serverName,
-		})
+		writeServerError(w, http.StatusServiceUnavailable, ErrorServerNotRunning, serverName,
+			fmt.Sprintf("MCP server '%s' is not running", serverName))
 		return
 	}
 
@@ -553,24 +820,19 @@ func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
 	requestBytes, err := json.Marshal(toolsListRequest)
 	if err != nil {
 		logger.System().Error(" Failed to marshal tools/list request: %v", err)
-		http.Error(w, "Failed to create tools request", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to create tools request")
 		return
 	}
 
 	// Send the tools/list request and receive response using serialized queue
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), "sessionID", sessionID), 30*time.Second)
 	defer cancel()
 
-	responseBytes, err := mcpServer.SendAndReceive(ctx, requestBytes)
+	responseBytes, err := s.forwardRequest(ctx, serverName, mcpServer, requestBytes)
 	if err != nil {
 		logger.System().Error(" Failed to send/receive tools/list request to server %s: %v", serverName, err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":   "request_failed",
-			"message": fmt.Sprintf("Failed to communicate with MCP server: %v", err),
-			"server":  serverName,
-		})
+		writeServerError(w, http.StatusInternalServerError, ErrorUpstreamFailed, serverName,
+			fmt.Sprintf("Failed to communicate with MCP server: %v", err))
 		return
 	}
 
@@ -583,16 +845,13 @@ func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
 	// to ensure consistency between /listtools endpoint and SSE connections.
 	//
 	// DO NOT RETURN RAW RESPONSE - this breaks Claude.ai tool discovery
+	s.translator.CacheToolSchemasFromResponse(serverName, responseBytes)
+	responseBytes = s.translator.FilterToolsListResponse(serverName, responseBytes)
 	normalizedResponse, err := s.translator.MCPToRemote(responseBytes)
 	if err != nil {
 		logger.System().Error(" Failed to normalize tools/list response from server %s: %v", serverName, err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":   "normalization_failed",
-			"message": fmt.Sprintf("Failed to normalize response from MCP server: %v", err),
-			"server":  serverName,
-		})
+		writeServerError(w, http.StatusInternalServerError, ErrorUpstreamInvalid, serverName,
+			fmt.Sprintf("Failed to normalize response from MCP server: %v", err))
 		return
 	}
 
@@ -600,13 +859,20 @@ func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
 	var normalizedMCPResponse map[string]interface{}
 	if err := json.Unmarshal(normalizedResponse, &normalizedMCPResponse); err != nil {
 		logger.System().Error(" Failed to parse normalized tools/list response from server %s: %v", serverName, err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":   "parse_failed",
-			"message": fmt.Sprintf("Failed to parse normalized response from MCP server: %v", err),
-			"server":  serverName,
-		})
+		writeServerError(w, http.StatusInternalServerError, ErrorUpstreamInvalid, serverName,
+			fmt.Sprintf("Failed to parse normalized response from MCP server: %v", err))
+		return
+	}
+
+	// The JSON-RPC id differs on every request, so it doesn't count towards the ETag
+	stable := make(map[string]interface{}, len(normalizedMCPResponse))
+	for key, value := range normalizedMCPResponse {
+		if key != "id" {
+			stable[key] = value
+		}
+	}
+	if checkNotModified(w, r, weakETag(serverName, stable)) {
+		logger.System().Debug("listtools for server %s not modified", serverName)
 		return
 	}
 
@@ -638,23 +904,76 @@ func (s *Server) handleMCPRequest(w http.ResponseWriter, r *http.Request) {
 			logger.System().Debug(" Using server name '%s' from URL path", serverName)
 		} else {
 			logger.System().Error(" No server name found in context or URL path for host: %s, path: %s", r.Host, r.URL.Path)
-			http.Error(w, "Invalid request format. Expected: {server}.mcp.{domain}/sse or /{server}/sse", http.StatusBadRequest)
+			s.sendRouteError(w, r, http.StatusBadRequest)
 			return
 		}
 	}
+	if !s.isRoutableServer(serverName) {
+		s.sendRouteError(w, r, http.StatusNotFound)
+		return
+	}
 
 	// Get session ID early for session-aware server selection
 	sessionID := s.getSessionID(r)
 	logger.System().Debug("Using session ID: %s for server selection", sessionID[:8])
 
-	// Use session-aware server selection
-	mcpServer, exists := s.mcpManager.GetServerForSession(sessionID, serverName)
-	if !exists {
-		logger.System().Error(" MCP server '%s' not found or failed to create for session %s", serverName, sessionID[:8])
-		http.Error(w, fmt.Sprintf("MCP server '%s' not available", serverName), http.StatusNotFound)
+	// Reconnecting SSE clients must prove continuity before reusing a known session
+	if r.Method == "GET" && !s.validateReconnectToken(r, sessionID) {
+		writeError(w, http.StatusForbidden, ErrorInvalidReconnectToken, "Invalid or missing reconnect token for existing session",
+			"Send the Mcp-Reconnect-Token received when the session was created, or open a new session")
+		return
+	}
+
+	// The aggregate server has no process of its own; its backends are started per request
+	aggregate := s.isAggregateServer(serverName)
+
+	if !aggregate && s.mcpManager.InMaintenance(serverName) {
+		writeMaintenanceResponse(w, serverName)
 		return
 	}
 
+	// Validate authentication
+	logger.System().Info("Validating authentication...")
+	principal, err := s.authenticatePrincipal(r)
+	if err != nil {
+		logger.System().Error(" Authentication failed for request from %s", r.RemoteAddr)
+		if writeRateLimited(w, err) {
+			return
+		}
+		logger.System().Info("=== MCP REQUEST END (AUTH FAILED) ===")
+		// Add WWW-Authenticate header for proper OAuth Bearer token flow
+		// resource_metadata points clients at the protected resource metadata for discovery
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=\"Remote MCP Server\", resource_metadata=\"%s\"", resourceMetadataURL(r)))
+		writeAuthError(w, http.StatusUnauthorized, ErrorUnauthorized, "Bearer token required for Remote MCP access")
+		return
+	}
+	logger.System().Info("SUCCESS: Authentication passed")
+	r = r.WithContext(context.WithValue(r.Context(), "mcpPrincipal", principal))
+
+	// Tenants only reach their own servers, and keep their session data apart
+	var admitted bool
+	if r, admitted = s.admitTenant(w, r, principal, serverName); !admitted {
+		return
+	}
+	if tenant := contextTenant(r.Context()); tenant != "" {
+		s.mcpManager.SetSessionRoot(sessionID, s.config.Tenants[tenant].SessionRoot)
+	}
+
+	// Persistent session data is keyed by the client, so it must be known before servers start
+	s.bindSessionIdentity(r, sessionID)
+
+	// Use session-aware server selection
+	var mcpServer *mcp.Server
+	if !aggregate {
+		var exists bool
+		mcpServer, exists = s.mcpManager.GetServerForSession(sessionID, serverName)
+		if !exists {
+			logger.System().Error(" MCP server '%s' not found or failed to create for session %s", serverName, sessionID[:8])
+			s.writeServerUnavailable(w, serverName)
+			return
+		}
+	}
+
 	// Consolidated request logging
 	logger.System().Debug(">>> MCP %s %s via %s", r.Method, r.URL.String(), serverName)
 
@@ -672,18 +991,18 @@ func (s *Server) handleMCPRequest(w http.ResponseWriter, r *http.Request) {
 		logger.System().Trace("Session: %s", sessionID)
 	}
 
-	// Validate authentication
-	logger.System().Info("Validating authentication...")
-	if !s.validateAuthentication(r) {
-		logger.System().Error(" Authentication failed for request from %s", r.RemoteAddr)
-		logger.System().Info("=== MCP REQUEST END (AUTH FAILED) ===")
-		// Add WWW-Authenticate header for proper OAuth Bearer token flow
-		w.Header().Set("WWW-Authenticate", "Bearer realm=\"Remote MCP Server\"")
-		w.Header().Set("Content-Type", "application/json")
-		http.Error(w, `{"error":"unauthorized","error_description":"Bearer token required for Remote MCP access"}`, http.StatusUnauthorized)
+	if aggregate {
+		switch r.Method {
+		case "GET":
+			s.handleSSEConnection(w, r, serverName)
+		case "POST":
+			s.handleAggregateMessage(s.connectionManager.Meter(sessionID, w), r, sessionID)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, ErrorMethodNotAllowed, "Method not allowed")
+		}
+		logger.System().Info("=== MCP REQUEST END (AGGREGATE %s) ===", r.Method)
 		return
 	}
-	logger.System().Info("SUCCESS: Authentication passed")
 
 	logger.System().Info("SUCCESS: Found MCP server: %s (running: %v)", serverName, mcpServer.IsRunning())
 
@@ -692,28 +1011,34 @@ func (s *Server) handleMCPRequest(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
 		logger.System().Info("Starting SSE connection handling...")
-		s.handleSSEConnection(w, r, mcpServer)
+		s.handleSSEConnection(w, r, serverName)
 		logger.System().Info("=== MCP REQUEST END (SSE) ===")
 	case "POST":
 		logger.System().Info("Starting POST message handling...")
-		s.handleMCPMessage(w, r, mcpServer)
+		s.handleMCPMessage(w, r, serverName, mcpServer)
 		logger.System().Info("=== MCP REQUEST END (POST) ===")
 	default:
 		logger.System().Error(" Method not allowed: %s", r.Method)
 		logger.System().Info("=== MCP REQUEST END (METHOD NOT ALLOWED) ===")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, ErrorMethodNotAllowed, "Method not allowed")
 	}
 }
 
 // handleSSEConnection establishes a Server-Sent Events connection
-func (s *Server) handleSSEConnection(w http.ResponseWriter, r *http.Request, mcpServer *mcp.Server) {
+func (s *Server) handleSSEConnection(w http.ResponseWriter, r *http.Request, serverName string) {
 	logger.System().Info("=== SSE CONNECTION START ===")
-	logger.System().Info("Setting up SSE connection for server: %s", mcpServer.Name)
+	logger.System().Info("Setting up SSE connection for server: %s", serverName)
 
 	// Get or generate session ID
 	sessionID := s.getSessionID(r)
 	logger.System().Info("Session ID for SSE connection: %s", sessionID)
 
+	// A reconnect within the resume grace keeps the session's MCP server processes
+	resumed := s.sessionResumer.Resume(sessionID)
+	if resumed {
+		logger.System().Info("Session %s resumed, keeping its MCP server processes", sessionID[:8])
+	}
+
 	// CRITICAL FIX: Register session in translator immediately
 	//
 	// Create an uninitialized session state so that IsInitialized() checks
@@ -739,13 +1064,21 @@ func (s *Server) handleSSEConnection(w http.ResponseWriter, r *http.Request, mcp
 
 	// Check connection limits and add to manager
 	logger.System().Info("Adding connection to manager...")
-	if err := s.connectionManager.AddConnection(sessionID, mcpServer.Name, ctx, cancel); err != nil {
+	if err := s.connectionManager.AddConnection(sessionID, serverName, ctx, cancel); err != nil {
 		logger.System().Error(" Failed to add connection for session %s: %v", sessionID, err)
 		logger.System().Info("=== SSE CONNECTION END (CONNECTION LIMIT) ===")
-		http.Error(w, "Too many connections", http.StatusTooManyRequests)
+		writeError(w, http.StatusTooManyRequests, ErrorTooManyConnections, "Too many connections")
 		return
 	}
+	s.connectionManager.SetTokenFingerprint(sessionID, tokenFingerprint(r))
+	if principal, _ := r.Context().Value("mcpPrincipal").(string); principal != "" {
+		s.connectionManager.SetPrincipal(sessionID, principal)
+	}
+	w = s.connectionManager.Meter(sessionID, w)
 	logger.System().Info("SUCCESS: Connection added to manager")
+	if !resumed {
+		s.notifySession(config.WebhookSessionCreated, serverName, sessionID)
+	}
 
 	// Set SSE headers
 	logger.System().Info("Setting SSE headers...")
@@ -754,11 +1087,12 @@ func (s *Server) handleSSEConnection(w http.ResponseWriter, r *http.Request, mcp
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("X-Session-ID", sessionID)
+	w.Header().Set("Mcp-Reconnect-Token", s.reconnectTokens.Rotate(sessionID))
 	logger.System().Info("SUCCESS: SSE headers set")
 
 	// Send required "endpoint" event for Remote MCP protocol
 	logger.System().Info("Sending endpoint event...")
-	if _, err := fmt.Fprintf(w, "event: endpoint\n"); err != nil {
+	if _, err := fmt.Fprintf(w, "id: %s\nevent: endpoint\n", formatEventID(sessionID, s.sseEvents.LastSeq(sessionID))); err != nil {
 		logger.System().Error(" Failed to write SSE endpoint event: %v", err)
 		logger.System().Info("=== SSE CONNECTION END (ENDPOINT EVENT FAILED) ===")
 		s.connectionManager.RemoveConnection(sessionID)
@@ -767,92 +1101,108 @@ func (s *Server) handleSSEConnection(w http.ResponseWriter, r *http.Request, mcp
 
 	// Construct the session endpoint URL that Claude will use for sending messages
 	logger.System().Info("INFO: Constructing session endpoint URL...")
-	scheme := "https"
-	if r.Header.Get("X-Forwarded-Proto") == "" {
-		scheme = "http"
-	}
-	host := r.Host
-	if r.Header.Get("X-Forwarded-Host") != "" {
-		host = r.Header.Get("X-Forwarded-Host")
-	}
+	baseURL := s.publicBaseURL(r)
 
 	// Determine if we're using subdomain-based or path-based routing
 	var sessionEndpoint string
-	if strings.Contains(host, ".mcp.") {
+	if fromHost, _ := r.Context().Value("mcpServerFromHost").(bool); fromHost {
 		// Subdomain-based routing: https://memory.mcp.domain.com/sessions/abc123
-		sessionEndpoint = fmt.Sprintf("%s://%s/sessions/%s", scheme, host, sessionID)
+		sessionEndpoint = fmt.Sprintf("%s/sessions/%s", baseURL, sessionID)
+	} else if pathServer, basePath := s.config.BasePathServer(r.URL.Path); pathServer == serverName && basePath != "" {
+		// Base path routing: https://mcp.domain.com/integrations/notion/v1/sessions/abc123
+		sessionEndpoint = fmt.Sprintf("%s%s/sessions/%s", baseURL, basePath, sessionID)
 	} else {
 		// Path-based routing: http://localhost:8080/memory/sessions/abc123
-		sessionEndpoint = fmt.Sprintf("%s://%s/%s/sessions/%s", scheme, host, mcpServer.Name, sessionID)
+		sessionEndpoint = fmt.Sprintf("%s/%s/sessions/%s", baseURL, serverName, sessionID)
 	}
 	logger.System().Info("INFO: Session endpoint URL: %s", sessionEndpoint)
 
-	endpointData := map[string]interface{}{
-		"uri": sessionEndpoint,
+	format := config.EndpointFormatObject
+	if s.config != nil {
+		format = s.config.GetEndpointFormat(serverName)
 	}
-
-	endpointJSON, err := json.Marshal(endpointData)
-	if err != nil {
-		logger.System().Error(" Failed to marshal endpoint data: %v", err)
-		logger.System().Info("=== SSE CONNECTION END (ENDPOINT JSON FAILED) ===")
-		s.connectionManager.RemoveConnection(sessionID)
-		return
-	}
-
-	logger.System().Info("INFO: Endpoint data: %s", string(endpointJSON))
-	if _, err := fmt.Fprintf(w, "data: %s\n\n", string(endpointJSON)); err != nil {
+	if err := writeEndpointData(w, format, sessionEndpoint); err != nil {
 		logger.System().Error(" Failed to write SSE endpoint data: %v", err)
 		logger.System().Info("=== SSE CONNECTION END (ENDPOINT DATA FAILED) ===")
 		s.connectionManager.RemoveConnection(sessionID)
 		return
 	}
 	logger.System().Info("SUCCESS: Endpoint event sent successfully")
+	s.connectionManager.RecordEvents(sessionID, 1)
+
+	// Replay the events the client missed while it was disconnected
+	notify := s.sseEvents.Attach(sessionID)
+	lastSent := s.replayFrom(r, sessionID)
+	lastSent, err := s.writePendingEvents(w, sessionID, lastSent)
+	if err != nil {
+		logger.System().Error(" Failed to replay SSE events for session %s: %v", sessionID, err)
+	}
 
 	// Flush to send the event immediately
 	if flusher, ok := w.(http.Flusher); ok {
 		flusher.Flush()
 	}
 
-	// Clean up when connection closes
+	// Clean up when connection closes; the session itself is kept for the resume grace
 	defer func() {
 		s.connectionManager.RemoveConnection(sessionID)
-		s.translator.RemoveConnection(sessionID)
-		s.mcpManager.CleanupSession(sessionID)
-		logger.System().Info("INFO: SSE connection and session cleanup completed for server %s, session %s", mcpServer.Name, sessionID[:8])
+		s.reconnectTokens.MarkDisconnected(sessionID)
+		s.sseEvents.Detach(sessionID)
+		s.sessionResumer.Schedule(sessionID, s.cleanupWhenIdle(sessionID, func() {
+			killed := !s.sseEvents.Known(sessionID) // Already reported by /admin/sessions
+			s.translator.RemoveConnection(sessionID)
+			s.mcpManager.CleanupSession(sessionID)
+			s.sseEvents.Forget(sessionID)
+			if !killed {
+				s.notifySession(config.WebhookSessionClosed, serverName, sessionID)
+			}
+			logger.System().Info("INFO: Session cleanup completed for server %s, session %s", serverName, sessionID[:8])
+		}))
+		logger.System().Info("INFO: SSE connection closed for server %s, session %s", serverName, sessionID[:8])
 	}()
 
 	// Create a ticker for periodic checks and timeouts
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
-	logger.System().Info("INFO: Starting SSE message loop for server %s, session %s", mcpServer.Name, sessionID)
+	logger.System().Info("INFO: Starting SSE message loop for server %s, session %s", serverName, sessionID)
 
 	// Add keep-alive ticker to detect client disconnection
-	keepAliveTicker := time.NewTicker(30 * time.Second)
+	heartbeatStyle, heartbeatInterval := s.heartbeatSettings(serverName)
+	keepAliveTicker := time.NewTicker(heartbeatInterval)
 	defer keepAliveTicker.Stop()
+	logger.System().Debug("SSE heartbeat for server %s: %s every %v", serverName, heartbeatStyle, heartbeatInterval)
 
-	// Add stale connection detection
-	lastActivityTime := time.Now()
-	staleConnectionTimeout := 5 * time.Minute // Detect connections idle for 5+ minutes
-	maxDebugMessages := 10                    // Limit debug spam
+	// Idle connections are closed by the background cleanup, which cancels ctx
+	maxDebugMessages := 10 // Limit debug spam
 	debugMessageCount := 0
 
 	for {
 		select {
 		case <-ctx.Done():
-			logger.System().Info("INFO: SSE context cancelled for server %s, session %s", mcpServer.Name, sessionID)
+			logger.System().Info("INFO: SSE context cancelled for server %s, session %s", serverName, sessionID)
 			return
+		case <-notify:
+			// Deliver messages the session's MCP servers sent on their own
+			if lastSent, err = s.writePendingEvents(w, sessionID, lastSent); err != nil {
+				logger.System().Info("INFO: Client disconnected for session %s (server %s): %v", sessionID, serverName, err)
+				return
+			}
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			s.connectionManager.Touch(sessionID)
 		case <-keepAliveTicker.C:
-			// Send keep-alive event to detect client disconnection
-			if _, err := fmt.Fprintf(w, "event: keep-alive\ndata: {\"timestamp\":%d}\n\n", time.Now().Unix()); err != nil {
-				logger.System().Info("INFO: Client disconnected for session %s (server %s): %v", sessionID, mcpServer.Name, err)
+			// Send heartbeat to detect client disconnection
+			if err := s.writeHeartbeat(w, heartbeatStyle, sessionID); err != nil {
+				logger.System().Info("INFO: Client disconnected for session %s (server %s): %v", sessionID, serverName, err)
 				return
 			}
 			if flusher, ok := w.(http.Flusher); ok {
 				flusher.Flush()
 			}
-			// Update activity time on successful keep-alive
-			lastActivityTime = time.Now()
+			// A keep-alive the client received keeps the connection from going idle
+			s.connectionManager.Touch(sessionID)
 		case <-ticker.C:
 			// CRITICAL FIX: Remove initialization check to prevent SSE deadlock
 			//
@@ -884,17 +1234,9 @@ func (s *Server) handleSSEConnection(w http.ResponseWriter, r *http.Request, mcp
 			// For now, SSE just maintains the connection and waits.
 			// Future: Add channel-based event system for notifications if needed.
 
-			// STALE CONNECTION DETECTION: Check if connection has been idle too long
-			if time.Since(lastActivityTime) > staleConnectionTimeout {
-				logger.System().Warn(" Stale SSE connection detected for server %s, session %s (idle for %v)",
-					mcpServer.Name, sessionID, time.Since(lastActivityTime))
-				logger.System().Info("INFO: Automatically closing stale connection to prevent resource leaks")
-				return
-			}
-
 			// REDUCE DEBUG SPAM: Only log first few debug messages to prevent log flooding
 			if debugMessageCount < maxDebugMessages {
-				logger.System().Debug(" SSE connection active for server %s, session %s - waiting for requests", mcpServer.Name, sessionID)
+				logger.System().Debug(" SSE connection active for server %s, session %s - waiting for requests", serverName, sessionID)
 				debugMessageCount++
 				if debugMessageCount == maxDebugMessages {
 					logger.System().Info("INFO: Debug message limit reached for session %s - silencing further debug logs", sessionID)
@@ -908,17 +1250,17 @@ func (s *Server) handleSSEConnection(w http.ResponseWriter, r *http.Request, mcp
 }
 
 // handleMCPMessage handles POST requests with MCP messages
-func (s *Server) handleMCPMessage(w http.ResponseWriter, r *http.Request, mcpServer *mcp.Server) {
+func (s *Server) handleMCPMessage(w http.ResponseWriter, r *http.Request, serverName string, mcpServer *mcp.Server) {
 	logger.System().Info("=== MCP MESSAGE START ===")
 	logger.System().Info("INFO: Processing POST message for server: %s", mcpServer.Name)
 
 	// Read the request body
 	logger.System().Info("INFO: Reading request body...")
-	body, err := io.ReadAll(r.Body)
+	body, err := s.readMessageBody(w, r, serverName)
 	if err != nil {
 		logger.System().Error(" Failed to read request body: %v", err)
 		logger.System().Info("=== MCP MESSAGE END (BODY READ FAILED) ===")
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		s.sendBodyReadError(w, err, false)
 		return
 	}
 	logger.System().Info("SUCCESS: Request body read (%d bytes)", len(body))
@@ -938,7 +1280,7 @@ func (s *Server) handleMCPMessage(w http.ResponseWriter, r *http.Request, mcpSer
 	if err := json.Unmarshal(body, &jsonrpcMsg); err != nil {
 		logger.System().Error(" Invalid JSON-RPC message: %v", err)
 		logger.System().Info("=== MCP MESSAGE END (JSON PARSE FAILED) ===")
-		http.Error(w, fmt.Sprintf("Invalid JSON-RPC message: %v", err), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrorInvalidMessage, fmt.Sprintf("Invalid JSON-RPC message: %v", err))
 		return
 	}
 	logger.System().Info("SUCCESS: JSON-RPC message parsed")
@@ -946,6 +1288,8 @@ func (s *Server) handleMCPMessage(w http.ResponseWriter, r *http.Request, mcpSer
 	// Generate or get session ID
 	sessionID := s.getSessionID(r)
 	logger.System().Info("INFO: Method: %s, ID: %v, SessionID: %s", jsonrpcMsg.Method, jsonrpcMsg.ID, sessionID)
+	s.connectionManager.RecordRequest(sessionID, jsonrpcMsg.Method)
+	w = s.connectionManager.Meter(sessionID, w)
 
 	// CRITICAL FIX: Only handle handshake messages if this is NOT a session endpoint request
 	//
@@ -962,7 +1306,7 @@ func (s *Server) handleMCPMessage(w http.ResponseWriter, r *http.Request, mcpSer
 		logger.System().Error(" Session endpoint request incorrectly routed to handleMCPMessage")
 		logger.System().Error(" This should not happen - check routing configuration")
 		logger.System().Info("=== MCP MESSAGE END (ROUTING ERROR) ===")
-		http.Error(w, "Internal routing error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Internal routing error")
 		return
 	}
 
@@ -970,7 +1314,7 @@ func (s *Server) handleMCPMessage(w http.ResponseWriter, r *http.Request, mcpSer
 	logger.System().Info("INFO: Checking if handshake message...")
 	if s.translator.IsHandshakeMessage(jsonrpcMsg.Method) {
 		logger.System().Info("INFO: Processing handshake message: %s", jsonrpcMsg.Method)
-		s.handleHandshakeMessage(w, r, sessionID, &jsonrpcMsg, mcpServer)
+		s.handleHandshakeMessage(w, r, serverName, sessionID, &jsonrpcMsg, mcpServer)
 		logger.System().Info("=== MCP MESSAGE END (HANDSHAKE) ===")
 		return
 	}
@@ -1002,30 +1346,65 @@ func (s *Server) handleMCPMessage(w http.ResponseWriter, r *http.Request, mcpSer
 	logger.System().Info("INFO: Session %s is initialized, handling non-handshake request %s synchronously",
 		sessionID, jsonrpcMsg.Method)
 
+	// Answers to server-initiated requests go straight back to the server
+	if s.forwardClientResponse(w, serverName, sessionID, &jsonrpcMsg, body) {
+		return
+	}
+	if s.handleClientCancellation(w, sessionID, &jsonrpcMsg) {
+		return
+	}
+
 	// Track the request for potential fallback handling
 	if jsonrpcMsg.Method != "" && jsonrpcMsg.ID != nil {
 		s.translator.TrackRequest(sessionID, jsonrpcMsg.ID, jsonrpcMsg.Method)
 		logger.System().Debug(" Tracking request ID %v, method %s for session %s", jsonrpcMsg.ID, jsonrpcMsg.Method, sessionID)
 	}
 
+	body = s.restoreResourceURIs(r, sessionID, serverName, jsonrpcMsg.Method, body)
+	if s.sendFallbackResponse(w, sessionID, &jsonrpcMsg) {
+		return
+	}
+
+	if s.rejectToolCall(w, r, serverName, sessionID, body, jsonrpcMsg.ID, false) {
+		return
+	}
+
+	// Bound to the client's HTTP request, so an aborted request stops waiting and cancels on the server.
+	// Cancelling by ID ends the wait for a tool call slot as well as the forward.
+	callCtx, cancelCall := context.WithCancel(r.Context())
+	defer cancelCall()
+	defer s.inFlight.Track(sessionID, jsonrpcMsg.ID, cancelCall)()
+
+	// The timeout starts once the call has a tool call slot
+	release, err := s.acquireToolCallSlot(callCtx, sessionID, jsonrpcMsg.Method)
+	if err != nil {
+		s.sendToolCallLimitError(w, jsonrpcMsg.ID, sessionID, err, false)
+		return
+	}
+	defer release()
+
 	// Send request and receive response from MCP server using serialized queue
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := s.requestContext(callCtx, sessionID, serverName, jsonrpcMsg.Method, 10*time.Second)
 	defer cancel()
+	ctx = context.WithValue(ctx, "responseHeader", w.Header()) // Header hooks set headers on the reply
 
-	responseBytes, err := mcpServer.SendAndReceive(ctx, body)
+	responseBytes, err := s.forwardRequest(ctx, serverName, mcpServer, body)
+	if err != nil && (s.finishCancelledRequest(ctx, w, r, jsonrpcMsg.ID, err, false) || s.rejectUnreadableResponse(w, err, jsonrpcMsg.ID, false)) {
+		return
+	}
 	if err != nil {
 		logger.System().Warn(" Failed to read response from MCP server %s for method %s: %v",
 			mcpServer.Name, jsonrpcMsg.Method, err)
 
 		// Check if we should provide a fallback response for this method
-		if s.translator.ShouldProvideFallback(jsonrpcMsg.Method) {
+		if s.translator.ShouldProvideFallback(sessionID, jsonrpcMsg.Method) {
 			logger.System().Info("INFO: Providing fallback response for method %s", jsonrpcMsg.Method)
 			fallbackResponse, fallbackErr := s.translator.CreateFallbackResponse(jsonrpcMsg.ID, jsonrpcMsg.Method)
 			if fallbackErr == nil {
 				responseBytes = fallbackResponse
 			} else {
 				logger.System().Error(" Failed to create fallback response: %v", fallbackErr)
-				http.Error(w, "Failed to receive response from MCP server", http.StatusInternalServerError)
+				s.sendTransportError(w, jsonrpcMsg.ID, ErrorInternal, "Failed to receive response from MCP server", false)
 				return
 			}
 		} else {
@@ -1037,18 +1416,23 @@ func (s *Server) handleMCPMessage(w http.ResponseWriter, r *http.Request, mcpSer
 				responseBytes = errorResponse
 			} else {
 				logger.System().Error(" Failed to create error response: %v", errorErr)
-				http.Error(w, "Failed to receive response from MCP server", http.StatusInternalServerError)
+				s.sendTransportError(w, jsonrpcMsg.ID, ErrorInternal, "Failed to receive response from MCP server", false)
 				return
 			}
 		}
 	}
 
+	if jsonrpcMsg.Method == "tools/list" {
+		s.translator.CacheToolSchemasFromResponse(serverName, responseBytes)
+		responseBytes = s.translator.FilterToolsListResponse(serverName, responseBytes)
+	}
+	s.recordSubscription(serverName, sessionID, &jsonrpcMsg, responseBytes)
+	responseBytes = s.translator.RewriteResourceURIs(sessionID, serverName, jsonrpcMsg.Method, responseBytes)
+
 	// Return response directly to Claude.ai (synchronous like session endpoint)
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Mcp-Session-Id", sessionID)
-	w.WriteHeader(http.StatusOK)
-
-	if _, err := w.Write(responseBytes); err != nil {
+	if err := s.writeResponse(w, responseBytes); err != nil {
 		logger.System().Error(" Failed to write synchronous response: %v", err)
 	} else {
 		logger.System().Info("INFO: Successfully returned synchronous response for %s to session %s via /sse endpoint",
@@ -1061,7 +1445,7 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Validate origin
 		if !s.validateOrigin(r) {
-			http.Error(w, "Origin not allowed", http.StatusForbidden)
+			writeError(w, http.StatusForbidden, ErrorOriginNotAllowed, "Origin not allowed")
 			return
 		}
 
@@ -1074,8 +1458,8 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 		}
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Session-ID, Mcp-Session-Id")
-		w.Header().Set("Access-Control-Expose-Headers", "X-Session-ID, WWW-Authenticate")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Session-ID, Mcp-Session-Id, Mcp-Reconnect-Token, X-Request-Id")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Session-ID, WWW-Authenticate, Mcp-Reconnect-Token, X-Request-Id")
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -1100,6 +1484,31 @@ func (s *Server) logRequest(r *http.Request) {
 	}
 }
 
+// validateReconnectToken checks the reconnect token presented for a known session
+// Sessions without token history are new and always accepted.
+func (s *Server) validateReconnectToken(r *http.Request, sessionID string) bool {
+	token := r.Header.Get("Mcp-Reconnect-Token")
+	if token == "" {
+		token = r.URL.Query().Get("reconnect_token")
+	}
+
+	known, valid := s.reconnectTokens.Validate(sessionID, token)
+	if !known || valid {
+		return true
+	}
+
+	if token == "" {
+		if s.config != nil && s.config.RequireReconnectToken {
+			logger.System().Warn(" Rejecting reconnect to session %s without reconnect token", sessionID)
+			return false
+		}
+		return true
+	}
+
+	logger.System().Warn(" Rejecting reconnect to session %s with invalid reconnect token from %s", sessionID, r.RemoteAddr)
+	return false
+}
+
 // getSessionID generates or retrieves a session ID for the request
 func (s *Server) getSessionID(r *http.Request) string {
 	// Try to get session ID from Remote MCP standard header
@@ -1114,6 +1523,12 @@ func (s *Server) getSessionID(r *http.Request) string {
 		return sessionID
 	}
 
+	// EventSource clients only send the ID of the last event they received when reconnecting
+	if sessionID, _, ok := parseEventID(r.Header.Get("Last-Event-ID")); ok && s.sseEvents.Known(sessionID) {
+		logger.System().Debug(" Using existing session ID from Last-Event-ID header: %s", sessionID)
+		return sessionID
+	}
+
 	// Generate a new session ID
 	bytes := make([]byte, 16)
 	if _, err := rand.Read(bytes); err != nil {
@@ -1127,11 +1542,22 @@ func (s *Server) getSessionID(r *http.Request) string {
 	return sessionID
 }
 
+// requestContext bounds a request to an MCP server by the timeout configured for its method, or def
+func (s *Server) requestContext(parent context.Context, sessionID, serverName, method string, def time.Duration) (context.Context, context.CancelFunc) {
+	ctx := context.WithValue(parent, "sessionID", sessionID)
+	if s.config != nil {
+		if timeout, ok := s.config.GetMethodTimeout(serverName, method); ok {
+			return mcp.WithConfiguredTimeout(ctx, timeout)
+		}
+	}
+	return context.WithTimeout(ctx, def)
+}
+
 // handleHandshakeMessage handles MCP handshake messages (initialize and initialized)
-func (s *Server) handleHandshakeMessage(w http.ResponseWriter, r *http.Request, sessionID string, msg *protocol.JSONRPCMessage, mcpServer *mcp.Server) {
+func (s *Server) handleHandshakeMessage(w http.ResponseWriter, r *http.Request, serverName, sessionID string, msg *protocol.JSONRPCMessage, mcpServer *mcp.Server) {
 	switch msg.Method {
 	case "initialize":
-		s.handleInitialize(w, r, sessionID, msg, mcpServer)
+		s.handleInitialize(w, r, serverName, sessionID, msg, mcpServer)
 	case "notifications/initialized":
 		s.handleInitialized(w, sessionID, msg)
 	default:
@@ -1140,7 +1566,7 @@ func (s *Server) handleHandshakeMessage(w http.ResponseWriter, r *http.Request,
 }
 
 // handleInitialize handles the initialize request
-func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, sessionID string, msg *protocol.JSONRPCMessage, mcpServer *mcp.Server) {
+func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, serverName, sessionID string, msg *protocol.JSONRPCMessage, mcpServer *mcp.Server) {
 	// Parse initialize parameters
 	var params protocol.InitializeParams
 	if msg.Params != nil {
@@ -1154,7 +1580,7 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, sessio
 	// Check if server is running
 	if !mcpServer.IsRunning() {
 		logger.System().Error(" MCP server '%s' is not running for initialize", mcpServer.Name)
-		s.sendErrorResponse(w, msg.ID, protocol.InvalidRequest, fmt.Sprintf("MCP server '%s' is not running", mcpServer.Name), false)
+		s.sendTransportError(w, msg.ID, ErrorServerNotRunning, fmt.Sprintf("MCP server '%s' is not running", mcpServer.Name), false)
 		return
 	}
 
@@ -1177,7 +1603,7 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, sessio
 	initRequestBytes, err := json.Marshal(initRequest)
 	if err != nil {
 		logger.System().Error(" Failed to marshal initialize request: %v", err)
-		s.sendErrorResponse(w, msg.ID, protocol.InternalError, "Failed to process initialize request", false)
+		s.sendTransportError(w, msg.ID, ErrorInternal, "Failed to process initialize request", false)
 		return
 	}
 
@@ -1189,12 +1615,13 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, sessio
 	//
 	// IMPORTANT: The 30-second timeout was increased from 10 seconds to handle slow MCP
 	// server initialization (especially npm-based servers). Reducing this timeout will
-	// cause "context deadline exceeded" errors during initialization.
+	// cause "context deadline exceeded" errors during initialization. Servers needing
+	// longer can set an "initialize" timeout in their config.
 	//
 	// The serialized request queue prevents stdio deadlocks and response mismatching that
 	// occur when multiple concurrent requests try to access the same MCP server simultaneously.
 	logger.System().Info("INFO: Waiting for initialize response from MCP server %s...", mcpServer.Name)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := s.requestContext(context.Background(), sessionID, serverName, "initialize", 30*time.Second)
 	defer cancel()
 
 	// Send initialize request and receive response using serialized queue
@@ -1209,7 +1636,10 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, sessio
 				logger.System().Error(" Failed to restart MCP server %s: %v", mcpServer.Name, restartErr)
 			} else {
 				logger.System().Info("INFO: Successfully restarted MCP server %s", mcpServer.Name)
-				// Retry initialize with new server instance
+				// Retry initialize with new server instance, which replaced the hung one
+				if restarted, exists := s.mcpManager.GetServerForSession(sessionID, serverName); exists {
+					mcpServer = restarted
+				}
 				retryCtx, retryCancel := context.WithTimeout(context.Background(), 30*time.Second)
 				defer retryCancel()
 				if retryBytes, retryErr := mcpServer.SendAndReceive(retryCtx, initRequestBytes); retryErr == nil {
@@ -1223,7 +1653,7 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, sessio
 		}
 
 		if err != nil {
-			s.sendErrorResponse(w, msg.ID, protocol.InternalError, "Failed to communicate with MCP server", false)
+			s.sendTransportError(w, msg.ID, ErrorUpstreamFailed, "Failed to communicate with MCP server", false)
 			return
 		}
 	}
@@ -1232,7 +1662,7 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, sessio
 	var mcpResponse protocol.JSONRPCMessage
 	if err := json.Unmarshal(responseBytes, &mcpResponse); err != nil {
 		logger.System().Error(" Failed to parse initialize response from MCP server %s: %v", mcpServer.Name, err)
-		s.sendErrorResponse(w, msg.ID, protocol.InternalError, "Invalid response from MCP server", false)
+		s.sendTransportError(w, msg.ID, ErrorUpstreamInvalid, "Invalid response from MCP server", false)
 		return
 	}
 
@@ -1242,6 +1672,9 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, sessio
 		if err != nil {
 			logger.System().Error(" Failed to store connection state: %v", err)
 		} else {
+			s.translator.RecordBackendCapabilities(sessionID, responseBytes)
+			responseBytes = s.translator.NegotiatedInitializeResponse(sessionID, responseBytes)
+
 			// CRITICAL FIX: Mark session as initialized immediately after successful initialize response
 			//
 			// This is essential for Remote MCP protocol compliance. Claude.ai expects to be able to
@@ -1266,9 +1699,7 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request, sessio
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Session-ID", sessionID)
 	w.Header().Set("Mcp-Session-Id", sessionID)
-	w.WriteHeader(http.StatusOK)
-
-	if _, err := w.Write(responseBytes); err != nil {
+	if err := s.writeResponse(w, responseBytes); err != nil {
 		logger.System().Error(" Failed to write initialize response: %v", err)
 	} else {
 		logger.System().Info("INFO: Forwarded initialize response from MCP server %s for session %s", mcpServer.Name, sessionID)
@@ -1301,10 +1732,14 @@ func (s *Server) handleSessionMessage(w http.ResponseWriter, r *http.Request) {
 			logger.System().Debug(" Using server name '%s' from URL path for session", serverName)
 		} else {
 			logger.System().Error(" No server name found in context or URL path for host: %s, path: %s", r.Host, r.URL.Path)
-			http.Error(w, "Invalid request format. Expected: {server}.mcp.{domain}/sessions/{id} or /{server}/sessions/{id}", http.StatusBadRequest)
+			s.sendRouteError(w, r, http.StatusBadRequest)
 			return
 		}
 	}
+	if !s.isRoutableServer(serverName) {
+		s.sendRouteError(w, r, http.StatusNotFound)
+		return
+	}
 
 	vars := mux.Vars(r)
 	sessionID := vars["sessionId"]
@@ -1317,25 +1752,49 @@ func (s *Server) handleSessionMessage(w http.ResponseWriter, r *http.Request) {
 	logger.System().Debug("Content-Type: %s", r.Header.Get("Content-Type"))
 
 	// Validate authentication
-	if !s.validateAuthentication(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	principal, err := s.authenticatePrincipal(r)
+	if err != nil {
+		if writeRateLimited(w, err) {
+			return
+		}
+		writeError(w, http.StatusUnauthorized, ErrorUnauthorized, "Unauthorized")
 		return
 	}
+	r = r.WithContext(context.WithValue(r.Context(), "mcpPrincipal", principal))
 
-	// Get the MCP server
-	mcpServer, exists := s.mcpManager.GetServer(serverName)
+	var admitted bool
+	if r, admitted = s.admitTenant(w, r, principal, serverName); !admitted {
+		return
+	}
+
+	// Responses to the session's requests count towards its connection's bytes sent
+	w = s.connectionManager.Meter(sessionID, w)
+
+	if s.isAggregateServer(serverName) {
+		s.handleAggregateMessage(w, r, sessionID)
+		return
+	}
+
+	if s.mcpManager.InMaintenance(serverName) {
+		writeMaintenanceResponse(w, serverName)
+		return
+	}
+
+	// Get the MCP server, or its fallback while failed over
+	mcpServer, exists := s.mcpManager.GetActiveServer(serverName)
 	if !exists {
-		http.Error(w, fmt.Sprintf("MCP server '%s' not found", serverName), http.StatusNotFound)
+		writeServerError(w, http.StatusNotFound, ErrorServerNotFound, serverName, fmt.Sprintf("MCP server '%s' not found", serverName),
+			"GET /listmcp lists the MCP servers this host serves")
 		return
 	}
 
 	// Read the request body first to check message type
 	logger.System().Debug("Reading session message body...")
-	body, err := io.ReadAll(r.Body)
+	body, err := s.readMessageBody(w, r, serverName)
 	if err != nil {
 		logger.System().Error(" Failed to read session message body: %v", err)
 		logger.System().Info("=== SESSION MESSAGE END (BODY READ FAILED) ===")
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		s.sendBodyReadError(w, err, true)
 		return
 	}
 	logger.System().Info("SUCCESS: Session message body read (%d bytes)", len(body))
@@ -1347,10 +1806,13 @@ func (s *Server) handleSessionMessage(w http.ResponseWriter, r *http.Request) {
 	if err := json.Unmarshal(body, &jsonrpcMsg); err != nil {
 		logger.System().Error(" Invalid session message JSON-RPC: %v", err)
 		logger.System().Info("=== SESSION MESSAGE END (JSON PARSE FAILED) ===")
-		http.Error(w, fmt.Sprintf("Invalid JSON-RPC message: %v", err), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrorInvalidMessage, fmt.Sprintf("Invalid JSON-RPC message: %v", err))
 		return
 	}
 	logger.System().Info("SUCCESS: Session message JSON-RPC parsed")
+
+	// Any client message, such as a ping sent as keep-alive, keeps the connection from going idle
+	s.connectionManager.RecordRequest(sessionID, jsonrpcMsg.Method)
 	logger.System().Debug("Session message method: %s, ID: %v, SessionID: %s", jsonrpcMsg.Method, jsonrpcMsg.ID, sessionID)
 
 	// CRITICAL FIX: Allow handshake messages on uninitialized sessions
@@ -1368,7 +1830,16 @@ func (s *Server) handleSessionMessage(w http.ResponseWriter, r *http.Request) {
 
 	if !isHandshake && !isInitialized {
 		logger.System().Error(" Session %s not initialized for non-handshake method %s", sessionID, jsonrpcMsg.Method)
-		http.Error(w, "Session not initialized", http.StatusBadRequest)
+		s.sendTransportError(w, jsonrpcMsg.ID, ErrorSessionNotInitialized, "Session not initialized", true,
+			"Send an initialize request on the session before other requests")
+		return
+	}
+
+	// Answers to server-initiated requests go straight back to the server
+	if s.forwardClientResponse(w, serverName, sessionID, &jsonrpcMsg, body) {
+		return
+	}
+	if s.handleClientCancellation(w, sessionID, &jsonrpcMsg) {
 		return
 	}
 
@@ -1407,11 +1878,20 @@ func (s *Server) handleSessionMessage(w http.ResponseWriter, r *http.Request) {
 	mcpRequestBytes, err := s.translator.RemoteToMCP(body)
 	if err != nil {
 		logger.System().Error(" Failed to convert Remote MCP to MCP format: %v", err)
-		http.Error(w, "Failed to process request", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Failed to process request")
 		return
 	}
 	logger.System().Debug("Converted request to MCP format: %s", string(mcpRequestBytes))
 
+	mcpRequestBytes = s.restoreResourceURIs(r, sessionID, serverName, jsonrpcMsg.Method, mcpRequestBytes)
+	if s.sendFallbackResponse(w, sessionID, &jsonrpcMsg) {
+		return
+	}
+
+	if s.rejectToolCall(w, r, serverName, sessionID, mcpRequestBytes, jsonrpcMsg.ID, true) {
+		return
+	}
+
 	// Send request and receive response from MCP server using serialized queue
 	//
 	// INVESTIGATION FIX: Increased timeout from 30s to 2 minutes for tools/call operations
@@ -1427,14 +1907,35 @@ func (s *Server) handleSessionMessage(w http.ResponseWriter, r *http.Request) {
 	// - Claude.ai reports "-32000: Connection closed" (client-side error)
 	//
 	// This timeout applies to all MCP operations sent through handleSessionMessage,
-	// including tools/call which is the most likely to exceed 30 seconds.
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	// including tools/call which is the most likely to exceed 30 seconds. Per-method
+	// "timeouts" in the config override it.
+	//
+	// The context derives from the client's HTTP request: when the client aborts it, or
+	// cancels it by ID, the wait ends and the server receives notifications/cancelled.
+	// The timeout starts once the call has a tool call slot.
+	callCtx, cancelCall := context.WithCancel(r.Context())
+	defer cancelCall()
+	defer s.inFlight.Track(sessionID, jsonrpcMsg.ID, cancelCall)()
+
+	// Bound parallel tool calls per session so one client cannot starve a shared backend
+	release, err := s.acquireToolCallSlot(callCtx, sessionID, jsonrpcMsg.Method)
+	if err != nil {
+		s.sendToolCallLimitError(w, jsonrpcMsg.ID, sessionID, err, true)
+		return
+	}
+	defer release()
+
+	ctx, cancel := s.requestContext(callCtx, sessionID, serverName, jsonrpcMsg.Method, 2*time.Minute)
 	defer cancel()
+	ctx = context.WithValue(ctx, "responseHeader", w.Header()) // Header hooks set headers on the reply
 
-	responseBytes, err := mcpServer.SendAndReceive(ctx, mcpRequestBytes)
+	responseBytes, err := s.forwardRequest(ctx, serverName, mcpServer, mcpRequestBytes)
+	if err != nil && (s.finishCancelledRequest(ctx, w, r, jsonrpcMsg.ID, err, true) || s.rejectUnreadableResponse(w, err, jsonrpcMsg.ID, true)) {
+		return
+	}
 	if err != nil {
 		logger.System().Error(" Failed to send/receive message to MCP server %s: %v", serverName, err)
-		http.Error(w, "Failed to communicate with MCP server", http.StatusInternalServerError)
+		s.sendTransportError(w, jsonrpcMsg.ID, ErrorUpstreamFailed, "Failed to communicate with MCP server", true)
 		return
 	}
 
@@ -1445,6 +1946,8 @@ func (s *Server) handleSessionMessage(w http.ResponseWriter, r *http.Request) {
 		if err := json.Unmarshal(responseBytes, &mcpResponse); err == nil && mcpResponse.Result != nil {
 			// Mark session as initialized after successful initialize
 			if jsonrpcMsg.Method == "initialize" {
+				s.translator.RecordBackendCapabilities(sessionID, responseBytes)
+				responseBytes = s.translator.NegotiatedInitializeResponse(sessionID, responseBytes)
 				err := s.translator.HandleInitialized(sessionID)
 				if err != nil {
 					logger.System().Error(" Failed to mark session as initialized: %v", err)
@@ -1459,34 +1962,76 @@ func (s *Server) handleSessionMessage(w http.ResponseWriter, r *http.Request) {
 	//
 	// The MCP server returns standard JSON-RPC format, but Claude.ai expects
 	// Remote MCP format. This conversion ensures proper protocol compliance.
+	if jsonrpcMsg.Method == "tools/list" {
+		s.translator.CacheToolSchemasFromResponse(serverName, responseBytes)
+		responseBytes = s.translator.FilterToolsListResponse(serverName, responseBytes)
+	}
+	s.recordSubscription(serverName, sessionID, &jsonrpcMsg, responseBytes)
+	responseBytes = s.translator.RewriteResourceURIs(sessionID, serverName, jsonrpcMsg.Method, responseBytes)
 	remoteMCPResponse, err := s.translator.MCPToRemote(responseBytes)
 	if err != nil {
 		logger.System().Error(" Failed to convert MCP to Remote MCP format: %v", err)
-		http.Error(w, "Failed to process response", http.StatusInternalServerError)
+		s.sendTransportError(w, jsonrpcMsg.ID, ErrorInternal, "Failed to process response", true)
 		return
 	}
-	logger.System().Debug("Converted response to Remote MCP format: %s", string(remoteMCPResponse))
+	logger.System().Debug("Converted response to Remote MCP format: %s", remoteMCPResponse)
 
 	// Return response directly to Claude.ai
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Mcp-Session-Id", sessionID)
-	w.WriteHeader(http.StatusOK)
-
-	if _, err := w.Write(remoteMCPResponse); err != nil {
+	if err := s.writeResponse(w, remoteMCPResponse); err != nil {
 		logger.System().Error(" Failed to write session response: %v", err)
 	} else {
 		logger.System().Info("INFO: Successfully returned synchronous response for %s to session %s", jsonrpcMsg.Method, sessionID)
 	}
 }
 
-// sendErrorResponse sends a JSON-RPC error response
+// rejectToolCall answers a tools/call with an InvalidParams error, and reports
+// whether it did so, when the tool is hidden by the server's allowedTools/blockedTools
+// or the arguments do not match the tool's cached inputSchema. Calls the policy
+// refuses are answered with an InvalidRequest error, and calls it holds for
+// approval return once an approver decided them.
+//
+// Stdio servers that don't validate their input can hang on malformed arguments,
+// so those calls never reach them.
+func (s *Server) rejectToolCall(w http.ResponseWriter, r *http.Request, serverName, sessionID string, request []byte, id interface{}, isRemoteMCP bool) bool {
+	toolName, arguments := protocol.ParseToolCall(request)
+	if toolName == "" {
+		return false
+	}
+
+	if !s.translator.IsToolAllowed(serverName, toolName) {
+		logger.System().Warn(" Blocked call to tool %s on server %s by tool filter", toolName, serverName)
+		s.sendErrorResponse(w, id, protocol.InvalidParams, fmt.Sprintf("Tool '%s' is not available", toolName), isRemoteMCP)
+		return true
+	}
+
+	if err := s.translator.ValidateToolArguments(serverName, toolName, arguments); err != nil {
+		logger.System().Warn(" Rejected call to tool %s on server %s: %v", toolName, serverName, err)
+		s.sendErrorResponse(w, id, protocol.InvalidParams, err.Error(), isRemoteMCP)
+		return true
+	}
+
+	var msg protocol.JSONRPCMessage
+	json.Unmarshal(request, &msg)
+	params, _ := msg.Params.(map[string]interface{})
+	if s.refusedByPolicy(w, r, newPolicyCall(r, sessionID, serverName, toolName, params), id, isRemoteMCP) {
+		return true
+	}
+
+	// The quota comes last, because admitting the call counts it
+	return s.refusedByQuota(w, r, serverName, toolName, id, isRemoteMCP)
+}
+
+// sendErrorResponse sends a JSON-RPC protocol error, in an HTTP 200 response
+// Failures to deliver the request go through sendTransportError instead.
 func (s *Server) sendErrorResponse(w http.ResponseWriter, id interface{}, code int, message string, isRemoteMCP bool) {
 	logger.System().Error(" Sending error response - Code: %d, Message: %s", code, message)
 
 	errorResponse, err := s.translator.CreateErrorResponse(id, code, message, isRemoteMCP)
 	if err != nil {
 		logger.System().Error(" Failed to create error response: %v", err)
-		http.Error(w, "Failed to create error response", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to create error response")
 		return
 	}
 
@@ -1500,25 +2045,164 @@ func (s *Server) sendErrorResponse(w http.ResponseWriter, id interface{}, code i
 	}
 }
 
+// sendFallbackResponse answers a method whose capability the session's MCP server did not announce
+// It returns false when the request should be forwarded to the server.
+func (s *Server) sendFallbackResponse(w http.ResponseWriter, sessionID string, msg *protocol.JSONRPCMessage) bool {
+	if !s.translator.ShouldProvideFallback(sessionID, msg.Method) {
+		return false
+	}
+	response, err := s.translator.CreateFallbackResponse(msg.ID, msg.Method)
+	if err != nil {
+		logger.System().Error(" Failed to create fallback response for %s: %v", msg.Method, err)
+		return false
+	}
+
+	logger.System().Debug("Answering %s for session %s: its MCP server does not support it", msg.Method, sessionID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Mcp-Session-Id", sessionID)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(response); err != nil {
+		logger.System().Error(" Failed to write fallback response: %v", err)
+	}
+	return true
+}
+
+// acquireToolCallSlot reserves a per-session slot for tools/call requests
+// Calls from a session over its disk quota are refused. Other methods are not
+// limited and receive a no-op release function. The wait in the session's queue
+// is bounded by toolCallSlotWait on top of ctx, so callers start the request's
+// own timeout once they have the slot.
+func (s *Server) acquireToolCallSlot(ctx context.Context, sessionID, method string) (func(), error) {
+	if method != "tools/call" {
+		return func() {}, nil
+	}
+	if err := s.mcpManager.CheckSessionQuota(sessionID); err != nil {
+		return nil, err
+	}
+	if s.toolCallLimiter == nil {
+		return func() {}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, toolCallSlotWait)
+	defer cancel()
+	return s.toolCallLimiter.Acquire(ctx, sessionID)
+}
+
+// sendToolCallLimitError reports a tools/call that could not obtain a slot or was refused by the disk quota
+func (s *Server) sendToolCallLimitError(w http.ResponseWriter, id interface{}, sessionID string, err error, isRemoteMCP bool) {
+	var quotaErr *mcp.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		s.sendQuotaExceededError(w, id, sessionID, quotaErr, isRemoteMCP)
+		return
+	}
+	if err != ErrSessionBusy {
+		logger.System().Error(" Gave up waiting for tool call slot in session %s: %v", sessionID, err)
+		s.sendTransportError(w, id, ErrorTimeout, "Timed out waiting for a tool call slot", isRemoteMCP)
+		return
+	}
+
+	running, waiting := s.toolCallLimiter.Stats(sessionID)
+	logger.System().Warn(" Rejecting tools/call for session %s: %d running, %d queued", sessionID, running, waiting)
+
+	data := map[string]interface{}{
+		"reason":        "session_busy",
+		"maxConcurrent": s.toolCallLimiter.MaxConcurrent(),
+		"maxQueued":     s.toolCallLimiter.MaxQueued(),
+		"running":       running,
+		"queued":        waiting,
+	}
+	errorResponse, createErr := s.translator.CreateErrorResponseWithData(id, protocol.SessionBusy,
+		"Too many concurrent tool calls for this session, retry later", data, isRemoteMCP)
+	if createErr != nil {
+		logger.System().Error(" Failed to create busy response: %v", createErr)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to create error response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Mcp-Session-Id", sessionID)
+	w.Header().Set("Retry-After", "1")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(errorResponse); err != nil {
+		logger.System().Error(" Failed to write busy response: %v", err)
+	}
+}
+
 // validateAuthentication validates the authentication for the request
 func (s *Server) validateAuthentication(r *http.Request) bool {
+	return s.authenticate(r) == nil
+}
+
+// errUnauthorized is returned by authenticate for missing or refused bearer tokens
+var errUnauthorized = errors.New("unauthorized")
+
+// authenticate checks the request's bearer token
+// Errors other than errUnauthorized may be a *rateLimitError, see writeRateLimited.
+func (s *Server) authenticate(r *http.Request) error {
+	_, err := s.authenticatePrincipal(r)
+	return err
+}
+
+// authenticatePrincipal checks the request's bearer token and returns who it authenticates
+// The principal is "api-key:<name>", "oidc:<subject>", or "oauth:<fingerprint>"
+// and "token:<fingerprint>" for tokens issued by the proxy and accepted as is.
+func (s *Server) authenticatePrincipal(r *http.Request) (string, error) {
 	// Check for Authorization header
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
 		logger.System().Error(" No authorization header found, authentication required")
-		return false
+		return "", errUnauthorized
 	}
 
 	// Parse Bearer token
 	if !strings.HasPrefix(authHeader, "Bearer ") {
 		logger.System().Error(" Invalid authorization header format, expected Bearer token")
-		return false
+		return "", errUnauthorized
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
 		logger.System().Error(" Empty bearer token")
-		return false
+		return "", errUnauthorized
+	}
+
+	// API keys are accepted in every mode, and are the only tokens in api-key mode
+	if name, found, err := s.apiKeys.Authenticate(token, s.requestServer(r)); found {
+		if err != nil {
+			logger.System().Error(" Refused API key: %v", err)
+			return "", err
+		}
+		logger.System().Debug("Authenticated with API key %s", name)
+		return "api-key:" + name, nil
+	}
+	if s.authMode() == config.AuthModeAPIKey {
+		logger.System().Error(" Unknown API key")
+		return "", errUnauthorized
+	}
+
+	// In oidc mode tokens are the provider's JWTs, or the proxy's own in-process clients'
+	if s.consentMode() == config.ConsentOIDC {
+		if clientID, valid := s.oauth.TokenClient(token); valid && clientID == inProcessClientID {
+			return "oauth:" + tokenFingerprint(r), nil
+		}
+		if s.oidc == nil {
+			return "", errUnauthorized
+		}
+		claims, err := s.oidc.Verify(r.Context(), token)
+		if err != nil {
+			logger.System().Error(" Invalid OIDC token: %v", err)
+			return "", errUnauthorized
+		}
+		logger.System().Debug("Authenticated OIDC subject %s", claims.Subject)
+		return "oidc:" + claims.Subject, nil
+	}
+
+	// With consent enabled only tokens issued through the consent page are valid
+	if s.consentMode() != config.ConsentOff {
+		if !s.oauth.ValidToken(token) {
+			logger.System().Error(" Unknown or expired bearer token")
+			return "", errUnauthorized
+		}
+		return "oauth:" + tokenFingerprint(r), nil
 	}
 
 	// Simple token validation - accept any non-empty token for Claude.ai compatibility
@@ -1529,7 +2213,7 @@ func (s *Server) validateAuthentication(r *http.Request) bool {
 		}
 		return token
 	}())
-	return true
+	return "token:" + tokenFingerprint(r), nil
 }
 
 // validateOrigin validates the Origin header for security
@@ -1562,7 +2246,7 @@ func (s *Server) validateOrigin(r *http.Request) bool {
 // handleOAuthMetadata returns OAuth server metadata for discovery
 func (s *Server) handleOAuthMetadata(w http.ResponseWriter, r *http.Request) {
 	metadata := map[string]interface{}{
-		"issuer":                 fmt.Sprintf("https://%s", r.Host),
+		"issuer":                 oauthIssuer(r),
 		"authorization_endpoint": fmt.Sprintf("https://%s/oauth/authorize", r.Host),
 		"token_endpoint":         fmt.Sprintf("https://%s/oauth/token", r.Host),
 		"registration_endpoint":  fmt.Sprintf("https://%s/oauth/register", r.Host),
@@ -1572,8 +2256,9 @@ func (s *Server) handleOAuthMetadata(w http.ResponseWriter, r *http.Request) {
 		"grant_types_supported": []string{
 			"authorization_code",
 		},
-		"scopes_supported": []string{
-			"mcp",
+		"scopes_supported": s.oauthScopes(oauthServer(r)),
+		"code_challenge_methods_supported": []string{
+			"S256",
 		},
 		"token_endpoint_auth_methods_supported": []string{
 			"client_secret_basic",
@@ -1592,6 +2277,26 @@ func (s *Server) handleClientRegistration(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Requested redirect URIs must be on the subdomain's allowlist
+	serverName := oauthServer(r)
+	var registration struct {
+		RedirectURIs []string `json:"redirect_uris"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&registration)
+	}
+	redirectURIs := registration.RedirectURIs
+	for _, uri := range redirectURIs {
+		if !s.redirectURIAllowed(serverName, uri) {
+			logger.System().Warn("OAuth client registration refused: redirect URI %s is not allowed", uri)
+			writeOAuthError(w, http.StatusBadRequest, "invalid_redirect_uri", "redirect URI "+uri+" is not allowed")
+			return
+		}
+	}
+	if len(redirectURIs) == 0 {
+		redirectURIs = s.defaultRedirectURIs(serverName)
+	}
+
 	// Generate a client ID and secret
 	clientID := generateRandomString(32)
 	clientSecret := generateRandomString(64)
@@ -1602,17 +2307,14 @@ func (s *Server) handleClientRegistration(w http.ResponseWriter, r *http.Request
 		"client_secret":            clientSecret,
 		"client_id_issued_at":      time.Now().Unix(),
 		"client_secret_expires_at": 0, // Never expires
-		"redirect_uris": []string{
-			"https://claude.ai/oauth/callback",
-			"https://www.claude.ai/oauth/callback",
-		},
+		"redirect_uris":            redirectURIs,
 		"grant_types": []string{
 			"authorization_code",
 		},
 		"response_types": []string{
 			"code",
 		},
-		"scope": "mcp",
+		"scope": strings.Join(s.oauthScopes(serverName), " "),
 	}
 
 	logger.System().Info("OAuth client registered - ID: %s", clientID)
@@ -1622,69 +2324,6 @@ func (s *Server) handleClientRegistration(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(registrationResponse)
 }
 
-// handleAuthorize handles OAuth authorization requests
-func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
-	clientID := r.URL.Query().Get("client_id")
-	redirectURI := r.URL.Query().Get("redirect_uri")
-	state := r.URL.Query().Get("state")
-	responseType := r.URL.Query().Get("response_type")
-
-	if clientID == "" || redirectURI == "" || responseType != "code" {
-		http.Error(w, "Invalid authorization request", http.StatusBadRequest)
-		return
-	}
-
-	// Generate authorization code
-	authCode := generateRandomString(32)
-
-	logger.System().Info("OAuth authorization request - Client: %s, Redirect: %s", clientID, redirectURI)
-
-	// Redirect with authorization code
-	callbackURL := fmt.Sprintf("%s?code=%s", redirectURI, authCode)
-	if state != "" {
-		callbackURL += fmt.Sprintf("&state=%s", state)
-	}
-
-	http.Redirect(w, r, callbackURL, http.StatusFound)
-}
-
-// handleToken handles OAuth token exchange
-func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	grantType := r.FormValue("grant_type")
-	code := r.FormValue("code")
-	clientID := r.FormValue("client_id")
-
-	if grantType != "authorization_code" || code == "" || clientID == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error":             "invalid_request",
-			"error_description": "Invalid token request",
-		})
-		return
-	}
-
-	// Generate access token
-	accessToken := generateRandomString(64)
-
-	tokenResponse := map[string]interface{}{
-		"access_token": accessToken,
-		"token_type":   "Bearer",
-		"expires_in":   3600,
-		"scope":        "mcp",
-	}
-
-	logger.System().Info("OAuth token issued - Client: %s, Token: %s...", clientID, accessToken[:10])
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tokenResponse)
-}
-
 // generateRandomString generates a cryptographically secure random string
 func generateRandomString(length int) string {
 	bytes := make([]byte, length/2)
@@ -1710,7 +2349,7 @@ func (s *Server) handleServerHealth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if s.healthChecker == nil {
-		http.Error(w, "Health checker not available", http.StatusServiceUnavailable)
+		writeError(w, http.StatusServiceUnavailable, ErrorFeatureDisabled, "Health checker not available")
 		return
 	}
 
@@ -1727,6 +2366,11 @@ func (s *Server) handleServerHealth(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
+	// Package installation progress while servers are (or were) being preinstalled
+	if preinstalls := s.mcpManager.PreinstallStatus(); len(preinstalls) > 0 {
+		response["preinstall"] = preinstalls
+	}
+
 	// Calculate summary
 	for _, health := range healthStatus {
 		switch health.Status {
@@ -1760,14 +2404,14 @@ func (s *Server) handleResourceMetrics(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if s.resourceMonitor == nil {
-		http.Error(w, "Resource monitor not available", http.StatusServiceUnavailable)
+		writeError(w, http.StatusServiceUnavailable, ErrorFeatureDisabled, "Resource monitor not available")
 		return
 	}
 
 	metrics, err := s.resourceMonitor.GetCurrentMetrics()
 	if err != nil {
 		logger.System().Error("Failed to get resource metrics: %v", err)
-		http.Error(w, "Failed to get resource metrics", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to get resource metrics")
 		return
 	}
 
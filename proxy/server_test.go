@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -134,9 +133,16 @@ func TestHealthEndpoint(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
 	}
 
-	expectedBody := `{"status":"healthy"}`
-	if strings.TrimSpace(rr.Body.String()) != expectedBody {
-		t.Errorf("Expected body %s, got %s", expectedBody, rr.Body.String())
+	var body struct {
+		Status        string    `json:"status"`
+		StartedAt     time.Time `json:"startedAt"`
+		UptimeSeconds *int64    `json:"uptimeSeconds"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse health response %s: %v", rr.Body.String(), err)
+	}
+	if body.Status != "healthy" || body.StartedAt.IsZero() || body.UptimeSeconds == nil {
+		t.Errorf("Expected healthy status with uptime, got %s", rr.Body.String())
 	}
 
 	// Check content type
@@ -146,6 +152,32 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestProbeEndpoints(t *testing.T) {
+	mcpManager := mcp.NewManager(map[string]config.MCPServer{})
+	router := NewServer(mcpManager).Router()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"HEAD", "/", http.StatusNoContent},
+		{"GET", "/favicon.ico", http.StatusNoContent},
+		{"HEAD", "/favicon.ico", http.StatusNoContent},
+		{"HEAD", "/health", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, rr.Code)
+		}
+	}
+}
+
 func TestCORSMiddleware(t *testing.T) {
 	configs := map[string]config.MCPServer{
 		"test-server": {
@@ -383,10 +415,11 @@ func TestConnectionCleanup(t *testing.T) {
 		t.Fatalf("Failed to add connection: %v", err)
 	}
 
-	// Manually adjust the connection time to be old
+	// Manually adjust the connection time to be old, without activity since
 	cm.mu.Lock()
 	if conn, exists := cm.connections["old-session"]; exists {
 		conn.ConnectedAt = time.Now().Add(-20 * time.Minute) // 20 minutes ago
+		conn.lastActivity.Store(conn.ConnectedAt.UnixNano())
 	}
 	cm.mu.Unlock()
 
@@ -399,8 +432,8 @@ func TestConnectionCleanup(t *testing.T) {
 		t.Fatalf("Failed to add new connection: %v", err)
 	}
 
-	// Clean up connections older than 10 minutes
-	cm.CleanupStaleConnections(10 * time.Minute)
+	// Clean up connections idle for more than 10 minutes
+	cm.CleanupStaleConnections(10*time.Minute, 0)
 
 	// Check that old connection was removed and new one remains
 	connections := cm.GetConnections()
@@ -417,6 +450,40 @@ func TestConnectionCleanup(t *testing.T) {
 	}
 }
 
+func TestConnectionIdlePolicy(t *testing.T) {
+	cm := NewConnectionManager(10, nil)
+	for _, sessionID := range []string{"active-session", "idle-session", "touched-session"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := cm.AddConnection(sessionID, "test-server", ctx, cancel); err != nil {
+			t.Fatalf("Failed to add connection: %v", err)
+		}
+	}
+
+	// All three connected 20 minutes ago; only the active one saw requests since
+	cm.mu.Lock()
+	for sessionID, conn := range cm.connections {
+		conn.ConnectedAt = time.Now().Add(-20 * time.Minute)
+		if sessionID != "active-session" {
+			conn.lastActivity.Store(conn.ConnectedAt.UnixNano())
+		}
+	}
+	cm.mu.Unlock()
+	cm.Touch("touched-session")
+
+	cm.CleanupStaleConnections(10*time.Minute, 0)
+	connections := cm.GetConnections()
+	if _, exists := connections["idle-session"]; exists || len(connections) != 2 {
+		t.Errorf("Expected only the idle connection to be removed, got %v", connections)
+	}
+
+	// A maximum age ends connections however active
+	cm.CleanupStaleConnections(10*time.Minute, 15*time.Minute)
+	if count := cm.GetConnectionCount(); count != 0 {
+		t.Errorf("Expected connections past the maximum age to be removed, %d remain", count)
+	}
+}
+
 func TestValidateAuthentication(t *testing.T) {
 	configs := map[string]config.MCPServer{}
 	mcpManager := mcp.NewManager(configs)
@@ -587,3 +654,35 @@ func BenchmarkSessionIDGeneration(b *testing.B) {
 		server.getSessionID(req)
 	}
 }
+
+func TestRequestContextTimeouts(t *testing.T) {
+	cfg := &config.Config{
+		MCPServers: map[string]config.MCPServer{
+			"crawler": {Command: "crawler", Timeouts: map[string]string{"tools/call": "300s"}},
+		},
+	}
+	server := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil)
+
+	remaining := func(ctx context.Context) time.Duration {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Fatal("Expected a deadline")
+		}
+		return time.Until(deadline)
+	}
+
+	ctx, cancel := server.requestContext(context.Background(), "session-1", "crawler", "tools/call", 2*time.Minute)
+	defer cancel()
+	if d := remaining(ctx); d < 4*time.Minute {
+		t.Errorf("Expected the configured tools/call timeout, got %v", d)
+	}
+	if sessionID, _ := ctx.Value("sessionID").(string); sessionID != "session-1" {
+		t.Errorf("Expected the session ID in the context, got %q", sessionID)
+	}
+
+	ctx, cancel = server.requestContext(context.Background(), "session-1", "crawler", "tools/list", 10*time.Second)
+	defer cancel()
+	if d := remaining(ctx); d > 10*time.Second {
+		t.Errorf("Expected the default timeout for tools/list, got %v", d)
+	}
+}
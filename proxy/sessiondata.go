@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/protocol"
+	"remote-mcp-proxy/state"
+)
+
+// bindSessionIdentity tells the manager which client a session belongs to when data is kept per client,
+// and passes on the request headers servers use as template variables
+func (s *Server) bindSessionIdentity(r *http.Request, sessionID string) {
+	s.mcpManager.SetSessionHeaders(sessionID, r.Header)
+	if !s.mcpManager.NeedsSessionIdentity(sessionID) {
+		return
+	}
+	if identity := s.clientIdentity(r); identity != "" {
+		s.mcpManager.SetSessionIdentity(sessionID, identity)
+	}
+}
+
+// clientIdentity returns the principal the auth middleware authenticated the request as
+// API keys are identified by name and OIDC tokens by subject, so a client keeps
+// its identity when its token is refreshed. It is empty for unauthenticated requests.
+func (s *Server) clientIdentity(r *http.Request) string {
+	principal, _ := r.Context().Value("mcpPrincipal").(string)
+	return principal
+}
+
+// sendQuotaExceededError reports a tools/call refused because the session directory is over its quota
+func (s *Server) sendQuotaExceededError(w http.ResponseWriter, id interface{}, sessionID string, quotaErr *mcp.QuotaExceededError, isRemoteMCP bool) {
+	logger.System().Warn(" Rejecting tools/call for session %s: %v", sessionID, quotaErr)
+
+	data := map[string]interface{}{
+		"reason":     "session_quota_exceeded",
+		"usedBytes":  quotaErr.Used,
+		"quotaBytes": quotaErr.Quota,
+	}
+	errorResponse, err := s.translator.CreateErrorResponseWithData(id, protocol.SessionQuotaExceeded,
+		"Session data exceeds its disk quota, delete files to continue", data, isRemoteMCP)
+	if err != nil {
+		logger.System().Error(" Failed to create quota response: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to create error response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Mcp-Session-Id", sessionID)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(errorResponse); err != nil {
+		logger.System().Error(" Failed to write quota response: %v", err)
+	}
+}
+
+// handleSessionData reports the size of a session's directory (GET) or deletes its files (DELETE)
+func (s *Server) handleSessionData(w http.ResponseWriter, r *http.Request) {
+	sessionID, _, found := s.lookupSession(w, mux.Vars(r)["sessionId"])
+	if !found {
+		return
+	}
+
+	response := map[string]interface{}{"session": sessionID}
+	if r.Method == http.MethodDelete {
+		actor := adminActor(r)
+		freed, err := s.mcpManager.ClearSessionData(sessionID)
+		s.mcpManager.GetIncidentStore().RecordIncident(state.Incident{
+			Kind:    state.IncidentAdmin,
+			Actor:   actor,
+			Action:  "clear-session-data",
+			Success: err == nil,
+			Details: map[string]interface{}{"session": sessionID, "freedBytes": freed},
+		})
+		if err != nil {
+			logger.System().Error("Failed to clear data of session %s for %s: %v", sessionID[:8], actor, err)
+			writeError(w, http.StatusInternalServerError, ErrorInternal, fmt.Sprintf("Failed to clear session data: %v", err))
+			return
+		}
+		response["freedBytes"] = freed
+	}
+	response["data"] = s.mcpManager.GetSessionData(sessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.System().Error("Failed to encode session data response: %v", err)
+	}
+}
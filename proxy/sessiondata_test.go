@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"remote-mcp-proxy/config"
+)
+
+func TestClientIdentity(t *testing.T) {
+	server, _ := newOAuthTestServer(t, apiKeyConfig(config.AuthModeOAuth))
+
+	authenticated := func(token, principal string) *http.Request {
+		req := keyRequest("memory", token)
+		return req.WithContext(context.WithValue(req.Context(), "mcpPrincipal", principal))
+	}
+
+	if ci := server.clientIdentity(authenticated("global-key", "api-key:ci")); ci != "api-key:ci" {
+		t.Errorf("Expected the API key principal, got %q", ci)
+	}
+
+	before := server.clientIdentity(authenticated("access-token-1", "oidc:alice"))
+	after := server.clientIdentity(authenticated("access-token-2", "oidc:alice"))
+	if before != "oidc:alice" || after != before {
+		t.Errorf("Expected a refreshed token to keep its principal, got %q and %q", before, after)
+	}
+
+	if unauthenticated := server.clientIdentity(keyRequest("memory", "some-oauth-token")); unauthenticated != "" {
+		t.Errorf("Expected no identity without an authenticated principal, got %q", unauthenticated)
+	}
+}
+
+func TestSessionDataEndpoint(t *testing.T) {
+	server, router := newOAuthTestServer(t, &config.Config{
+		AdminToken: "admin",
+		MCPServers: map[string]config.MCPServer{"memory": {Command: "echo"}},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.connectionManager.AddConnection("session-abcdef123", "memory", ctx, cancel)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{"GET", "/admin/sessions/other-session/data", http.StatusNotFound},
+		{"GET", "/admin/sessions/sess/data", http.StatusBadRequest},
+		{"GET", "/admin/sessions/session-/data", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d: %s", tt.method, tt.path, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
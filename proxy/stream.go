@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"net/http"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+)
+
+// streamSettings returns the size above which responses are streamed, and the chunk size
+func (s *Server) streamSettings() (int, int) {
+	if s.config == nil {
+		return config.DefaultStreamThreshold, config.DefaultStreamChunkSize
+	}
+	chunkSize := s.config.StreamChunkSize
+	if chunkSize <= 0 {
+		chunkSize = config.DefaultStreamChunkSize
+	}
+	return s.config.StreamThreshold, chunkSize
+}
+
+// writeResponse writes a 200 response carrying a JSON-RPC message
+// Bodies above the stream threshold go out as chunked HTTP, flushed chunk by chunk,
+// so clients and reverse proxies see data arriving instead of waiting on one write
+// of several megabytes.
+func (s *Server) writeResponse(w http.ResponseWriter, body []byte) error {
+	threshold, chunkSize := s.streamSettings()
+	flusher, canFlush := w.(http.Flusher)
+	if threshold <= 0 || len(body) <= threshold || !canFlush {
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write(body)
+		return err
+	}
+
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx-style proxies from buffering the chunks
+	w.WriteHeader(http.StatusOK)
+	for start := 0; start < len(body); start += chunkSize {
+		end := min(start+chunkSize, len(body))
+		if _, err := w.Write(body[start:end]); err != nil {
+			return err
+		}
+		flusher.Flush()
+	}
+
+	logger.System().Info("Streamed %d byte response in %d byte chunks", len(body), chunkSize)
+	return nil
+}
@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+// countingRecorder counts the flushes of a streamed response
+type countingRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (r *countingRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+}
+
+func TestWriteResponseStreaming(t *testing.T) {
+	cfg := &config.Config{
+		MCPServers:      map[string]config.MCPServer{},
+		StreamThreshold: 100,
+		StreamChunkSize: 40,
+	}
+	server := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil)
+
+	// Small bodies are written at once
+	small := &countingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if err := server.writeResponse(small, []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)); err != nil {
+		t.Fatalf("writeResponse failed: %v", err)
+	}
+	if small.flushes != 0 || small.Header().Get("X-Accel-Buffering") != "" {
+		t.Errorf("Expected a small response not to be streamed, got %d flushes", small.flushes)
+	}
+
+	// Large bodies go out in flushed chunks, unchanged
+	body := append([]byte(`{"jsonrpc":"2.0","id":1,"result":{"text":"`), bytes.Repeat([]byte("x"), 200)...)
+	body = append(body, `"}}`...)
+	large := &countingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if err := server.writeResponse(large, body); err != nil {
+		t.Fatalf("writeResponse failed: %v", err)
+	}
+	if want := (len(body) + 39) / 40; large.flushes != want {
+		t.Errorf("Expected %d flushed chunks, got %d", want, large.flushes)
+	}
+	if large.Header().Get("X-Accel-Buffering") != "no" {
+		t.Error("Expected proxy buffering to be disabled for a streamed response")
+	}
+	if !bytes.Equal(large.Body.Bytes(), body) {
+		t.Error("Expected the streamed body to match the response")
+	}
+
+	// A zero threshold turns streaming off
+	cfg.StreamThreshold = 0
+	unstreamed := &countingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if err := server.writeResponse(unstreamed, body); err != nil {
+		t.Fatalf("writeResponse failed: %v", err)
+	}
+	if unstreamed.flushes != 0 {
+		t.Errorf("Expected no streaming with a zero threshold, got %d flushes", unstreamed.flushes)
+	}
+}
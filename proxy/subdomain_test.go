@@ -1,10 +1,14 @@
 package proxy
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/gorilla/mux"
+
 	"remote-mcp-proxy/config"
 	"remote-mcp-proxy/mcp"
 )
@@ -190,6 +194,205 @@ func TestSubdomainRouting(t *testing.T) {
 	}
 }
 
+func TestRoutingMode(t *testing.T) {
+	tests := []struct {
+		routing        string
+		host           string
+		path           string
+		expectedStatus int
+	}{
+		{config.RoutingSubdomain, "memory.mcp.example.com", "/sse", http.StatusUnauthorized},
+		{config.RoutingSubdomain, "memory.mcp.example.com", "/memory/sse", http.StatusNotFound},
+		{config.RoutingSubdomain, "localhost:8080", "/memory/sse", http.StatusNotFound},
+		{config.RoutingPath, "localhost:8080", "/memory/sse", http.StatusUnauthorized},
+		{config.RoutingPath, "memory.mcp.example.com", "/sse", http.StatusNotFound},
+		{config.RoutingBoth, "memory.mcp.example.com", "/sse", http.StatusUnauthorized},
+		{config.RoutingBoth, "localhost:8080", "/memory/sse", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.routing+" "+tt.host+tt.path, func(t *testing.T) {
+			cfg := &config.Config{
+				Domain:  "example.com",
+				Routing: tt.routing,
+				MCPServers: map[string]config.MCPServer{
+					"memory": {Command: "echo", Args: []string{"test"}},
+				},
+			}
+			server := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Host = tt.host
+			recorder := httptest.NewRecorder()
+			server.Router().ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestHostRouting(t *testing.T) {
+	cfg := &config.Config{
+		Domain:  "example.com",
+		Domains: []string{"tenant-b.org"},
+		MCPServers: map[string]config.MCPServer{
+			"memory": {Command: "echo"},
+			"notion": {Command: "echo", Hostnames: []string{"notion.example.org"}},
+		},
+	}
+	server := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil)
+
+	for host, want := range map[string]string{
+		"memory.mcp.example.com":   "memory",
+		"memory.mcp.tenant-b.org":  "memory",
+		"Memory.MCP.Tenant-B.org":  "memory",
+		"memory.mcp.other.net":     "",
+		"notion.example.org":       "notion",
+		"notion.example.org:443":   "notion",
+		"notion.mcp.tenant-b.org":  "notion",
+		"memory.example.org":       "",
+		"unknown.mcp.tenant-b.org": "",
+	} {
+		req := httptest.NewRequest("GET", "/sse", nil)
+		req.Host = host
+
+		var captured string
+		var fromHost bool
+		server.subdomainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured, _ = r.Context().Value("mcpServer").(string)
+			fromHost, _ = r.Context().Value("mcpServerFromHost").(bool)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+
+		if captured != want || fromHost != (want != "") {
+			t.Errorf("Host %s: expected server %q, got %q (from host: %v)", host, want, captured, fromHost)
+		}
+	}
+
+	if endpoint := server.landingEndpoint("https://mcp.example.com", "notion", false); endpoint != "https://notion.example.org/sse" {
+		t.Errorf("Expected the landing page to list the custom hostname, got %s", endpoint)
+	}
+}
+
+func TestRouteNotFound(t *testing.T) {
+	cfg := &config.Config{
+		Domain:  "example.com",
+		Routing: config.RoutingBoth,
+		MCPServers: map[string]config.MCPServer{
+			"memory": {Command: "echo"},
+			"notion": {Command: "echo", Hostnames: []string{"notion.example.org"}},
+		},
+	}
+	router := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil).Router()
+
+	for _, tt := range []struct {
+		host, path string
+		status     int
+		attempts   []string
+	}{
+		{"localhost:8080", "/memroy/sse", http.StatusNotFound, []string{"subdomain", "path"}},
+		{"memroy.mcp.example.com", "/sse", http.StatusBadRequest, []string{"subdomain"}},
+		{"mcp.example.com", "/sessions/abc", http.StatusBadRequest, []string{"subdomain", "path"}},
+		{"localhost:8080", "/robots.txt", http.StatusNotFound, []string{"subdomain", "path"}},
+	} {
+		req := httptest.NewRequest("POST", tt.path, nil)
+		req.Host = tt.host
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		var body struct {
+			Error           string         `json:"error"`
+			Attempts        []routeAttempt `json:"attempts"`
+			Servers         []string       `json:"servers"`
+			ExpectedFormats []string       `json:"expectedFormats"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || recorder.Code != tt.status || body.Error != "no_route" {
+			t.Errorf("%s%s: expected a %d route error, got %d %s", tt.host, tt.path, tt.status, recorder.Code, recorder.Body.String())
+			continue
+		}
+		if len(body.Attempts) != len(tt.attempts) {
+			t.Errorf("%s%s: expected attempts %v, got %+v", tt.host, tt.path, tt.attempts, body.Attempts)
+		}
+		for i, attempt := range body.Attempts {
+			if i < len(tt.attempts) && (attempt.Strategy != tt.attempts[i] || attempt.Matched) {
+				t.Errorf("%s%s: expected a failed %s attempt, got %+v", tt.host, tt.path, tt.attempts[i], attempt)
+			}
+		}
+		if strings.Join(body.Servers, ",") != "memory,notion" {
+			t.Errorf("Expected the servers to be listed, got %v", body.Servers)
+		}
+		want := []string{"https://{server}.mcp.example.com/sse", "https://notion.example.org/sse", "http://" + tt.host + "/{server}/sse"}
+		if strings.Join(body.ExpectedFormats, " ") != strings.Join(want, " ") {
+			t.Errorf("Expected formats %v, got %v", want, body.ExpectedFormats)
+		}
+	}
+}
+
+func TestBasePathRouting(t *testing.T) {
+	cfg := &config.Config{
+		Domain:  "example.com",
+		Routing: config.RoutingSubdomain,
+		MCPServers: map[string]config.MCPServer{
+			"memory": {Command: "echo"},
+			"notion": {Command: "echo", BasePath: "/integrations/notion/v1"},
+		},
+	}
+	server := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil)
+
+	for _, tt := range []struct{ host, path, want string }{
+		{"ingress.example.com", "/integrations/notion/v1/sse", "notion"},
+		{"ingress.example.com", "/integrations/notion/v1/sessions/abc", "notion"},
+		{"notion.mcp.example.com", "/integrations/notion/v1/sse", "notion"},
+		{"memory.mcp.example.com", "/integrations/notion/v1/sse", ""},
+		{"ingress.example.com", "/integrations/notion/v2/sse", ""},
+	} {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+
+		var captured string
+		server.subdomainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured, _ = r.Context().Value("mcpServer").(string)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+
+		if captured != tt.want {
+			t.Errorf("%s%s: expected server %q, got %q", tt.host, tt.path, tt.want, captured)
+		}
+	}
+
+	if endpoint := server.landingEndpoint("https://ingress.example.com", "notion", false); endpoint != "https://ingress.example.com/integrations/notion/v1/sse" {
+		t.Errorf("Expected the landing page to list the base path, got %s", endpoint)
+	}
+
+	req := httptest.NewRequest("GET", "/.well-known/oauth-protected-resource/integrations/notion/v1/sse", nil)
+	req.Host = "ingress.example.com"
+	recorder := httptest.NewRecorder()
+	server.Router().ServeHTTP(recorder, req)
+	var metadata struct {
+		Resource string `json:"resource"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &metadata); err != nil || metadata.Resource != "https://ingress.example.com/integrations/notion/v1/sse" {
+		t.Errorf("Expected the base path as protected resource, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestPublicBaseURL(t *testing.T) {
+	server := NewServerWithConfig(mcp.NewManager(nil), &config.Config{Domain: "example.com"}, nil, nil)
+	for remoteAddr, want := range map[string]string{
+		"172.18.0.2:41234":  "https://memory.mcp.example.com", // Traefik on the Docker network
+		"203.0.113.7:41234": "http://proxy.internal:8080",
+	} {
+		req := httptest.NewRequest("GET", "/sse", nil)
+		req.Host = "proxy.internal:8080"
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "memory.mcp.example.com, evil.example.net")
+		if got := server.publicBaseURL(req); got != want {
+			t.Errorf("Peer %s: expected %s, got %s", remoteAddr, want, got)
+		}
+	}
+}
+
 func TestConfigValidateSubdomain(t *testing.T) {
 	cfg := &config.Config{
 		Domain: "example.com",
@@ -320,3 +523,25 @@ func TestEnvironmentConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestEndpointsAreReservedNames(t *testing.T) {
+	server := NewServerWithConfig(mcp.NewManager(nil), &config.Config{Routing: config.RoutingBoth}, nil, nil)
+
+	err := server.routes().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		segment := strings.Split(strings.TrimPrefix(template, "/"), "/")[0]
+		if segment == "" || strings.HasPrefix(segment, "{") {
+			return nil
+		}
+		if !config.IsReservedName(segment) {
+			t.Errorf("Endpoint %s is not reserved: a server named %q would collide with it", template, segment)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
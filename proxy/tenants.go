@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"remote-mcp-proxy/logger"
+)
+
+// requestTenant returns the tenant of a request authenticated as principal, see config.Tenant
+// A host naming a tenant that lists principals or clients only admits those;
+// one listing neither admits every authenticated client.
+func (s *Server) requestTenant(r *http.Request, principal string) (string, error) {
+	if s.config == nil || len(s.config.Tenants) == 0 {
+		return "", nil
+	}
+
+	clientID := ""
+	if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != "" {
+		clientID, _ = s.oauth.TokenClient(token)
+	}
+
+	tenant := s.config.HostTenant(r.Host)
+	if tenant == "" {
+		return s.config.PrincipalTenant(principal, clientID), nil
+	}
+	members := s.config.Tenants[tenant]
+	if (len(members.Principals) > 0 || len(members.Clients) > 0) && !s.config.TenantMember(tenant, principal, clientID) {
+		return "", fmt.Errorf("%s is not a member of tenant %s", principal, tenant)
+	}
+	return tenant, nil
+}
+
+// admitTenant resolves the tenant of an authenticated request and checks it may use serverName
+// It answers requests the tenant may not make, and otherwise returns the request
+// with the tenant in its context ("mcpTenant"). Servers of other tenants are
+// answered as unknown, so tenants can't discover each other's servers.
+func (s *Server) admitTenant(w http.ResponseWriter, r *http.Request, principal, serverName string) (*http.Request, bool) {
+	tenant, err := s.requestTenant(r, principal)
+	if err != nil {
+		logger.System().Warn("Refused request for server %s: %v", serverName, err)
+		writeAuthError(w, http.StatusForbidden, ErrorForbidden, "This token does not belong to the tenant of this host")
+		return r, false
+	}
+	if !s.isAggregateServer(serverName) && !s.config.TenantServes(tenant, serverName) {
+		logger.System().Warn("Refused request of tenant %q for server %s of another tenant", tenant, serverName)
+		s.sendRouteError(w, r, http.StatusNotFound)
+		return r, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), "mcpTenant", tenant)), true
+}
+
+// contextTenant returns the tenant admitTenant stored in a request context
+func contextTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value("mcpTenant").(string)
+	return tenant
+}
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+	"remote-mcp-proxy/protocol"
+)
+
+func TestRequestTenant(t *testing.T) {
+	cfg := &config.Config{
+		MCPServers: map[string]config.MCPServer{"github": {Command: "echo"}, "billing": {Command: "echo"}},
+		Tenants: map[string]config.Tenant{
+			"team-a": {Principals: []string{"api-key:team-a-*"}, Servers: []string{"github"}},
+			"team-b": {Clients: []string{"client-b"}, Servers: []string{"billing"}},
+			"kiosk":  {Servers: []string{"github"}},
+		},
+	}
+	server := NewServerWithConfig(mcp.NewManager(cfg.MCPServers), cfg, nil, nil)
+	clientToken := server.oauth.IssueClientToken("client-b", time.Hour)
+
+	for _, tt := range []struct {
+		host, token, principal, want string
+		refused                      bool
+	}{
+		{host: "localhost", principal: "api-key:team-a-ci", want: "team-a"},
+		{host: "localhost", token: clientToken, principal: "oauth:0f3c", want: "team-b"},
+		{host: "localhost", principal: "api-key:ops", want: ""},
+		{host: "github.mcp.team-a.localhost", principal: "api-key:team-a-ci", want: "team-a"},
+		{host: "github.mcp.team-a.localhost", principal: "api-key:ops", refused: true},
+		{host: "billing.mcp.team-b.localhost", principal: "api-key:team-a-ci", refused: true},
+		{host: "github.mcp.kiosk.localhost", principal: "api-key:ops", want: "kiosk"},
+	} {
+		req := httptest.NewRequest("POST", "/sse", nil)
+		req.Host = tt.host
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		tenant, err := server.requestTenant(req, tt.principal)
+		if (err != nil) != tt.refused || tenant != tt.want {
+			t.Errorf("%s on %s: expected tenant %q (refused: %v), got %q (%v)", tt.principal, tt.host, tt.want, tt.refused, tenant, err)
+		}
+	}
+}
+
+func TestTenantResourceContent(t *testing.T) {
+	cfg := &config.Config{
+		AuthMode: config.AuthModeAPIKey,
+		APIKeys:  []config.APIKey{{Name: "team-a-ci", Key: "team-a-key"}, {Name: "team-b-ci", Key: "team-b-key"}},
+		MCPServers: map[string]config.MCPServer{
+			"github":  {Command: "echo", RewriteResourceURIs: true},
+			"billing": {Command: "echo", RewriteResourceURIs: true},
+		},
+		Tenants: map[string]config.Tenant{
+			"team-a": {Principals: []string{"api-key:team-a-*"}, Servers: []string{"github"}},
+			"team-b": {Principals: []string{"api-key:team-b-*"}, Servers: []string{"billing"}},
+		},
+	}
+	_, handler := newOAuthTestServer(t, cfg)
+
+	read := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/resources/billing/"+protocol.EncodeResourceURI("file:///invoices.csv"), nil)
+		req.Host = "localhost"
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Another tenant's resources are answered like an unknown route
+	if recorder := read("team-a-key"); !strings.Contains(recorder.Body.String(), ErrorNoRoute) {
+		t.Errorf("Expected team-a to be refused team-b's resources, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := read("team-b-key"); strings.Contains(recorder.Body.String(), ErrorNoRoute) {
+		t.Errorf("Expected team-b to reach its own resources, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestTenantsInProcess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	root := t.TempDir()
+	embedded, err := NewInProcess(&config.Config{
+		MCPServers: map[string]config.MCPServer{"helper": helperMCPServerConfig(), "shared": helperMCPServerConfig()},
+		Tenants: map[string]config.Tenant{
+			"team-a": {Principals: []string{"token:*"}, Servers: []string{"helper"}, SessionRoot: root},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if response, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "hello"}); err != nil || response.Error != nil {
+		t.Fatalf("Expected the tenant's server to answer, got %+v (%v)", response, err)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) == 0 {
+		t.Error("Expected the session directory in the tenant's session root")
+	}
+
+	other := embedded.NewClient("shared")
+	defer other.Close()
+	if _, err := other.Initialize(ctx); err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Errorf("Expected a server of no tenant to be unknown to the tenant, got %v", err)
+	}
+
+	// Listings show the servers of the host's tenant
+	for host, want := range map[string]string{"localhost": "shared", "helper.mcp.team-a.localhost": "helper"} {
+		req := httptest.NewRequest("GET", "/listmcp", nil)
+		req.Host = host
+		recorder := httptest.NewRecorder()
+		embedded.Handler.ServeHTTP(recorder, req)
+		var listed struct {
+			Servers []mcp.ServerStatus `json:"servers"`
+		}
+		json.NewDecoder(recorder.Body).Decode(&listed)
+		if len(listed.Servers) != 1 || listed.Servers[0].Name != want {
+			t.Errorf("Host %s: expected only %s to be listed, got %+v", host, want, listed.Servers)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+// TopologyNode is a vertex in the proxy topology graph
+type TopologyNode struct {
+	ID     string                 `json:"id"`
+	Kind   string                 `json:"kind"` // token, session, server, process
+	Label  string                 `json:"label"`
+	Health string                 `json:"health"` // healthy, unhealthy, unknown
+	Attrs  map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// TopologyEdge connects two topology nodes
+type TopologyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Topology is a graph of tokens → sessions → servers → upstream processes
+type Topology struct {
+	Nodes     []TopologyNode `json:"nodes"`
+	Edges     []TopologyEdge `json:"edges"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// healthColors maps health states to Graphviz fill colors
+var healthColors = map[string]string{
+	"healthy":   "palegreen",
+	"unhealthy": "salmon",
+	"unknown":   "lightgrey",
+}
+
+// buildTopology assembles the current topology from the connection and MCP managers
+func (s *Server) buildTopology() *Topology {
+	topology := &Topology{
+		Nodes:     []TopologyNode{},
+		Edges:     []TopologyEdge{},
+		Timestamp: time.Now(),
+	}
+	seen := make(map[string]bool)
+	addNode := func(node TopologyNode) {
+		if !seen[node.ID] {
+			seen[node.ID] = true
+			topology.Nodes = append(topology.Nodes, node)
+		}
+	}
+
+	// Health of configured servers, used to color session instances of the same server
+	serverHealth := make(map[string]string)
+	if s.healthChecker != nil {
+		for name, health := range s.healthChecker.GetHealthStatus() {
+			serverHealth[name] = health.Status
+		}
+	}
+	healthOf := func(baseName string, running bool) string {
+		if !running {
+			return "unhealthy"
+		}
+		if status, exists := serverHealth[baseName]; exists {
+			return status
+		}
+		return "healthy"
+	}
+
+	// Global servers (shared processes not bound to a session)
+	for _, status := range s.mcpManager.GetAllServers() {
+		serverID := "server:" + status.Name
+		addNode(TopologyNode{
+			ID:     serverID,
+			Kind:   "server",
+			Label:  status.Name,
+			Health: healthOf(status.Name, status.Running),
+			Attrs:  map[string]interface{}{"scope": "global", "command": status.Command},
+		})
+		if status.Running {
+			processID := fmt.Sprintf("process:%d", status.PID)
+			addNode(TopologyNode{ID: processID, Kind: "process", Label: fmt.Sprintf("pid %d", status.PID), Health: "healthy"})
+			topology.Edges = append(topology.Edges, TopologyEdge{From: serverID, To: processID})
+		}
+	}
+
+	// Sessions, the token that opened them and their server instances
+	connections := s.connectionManager.GetConnections()
+	sessionIDs := s.mcpManager.GetSessionIDs()
+	for sessionID := range connections {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	sort.Strings(sessionIDs)
+
+	for _, sessionID := range sessionIDs {
+		sessionNodeID := "session:" + sessionID
+		if seen[sessionNodeID] {
+			continue
+		}
+
+		attrs := map[string]interface{}{}
+		sessionHealth := "unknown"
+		if conn, exists := connections[sessionID]; exists {
+			sessionHealth = "healthy"
+			attrs["connectedAt"] = conn.ConnectedAt
+			attrs["serverName"] = conn.ServerName
+
+			if conn.TokenFingerprint != "" {
+				tokenID := "token:" + conn.TokenFingerprint
+				addNode(TopologyNode{ID: tokenID, Kind: "token", Label: conn.TokenFingerprint, Health: "healthy"})
+				topology.Edges = append(topology.Edges, TopologyEdge{From: tokenID, To: sessionNodeID})
+			}
+		}
+		addNode(TopologyNode{ID: sessionNodeID, Kind: "session", Label: shortID(sessionID), Health: sessionHealth, Attrs: attrs})
+
+		for baseName, server := range s.mcpManager.GetSessionServerMap(sessionID) {
+			serverID := "server:" + server.Name
+			running := server.IsRunning()
+			addNode(TopologyNode{
+				ID:     serverID,
+				Kind:   "server",
+				Label:  server.Name,
+				Health: healthOf(baseName, running),
+				Attrs: map[string]interface{}{
+					"scope":            "session",
+					"activeOperations": server.GetActiveOperationCount(),
+				},
+			})
+			topology.Edges = append(topology.Edges, TopologyEdge{From: sessionNodeID, To: serverID})
+
+			if pid := server.PID(); pid > 0 {
+				processID := fmt.Sprintf("process:%d", pid)
+				addNode(TopologyNode{ID: processID, Kind: "process", Label: fmt.Sprintf("pid %d", pid), Health: "healthy"})
+				topology.Edges = append(topology.Edges, TopologyEdge{From: serverID, To: processID})
+			}
+		}
+	}
+
+	return topology
+}
+
+// DOT renders the topology in Graphviz DOT format with health colorization
+func (t *Topology) DOT() string {
+	shapes := map[string]string{
+		"token":   "hexagon",
+		"session": "ellipse",
+		"server":  "box",
+		"process": "component",
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph topology {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [style=filled];\n")
+	for _, node := range t.Nodes {
+		color, exists := healthColors[node.Health]
+		if !exists {
+			color = healthColors["unknown"]
+		}
+		fmt.Fprintf(&b, "  %q [label=%q, shape=%s, fillcolor=%s];\n", node.ID, node.Label, shapes[node.Kind], color)
+	}
+	for _, edge := range t.Edges {
+		fmt.Fprintf(&b, "  %q -> %q;\n", edge.From, edge.To)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// handleTopology returns the session/server topology as JSON or Graphviz DOT (?format=dot)
+func (s *Server) handleTopology(w http.ResponseWriter, r *http.Request) {
+	topology := s.buildTopology()
+
+	if r.URL.Query().Get("format") == "dot" {
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte(topology.DOT())); err != nil {
+			logger.System().Error("Failed to write topology DOT response: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(topology); err != nil {
+		logger.System().Error("Failed to encode topology response: %v", err)
+	}
+}
+
+// shortID safely shortens a session ID for display
+func shortID(sessionID string) string {
+	if len(sessionID) > 8 {
+		return sessionID[:8]
+	}
+	return sessionID
+}
@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/mcp"
+)
+
+func TestTopologyEndpoint(t *testing.T) {
+	mcpManager := mcp.NewManager(map[string]config.MCPServer{
+		"memory": {Command: "echo"},
+	})
+	server := NewServerWithConfig(mcpManager, &config.Config{AdminToken: testAdminToken}, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.connectionManager.AddConnection("session-abcdef123", "memory", ctx, cancel)
+	server.connectionManager.SetTokenFingerprint("session-abcdef123", "0123456789ab")
+
+	w := adminRequest(server.Router(), "GET", "/admin/topology")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var topology Topology
+	if err := json.Unmarshal(w.Body.Bytes(), &topology); err != nil {
+		t.Fatalf("Failed to parse topology: %v", err)
+	}
+
+	kinds := make(map[string]int)
+	for _, node := range topology.Nodes {
+		kinds[node.Kind]++
+	}
+	if kinds["token"] != 1 || kinds["session"] != 1 || kinds["server"] != 1 {
+		t.Errorf("Unexpected node kinds: %v", kinds)
+	}
+
+	found := false
+	for _, edge := range topology.Edges {
+		if edge.From == "token:0123456789ab" && edge.To == "session:session-abcdef123" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected token -> session edge, got %v", topology.Edges)
+	}
+
+	// DOT output colors the stopped global server as unhealthy
+	w = adminRequest(server.Router(), "GET", "/admin/topology?format=dot")
+
+	dot := w.Body.String()
+	if !strings.HasPrefix(dot, "digraph topology {") {
+		t.Errorf("Expected DOT output, got %s", dot)
+	}
+	if !strings.Contains(dot, `"server:memory" [label="memory", shape=box, fillcolor=salmon]`) {
+		t.Errorf("Expected unhealthy server node in DOT output, got %s", dot)
+	}
+}
+
+func TestAdminTokenRequired(t *testing.T) {
+	mcpManager := mcp.NewManager(map[string]config.MCPServer{})
+	server := NewServerWithConfig(mcpManager, &config.Config{AdminToken: "secret"}, nil, nil)
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		expected int
+	}{
+		{"no token", "", "", http.StatusUnauthorized},
+		{"wrong token", "Authorization", "Bearer wrong", http.StatusUnauthorized},
+		{"bearer token", "Authorization", "Bearer secret", http.StatusOK},
+		{"admin header", "X-Admin-Token", "secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/topology", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			server.Router().ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
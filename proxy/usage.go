@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/protocol"
+	"remote-mcp-proxy/state"
+)
+
+// EnableUsageAccounting counts the tool calls of principals in usage, which may be persistent
+// Without it the counters are kept in memory only.
+func (s *Server) EnableUsageAccounting(usage *state.Usage) {
+	s.usage = usage
+}
+
+// recordUsage counts the bytes of a tools/call response against the principal that made the call
+// The call itself was counted when refusedByQuota admitted it. Calls without a
+// principal, made while authentication is off, are not counted.
+func (s *Server) recordUsage(ctx context.Context, msg *HookMessage) {
+	principal, _ := ctx.Value("mcpPrincipal").(string)
+	if msg.Method != "tools/call" || principal == "" {
+		return
+	}
+	s.usage.Record(principal, msg.Server, state.UsageCounters{Bytes: int64(len(msg.Message))}, time.Now())
+}
+
+// exceededQuota returns the first quota a principal has used up for calls to serverName today, and what it used
+// The principal's tenant adds its own quotas to the top-level ones.
+func (s *Server) exceededQuota(principal, tenant, serverName string, now time.Time) (config.UsageQuota, state.UsageCounters, bool) {
+	if s.config == nil || principal == "" {
+		return config.UsageQuota{}, state.UsageCounters{}, false
+	}
+	quotas := s.config.Quotas
+	if tenant != "" {
+		quotas = append(slices.Clip(quotas), s.config.Tenants[tenant].Quotas...)
+	}
+	for _, quota := range quotas {
+		if len(quota.Principals) > 0 && !matchesAny(quota.Principals, principal) {
+			continue
+		}
+		if len(quota.Servers) > 0 && !matchesAny(quota.Servers, serverName) {
+			continue
+		}
+		var counted func(string) bool
+		if len(quota.Servers) > 0 {
+			servers := quota.Servers
+			counted = func(name string) bool { return matchesAny(servers, name) }
+		}
+		used := s.usage.Day(principal, now, counted)
+		if (quota.CallsPerDay > 0 && used.Calls >= quota.CallsPerDay) || (quota.BytesPerDay > 0 && used.Bytes >= quota.BytesPerDay) {
+			return quota, used, true
+		}
+	}
+	return config.UsageQuota{}, state.UsageCounters{}, false
+}
+
+// refusedByQuota answers a tool call of a principal over one of its quotas with a 429 error, and reports whether it did so
+// A call it admits is counted right away, so concurrent calls can't all pass the
+// check and overshoot a quota. Retry-After tells the client when the quotas reset,
+// at the next UTC midnight.
+func (s *Server) refusedByQuota(w http.ResponseWriter, r *http.Request, serverName, toolName string, id interface{}, isRemoteMCP bool) bool {
+	principal, _ := r.Context().Value("mcpPrincipal").(string)
+	now := time.Now().UTC()
+	s.quotaMu.Lock()
+	quota, used, exceeded := s.exceededQuota(principal, contextTenant(r.Context()), serverName, now)
+	if !exceeded && principal != "" {
+		s.usage.Record(principal, serverName, state.UsageCounters{Calls: 1}, now)
+	}
+	s.quotaMu.Unlock()
+	if !exceeded {
+		return false
+	}
+
+	resetsAt := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	logger.System().Warn("Refusing call to tool %s on server %s: %q used up its daily quota (%d calls, %d bytes)", toolName, serverName, principal, used.Calls, used.Bytes)
+
+	data := map[string]interface{}{
+		"reason":   "usage_quota",
+		"quota":    quota,
+		"used":     used,
+		"resetsAt": resetsAt,
+	}
+	errorResponse, err := s.translator.CreateErrorResponseWithData(id, protocol.UsageQuotaExceeded,
+		"Daily usage quota exceeded, retry after it resets", data, isRemoteMCP)
+	if err != nil {
+		logger.System().Error(" Failed to create quota response: %v", err)
+		writeError(w, http.StatusInternalServerError, ErrorInternal, "Failed to create error response")
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(int(resetsAt.Sub(now).Seconds())+1))
+	w.WriteHeader(http.StatusTooManyRequests)
+	if _, err := w.Write(errorResponse); err != nil {
+		logger.System().Error(" Failed to write quota response: %v", err)
+	}
+	return true
+}
+
+// handleUsage reports the tool calls and result bytes of each principal, in total and per server: GET /usage
+// The principal and server parameters narrow the report, and since and until
+// (UTC days, YYYY-MM-DD, inclusive) its period, which defaults to the current month.
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := state.UsageFilter{
+		Principal: query.Get("principal"),
+		Server:    query.Get("server"),
+		Since:     time.Now().UTC().Format("2006-01") + "-01",
+		Until:     query.Get("until"),
+	}
+	if since := query.Get("since"); since != "" {
+		filter.Since = since
+	}
+	for _, day := range []string{filter.Since, filter.Until} {
+		if _, err := time.Parse(state.UsageDayLayout, day); day != "" && err != nil {
+			writeError(w, http.StatusBadRequest, ErrorInvalidRequest, fmt.Sprintf("Invalid day %q (expected YYYY-MM-DD)", day))
+			return
+		}
+	}
+
+	report := s.usage.Report(filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"since":      filter.Since,
+		"until":      filter.Until,
+		"principals": report,
+		"count":      len(report),
+	}); err != nil {
+		logger.System().Error("Failed to encode usage response: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/state"
+)
+
+func TestUsageQuotaInProcess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping in-process test in short mode")
+	}
+
+	embedded, err := NewInProcess(&config.Config{
+		AdminToken: testAdminToken,
+		MCPServers: map[string]config.MCPServer{"helper": helperMCPServerConfig()},
+		Quotas:     []config.UsageQuota{{Principals: []string{"token:*"}, Servers: []string{"helper"}, CallsPerDay: 2}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in-process proxy: %v", err)
+	}
+	defer embedded.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := embedded.NewClient("helper")
+	defer client.Close()
+	if _, err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if response, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "hello"}); err != nil || response.Error != nil {
+			t.Fatalf("Expected call %d within the quota to pass, got %+v (%v)", i+1, response, err)
+		}
+	}
+	_, err = client.CallTool(ctx, "echo", map[string]interface{}{"text": "hello"})
+	if err == nil || !strings.Contains(err.Error(), "HTTP 429") || !strings.Contains(err.Error(), "-32003") {
+		t.Fatalf("Expected the call over the quota to be refused with 429, got %v", err)
+	}
+
+	recorder := adminRequest(embedded.Handler, "GET", "/usage?server=helper")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the usage report, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var report struct {
+		Principals []state.PrincipalUsage `json:"principals"`
+	}
+	json.NewDecoder(recorder.Body).Decode(&report)
+	if len(report.Principals) != 1 || !strings.HasPrefix(report.Principals[0].Principal, "token:") {
+		t.Fatalf("Expected the client's usage, got %s", recorder.Body.String())
+	}
+	helper := report.Principals[0].Servers["helper"]
+	if report.Principals[0].Calls != 2 || helper.Calls != 2 || helper.Bytes == 0 {
+		t.Errorf("Expected two counted calls with their bytes, got %+v", report.Principals[0])
+	}
+
+	if recorder := adminRequest(embedded.Handler, "GET", "/usage?since=yesterday"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid day to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestUsageQuotaConcurrentCalls(t *testing.T) {
+	server, _ := newOAuthTestServer(t, &config.Config{
+		Quotas: []config.UsageQuota{{CallsPerDay: 3}},
+	})
+
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/helper/mcp", nil)
+			req = req.WithContext(context.WithValue(req.Context(), "mcpPrincipal", "api-key:ci"))
+			if !server.refusedByQuota(httptest.NewRecorder(), req, "helper", "echo", 1, false) {
+				admitted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if admitted.Load() != 3 {
+		t.Errorf("Expected exactly 3 concurrent calls to be admitted, got %d", admitted.Load())
+	}
+	if used := server.usage.Day("api-key:ci", time.Now(), nil); used.Calls != 3 || used.Bytes != 0 {
+		t.Errorf("Expected the admitted calls to be counted before any result, got %+v", used)
+	}
+}
@@ -0,0 +1,125 @@
+package state
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix starts every value sealed by a Cipher, followed by the key ID
+// and the base64 nonce and ciphertext: "enc:v1:<key id>:<data>". Sealed values
+// hold no newline, so each line of a JSONL file can be sealed on its own.
+const encryptedPrefix = "enc:v1:"
+
+// Cipher encrypts the files of the state directory with AES-256-GCM
+//
+// The first key seals; every key opens what it sealed, so a rotated key stays
+// listed until the files it encrypted have been rewritten. A nil *Cipher
+// leaves files in plaintext, so callers need no enabled checks.
+type Cipher struct {
+	keys []cipherKey // Current key first
+}
+
+// cipherKey is an AES-256-GCM key and the ID naming it in sealed values
+type cipherKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewCipher returns a cipher sealing with the first of keys and opening with any of them
+// Keys are base64 encoded 32 byte keys, e.g. from "openssl rand -base64 32".
+// Without keys it returns nil, which leaves files in plaintext.
+func NewCipher(keys ...string) (*Cipher, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	c := &Cipher{}
+	for i, encoded := range keys {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %d is not a base64 encoded 32 byte key", i+1)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		c.keys = append(c.keys, cipherKey{id: hex.EncodeToString(sum[:4]), aead: aead})
+	}
+	return c, nil
+}
+
+// KeyID returns the ID of the key sealing new values, empty for a nil cipher
+func (c *Cipher) KeyID() string {
+	if c == nil {
+		return ""
+	}
+	return c.keys[0].id
+}
+
+// Seal encrypts plaintext with the current key; a nil cipher returns it unchanged
+func (c *Cipher) Seal(plaintext []byte) ([]byte, error) {
+	if c == nil {
+		return plaintext, nil
+	}
+	key := c.keys[0]
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := key.aead.Seal(nonce, nonce, plaintext, []byte(key.id))
+	return []byte(encryptedPrefix + key.id + ":" + base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// Open decrypts a value sealed by Seal, and returns plaintext values unchanged
+// current reports whether the value is stored the way Seal would store it now:
+// false for plaintext while encrypting and for values sealed with a previous
+// key, which callers rewrite to complete a rotation.
+func (c *Cipher) Open(data []byte) (plaintext []byte, current bool, err error) {
+	if !bytes.HasPrefix(data, []byte(encryptedPrefix)) {
+		return data, c == nil, nil
+	}
+	keyID, encoded, found := strings.Cut(string(data[len(encryptedPrefix):]), ":")
+	if !found {
+		return nil, false, fmt.Errorf("malformed encrypted value")
+	}
+	if c == nil {
+		return nil, false, &KeyError{KeyID: keyID}
+	}
+	for i, key := range c.keys {
+		if key.id != keyID {
+			continue
+		}
+		sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(sealed) < key.aead.NonceSize() {
+			return nil, false, fmt.Errorf("malformed encrypted value")
+		}
+		nonce, ciphertext := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+		plaintext, err := key.aead.Open(nil, nonce, ciphertext, []byte(key.id))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decrypt value sealed with key %s: %w", keyID, err)
+		}
+		return plaintext, i == 0, nil
+	}
+	return nil, false, &KeyError{KeyID: keyID}
+}
+
+// KeyError reports a value sealed with a key the cipher doesn't have
+// Files holding such values are left untouched rather than read as empty.
+type KeyError struct {
+	KeyID string
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("encrypted with key %s, which is not configured (see STATE_ENCRYPTION_KEY and STATE_ENCRYPTION_PREVIOUS_KEYS)", e.KeyID)
+}
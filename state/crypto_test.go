@@ -0,0 +1,114 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	testKey      = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	testRotation = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher(testKey)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	sealed, err := c.Seal([]byte(`{"secret":"hunter2"}`))
+	if err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("hunter2")) || bytes.ContainsRune(sealed, '\n') {
+		t.Fatalf("Expected an opaque single line, got %s", sealed)
+	}
+
+	plaintext, current, err := c.Open(sealed)
+	if err != nil || !current || string(plaintext) != `{"secret":"hunter2"}` {
+		t.Errorf("Expected the value back with the current key, got %q %v %v", plaintext, current, err)
+	}
+	if plaintext, current, err := c.Open([]byte(`{"plain":true}`)); err != nil || current || string(plaintext) != `{"plain":true}` {
+		t.Errorf("Expected plaintext to be read and reported stale, got %q %v %v", plaintext, current, err)
+	}
+
+	rotated, _ := NewCipher(testRotation, testKey)
+	if _, current, err := rotated.Open(sealed); err != nil || current {
+		t.Errorf("Expected a previous key to open the value and report it stale, got %v %v", current, err)
+	}
+	var keyErr *KeyError
+	other, _ := NewCipher(testRotation)
+	if _, _, err := other.Open(sealed); !errors.As(err, &keyErr) {
+		t.Errorf("Expected a key error without the sealing key, got %v", err)
+	}
+	var none *Cipher
+	if _, _, err := none.Open(sealed); !errors.As(err, &keyErr) {
+		t.Errorf("Expected a key error without any key, got %v", err)
+	}
+
+	if _, err := NewCipher("c2hvcnQ="); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+}
+
+func TestStateRotation(t *testing.T) {
+	dir := t.TempDir()
+	old, _ := NewCipher(testKey)
+	rotated, _ := NewCipher(testRotation, testKey)
+
+	// Plaintext files from before encryption are encrypted when loaded
+	store, _ := NewStore(dir, 100, time.Hour)
+	store.RecordIncident(Incident{Kind: IncidentAdmin, Actor: "admin", Reason: "plaintext"})
+	usage, _ := NewUsage(dir, 0)
+	usage.Record("api-key:ci", "github", UsageCounters{Calls: 1}, time.Now())
+	usage.Save()
+
+	if _, err := NewEncryptedStore(dir, old, 100, time.Hour); err != nil {
+		t.Fatalf("Failed to encrypt the incident history: %v", err)
+	}
+	if _, err := NewEncryptedUsage(dir, old, 0); err != nil {
+		t.Fatalf("Failed to encrypt the usage counters: %v", err)
+	}
+	for _, name := range []string{incidentsFile, usageFile} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); !bytes.HasPrefix(data, []byte(encryptedPrefix+old.KeyID())) {
+			t.Errorf("Expected %s to be encrypted, got %s", name, data)
+		}
+	}
+
+	// Without the key the files are refused rather than read as empty
+	if _, err := NewStore(dir, 100, time.Hour); err == nil {
+		t.Error("Expected the encrypted incident history to be refused without its key")
+	}
+	if _, err := NewUsage(dir, 0); err == nil {
+		t.Error("Expected the encrypted usage counters to be refused without their key")
+	}
+
+	// A rotated key rewrites the files, which then no longer need the old one
+	store, err := NewEncryptedStore(dir, rotated, 100, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open the incident history after rotation: %v", err)
+	}
+	store.RecordIncident(Incident{Kind: IncidentAdmin, Actor: "admin", Reason: "rotated"})
+	if _, err := NewEncryptedUsage(dir, rotated, 0); err != nil {
+		t.Fatalf("Failed to open the usage counters after rotation: %v", err)
+	}
+
+	rotatedOnly, _ := NewCipher(testRotation)
+	reopened, err := NewEncryptedStore(dir, rotatedOnly, 100, time.Hour)
+	if err != nil {
+		t.Fatalf("Expected the incident history to be re-encrypted, got %v", err)
+	}
+	if incidents := reopened.Incidents(IncidentFilter{}); len(incidents) != 2 || incidents[1].Reason != "plaintext" {
+		t.Errorf("Expected both incidents after rotation, got %+v", incidents)
+	}
+	counters, err := NewEncryptedUsage(dir, rotatedOnly, 0)
+	if err != nil {
+		t.Fatalf("Expected the usage counters to be re-encrypted, got %v", err)
+	}
+	if report := counters.Report(UsageFilter{}); len(report) != 1 || report[0].Calls != 1 {
+		t.Errorf("Expected the counted call after rotation, got %+v", report)
+	}
+}
@@ -0,0 +1,112 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// Incident kinds
+const (
+	IncidentCrash   = "crash"   // MCP server process exited unexpectedly
+	IncidentRestart = "restart" // MCP server restarted by the health checker or an operator
+	IncidentHealth  = "health"  // Health status flipped between healthy and unhealthy
+	IncidentAdmin   = "admin"   // Other operator action, e.g. stop or maintenance mode
+	IncidentPolicy  = "policy"  // Tool call refused by the proxy policy
+	IncidentScan    = "scan"    // Sensitive content found in a tool result
+)
+
+// Incident is one entry of the restart/crash/health/admin history
+type Incident struct {
+	ID        string                 `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Kind      string                 `json:"kind"`
+	Server    string                 `json:"server,omitempty"`
+	Actor     string                 `json:"actor"`            // Who: "health-checker", "monitor" or the admin
+	Action    string                 `json:"action,omitempty"` // What, e.g. "restart" or "set-maintenance"
+	Reason    string                 `json:"reason,omitempty"` // Why, e.g. the last health check error
+	Success   bool                   `json:"success"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// IncidentFilter selects incidents; zero values match everything
+type IncidentFilter struct {
+	Server string
+	Kind   string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// RecordIncident adds an incident to the history
+// Timestamp and ID are filled in when empty.
+func (s *Store) RecordIncident(incident Incident) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if incident.Timestamp.IsZero() {
+		incident.Timestamp = time.Now()
+	}
+	s.nextID++
+	if incident.ID == "" {
+		incident.ID = fmt.Sprintf("%d-%d", incident.Timestamp.UnixNano(), s.nextID)
+	}
+
+	s.incidents = append(s.incidents, incident)
+	if s.dir != "" {
+		s.appendToFile(incident)
+	}
+	s.prune(time.Now())
+	s.compactIfNeeded()
+	observers := s.observers
+	s.mu.Unlock()
+
+	for _, observer := range observers {
+		observer(incident)
+	}
+}
+
+// OnIncident calls observer with every incident recorded from now on
+// Observers run on the recording goroutine, so they should not block.
+func (s *Store) OnIncident(observer func(Incident)) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observers = append(s.observers, observer)
+}
+
+// Incidents returns the matching incidents, newest first
+func (s *Store) Incidents(filter IncidentFilter) []Incident {
+	result := []Incident{}
+	if s == nil {
+		return result
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.incidents) - 1; i >= 0; i-- {
+		incident := s.incidents[i]
+		if filter.Server != "" && incident.Server != filter.Server {
+			continue
+		}
+		if filter.Kind != "" && incident.Kind != filter.Kind {
+			continue
+		}
+		if !filter.Since.IsZero() && incident.Timestamp.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && incident.Timestamp.After(filter.Until) {
+			continue
+		}
+		result = append(result, incident)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result
+}
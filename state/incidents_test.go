@@ -0,0 +1,81 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncidentHistoryPersists(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewStore(dir, 100, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	store.RecordIncident(Incident{Kind: IncidentCrash, Server: "memory", Actor: "monitor", Reason: "exit status 1"})
+	store.RecordIncident(Incident{Kind: IncidentRestart, Server: "memory", Actor: "health-checker", Success: true})
+	store.RecordIncident(Incident{Kind: IncidentAdmin, Server: "notion", Actor: "admin", Action: "stop", Success: true})
+
+	reopened, err := NewStore(dir, 100, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+
+	incidents := reopened.Incidents(IncidentFilter{Server: "memory"})
+	if len(incidents) != 2 {
+		t.Fatalf("Expected 2 memory incidents after reload, got %+v", incidents)
+	}
+	if incidents[0].Kind != IncidentRestart || incidents[1].Kind != IncidentCrash {
+		t.Errorf("Expected newest first, got %s then %s", incidents[0].Kind, incidents[1].Kind)
+	}
+	if incidents[0].ID == "" || incidents[0].Timestamp.IsZero() {
+		t.Errorf("Expected ID and timestamp to be filled in, got %+v", incidents[0])
+	}
+
+	if admin := reopened.Incidents(IncidentFilter{Kind: IncidentAdmin}); len(admin) != 1 || admin[0].Action != "stop" {
+		t.Errorf("Expected one admin incident, got %+v", admin)
+	}
+	if limited := reopened.Incidents(IncidentFilter{Limit: 1}); len(limited) != 1 {
+		t.Errorf("Expected limit to apply, got %d incidents", len(limited))
+	}
+}
+
+func TestIncidentRetention(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewStore(dir, 5, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	store.RecordIncident(Incident{Kind: IncidentHealth, Timestamp: time.Now().Add(-2 * time.Hour)})
+	if incidents := store.Incidents(IncidentFilter{}); len(incidents) != 0 {
+		t.Errorf("Expected expired incident to be dropped, got %+v", incidents)
+	}
+
+	for i := 0; i < 300; i++ {
+		store.RecordIncident(Incident{Kind: IncidentHealth, Server: "memory"})
+	}
+	if incidents := store.Incidents(IncidentFilter{}); len(incidents) != 5 {
+		t.Errorf("Expected 5 retained incidents, got %d", len(incidents))
+	}
+
+	// The file is compacted instead of growing with every expired entry
+	data, err := os.ReadFile(filepath.Join(dir, incidentsFile))
+	if err != nil {
+		t.Fatalf("Failed to read incident file: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines > 2*5+100 {
+		t.Errorf("Expected incident file to be compacted, has %d lines", lines)
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var store *Store
+	store.RecordIncident(Incident{Kind: IncidentCrash})
+	if incidents := store.Incidents(IncidentFilter{}); len(incidents) != 0 {
+		t.Errorf("Expected no incidents from nil store, got %+v", incidents)
+	}
+}
@@ -0,0 +1,206 @@
+// Package state persists operational history across proxy restarts
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+// incidentsFile is the JSONL file holding the incident history inside the state directory
+const incidentsFile = "incidents.jsonl"
+
+// Store keeps the incident history in memory and appends it to {dir}/incidents.jsonl
+//
+// Retention is enforced by entry count and age; once enough entries have expired
+// the file is rewritten so it never grows much beyond the retained history.
+// With a cipher each line is encrypted on its own.
+// A nil *Store records nothing, so callers need no enabled checks.
+type Store struct {
+	dir          string // Empty for memory only
+	cipher       *Cipher
+	maxIncidents int
+	retention    time.Duration
+	incidents    []Incident // Oldest first
+	fileEntries  int        // Lines in the incidents file, including expired ones
+	nextID       int64
+	observers    []func(Incident) // Called after each incident is recorded
+	mu           sync.Mutex
+}
+
+// NewStore opens the state store in dir, loading the incidents retained from
+// previous runs. An empty dir keeps the history in memory only.
+func NewStore(dir string, maxIncidents int, retention time.Duration) (*Store, error) {
+	return NewEncryptedStore(dir, nil, maxIncidents, retention)
+}
+
+// NewEncryptedStore opens the state store in dir like NewStore, encrypting the incident history with cipher
+// Lines in plaintext or sealed with a previous key are rewritten with the current one.
+func NewEncryptedStore(dir string, cipher *Cipher, maxIncidents int, retention time.Duration) (*Store, error) {
+	s := &Store{
+		dir:          dir,
+		cipher:       cipher,
+		maxIncidents: maxIncidents,
+		retention:    retention,
+	}
+	if dir == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	stale, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	if stale {
+		s.compact()
+	} else {
+		s.compactIfNeeded()
+	}
+	return s, nil
+}
+
+// load reads the incidents file, skipping lines that cannot be parsed
+// stale reports lines not stored with the current key, which compaction rewrites.
+// Lines sealed with a key the cipher doesn't have fail the load, so that
+// compaction can't drop them.
+func (s *Store) load() (stale bool, err error) {
+	file, err := os.Open(filepath.Join(s.dir, incidentsFile))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open incident history: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		s.fileEntries++
+		line, current, err := s.cipher.Open(scanner.Bytes())
+		var keyErr *KeyError
+		if errors.As(err, &keyErr) {
+			return false, fmt.Errorf("failed to read incident history: %w", err)
+		}
+		stale = stale || !current
+		var incident Incident
+		if err != nil || json.Unmarshal(line, &incident) != nil {
+			continue
+		}
+		s.incidents = append(s.incidents, incident)
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read incident history: %w", err)
+	}
+
+	s.nextID = int64(len(s.incidents))
+	logger.System().Info("Loaded %d incidents from %s", len(s.incidents), s.dir)
+	return stale, nil
+}
+
+// marshal encodes an incident as a line of the incidents file, without the newline
+func (s *Store) marshal(incident Incident) ([]byte, error) {
+	line, err := json.Marshal(incident)
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.Seal(line)
+}
+
+// prune drops incidents beyond the retention limits
+// NOTE: This method must be called with s.mu locked
+func (s *Store) prune(now time.Time) {
+	drop := 0
+	if s.retention > 0 {
+		cutoff := now.Add(-s.retention)
+		for drop < len(s.incidents) && s.incidents[drop].Timestamp.Before(cutoff) {
+			drop++
+		}
+	}
+	if s.maxIncidents > 0 && len(s.incidents)-drop > s.maxIncidents {
+		drop = len(s.incidents) - s.maxIncidents
+	}
+	if drop > 0 {
+		s.incidents = append([]Incident(nil), s.incidents[drop:]...)
+	}
+}
+
+// compactIfNeeded rewrites the incidents file once it holds twice the retained entries
+// NOTE: This method must be called with s.mu locked
+func (s *Store) compactIfNeeded() {
+	if s.dir == "" || s.fileEntries <= 2*len(s.incidents)+100 {
+		return
+	}
+	s.compact()
+}
+
+// compact rewrites the incidents file with the retained incidents
+// NOTE: This method must be called with s.mu locked
+func (s *Store) compact() {
+	filename := filepath.Join(s.dir, incidentsFile)
+	tmp := filename + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		logger.System().Warn("Failed to compact incident history: %v", err)
+		return
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, incident := range s.incidents {
+		line, err := s.marshal(incident)
+		if err != nil {
+			continue
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		logger.System().Warn("Failed to compact incident history: %v", err)
+		return
+	}
+	file.Close()
+
+	if err := os.Rename(tmp, filename); err != nil {
+		logger.System().Warn("Failed to compact incident history: %v", err)
+		return
+	}
+	s.fileEntries = len(s.incidents)
+}
+
+// appendToFile writes an incident to the incidents file
+// NOTE: This method must be called with s.mu locked
+func (s *Store) appendToFile(incident Incident) {
+	line, err := s.marshal(incident)
+	if err != nil {
+		return
+	}
+
+	filename := filepath.Join(s.dir, incidentsFile)
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		logger.System().Warn("Failed to open incident history %s: %v", filename, err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		logger.System().Warn("Failed to write incident history %s: %v", filename, err)
+		return
+	}
+	s.fileEntries++
+}
@@ -0,0 +1,267 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/logger"
+)
+
+// usageFile is the JSON file holding the usage counters inside the state directory
+const usageFile = "usage.json"
+
+// UsageDayLayout formats the UTC days usage is counted by
+const UsageDayLayout = "2006-01-02"
+
+// usageSaveInterval is the least time between two writes of the usage file while calls are counted
+var usageSaveInterval = 30 * time.Second
+
+// UsageCounters count tool calls and the bytes of their results
+type UsageCounters struct {
+	Calls int64 `json:"calls"`
+	Bytes int64 `json:"bytes"`
+}
+
+// add adds other to the counters
+func (c *UsageCounters) add(other UsageCounters) {
+	c.Calls += other.Calls
+	c.Bytes += other.Bytes
+}
+
+// Usage counts the tool calls of each principal per UTC day and server, and saves them to {dir}/usage.json
+//
+// The file is rewritten at most every usageSaveInterval while calls are
+// counted, and by Save; days older than the retention are dropped. With a
+// cipher the file is encrypted. A nil *Usage counts nothing, so callers need
+// no enabled checks.
+type Usage struct {
+	dir       string // Empty for memory only
+	cipher    *Cipher
+	retention time.Duration
+	days      map[string]map[string]map[string]*UsageCounters // Day -> principal -> server
+	dirty     bool                                            // Counted since the last save
+	savedAt   time.Time
+	mu        sync.Mutex
+	saveMu    sync.Mutex // Serializes writes of the usage file
+}
+
+// NewUsage opens the usage counters in dir, loading those retained from
+// previous runs. An empty dir keeps the counters in memory only.
+func NewUsage(dir string, retention time.Duration) (*Usage, error) {
+	return NewEncryptedUsage(dir, nil, retention)
+}
+
+// NewEncryptedUsage opens the usage counters in dir like NewUsage, encrypting the usage file with cipher
+// A file in plaintext or sealed with a previous key is rewritten with the current one.
+func NewEncryptedUsage(dir string, cipher *Cipher, retention time.Duration) (*Usage, error) {
+	u := &Usage{
+		dir:       dir,
+		cipher:    cipher,
+		retention: retention,
+		days:      make(map[string]map[string]map[string]*UsageCounters),
+		savedAt:   time.Now(),
+	}
+	if dir == "" {
+		return u, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, usageFile))
+	if os.IsNotExist(err) {
+		return u, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage counters: %w", err)
+	}
+	data, current, err := cipher.Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage counters: %w", err)
+	}
+	if err := json.Unmarshal(data, &u.days); err != nil {
+		return nil, fmt.Errorf("failed to parse usage counters: %w", err)
+	}
+	u.prune(time.Now())
+	logger.System().Info("Loaded usage counters of %d days from %s", len(u.days), dir)
+
+	if !current {
+		u.dirty = true
+		if err := u.Save(); err != nil {
+			logger.System().Warn("Failed to re-encrypt usage counters: %v", err)
+		}
+	}
+	return u, nil
+}
+
+// prune drops the days beyond the retention
+// NOTE: This method must be called with u.mu locked
+func (u *Usage) prune(now time.Time) {
+	if u.retention <= 0 {
+		return
+	}
+	cutoff := now.UTC().Add(-u.retention).Format(UsageDayLayout)
+	for day := range u.days {
+		if day < cutoff {
+			delete(u.days, day)
+			u.dirty = true
+		}
+	}
+}
+
+// Record counts tool calls of a principal to a server, and the bytes of their results
+func (u *Usage) Record(principal, serverName string, counters UsageCounters, at time.Time) {
+	if u == nil {
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	day := at.UTC().Format(UsageDayLayout)
+
+	u.mu.Lock()
+	principals, exists := u.days[day]
+	if !exists {
+		principals = make(map[string]map[string]*UsageCounters)
+		u.days[day] = principals
+		u.prune(at)
+	}
+	servers, exists := principals[principal]
+	if !exists {
+		servers = make(map[string]*UsageCounters)
+		principals[principal] = servers
+	}
+	if servers[serverName] == nil {
+		servers[serverName] = &UsageCounters{}
+	}
+	servers[serverName].add(counters)
+	u.dirty = true
+	save := u.dir != "" && time.Since(u.savedAt) >= usageSaveInterval
+	u.mu.Unlock()
+
+	if save {
+		if err := u.Save(); err != nil {
+			logger.System().Warn("Failed to save usage counters: %v", err)
+		}
+	}
+}
+
+// Day returns what a principal used on the UTC day of at, on the servers
+// accepted by match (every server when match is nil)
+func (u *Usage) Day(principal string, at time.Time, match func(serverName string) bool) UsageCounters {
+	var total UsageCounters
+	if u == nil {
+		return total
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for serverName, counters := range u.days[at.UTC().Format(UsageDayLayout)][principal] {
+		if match == nil || match(serverName) {
+			total.add(*counters)
+		}
+	}
+	return total
+}
+
+// UsageFilter selects the usage to report
+type UsageFilter struct {
+	Principal string // Only this principal (every principal when empty)
+	Server    string // Only this server (every server when empty)
+	Since     string // First UTC day, formatted with UsageDayLayout (unbounded when empty)
+	Until     string // Last UTC day, formatted with UsageDayLayout (unbounded when empty)
+}
+
+// PrincipalUsage is what one principal used, in total and per server
+type PrincipalUsage struct {
+	Principal string `json:"principal"`
+	UsageCounters
+	Servers map[string]UsageCounters `json:"servers"`
+}
+
+// Report sums the usage the filter selects per principal, ordered by principal
+func (u *Usage) Report(filter UsageFilter) []PrincipalUsage {
+	if u == nil {
+		return []PrincipalUsage{}
+	}
+
+	u.mu.Lock()
+	byPrincipal := make(map[string]*PrincipalUsage)
+	for day, principals := range u.days {
+		if (filter.Since != "" && day < filter.Since) || (filter.Until != "" && day > filter.Until) {
+			continue
+		}
+		for principal, servers := range principals {
+			if filter.Principal != "" && principal != filter.Principal {
+				continue
+			}
+			for serverName, counters := range servers {
+				if filter.Server != "" && serverName != filter.Server {
+					continue
+				}
+				usage := byPrincipal[principal]
+				if usage == nil {
+					usage = &PrincipalUsage{Principal: principal, Servers: make(map[string]UsageCounters)}
+					byPrincipal[principal] = usage
+				}
+				usage.add(*counters)
+				perServer := usage.Servers[serverName]
+				perServer.add(*counters)
+				usage.Servers[serverName] = perServer
+			}
+		}
+	}
+	u.mu.Unlock()
+
+	report := make([]PrincipalUsage, 0, len(byPrincipal))
+	for _, usage := range byPrincipal {
+		report = append(report, *usage)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Principal < report[j].Principal
+	})
+	return report
+}
+
+// Save writes the counters to the usage file if they changed since the last save
+func (u *Usage) Save() error {
+	if u == nil || u.dir == "" {
+		return nil
+	}
+	u.saveMu.Lock()
+	defer u.saveMu.Unlock()
+
+	u.mu.Lock()
+	if !u.dirty {
+		u.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(u.days)
+	u.dirty = false
+	u.savedAt = time.Now()
+	u.mu.Unlock()
+	if err == nil {
+		data, err = u.cipher.Seal(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(u.dir, usageFile)
+	tmp := filename + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		u.mu.Lock()
+		u.dirty = true // Retried on the next save
+		u.mu.Unlock()
+	}
+	return err
+}
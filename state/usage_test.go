@@ -0,0 +1,67 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsagePersists(t *testing.T) {
+	dir := t.TempDir()
+	today := time.Date(2026, 3, 14, 23, 30, 0, 0, time.UTC)
+	yesterday := today.Add(-24 * time.Hour)
+
+	usage, err := NewUsage(dir, 0)
+	if err != nil {
+		t.Fatalf("Failed to open usage: %v", err)
+	}
+	usage.Record("api-key:ci", "github", UsageCounters{Calls: 1, Bytes: 100}, today)
+	usage.Record("api-key:ci", "github", UsageCounters{Calls: 1, Bytes: 50}, today)
+	usage.Record("api-key:ci", "files", UsageCounters{Calls: 1, Bytes: 7}, today)
+	usage.Record("api-key:ci", "github", UsageCounters{Calls: 1, Bytes: 1000}, yesterday)
+	usage.Record("oidc:alice", "files", UsageCounters{Calls: 2, Bytes: 20}, today)
+
+	if day := usage.Day("api-key:ci", today, nil); day != (UsageCounters{Calls: 3, Bytes: 157}) {
+		t.Errorf("Unexpected usage today: %+v", day)
+	}
+	if day := usage.Day("api-key:ci", today, func(server string) bool { return server == "files" }); day != (UsageCounters{Calls: 1, Bytes: 7}) {
+		t.Errorf("Unexpected usage of files today: %+v", day)
+	}
+
+	if err := usage.Save(); err != nil {
+		t.Fatalf("Failed to save usage: %v", err)
+	}
+	reopened, err := NewUsage(dir, 0)
+	if err != nil {
+		t.Fatalf("Failed to reopen usage: %v", err)
+	}
+
+	report := reopened.Report(UsageFilter{})
+	if len(report) != 2 || report[0].Principal != "api-key:ci" || report[1].Principal != "oidc:alice" {
+		t.Fatalf("Expected both principals after reload, got %+v", report)
+	}
+	if report[0].UsageCounters != (UsageCounters{Calls: 4, Bytes: 1157}) || report[0].Servers["github"] != (UsageCounters{Calls: 3, Bytes: 1150}) {
+		t.Errorf("Unexpected totals: %+v", report[0])
+	}
+
+	since := reopened.Report(UsageFilter{Principal: "api-key:ci", Server: "github", Since: today.Format(UsageDayLayout)})
+	if len(since) != 1 || since[0].UsageCounters != (UsageCounters{Calls: 2, Bytes: 150}) || len(since[0].Servers) != 1 {
+		t.Errorf("Expected the filter to apply, got %+v", since)
+	}
+}
+
+func TestUsageRetention(t *testing.T) {
+	usage, _ := NewUsage("", 48*time.Hour)
+	now := time.Now()
+	usage.Record("api-key:ci", "github", UsageCounters{Calls: 1}, now.Add(-10*24*time.Hour))
+	usage.Record("api-key:ci", "github", UsageCounters{Calls: 1}, now)
+
+	if report := usage.Report(UsageFilter{}); len(report) != 1 || report[0].Calls != 1 {
+		t.Errorf("Expected the expired day to be dropped, got %+v", report)
+	}
+
+	var disabled *Usage
+	disabled.Record("api-key:ci", "github", UsageCounters{Calls: 1}, now)
+	if day := disabled.Day("api-key:ci", now, nil); day.Calls != 0 {
+		t.Errorf("Expected a nil usage to count nothing, got %+v", day)
+	}
+}
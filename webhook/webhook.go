@@ -0,0 +1,229 @@
+// Package webhook delivers server and session events to operator endpoints
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/logger"
+	"remote-mcp-proxy/state"
+)
+
+const (
+	queueSize       = 256              // Events waiting for delivery before new ones are dropped
+	maxAttempts     = 3                // Deliveries per event and webhook, including the first
+	deliveryTimeout = 10 * time.Second // Per attempt
+)
+
+// Event is the JSON body POSTed to webhooks
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"` // One of config.WebhookEvents
+	Timestamp time.Time              `json:"timestamp"`
+	Server    string                 `json:"server,omitempty"`
+	Session   string                 `json:"session,omitempty"`
+	Actor     string                 `json:"actor,omitempty"`  // Who caused it, e.g. "health-checker" or the admin
+	Action    string                 `json:"action,omitempty"` // e.g. "healthy -> unhealthy" or "failover"
+	Reason    string                 `json:"reason,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Notifier queues events and delivers them to every webhook subscribed to their type
+//
+// Deliveries happen on a background goroutine, so Notify never blocks the
+// caller. A nil *Notifier delivers nothing, so callers need no enabled checks.
+type Notifier struct {
+	hooks      []config.Webhook
+	client     *http.Client
+	queue      chan Event
+	done       chan struct{}
+	retryDelay time.Duration // Doubled after each failed attempt
+	nextID     int64
+	mu         sync.Mutex
+	closed     bool
+}
+
+// NewNotifier starts delivering events to hooks, or returns nil when there are none
+func NewNotifier(hooks []config.Webhook) *Notifier {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	n := &Notifier{
+		hooks:      hooks,
+		client:     &http.Client{Timeout: deliveryTimeout},
+		queue:      make(chan Event, queueSize),
+		done:       make(chan struct{}),
+		retryDelay: time.Second,
+	}
+	go n.run()
+	logger.System().Info("Webhooks enabled for %d endpoint(s)", len(hooks))
+	return n
+}
+
+// Notify queues an event, filling in its ID and timestamp when empty
+// Events are dropped with a warning when the queue is full or the notifier is closed.
+func (n *Notifier) Notify(event Event) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	n.nextID++
+	if event.ID == "" {
+		event.ID = fmt.Sprintf("%d-%d", event.Timestamp.UnixNano(), n.nextID)
+	}
+
+	select {
+	case n.queue <- event:
+	default:
+		logger.System().Warn("Webhook queue full, dropping %s event for %s", event.Type, event.Server)
+	}
+}
+
+// Close stops accepting events and waits up to timeout for queued ones to be delivered
+func (n *Notifier) Close(timeout time.Duration) {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	select {
+	case <-n.done:
+	case <-time.After(timeout):
+		logger.System().Warn("Timed out delivering queued webhook events")
+	}
+}
+
+// run delivers queued events until the queue is closed
+func (n *Notifier) run() {
+	defer close(n.done)
+
+	for event := range n.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			logger.System().Error("Failed to encode webhook event %s: %v", event.Type, err)
+			continue
+		}
+		for _, hook := range n.hooks {
+			if subscribed(hook, event.Type) {
+				n.deliver(hook, event, body)
+			}
+		}
+	}
+}
+
+// deliver POSTs an event to one webhook, retrying failed attempts with backoff
+func (n *Notifier) deliver(hook config.Webhook, event Event, body []byte) {
+	delay := n.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := n.post(hook, event, body)
+		if err == nil {
+			logger.System().Debug("Delivered %s webhook event %s to %s", event.Type, event.ID, hook.URL)
+			return
+		}
+		if attempt == maxAttempts {
+			logger.System().Warn("Failed to deliver %s webhook event to %s after %d attempts: %v", event.Type, hook.URL, attempt, err)
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post makes one delivery attempt; any status other than 2xx is a failure
+func (n *Notifier) post(hook config.Webhook, event Event, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "remote-mcp-proxy")
+	req.Header.Set("X-Webhook-Event", event.Type)
+	req.Header.Set("X-Webhook-ID", event.ID)
+	if hook.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", Sign(hook.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the X-Webhook-Signature of a body: "sha256=" and the hex HMAC-SHA256 keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// subscribed reports whether a webhook receives events of a type
+func subscribed(hook config.Webhook, eventType string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range hook.Events {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// incidentEvents maps the incident kinds that webhooks report to their event type
+var incidentEvents = map[string]string{
+	state.IncidentCrash:   config.WebhookServerCrash,
+	state.IncidentRestart: config.WebhookServerRestart,
+	state.IncidentHealth:  config.WebhookServerHealth,
+}
+
+// NotifyIncident sends crash, restart and health incidents as events
+// It is meant to be registered with state.Store.OnIncident.
+func (n *Notifier) NotifyIncident(incident state.Incident) {
+	eventType, reported := incidentEvents[incident.Kind]
+	if !reported {
+		return
+	}
+
+	details := map[string]interface{}{"success": incident.Success, "incident": incident.ID}
+	for key, value := range incident.Details {
+		details[key] = value
+	}
+	n.Notify(Event{
+		Type:      eventType,
+		Timestamp: incident.Timestamp,
+		Server:    incident.Server,
+		Actor:     incident.Actor,
+		Action:    incident.Action,
+		Reason:    incident.Reason,
+		Details:   details,
+	})
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"remote-mcp-proxy/config"
+	"remote-mcp-proxy/state"
+)
+
+func TestNotifier(t *testing.T) {
+	var mu sync.Mutex
+	var received []Event
+	failures := 1
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got := r.Header.Get("X-Webhook-Signature"); got != Sign("s3cret", body) {
+			t.Errorf("Expected a valid signature, got %q", got)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("Failed to parse event: %v", err)
+		}
+		received = append(received, event)
+	}))
+	defer endpoint.Close()
+
+	n := NewNotifier([]config.Webhook{{
+		URL:    endpoint.URL,
+		Secret: "s3cret",
+		Events: []string{config.WebhookServerCrash, config.WebhookSessionClosed},
+	}})
+	n.retryDelay = time.Millisecond
+
+	// Incidents become events through the store
+	store, err := state.NewStore("", 100, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.OnIncident(n.NotifyIncident)
+	store.RecordIncident(state.Incident{Kind: state.IncidentCrash, Server: "memory", Actor: "monitor", Reason: "exit status 1"})
+	store.RecordIncident(state.Incident{Kind: state.IncidentAdmin, Server: "memory", Action: "stop"})
+
+	n.Notify(Event{Type: config.WebhookSessionCreated, Server: "memory", Session: "abc"}) // Not subscribed
+	n.Notify(Event{Type: config.WebhookSessionClosed, Server: "memory", Session: "abc"})
+	n.Close(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 events, got %+v", received)
+	}
+	if received[0].Type != config.WebhookServerCrash || received[0].Reason != "exit status 1" || received[0].ID == "" {
+		t.Errorf("Unexpected crash event, got %+v", received[0])
+	}
+	if received[1].Type != config.WebhookSessionClosed || received[1].Session != "abc" {
+		t.Errorf("Unexpected session event, got %+v", received[1])
+	}
+
+	// A nil notifier accepts events without delivering them
+	var disabled *Notifier
+	disabled.Notify(Event{Type: config.WebhookServerCrash})
+	disabled.Close(time.Second)
+}